@@ -0,0 +1,333 @@
+/*
+Package main tails a conversation live, to debug the delivery of the
+real-time events.
+
+It authenticates as a user (or a bot account) with a session token,
+from -token or WASATEXT_TOKEN, or by logging in with -name, prints the
+last messages of the conversation, then every event of the conversation
+the server pushes over /ws until interrupted:
+
+	go run ./cmd/wasatail -name alice <conversationId>
+	WASATEXT_TOKEN=... go run ./cmd/wasatail -json <conversationId> | jq .type
+
+With -json each event is printed as the server sent it, one per line,
+and the last messages as message events, so that other tools can read
+the output. wasatail does not acknowledge the messages it gets: tailing
+a conversation does not mark them received. When the connection drops
+it reconnects; the events in between are lost, as for any client.
+*/
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"wasatext/service/api"
+	"wasatext/service/ids"
+	"wasatext/service/websocket"
+)
+
+const (
+	// readTimeout is how long without a frame before the connection is
+	// taken for dead; the server pings every 30 seconds
+	readTimeout = 90 * time.Second
+
+	// Reconnection delays, doubled after each failure
+	minRetryDelay = time.Second
+	maxRetryDelay = 30 * time.Second
+
+	requestTimeout = 10 * time.Second
+)
+
+// event is any event of /ws; the fields are those of its type
+type event struct {
+	Type           string                   `json:"type"`
+	ConversationID ids.ConversationID       `json:"conversationId"`
+	Message        *api.MessageResponse     `json:"message,omitempty"`
+	MessageID      ids.MessageID            `json:"messageId,omitempty"`
+	Reactions      []api.CommentResponse    `json:"reactions,omitempty"`
+	Pinned         bool                     `json:"pinned,omitempty"`
+	Statuses       map[ids.MessageID]string `json:"statuses,omitempty"`
+	UserName       string                   `json:"userName,omitempty"`
+	Reminder       *api.ReminderResponse    `json:"reminder,omitempty"`
+}
+
+// tail is the conversation being tailed and how to print it
+type tail struct {
+	server         *url.URL
+	token          string
+	conversationID ids.ConversationID
+	json           bool
+	out            io.Writer
+}
+
+func main() {
+	if err := run(); err != nil {
+		log.Printf("error: %v", err)
+		os.Exit(1)
+	}
+}
+
+// run parses the flags, authenticates, prints the last messages and
+// tails the events until interrupted
+func run() error {
+	server := flag.String("server", "http://localhost:3000", "URL of the WASAText server")
+	token := flag.String("token", os.Getenv("WASATEXT_TOKEN"), "session token of the user or bot (default $WASATEXT_TOKEN)")
+	name := flag.String("name", "", "log in as this user instead of giving a token")
+	workspace := flag.String("workspace", "", "workspace of the -name user (default the default workspace)")
+	history := flag.Int("n", 10, "print this many of the last messages first")
+	jsonOutput := flag.Bool("json", false, "print the events as JSON, one per line")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <conversationId>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		return errors.New("one conversation ID is needed")
+	}
+	conversationID, err := ids.ParseConversationID(flag.Arg(0))
+	if err != nil {
+		return err
+	}
+	serverURL, err := url.Parse(strings.TrimSuffix(*server, "/"))
+	if err != nil {
+		return err
+	}
+	if *history < 0 {
+		return errors.New("-n must not be negative")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	t := &tail{server: serverURL, token: *token, conversationID: conversationID, json: *jsonOutput, out: os.Stdout}
+	if *name != "" {
+		if t.token, err = t.login(ctx, *name, *workspace); err != nil {
+			return err
+		}
+	}
+	if t.token == "" {
+		return errors.New("give a session token with -token (or WASATEXT_TOKEN), or a user name with -name")
+	}
+
+	// The last messages also check the conversation can be read
+	if err := t.printHistory(ctx, *history); err != nil {
+		return err
+	}
+	t.follow(ctx)
+	return nil
+}
+
+// login opens a session for a user, creating them if needed, and
+// returns its token
+func (t *tail) login(ctx context.Context, name, workspace string) (string, error) {
+	body, err := json.Marshal(api.LoginRequest{Name: name, Workspace: workspace})
+	if err != nil {
+		return "", err
+	}
+	var session api.LoginResponse
+	if err := t.call(ctx, http.MethodPost, "/session", body, &session); err != nil {
+		return "", fmt.Errorf("logging in: %w", err)
+	}
+	return session.Token, nil
+}
+
+// printHistory prints the last n messages of the conversation, the
+// oldest first
+func (t *tail) printHistory(ctx context.Context, n int) error {
+	var conversation api.ConversationResponse
+	path := "/conversations/" + string(t.conversationID) + "?limit=" + strconv.Itoa(max(n, 1))
+	if err := t.call(ctx, http.MethodGet, path, nil, &conversation); err != nil {
+		return fmt.Errorf("reading the conversation: %w", err)
+	}
+	log.Printf("Tailing %q (%s)", conversation.Name, t.conversationID)
+
+	messages := conversation.Messages // the newest first
+	if len(messages) > n {
+		messages = messages[:n]
+	}
+	for _, msg := range slices.Backward(messages) {
+		e := event{Type: api.EventMessage, ConversationID: t.conversationID, Message: &msg}
+		if t.json {
+			data, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			t.printLine(data)
+			continue
+		}
+		at := msg.Timestamp
+		if timestamp, err := time.Parse(time.RFC3339, msg.Timestamp); err == nil {
+			at = timestamp.Local().Format(time.DateTime)
+		}
+		t.printEvent(at, e)
+	}
+	return nil
+}
+
+// follow prints the events of the conversation until ctx is done,
+// reconnecting when the connection drops
+func (t *tail) follow(ctx context.Context) {
+	delay := minRetryDelay
+	for {
+		connected, err := t.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			delay = minRetryDelay
+		}
+		log.Printf("Disconnected (%v), reconnecting in %s", err, delay)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(2*delay, maxRetryDelay)
+	}
+}
+
+// listen opens a WebSocket and prints its events until it ends or ctx
+// is done; connected tells whether it opened at all
+func (t *tail) listen(ctx context.Context) (connected bool, err error) {
+	wsURL := *t.server
+	wsURL.Path += "/ws"
+	dialCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+	conn, err := websocket.Dial(dialCtx, wsURL.String(), http.Header{"Authorization": {"Bearer " + t.token}})
+	cancel()
+	if err != nil {
+		return false, err
+	}
+	log.Printf("Connected to %s", wsURL.Redacted())
+
+	// Closing the connection is what stops ReadMessage on interrupt
+	stopped := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer func() {
+		if stopped() {
+			_ = conn.Close()
+		}
+	}()
+
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(readTimeout))
+		opcode, data, err := conn.ReadMessage()
+		if err != nil {
+			return true, err
+		}
+		if opcode != websocket.OpText {
+			continue
+		}
+
+		var e event
+		if err := json.Unmarshal(data, &e); err != nil {
+			log.Printf("Unreadable event: %v", err)
+			continue
+		}
+		if e.ConversationID != t.conversationID {
+			continue
+		}
+		if t.json {
+			t.printLine(data)
+		} else {
+			t.printEvent(time.Now().Format(time.DateTime), e)
+		}
+	}
+}
+
+// printEvent prints an event as a line of text
+func (t *tail) printEvent(at string, e event) {
+	var detail string
+	switch e.Type {
+	case api.EventMessage, api.EventMessageEdited:
+		if e.Message == nil {
+			break
+		}
+		detail = string(e.Message.MessageID) + " " + e.Message.SenderName + ": " + e.Message.Content
+		if e.Message.HasPhoto {
+			detail += " [photo]"
+		}
+		if e.Message.ReplyTo != "" {
+			detail += " (reply to " + string(e.Message.ReplyTo) + ")"
+		}
+	case api.EventMessageDeleted:
+		detail = string(e.MessageID)
+	case api.EventReaction:
+		reactions := make([]string, 0, len(e.Reactions))
+		for _, r := range e.Reactions {
+			reactions = append(reactions, r.Emoticon+" "+r.UserName)
+		}
+		detail = string(e.MessageID) + " " + strings.Join(reactions, ", ")
+	case api.EventPin:
+		detail = string(e.MessageID) + " unpinned"
+		if e.Pinned {
+			detail = string(e.MessageID) + " pinned"
+		}
+	case api.EventStatus:
+		statuses := make([]string, 0, len(e.Statuses))
+		for messageID, status := range e.Statuses {
+			statuses = append(statuses, string(messageID)+"="+status)
+		}
+		slices.Sort(statuses)
+		detail = strings.Join(statuses, " ")
+	case api.EventTyping:
+		detail = e.UserName
+	case api.EventReminder:
+		if e.Reminder != nil {
+			detail = string(e.Reminder.MessageID) + " " + e.Reminder.SenderName + ": " + e.Reminder.Snippet
+		}
+	}
+	t.printLine([]byte(fmt.Sprintf("%s %-14s %s", at, e.Type, detail)))
+}
+
+// printLine writes a line to the output
+func (t *tail) printLine(line []byte) {
+	if _, err := t.out.Write(append(line, '\n')); err != nil {
+		log.Printf("Error writing the output: %v", err)
+	}
+}
+
+// call sends a request to the API and decodes the JSON answer into out
+func (t *tail) call(ctx context.Context, method, path string, body []byte, out any) error {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, t.server.String()+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if t.token != "" {
+		req.Header.Set("Authorization", "Bearer "+t.token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("Error closing response body: %v", err)
+		}
+	}()
+	if resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(message))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
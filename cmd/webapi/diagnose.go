@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"wasatext/service/api"
+	"wasatext/service/database"
+	"wasatext/service/ids"
+	"wasatext/service/redis"
+	"wasatext/service/storage"
+)
+
+// diagnosisCheck is a line of the report of --diagnose
+type diagnosisCheck struct {
+	name   string
+	ok     bool
+	detail string
+}
+
+/*
+diagnose checks what the server needs before it takes traffic, without
+starting it: the configuration (file and environment), the media
+directory (a blob is written, read back and deleted), the database
+file (see database.Diagnose) and the Redis server of the event
+backplane, when there is one. It prints a report to out and reports
+whether every check passed.
+*/
+func diagnose(out io.Writer) bool {
+	var checks []diagnosisCheck
+	add := func(name string, err error, detail string) {
+		if err != nil {
+			checks = append(checks, diagnosisCheck{name, false, err.Error()})
+			return
+		}
+		checks = append(checks, diagnosisCheck{name, true, detail})
+	}
+
+	// Step 1: The configuration
+	configPath := configurationPath()
+	fileCfg, err := readConfigurationFile(configPath)
+	add("configuration file", err, configPath)
+	var apiCfg api.Config
+	if err == nil {
+		apiCfg, err = loadAPIConfiguration(configPath)
+		add("API configuration", err, "valid")
+	}
+	port := listenPort(fileCfg)
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		add("port", fmt.Errorf("invalid port %q", port), "")
+	} else {
+		add("port", nil, port)
+	}
+	grpcPort, err := grpcPortSetting(fileCfg)
+	if grpcPort == "" && err == nil {
+		grpcPort = "off"
+	}
+	add("gRPC port", err, grpcPort)
+	format, err := ids.ParseFormat(idFormatSetting(fileCfg))
+	add("ID format", err, string(format))
+	_, err = readJobIntervals()
+	add("job intervals", err, "valid")
+	_, err = shutdownTimeoutSetting(fileCfg)
+	add("shutdown timeout", err, "valid")
+
+	// Step 2: The media directory
+	dbPath, mediaDir := storageLocation(fileCfg)
+	add("media storage", probeMedia(mediaDir), mediaDir+" is writable")
+
+	// Step 3: The database
+	checks = append(checks, diagnoseDatabase(dbPath)...)
+
+	// Step 4: The Redis server of the event backplane, if any
+	if redisURL := apiCfg.Backplane.RedisURL; redisURL != "" {
+		err := probeRedis(redisURL)
+		add("event backplane", err, "Redis answers")
+	}
+
+	// Step 5: The report
+	healthy := true
+	for _, c := range checks {
+		status := "ok  "
+		if !c.ok {
+			status = "FAIL"
+			healthy = false
+		}
+		fmt.Fprintf(out, "[%s] %-20s %s\n", status, c.name, c.detail)
+	}
+	if healthy {
+		fmt.Fprintln(out, "All checks passed.")
+	} else {
+		fmt.Fprintln(out, "Some checks failed: fix them before starting the server.")
+	}
+	return healthy
+}
+
+// probeMedia writes a blob to the media directory, reads it back and
+// deletes it
+func probeMedia(dir string) error {
+	blobs, err := storage.NewFileStore(dir)
+	if err != nil {
+		return err
+	}
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return err
+	}
+	id := hex.EncodeToString(raw)
+	probe := []byte("wasatext diagnose probe")
+	if err := blobs.Put(id, probe); err != nil {
+		return err
+	}
+	defer func() { _ = blobs.Delete(id) }()
+
+	data, err := blobs.Get(id)
+	if err != nil {
+		return err
+	}
+	if string(data) != string(probe) {
+		return fmt.Errorf("the probe blob read back from %s differs", dir)
+	}
+	return blobs.Delete(id)
+}
+
+// probeRedis connects to a Redis server, authenticated, and pings it
+func probeRedis(redisURL string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	conn, err := redis.Dial(ctx, redisURL)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Do(ctx, "PING")
+	return err
+}
+
+// diagnoseDatabase turns database.Diagnose into checks
+func diagnoseDatabase(dbPath string) []diagnosisCheck {
+	d, err := database.Diagnose(context.Background(), dbPath)
+	if err != nil {
+		return []diagnosisCheck{{"database", false, dbPath + ": " + err.Error()}}
+	}
+	if !d.Exists {
+		// The server creates the file: its directory must take it
+		f, err := os.CreateTemp(filepath.Dir(dbPath), ".wasatext-diagnose-*")
+		if err != nil {
+			return []diagnosisCheck{{"database", false, dbPath + " does not exist and cannot be created: " + err.Error()}}
+		}
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return []diagnosisCheck{{"database", true, dbPath + " does not exist yet, it will be created"}}
+	}
+
+	checks := []diagnosisCheck{{"database", true, dbPath}}
+	switch {
+	case d.SchemaVersion > d.LatestVersion:
+		checks = append(checks, diagnosisCheck{"schema version", false,
+			fmt.Sprintf("%d, newer than this build (%d): the database was migrated by a later version", d.SchemaVersion, d.LatestVersion)})
+	case d.SchemaVersion < d.LatestVersion:
+		checks = append(checks, diagnosisCheck{"schema version", true,
+			fmt.Sprintf("%d, migrations %d to %d will be applied on start", d.SchemaVersion, d.SchemaVersion+1, d.LatestVersion)})
+	default:
+		checks = append(checks, diagnosisCheck{"schema version", true, strconv.Itoa(d.SchemaVersion) + ", up to date"})
+	}
+	if d.FTS5 {
+		checks = append(checks, diagnosisCheck{"FTS5", true, "available"})
+	} else {
+		checks = append(checks, diagnosisCheck{"FTS5", false, "SQLite was built without FTS5: build with -tags sqlite_fts5"})
+	}
+	if len(d.IntegrityErrors) == 0 {
+		checks = append(checks, diagnosisCheck{"integrity", true, "quick check passed"})
+	} else {
+		checks = append(checks, diagnosisCheck{"integrity", false,
+			fmt.Sprintf("%d problems, the first: %s", len(d.IntegrityErrors), d.IntegrityErrors[0])})
+	}
+	if d.WriteError == "" {
+		checks = append(checks, diagnosisCheck{"read/write probe", true, "passed"})
+	} else {
+		checks = append(checks, diagnosisCheck{"read/write probe", false, d.WriteError})
+	}
+	return checks
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"wasatext/service/database"
+)
+
+// eventReminderInterval is how often the due event reminders are posted
+const eventReminderInterval = time.Minute
+
+// messageReminderInterval is how often the due message reminders are delivered
+const messageReminderInterval = time.Minute
+
+// jobIntervals are the schedules of the background jobs
+type jobIntervals struct {
+	retention   time.Duration // how long deleted accounts are kept
+	purge       time.Duration
+	maintenance time.Duration // 0: off
+	checkpoint  time.Duration // 0: off
+}
+
+// readJobIntervals reads the schedules of the jobs from the environment
+func readJobIntervals() (jobIntervals, error) {
+	var ji jobIntervals
+	var err error
+	if ji.retention, err = durationFromEnv("WASATEXT_PURGE_RETENTION", 30*24*time.Hour); err != nil {
+		return ji, err
+	}
+	if ji.purge, err = durationFromEnv("WASATEXT_PURGE_INTERVAL", time.Hour); err != nil {
+		return ji, err
+	}
+	// Database maintenance is off unless an interval is set (e.g. "168h")
+	if ji.maintenance, err = durationFromEnv("WASATEXT_MAINTENANCE_INTERVAL", 0); err != nil {
+		return ji, err
+	}
+	// The write-ahead log is truncated every few minutes; 0 turns it off
+	ji.checkpoint, err = durationFromEnv("WASATEXT_CHECKPOINT_INTERVAL", 5*time.Minute)
+	return ji, err
+}
+
+// jobGroup starts the background jobs and waits for them to stop
+type jobGroup struct {
+	running sync.WaitGroup
+}
+
+// schedule starts scheduleJob in its own goroutine
+func (g *jobGroup) schedule(ctx context.Context, name string, interval time.Duration, runAtStart bool, job func(ctx context.Context) error) {
+	g.running.Add(1)
+	go func() {
+		defer g.running.Done()
+		scheduleJob(ctx, name, interval, runAtStart, job)
+	}()
+}
+
+// wait returns once every job stopped, after their ctx was cancelled
+func (g *jobGroup) wait() {
+	g.running.Wait()
+}
+
+/*
+scheduleJob runs job every interval until ctx is cancelled, passing it
+ctx. With runAtStart the first run happens right away instead of after
+the first interval. Errors are logged; the job keeps its schedule.
+*/
+func scheduleJob(ctx context.Context, name string, interval time.Duration, runAtStart bool, job func(ctx context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	if runAtStart {
+		if err := job(ctx); err != nil {
+			log.Printf("%s job failed: %v", name, err)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := job(ctx); err != nil {
+			log.Printf("%s job failed: %v", name, err)
+		}
+	}
+}
+
+// purgeJob hard-deletes accounts that were deleted more than `retention` ago
+func purgeJob(db database.AppDatabase, retention time.Duration) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		records, err := db.PurgeDeletedUsers(ctx, time.Now().Add(-retention))
+		for _, rec := range records {
+			log.Printf("Purged account %s (%d messages, %d reactions, %d media)",
+				rec.UserID, rec.MessagesDeleted, rec.CommentsDeleted, rec.MediaDeleted)
+		}
+		return err
+	}
+}
+
+// conversationPurgeJob hard-deletes the groups and conversations deleted
+// by an admin whose retention has passed
+func conversationPurgeJob(db database.AppDatabase) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		purged, err := db.PurgeDeletedConversations(ctx, time.Now())
+		for _, pc := range purged {
+			log.Printf("Purged deleted conversation %s (%d messages)", pc.ConversationID, pc.MessagesDeleted)
+		}
+		return err
+	}
+}
+
+// checkpointJob truncates the write-ahead log of the database
+func checkpointJob(db database.AppDatabase) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		report, err := db.Checkpoint(ctx)
+		if err != nil {
+			return err
+		}
+		if report.Busy {
+			log.Printf("WAL checkpoint busy: %d of %d pages copied, the log (%d bytes) was not truncated",
+				report.CheckpointedFrames, report.LogFrames, report.WALSizeAfter)
+		}
+		return nil
+	}
+}
+
+// maintenanceJob checks the integrity of the database file, repairs the
+// search index and vacuums the file
+func maintenanceJob(db database.AppDatabase) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		report, err := db.RunMaintenance(ctx)
+		if err != nil {
+			return err
+		}
+		for _, problem := range report.IntegrityErrors {
+			log.Printf("Database integrity problem: %s", problem)
+		}
+		if report.SearchIndexRepaired > 0 {
+			log.Printf("Search index out of sync with the messages: %d entries repaired", report.SearchIndexRepaired)
+		}
+		log.Printf("Database maintenance done (%s vacuum, %d -> %d bytes, %d integrity errors)",
+			report.VacuumMode, report.SizeBefore, report.SizeAfter, len(report.IntegrityErrors))
+		return nil
+	}
+}
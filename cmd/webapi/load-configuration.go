@@ -0,0 +1,433 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+
+	"wasatext/service/api"
+	"wasatext/service/ids"
+	"wasatext/service/notifications"
+)
+
+// fileConfiguration is the layout of the configuration file (JSON, see demo/config.yaml)
+type fileConfiguration struct {
+	API struct {
+		Port            int      `json:"port"`
+		GRPCPort        int      `json:"grpcPort"` // the gRPC API, off when 0
+		ShutdownTimeout duration `json:"shutdownTimeout"`
+	} `json:"api"`
+	Database struct {
+		File     string `json:"file"`
+		MediaDir string `json:"mediaDir"`
+		IDFormat string `json:"idFormat"`
+	} `json:"database"`
+	Debug    bool   `json:"debug"`
+	LogLevel string `json:"logLevel"`
+	CORS     struct {
+		AllowedOrigins []string `json:"allowedOrigins"`
+	} `json:"cors"`
+	Features map[string]bool `json:"features"`
+	Spam     struct {
+		NewAccountAge              duration `json:"newAccountAge"`
+		MaxNewConversationsPerHour int      `json:"maxNewConversationsPerHour"`
+		FloodWindow                duration `json:"floodWindow"`
+		FloodThreshold             int      `json:"floodThreshold"`
+		ThrottleDuration           duration `json:"throttleDuration"`
+	} `json:"spam"`
+	Usage struct {
+		DailyMessages    *int     `json:"dailyMessages"`
+		DailyUploads     *int     `json:"dailyUploads"`
+		ThrottleDelay    duration `json:"throttleDelay"`
+		ThrottleStep     int      `json:"throttleStep"`
+		MaxThrottleDelay duration `json:"maxThrottleDelay"`
+	} `json:"usage"`
+	RateLimit struct {
+		Enabled *bool                    `json:"enabled"`
+		Classes map[string]rateLimitJSON `json:"classes"`
+	} `json:"rateLimit"`
+	APIUsage struct {
+		SampleRate *int      `json:"sampleRate"`
+		Retention  *duration `json:"retention"`
+	} `json:"apiUsage"`
+	FilterWords             []string `json:"filterWords"`
+	HoneypotUsers           []string `json:"honeypotUsers"`
+	MaxConversationMessages *int     `json:"maxConversationMessages"`
+	MediaURLTTL             duration `json:"mediaUrlTtl"`
+	InviteQuota             *int     `json:"inviteQuota"`
+	MaxPhotoSize            int64    `json:"maxPhotoSize"`
+	MaxPhotoDimension       *int     `json:"maxPhotoDimension"`
+	// 0 is meaningful (kept until restored)
+	DeletedConversationRetention *duration `json:"deletedConversationRetention"`
+	WebUI                        struct {
+		APIBaseURL string                    `json:"apiBaseUrl"`
+		AppName    string                    `json:"appName"`
+		Tagline    string                    `json:"tagline"`
+		Workspaces map[string]brandingConfig `json:"workspaces"`
+	} `json:"webui"`
+	OCR struct {
+		Command []string `json:"command"`
+		Timeout duration `json:"timeout"`
+	} `json:"ocr"`
+	Push struct {
+		VAPIDPrivateKey string `json:"vapidPrivateKey"`
+		Subject         string `json:"subject"`
+	} `json:"push"`
+	Sandbox struct {
+		Enabled       bool     `json:"enabled"`
+		Latency       duration `json:"latency"`
+		LatencyJitter duration `json:"latencyJitter"`
+		ErrorRate     float64  `json:"errorRate"`
+	} `json:"sandbox"`
+	Chaos struct {
+		Enabled       bool    `json:"enabled"`
+		ErrorRate     float64 `json:"errorRate"`
+		DBTimeoutRate float64 `json:"dbTimeoutRate"`
+		DropEventRate float64 `json:"dropEventRate"`
+	} `json:"chaos"`
+	RejectionLog struct {
+		Path     string `json:"path"`
+		MaxSize  int64  `json:"maxSize"`
+		MaxFiles *int   `json:"maxFiles"`
+	} `json:"rejectionLog"`
+	Matrix struct {
+		Homeserver  string `json:"homeserver"`
+		AccessToken string `json:"accessToken"`
+		BotID       string `json:"botId"`
+		Rooms       []struct {
+			ConversationID string `json:"conversationId"`
+			RoomID         string `json:"roomId"`
+			Direction      string `json:"direction"`
+		} `json:"rooms"`
+	} `json:"matrix"`
+	Backplane struct {
+		RedisURL string `json:"redisUrl"`
+		Channel  string `json:"channel"`
+	} `json:"backplane"`
+}
+
+// rateLimitJSON is the rate limit of a route class in the file
+type rateLimitJSON struct {
+	PerMinute int `json:"perMinute"`
+	Burst     int `json:"burst"`
+}
+
+// brandingConfig is the branding of one workspace in the file
+type brandingConfig struct {
+	AppName string `json:"appName"`
+	Tagline string `json:"tagline"`
+}
+
+// duration is a time.Duration written as a string ("15m") in the file
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(parsed)
+	return nil
+}
+
+// readConfigurationFile reads the configuration file.
+// A missing file is not an error: every setting has a default.
+func readConfigurationFile(path string) (fileConfiguration, error) {
+	var fc fileConfiguration
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fc, nil
+	}
+	if err != nil {
+		return fc, err
+	}
+
+	if err := json.Unmarshal(data, &fc); err != nil {
+		return fc, errors.New("parsing " + path + ": " + err.Error())
+	}
+	return fc, nil
+}
+
+/*
+loadAPIConfiguration builds the handler configuration from the
+configuration file and the environment. Environment variables win over
+the file. It is called at startup and again on every reload.
+*/
+func loadAPIConfiguration(path string) (api.Config, error) {
+	fc, err := readConfigurationFile(path)
+	if err != nil {
+		return api.Config{}, err
+	}
+
+	cfg := api.DefaultConfig()
+	cfg.AdminToken = os.Getenv("WASATEXT_ADMIN_TOKEN")
+
+	if fc.Debug {
+		cfg.LogLevel = api.LogLevelDebug
+	}
+	if fc.LogLevel != "" {
+		cfg.LogLevel = fc.LogLevel
+	}
+	if level := os.Getenv("WASATEXT_LOG_LEVEL"); level != "" {
+		cfg.LogLevel = level
+	}
+	switch cfg.LogLevel {
+	case api.LogLevelDebug, api.LogLevelInfo, api.LogLevelError:
+	default:
+		return api.Config{}, errors.New("invalid log level: " + cfg.LogLevel)
+	}
+
+	if len(fc.CORS.AllowedOrigins) > 0 {
+		cfg.CORSOrigins = fc.CORS.AllowedOrigins
+	}
+	if fc.Features != nil {
+		cfg.Features = fc.Features
+	}
+
+	// Spam thresholds: only override what the file sets
+	if fc.Spam.NewAccountAge > 0 {
+		cfg.Spam.NewAccountAge = time.Duration(fc.Spam.NewAccountAge)
+	}
+	if fc.Spam.MaxNewConversationsPerHour > 0 {
+		cfg.Spam.MaxNewConversationsPerHour = fc.Spam.MaxNewConversationsPerHour
+	}
+	if fc.Spam.FloodWindow > 0 {
+		cfg.Spam.FloodWindow = time.Duration(fc.Spam.FloodWindow)
+	}
+	if fc.Spam.FloodThreshold > 0 {
+		cfg.Spam.FloodThreshold = fc.Spam.FloodThreshold
+	}
+	if fc.Spam.ThrottleDuration > 0 {
+		cfg.Spam.ThrottleDuration = time.Duration(fc.Spam.ThrottleDuration)
+	}
+
+	// Usage limits: 0 turns a limit off, so only a missing value keeps the default
+	if fc.Usage.DailyMessages != nil {
+		if *fc.Usage.DailyMessages < 0 {
+			return api.Config{}, errors.New("invalid usage.dailyMessages: must not be negative")
+		}
+		cfg.Usage.DailyMessages = *fc.Usage.DailyMessages
+	}
+	if fc.Usage.DailyUploads != nil {
+		if *fc.Usage.DailyUploads < 0 {
+			return api.Config{}, errors.New("invalid usage.dailyUploads: must not be negative")
+		}
+		cfg.Usage.DailyUploads = *fc.Usage.DailyUploads
+	}
+	if fc.Usage.ThrottleDelay > 0 {
+		cfg.Usage.ThrottleDelay = time.Duration(fc.Usage.ThrottleDelay)
+	}
+	if fc.Usage.ThrottleStep > 0 {
+		cfg.Usage.ThrottleStep = fc.Usage.ThrottleStep
+	}
+	if fc.Usage.MaxThrottleDelay > 0 {
+		cfg.Usage.MaxThrottleDelay = time.Duration(fc.Usage.MaxThrottleDelay)
+	}
+
+	// Rate limits: the file overrides the classes it names
+	if fc.RateLimit.Enabled != nil {
+		cfg.RateLimit.Enabled = *fc.RateLimit.Enabled
+	}
+	for class, limit := range fc.RateLimit.Classes {
+		if _, ok := cfg.RateLimit.Classes[class]; !ok {
+			return api.Config{}, errors.New("invalid rateLimit class: " + class + " (login, admin, read or write)")
+		}
+		if limit.PerMinute < 0 || limit.Burst < 0 {
+			return api.Config{}, errors.New("invalid rateLimit." + class + ": must not be negative")
+		}
+		if limit.PerMinute > 0 && limit.Burst == 0 {
+			limit.Burst = 1
+		}
+		cfg.RateLimit.Classes[class] = api.RateLimit{PerMinute: limit.PerMinute, Burst: limit.Burst}
+	}
+
+	// API usage analytics: 0 turns the sampling off, or keeps the counts for good
+	if fc.APIUsage.SampleRate != nil {
+		if *fc.APIUsage.SampleRate < 0 {
+			return api.Config{}, errors.New("invalid apiUsage.sampleRate: must not be negative")
+		}
+		cfg.APIUsage.SampleRate = *fc.APIUsage.SampleRate
+	}
+	if fc.APIUsage.Retention != nil {
+		if *fc.APIUsage.Retention < 0 {
+			return api.Config{}, errors.New("invalid apiUsage.retention: must not be negative")
+		}
+		cfg.APIUsage.Retention = time.Duration(*fc.APIUsage.Retention)
+	}
+
+	// 0 is meaningful (no cap), so only a missing value keeps the default
+	if fc.MaxConversationMessages != nil {
+		if *fc.MaxConversationMessages < 0 {
+			return api.Config{}, errors.New("invalid maxConversationMessages: must not be negative")
+		}
+		cfg.MaxConversationMessages = *fc.MaxConversationMessages
+	}
+
+	if fc.InviteQuota != nil {
+		if *fc.InviteQuota < 0 {
+			return api.Config{}, errors.New("invalid inviteQuota: must not be negative")
+		}
+		cfg.InviteQuota = *fc.InviteQuota
+	}
+
+	if fc.MaxPhotoSize < 0 {
+		return api.Config{}, errors.New("invalid maxPhotoSize: must not be negative")
+	}
+	if fc.MaxPhotoSize > 0 {
+		cfg.MaxPhotoSize = fc.MaxPhotoSize
+	}
+	if fc.MaxPhotoDimension != nil {
+		if *fc.MaxPhotoDimension < 0 {
+			return api.Config{}, errors.New("invalid maxPhotoDimension: must not be negative")
+		}
+		cfg.MaxPhotoDimension = *fc.MaxPhotoDimension
+	}
+	if fc.DeletedConversationRetention != nil {
+		if *fc.DeletedConversationRetention < 0 {
+			return api.Config{}, errors.New("invalid deletedConversationRetention: must not be negative")
+		}
+		cfg.DeletedConversationRetention = time.Duration(*fc.DeletedConversationRetention)
+	}
+
+	// What the embedded frontend is told (GET /config.js)
+	cfg.WebUI = api.WebUIConfig{
+		APIBaseURL: fc.WebUI.APIBaseURL,
+		Branding:   api.Branding{AppName: fc.WebUI.AppName, Tagline: fc.WebUI.Tagline},
+	}
+	if url := os.Getenv("WASATEXT_API_BASE_URL"); url != "" {
+		cfg.WebUI.APIBaseURL = url
+	}
+	if len(fc.WebUI.Workspaces) > 0 {
+		cfg.WebUI.WorkspaceBranding = make(map[string]api.Branding, len(fc.WebUI.Workspaces))
+		for id, b := range fc.WebUI.Workspaces {
+			cfg.WebUI.WorkspaceBranding[id] = api.Branding{AppName: b.AppName, Tagline: b.Tagline}
+		}
+	}
+
+	// OCR of the message photos, off unless a command is set
+	cfg.OCR = api.OCRConfig{Command: fc.OCR.Command, Timeout: time.Duration(fc.OCR.Timeout)}
+	if cfg.OCR.Timeout < 0 {
+		return api.Config{}, errors.New("invalid ocr.timeout: must not be negative")
+	}
+
+	// Push notifications, off unless a VAPID key is set; the key is best
+	// kept out of the file, in the environment
+	cfg.Push = api.PushConfig{VAPIDPrivateKey: fc.Push.VAPIDPrivateKey, Subject: fc.Push.Subject}
+	if key := os.Getenv("WASATEXT_VAPID_PRIVATE_KEY"); key != "" {
+		cfg.Push.VAPIDPrivateKey = key
+	}
+	if cfg.Push.VAPIDPrivateKey != "" {
+		if _, err := notifications.NewVAPID(cfg.Push.VAPIDPrivateKey, cfg.Push.Subject); err != nil {
+			return api.Config{}, errors.New("invalid push configuration: " + err.Error())
+		}
+	}
+
+	// The secret only comes from the environment, like the admin token
+	cfg.MediaURLSecret = os.Getenv("WASATEXT_MEDIA_URL_SECRET")
+	if fc.MediaURLTTL > 0 {
+		cfg.MediaURLTTL = time.Duration(fc.MediaURLTTL)
+	}
+
+	// The developer sandbox lets anyone wipe the database: only for development
+	cfg.Sandbox = api.SandboxConfig{
+		Enabled:       fc.Sandbox.Enabled || os.Getenv("WASATEXT_SANDBOX") == "1",
+		Latency:       time.Duration(fc.Sandbox.Latency),
+		LatencyJitter: time.Duration(fc.Sandbox.LatencyJitter),
+		ErrorRate:     fc.Sandbox.ErrorRate,
+	}
+	if cfg.Sandbox.Latency < 0 || cfg.Sandbox.LatencyJitter < 0 {
+		return api.Config{}, errors.New("invalid sandbox latency: must not be negative")
+	}
+	if cfg.Sandbox.ErrorRate < 0 || cfg.Sandbox.ErrorRate > 1 {
+		return api.Config{}, errors.New("invalid sandbox.errorRate: must be between 0 and 1")
+	}
+
+	// Fault injection fails real requests: only from the file, on purpose
+	cfg.Chaos = api.ChaosConfig{
+		Enabled:       fc.Chaos.Enabled,
+		ErrorRate:     fc.Chaos.ErrorRate,
+		DBTimeoutRate: fc.Chaos.DBTimeoutRate,
+		DropEventRate: fc.Chaos.DropEventRate,
+	}
+	for name, rate := range map[string]float64{
+		"errorRate":     cfg.Chaos.ErrorRate,
+		"dbTimeoutRate": cfg.Chaos.DBTimeoutRate,
+		"dropEventRate": cfg.Chaos.DropEventRate,
+	} {
+		if rate < 0 || rate > 1 {
+			return api.Config{}, errors.New("invalid chaos." + name + ": must be between 0 and 1")
+		}
+	}
+
+	// The rejection log, off unless a path is set
+	cfg.RejectionLog.Path = fc.RejectionLog.Path
+	if path := os.Getenv("WASATEXT_REJECTION_LOG"); path != "" {
+		cfg.RejectionLog.Path = path
+	}
+	if fc.RejectionLog.MaxSize < 0 {
+		return api.Config{}, errors.New("invalid rejectionLog.maxSize: must not be negative")
+	}
+	if fc.RejectionLog.MaxSize > 0 {
+		cfg.RejectionLog.MaxSize = fc.RejectionLog.MaxSize
+	}
+	// 0 keeps no rotated file, so only a missing value keeps the default
+	if fc.RejectionLog.MaxFiles != nil {
+		if *fc.RejectionLog.MaxFiles < 0 {
+			return api.Config{}, errors.New("invalid rejectionLog.maxFiles: must not be negative")
+		}
+		cfg.RejectionLog.MaxFiles = *fc.RejectionLog.MaxFiles
+	}
+
+	// The Matrix bridge, off unless a homeserver is set; the access token
+	// is best kept out of the file, in the environment
+	cfg.Matrix = api.MatrixConfig{
+		Homeserver:  fc.Matrix.Homeserver,
+		AccessToken: fc.Matrix.AccessToken,
+		BotID:       ids.UserID(fc.Matrix.BotID),
+	}
+	if token := os.Getenv("WASATEXT_MATRIX_ACCESS_TOKEN"); token != "" {
+		cfg.Matrix.AccessToken = token
+	}
+	for _, room := range fc.Matrix.Rooms {
+		cfg.Matrix.Rooms = append(cfg.Matrix.Rooms, api.MatrixRoom{
+			ConversationID: ids.ConversationID(room.ConversationID),
+			RoomID:         room.RoomID,
+			Direction:      room.Direction,
+		})
+	}
+	if cfg.Matrix.Homeserver != "" {
+		if _, err := ids.ParseUserID(fc.Matrix.BotID); err != nil {
+			return api.Config{}, errors.New("invalid matrix.botId: " + fc.Matrix.BotID)
+		}
+	}
+	if err := cfg.Matrix.Validate(); err != nil {
+		return api.Config{}, errors.New("invalid matrix configuration: " + err.Error())
+	}
+
+	// The event backplane, off unless a Redis server is set; its URL
+	// holds the password, best kept in the environment
+	cfg.Backplane = api.BackplaneConfig{RedisURL: fc.Backplane.RedisURL, Channel: fc.Backplane.Channel}
+	if redisURL := os.Getenv("WASATEXT_REDIS_URL"); redisURL != "" {
+		cfg.Backplane.RedisURL = redisURL
+	}
+	if err := cfg.Backplane.Validate(); err != nil {
+		return api.Config{}, errors.New("invalid backplane configuration: " + err.Error())
+	}
+
+	cfg.FilterWords = fc.FilterWords
+	if words := listFromEnv("WASATEXT_FILTER_WORDS"); words != nil {
+		cfg.FilterWords = words
+	}
+	cfg.HoneypotUsers = fc.HoneypotUsers
+	if users := listFromEnv("WASATEXT_HONEYPOT_USERS"); users != nil {
+		cfg.HoneypotUsers = users
+	}
+
+	return cfg, nil
+}
@@ -1,25 +1,56 @@
 /*
 Package main is the entry point for the WASAText web API server.
 
-It sets up the database, API router, and starts the HTTP server.
+It sets up the database, API router, and starts the HTTP server. With
+--diagnose it checks the configuration, the media storage and the
+database instead, prints a report and exits, with status 1 when a check
+failed.
+
 This package follows the project structure guidelines for the WASA course.
 */
 package main
 
 import (
+	"context"
 	"errors"
+	"flag"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"wasatext/service/api"
 	"wasatext/service/database"
+	"wasatext/service/ids"
+	"wasatext/service/storage"
+)
+
+const (
+	// defaultShutdownTimeout is how long the requests under way may take
+	// to finish on shutdown
+	defaultShutdownTimeout = 15 * time.Second
 
-	"golang.org/x/time/rate"
+	// readHeaderTimeout bounds how long a client may take to send the
+	// headers of a request
+	readHeaderTimeout = 10 * time.Second
 )
 
 // Main entry point
 func main() {
+	diagnoseOnly := flag.Bool("diagnose", false, "check the configuration, media storage and database, then exit")
+	flag.Parse()
+	if *diagnoseOnly {
+		if !diagnose(os.Stdout) {
+			os.Exit(1)
+		}
+		return
+	}
+
 	if err := run(); err != nil {
 		log.Printf("error: %v", err)
 	}
@@ -29,21 +60,31 @@ func main() {
 func run() error {
 	log.Println("Starting WASAText server...")
 
-	// Just a dummy usage of rate limiter dependency to force vendoring
-	_ = rate.NewLimiter(1, 5)
-
-	// Step 1: Get the port to listen on (default: 3000)
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "3000"
+	// Step 1: Read the configuration file (optional, default: config.yaml)
+	configPath := configurationPath()
+	fileCfg, err := readConfigurationFile(configPath)
+	if err != nil {
+		return err
+	}
+	port := listenPort(fileCfg)
+	grpcPort, err := grpcPortSetting(fileCfg)
+	if err != nil {
+		return err
 	}
 
 	// Step 2: Initialize the database
-	dbPath := os.Getenv("WASATEXT_DB_FILENAME")
-	if dbPath == "" {
-		dbPath = "wasatext.db"
+	dbPath, mediaDir := storageLocation(fileCfg)
+	// New IDs are UUIDs unless configured otherwise; both are accepted
+	format, err := ids.ParseFormat(idFormatSetting(fileCfg))
+	if err != nil {
+		return err
+	}
+	ids.SetFormat(format)
+	blobs, err := storage.NewFileStore(mediaDir)
+	if err != nil {
+		return errors.New("error initializing the media store: " + err.Error())
 	}
-	db, err := database.New(dbPath)
+	db, err := database.New(dbPath, blobs)
 	if err != nil {
 		return errors.New("error initializing database: " + err.Error())
 	}
@@ -53,10 +94,57 @@ func run() error {
 		}
 	}()
 
-	// Step 3: Create the API handler
-	apiHandler := api.New(db)
+	// Step 3: Start the background jobs
+	intervals, err := readJobIntervals()
+	if err != nil {
+		return err
+	}
+	// The jobs stop before the database is closed
+	ctx, cancel := context.WithCancel(context.Background())
+	var jobs jobGroup
+	defer jobs.wait()
+	defer cancel()
+	jobs.schedule(ctx, "Purge", intervals.purge, true, purgeJob(db, intervals.retention))
+	jobs.schedule(ctx, "Conversation purge", intervals.purge, true, conversationPurgeJob(db))
+	if intervals.maintenance > 0 {
+		jobs.schedule(ctx, "Maintenance", intervals.maintenance, false, maintenanceJob(db))
+	}
+	if intervals.checkpoint > 0 {
+		jobs.schedule(ctx, "Checkpoint", intervals.checkpoint, false, checkpointJob(db))
+	}
 
-	// Step 4: Create the router
+	// Step 4: Create the API handler
+	loadConfig := func() (api.Config, error) {
+		return loadAPIConfiguration(configPath)
+	}
+	apiCfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if apiCfg.Sandbox.Enabled {
+		log.Printf("Warning: developer sandbox enabled, anyone can reset the database with POST /sandbox/reset")
+	}
+	if apiCfg.Chaos.Enabled {
+		log.Printf("Warning: fault injection enabled, requests and WebSocket events will fail on purpose")
+	}
+	apiHandler := api.New(db, apiCfg)
+	apiHandler.SetConfigLoader(loadConfig)
+	jobs.schedule(ctx, "Event reminders", eventReminderInterval, true, apiHandler.SendEventReminders)
+	jobs.schedule(ctx, "Message reminders", messageReminderInterval, true, apiHandler.SendMessageReminders)
+
+	// Reload the mutable configuration on SIGHUP
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+	go func() {
+		for range hangup {
+			if err := apiHandler.ReloadConfig(); err != nil {
+				log.Printf("Configuration reload failed, keeping the old one: %v", err)
+			}
+		}
+	}()
+
+	// Step 5: Create the router
 	router := api.NewRouter(apiHandler)
 
 	// Register WebUI
@@ -66,17 +154,179 @@ func run() error {
 		log.Printf("Warning: failed to register WebUI: %v", err)
 	}
 
-	// Step 5: Start the server
+	// The specification is written by hand: tell where it drifted
+	for _, problem := range api.CheckOpenAPI(router) {
+		log.Printf("Warning: %s", problem)
+	}
+
+	// Step 6: Start the server
+	shutdownTimeout, err := shutdownTimeoutSetting(fileCfg)
+	if err != nil {
+		return err
+	}
 	log.Printf("WASAText server starting on port %s...", port)
 	log.Printf("API available at http://localhost:%s/", port)
 
 	// Wrap the router with CORS middleware
-	handler := api.CorsMiddleware(router)
+	server := &http.Server{
+		Addr:              ":" + port,
+		Handler:           apiHandler.CorsMiddleware(router),
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+	// The event streams of GET /events never end by themselves
+	server.RegisterOnShutdown(apiHandler.CloseEventStreams)
+	failed := make(chan error, 2)
+	go func() {
+		if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			failed <- err
+		}
+	}()
 
-	err = http.ListenAndServe(":"+port, handler)
-	if err != nil {
+	// The gRPC API, if configured, on its own port: unencrypted HTTP/2
+	// only, its unary methods running through the router
+	var grpcServer *http.Server
+	if grpcPort != "" {
+		var protocols http.Protocols
+		protocols.SetUnencryptedHTTP2(true)
+		grpcServer = &http.Server{
+			Addr:              ":" + grpcPort,
+			Handler:           api.NewGRPCServer(apiHandler, router),
+			Protocols:         &protocols,
+			ReadHeaderTimeout: readHeaderTimeout,
+		}
+		// The message streams never end by themselves
+		grpcServer.RegisterOnShutdown(apiHandler.CloseEventStreams)
+		log.Printf("gRPC API available at localhost:%s (h2c, see doc/wasatext.proto)", grpcPort)
+		go func() {
+			if err := grpcServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				failed <- err
+			}
+		}()
+	}
+
+	// Step 7: Serve until SIGINT or SIGTERM, then shut down gracefully:
+	// the requests under way finish, the event streams are closed and the
+	// workers stop; the jobs and the database (deferred above) go last
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(stop)
+	select {
+	case err := <-failed:
 		return errors.New("server failed to start: " + err.Error())
+	case sig := <-stop:
+		log.Printf("Received %v, shutting down (up to %v)...", sig, shutdownTimeout)
 	}
 
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelShutdown()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Requests still under way at the shutdown timeout: %v", err)
+	}
+	if grpcServer != nil {
+		if err := grpcServer.Shutdown(shutdownCtx); err != nil {
+			log.Printf("gRPC calls still under way at the shutdown timeout: %v", err)
+		}
+	}
+	if err := apiHandler.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Background workers still running at the shutdown timeout: %v", err)
+	}
+	log.Println("Server stopped")
 	return nil
 }
+
+// configurationPath returns the path of the configuration file
+func configurationPath() string {
+	if path := os.Getenv("WASATEXT_CONFIG"); path != "" {
+		return path
+	}
+	return "config.yaml"
+}
+
+// listenPort returns the port to listen on (default: 3000)
+func listenPort(fc fileConfiguration) string {
+	if port := os.Getenv("PORT"); port != "" {
+		return port
+	}
+	if fc.API.Port != 0 {
+		return strconv.Itoa(fc.API.Port)
+	}
+	return "3000"
+}
+
+// grpcPortSetting returns the port of the gRPC API, "" when it is off
+// (the default)
+func grpcPortSetting(fc fileConfiguration) (string, error) {
+	port := os.Getenv("WASATEXT_GRPC_PORT")
+	if port == "" && fc.API.GRPCPort != 0 {
+		port = strconv.Itoa(fc.API.GRPCPort)
+	}
+	if port == "" || port == "0" {
+		return "", nil
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return "", errors.New("invalid gRPC port: " + port)
+	}
+	return port, nil
+}
+
+// storageLocation returns the database file and the media directory.
+// Photos are kept next to the database unless configured otherwise.
+func storageLocation(fc fileConfiguration) (dbPath, mediaDir string) {
+	dbPath = os.Getenv("WASATEXT_DB_FILENAME")
+	if dbPath == "" {
+		dbPath = fc.Database.File
+	}
+	if dbPath == "" {
+		dbPath = "wasatext.db"
+	}
+	mediaDir = os.Getenv("WASATEXT_MEDIA_DIR")
+	if mediaDir == "" {
+		mediaDir = fc.Database.MediaDir
+	}
+	if mediaDir == "" {
+		mediaDir = strings.TrimSuffix(dbPath, filepath.Ext(dbPath)) + "-media"
+	}
+	return dbPath, mediaDir
+}
+
+// idFormatSetting returns the configured format of the new IDs
+func idFormatSetting(fc fileConfiguration) string {
+	if format := os.Getenv("WASATEXT_ID_FORMAT"); format != "" {
+		return format
+	}
+	return fc.Database.IDFormat
+}
+
+// shutdownTimeoutSetting returns how long the requests under way may
+// take to finish on shutdown
+func shutdownTimeoutSetting(fc fileConfiguration) (time.Duration, error) {
+	timeout := defaultShutdownTimeout
+	if fc.API.ShutdownTimeout > 0 {
+		timeout = time.Duration(fc.API.ShutdownTimeout)
+	}
+	return durationFromEnv("WASATEXT_SHUTDOWN_TIMEOUT", timeout)
+}
+
+// durationFromEnv reads a duration such as "720h" from an environment variable
+func durationFromEnv(name string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, errors.New("invalid " + name + ": " + value)
+	}
+	return d, nil
+}
+
+// listFromEnv reads a comma-separated list from an environment variable
+func listFromEnv(name string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(name), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
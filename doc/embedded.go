@@ -0,0 +1,9 @@
+package doc
+
+import _ "embed"
+
+// OpenAPI holds the OpenAPI specification of the API (api.yaml), served
+// by GET /openapi.yaml.
+//
+//go:embed api.yaml
+var OpenAPI []byte
@@ -0,0 +1,694 @@
+/*
+Admin API handlers.
+
+These endpoints are not part of the PDF specification. They are meant
+for whoever operates the server and require the admin token
+(WASATEXT_ADMIN_TOKEN) instead of a user identifier.
+
+This file contains:
+- getPurgeReport: List accounts removed by the purge job
+- getSpamScores: List users flagged by the anti-spam heuristics
+- getModerationQueue: List reports, spam detections and filter flags
+- resolveModerationItem: Act on a moderation queue item
+- getModerationAudit: List the moderation actions taken
+- reloadConfiguration: Reload the mutable configuration
+- createWorkspace: Create a new workspace
+- exportConversation: Download a conversation as an HTML archive
+- exportUsersCSV: Download the users as CSV
+- exportActivityCSV: Download the message activity as CSV
+- runMaintenance: Check the database integrity and vacuum it
+*/
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"wasatext/service/database"
+	"wasatext/service/export"
+	"wasatext/service/ids"
+
+	"github.com/gorilla/mux"
+)
+
+// PurgeRecordResponse describes one purged account
+type PurgeRecordResponse struct {
+	UserID          ids.UserID `json:"userId"`
+	DeletedAt       string     `json:"deletedAt"`
+	PurgedAt        string     `json:"purgedAt"`
+	MessagesDeleted int64      `json:"messagesDeleted"`
+	CommentsDeleted int64      `json:"commentsDeleted"`
+	MediaDeleted    int64      `json:"mediaDeleted"`
+}
+
+// SpamScoreResponse summarizes a user's spam events
+type SpamScoreResponse struct {
+	UserID         ids.UserID `json:"userId"`
+	UserName       string     `json:"userName"`
+	Score          int        `json:"score"`
+	Events         int        `json:"events"`
+	LastEventAt    string     `json:"lastEventAt"`
+	ThrottledUntil string     `json:"throttledUntil,omitempty"`
+}
+
+// CreateWorkspaceRequest is the body for POST /admin/workspaces
+type CreateWorkspaceRequest struct {
+	WorkspaceID string `json:"workspaceId"`
+	Name        string `json:"name"`
+}
+
+// workspaceIDPattern restricts workspace IDs to short lowercase slugs
+var workspaceIDPattern = regexp.MustCompile(`^[a-z0-9-]{2,32}$`)
+
+// MaintenanceResponse is the result of POST /admin/maintenance
+type MaintenanceResponse struct {
+	StartedAt       string   `json:"startedAt"`
+	DurationMs      int64    `json:"durationMs"`
+	IntegrityOK     bool     `json:"integrityOk"`
+	IntegrityErrors []string `json:"integrityErrors"`
+	VacuumMode      string   `json:"vacuumMode"` // full, incremental
+	SizeBefore      int64    `json:"sizeBefore"`
+	SizeAfter       int64    `json:"sizeAfter"`
+	FreePagesBefore int64    `json:"freePagesBefore"`
+	FreePagesAfter  int64    `json:"freePagesAfter"`
+	// Search index entries dropped or added to match the messages
+	SearchIndexRepaired int64 `json:"searchIndexRepaired"`
+}
+
+// ModerationItemResponse is one entry of the moderation queue
+type ModerationItemResponse struct {
+	ItemID         int64         `json:"itemId"`
+	Source         string        `json:"source"` // report, spam, filter, honeypot
+	UserID         ids.UserID    `json:"userId"`
+	UserName       string        `json:"userName"`
+	MessageID      ids.MessageID `json:"messageId,omitempty"`
+	MessageContent string        `json:"messageContent,omitempty"`
+	ReporterID     ids.UserID    `json:"reporterId,omitempty"`
+	Reason         string        `json:"reason"`
+	CreatedAt      string        `json:"createdAt"`
+	ResolvedAt     string        `json:"resolvedAt,omitempty"`
+	Resolution     string        `json:"resolution,omitempty"`
+}
+
+// ModerationActionRequest is the body for POST /admin/queue/{itemId}/actions
+type ModerationActionRequest struct {
+	Action string `json:"action"` // dismiss, delete_message, warn, ban
+	Note   string `json:"note,omitempty"`
+}
+
+// ModerationAuditResponse is one entry of the moderation audit log
+type ModerationAuditResponse struct {
+	AuditID   int64         `json:"auditId"`
+	ItemID    int64         `json:"itemId"`
+	Action    string        `json:"action"`
+	UserID    ids.UserID    `json:"userId"`
+	MessageID ids.MessageID `json:"messageId,omitempty"`
+	Note      string        `json:"note,omitempty"`
+	CreatedAt string        `json:"createdAt"`
+}
+
+/*
+GetPurgeReport handles GET /admin/purges
+operationId: getPurgeReport
+
+Returns every account hard-deleted by the purge job, most recent first.
+*/
+func (h *Handler) GetPurgeReport(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check admin authentication
+	if !h.isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Step 2: Get the purge log
+	records, err := h.db.GetPurgeLog(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 3: Convert to response format
+	response := []PurgeRecordResponse{}
+	for _, rec := range records {
+		response = append(response, PurgeRecordResponse{
+			UserID:          rec.UserID,
+			DeletedAt:       rec.DeletedAt.Format(time.RFC3339),
+			PurgedAt:        rec.PurgedAt.Format(time.RFC3339),
+			MessagesDeleted: rec.MessagesDeleted,
+			CommentsDeleted: rec.CommentsDeleted,
+			MediaDeleted:    rec.MediaDeleted,
+		})
+	}
+
+	// Step 4: Return the report
+	writePage(w, r, response)
+}
+
+/*
+GetSpamScores handles GET /admin/spam
+operationId: getSpamScores
+
+Returns every user with spam detections, highest score first,
+including whether they are currently throttled.
+*/
+func (h *Handler) GetSpamScores(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check admin authentication
+	if !h.isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Step 2: Get the scores
+	scores, err := h.db.GetSpamScores(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 3: Convert to response format
+	response := []SpamScoreResponse{}
+	for _, s := range scores {
+		item := SpamScoreResponse{
+			UserID:      s.UserID,
+			UserName:    s.UserName,
+			Score:       s.Score,
+			Events:      s.Events,
+			LastEventAt: s.LastEventAt.Format(time.RFC3339),
+		}
+		if s.ThrottledUntil != nil {
+			item.ThrottledUntil = s.ThrottledUntil.Format(time.RFC3339)
+		}
+		response = append(response, item)
+	}
+
+	// Step 4: Return the scores
+	writePage(w, r, response)
+}
+
+/*
+GetModerationQueue handles GET /admin/queue
+operationId: getModerationQueue
+
+Returns the open moderation items (user reports, spam detections,
+filter and honeypot flags), oldest first. Pass ?status=all to include
+resolved items.
+*/
+func (h *Handler) GetModerationQueue(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check admin authentication
+	if !h.isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Step 2: Get the queue
+	includeResolved := r.URL.Query().Get("status") == "all"
+	items, err := h.db.GetModerationQueue(r.Context(), includeResolved)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 3: Convert to response format
+	response := []ModerationItemResponse{}
+	for _, item := range items {
+		resp := ModerationItemResponse{
+			ItemID:         item.ID,
+			Source:         item.Source,
+			UserID:         item.UserID,
+			UserName:       item.UserName,
+			MessageContent: item.MessageContent,
+			Reason:         item.Reason,
+			CreatedAt:      item.CreatedAt.Format(time.RFC3339),
+			Resolution:     item.Resolution,
+		}
+		if item.MessageID != nil {
+			resp.MessageID = *item.MessageID
+		}
+		if item.ReporterID != nil {
+			resp.ReporterID = *item.ReporterID
+		}
+		if item.ResolvedAt != nil {
+			resp.ResolvedAt = item.ResolvedAt.Format(time.RFC3339)
+		}
+		response = append(response, resp)
+	}
+
+	// Step 4: Return the queue
+	writePage(w, r, response)
+}
+
+/*
+ResolveModerationItem handles POST /admin/queue/{itemId}/actions
+operationId: resolveModerationItem
+
+Applies one action to a queue item:
+- dismiss: close the item without doing anything
+- delete_message: delete the flagged message
+- warn: give the user a warning (the note is the reason)
+- ban: ban the user (no more login or sending)
+The action is executed and audited in a single transaction.
+*/
+func (h *Handler) ResolveModerationItem(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check admin authentication
+	if !h.isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Step 2: Get the item ID from URL
+	vars := mux.Vars(r)
+	itemID, err := strconv.ParseInt(vars["itemId"], 10, 64)
+	if err != nil {
+		http.Error(w, "Moderation item not found", http.StatusNotFound)
+		return
+	}
+
+	// Step 3: Parse the request
+	var req ModerationActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Step 4: Apply the action
+	err = h.db.ResolveModerationItem(r.Context(), itemID, req.Action, req.Note)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 5: Return success (204 No Content)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+GetModerationAudit handles GET /admin/audit
+operationId: getModerationAudit
+
+Returns every moderation action taken, most recent first.
+*/
+func (h *Handler) GetModerationAudit(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check admin authentication
+	if !h.isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Step 2: Get the audit log
+	entries, err := h.db.GetModerationAudit(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 3: Convert to response format
+	response := []ModerationAuditResponse{}
+	for _, e := range entries {
+		resp := ModerationAuditResponse{
+			AuditID:   e.ID,
+			ItemID:    e.ItemID,
+			Action:    e.Action,
+			UserID:    e.UserID,
+			Note:      e.Note,
+			CreatedAt: e.CreatedAt.Format(time.RFC3339),
+		}
+		if e.MessageID != nil {
+			resp.MessageID = *e.MessageID
+		}
+		response = append(response, resp)
+	}
+
+	// Step 4: Return the audit log
+	writePage(w, r, response)
+}
+
+/*
+ReloadConfiguration handles POST /admin/config/reload
+operationId: reloadConfiguration
+
+Reads the configuration file again and applies the mutable settings
+(rate limits, CORS origins, feature flags, log level) without a restart.
+Sending SIGHUP to the server does the same.
+*/
+func (h *Handler) ReloadConfiguration(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check admin authentication
+	if !h.isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Step 2: Reload (the old configuration stays active on error)
+	err := h.ReloadConfig()
+	if errors.Is(err, ErrNoConfigLoader) {
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusUnprocessableEntity, ErrorResponse{
+			Message: "Invalid configuration: " + err.Error(),
+		})
+		return
+	}
+
+	// Step 3: Return success (204 No Content)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+CreateWorkspace handles POST /admin/workspaces
+operationId: createWorkspace
+
+Creates a new, empty workspace. Users join it by choosing it at login.
+*/
+func (h *Handler) CreateWorkspace(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check admin authentication
+	if !h.isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Step 2: Parse and validate the request
+	var req CreateWorkspaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if !workspaceIDPattern.MatchString(req.WorkspaceID) {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{
+			Message: "Workspace ID must be 2-32 lowercase letters, digits or dashes",
+		})
+		return
+	}
+	if req.Name == "" || len(req.Name) > 64 {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{
+			Message: "Workspace name must be between 1 and 64 characters",
+		})
+		return
+	}
+
+	// Step 3: Create the workspace
+	ws, err := h.db.CreateWorkspace(r.Context(), req.WorkspaceID, req.Name)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 4: Return the workspace
+	writeJSON(w, http.StatusCreated, WorkspaceResponse{
+		WorkspaceID: ws.ID,
+		Name:        ws.Name,
+	})
+}
+
+/*
+ExportConversation handles GET /admin/conversations/{conversationId}/export
+operationId: exportConversation
+
+Renders the conversation as a standalone HTML archive (messages and
+inlined photo thumbnails) for compliance archiving.
+*/
+func (h *Handler) ExportConversation(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check admin authentication
+	if !h.isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Step 2: Get the whole conversation
+	conversationID, ok := pathConversationID(w, r)
+	if !ok {
+		return
+	}
+	password, ok := exportPassword(w, r)
+	if !ok {
+		return
+	}
+	conv, err := h.db.GetConversationArchive(r.Context(), conversationID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 3: Render the archive into memory first, so a rendering
+	// error can still be reported with a proper status code
+	var buf bytes.Buffer
+	loadPhoto := func(photoID string) ([]byte, error) { return h.db.GetPhoto(r.Context(), photoID) }
+	if err := export.WriteHTML(&buf, conv, loadPhoto, time.Now()); err != nil {
+		log.Printf("Error exporting conversation %s: %v", conversationID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Step 4: Return the archive as a download
+	h.infof("Conversation %s exported (%d messages)", conversationID, len(conv.Messages))
+	filename := "conversation-" + string(conversationID) + ".html"
+	if password != "" {
+		writeEncryptedExport(w, filename, buf.Bytes(), password)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// exportPasswordHeader carries the optional password of an encrypted export.
+// It is a header rather than a query parameter so it stays out of access logs.
+const exportPasswordHeader = "X-Export-Password"
+
+// exportPassword reads the optional export password.
+// It answers 400 and returns false when the password is too short.
+func exportPassword(w http.ResponseWriter, r *http.Request) (string, bool) {
+	password := r.Header.Get(exportPasswordHeader)
+	if password != "" && len(password) < export.MinPasswordLength {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{
+			Message: "Export password must be at least " + strconv.Itoa(export.MinPasswordLength) + " characters",
+		})
+		return "", false
+	}
+	return password, true
+}
+
+// writeEncryptedExport sends a file as a password-protected zip download
+func writeEncryptedExport(w http.ResponseWriter, filename string, content []byte, password string) {
+	var buf bytes.Buffer
+	if err := export.WriteEncryptedZip(&buf, filename, content, password, time.Now()); err != nil {
+		log.Printf("Error encrypting export %s: %v", filename, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+strings.TrimSuffix(filename, path.Ext(filename))+`.zip"`)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buf.Bytes())
+}
+
+/*
+parseReportRange reads the optional "from" and "to" query parameters of
+the CSV exports. Both accept a date (2006-01-02) or an RFC 3339 time;
+a date given as "to" includes that whole day.
+*/
+func parseReportRange(r *http.Request) (from, to time.Time, err error) {
+	if from, err = parseReportTime(r.URL.Query().Get("from"), false); err != nil {
+		return from, to, err
+	}
+	if to, err = parseReportTime(r.URL.Query().Get("to"), true); err != nil {
+		return from, to, err
+	}
+	return from, to, nil
+}
+
+// parseReportTime parses a date or an RFC 3339 time, "" giving the zero
+// time. A date is its midnight, or the next one with endOfDay.
+func parseReportTime(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// reportFields reads the optional comma-separated "fields" query parameter
+func reportFields(r *http.Request) []string {
+	fields := r.URL.Query().Get("fields")
+	if fields == "" {
+		return nil
+	}
+	return strings.Split(fields, ",")
+}
+
+/*
+writeCSVExport sends a CSV export. Without a password the rows are
+streamed: once the header is sent the status can no longer change, so
+a failure halfway is only logged. With a password the file is built in
+memory and sent as an encrypted zip.
+*/
+func writeCSVExport[T any](w http.ResponseWriter, filename, password string, columns []export.Column[T], rows func(fn func(T) error) error) {
+	if password != "" {
+		var buf bytes.Buffer
+		cw, err := export.NewCSVWriter(&buf, columns, nil)
+		if err == nil {
+			err = rows(cw.Write)
+		}
+		if err == nil {
+			err = cw.Flush()
+		}
+		if err != nil {
+			log.Printf("Error exporting %s: %v", filename, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		writeEncryptedExport(w, filename, buf.Bytes(), password)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+	var flush func()
+	if flusher, ok := w.(http.Flusher); ok {
+		flush = flusher.Flush
+	}
+
+	cw, err := export.NewCSVWriter(w, columns, flush)
+	if err != nil {
+		log.Printf("Error exporting %s: %v", filename, err)
+		return
+	}
+	if err := rows(cw.Write); err != nil {
+		log.Printf("Error exporting %s: %v", filename, err)
+	}
+	if err := cw.Flush(); err != nil {
+		log.Printf("Error exporting %s: %v", filename, err)
+	}
+}
+
+/*
+ExportUsersCSV handles GET /admin/export/users.csv
+operationId: exportUsersCSV
+
+Streams every user as CSV. "from" and "to" filter on the account
+creation time; "fields" picks and orders the columns.
+*/
+func (h *Handler) ExportUsersCSV(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check admin authentication
+	if !h.isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Step 2: Parse the filters
+	from, to, err := parseReportRange(r)
+	if err != nil {
+		http.Error(w, "Invalid date range", http.StatusBadRequest)
+		return
+	}
+	columns, err := export.SelectColumns(export.UserColumns, reportFields(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	password, ok := exportPassword(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 3: Send the rows
+	writeCSVExport(w, "users.csv", password, columns, func(fn func(database.UserReportRow) error) error {
+		return h.db.ExportUsers(r.Context(), from, to, fn)
+	})
+}
+
+/*
+ExportActivityCSV handles GET /admin/export/activity.csv
+operationId: exportActivityCSV
+
+Streams one CSV row per message sent (never its content). "from" and
+"to" filter on the message time; "fields" picks and orders the columns.
+*/
+func (h *Handler) ExportActivityCSV(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check admin authentication
+	if !h.isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Step 2: Parse the filters
+	from, to, err := parseReportRange(r)
+	if err != nil {
+		http.Error(w, "Invalid date range", http.StatusBadRequest)
+		return
+	}
+	columns, err := export.SelectColumns(export.ActivityColumns, reportFields(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	password, ok := exportPassword(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 3: Send the rows
+	writeCSVExport(w, "activity.csv", password, columns, func(fn func(database.ActivityReportRow) error) error {
+		return h.db.ExportActivity(r.Context(), from, to, fn)
+	})
+}
+
+/*
+RunMaintenance handles POST /admin/maintenance
+operationId: runMaintenance
+
+Runs PRAGMA integrity_check and vacuums the database file, reporting
+the size before and after. The same maintenance can be scheduled with
+WASATEXT_MAINTENANCE_INTERVAL.
+*/
+func (h *Handler) RunMaintenance(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check admin authentication
+	if !h.isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Step 2: Run the maintenance
+	report, err := h.db.RunMaintenance(r.Context())
+	if err != nil {
+		log.Printf("Database maintenance failed: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Step 3: Return the report
+	response := MaintenanceResponse{
+		StartedAt:       report.StartedAt.Format(time.RFC3339),
+		DurationMs:      report.Duration.Milliseconds(),
+		IntegrityOK:     len(report.IntegrityErrors) == 0,
+		IntegrityErrors: report.IntegrityErrors,
+		VacuumMode:      report.VacuumMode,
+		SizeBefore:      report.SizeBefore,
+		SizeAfter:       report.SizeAfter,
+		FreePagesBefore: report.FreePagesBefore,
+		FreePagesAfter:  report.FreePagesAfter,
+
+		SearchIndexRepaired: report.SearchIndexRepaired,
+	}
+	if response.IntegrityErrors == nil {
+		response.IntegrityErrors = []string{}
+	}
+	writeJSON(w, http.StatusOK, response)
+}
@@ -9,26 +9,127 @@ the appropriate handler function. That function:
 3. Calls the database
 4. Returns a response
 
-This file sets up the router and CORS middleware.
+This file sets up the router, the CORS middleware and the session
+authentication middleware.
 */
 package api
 
 import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log"
 	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"wasatext/service/database"
+	"wasatext/service/events"
+	"wasatext/service/globaltime"
+	"wasatext/service/graphql"
+	"wasatext/service/ids"
+	"wasatext/service/notifications"
 
 	"github.com/gorilla/mux"
 )
 
 // Handler contains all API handler methods
 type Handler struct {
-	db database.AppDatabase
+	db           database.AppDatabase
+	clock        globaltime.Time
+	cfg          atomic.Pointer[Config]
+	configLoader func() (Config, error)
+	mediaKey     []byte // signs media URLs when no secret is configured
+	hub          *hub   // the open WebSockets (see events.go)
+	hookLimiters hookLimiters
+	rateLimiters rateLimiters            // the request rate limits (see ratelimit.go)
+	fanout       *fanoutWorker           // inserts the receipts of large groups (see fanout.go)
+	media        *mediaWorker            // makes the renditions of new photos (see processing.go)
+	presence     presenceMap             // last heartbeats (see presence.go)
+	rejections   *rejectionLog           // the rejected requests (see rejections.go)
+	apiUsage     *apiUsageRecorder       // the sampled requests (see apiusage.go)
+	scheduler    *messageScheduler       // sends the scheduled messages (see scheduled.go)
+	push         *notifications.Notifier // sends the push notifications (see push.go)
+	webhooks     *webhookDispatcher      // posts the webhook deliveries (see webhooks.go)
+	graphql      *graphql.Schema         // the schema of POST /graphql (see graphql.go)
+	matrix       *matrixBridge           // relays messages to and from Matrix rooms (see matrix.go)
+	bus          *events.Bus             // hands the events of the writes to their side effects
+	backplane    *backplane              // relays the real-time events between instances (see backplane.go)
+	started      time.Time               // when the handler was created, for the uptime
+	status       statusCache             // the last public status report (see status.go)
+	stopWorkers  context.CancelFunc
+	workers      sync.WaitGroup
 }
 
-// New creates a new API handler
-func New(db database.AppDatabase) *Handler {
-	return &Handler{db: db}
+// New creates a new API handler and starts its background workers,
+// which run until Shutdown
+func New(db database.AppDatabase, cfg Config) *Handler {
+	return NewWithClock(db, cfg, globaltime.RealTime{})
+}
+
+// NewWithClock is New with the clock telling when the scheduled messages
+// are due, so that a test can move time forward
+func NewWithClock(db database.AppDatabase, cfg Config, clock globaltime.Time) *Handler {
+	ctx, cancel := context.WithCancel(context.Background())
+	h := &Handler{db: db, clock: clock, started: time.Now(), mediaKey: newMediaKey(), hub: newHub(), fanout: newFanoutWorker(db), rejections: newRejectionLog(), stopWorkers: cancel}
+	h.UpdateConfig(cfg)
+	h.hub.dropEvent = h.chaosDropEvent
+	h.media = newMediaWorker(db, h.config)
+	h.apiUsage = newAPIUsageRecorder(db, h.config)
+	h.scheduler = newMessageScheduler(db, clock, h.sendScheduledMessage)
+	h.push = notifications.NewNotifier(db, func() *notifications.VAPID { return h.config().Push.keys() })
+	h.webhooks = newWebhookDispatcher(db, clock)
+	h.graphql = h.graphqlSchema()
+	h.matrix = newMatrixBridge()
+	h.backplane = newBackplane()
+
+	// What follows from the writes (see service/events): the push
+	// notifications, the webhooks, the Matrix bridge, then the events
+	// of the clients
+	h.bus = events.NewBus()
+	events.Subscribe(h.bus, h.pushMessage)
+	events.Subscribe(h.bus, h.emitMessageCreated)
+	events.Subscribe(h.bus, h.emitMemberAdded)
+	events.Subscribe(h.bus, h.relayToMatrix)
+	events.Subscribe(h.bus, h.publishMessage)
+	events.Subscribe(h.bus, h.publishReactionAdded)
+	events.Subscribe(h.bus, h.publishReactionRemoved)
+
+	for _, run := range []func(context.Context){h.fanout.run, h.media.run, h.apiUsage.run, h.scheduler.run, h.push.Run, h.webhooks.run, h.runPresence, h.runMatrixSync, h.runMatrixRelay, h.runBackplanePublish, h.runBackplaneSubscribe} {
+		h.workers.Add(1)
+		go func() {
+			defer h.workers.Done()
+			run(ctx)
+		}()
+	}
+	return h
+}
+
+/*
+Shutdown stops the handler once the HTTP server no longer takes
+requests: the WebSockets are closed, the background workers finish
+the work under way (the API usage is written) and stop, and the
+rejection log is closed. The database can be closed after it. It
+returns ctx.Err() when ctx ends before everything stopped.
+*/
+func (h *Handler) Shutdown(ctx context.Context) error {
+	h.hub.closeAll()
+	h.stopWorkers()
+
+	stopped := make(chan struct{})
+	go func() {
+		h.hub.wait()
+		h.workers.Wait()
+		h.rejections.close()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 // NewRouter creates a new router with all routes
@@ -37,17 +138,98 @@ func NewRouter(h *Handler) *mux.Router {
 	// It matches URLs to handler functions
 	r := mux.NewRouter()
 
+	// Rejected requests are logged, if configured (see rejections.go)
+	r.Use(h.RejectionLogMiddleware)
+
+	// Deprecated routes announce their removal (see changelog.go)
+	r.Use(DeprecationMiddleware)
+
+	// Session tokens are resolved to users once, before the handlers
+	r.Use(h.AuthMiddleware)
+
+	// Requests sampled for the API usage analytics, rate limited ones
+	// included (see apiusage.go)
+	r.Use(h.APIUsageMiddleware)
+
+	// Rate limits per user or address (see ratelimit.go)
+	r.Use(h.RateLimitMiddleware)
+
+	// Fake latency and failures in the developer sandbox (see sandbox.go)
+	r.Use(h.SandboxMiddleware)
+
+	// Injected faults for resilience testing (see chaos.go)
+	r.Use(h.ChaosMiddleware)
+
+	// ===========================================
+	// API CHANGELOG
+	// ===========================================
+	r.HandleFunc("/api/changelog", h.GetChangelog).Methods("GET", "OPTIONS")
+
+	// ===========================================
+	// OPENAPI SPECIFICATION (see openapi.go)
+	// ===========================================
+	r.HandleFunc("/openapi.yaml", h.GetOpenAPI).Methods("GET", "OPTIONS")
+	r.HandleFunc("/api/docs", h.GetAPIDocs).Methods("GET", "OPTIONS")
+
+	// ===========================================
+	// WEB FRONTEND CONFIGURATION (see webui.go)
+	// ===========================================
+	r.HandleFunc("/config.js", h.GetWebUIConfig).Methods("GET", "OPTIONS")
+
+	// ===========================================
+	// PUBLIC STATUS (see status.go)
+	// ===========================================
+	r.HandleFunc("/status", h.GetStatus).Methods("GET", "OPTIONS")
+
 	// ===========================================
 	// LOGIN API (from PDF - doLogin)
 	// ===========================================
 	r.HandleFunc("/session", h.DoLogin).Methods("POST", "OPTIONS")
+	r.HandleFunc("/session", h.DoLogout).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/workspaces", h.ListWorkspaces).Methods("GET", "OPTIONS")
+	r.HandleFunc("/invites", h.CreateInvite).Methods("POST", "OPTIONS")
+	r.HandleFunc("/invites", h.ListMyInvites).Methods("GET", "OPTIONS")
+	r.HandleFunc("/invites/{code}", h.RevokeMyInvite).Methods("DELETE", "OPTIONS")
 
 	// ===========================================
 	// USER APIs
 	// ===========================================
 	r.HandleFunc("/users", h.SearchUsers).Methods("GET", "OPTIONS")
 	r.HandleFunc("/users/{userId}/username", h.SetMyUserName).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/users/{userId}/about", h.SetMyAbout).Methods("PUT", "OPTIONS")
 	r.HandleFunc("/users/{userId}/photo", h.SetMyPhoto).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/users/{userId}/photo", h.GetUserPhoto).Methods("GET", "OPTIONS")
+	r.HandleFunc("/users/{userId}", h.GetUserProfile).Methods("GET", "OPTIONS")
+	r.HandleFunc("/users/{userId}", h.DeleteMyAccount).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/users/{userId}/warnings", h.GetMyWarnings).Methods("GET", "OPTIONS")
+	r.HandleFunc("/users/{userId}/block", h.BlockUser).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/users/{userId}/block", h.UnblockUser).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/users/me/deactivate", h.DeactivateMyAccount).Methods("POST", "OPTIONS")
+	r.HandleFunc("/users/me/key-backup", h.GetMyKeyBackup).Methods("GET", "OPTIONS")
+	r.HandleFunc("/users/me/key-backup", h.PutMyKeyBackup).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/users/me/key-backup", h.DeleteMyKeyBackup).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/users/me/privacy", h.GetMyPrivacy).Methods("GET", "OPTIONS")
+	r.HandleFunc("/users/me/privacy", h.SetMyPrivacy).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/users/me/usage", h.GetMyUsage).Methods("GET", "OPTIONS")
+	r.HandleFunc("/users/me/api-usage", h.GetMyAPIUsage).Methods("GET", "OPTIONS")
+	r.HandleFunc("/users/me/notes", h.GetMyNotes).Methods("GET", "OPTIONS")
+	r.HandleFunc("/users/me/reminders", h.GetMyReminders).Methods("GET", "OPTIONS")
+	r.HandleFunc("/users/me/scheduled-messages", h.GetMyScheduledMessages).Methods("GET", "OPTIONS")
+	r.HandleFunc("/users/me/scheduled-messages/{scheduledMessageId}", h.CancelScheduledMessage).Methods("DELETE", "OPTIONS")
+
+	// ===========================================
+	// PRESENCE APIs (in memory, last seen saved; see presence.go)
+	// ===========================================
+	r.HandleFunc("/presence/heartbeat", h.SendHeartbeat).Methods("POST", "OPTIONS")
+	r.HandleFunc("/presence", h.GetPresence).Methods("GET", "OPTIONS")
+
+	// ===========================================
+	// PUSH NOTIFICATION APIs (see push.go)
+	// ===========================================
+	r.HandleFunc("/push/vapid-key", h.GetVAPIDKey).Methods("GET", "OPTIONS")
+	r.HandleFunc("/push/subscriptions", h.CreatePushSubscription).Methods("POST", "OPTIONS")
+	r.HandleFunc("/push/subscriptions", h.GetMyPushSubscriptions).Methods("GET", "OPTIONS")
+	r.HandleFunc("/push/subscriptions/{subscriptionId}", h.DeletePushSubscription).Methods("DELETE", "OPTIONS")
 
 	// ===========================================
 	// CONVERSATION APIs
@@ -55,42 +237,188 @@ func NewRouter(h *Handler) *mux.Router {
 	r.HandleFunc("/conversations", h.GetMyConversations).Methods("GET", "OPTIONS")
 	r.HandleFunc("/conversations", h.StartConversation).Methods("POST", "OPTIONS")
 	r.HandleFunc("/conversations/{conversationId}", h.GetConversation).Methods("GET", "OPTIONS")
+	r.HandleFunc("/conversations/{conversationId}/read", h.MarkConversationRead).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/conversations/{conversationId}/clear", h.ClearConversation).Methods("POST", "OPTIONS")
+	r.HandleFunc("/conversations/{conversationId}/privacy", h.GetConversationPrivacy).Methods("GET", "OPTIONS")
+	r.HandleFunc("/conversations/{conversationId}/privacy", h.SetConversationPrivacy).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/conversations/{conversationId}/permissions", h.GetConversationPermissions).Methods("GET", "OPTIONS")
+	r.HandleFunc("/conversations/{conversationId}/mute", h.GetConversationMute).Methods("GET", "OPTIONS")
+	r.HandleFunc("/conversations/{conversationId}/mute", h.MuteConversation).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/conversations/{conversationId}/mute", h.UnmuteConversation).Methods("DELETE", "OPTIONS")
 
 	// ===========================================
 	// MESSAGE APIs
 	// ===========================================
 	r.HandleFunc("/conversations/{conversationId}/messages", h.SendMessage).Methods("POST", "OPTIONS")
+	r.HandleFunc("/conversations/{conversationId}/events", h.CreateEvent).Methods("POST", "OPTIONS")
+	r.HandleFunc("/conversations/{conversationId}/messages/search", h.SearchConversationMessages).Methods("GET", "OPTIONS")
+	r.HandleFunc("/messages/search", h.SearchMessages).Methods("GET", "OPTIONS")
+	r.HandleFunc("/mentions", h.GetMyMentions).Methods("GET", "OPTIONS")
+	r.HandleFunc("/messages/{messageId}/note", h.SetMessageNote).Methods("POST", "OPTIONS")
+	r.HandleFunc("/messages/{messageId}/note", h.DeleteMessageNote).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/messages/{messageId}/remind", h.SetMessageReminder).Methods("POST", "OPTIONS")
+	r.HandleFunc("/messages/{messageId}/remind", h.DeleteMessageReminder).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/messages/{messageId}/rsvp", h.SetRSVP).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/messages/{messageId}/rsvp", h.DeleteRSVP).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/messages/{messageId}/rsvps", h.GetRSVPs).Methods("GET", "OPTIONS")
+	r.HandleFunc("/conversations/{conversationId}/messages/{messageId}", h.EditMessage).Methods("PUT", "OPTIONS")
 	r.HandleFunc("/conversations/{conversationId}/messages/{messageId}", h.DeleteMessage).Methods("DELETE", "OPTIONS")
 	r.HandleFunc("/conversations/{conversationId}/messages/{messageId}/forward", h.ForwardMessage).Methods("POST", "OPTIONS")
+	r.HandleFunc("/conversations/{conversationId}/messages/{messageId}/reports", h.ReportMessage).Methods("POST", "OPTIONS")
+	r.HandleFunc("/conversations/{conversationId}/messages/{messageId}/receipts", h.GetMessageReceipts).Methods("GET", "OPTIONS")
+	r.HandleFunc("/conversations/{conversationId}/messages/{messageId}/pin", h.PinMessage).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/conversations/{conversationId}/messages/{messageId}/pin", h.UnpinMessage).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/conversations/{conversationId}/pins", h.GetPins).Methods("GET", "OPTIONS")
+	r.HandleFunc("/conversations/{conversationId}/messages/{messageId}/photo", h.GetMessagePhoto).Methods("GET", "OPTIONS")
 
 	// ===========================================
 	// COMMENT (REACTION) APIs
 	// ===========================================
 	r.HandleFunc("/conversations/{conversationId}/messages/{messageId}/comments", h.CommentMessage).Methods("POST", "OPTIONS")
 	r.HandleFunc("/conversations/{conversationId}/messages/{messageId}/comments", h.UncommentMessage).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/conversations/{conversationId}/messages/{messageId}/comments/{emoticon}", h.UncommentMessageEmoticon).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/conversations/{conversationId}/reactions", h.GetReactions).Methods("GET", "OPTIONS")
+	r.HandleFunc("/conversations/{conversationId}/stats/reactions", h.GetReactionStats).Methods("GET", "OPTIONS")
+
+	// ===========================================
+	// WEBHOOK APIs (the hook token is the only credential of POST /hooks)
+	// ===========================================
+	r.HandleFunc("/conversations/{conversationId}/hooks", h.CreateHook).Methods("POST", "OPTIONS")
+	r.HandleFunc("/conversations/{conversationId}/hooks", h.ListHooks).Methods("GET", "OPTIONS")
+	r.HandleFunc("/conversations/{conversationId}/hooks/{hookToken}", h.DeleteHook).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/hooks/{hookToken}", h.PostHook).Methods("POST", "OPTIONS")
+
+	// ===========================================
+	// WIDGET TOKEN APIs (read one conversation, see widgets.go)
+	// ===========================================
+	r.HandleFunc("/conversations/{conversationId}/widget-tokens", h.CreateWidgetToken).Methods("POST", "OPTIONS")
+	r.HandleFunc("/conversations/{conversationId}/widget-tokens", h.ListWidgetTokens).Methods("GET", "OPTIONS")
+	r.HandleFunc("/conversations/{conversationId}/widget-tokens/{widgetToken}", h.RevokeWidgetToken).Methods("DELETE", "OPTIONS")
 
 	// ===========================================
 	// GROUP APIs
 	// ===========================================
 	r.HandleFunc("/groups", h.CreateGroup).Methods("POST", "OPTIONS")
+	r.HandleFunc("/groups/{groupId}", h.GetGroup).Methods("GET", "OPTIONS")
 	r.HandleFunc("/groups/{groupId}/members", h.AddToGroup).Methods("POST", "OPTIONS")
 	r.HandleFunc("/groups/{groupId}/members/me", h.LeaveGroup).Methods("DELETE", "OPTIONS")
 	r.HandleFunc("/groups/{groupId}/name", h.SetGroupName).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/groups/{groupId}/description", h.SetGroupDescription).Methods("PUT", "OPTIONS")
 	r.HandleFunc("/groups/{groupId}/photo", h.SetGroupPhoto).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/groups/{groupId}/photo", h.GetGroupPhoto).Methods("GET", "OPTIONS")
+	r.HandleFunc("/groups/{groupId}/kind", h.SetGroupKind).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/groups/{groupId}/governance", h.SetGroupGovernance).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/groups/{groupId}/proposals", h.CreateProposal).Methods("POST", "OPTIONS")
+	r.HandleFunc("/groups/{groupId}/proposals", h.GetProposals).Methods("GET", "OPTIONS")
+	r.HandleFunc("/groups/{groupId}/proposals/{proposalId}/vote", h.VoteOnProposal).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/channels", h.ListChannels).Methods("GET", "OPTIONS")
+	r.HandleFunc("/groups/{groupId}/feed", h.SetChannelFeed).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/channels/{groupId}/feed.atom", h.GetChannelFeed).Methods("GET", "OPTIONS")
+	r.HandleFunc("/groups/{groupId}/guest-tokens", h.CreateGuestToken).Methods("POST", "OPTIONS")
+	r.HandleFunc("/groups/{groupId}/guest-tokens/{token}", h.RevokeGuestToken).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/groups/{groupId}/webhooks", h.CreateGroupWebhook).Methods("POST", "OPTIONS")
+	r.HandleFunc("/groups/{groupId}/webhooks", h.ListGroupWebhooks).Methods("GET", "OPTIONS")
+	r.HandleFunc("/groups/{groupId}/webhooks/{webhookId}", h.DeleteGroupWebhook).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/groups/{groupId}/webhooks/{webhookId}/deliveries", h.GetGroupWebhookDeliveries).Methods("GET", "OPTIONS")
+
+	// ===========================================
+	// BOT APIs (bot token instead of a session, see bots.go)
+	// ===========================================
+	r.HandleFunc("/bots/{botId}/messages", h.PostBotMessage).Methods("POST", "OPTIONS")
+	r.HandleFunc("/bots/{botId}/webhook", h.SetBotWebhook).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/bots/{botId}/webhook", h.DeleteBotWebhook).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/bots/{botId}/webhook/deliveries", h.GetBotWebhookDeliveries).Methods("GET", "OPTIONS")
+
+	// ===========================================
+	// MEDIA (signed URL instead of the bearer token)
+	// ===========================================
+	r.HandleFunc("/media/{mediaId}", h.GetMedia).Methods("GET", "OPTIONS")
+	r.HandleFunc("/media/{mediaId}/status", h.GetMediaStatus).Methods("GET", "OPTIONS")
+
+	// ===========================================
+	// REAL-TIME EVENTS (WebSocket, or Server-Sent Events)
+	// ===========================================
+	r.HandleFunc("/ws", h.Events).Methods("GET")
+	r.HandleFunc("/events", h.EventStream).Methods("GET", "OPTIONS")
+
+	// ===========================================
+	// GRAPHQL (read-only, see graphql.go)
+	// ===========================================
+	r.HandleFunc("/graphql", h.GraphQL).Methods("GET", "POST", "OPTIONS")
+	r.HandleFunc("/graphql/schema", h.GetGraphQLSchema).Methods("GET", "OPTIONS")
+
+	// ===========================================
+	// GUEST APIs (read-only, guest token instead of user ID)
+	// ===========================================
+	r.HandleFunc("/guest/conversation", h.GetGuestConversation).Methods("GET", "OPTIONS")
+
+	// ===========================================
+	// ADMIN APIs
+	// ===========================================
+	r.HandleFunc("/admin/purges", h.GetPurgeReport).Methods("GET", "OPTIONS")
+	r.HandleFunc("/admin/spam", h.GetSpamScores).Methods("GET", "OPTIONS")
+	r.HandleFunc("/admin/queue", h.GetModerationQueue).Methods("GET", "OPTIONS")
+	r.HandleFunc("/admin/queue/{itemId}/actions", h.ResolveModerationItem).Methods("POST", "OPTIONS")
+	r.HandleFunc("/admin/audit", h.GetModerationAudit).Methods("GET", "OPTIONS")
+	r.HandleFunc("/admin/config/reload", h.ReloadConfiguration).Methods("POST", "OPTIONS")
+	r.HandleFunc("/admin/workspaces", h.CreateWorkspace).Methods("POST", "OPTIONS")
+	r.HandleFunc("/admin/imports/whatsapp", h.ImportWhatsAppChat).Methods("POST", "OPTIONS")
+	r.HandleFunc("/admin/groups/{groupId}/kind", h.SetGroupKindAdmin).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/admin/groups/{groupId}", h.DeleteGroupAdmin).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/admin/groups/{groupId}/restore", h.RestoreGroupAdmin).Methods("POST", "OPTIONS")
+	r.HandleFunc("/admin/conversations/{conversationId}", h.DeleteConversationAdmin).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/admin/conversations/{conversationId}/restore", h.RestoreConversationAdmin).Methods("POST", "OPTIONS")
+	r.HandleFunc("/admin/deleted-conversations", h.GetDeletedConversations).Methods("GET", "OPTIONS")
+	r.HandleFunc("/admin/deletion-audit", h.GetDeletionAudit).Methods("GET", "OPTIONS")
+	r.HandleFunc("/admin/invites", h.CreateAdminInvite).Methods("POST", "OPTIONS")
+	r.HandleFunc("/admin/invites", h.ListInvites).Methods("GET", "OPTIONS")
+	r.HandleFunc("/admin/invites/{code}", h.RevokeInvite).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/admin/webhooks", h.CreateWebhook).Methods("POST", "OPTIONS")
+	r.HandleFunc("/admin/webhooks", h.ListWebhooks).Methods("GET", "OPTIONS")
+	r.HandleFunc("/admin/webhooks/{webhookId}", h.DeleteWebhook).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/admin/webhooks/{webhookId}/deliveries", h.GetWebhookDeliveries).Methods("GET", "OPTIONS")
+	r.HandleFunc("/admin/bots", h.CreateBot).Methods("POST", "OPTIONS")
+	r.HandleFunc("/admin/bots", h.ListBots).Methods("GET", "OPTIONS")
+	r.HandleFunc("/admin/bots/{botId}", h.DeleteBot).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/admin/bots/{botId}/token", h.ResetBotToken).Methods("POST", "OPTIONS")
+	r.HandleFunc("/admin/conversations/{conversationId}/export", h.ExportConversation).Methods("GET", "OPTIONS")
+	r.HandleFunc("/admin/conversations/{conversationId}/integrity", h.VerifyConversation).Methods("GET", "OPTIONS")
+	r.HandleFunc("/admin/export/users.csv", h.ExportUsersCSV).Methods("GET", "OPTIONS")
+	r.HandleFunc("/admin/export/activity.csv", h.ExportActivityCSV).Methods("GET", "OPTIONS")
+	r.HandleFunc("/admin/maintenance", h.RunMaintenance).Methods("POST", "OPTIONS")
+	r.HandleFunc("/admin/metrics", h.GetMetrics).Methods("GET", "OPTIONS")
+	r.HandleFunc("/admin/api-usage", h.GetAdminAPIUsage).Methods("GET", "OPTIONS")
+	r.HandleFunc("/admin/branding", h.GetBranding).Methods("GET", "OPTIONS")
+	r.HandleFunc("/admin/branding", h.SetBranding).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/admin/branding/logo", h.SetBrandingLogo).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/admin/branding/logo", h.DeleteBrandingLogo).Methods("DELETE", "OPTIONS")
+
+	// ===========================================
+	// DEVELOPER SANDBOX (only with the sandbox enabled)
+	// ===========================================
+	r.HandleFunc("/sandbox/reset", h.ResetSandbox).Methods("POST", "OPTIONS")
 
 	return r
 }
 
 /*
-CorsMiddleware handles the CORS headers
+CorsMiddleware handles the CORS headers.
+The allowed origins come from the live configuration, so they can be
+changed with a configuration reload.
 */
-func CorsMiddleware(next http.Handler) http.Handler {
+func (h *Handler) CorsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.debugf("%s %s", r.Method, r.URL.Path)
+
 		// Set CORS headers (as specified in PDF)
-		w.Header().Set("Access-Control-Allow-Origin", "*")                                // Allow ALL origins
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS") // Allowed HTTP methods
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")     // Allowed request headers
-		w.Header().Set("Access-Control-Max-Age", "1")                                     // Cache preflight for 1 second (PDF requirement)
+		if origin := allowedOrigin(h.config().CORSOrigins, r.Header.Get("Origin")); origin != "" {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+		w.Header().Add("Vary", "Origin")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")                                                   // Allowed HTTP methods
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Export-Password, X-Sandbox-Delay, X-Sandbox-Status") // Allowed request headers
+		w.Header().Set("Access-Control-Max-Age", "1")                                                                                       // Cache preflight for 1 second (PDF requirement)
+		w.Header().Set("Access-Control-Expose-Headers", "Deprecation, Sunset, Link, X-Chaos-Fault")                                         // Let clients see deprecations and injected faults
 
 		// Handle preflight requests
 		// Preflight = browser sends OPTIONS request first to check if actual request is allowed
@@ -104,12 +432,127 @@ func CorsMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// Helper function to get user ID from Authorization header
-// Format: "Bearer <user-identifier>"
-func getUserIDFromAuth(r *http.Request) string {
+// allowedOrigin returns the Access-Control-Allow-Origin value for a request origin,
+// or "" if the origin is not allowed
+func allowedOrigin(allowed []string, origin string) string {
+	for _, o := range allowed {
+		if o == "*" {
+			return "*"
+		}
+		if origin != "" && o == origin {
+			return origin
+		}
+	}
+	return ""
+}
+
+// getBearerToken returns the token of the Authorization header
+// Format: "Bearer <token>"
+func getBearerToken(r *http.Request) string {
 	auth := r.Header.Get("Authorization")
 	if len(auth) > 7 && auth[:7] == "Bearer " {
 		return auth[7:]
 	}
 	return ""
 }
+
+// authUserKey is the request context key of the authenticated user
+type authUserKey struct{}
+
+/*
+AuthMiddleware resolves the session token of the Authorization header
+(see DoLogin) and records its user in the request context. Requests
+without a valid session go on unauthenticated: each handler decides
+whether it needs a user, a guest token or the admin token.
+
+A widget token (see widgets.go) also authenticates its user, but only
+for the requests in its scope; the others are refused here.
+*/
+func (h *Handler) AuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := getBearerToken(r)
+		userID := h.sessionUser(r.Context(), token)
+		if database.IsWidgetToken(token) {
+			var ok bool
+			if userID, ok = h.widgetUser(w, r, token); !ok {
+				return
+			}
+		}
+		if userID != "" {
+			r = r.WithContext(context.WithValue(r.Context(), authUserKey{}, userID))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sessionUser returns the user of a session token, or "" when the token
+// is not a valid session token
+func (h *Handler) sessionUser(ctx context.Context, token string) ids.UserID {
+	if !database.IsSessionToken(token) {
+		return ""
+	}
+	userID, err := h.db.GetSessionUser(ctx, token)
+	if err != nil {
+		if !errors.Is(err, database.ErrSessionNotFound) {
+			log.Printf("Error resolving a session: %v", err)
+		}
+		return ""
+	}
+	return userID
+}
+
+// getUserIDFromAuth returns the user authenticated by AuthMiddleware,
+// or "" when the request has no valid session token
+func getUserIDFromAuth(r *http.Request) ids.UserID {
+	userID, _ := r.Context().Value(authUserKey{}).(ids.UserID)
+	return userID
+}
+
+// isAdmin checks the Authorization header against the admin token
+func (h *Handler) isAdmin(r *http.Request) bool {
+	adminToken := h.config().AdminToken
+	if adminToken == "" {
+		return false
+	}
+	token := getBearerToken(r)
+	return subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1
+}
+
+// The path* helpers read an ID from the URL path.
+// They answer 400 and return false when it is not a valid ID.
+
+func pathUserID(w http.ResponseWriter, r *http.Request) (ids.UserID, bool) {
+	id, err := ids.ParseUserID(mux.Vars(r)["userId"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return "", false
+	}
+	return id, true
+}
+
+func pathConversationID(w http.ResponseWriter, r *http.Request) (ids.ConversationID, bool) {
+	id, err := ids.ParseConversationID(mux.Vars(r)["conversationId"])
+	if err != nil {
+		http.Error(w, "Invalid conversation ID", http.StatusBadRequest)
+		return "", false
+	}
+	return id, true
+}
+
+func pathMessageID(w http.ResponseWriter, r *http.Request) (ids.MessageID, bool) {
+	id, err := ids.ParseMessageID(mux.Vars(r)["messageId"])
+	if err != nil {
+		http.Error(w, "Invalid message ID", http.StatusBadRequest)
+		return "", false
+	}
+	return id, true
+}
+
+func pathGroupID(w http.ResponseWriter, r *http.Request) (ids.GroupID, bool) {
+	id, err := ids.ParseGroupID(mux.Vars(r)["groupId"])
+	if err != nil {
+		http.Error(w, "Invalid group ID", http.StatusBadRequest)
+		return "", false
+	}
+	return id, true
+}
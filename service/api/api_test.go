@@ -0,0 +1,105 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"wasatext/service/database"
+	"wasatext/service/storage"
+)
+
+// testServer is the API over a database in a temporary directory
+type testServer struct {
+	t   testing.TB
+	db  database.AppDatabase
+	srv *httptest.Server
+}
+
+// newTestServer starts the API with the default configuration; it is
+// shut down at the end of the test
+func newTestServer(t testing.TB) *testServer {
+	t.Helper()
+	return newTestServerWithConfig(t, DefaultConfig())
+}
+
+// newTestServerWithConfig starts the API with the given configuration
+func newTestServerWithConfig(t testing.TB, cfg Config) *testServer {
+	t.Helper()
+	dir := t.TempDir()
+	blobs, err := storage.NewFileStore(filepath.Join(dir, "blobs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := database.New(filepath.Join(dir, "wasatext.db"), blobs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := New(db, cfg)
+	srv := httptest.NewServer(h.CorsMiddleware(NewRouter(h)))
+	t.Cleanup(func() {
+		srv.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = h.Shutdown(ctx)
+		_ = db.Close()
+	})
+	return &testServer{t: t, db: db, srv: srv}
+}
+
+// login opens a session for a user, creating them if needed
+func (s *testServer) login(name string) LoginResponse {
+	s.t.Helper()
+	var session LoginResponse
+	s.decode(s.request(http.MethodPost, "/session", "", map[string]string{"name": name}, http.StatusCreated), &session)
+	return session
+}
+
+// request sends a request with a JSON body (nil for none) and returns
+// the body of the response, failing the test unless it has the status
+func (s *testServer) request(method, path, token string, body any, status int) []byte {
+	s.t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			s.t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, s.srv.URL+path, reader)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := s.srv.Client().Do(req)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	if resp.StatusCode != status {
+		s.t.Fatalf("%s %s: status %d, want %d: %s", method, path, resp.StatusCode, status, data)
+	}
+	return data
+}
+
+// decode unmarshals a response body
+func (s *testServer) decode(data []byte, v any) {
+	s.t.Helper()
+	if err := json.Unmarshal(data, v); err != nil {
+		s.t.Fatalf("decoding %s: %v", data, err)
+	}
+}
@@ -0,0 +1,327 @@
+/*
+API usage analytics.
+
+APIUsageMiddleware samples the requests of the authenticated users: one
+request in Config.APIUsage.SampleRate is counted, for SampleRate
+requests, by user, day (UTC) and route (the method and the route
+template, without the IDs). The counts are kept in memory and written
+to the database every minute and when the server stops, and kept for
+Retention (see service/database/apiusage.go).
+
+Users see their own with GET /users/me/api-usage, to find out what their
+client sends; the admin sees the totals per route and the heaviest
+users with GET /admin/api-usage, to spot misbehaving clients and tune
+the rate limits (see ratelimit.go). Being sampled, the counts are
+estimates: close for the routes called often, rough for the others.
+They lag up to a minute behind.
+*/
+package api
+
+import (
+	"context"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"wasatext/service/database"
+	"wasatext/service/ids"
+)
+
+// APIUsageConfig holds the settings of the API usage analytics
+type APIUsageConfig struct {
+	// SampleRate counts one request in SampleRate; 0 turns the analytics off
+	SampleRate int
+
+	// Retention is how long the counts are kept; 0 keeps them for good
+	Retention time.Duration
+}
+
+// DefaultAPIUsageConfig returns the settings used when none are configured
+func DefaultAPIUsageConfig() APIUsageConfig {
+	return APIUsageConfig{SampleRate: 10, Retention: 30 * 24 * time.Hour}
+}
+
+const (
+	// apiUsageFlushInterval is how often the counts are written
+	apiUsageFlushInterval = time.Minute
+
+	// Days covered by the reports, and users in the admin report
+	defaultAPIUsageDays  = 7
+	maxAPIUsageDays      = 90
+	defaultAPIUsageUsers = 20
+	maxAPIUsageUsers     = 100
+)
+
+// APIUsageEndpoint is the estimated requests to a route
+type APIUsageEndpoint struct {
+	Method   string `json:"method"`
+	Route    string `json:"route"`
+	Requests int    `json:"requests"`
+	Users    int    `json:"users,omitempty"` // in the admin report, users who called it
+}
+
+// APIUsageDay is the estimated requests of a day
+type APIUsageDay struct {
+	Day      string `json:"day"` // YYYY-MM-DD, UTC
+	Requests int    `json:"requests"`
+}
+
+// APIUsageUser is a user and their estimated requests
+type APIUsageUser struct {
+	UserID   ids.UserID `json:"userId"`
+	UserName string     `json:"userName"`
+	Requests int        `json:"requests"`
+}
+
+// MyAPIUsageResponse is the body of GET /users/me/api-usage
+type MyAPIUsageResponse struct {
+	Since      string             `json:"since"`      // first day covered, YYYY-MM-DD
+	SampleRate int                `json:"sampleRate"` // one request in sampleRate was counted
+	Requests   int                `json:"requests"`   // estimated, in all
+	Endpoints  []APIUsageEndpoint `json:"endpoints"`  // the most requested first
+	Days       []APIUsageDay      `json:"days"`       // the days with requests, oldest first
+}
+
+// AdminAPIUsageResponse is the body of GET /admin/api-usage
+type AdminAPIUsageResponse struct {
+	Since      string             `json:"since"`
+	SampleRate int                `json:"sampleRate"`
+	Requests   int                `json:"requests"`
+	Endpoints  []APIUsageEndpoint `json:"endpoints"` // the most requested first
+	Users      []APIUsageUser     `json:"users"`     // the heaviest first
+}
+
+// apiUsageRecorder holds the counts not written yet
+type apiUsageRecorder struct {
+	db     database.AppDatabase
+	config func() *Config
+	mu     sync.Mutex
+	counts map[database.APIUsageKey]int
+}
+
+// newAPIUsageRecorder returns the recorder; New starts it
+func newAPIUsageRecorder(db database.AppDatabase, config func() *Config) *apiUsageRecorder {
+	return &apiUsageRecorder{db: db, config: config, counts: make(map[database.APIUsageKey]int)}
+}
+
+// add counts requests
+func (ar *apiUsageRecorder) add(key database.APIUsageKey, requests int) {
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+	ar.counts[key] += requests
+}
+
+// run writes the counts every minute until ctx is cancelled, then
+// writes what is left
+func (ar *apiUsageRecorder) run(ctx context.Context) {
+	ticker := time.NewTicker(apiUsageFlushInterval)
+	defer ticker.Stop()
+
+	work := context.WithoutCancel(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			ar.flush(work)
+			return
+		case <-ticker.C:
+			ar.flush(work)
+		}
+	}
+}
+
+// flush writes the counts and drops those past the retention. Counts
+// that could not be written are kept for the next flush.
+func (ar *apiUsageRecorder) flush(ctx context.Context) {
+	ar.mu.Lock()
+	counts := ar.counts
+	ar.counts = make(map[database.APIUsageKey]int)
+	ar.mu.Unlock()
+	if len(counts) == 0 {
+		return
+	}
+
+	var oldestDay string
+	if retention := ar.config().APIUsage.Retention; retention > 0 {
+		oldestDay = database.UsageDay(time.Now().Add(-retention))
+	}
+	if err := ar.db.RecordAPIUsage(ctx, counts, oldestDay); err != nil {
+		log.Printf("Error recording the API usage: %v", err)
+		for key, requests := range counts {
+			ar.add(key, requests)
+		}
+	}
+}
+
+// APIUsageMiddleware samples the requests of the authenticated users
+func (h *Handler) APIUsageMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rate := h.config().APIUsage.SampleRate
+		userID := getUserIDFromAuth(r)
+		if rate > 0 && userID != "" && r.Method != http.MethodOptions && rand.IntN(rate) == 0 {
+			if route := mux.CurrentRoute(r); route != nil {
+				if template, err := route.GetPathTemplate(); err == nil && template != "/" {
+					h.apiUsage.add(database.APIUsageKey{
+						UserID: userID,
+						Day:    database.UsageDay(time.Now()),
+						Method: r.Method,
+						Route:  template,
+					}, rate)
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// parseAPIUsageDays reads ?days= and returns the first day covered; it
+// answers 400 itself
+func parseAPIUsageDays(w http.ResponseWriter, r *http.Request) (string, bool) {
+	days := defaultAPIUsageDays
+	if value := r.URL.Query().Get("days"); value != "" {
+		requested, err := strconv.Atoi(value)
+		if err != nil || requested < 1 || requested > maxAPIUsageDays {
+			http.Error(w, "Invalid days (1 to "+strconv.Itoa(maxAPIUsageDays)+")", http.StatusBadRequest)
+			return "", false
+		}
+		days = requested
+	}
+	// Today is the last of the days
+	return database.UsageDay(time.Now().AddDate(0, 0, 1-days)), true
+}
+
+/*
+GetMyAPIUsage handles GET /users/me/api-usage
+operationId: getMyApiUsage
+
+Returns the estimated requests the user sent over the last "days" days
+(7 by default, today included), per route and per day.
+*/
+func (h *Handler) GetMyAPIUsage(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Parse the period
+	since, ok := parseAPIUsageDays(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 3: Get the counts of the user
+	rows, err := h.db.GetAPIUsage(r.Context(), authUserID, since)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 4: Sum them per route and per day; the rows come by day
+	response := MyAPIUsageResponse{
+		Since:      since,
+		SampleRate: h.config().APIUsage.SampleRate,
+		Endpoints:  []APIUsageEndpoint{},
+		Days:       []APIUsageDay{},
+	}
+	endpoints := make(map[[2]string]int)
+	for _, row := range rows {
+		response.Requests += row.Requests
+		endpoints[[2]string{row.Method, row.Route}] += row.Requests
+		if n := len(response.Days); n > 0 && response.Days[n-1].Day == row.Day {
+			response.Days[n-1].Requests += row.Requests
+		} else {
+			response.Days = append(response.Days, APIUsageDay{Day: row.Day, Requests: row.Requests})
+		}
+	}
+	for endpoint, requests := range endpoints {
+		response.Endpoints = append(response.Endpoints, APIUsageEndpoint{Method: endpoint[0], Route: endpoint[1], Requests: requests})
+	}
+	sortAPIUsageEndpoints(response.Endpoints)
+
+	// Step 5: Return the usage
+	writeJSON(w, http.StatusOK, response)
+}
+
+/*
+GetAdminAPIUsage handles GET /admin/api-usage
+operationId: getAdminApiUsage
+
+Returns the estimated requests of all users over the last "days" days
+(7 by default) per route, and the "limit" users (20 by default) who
+sent the most.
+*/
+func (h *Handler) GetAdminAPIUsage(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check admin authentication
+	if !h.isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Step 2: Parse the period and the limit
+	since, ok := parseAPIUsageDays(w, r)
+	if !ok {
+		return
+	}
+	limit := defaultAPIUsageUsers
+	if value := r.URL.Query().Get("limit"); value != "" {
+		requested, err := strconv.Atoi(value)
+		if err != nil || requested < 1 || requested > maxAPIUsageUsers {
+			http.Error(w, "Invalid limit (1 to "+strconv.Itoa(maxAPIUsageUsers)+")", http.StatusBadRequest)
+			return
+		}
+		limit = requested
+	}
+
+	// Step 3: Get the totals and the heaviest users
+	totals, err := h.db.GetAPIUsageTotals(r.Context(), since)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	users, err := h.db.GetTopAPIUsers(r.Context(), since, limit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 4: Convert to response format
+	response := AdminAPIUsageResponse{
+		Since:      since,
+		SampleRate: h.config().APIUsage.SampleRate,
+		Endpoints:  make([]APIUsageEndpoint, 0, len(totals)),
+		Users:      make([]APIUsageUser, 0, len(users)),
+	}
+	for _, total := range totals {
+		response.Requests += total.Requests
+		response.Endpoints = append(response.Endpoints, APIUsageEndpoint{
+			Method:   total.Method,
+			Route:    total.Route,
+			Requests: total.Requests,
+			Users:    total.Users,
+		})
+	}
+	for _, user := range users {
+		response.Users = append(response.Users, APIUsageUser{UserID: user.UserID, UserName: user.UserName, Requests: user.Requests})
+	}
+
+	// Step 5: Return the usage
+	writeJSON(w, http.StatusOK, response)
+}
+
+// sortAPIUsageEndpoints puts the most requested routes first
+func sortAPIUsageEndpoints(endpoints []APIUsageEndpoint) {
+	slices.SortFunc(endpoints, func(a, b APIUsageEndpoint) int {
+		if a.Requests != b.Requests {
+			return b.Requests - a.Requests
+		}
+		return strings.Compare(a.Route+" "+a.Method, b.Route+" "+b.Method)
+	})
+}
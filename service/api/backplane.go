@@ -0,0 +1,258 @@
+/*
+Event backplane over Redis pub/sub.
+
+The clients of the real-time events (GET /ws, GET /events and the gRPC
+message streams) only get the events of the server instance they are
+connected to. Behind a load balancer, the participants of a
+conversation may be connected to other instances than the one that
+took the message: with a backplane configured (backplane.redisUrl),
+every instance publishes the events it delivers to a Redis channel,
+and delivers to its own clients those the other instances published.
+
+Each instance runs two workers: runBackplanePublish, which publishes
+the events deliver queued, in order, on one connection, and
+runBackplaneSubscribe, which receives those of the other instances on
+another (an instance skips its own). Both reconnect after a failure,
+and when the configuration changes. The backplane is best effort, like
+the events themselves: an event published while Redis is unreachable
+is lost, and the clients catch up by fetching GET /conversations, as
+after any disconnect.
+
+Only the events cross instances. The presence of the users, the typing
+indicators they may send and the events kept for the clients resuming
+GET /events (see sse.go) stay with each instance: a client resuming on
+another instance gets a resync event.
+*/
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"wasatext/service/ids"
+	"wasatext/service/redis"
+)
+
+const (
+	backplaneIdle       = 30 * time.Second // how long the subscriber waits for an event before pinging Redis
+	backplaneRetryDelay = 5 * time.Second  // the delay before reconnecting after a failure
+	backplaneQueueSize  = 1024             // events waiting to be published
+
+	// DefaultBackplaneChannel is the channel of the events when none is configured
+	DefaultBackplaneChannel = "wasatext:events"
+)
+
+// BackplaneConfig sets up the event backplane
+type BackplaneConfig struct {
+	// RedisURL is the Redis server, e.g. redis://:password@redis:6379,
+	// or rediss:// over TLS. The backplane is off when it is empty, the
+	// default.
+	RedisURL string
+
+	// Channel is the pub/sub channel of the events, the same for every
+	// instance of a deployment
+	Channel string
+}
+
+// enabled reports whether the backplane is on
+func (c BackplaneConfig) enabled() bool {
+	return c.RedisURL != ""
+}
+
+// Validate checks the URL of the Redis server
+func (c BackplaneConfig) Validate() error {
+	if c.RedisURL == "" {
+		return nil
+	}
+	u, err := url.Parse(c.RedisURL)
+	if err != nil || u.Scheme != "redis" && u.Scheme != "rediss" || u.Hostname() == "" {
+		return errors.New("the Redis URL must be redis://[[user]:password@]host[:port][/db] or rediss://...")
+	}
+	return nil
+}
+
+// backplaneEvent is an event published to the other instances
+type backplaneEvent struct {
+	Origin  string          `json:"origin"` // the instance that published it
+	UserIDs []ids.UserID    `json:"userIds"`
+	Event   json.RawMessage `json:"event"`
+}
+
+// backplane is the state of the two workers
+type backplane struct {
+	instance   string // identifies the events of this instance
+	queue      chan backplaneEvent
+	subscribed atomic.Bool // the subscriber is connected
+}
+
+func newBackplane() *backplane {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	return &backplane{instance: hex.EncodeToString(buf), queue: make(chan backplaneEvent, backplaneQueueSize)}
+}
+
+// channel returns the configured channel, or the default one
+func (c BackplaneConfig) channel() string {
+	if c.Channel == "" {
+		return DefaultBackplaneChannel
+	}
+	return c.Channel
+}
+
+// deliver sends an event to the clients of the users: those of this
+// instance, and through the backplane those of the others
+func (h *Handler) deliver(userIDs []ids.UserID, data []byte) {
+	h.hub.sendTo(userIDs, data)
+	if !h.config().Backplane.enabled() {
+		return
+	}
+	select {
+	case h.backplane.queue <- backplaneEvent{Origin: h.backplane.instance, UserIDs: userIDs, Event: data}:
+	default:
+		log.Printf("Event backplane: queue full, an event was not published")
+	}
+}
+
+// unheard reports whether no client may get an event: none is connected
+// to this instance, and there is no backplane to the others
+func (h *Handler) unheard() bool {
+	return h.hub.empty() && !h.config().Backplane.enabled()
+}
+
+// runBackplanePublish publishes the queued events until ctx is
+// cancelled; those still queued are dropped
+func (h *Handler) runBackplanePublish(ctx context.Context) {
+	var conn *redis.Conn
+	var connCfg BackplaneConfig
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+	for {
+		var event backplaneEvent
+		select {
+		case <-ctx.Done():
+			return
+		case event = <-h.backplane.queue:
+		}
+		cfg := h.config().Backplane
+		if conn != nil && cfg != connCfg {
+			conn.Close()
+			conn = nil
+		}
+		if !cfg.enabled() {
+			continue
+		}
+		data, err := json.Marshal(event)
+		if err != nil {
+			log.Printf("Event backplane: encoding an event: %v", err)
+			continue
+		}
+
+		// One more try on a new connection: the one kept may have been
+		// closed by Redis while idle
+		for attempt := 0; attempt < 2; attempt++ {
+			if conn == nil {
+				if conn, err = redis.Dial(ctx, cfg.RedisURL); err != nil {
+					break
+				}
+				connCfg = cfg
+			}
+			if _, err = conn.Publish(ctx, cfg.channel(), data); err == nil {
+				break
+			}
+			conn.Close()
+			conn = nil
+		}
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Event backplane: an event was not published: %v", err)
+			backplanePause(ctx, backplaneRetryDelay)
+		}
+	}
+}
+
+// runBackplaneSubscribe delivers the events of the other instances to
+// the clients of this one until ctx is cancelled
+func (h *Handler) runBackplaneSubscribe(ctx context.Context) {
+	for ctx.Err() == nil {
+		cfg := h.config().Backplane
+		if !cfg.enabled() {
+			backplanePause(ctx, backplaneIdle)
+			continue
+		}
+		err := h.receiveBackplane(ctx, cfg)
+		h.backplane.subscribed.Store(false)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Event backplane: %v", err)
+			backplanePause(ctx, backplaneRetryDelay)
+		}
+	}
+}
+
+// receiveBackplane subscribes to the channel and delivers its events,
+// until the connection fails or the configuration changes (nil)
+func (h *Handler) receiveBackplane(ctx context.Context, cfg BackplaneConfig) error {
+	conn, err := redis.Dial(ctx, cfg.RedisURL)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	if err := conn.Subscribe(ctx, cfg.channel()); err != nil {
+		return err
+	}
+	h.backplane.subscribed.Store(true)
+
+	pinged := false
+	for h.config().Backplane == cfg {
+		msg, err := conn.Receive(backplaneIdle)
+		if errors.Is(err, redis.ErrIdle) {
+			// A second silence after a ping: the connection is gone
+			if pinged {
+				return errors.New("no answer from Redis to a ping")
+			}
+			if err := conn.Ping(); err != nil {
+				return err
+			}
+			pinged = true
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		pinged = false
+		if msg.Pong {
+			continue
+		}
+
+		var event backplaneEvent
+		if err := json.Unmarshal(msg.Payload, &event); err != nil {
+			log.Printf("Event backplane: skipping a malformed event: %v", err)
+			continue
+		}
+		if event.Origin == h.backplane.instance {
+			continue
+		}
+		h.hub.sendTo(event.UserIDs, event.Event)
+	}
+	return nil
+}
+
+// backplanePause waits for d, or until ctx is cancelled
+func backplanePause(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
@@ -0,0 +1,71 @@
+/*
+User blocking API handlers.
+
+This file contains:
+- blockUser: Block another user
+- unblockUser: Lift the block
+
+A blocked user can no longer start a direct conversation with the user
+who blocked them, nor send messages in the one they share.
+*/
+package api
+
+import (
+	"net/http"
+)
+
+/*
+BlockUser handles PUT /users/{userId}/block
+operationId: blockUser
+
+Blocks the user of the path; blocking a user twice is not an error.
+*/
+func (h *Handler) BlockUser(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Block the user
+	userID, ok := pathUserID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.db.BlockUser(r.Context(), authUserID, userID); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 3: Return success (204 No Content)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+UnblockUser handles DELETE /users/{userId}/block
+operationId: unblockUser
+
+Lifts the block on the user of the path, if there is one.
+*/
+func (h *Handler) UnblockUser(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Lift the block
+	userID, ok := pathUserID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.db.UnblockUser(r.Context(), authUserID, userID); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 3: Return success (204 No Content)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,396 @@
+/*
+Bot API handlers.
+
+Bots are accounts run by programs: reminder bots, bridges to other
+chats, ... The admin of the server creates a bot in a workspace and
+hands its token to the program; users then add the bot to their groups,
+or start a conversation with it, like with anyone (see
+database/bots.go). The bot posts to the conversations it takes part in
+with POST /bots/{botId}/messages, and is told of their new messages and
+members by its webhook, signed like the other outbound webhooks (see
+webhooks.go). Its token only works for the /bots/{botId} endpoints.
+
+This file contains:
+- createBot: Create a bot account (admin)
+- listBots: List the bots (admin)
+- deleteBot: Delete a bot (admin)
+- resetBotToken: Give a bot a new token (admin)
+- postBotMessage: Post a message as a bot
+- setBotWebhook: Set the webhook of a bot
+- deleteBotWebhook: Remove the webhook of a bot
+- getBotWebhookDeliveries: The last deliveries of the webhook of a bot
+*/
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"wasatext/service/database"
+	"wasatext/service/events"
+	"wasatext/service/ids"
+
+	"github.com/gorilla/mux"
+)
+
+// maxBotMessageLength is the longest message a bot posts, in bytes
+const maxBotMessageLength = 4000
+
+// CreateBotRequest is the body of POST /admin/bots
+type CreateBotRequest struct {
+	Name      string `json:"name"`
+	Workspace string `json:"workspace,omitempty"` // the default workspace if omitted
+}
+
+// BotResponse is a bot account
+type BotResponse struct {
+	BotID      ids.UserID `json:"botId"` // its user ID, to add it to groups
+	Name       string     `json:"name"`
+	Workspace  string     `json:"workspace"`
+	Token      string     `json:"token,omitempty"`      // only when created or its token is reset
+	WebhookURL string     `json:"webhookUrl,omitempty"` // where it is told of events, if set
+	CreatedAt  string     `json:"createdAt"`
+}
+
+// BotTokenResponse is the response of POST /admin/bots/{botId}/token
+type BotTokenResponse struct {
+	Token string `json:"token"`
+}
+
+// PostBotMessageRequest is the body of POST /bots/{botId}/messages
+type PostBotMessageRequest struct {
+	ConversationID string `json:"conversationId"`
+	Content        string `json:"content"`
+	ReplyTo        string `json:"replyTo,omitempty"`
+}
+
+// botResponse converts a bot to its response format
+func botResponse(bot database.Bot) BotResponse {
+	return BotResponse{
+		BotID:      bot.ID,
+		Name:       bot.Name,
+		Workspace:  bot.WorkspaceID,
+		Token:      bot.Token,
+		WebhookURL: bot.WebhookURL,
+		CreatedAt:  bot.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+/*
+CreateBot handles POST /admin/bots
+operationId: createBot
+
+Creates a bot account in a workspace, named like a user (3-16
+characters), and returns it with its token, which is only shown here.
+*/
+func (h *Handler) CreateBot(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check admin authentication
+	if !h.isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Step 2: Parse and validate the request
+	var req CreateBotRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.Name) < minUserNameLength || len(req.Name) > maxUserNameLength {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{
+			Message: "Username must be between 3 and 16 characters",
+		})
+		return
+	}
+	workspaceID := req.Workspace
+	if workspaceID == "" {
+		workspaceID = database.DefaultWorkspaceID
+	}
+
+	// Step 3: Create the bot
+	bot, err := h.db.CreateBot(r.Context(), workspaceID, req.Name)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	h.infof("Bot %s created in workspace %s", bot.ID, workspaceID)
+	writeJSON(w, http.StatusCreated, botResponse(*bot))
+}
+
+/*
+ListBots handles GET /admin/bots
+operationId: listBots
+
+Lists the bots of every workspace, the oldest first, without their tokens.
+*/
+func (h *Handler) ListBots(w http.ResponseWriter, r *http.Request) {
+	if !h.isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	bots, err := h.db.ListBots(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	response := make([]BotResponse, 0, len(bots))
+	for _, bot := range bots {
+		response = append(response, botResponse(bot))
+	}
+	writePage(w, r, response)
+}
+
+/*
+DeleteBot handles DELETE /admin/bots/{botId}
+operationId: deleteBot
+
+Deletes a bot: its token stops working at once, its webhook is removed,
+and its account is purged like a deleted user's.
+*/
+func (h *Handler) DeleteBot(w http.ResponseWriter, r *http.Request) {
+	if !h.isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	botID, ok := pathBotID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.db.DeleteBot(r.Context(), botID); err != nil {
+		writeError(w, err)
+		return
+	}
+	h.infof("Bot %s deleted", botID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+ResetBotToken handles POST /admin/bots/{botId}/token
+operationId: resetBotToken
+
+Gives a bot a new token, for a token that leaked; the old one stops
+working at once.
+*/
+func (h *Handler) ResetBotToken(w http.ResponseWriter, r *http.Request) {
+	if !h.isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	botID, ok := pathBotID(w, r)
+	if !ok {
+		return
+	}
+	token, err := h.db.ResetBotToken(r.Context(), botID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, BotTokenResponse{Token: token})
+}
+
+/*
+PostBotMessage handles POST /bots/{botId}/messages
+operationId: postBotMessage
+
+Posts a text message as the bot to a conversation it takes part in. The
+anti-spam limits and the quotas apply to bots as to users.
+*/
+func (h *Handler) PostBotMessage(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check the bot token
+	botID, ok := h.botAuth(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 2: Parse and validate the request
+	var req PostBotMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	conversationID, err := ids.ParseConversationID(req.ConversationID)
+	if err != nil {
+		http.Error(w, "Invalid conversation ID", http.StatusBadRequest)
+		return
+	}
+	var replyTo *ids.MessageID
+	if req.ReplyTo != "" {
+		replyToID, err := ids.ParseMessageID(req.ReplyTo)
+		if err != nil {
+			http.Error(w, "Invalid replyTo", http.StatusBadRequest)
+			return
+		}
+		replyTo = &replyToID
+	}
+	content := strings.TrimSpace(req.Content)
+	if content == "" || len(content) > maxBotMessageLength {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Message: "content must be between 1 and 4000 characters"})
+		return
+	}
+
+	// Step 3: Check that the bot takes part in the conversation
+	if _, err := h.db.GetConversation(r.Context(), botID, conversationID); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 4: Apply the anti-spam limits and the fair-use quotas
+	if !h.checkThrottle(r.Context(), w, botID) || !h.checkMessageFlood(r.Context(), w, botID, conversationID, content) ||
+		!h.checkUsage(r.Context(), w, botID, 1, 0) {
+		return
+	}
+
+	// Step 5: Create the message, push it to the participants and return it
+	response, err := h.createBotMessage(r.Context(), botID, conversationID, content, nil, replyTo)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	h.recordUsage(r.Context(), botID, 1, 0)
+	writeJSON(w, http.StatusCreated, response)
+}
+
+// createBotMessage creates a message of a bot, photo optional, and
+// pushes it to the participants; the checks are the caller's
+func (h *Handler) createBotMessage(ctx context.Context, botID ids.UserID, conversationID ids.ConversationID, content string, photo []byte, replyTo *ids.MessageID) (MessageResponse, error) {
+	msg, err := h.db.CreateMessage(ctx, conversationID, botID, content, photo, replyTo)
+	if err != nil {
+		return MessageResponse{}, err
+	}
+	h.fanout.enqueue(msg)
+	if msg.PhotoID != "" {
+		h.media.poke()
+	}
+	h.flagFilteredMessage(ctx, msg, conversationID)
+
+	response := MessageResponse{
+		MessageID:      msg.ID,
+		SenderID:       msg.SenderID,
+		SenderName:     msg.SenderName,
+		Content:        msg.Content,
+		Language:       msg.Language,
+		HasPhoto:       msg.PhotoID != "",
+		PhotoURL:       h.photoURL(mediaMessage, string(msg.ID), msg.PhotoID),
+		PhotoState:     msg.PhotoState,
+		Timestamp:      msg.Timestamp.Format("2006-01-02T15:04:05Z07:00"),
+		Status:         msg.Status,
+		Mentions:       mentionResponses(msg.Mentions),
+		Comments:       []CommentResponse{},
+		ReactionCounts: []ReactionCount{},
+	}
+	response.ReplyTo, response.Reply = replyFields(*msg)
+	h.bus.Publish(ctx, events.MessageCreated{Message: *msg})
+	return response, nil
+}
+
+/*
+SetBotWebhook handles PUT /bots/{botId}/webhook
+operationId: setBotWebhook
+
+Sets the public https URL the bot is told of events at: message.created
+and group.member_added in the conversations it takes part in, but for
+its own messages. It replaces the webhook the bot had, with its log,
+and returns the new secret, which is only shown here.
+*/
+func (h *Handler) SetBotWebhook(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check the bot token
+	botID, ok := h.botAuth(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 2: Parse and validate the request
+	var req CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	events, ok := checkWebhookRequest(w, req, true, groupWebhookEvents)
+	if !ok {
+		return
+	}
+
+	// Step 3: Set the webhook and return it with its secret
+	webhook, err := h.db.SetBotWebhook(r.Context(), botID, req.URL, events)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	response := webhookResponse(*webhook)
+	response.Secret = webhook.Secret
+	writeJSON(w, http.StatusOK, response)
+}
+
+/*
+DeleteBotWebhook handles DELETE /bots/{botId}/webhook
+operationId: deleteBotWebhook
+
+Removes the webhook of the bot, with its pending deliveries.
+*/
+func (h *Handler) DeleteBotWebhook(w http.ResponseWriter, r *http.Request) {
+	botID, ok := h.botAuth(w, r)
+	if !ok {
+		return
+	}
+	if err := h.db.DeleteBotWebhook(r.Context(), botID); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+GetBotWebhookDeliveries handles GET /bots/{botId}/webhook/deliveries
+operationId: getBotWebhookDeliveries
+
+Returns the last deliveries of the webhook of the bot, the most recent first.
+*/
+func (h *Handler) GetBotWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	botID, ok := h.botAuth(w, r)
+	if !ok {
+		return
+	}
+	deliveries, err := h.db.GetBotWebhookDeliveries(r.Context(), botID, webhookLogLimit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writePage(w, r, webhookDeliveryResponses(deliveries))
+}
+
+// botAuth checks that the bearer token is the token of the bot of
+// {botId}, and returns the bot; it answers 401 or 403 itself
+func (h *Handler) botAuth(w http.ResponseWriter, r *http.Request) (ids.UserID, bool) {
+	botID, ok := pathBotID(w, r)
+	if !ok {
+		return "", false
+	}
+	tokenBot, err := h.db.GetBotByToken(r.Context(), getBearerToken(r))
+	if errors.Is(err, database.ErrBotNotFound) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return "", false
+	}
+	if err != nil {
+		writeError(w, err)
+		return "", false
+	}
+	if tokenBot != botID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return "", false
+	}
+	return botID, true
+}
+
+// pathBotID reads {botId}; it answers 400 itself
+func pathBotID(w http.ResponseWriter, r *http.Request) (ids.UserID, bool) {
+	id, err := ids.ParseUserID(mux.Vars(r)["botId"])
+	if err != nil {
+		http.Error(w, "Invalid bot ID", http.StatusBadRequest)
+		return "", false
+	}
+	return id, true
+}
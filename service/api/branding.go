@@ -0,0 +1,172 @@
+/*
+Branding API handlers.
+
+This file contains:
+- getBranding: The branding set by the admin
+- setBranding: Set the application name and the accent color
+- setBrandingLogo: Upload the logo
+- deleteBrandingLogo: Remove the logo
+
+The branding is kept in the database (see database/settings.go), so the
+admin changes it without touching the configuration file. The frontend
+gets it from GET /config.js; the webui.branding of the configuration
+file is the default, and its per-workspace branding still wins.
+*/
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"wasatext/service/database"
+)
+
+// maxAppNameLength is the longest application name, in characters
+const maxAppNameLength = 32
+
+// accentColorPattern is a CSS hex color
+var accentColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// SetBrandingRequest is the body of PUT /admin/branding
+type SetBrandingRequest struct {
+	AppName     string `json:"appName"`
+	AccentColor string `json:"accentColor"`
+}
+
+// BrandingResponse is the branding set by the admin
+type BrandingResponse struct {
+	AppName     string `json:"appName,omitempty"`
+	AccentColor string `json:"accentColor,omitempty"`
+	HasLogo     bool   `json:"hasLogo"`
+	LogoURL     string `json:"logoUrl,omitempty"` // signed, short-lived (see media.go)
+}
+
+// brandingResponse converts the branding to its response format
+func (h *Handler) brandingResponse(b database.Branding) BrandingResponse {
+	return BrandingResponse{
+		AppName:     b.AppName,
+		AccentColor: b.AccentColor,
+		HasLogo:     b.LogoID != "",
+		LogoURL:     h.photoURL(mediaBranding, b.LogoID, b.LogoID),
+	}
+}
+
+/*
+GetBranding handles GET /admin/branding
+operationId: getBranding
+
+Returns the branding set by the admin; what is not set is left out.
+*/
+func (h *Handler) GetBranding(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check admin authentication
+	if !h.isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Step 2: Get and return the branding
+	branding, err := h.db.GetBranding(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, h.brandingResponse(*branding))
+}
+
+/*
+SetBranding handles PUT /admin/branding
+operationId: setBranding
+
+Sets the application name and the accent color; an empty value goes
+back to the default.
+*/
+func (h *Handler) SetBranding(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check admin authentication
+	if !h.isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Step 2: Parse and validate the request
+	var req SetBrandingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	appName := strings.TrimSpace(req.AppName)
+	if utf8.RuneCountInString(appName) > maxAppNameLength {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{
+			Message: "appName must be at most " + strconv.Itoa(maxAppNameLength) + " characters",
+		})
+		return
+	}
+	if req.AccentColor != "" && !accentColorPattern.MatchString(req.AccentColor) {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Message: "accentColor must be a color like #1a7f5a"})
+		return
+	}
+
+	// Step 3: Save and return the branding
+	branding, err := h.db.SetBranding(r.Context(), appName, strings.ToLower(req.AccentColor))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, h.brandingResponse(*branding))
+}
+
+/*
+SetBrandingLogo handles PUT /admin/branding/logo
+operationId: setBrandingLogo
+
+Uploads the logo (the image in the request body), replacing the
+previous one.
+*/
+func (h *Handler) SetBrandingLogo(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check admin authentication
+	if !h.isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Step 2: Read the logo from request body
+	logo, ok := h.readPhoto(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 3: Save it and return the branding
+	branding, err := h.db.SetBrandingLogo(r.Context(), logo)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	h.media.poke()
+	writeJSON(w, http.StatusOK, h.brandingResponse(*branding))
+}
+
+/*
+DeleteBrandingLogo handles DELETE /admin/branding/logo
+operationId: deleteBrandingLogo
+
+Removes the logo.
+*/
+func (h *Handler) DeleteBrandingLogo(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check admin authentication
+	if !h.isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Step 2: Remove the logo
+	if err := h.db.DeleteBrandingLogo(r.Context()); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 3: Return success (204 No Content)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,203 @@
+/*
+API changelog and route deprecations.
+
+Client developers follow the API through GET /api/changelog. Every
+change to the API adds an entry to changelog below (newest release
+first), and a route that is going away is listed in deprecatedRoutes:
+from then on its responses carry the Deprecation and Sunset headers
+(RFC 9745, RFC 8594) and a link to its replacement.
+*/
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// APIVersion is the current version of the API (info.version in doc/api.yaml)
+const APIVersion = "1.1.0"
+
+// Kinds of change
+const (
+	ChangeAdded      = "added"
+	ChangeChanged    = "changed"
+	ChangeDeprecated = "deprecated"
+	ChangeRemoved    = "removed"
+)
+
+// ChangeEntry is one change to the API
+type ChangeEntry struct {
+	Kind        string `json:"kind"` // added, changed, deprecated, removed
+	Breaking    bool   `json:"breaking"`
+	Description string `json:"description"`
+}
+
+// Release groups the changes of one API version
+type Release struct {
+	Version string        `json:"version"`
+	Date    string        `json:"date"`
+	Changes []ChangeEntry `json:"changes"`
+}
+
+// Deprecation describes a route that will be removed
+type Deprecation struct {
+	Method      string    `json:"method"`
+	Path        string    `json:"path"` // route template, e.g. /users/{userId}
+	Since       time.Time `json:"since"`
+	Sunset      time.Time `json:"sunset"` // when the route stops working
+	Replacement string    `json:"replacement,omitempty"`
+}
+
+// ChangelogResponse is the body of GET /api/changelog
+type ChangelogResponse struct {
+	Version      string        `json:"version"`
+	Releases     []Release     `json:"releases"`
+	Deprecations []Deprecation `json:"deprecations"`
+}
+
+// changelog lists the API releases, newest first
+var changelog = []Release{
+	{Version: "1.1.0", Date: "2026-10-16", Changes: []ChangeEntry{
+		{ChangeAdded, false, "Event backplane (backplane.redisUrl, WASATEXT_REDIS_URL): the instances of the server behind a load balancer relay their real-time events through Redis pub/sub, so that clients of GET /ws, GET /events and StreamMessages get the events of every instance; GET /status reports it as the backplane component."},
+		{ChangeAdded, false, "GET /events streams the events of GET /ws as Server-Sent Events, for clients that cannot keep a WebSocket open; a client reconnecting with Last-Event-ID gets the events it missed, or a resync event when they are no longer known."},
+		{ChangeAdded, false, "Matrix bridge (matrix in the configuration): bridged conversations relay their text and photos to a Matrix room, and the messages of the room are posted by a bot account under the name of their Matrix sender."},
+		{ChangeAdded, false, "gRPC API on a second port (api.grpcPort, WASATEXT_GRPC_PORT): the WASAText service of doc/wasatext.proto mirrors GET /conversations, GET /conversations/{conversationId} and POST /conversations/{conversationId}/messages, and StreamMessages streams the new messages like GET /ws."},
+		{ChangeAdded, false, "POST /graphql runs read-only GraphQL queries over users, conversations, messages and reactions, returning only the fields selected, e.g. the conversations with their last 20 messages and comments in one request; GET /graphql/schema returns the schema."},
+		{ChangeAdded, false, "Bot accounts: POST /admin/bots creates a bot with its own token, which posts with POST /bots/{botId}/messages to the conversations it was added to and is told of their events by PUT /bots/{botId}/webhook. Users carry bot in GET /users/{userId}."},
+		{ChangeAdded, false, "Outbound webhooks: POST /groups/{groupId}/webhooks (group admin) and POST /admin/webhooks (global) register URLs told of message.created, group.member_added and user.registered by signed POSTs, retried with backoff; .../deliveries is the delivery log."},
+		{ChangeAdded, false, "POST /admin/imports/whatsapp imports a WhatsApp chat export as a group, with its original times, its photos and placeholder accounts for the senders without one."},
+		{ChangeAdded, false, "GET /users/{userId} returns the profile of a user of the workspace, with about and presence."},
+		{ChangeAdded, false, "PUT /users/{userId}/about sets a status line of up to 139 characters, returned as about with the user in searches and member lists."},
+		{ChangeAdded, false, "Users in searches and member lists carry online and lastSeen; lastSeen is saved and survives restarts. GET and PUT /users/me/privacy hide the user's presence (hidePresence)."},
+		{ChangeAdded, false, "Web Push notifications of new messages to offline participants: GET /push/vapid-key, POST, GET and DELETE /push/subscriptions; GET, PUT and DELETE /conversations/{conversationId}/mute silence a conversation."},
+		{ChangeAdded, false, "Adding, joining, leaving and renaming a group post a system message; such messages carry notice (kind, userId, userName, name) for clients to render."},
+		{ChangeAdded, false, "GET /status reports without authentication the version, uptime, messages of the last hour and health of the components, cached for 30 seconds."},
+		{ChangeAdded, false, "GET /groups/{groupId} returns a group, PUT /groups/{groupId}/description sets its description; groups carry description and createdAt, and their members a role (admin or member)."},
+		{ChangeAdded, false, "GET, PUT and DELETE /users/me/key-backup keep an encrypted key backup per user, opaque to the server, so that clients encrypting end to end restore their keys on a new device; replaces makes an upload fail with 409 if another device backed up in between."},
+		{ChangeAdded, false, "PUT and DELETE /conversations/{conversationId}/messages/{messageId}/pin pin and unpin messages, GET /conversations/{conversationId}/pins lists them, pinned messages carry pinned: true and changes are pushed as pin events."},
+		{ChangeAdded, false, "PUT /groups/{groupId}/governance puts a group under governance: its members vote on renaming it and removing members with POST, GET /groups/{groupId}/proposals and PUT .../proposals/{proposalId}/vote, and PUT /groups/{groupId}/name answers 409. Groups carry governance."},
+		{ChangeAdded, false, "POST /users/me/deactivate deactivates the account until the next login: the user is hidden from the search and shown deactivated in the members and the conversation list."},
+		{ChangeAdded, false, "Messages list the participants their text mentions with @username in mentions, and GET /mentions lists the messages mentioning the user."},
+		{ChangeChanged, true, "A user can react to a message with several distinct emoticons: POST .../comments adds a reaction instead of replacing theirs, DELETE .../comments/{emoticon} removes one and DELETE .../comments all of them. Messages, GET /conversations/{conversationId}/reactions and reaction events carry the counts per emoticon."},
+		{ChangeAdded, false, "GET /conversations?forwardableFor={messageId} lists only the conversations the message can be forwarded into, for the forward picker."},
+		{ChangeAdded, false, "POST /conversations/{conversationId}/messages takes an optional sendAt: a text message for later is scheduled (202) and sent at that time; GET /users/me/scheduled-messages lists the pending ones and DELETE /users/me/scheduled-messages/{scheduledMessageId} cancels one."},
+		{ChangeChanged, false, "User, conversation, message and group IDs are UUIDs or, on deployments configured with database.idFormat short, 11-character base62 IDs; IDs of both formats are accepted everywhere."},
+		{ChangeAdded, false, "Every change to a message is appended to a hash chain per conversation; GET /admin/conversations/{conversationId}/integrity verifies it, and conversation exports print the hash of every message and the head of the chain."},
+		{ChangeAdded, false, "DELETE /admin/groups/{groupId} and DELETE /admin/conversations/{conversationId} hide a group or conversation from its members; POST .../restore gives it back until it is purged."},
+		{ChangeAdded, false, "GET /admin/deleted-conversations lists the deleted groups and conversations not purged yet, GET /admin/deletion-audit the deletions, restorations and purges."},
+		{ChangeAdded, false, "The photo endpoints take ?size=thumb|full, the same as ?quality=thumb|original."},
+		{ChangeChanged, false, "Thumbnails (?quality=thumb) are scaled to 320 pixels instead of 200."},
+		{ChangeAdded, false, "GET /users/me/api-usage returns the estimated requests of the user per route and per day, and GET /admin/api-usage those of all users with the heaviest users; requests are sampled."},
+		{ChangeChanged, true, "Uploaded photos must be JPEG, PNG, GIF or WebP images, read from their bytes: other files are answered 415, and photos wider or higher than maxPhotoDimension pixels (GET /config.js) 413. Their EXIF and other metadata are stripped, except the orientation."},
+		{ChangeAdded, false, "GET /admin/metrics returns the sizes of the database and write-ahead log files, their pages and the WAL checkpoints, in the Prometheus text format."},
+		{ChangeAdded, false, "GET /openapi.yaml returns the OpenAPI specification of the API, and GET /api/docs browses it."},
+		{ChangeAdded, false, "Requests are rate limited per user, or per address before logging in, with stricter limits on POST /session; over a limit any endpoint answers 429 with Retry-After."},
+		{ChangeAdded, false, "Opt-in fault injection (chaos in the configuration file) fails API requests, times out their database calls and drops WebSocket events, each marked with the X-Chaos-Fault header."},
+		{ChangeAdded, false, "Messages and search results carry the detected language of their text, and conversations the language of their latest messages; the searches take ?lang= to keep one language."},
+		{ChangeAdded, false, "POST /messages/{messageId}/remind sets a reminder on a message, delivered as a reminder WebSocket event; GET /users/me/reminders lists them and DELETE cancels or dismisses one."},
+		{ChangeAdded, false, "POST /conversations/{conversationId}/events posts an event in a group; members answer with PUT /messages/{messageId}/rsvp, messages carry an event summary, and a reminder is posted an hour before."},
+		{ChangeAdded, false, "With OCR configured (ocr.command), the message search also finds photos by the text they show."},
+		{ChangeAdded, false, "WebSocket clients acknowledge message events with {\"type\":\"ack\"}; the message is received, and its sender told, right away."},
+		{ChangeAdded, false, "/admin/branding and /admin/branding/logo set the application name, accent color and logo, kept in the database; GET /config.js now also carries accentColor and logoUrl."},
+		{ChangeAdded, false, "GET /config.js gives the web frontend the settings of the deployment (API base URL, feature flags, maxPhotoSize, branding, per workspace with ?workspace=); uploading a photo larger than maxPhotoSize (10 MB by default) is answered with 413."},
+		{ChangeAdded, false, "PUT and DELETE /users/{userId}/block block and unblock a user, who then cannot start a direct conversation with you or message you there (403); GET /users flags them with blocked, and GET /conversations takes hideBlocked=true."},
+		{ChangeChanged, false, "Lists inside a response (message comments, group members, conversation messages) are always arrays, [] when empty, never null."},
+		{ChangeChanged, true, "Every list endpoint (GET /conversations, /users, /workspaces, /channels, /users/me/notes, the hooks, widget tokens and warnings, and the admin lists) answers with {items, nextCursor, total} instead of a bare array, and pages with ?limit= and ?cursor=."},
+		{ChangeAdded, false, "New photos are processed in the background: photo messages carry processingState (pending, ready or failed) and GET /media/{mediaId}/status reports it through the signed photo URL."},
+		{ChangeAdded, false, "The photo endpoints and GET /media/{mediaId} take quality=original|high|medium|thumb to get a photo scaled down for small screens and slow connections."},
+		{ChangeAdded, false, "DELETE /conversations/{conversationId}/messages/{messageId} takes scope=me to hide any message from you only; with scope=everyone (the default) a message replies refer to stays as a tombstone with deleted set, pushed as messageEdited."},
+		{ChangeAdded, false, "POST and DELETE /messages/{messageId}/note attach a private note to a message or remove it; GET /users/me/notes lists your notes."},
+		{ChangeAdded, false, "POST /conversations/{conversationId}/clear hides the messages sent before a time, or all of them, from you only; GET /conversations/{conversationId} reports it as clearedBefore."},
+		{ChangeChanged, true, "GET /conversations/{conversationId} no longer marks the conversation as read; call the new PUT /conversations/{conversationId}/read (optionally with upToMessageId) once the user has seen the messages."},
+		{ChangeChanged, false, "Sending or editing a reply now returns its replyPreview, also carried by the real-time message event; a replyTo that is not in the conversation is answered with an unavailable preview instead of being echoed."},
+		{ChangeAdded, false, "GET /conversations/{conversationId}/stats/reactions ranks the most used emoticons, the users reacting the most and the most reacted messages, optionally over a date range."},
+		{ChangeAdded, false, "GET /conversations/{conversationId}/permissions returns what you may do in a conversation (send, rename, add members...), as the server decides it."},
+		{ChangeAdded, false, "POST /admin/maintenance also repairs the message search index, reported as searchIndexRepaired."},
+		{ChangeAdded, false, "Full-text message search: GET /messages/search?q= across your conversations and GET /conversations/{conversationId}/messages/search?q= in one, newest matches first with ?before= paging."},
+		{ChangeAdded, false, "Developer sandbox (off unless configured): POST /sandbox/reset reseeds the fixtures, and the X-Sandbox-Delay and X-Sandbox-Status headers fake latency and failures (code sandbox_fault)."},
+		{ChangeChanged, false, "The ETag of a photo is now its media ID: replacing a photo changes it, and revalidating does not read the photo."},
+		{ChangeAdded, false, "Widget tokens (feature widgetTokens): /conversations/{conversationId}/widget-tokens mints tokens that can only read that conversation, to embed it in another site."},
+		{ChangeAdded, false, "Presence: POST /presence/heartbeat (or an open /ws) marks a user online for a minute, GET /presence?userIds= tells who is online and when they were last seen."},
+		{ChangeAdded, false, "GET /users/{userId}/photo, GET /groups/{groupId}/photo and GET /conversations/{conversationId}/messages/{messageId}/photo return the photo bytes with an ETag and cache headers."},
+		{ChangeChanged, true, "Requests are authenticated with the session token returned by POST /session (token) instead of the user identifier, also as ?token= on /ws; DELETE /session logs out."},
+		{ChangeChanged, false, "In conversations of more than 256 participants, receipts are created in the background: a new message stays \"sent\" until they all exist, then its status converges as usual."},
+		{ChangeAdded, false, "Channel admins can publish an Atom feed of the latest messages (PUT /groups/{groupId}/feed, GET /channels/{groupId}/feed.atom); channels list publicFeed and feedUrl."},
+		{ChangeAdded, false, "GET /conversations/{conversationId}/messages/{messageId}/receipts lists, for the sender, when each recipient received and read the message."},
+		{ChangeAdded, false, "Inbound webhooks (feature webhooks): POST /hooks/{hookToken} posts into a conversation under the hook's name, with a per-hook rate limit; hooks are managed with /conversations/{conversationId}/hooks and their messages have viaHook set."},
+		{ChangeAdded, false, "PUT /conversations/{conversationId}/messages/{messageId} edits the text of a sent message; messages carry edited and editedAt, and /ws pushes messageEdited."},
+		{ChangeAdded, false, "Daily fair-use limits on messages and uploads: past them requests are slowed down and answered with 429 (code usage_limit) until the delay passed; GET /users/me/usage shows the counters."},
+		{ChangeAdded, false, "GET /ws opens a WebSocket pushing new messages, deletions, reactions, statuses and typing indicators."},
+		{ChangeAdded, false, "Groups can become public channels and back (PUT /groups/{groupId}/kind, PUT /admin/groups/{groupId}/kind, GET /channels); groups have a kind, conversations an isChannel flag and notices are messages with system set."},
+		{ChangeAdded, false, "Invite-only mode (feature inviteOnly): POST /session takes an inviteCode for new accounts; invites are managed with /invites and /admin/invites."},
+		{ChangeAdded, false, "GET/PUT /conversations/{conversationId}/privacy: per-conversation typing indicator and read receipt settings, enforced by the server."},
+		{ChangeChanged, false, "Replies in conversations carry a replyPreview; when the replied-to message was deleted or is not in the conversation, it has kind \"unavailable\" and replyTo is left out."},
+		{ChangeAdded, false, "GET /conversations/{conversationId}/reactions?messageIds=a,b,c returns the reactions of several messages at once."},
+		{ChangeAdded, false, "Responses carry a signed, short-lived photoUrl next to hasPhoto; GET /media/{mediaId} serves the photo without the Authorization header."},
+		{ChangeAdded, false, "Admin exports take an optional X-Export-Password header and are then sent as an AES-encrypted zip."},
+		{ChangeAdded, false, "GET /api/changelog lists API changes and deprecated routes."},
+		{ChangeChanged, false, "Errors about the data (not found, conflict, forbidden, invalid) are JSON objects with a message and a code."},
+		{ChangeChanged, true, "User, conversation, message and group IDs must be UUIDs; any other ID is answered with 400."},
+		{ChangeChanged, true, "GET /conversations/{conversationId} returns at most 200 messages (configurable); older ones are fetched with ?before=."},
+		{ChangeAdded, false, "Admin endpoints: database maintenance, CSV exports of users and activity, HTML export of a conversation."},
+		{ChangeAdded, false, "Guest tokens give read-only access to a group conversation (POST /groups/{groupId}/guest-tokens, GET /guest/conversation)."},
+		{ChangeAdded, false, "Workspaces: POST /session takes an optional workspace; GET /workspaces lists them."},
+		{ChangeAdded, false, "POST /admin/config/reload reloads the configuration."},
+		{ChangeAdded, false, "Message reports and the admin moderation queue (/admin/queue, /admin/audit); GET /users/{userId}/warnings."},
+		{ChangeAdded, false, "Anti-spam limits answer 429 with Retry-After; GET /admin/spam lists spam scores."},
+		{ChangeAdded, false, "DELETE /users/{userId} deletes the account; it is purged after a retention period."},
+		{ChangeChanged, false, "Message status (sent, received, read) is computed from per-recipient receipts."},
+	}},
+	{Version: "1.0.0", Date: "2026-10-15", Changes: []ChangeEntry{
+		{ChangeAdded, false, "First version of the API."},
+	}},
+}
+
+// deprecatedRoutes lists the routes that are going away (none yet)
+var deprecatedRoutes []Deprecation
+
+/*
+GetChangelog handles GET /api/changelog
+operationId: getChangelog
+
+Lists the API releases and the deprecated routes. No authentication.
+*/
+func (h *Handler) GetChangelog(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, ChangelogResponse{
+		Version:      APIVersion,
+		Releases:     changelog,
+		Deprecations: append([]Deprecation{}, deprecatedRoutes...),
+	})
+}
+
+// findDeprecation returns the deprecation of a route, or nil
+func findDeprecation(method, path string) *Deprecation {
+	for i := range deprecatedRoutes {
+		if deprecatedRoutes[i].Method == method && deprecatedRoutes[i].Path == path {
+			return &deprecatedRoutes[i]
+		}
+	}
+	return nil
+}
+
+// DeprecationMiddleware adds the deprecation headers to responses of deprecated routes
+func DeprecationMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route := mux.CurrentRoute(r); route != nil {
+			path, err := route.GetPathTemplate()
+			if err == nil {
+				if d := findDeprecation(r.Method, path); d != nil {
+					w.Header().Set("Deprecation", "@"+strconv.FormatInt(d.Since.Unix(), 10))
+					w.Header().Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+					w.Header().Add("Link", `</api/changelog>; rel="deprecation"; type="application/json"`)
+					if d.Replacement != "" {
+						w.Header().Add("Link", "<"+d.Replacement+`>; rel="successor-version"`)
+					}
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
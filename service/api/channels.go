@@ -0,0 +1,391 @@
+/*
+Channel API handlers.
+
+A group can be turned into a public channel and back. The history,
+members and photo stay; the permissions change: in a channel only the
+group admin posts, edits the channel and adds subscribers, and anyone in
+the workspace can join with POST /groups/{groupId}/members (their own
+userId). Every change leaves a system message in the conversation.
+
+The admin can also publish the channel as an Atom feed of its latest
+messages. The feed has no authentication, so it is off until the admin
+turns it on, and it disappears when the channel becomes a group again.
+
+This file contains:
+- setGroupKind: Turn a group into a channel or back (group admin only)
+- setGroupKindAdmin: The same, for the admins of the server
+- listChannels: The channels of the user's workspace
+- setChannelFeed: Publish the Atom feed of a channel or take it down (admin only)
+- getChannelFeed: The Atom feed of a channel
+*/
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"time"
+
+	"wasatext/service/database"
+	"wasatext/service/events"
+	"wasatext/service/ids"
+)
+
+// SetGroupKindRequest is the body for PUT /groups/{groupId}/kind and PUT /admin/groups/{groupId}/kind
+type SetGroupKindRequest struct {
+	Kind string `json:"kind"` // group, channel
+}
+
+// ChannelResponse is a channel in GET /channels
+type ChannelResponse struct {
+	GroupID     ids.GroupID `json:"groupId"`
+	Name        string      `json:"name"`
+	HasPhoto    bool        `json:"hasPhoto"`
+	PhotoURL    string      `json:"photoUrl,omitempty"`
+	Subscribers int         `json:"subscribers"`
+	Joined      bool        `json:"joined"`
+	PublicFeed  bool        `json:"publicFeed"`
+	FeedURL     string      `json:"feedUrl,omitempty"` // path of the Atom feed, when public
+}
+
+// SetChannelFeedRequest is the body for PUT /groups/{groupId}/feed
+type SetChannelFeedRequest struct {
+	Public *bool `json:"public"`
+}
+
+// ChannelFeedResponse is the answer to PUT /groups/{groupId}/feed
+type ChannelFeedResponse struct {
+	Public  bool   `json:"public"`
+	FeedURL string `json:"feedUrl,omitempty"`
+}
+
+const (
+	// channelFeedLimit is how many of the latest messages the Atom feed shows
+	channelFeedLimit = 50
+	// feedTitleLength is how much of a message becomes the title of its entry
+	feedTitleLength = 80
+)
+
+// atomFeed, atomEntry and friends are the parts of an Atom feed (RFC 4287)
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Content atomContent `xml:"content"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+/*
+SetGroupKind handles PUT /groups/{groupId}/kind
+operationId: setGroupKind
+
+Turns a group into a channel or a channel into a group. Only the group
+admin (the user who created the group) can do this.
+*/
+func (h *Handler) SetGroupKind(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get group ID from URL and check the admin
+	groupID, ok := pathGroupID(w, r)
+	if !ok {
+		return
+	}
+	if !h.requireGroupAdmin(r.Context(), w, groupID, authUserID, "change the kind of the group") {
+		return
+	}
+	user, err := h.db.GetUserByID(r.Context(), authUserID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 3: Parse request body
+	kind, ok := parseGroupKind(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 4: Change the kind and return the group
+	h.changeGroupKind(r.Context(), w, groupID, kind, authUserID, user.Name)
+}
+
+/*
+SetGroupKindAdmin handles PUT /admin/groups/{groupId}/kind
+operationId: setGroupKindAdmin
+
+Turns any group into a channel or back. The system message is sent on
+behalf of the group admin; a group without an admin cannot become a
+channel (409).
+*/
+func (h *Handler) SetGroupKindAdmin(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check the admin token
+	if !h.isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Step 2: Get group ID from URL
+	groupID, ok := pathGroupID(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 3: Parse request body
+	kind, ok := parseGroupKind(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 4: Change the kind and return the group
+	h.infof("Group %s turned into a %s by an admin", groupID, kind)
+	h.changeGroupKind(r.Context(), w, groupID, kind, "", "An administrator")
+}
+
+/*
+ListChannels handles GET /channels
+operationId: listChannels
+
+Returns the channels of the user's workspace, joined or not.
+*/
+func (h *Handler) ListChannels(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get the channels
+	channels, err := h.db.ListChannels(r.Context(), authUserID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 3: Convert to response format
+	response := []ChannelResponse{}
+	for _, ch := range channels {
+		response = append(response, ChannelResponse{
+			GroupID:     ch.ID,
+			Name:        ch.Name,
+			HasPhoto:    ch.PhotoID != "",
+			PhotoURL:    h.photoURL(mediaGroup, string(ch.ID), ch.PhotoID),
+			Subscribers: ch.Subscribers,
+			Joined:      ch.Joined,
+			PublicFeed:  ch.PublicFeed,
+			FeedURL:     channelFeedPath(ch.ID, ch.PublicFeed),
+		})
+	}
+
+	writePage(w, r, response)
+}
+
+/*
+SetChannelFeed handles PUT /groups/{groupId}/feed
+operationId: setChannelFeed
+
+Publishes the Atom feed of a channel, or takes it down. Only the channel
+admin can do this.
+*/
+func (h *Handler) SetChannelFeed(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get group ID from URL and check the admin
+	groupID, ok := pathGroupID(w, r)
+	if !ok {
+		return
+	}
+	if !h.requireGroupAdmin(r.Context(), w, groupID, authUserID, "publish the channel feed") {
+		return
+	}
+
+	// Step 3: Parse request body
+	var req SetChannelFeedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Public == nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Step 4: Update the setting
+	if err := h.db.SetChannelFeed(r.Context(), groupID, *req.Public); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	h.infof("Feed of channel %s public: %t", groupID, *req.Public)
+	writeJSON(w, http.StatusOK, ChannelFeedResponse{
+		Public:  *req.Public,
+		FeedURL: channelFeedPath(groupID, *req.Public),
+	})
+}
+
+/*
+GetChannelFeed handles GET /channels/{groupId}/feed.atom
+operationId: getChannelFeed
+
+Returns the latest messages of a channel as an Atom feed. There is no
+authentication: channels whose feed is not public are not found.
+*/
+func (h *Handler) GetChannelFeed(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Get group ID from URL
+	groupID, ok := pathGroupID(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 2: Get the feed (only public feeds of channels are found)
+	feed, err := h.db.GetChannelFeed(r.Context(), groupID, channelFeedLimit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 3: Build the Atom document
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	doc := atomFeed{
+		ID:      feedID(string(feed.ID)),
+		Title:   feed.Name,
+		Updated: time.Now().UTC().Format(time.RFC3339),
+		Link:    atomLink{Rel: "self", Href: scheme + "://" + r.Host + r.URL.Path},
+	}
+	if len(feed.Messages) > 0 {
+		doc.Updated = feedTime(feed.Messages[0])
+	}
+	for _, msg := range feed.Messages {
+		content := msg.Content
+		if content == "" && msg.PhotoID != "" {
+			content = "[Photo]"
+		}
+		doc.Entries = append(doc.Entries, atomEntry{
+			ID:      feedID(string(msg.ID)),
+			Title:   feedTitle(content),
+			Updated: feedTime(msg),
+			Author:  atomAuthor{Name: msg.SenderName},
+			Content: atomContent{Type: "text", Body: content},
+		})
+	}
+
+	// Step 4: Send it
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	_, _ = w.Write([]byte(xml.Header))
+	_, _ = w.Write(data)
+}
+
+// channelFeedPath is the path of the Atom feed of a channel, "" when it is not public
+func channelFeedPath(groupID ids.GroupID, public bool) string {
+	if !public {
+		return ""
+	}
+	return "/channels/" + string(groupID) + "/feed.atom"
+}
+
+// feedID is the Atom ID of a channel or message: a UUID URN, or for a
+// short ID (see ids.SetFormat) a URN of the application
+func feedID(id string) string {
+	if ids.IsShort(id) {
+		return "urn:wasatext:" + id
+	}
+	return "urn:uuid:" + id
+}
+
+// feedTime is when a feed entry was last updated
+func feedTime(msg database.Message) string {
+	if msg.EditedAt != nil {
+		return msg.EditedAt.UTC().Format(time.RFC3339)
+	}
+	return msg.Timestamp.UTC().Format(time.RFC3339)
+}
+
+// feedTitle is the first line of a message, cut to feedTitleLength runes
+func feedTitle(content string) string {
+	title, _, _ := strings.Cut(content, "\n")
+	if runes := []rune(title); len(runes) > feedTitleLength {
+		title = string(runes[:feedTitleLength-1]) + "…"
+	}
+	return title
+}
+
+// parseGroupKind reads the body of a kind change.
+// It answers 400 and returns false when the body is invalid.
+func parseGroupKind(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var req SetGroupKindRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return "", false
+	}
+	if req.Kind != database.GroupKindGroup && req.Kind != database.GroupKindChannel {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Message: "kind must be \"group\" or \"channel\""})
+		return "", false
+	}
+	return req.Kind, true
+}
+
+// changeGroupKind changes the kind of a group, leaving a notice signed
+// with actorName, and answers with the group
+func (h *Handler) changeGroupKind(ctx context.Context, w http.ResponseWriter, groupID ids.GroupID, kind string, actorID ids.UserID, actorName string) {
+	notice := actorName + " turned this channel into a group"
+	if kind == database.GroupKindChannel {
+		notice = actorName + " turned this group into a channel"
+	}
+
+	msg, err := h.db.SetGroupKind(ctx, groupID, kind, actorID, notice)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	h.fanout.enqueue(msg)
+	if msg != nil {
+		h.bus.Publish(ctx, events.MessageCreated{Message: *msg})
+	}
+
+	group, err := h.db.GetGroup(ctx, groupID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.groupResponse(group))
+}
@@ -0,0 +1,96 @@
+/*
+Fault injection for resilience testing.
+
+With Config.Chaos.Enabled the server misbehaves on purpose, so that the
+retries of the clients and the delivery through acks (see events.go)
+can be checked under failure:
+
+  - ChaosMiddleware fails a share ErrorRate of the API requests with 500
+    before they run, and runs a share DBTimeoutRate of them with a
+    context already past its deadline: their database calls fail as on
+    a timeout, and the request answers whatever a real timeout gives.
+  - A share DropEventRate of the WebSocket events is lost on its way to
+    each client, as over a flaky network.
+
+Every injected fault is logged and marked with the X-Chaos-Fault response
+header. Unlike the sandbox, chaos leaves the data alone, but it fails
+real requests: only turn it on for a test deployment. The admin and
+sandbox endpoints are spared, so that the configuration can still be
+reloaded to turn it off, and fixtures seeded.
+*/
+package api
+
+import (
+	"context"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// ChaosConfig holds the fault injection settings, shares from 0 to 1
+type ChaosConfig struct {
+	Enabled       bool
+	ErrorRate     float64 // API requests failed with 500
+	DBTimeoutRate float64 // API requests whose database calls time out
+	DropEventRate float64 // WebSocket events lost, per client
+}
+
+// headerChaosFault tells a client which fault was injected
+const headerChaosFault = "X-Chaos-Fault"
+
+// Faults injected into the requests
+const (
+	chaosFaultError     = "error"
+	chaosFaultDBTimeout = "db-timeout"
+)
+
+// codeChaosFault is the code of the failures injected by chaos
+const codeChaosFault = "chaos_fault"
+
+// ChaosMiddleware injects the request faults of Config.Chaos
+func (h *Handler) ChaosMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chaos := h.config().Chaos
+		if !chaos.Enabled || !chaosApplies(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		switch {
+		case chaos.ErrorRate > 0 && rand.Float64() < chaos.ErrorRate:
+			log.Printf("Chaos: failing %s %s", r.Method, r.URL.Path)
+			w.Header().Set(headerChaosFault, chaosFaultError)
+			writeJSON(w, http.StatusInternalServerError, ErrorResponse{Message: "Injected failure", Code: codeChaosFault})
+		case chaos.DBTimeoutRate > 0 && rand.Float64() < chaos.DBTimeoutRate:
+			log.Printf("Chaos: timing out the database calls of %s %s", r.Method, r.URL.Path)
+			w.Header().Set(headerChaosFault, chaosFaultDBTimeout)
+			ctx, cancel := context.WithDeadline(r.Context(), time.Now())
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// chaosApplies reports whether chaos may touch a request: the web UI
+// (served on "/" by cmd/webapi), the admin and the sandbox endpoints
+// are spared
+func chaosApplies(r *http.Request) bool {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return true
+	}
+	template, _ := route.GetPathTemplate()
+	return template != "/" && !strings.HasPrefix(template, "/admin/") && !strings.HasPrefix(template, "/sandbox/")
+}
+
+// chaosDropEvent decides whether an event is lost on its way to a client
+func (h *Handler) chaosDropEvent() bool {
+	chaos := h.config().Chaos
+	return chaos.Enabled && chaos.DropEventRate > 0 && rand.Float64() < chaos.DropEventRate
+}
@@ -0,0 +1,232 @@
+/*
+Runtime configuration of the API handlers.
+
+The configuration can be swapped while the server is running (on SIGHUP
+or through POST /admin/config/reload). Handlers always read it through
+h.config(), so each request sees one consistent snapshot and nothing has
+to be restarted: open connections simply pick up the new values on
+their next request.
+*/
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Config holds the server settings the handlers need
+type Config struct {
+	// AdminToken is the bearer token for the /admin endpoints.
+	// When empty, the admin endpoints are disabled.
+	AdminToken string
+
+	// LogLevel is "debug", "info" or "error".
+	// At "debug" every request is logged.
+	LogLevel string
+
+	// CORSOrigins lists the origins allowed to call the API ("*" allows all)
+	CORSOrigins []string
+
+	// Features turns optional features on and off (see defaultFeatures)
+	Features map[string]bool
+
+	// Spam holds the anti-spam thresholds
+	Spam SpamConfig
+
+	// Usage holds the soft daily limits (see usage.go)
+	Usage UsageConfig
+
+	// RateLimit holds the request rate limits (see ratelimit.go)
+	RateLimit RateLimitConfig
+
+	// APIUsage samples the requests of the users (see apiusage.go)
+	APIUsage APIUsageConfig
+
+	// FilterWords are words that put a message on the moderation queue
+	FilterWords []string
+
+	// HoneypotUsers are usernames of trap accounts; contacting one
+	// puts the sender on the moderation queue
+	HoneypotUsers []string
+
+	// MaxConversationMessages caps how many messages GET /conversations/{id}
+	// returns at once; older ones are fetched page by page. 0 means no cap.
+	MaxConversationMessages int
+
+	// MediaURLSecret signs the photo URLs (see media.go). When empty, a
+	// random key is used and the URLs do not survive a restart.
+	MediaURLSecret string
+
+	// MediaURLTTL is how long a signed photo URL stays valid
+	MediaURLTTL time.Duration
+
+	// InviteQuota is how many invites each user can mint
+	// (see the inviteOnly feature)
+	InviteQuota int
+
+	// MaxPhotoSize is the largest photo a user can upload, in bytes
+	MaxPhotoSize int64
+
+	// MaxPhotoDimension is the largest width and height of an uploaded
+	// photo, in pixels; 0 means no limit
+	MaxPhotoDimension int
+
+	// DeletedConversationRetention is how long a group or conversation
+	// deleted by an admin can be restored before it is purged (see
+	// deletions.go); 0 keeps it until restored
+	DeletedConversationRetention time.Duration
+
+	// WebUI is what the embedded frontend is told (see webui.go)
+	WebUI WebUIConfig
+
+	// OCR reads the text of the message photos for the search (see
+	// processing.go), off by default
+	OCR OCRConfig
+
+	// Push sends push notifications of new messages (see push.go), off
+	// by default
+	Push PushConfig
+
+	// Sandbox is the developer sandbox (see sandbox.go), off by default
+	Sandbox SandboxConfig
+
+	// Chaos injects faults for resilience testing (see chaos.go), off
+	// by default
+	Chaos ChaosConfig
+
+	// RejectionLog logs the rejected requests (see rejections.go), off
+	// by default
+	RejectionLog RejectionLogConfig
+
+	// Matrix bridges conversations to Matrix rooms (see matrix.go), off
+	// by default
+	Matrix MatrixConfig
+
+	// Backplane relays the real-time events between the instances of
+	// the server through Redis (see backplane.go), off by default
+	Backplane BackplaneConfig
+}
+
+// Log levels
+const (
+	LogLevelDebug = "debug"
+	LogLevelInfo  = "info"
+	LogLevelError = "error"
+)
+
+// Feature flags
+const (
+	FeatureMessageReports  = "messageReports"
+	FeatureAccountDeletion = "accountDeletion"
+	FeatureGuestAccess     = "guestAccess"
+	FeatureInviteOnly      = "inviteOnly"   // new accounts need an invite code
+	FeatureWebhooks        = "webhooks"     // external systems post through POST /hooks/{hookToken}
+	FeatureWidgetTokens    = "widgetTokens" // tokens reading one conversation, for embedding
+)
+
+// defaultFeatures is the state of each feature flag when it is not configured
+var defaultFeatures = map[string]bool{
+	FeatureMessageReports:  true,
+	FeatureAccountDeletion: true,
+	FeatureGuestAccess:     true,
+	FeatureInviteOnly:      false,
+	FeatureWebhooks:        true,
+	FeatureWidgetTokens:    true,
+}
+
+// DefaultMaxConversationMessages is the default page size of a conversation
+const DefaultMaxConversationMessages = 200
+
+// DefaultInviteQuota is the default number of invites per user
+const DefaultInviteQuota = 5
+
+// DefaultConfig returns the configuration used when nothing is configured
+func DefaultConfig() Config {
+	return Config{
+		LogLevel:    LogLevelInfo,
+		CORSOrigins: []string{"*"}, // Allow ALL origins (as specified in PDF)
+		Features:    map[string]bool{},
+		Spam:        DefaultSpamConfig(),
+		Usage:       DefaultUsageConfig(),
+		RateLimit:   DefaultRateLimitConfig(),
+		APIUsage:    DefaultAPIUsageConfig(),
+
+		MaxConversationMessages: DefaultMaxConversationMessages,
+		MediaURLTTL:             DefaultMediaURLTTL,
+		InviteQuota:             DefaultInviteQuota,
+		MaxPhotoSize:            DefaultMaxPhotoSize,
+		MaxPhotoDimension:       DefaultMaxPhotoDimension,
+
+		DeletedConversationRetention: DefaultDeletedConversationRetention,
+		RejectionLog:                 RejectionLogConfig{MaxSize: DefaultRejectionLogMaxSize, MaxFiles: DefaultRejectionLogMaxFiles},
+	}
+}
+
+// ErrNoConfigLoader is returned when reloading without a configuration source
+var ErrNoConfigLoader = errors.New("configuration reload is not available")
+
+// config returns the current configuration snapshot
+func (h *Handler) config() *Config {
+	return h.cfg.Load()
+}
+
+// UpdateConfig replaces the configuration used by the handlers
+func (h *Handler) UpdateConfig(cfg Config) {
+	h.cfg.Store(&cfg)
+}
+
+// SetConfigLoader sets the function used by ReloadConfig to read the configuration
+func (h *Handler) SetConfigLoader(loader func() (Config, error)) {
+	h.configLoader = loader
+}
+
+// ReloadConfig reads the configuration again and swaps it in.
+// On error the current configuration is kept.
+func (h *Handler) ReloadConfig() error {
+	if h.configLoader == nil {
+		return ErrNoConfigLoader
+	}
+
+	cfg, err := h.configLoader()
+	if err != nil {
+		return err
+	}
+
+	h.UpdateConfig(cfg)
+	h.infof("Configuration reloaded (log level %s, %d CORS origins)", cfg.LogLevel, len(cfg.CORSOrigins))
+	return nil
+}
+
+// featureEnabled reports whether a feature flag is on
+func (h *Handler) featureEnabled(name string) bool {
+	if enabled, ok := h.config().Features[name]; ok {
+		return enabled
+	}
+	return defaultFeatures[name]
+}
+
+// requireFeature answers 404 when a feature is switched off.
+// It returns false when a response has already been written.
+func (h *Handler) requireFeature(w http.ResponseWriter, name string) bool {
+	if h.featureEnabled(name) {
+		return true
+	}
+	http.Error(w, "Not found", http.StatusNotFound)
+	return false
+}
+
+// debugf logs only when the log level is "debug"
+func (h *Handler) debugf(format string, args ...interface{}) {
+	if h.config().LogLevel == LogLevelDebug {
+		log.Printf(format, args...)
+	}
+}
+
+// infof logs unless the log level is "error"
+func (h *Handler) infof(format string, args ...interface{}) {
+	if h.config().LogLevel != LogLevelError {
+		log.Printf(format, args...)
+	}
+}
@@ -4,59 +4,154 @@ Conversation API handlers.
 This file contains:
 - getMyConversations: Get list of all conversations
 - getConversation: Get a specific conversation with messages
+- markConversationRead: Mark the messages of a conversation as read
+- clearConversation: Hide the history of a conversation from the user
 - startConversation: Start a new direct conversation
+- getConversationPrivacy / setConversationPrivacy: What the user shares in a conversation
+- getConversationPermissions: What the user may do in a conversation
 */
 package api
 
 import (
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
+	"strconv"
+	"time"
 
 	"wasatext/service/database"
-
-	"github.com/gorilla/mux"
+	"wasatext/service/ids"
 )
 
 // ConversationPreviewResponse is used for the conversation list
 type ConversationPreviewResponse struct {
-	ConversationID     string `json:"conversationId"`
-	IsGroup            bool   `json:"isGroup"`
-	Name               string `json:"name"`
-	HasPhoto           bool   `json:"hasPhoto"`
-	LastMessageTime    string `json:"lastMessageTimestamp,omitempty"`
-	LastMessagePreview string `json:"lastMessagePreview,omitempty"`
-	LastMessageIsPhoto bool   `json:"lastMessageIsPhoto"`
+	ConversationID     ids.ConversationID `json:"conversationId"`
+	IsGroup            bool               `json:"isGroup"`
+	IsChannel          bool               `json:"isChannel,omitempty"`
+	Name               string             `json:"name"`
+	HasPhoto           bool               `json:"hasPhoto"`
+	PhotoURL           string             `json:"photoUrl,omitempty"` // signed, short-lived (see media.go)
+	LastMessageTime    string             `json:"lastMessageTimestamp,omitempty"`
+	LastMessagePreview string             `json:"lastMessagePreview,omitempty"`
+	LastMessageIsPhoto bool               `json:"lastMessageIsPhoto"`
+	Blocked            bool               `json:"blocked,omitempty"`     // a direct conversation with a user you blocked
+	Deactivated        bool               `json:"deactivated,omitempty"` // a direct conversation with a user who deactivated their account
 }
 
 // ConversationResponse is the full conversation with messages
 type ConversationResponse struct {
-	ConversationID string            `json:"conversationId"`
-	IsGroup        bool              `json:"isGroup"`
-	Name           string            `json:"name"`
-	HasPhoto       bool              `json:"hasPhoto"`
-	Members        []UserResponse    `json:"members,omitempty"`
-	Messages       []MessageResponse `json:"messages"`
+	ConversationID ids.ConversationID `json:"conversationId"`
+	IsGroup        bool               `json:"isGroup"`
+	IsChannel      bool               `json:"isChannel,omitempty"`
+	Name           string             `json:"name"`
+	HasPhoto       bool               `json:"hasPhoto"`
+	PhotoURL       string             `json:"photoUrl,omitempty"`
+	Members        []UserResponse     `json:"members,omitempty"`
+	Messages       []MessageResponse  `json:"messages"`
+	HasMore        bool               `json:"hasMore"`                 // older messages can be fetched
+	Language       string             `json:"language,omitempty"`      // of most of the latest messages
+	NextBefore     ids.MessageID      `json:"nextBefore,omitempty"`    // "before" value for the next page
+	Warning        string             `json:"warning,omitempty"`       // set when the history was cut by the server cap
+	ClearedBefore  string             `json:"clearedBefore,omitempty"` // the user cleared the messages sent before
 }
 
 // MessageResponse represents a message
 type MessageResponse struct {
-	MessageID  string            `json:"messageId"`
-	SenderID   string            `json:"senderId"`
-	SenderName string            `json:"senderName"`
-	Content    string            `json:"content,omitempty"`
-	HasPhoto   bool              `json:"hasPhoto"`
-	Timestamp  string            `json:"timestamp"`
-	Status     string            `json:"status"` // sent, received, read
-	ReplyTo    string            `json:"replyTo,omitempty"`
-	Comments   []CommentResponse `json:"comments"`
+	MessageID      ids.MessageID     `json:"messageId"`
+	SenderID       ids.UserID        `json:"senderId"`
+	SenderName     string            `json:"senderName"`
+	Content        string            `json:"content,omitempty"`
+	Language       string            `json:"language,omitempty"` // detected in the text (ISO 639-1), e.g. the source for a translation
+	HasPhoto       bool              `json:"hasPhoto"`
+	PhotoURL       string            `json:"photoUrl,omitempty"`
+	PhotoState     string            `json:"processingState,omitempty"` // pending, ready or failed (see processing.go)
+	Timestamp      string            `json:"timestamp"`
+	Status         string            `json:"status"` // sent, received, read
+	ReplyTo        ids.MessageID     `json:"replyTo,omitempty"`
+	Reply          *ReplyPreview     `json:"replyPreview,omitempty"`
+	System         bool              `json:"system,omitempty"` // a notice about the conversation, sent on behalf of SenderID
+	Edited         bool              `json:"edited"`           // the sender changed the text after sending it
+	EditedAt       string            `json:"editedAt,omitempty"`
+	ViaHook        bool              `json:"viaHook,omitempty"` // posted through a webhook of SenderID, SenderName is the hook's
+	Deleted        bool              `json:"deleted,omitempty"` // deleted for everyone, kept for the replies to it
+	Event          *EventResponse    `json:"event,omitempty"`   // the event the message announces (see groupevents.go)
+	Mentions       []MentionResponse `json:"mentions"`          // the participants its text mentions with @name
+	Pinned         bool              `json:"pinned,omitempty"`  // pinned in the conversation (see pins.go)
+	Notice         *NoticeResponse   `json:"notice,omitempty"`  // what a group notice reports (see groups.go)
+	Comments       []CommentResponse `json:"comments"`
+	ReactionCounts []ReactionCount   `json:"reactionCounts"` // Comments counted by emoticon, the most used first
+}
+
+// Kinds of reply preview
+const (
+	ReplyKindMessage     = "message"
+	ReplyKindUnavailable = "unavailable" // deleted, or not in this conversation
+)
+
+// ReplyPreview shows the message a reply refers to
+type ReplyPreview struct {
+	Kind       string `json:"kind"` // message, unavailable
+	SenderName string `json:"senderName,omitempty"`
+	Content    string `json:"content,omitempty"` // the beginning of the text
+	HasPhoto   bool   `json:"hasPhoto,omitempty"`
 }
 
 // CommentResponse represents a reaction
 type CommentResponse struct {
-	UserID   string `json:"userId"`
-	UserName string `json:"userName"`
+	UserID   ids.UserID `json:"userId"`
+	UserName string     `json:"userName"`
+	Emoticon string     `json:"emoticon"`
+}
+
+// MentionResponse is a user a message mentions
+type MentionResponse struct {
+	UserID   ids.UserID `json:"userId"`
+	UserName string     `json:"userName"`
+}
+
+// ReactionCount is how many users reacted to a message with an emoticon
+type ReactionCount struct {
 	Emoticon string `json:"emoticon"`
+	Count    int    `json:"count"`
+}
+
+// PrivacyResponse is what the user shares with the others in a conversation
+type PrivacyResponse struct {
+	TypingIndicators bool `json:"typingIndicators"`
+	ReadReceipts     bool `json:"readReceipts"`
+}
+
+// PermissionsResponse is what the user may do in a conversation
+type PermissionsResponse struct {
+	Send                 bool `json:"send"`
+	DeleteOthersMessages bool `json:"deleteOthersMessages"`
+	Rename               bool `json:"rename"`
+	SetPhoto             bool `json:"setPhoto"`
+	AddMembers           bool `json:"addMembers"`
+	Leave                bool `json:"leave"`
+	ManageGroup          bool `json:"manageGroup"`
+}
+
+// MarkReadRequest is the optional body for PUT /conversations/{id}/read
+type MarkReadRequest struct {
+	UpToMessageID string `json:"upToMessageId,omitempty"` // the newest message the user saw
+}
+
+// ClearRequest is the optional body for POST /conversations/{id}/clear
+type ClearRequest struct {
+	Before string `json:"before,omitempty"` // a date or an RFC 3339 time; everything when left out
+}
+
+// ClearResponse tells where the history of a cleared conversation starts
+type ClearResponse struct {
+	ClearedBefore string `json:"clearedBefore"`
+}
+
+// PrivacyRequest is the body for PUT /conversations/{id}/privacy
+type PrivacyRequest struct {
+	TypingIndicators *bool `json:"typingIndicators"`
+	ReadReceipts     *bool `json:"readReceipts"`
 }
 
 // StartConversationRequest is the body for POST /conversations
@@ -75,6 +170,11 @@ list must display the username of the other person or the group name,
 the user profile photo or the group photo, the date and time of the
 latest message, the preview (snippet) of the text message, or an icon
 for a photo message."
+With ?hideBlocked=true the direct conversations with blocked users are
+left out. With ?forwardableFor={messageId} only the conversations the
+user may forward that message into are listed, for the forward picker:
+not the channels they do not run, nor the direct conversations where
+they are blocked.
 */
 func (h *Handler) GetMyConversations(w http.ResponseWriter, r *http.Request) {
 	// Step 1: Check authentication
@@ -84,23 +184,47 @@ func (h *Handler) GetMyConversations(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Step 2: Get conversations from database
-	conversations, err := h.db.GetConversations(authUserID)
+	// Step 2: Get conversations from database, and where the message
+	// to forward may go
+	conversations, err := h.db.GetConversations(r.Context(), authUserID)
 	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
+	var forwardTargets map[ids.ConversationID]bool
+	if value := r.URL.Query().Get("forwardableFor"); value != "" {
+		messageID, err := ids.ParseMessageID(value)
+		if err != nil {
+			http.Error(w, "Invalid forwardableFor", http.StatusBadRequest)
+			return
+		}
+		if forwardTargets, err = h.db.GetForwardTargets(r.Context(), authUserID, messageID); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
 
 	// Step 3: Convert to response format
-	var response []ConversationPreviewResponse
+	hideBlocked := r.URL.Query().Get("hideBlocked") == "true"
+	response := []ConversationPreviewResponse{}
 	for _, c := range conversations {
+		if hideBlocked && c.Blocked {
+			continue
+		}
+		if forwardTargets != nil && !forwardTargets[c.ID] {
+			continue
+		}
 		preview := ConversationPreviewResponse{
 			ConversationID:     c.ID,
 			IsGroup:            c.IsGroup,
+			IsChannel:          c.IsChannel,
 			Name:               c.Name,
-			HasPhoto:           len(c.Photo) > 0,
+			HasPhoto:           c.PhotoID != "",
+			PhotoURL:           h.conversationPhotoURL(c.IsGroup, c.PhotoOwnerID, c.PhotoID),
 			LastMessagePreview: c.LastMessagePreview,
 			LastMessageIsPhoto: c.LastMessageIsPhoto,
+			Blocked:            c.Blocked,
+			Deactivated:        c.Deactivated,
 		}
 
 		if !c.LastMessageTime.IsZero() {
@@ -110,8 +234,11 @@ func (h *Handler) GetMyConversations(w http.ResponseWriter, r *http.Request) {
 		response = append(response, preview)
 	}
 
-	// Step 4: Return the conversations
-	writeJSON(w, http.StatusOK, response)
+	// Step 4: Tell the senders their messages were received
+	h.publishDelivered(r.Context(), authUserID, conversations)
+
+	// Step 5: Return the conversations
+	writePage(w, r, response)
 }
 
 /*
@@ -125,6 +252,9 @@ timestamp, the content (whether text or photo), and the sender's
 username for received messages, or one/two checkmarks to indicate
 the status of sent messages. Any reactions (comments) on messages
 are also displayed, along with the names of the users who posted them."
+
+Reading a conversation does not mark it as read: clients call
+PUT /conversations/{conversationId}/read once the user has seen it.
 */
 func (h *Handler) GetConversation(w http.ResponseWriter, r *http.Request) {
 	// Step 1: Check authentication
@@ -134,18 +264,42 @@ func (h *Handler) GetConversation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Step 2: Get conversation ID from URL
-	vars := mux.Vars(r)
-	conversationID := vars["conversationId"]
+	// Step 2: Get conversation ID from URL and the page to return.
+	// The page size is capped by the server configuration.
+	conversationID, ok := pathConversationID(w, r)
+	if !ok {
+		return
+	}
+
+	maxMessages := h.config().MaxConversationMessages
+	limit := maxMessages
+	if value := r.URL.Query().Get("limit"); value != "" {
+		requested, err := strconv.Atoi(value)
+		if err != nil || requested < 1 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		if maxMessages == 0 || requested < maxMessages {
+			limit = requested
+		}
+	}
+	var before ids.MessageID
+	if value := r.URL.Query().Get("before"); value != "" {
+		var err error
+		if before, err = ids.ParseMessageID(value); err != nil {
+			http.Error(w, "Invalid before: not a message ID", http.StatusBadRequest)
+			return
+		}
+	}
 
 	// Step 3: Get conversation from database
-	conv, err := h.db.GetConversation(authUserID, conversationID)
-	if errors.Is(err, database.ErrConversationNotFound) {
-		http.Error(w, "Conversation not found", http.StatusNotFound)
+	conv, err := h.db.GetConversationPage(r.Context(), authUserID, conversationID, before, limit)
+	if errors.Is(err, database.ErrMessageNotFound) {
+		http.Error(w, "Invalid before: message not found", http.StatusBadRequest)
 		return
 	}
 	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -153,53 +307,152 @@ func (h *Handler) GetConversation(w http.ResponseWriter, r *http.Request) {
 	response := ConversationResponse{
 		ConversationID: conv.ID,
 		IsGroup:        conv.IsGroup,
+		IsChannel:      conv.IsChannel,
 		Name:           conv.Name,
-		HasPhoto:       len(conv.Photo) > 0,
+		HasPhoto:       conv.PhotoID != "",
+		PhotoURL:       h.conversationPhotoURL(conv.IsGroup, conv.PhotoOwnerID, conv.PhotoID),
+		Messages:       []MessageResponse{},
+		Language:       conv.Language,
+	}
+	if conv.ClearedBefore != nil {
+		response.ClearedBefore = conv.ClearedBefore.Format(time.RFC3339)
 	}
 
 	// Add members
-	for _, m := range conv.Members {
-		response.Members = append(response.Members, UserResponse{
-			Identifier: m.ID,
-			Name:       m.Name,
-			HasPhoto:   len(m.Photo) > 0,
-		})
-	}
+	response.Members = h.memberResponses(conv.Members)
 
 	// Add messages
 	for _, msg := range conv.Messages {
-		msgResp := MessageResponse{
-			MessageID:  msg.ID,
-			SenderID:   msg.SenderID,
-			SenderName: msg.SenderName,
-			Content:    msg.Content,
-			HasPhoto:   len(msg.Photo) > 0,
-			Timestamp:  msg.Timestamp.Format("2006-01-02T15:04:05Z07:00"),
-			Status:     msg.Status,
-		}
+		response.Messages = append(response.Messages, h.messageResponse(msg))
+	}
 
-		if msg.ReplyTo != nil {
-			msgResp.ReplyTo = *msg.ReplyTo
+	// Tell the client how to get the older messages
+	if conv.HasMore && len(conv.Messages) > 0 {
+		response.HasMore = true
+		response.NextBefore = conv.Messages[len(conv.Messages)-1].ID
+		if limit == maxMessages {
+			response.Warning = "Only the latest " + strconv.Itoa(maxMessages) +
+				" messages are returned; use ?before=" + string(response.NextBefore) + " to load older ones"
 		}
+	}
 
-		// Add comments (reactions)
-		for _, c := range msg.Comments {
-			msgResp.Comments = append(msgResp.Comments, CommentResponse{
-				UserID:   c.UserID,
-				UserName: c.UserName,
-				Emoticon: c.Emoticon,
-			})
+	// Step 5: Return the conversation
+	writeJSON(w, http.StatusOK, response)
+}
+
+/*
+MarkConversationRead handles PUT /conversations/{conversationId}/read
+operationId: markConversationRead
+
+Marks the messages of the conversation as read: all of them, or those up
+to upToMessageId, the newest message the user saw. Senders see their
+messages as read unless the user turned read receipts off here.
+*/
+func (h *Handler) MarkConversationRead(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Parse the conversation and the optional body
+	conversationID, ok := pathConversationID(w, r)
+	if !ok {
+		return
+	}
+	var req MarkReadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	var upTo ids.MessageID
+	if req.UpToMessageID != "" {
+		var err error
+		if upTo, err = ids.ParseMessageID(req.UpToMessageID); err != nil {
+			http.Error(w, "Invalid upToMessageId: not a message ID", http.StatusBadRequest)
+			return
 		}
+	}
+
+	// Step 3: Mark the messages as read (this also checks the user is a participant)
+	err := h.db.MarkConversationAsRead(r.Context(), conversationID, authUserID, upTo)
+	if errors.Is(err, database.ErrMessageNotFound) {
+		http.Error(w, "Invalid upToMessageId: message not found", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
 
-		response.Messages = append(response.Messages, msgResp)
+	// Step 4: Tell the senders their messages were read
+	h.publishStatuses(r.Context(), conversationID, authUserID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+replyFields returns the replyTo and replyPreview of a message. When the replied-to message was deleted or is not in
+the conversation, only a preview of kind "unavailable" is returned: the
+client gets no ID it could not resolve.
+*/
+func replyFields(msg database.Message) (ids.MessageID, *ReplyPreview) {
+	if msg.ReplyTo == nil || msg.Reply == nil {
+		return "", nil
+	}
+	if !msg.Reply.Available {
+		return "", &ReplyPreview{Kind: ReplyKindUnavailable}
+	}
+	return *msg.ReplyTo, &ReplyPreview{
+		Kind:       ReplyKindMessage,
+		SenderName: msg.Reply.SenderName,
+		Content:    msg.Reply.Content,
+		HasPhoto:   msg.Reply.HasPhoto,
 	}
+}
 
-	// Step 5: Return the conversation
-	writeJSON(w, http.StatusOK, response)
+// messageResponse converts a message of a conversation to its response
+// format, with its reply preview and reactions
+func (h *Handler) messageResponse(msg database.Message) MessageResponse {
+	response := MessageResponse{
+		MessageID:  msg.ID,
+		SenderID:   msg.SenderID,
+		SenderName: msg.SenderName,
+		Content:    msg.Content,
+		Language:   msg.Language,
+		HasPhoto:   msg.PhotoID != "",
+		PhotoURL:   h.photoURL(mediaMessage, string(msg.ID), msg.PhotoID),
+		PhotoState: msg.PhotoState,
+		Timestamp:  msg.Timestamp.Format("2006-01-02T15:04:05Z07:00"),
+		Status:     msg.Status,
+		System:     msg.System,
+		Edited:     msg.EditedAt != nil,
+		EditedAt:   editedAt(msg),
+		ViaHook:    msg.ViaHook,
+		Deleted:    msg.Deleted,
+		Event:      eventResponse(msg.Event),
+		Mentions:   mentionResponses(msg.Mentions),
+		Pinned:     msg.Pinned,
+		Notice:     noticeResponse(msg.Notice),
+	}
+	response.ReplyTo, response.Reply = replyFields(msg)
+	response.Comments = commentResponses(msg.Comments)
+	response.ReactionCounts = reactionCounts(response.Comments)
+	return response
+}
+
+// editedAt formats when a message was last edited, "" if it never was
+func editedAt(msg database.Message) string {
+	if msg.EditedAt == nil {
+		return ""
+	}
+	return msg.EditedAt.Format("2006-01-02T15:04:05Z07:00")
 }
 
 /*
 StartConversation handles POST /conversations
+operationId: startConversation
+
 This allows a user to start a new conversation with another user.
 
 From PDF:
@@ -222,25 +475,194 @@ func (h *Handler) StartConversation(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Step 3: Check if the other user exists
-	_, err := h.db.GetUserByID(req.UserID)
-	if errors.Is(err, database.ErrUserNotFound) {
-		http.Error(w, "User not found", http.StatusNotFound)
+	otherUserID, err := ids.ParseUserID(req.UserID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
 		return
 	}
+	otherUser, err := h.db.GetUserByID(r.Context(), otherUserID)
 	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
-	// Step 4: Get or create the conversation
-	convID, err := h.db.GetOrCreateDirectConversation(authUserID, req.UserID)
+	// Step 4: Apply the anti-spam limits
+	if !h.checkNewConversationLimit(r.Context(), w, authUserID) {
+		return
+	}
+	h.flagHoneypotContact(r.Context(), authUserID, otherUser)
+
+	// Step 5: Get or create the conversation
+	convID, err := h.db.GetOrCreateDirectConversation(r.Context(), authUserID, otherUserID)
 	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
-	// Step 5: Return the conversation ID
-	writeJSON(w, http.StatusCreated, map[string]string{
+	// Step 6: Return the conversation ID
+	writeJSON(w, http.StatusCreated, map[string]ids.ConversationID{
 		"conversationId": convID,
 	})
 }
+
+/*
+GetConversationPrivacy handles GET /conversations/{conversationId}/privacy
+operationId: getConversationPrivacy
+
+Returns whether the user shares typing indicators and read receipts
+with the others in this conversation.
+*/
+func (h *Handler) GetConversationPrivacy(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get the settings
+	conversationID, ok := pathConversationID(w, r)
+	if !ok {
+		return
+	}
+	settings, err := h.db.GetPrivacySettings(r.Context(), authUserID, conversationID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 3: Return the settings
+	writeJSON(w, http.StatusOK, PrivacyResponse{
+		TypingIndicators: settings.TypingIndicators,
+		ReadReceipts:     settings.ReadReceipts,
+	})
+}
+
+/*
+SetConversationPrivacy handles PUT /conversations/{conversationId}/privacy
+operationId: setConversationPrivacy
+
+Changes what the user shares in this conversation. The server enforces
+it: without read receipts, marking the conversation as read only confirms
+delivery, so the others never see their messages as read.
+*/
+func (h *Handler) SetConversationPrivacy(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Parse the request; both settings are required
+	conversationID, ok := pathConversationID(w, r)
+	if !ok {
+		return
+	}
+	var req PrivacyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.TypingIndicators == nil || req.ReadReceipts == nil {
+		http.Error(w, "typingIndicators and readReceipts are required", http.StatusBadRequest)
+		return
+	}
+
+	// Step 3: Save the settings
+	settings := database.PrivacySettings{
+		TypingIndicators: *req.TypingIndicators,
+		ReadReceipts:     *req.ReadReceipts,
+	}
+	if err := h.db.SetPrivacySettings(r.Context(), authUserID, conversationID, settings); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 4: Return the new settings
+	writeJSON(w, http.StatusOK, PrivacyResponse{
+		TypingIndicators: settings.TypingIndicators,
+		ReadReceipts:     settings.ReadReceipts,
+	})
+}
+
+/*
+GetConversationPermissions handles GET /conversations/{conversationId}/permissions
+operationId: getConversationPermissions
+
+Returns what the user may do in this conversation, so that clients show
+only the actions the server would accept.
+*/
+func (h *Handler) GetConversationPermissions(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get the permissions
+	conversationID, ok := pathConversationID(w, r)
+	if !ok {
+		return
+	}
+	permissions, err := h.db.GetConversationPermissions(r.Context(), authUserID, conversationID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 3: Return the permissions
+	writeJSON(w, http.StatusOK, PermissionsResponse{
+		Send:                 permissions.Send,
+		DeleteOthersMessages: permissions.DeleteOthersMessages,
+		Rename:               permissions.Rename,
+		SetPhoto:             permissions.SetPhoto,
+		AddMembers:           permissions.AddMembers,
+		Leave:                permissions.Leave,
+		ManageGroup:          permissions.ManageGroup,
+	})
+}
+
+/*
+ClearConversation handles POST /conversations/{conversationId}/clear
+operationId: clearConversation
+
+Clears the conversation for the user: the messages sent before the
+given time ("before", a date or an RFC 3339 time), or all of them, are
+hidden from the user only. The others keep the whole history.
+*/
+func (h *Handler) ClearConversation(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Parse the conversation and the optional body
+	conversationID, ok := pathConversationID(w, r)
+	if !ok {
+		return
+	}
+	var req ClearRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	before, err := parseReportTime(req.Before, false)
+	if err != nil {
+		http.Error(w, "Invalid before: expected a date or an RFC 3339 time", http.StatusBadRequest)
+		return
+	}
+
+	// Step 3: Clear the history (this also checks the user is a participant)
+	clearedBefore, err := h.db.ClearConversation(r.Context(), authUserID, conversationID, before)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 4: Return where the history now starts
+	writeJSON(w, http.StatusOK, ClearResponse{ClearedBefore: clearedBefore.Format(time.RFC3339)})
+}
@@ -0,0 +1,304 @@
+/*
+Soft deletion of groups and conversations (admin).
+
+An admin deletes a group or a conversation instead of editing the
+database by hand: it disappears for its members, who can no longer
+read it, post in it or find it, but nothing is lost yet. It can be
+restored, as it was, for Config.DeletedConversationRetention; then the
+purge job hard-deletes it (see cmd/webapi/jobs.go). A group and its
+conversation are deleted and restored together. Every deletion,
+restoration and purge is written to an audit log of its own.
+
+This file contains:
+- deleteGroupAdmin, deleteConversationAdmin: Soft-delete
+- restoreGroupAdmin, restoreConversationAdmin: Restore
+- getDeletedConversations: List what can still be restored
+- getDeletionAudit: List the deletions, restorations and purges
+*/
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"wasatext/service/ids"
+)
+
+// DefaultDeletedConversationRetention is how long deleted groups and
+// conversations can be restored, when not configured
+const DefaultDeletedConversationRetention = 30 * 24 * time.Hour
+
+// DeletionRequest is the optional body of the deletion and restoration endpoints
+type DeletionRequest struct {
+	Note string `json:"note,omitempty"` // why, for the audit log
+}
+
+// DeletedConversationResponse is a deleted conversation that can be restored
+type DeletedConversationResponse struct {
+	ConversationID ids.ConversationID `json:"conversationId"`
+	GroupID        ids.GroupID        `json:"groupId,omitempty"`
+	Name           string             `json:"name"`
+	Participants   int                `json:"participants"`
+	Messages       int                `json:"messages"`
+	DeletedAt      string             `json:"deletedAt"`
+	PurgeAt        string             `json:"purgeAt,omitempty"` // absent when kept until restored
+}
+
+// DeletionAuditResponse is one entry of the deletion audit log
+type DeletionAuditResponse struct {
+	AuditID        int64              `json:"auditId"`
+	Action         string             `json:"action"` // delete, restore, purge
+	ConversationID ids.ConversationID `json:"conversationId"`
+	GroupID        ids.GroupID        `json:"groupId,omitempty"`
+	Note           string             `json:"note,omitempty"`
+	CreatedAt      string             `json:"createdAt"`
+}
+
+// parseDeletionNote reads the optional body; it answers 400 itself
+func parseDeletionNote(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var req DeletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return "", false
+	}
+	return req.Note, true
+}
+
+// deletionPurgeAt returns when a conversation deleted now is purged, nil
+// when the retention keeps it until restored
+func (h *Handler) deletionPurgeAt() *time.Time {
+	retention := h.config().DeletedConversationRetention
+	if retention <= 0 {
+		return nil
+	}
+	purgeAt := time.Now().Add(retention)
+	return &purgeAt
+}
+
+/*
+DeleteGroupAdmin handles DELETE /admin/groups/{groupId}
+operationId: deleteGroupAdmin
+
+Hides a group and its conversation from its members until it is
+restored or purged.
+*/
+func (h *Handler) DeleteGroupAdmin(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check admin authentication
+	if !h.isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Step 2: Get the group ID and the note
+	groupID, ok := pathGroupID(w, r)
+	if !ok {
+		return
+	}
+	note, ok := parseDeletionNote(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 3: Hide the group
+	if err := h.db.SoftDeleteGroup(r.Context(), groupID, h.deletionPurgeAt(), note); err != nil {
+		writeError(w, err)
+		return
+	}
+	h.infof("Group %s deleted by an admin", groupID)
+
+	// Step 4: Return success (204 No Content)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+DeleteConversationAdmin handles DELETE /admin/conversations/{conversationId}
+operationId: deleteConversationAdmin
+
+Hides a conversation from its participants until it is restored or
+purged. The conversation of a group takes the group with it.
+*/
+func (h *Handler) DeleteConversationAdmin(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check admin authentication
+	if !h.isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Step 2: Get the conversation ID and the note
+	conversationID, ok := pathConversationID(w, r)
+	if !ok {
+		return
+	}
+	note, ok := parseDeletionNote(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 3: Hide the conversation
+	if err := h.db.SoftDeleteConversation(r.Context(), conversationID, h.deletionPurgeAt(), note); err != nil {
+		writeError(w, err)
+		return
+	}
+	h.infof("Conversation %s deleted by an admin", conversationID)
+
+	// Step 4: Return success (204 No Content)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+RestoreGroupAdmin handles POST /admin/groups/{groupId}/restore
+operationId: restoreGroupAdmin
+
+Gives a deleted group and its conversation back to their members.
+*/
+func (h *Handler) RestoreGroupAdmin(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check admin authentication
+	if !h.isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Step 2: Get the group ID and the note
+	groupID, ok := pathGroupID(w, r)
+	if !ok {
+		return
+	}
+	note, ok := parseDeletionNote(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 3: Restore the group
+	if err := h.db.RestoreGroup(r.Context(), groupID, note); err != nil {
+		writeError(w, err)
+		return
+	}
+	h.infof("Group %s restored by an admin", groupID)
+
+	// Step 4: Return success (204 No Content)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+RestoreConversationAdmin handles POST /admin/conversations/{conversationId}/restore
+operationId: restoreConversationAdmin
+
+Gives a deleted conversation back to its participants. A direct
+conversation cannot be restored once its participants started another
+one (409).
+*/
+func (h *Handler) RestoreConversationAdmin(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check admin authentication
+	if !h.isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Step 2: Get the conversation ID and the note
+	conversationID, ok := pathConversationID(w, r)
+	if !ok {
+		return
+	}
+	note, ok := parseDeletionNote(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 3: Restore the conversation
+	if err := h.db.RestoreConversation(r.Context(), conversationID, note); err != nil {
+		writeError(w, err)
+		return
+	}
+	h.infof("Conversation %s restored by an admin", conversationID)
+
+	// Step 4: Return success (204 No Content)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+GetDeletedConversations handles GET /admin/deleted-conversations
+operationId: getDeletedConversations
+
+Returns the deleted groups and conversations not purged yet, the most
+recently deleted first, with when they will be.
+*/
+func (h *Handler) GetDeletedConversations(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check admin authentication
+	if !h.isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Step 2: Get the deleted conversations
+	conversations, err := h.db.GetDeletedConversations(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 3: Convert to response format
+	response := make([]DeletedConversationResponse, 0, len(conversations))
+	for _, dc := range conversations {
+		resp := DeletedConversationResponse{
+			ConversationID: dc.ConversationID,
+			Name:           dc.Name,
+			Participants:   dc.Participants,
+			Messages:       dc.Messages,
+			DeletedAt:      dc.DeletedAt.Format(time.RFC3339),
+		}
+		if dc.GroupID != nil {
+			resp.GroupID = *dc.GroupID
+		}
+		if dc.PurgeAt != nil {
+			resp.PurgeAt = dc.PurgeAt.Format(time.RFC3339)
+		}
+		response = append(response, resp)
+	}
+
+	// Step 4: Return the list
+	writePage(w, r, response)
+}
+
+/*
+GetDeletionAudit handles GET /admin/deletion-audit
+operationId: getDeletionAudit
+
+Returns every deletion, restoration and purge of a group or
+conversation, most recent first.
+*/
+func (h *Handler) GetDeletionAudit(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check admin authentication
+	if !h.isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Step 2: Get the audit log
+	entries, err := h.db.GetDeletionAudit(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 3: Convert to response format
+	response := make([]DeletionAuditResponse, 0, len(entries))
+	for _, e := range entries {
+		resp := DeletionAuditResponse{
+			AuditID:        e.ID,
+			Action:         e.Action,
+			ConversationID: e.ConversationID,
+			Note:           e.Note,
+			CreatedAt:      e.CreatedAt.Format(time.RFC3339),
+		}
+		if e.GroupID != nil {
+			resp.GroupID = *e.GroupID
+		}
+		response = append(response, resp)
+	}
+
+	// Step 4: Return the audit log
+	writePage(w, r, response)
+}
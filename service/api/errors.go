@@ -0,0 +1,57 @@
+/*
+Mapping of domain errors to HTTP responses.
+
+Handlers pass database errors to writeError instead of checking each
+kind themselves; the HTTP status of every kind of failure is decided
+here. A handler only checks a kind itself when its endpoint answers
+differently on purpose (e.g. hiding that a group exists).
+*/
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"wasatext/service/database"
+)
+
+// statusForCode is the HTTP status of each kind of domain error
+var statusForCode = map[database.ErrorCode]int{
+	database.CodeNotFound:  http.StatusNotFound,
+	database.CodeConflict:  http.StatusConflict,
+	database.CodeForbidden: http.StatusForbidden,
+	database.CodeInvalid:   http.StatusBadRequest,
+}
+
+/*
+writeError answers a failed database call. A domain error is answered
+with its status, code and message; anything else is logged and answered
+with a 500 that does not leak internal details.
+*/
+func writeError(w http.ResponseWriter, err error) {
+	var domainErr *database.Error
+	if errors.As(err, &domainErr) {
+		status, ok := statusForCode[domainErr.Code]
+		if !ok {
+			status = http.StatusInternalServerError
+		}
+		writeJSON(w, status, ErrorResponse{
+			Message: capitalize(domainErr.Message),
+			Code:    string(domainErr.Code),
+		})
+		return
+	}
+
+	log.Printf("Internal error: %v", err)
+	http.Error(w, "Internal server error", http.StatusInternalServerError)
+}
+
+// capitalize turns an error message into a sentence for the client
+func capitalize(message string) string {
+	if message == "" {
+		return message
+	}
+	return strings.ToUpper(message[:1]) + message[1:]
+}
@@ -0,0 +1,636 @@
+/*
+Real-time events over WebSocket.
+
+GET /ws upgrades to a WebSocket; from then on the server pushes what
+happens in the user's conversations, so clients do not have to poll
+GET /conversations/{conversationId}. Browsers cannot set the
+Authorization header on a WebSocket, so the session token may also be
+given as ?token=.
+
+Every event is a JSON text message with a type and a conversationId:
+
+	message         a new message (message: same as in the conversation)
+	messageEdited   the text of a message was edited (message)
+	messageDeleted  a message was deleted (messageId)
+	reaction        the reactions of a message changed (messageId, reactions)
+	pin             a message was pinned or unpinned (messageId, pinned)
+	status          the status of your messages changed (statuses: by message ID)
+	typing          someone is typing (userId, userName)
+	reminder        one of your reminders is due (reminder: same as in
+	                GET /users/me/reminders)
+
+GET /events streams the same events as Server-Sent Events, for the
+clients that cannot keep a WebSocket open, and replays those a client
+missed when it reconnects (see sse.go).
+
+Clients send {"type":"typing","conversationId":"..."} while the user
+types. It is only relayed when the user shares typing indicators in that
+conversation, and status events follow the read receipts settings (see
+PUT /conversations/{conversationId}/privacy).
+
+Clients also acknowledge each message event they get with
+{"type":"ack","conversationId":"...","messageId":"..."}: the message is
+then received, and its sender told so, right away. Clients that do not
+ack still deliver their messages by fetching GET /conversations.
+*/
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"wasatext/service/database"
+	"wasatext/service/events"
+	"wasatext/service/ids"
+	"wasatext/service/websocket"
+)
+
+// Types of event
+const (
+	EventMessage        = "message"
+	EventMessageEdited  = "messageEdited"
+	EventMessageDeleted = "messageDeleted"
+	EventReaction       = "reaction"
+	EventPin            = "pin"
+	EventStatus         = "status"
+	EventTyping         = "typing"
+	EventReminder       = "reminder"
+	EventResync         = "resync" // on GET /events only (see sse.go)
+	EventAck            = "ack"    // sent by clients only
+)
+
+const (
+	wsPingInterval = 30 * time.Second // how often the server pings idle clients
+	wsReadTimeout  = 2 * wsPingInterval
+	wsWriteTimeout = 10 * time.Second
+	wsSendBuffer   = 64 // events queued for a client before it is dropped as too slow
+
+	// eventReplaySize and eventReplayWindow bound the events kept for
+	// the clients resuming after a disconnect (see sse.go): the last
+	// ones, while a user may still resume
+	eventReplaySize   = 1000
+	eventReplayWindow = 2 * time.Minute
+
+	// statusEventLimit is how many of the latest messages a status event covers
+	statusEventLimit = 50
+)
+
+// eventHeader starts every event
+type eventHeader struct {
+	Type           string             `json:"type"`
+	ConversationID ids.ConversationID `json:"conversationId"`
+}
+
+// MessageEvent is pushed for a new or edited message
+type MessageEvent struct {
+	eventHeader
+	Message MessageResponse `json:"message"`
+}
+
+// MessageDeletedEvent is pushed when a message is deleted
+type MessageDeletedEvent struct {
+	eventHeader
+	MessageID ids.MessageID `json:"messageId"`
+}
+
+// ReactionEvent is pushed when the reactions of a message change
+type ReactionEvent struct {
+	eventHeader
+	MessageID ids.MessageID     `json:"messageId"`
+	Reactions []CommentResponse `json:"reactions"`
+	Counts    []ReactionCount   `json:"counts"` // the reactions counted by emoticon
+}
+
+// PinEvent is pushed when a message is pinned or unpinned
+type PinEvent struct {
+	eventHeader
+	MessageID ids.MessageID `json:"messageId"`
+	Pinned    bool          `json:"pinned"`
+}
+
+// StatusEvent is pushed to a sender when their messages are received or read
+type StatusEvent struct {
+	eventHeader
+	Statuses map[ids.MessageID]string `json:"statuses"` // sent, received, read
+}
+
+// TypingEvent is pushed while someone is typing
+type TypingEvent struct {
+	eventHeader
+	UserID   ids.UserID `json:"userId"`
+	UserName string     `json:"userName"`
+}
+
+// clientEvent is what clients send over the WebSocket
+type clientEvent struct {
+	Type           string `json:"type"`
+	ConversationID string `json:"conversationId"`
+	MessageID      string `json:"messageId"` // ack
+}
+
+// wsClient is one open WebSocket
+type wsClient struct {
+	userID   ids.UserID
+	userName string
+	conn     *websocket.Conn // nil for an SSE or gRPC stream (see sse.go, grpc.go)
+	send     chan hubEvent
+	done     chan struct{}
+	stop     sync.Once
+}
+
+// hubEvent is an event on its way to the clients, with its ID (see eventID)
+type hubEvent struct {
+	id   string
+	data []byte
+}
+
+// recentEvent is an event kept for the clients resuming after a disconnect
+type recentEvent struct {
+	hubEvent
+	seq     uint64
+	userIDs []ids.UserID
+}
+
+// close stops the client; the writer closes the connection
+func (c *wsClient) close() {
+	c.stop.Do(func() { close(c.done) })
+}
+
+// hub is the registry of the open WebSockets, by user
+type hub struct {
+	mu      sync.Mutex
+	clients map[ids.UserID]map[*wsClient]struct{}
+	served  sync.WaitGroup // the clients not removed yet
+
+	// dropEvent decides whether to lose an event on its way to one
+	// client (see chaos.go); nil keeps them all
+	dropEvent func() bool
+
+	// The last events, numbered within epoch, for the clients resuming
+	// after a disconnect; left is when each user closed their last
+	// client. Once no user may resume, the events are forgotten and
+	// the numbers start over in a new epoch.
+	epoch  string
+	lastID uint64
+	recent []recentEvent
+	left   map[ids.UserID]time.Time
+}
+
+func newHub() *hub {
+	return &hub{
+		clients: make(map[ids.UserID]map[*wsClient]struct{}),
+		epoch:   newEventEpoch(),
+		left:    make(map[ids.UserID]time.Time),
+	}
+}
+
+// newEventEpoch returns an epoch that no earlier run of the hub used
+func newEventEpoch() string {
+	return strconv.FormatInt(time.Now().UnixNano(), 36)
+}
+
+func (hb *hub) add(c *wsClient) {
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+	if hb.clients[c.userID] == nil {
+		hb.clients[c.userID] = make(map[*wsClient]struct{})
+	}
+	hb.clients[c.userID][c] = struct{}{}
+	delete(hb.left, c.userID)
+	hb.served.Add(1)
+}
+
+/*
+addResuming adds a client that resumes after lastEventID, the ID of
+the last event it got (see eventID). It returns the events of the user
+sent since (none for a client that starts afresh, with no ID), and the
+ID of the last event sent; ok is false when they are no longer known: the ID is from another epoch, or too old.
+*/
+func (hb *hub) addResuming(c *wsClient, lastEventID string) (missed []hubEvent, lastID string, ok bool) {
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+	if hb.clients[c.userID] == nil {
+		hb.clients[c.userID] = make(map[*wsClient]struct{})
+	}
+	hb.clients[c.userID][c] = struct{}{}
+	delete(hb.left, c.userID)
+	hb.served.Add(1)
+	lastID = hb.eventID(hb.lastID)
+	if lastEventID == "" {
+		return nil, lastID, true
+	}
+
+	epoch, value, _ := strings.Cut(lastEventID, "-")
+	after, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || epoch != hb.epoch || after > hb.lastID {
+		return nil, lastID, false
+	}
+	if after < hb.lastID && (len(hb.recent) == 0 || hb.recent[0].seq > after+1) {
+		return nil, lastID, false
+	}
+	for _, e := range hb.recent {
+		if e.seq > after && slices.Contains(e.userIDs, c.userID) {
+			missed = append(missed, e.hubEvent)
+		}
+	}
+	return missed, lastID, true
+}
+
+// eventID returns the ID of the event numbered seq, which clients
+// resume from, e.g. "m2x4k1-42"
+func (hb *hub) eventID(seq uint64) string {
+	return hb.epoch + "-" + strconv.FormatUint(seq, 10)
+}
+
+func (hb *hub) remove(c *wsClient) {
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+	delete(hb.clients[c.userID], c)
+	if len(hb.clients[c.userID]) == 0 {
+		delete(hb.clients, c.userID)
+		hb.left[c.userID] = time.Now()
+	}
+	hb.served.Done()
+}
+
+// closeAll closes every WebSocket; wait returns once they are all gone
+func (hb *hub) closeAll() {
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+	for _, clients := range hb.clients {
+		for c := range clients {
+			c.close()
+		}
+	}
+}
+
+// disconnect closes the WebSockets of a user
+func (hb *hub) disconnect(userID ids.UserID) {
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+	for c := range hb.clients[userID] {
+		c.close()
+	}
+}
+
+func (hb *hub) wait() {
+	hb.served.Wait()
+}
+
+// CloseEventStreams closes the WebSockets and the SSE and gRPC event
+// streams, which would otherwise keep a server from shutting down;
+// Shutdown closes them as well
+func (h *Handler) CloseEventStreams() {
+	h.hub.closeAll()
+}
+
+// reachable reports whether a user has an open client, or closed the
+// last one recently enough to resume it
+func (hb *hub) reachable(userID ids.UserID) bool {
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+	left, ok := hb.left[userID]
+	return len(hb.clients[userID]) > 0 || ok && time.Since(left) < eventReplayWindow
+}
+
+// empty reports whether no client is open at all, nor may resume. The
+// events kept are then forgotten, and a new epoch starts.
+func (hb *hub) empty() bool {
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+	if len(hb.clients) > 0 {
+		return false
+	}
+	for userID, left := range hb.left {
+		if time.Since(left) < eventReplayWindow {
+			return false
+		}
+		delete(hb.left, userID)
+	}
+	if hb.lastID > 0 {
+		hb.epoch, hb.lastID, hb.recent = newEventEpoch(), 0, nil
+	}
+	return true
+}
+
+// sendTo queues an event for every client of the users, and keeps it
+// for those who resume. A client whose queue is full is dropped rather
+// than slowing everyone down.
+func (hb *hub) sendTo(userIDs []ids.UserID, data []byte) {
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+	hb.lastID++
+	event := hubEvent{id: hb.eventID(hb.lastID), data: data}
+	if len(hb.recent) == eventReplaySize {
+		hb.recent = slices.Delete(hb.recent, 0, 1)
+	}
+	hb.recent = append(hb.recent, recentEvent{event, hb.lastID, slices.Clone(userIDs)})
+
+	for _, userID := range userIDs {
+		for c := range hb.clients[userID] {
+			if hb.dropEvent != nil && hb.dropEvent() {
+				continue
+			}
+			select {
+			case c.send <- event:
+			default:
+				c.close()
+			}
+		}
+	}
+}
+
+/*
+Events handles GET /ws
+operationId: events
+
+Upgrades to a WebSocket and pushes the events of the user's
+conversations until the connection is closed.
+*/
+func (h *Handler) Events(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication (header, or ?token= for browsers)
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		authUserID = h.sessionUser(r.Context(), r.URL.Query().Get("token"))
+	}
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := h.db.GetUserByID(r.Context(), authUserID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 2: Only pages of the allowed origins may open a WebSocket
+	if origin := r.Header.Get("Origin"); origin != "" && allowedOrigin(h.config().CORSOrigins, origin) == "" {
+		http.Error(w, "Origin not allowed", http.StatusForbidden)
+		return
+	}
+
+	// Step 3: Upgrade (Upgrade answers the error itself)
+	conn, err := websocket.Upgrade(w, r)
+	if err != nil {
+		return
+	}
+
+	// Step 4: Register the client and serve it until it goes away
+	client := &wsClient{
+		userID:   user.ID,
+		userName: user.Name,
+		conn:     conn,
+		send:     make(chan hubEvent, wsSendBuffer),
+		done:     make(chan struct{}),
+	}
+	h.hub.add(client)
+	h.presence.touch(user.ID, time.Now())
+	h.debugf("WebSocket opened for %s", user.ID)
+
+	go h.writeEvents(client)
+	h.readEvents(r.Context(), client)
+
+	h.hub.remove(client)
+	client.close()
+	h.debugf("WebSocket closed for %s", user.ID)
+}
+
+// writeEvents sends the queued events and the pings of a client
+func (h *Handler) writeEvents(c *wsClient) {
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+	defer c.conn.Close()
+
+	for {
+		var err error
+		select {
+		case <-c.done:
+			return
+		case event := <-c.send:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			err = c.conn.WriteText(event.data)
+		case <-ticker.C:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			err = c.conn.Ping()
+		}
+		if err != nil {
+			c.close()
+			return
+		}
+	}
+}
+
+// readEvents handles what a client sends until the connection ends
+func (h *Handler) readEvents(ctx context.Context, c *wsClient) {
+	for {
+		_ = c.conn.SetReadDeadline(time.Now().Add(wsReadTimeout))
+		opcode, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		// Any frame, the pongs to our pings included, is a heartbeat
+		h.presence.touch(c.userID, time.Now())
+		if opcode != websocket.OpText {
+			continue
+		}
+
+		var event clientEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			continue
+		}
+		switch event.Type {
+		case EventTyping:
+			h.relayTyping(ctx, c, event.ConversationID)
+		case EventAck:
+			h.ackMessage(ctx, c, event.ConversationID, event.MessageID)
+		}
+	}
+}
+
+// relayTyping tells the others in a conversation that the user is
+// typing, if the user shares typing indicators there
+func (h *Handler) relayTyping(ctx context.Context, c *wsClient, value string) {
+	conversationID, err := ids.ParseConversationID(value)
+	if err != nil {
+		return
+	}
+
+	// This also checks the user is a participant
+	settings, err := h.db.GetPrivacySettings(ctx, c.userID, conversationID)
+	if err != nil || !settings.TypingIndicators {
+		return
+	}
+
+	h.publishExcept(ctx, conversationID, c.userID, TypingEvent{
+		eventHeader: eventHeader{EventTyping, conversationID},
+		UserID:      c.userID,
+		UserName:    c.userName,
+	})
+}
+
+// ackMessage marks a message as received by the user and tells its
+// sender; acks of unknown or already received messages are ignored
+func (h *Handler) ackMessage(ctx context.Context, c *wsClient, conversationValue, messageValue string) {
+	conversationID, err := ids.ParseConversationID(conversationValue)
+	if err != nil {
+		return
+	}
+	messageID, err := ids.ParseMessageID(messageValue)
+	if err != nil {
+		return
+	}
+
+	changed, err := h.db.MarkMessageDelivered(ctx, c.userID, conversationID, messageID)
+	if err != nil {
+		log.Printf("Error acknowledging %s: %v", messageID, err)
+		return
+	}
+	if changed {
+		h.publishStatuses(ctx, conversationID, c.userID)
+	}
+}
+
+// publish pushes an event to every participant of a conversation
+func (h *Handler) publish(ctx context.Context, conversationID ids.ConversationID, event any) {
+	h.publishExcept(ctx, conversationID, "", event)
+}
+
+// publishExcept pushes an event to every participant of a conversation but one
+func (h *Handler) publishExcept(ctx context.Context, conversationID ids.ConversationID, except ids.UserID, event any) {
+	if h.unheard() {
+		return
+	}
+
+	participants, err := h.db.GetParticipants(ctx, conversationID)
+	if err != nil {
+		log.Printf("Error listing the participants of %s: %v", conversationID, err)
+		return
+	}
+	recipients := participants[:0]
+	for _, id := range participants {
+		if id != except {
+			recipients = append(recipients, id)
+		}
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Error encoding a %T: %v", event, err)
+		return
+	}
+	h.deliver(recipients, data)
+}
+
+// publishMessage pushes a new message to the participants of its conversation
+func (h *Handler) publishMessage(ctx context.Context, e events.MessageCreated) {
+	if h.unheard() {
+		return
+	}
+	h.publish(ctx, e.Message.ConversationID, MessageEvent{
+		eventHeader: eventHeader{EventMessage, e.Message.ConversationID},
+		Message:     h.messageResponse(e.Message),
+	})
+}
+
+// publishReactionAdded and publishReactionRemoved push the reactions
+// of a message after they changed
+func (h *Handler) publishReactionAdded(ctx context.Context, e events.ReactionAdded) {
+	h.publishReactions(ctx, e.UserID, e.ConversationID, e.MessageID)
+}
+
+func (h *Handler) publishReactionRemoved(ctx context.Context, e events.ReactionRemoved) {
+	h.publishReactions(ctx, e.UserID, e.ConversationID, e.MessageID)
+}
+
+// publishReactions pushes the current reactions of a message
+func (h *Handler) publishReactions(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID, messageID ids.MessageID) {
+	if h.unheard() {
+		return
+	}
+
+	comments, err := h.db.GetComments(ctx, userID, conversationID, []ids.MessageID{messageID})
+	if err != nil {
+		log.Printf("Error loading the reactions of %s: %v", messageID, err)
+		return
+	}
+
+	reactions := commentResponses(comments[messageID])
+	h.publish(ctx, conversationID, ReactionEvent{
+		eventHeader: eventHeader{EventReaction, conversationID},
+		MessageID:   messageID,
+		Reactions:   reactions,
+		Counts:      reactionCounts(reactions),
+	})
+}
+
+/*
+publishStatuses pushes the status of the latest messages of a
+conversation to their senders, after someone received or read them.
+Only the senders that are reachable and not the reader are looked up;
+with a backplane, they may be on another instance.
+*/
+func (h *Handler) publishStatuses(ctx context.Context, conversationID ids.ConversationID, readerID ids.UserID) {
+	if h.unheard() {
+		return
+	}
+
+	participants, err := h.db.GetParticipants(ctx, conversationID)
+	if err != nil {
+		log.Printf("Error listing the participants of %s: %v", conversationID, err)
+		return
+	}
+	online := false
+	for _, id := range participants {
+		if id != readerID && (h.hub.reachable(id) || h.config().Backplane.enabled()) {
+			online = true
+			break
+		}
+	}
+	if !online {
+		return
+	}
+
+	statuses, err := h.db.GetMessageStatuses(ctx, conversationID, statusEventLimit)
+	if err != nil {
+		log.Printf("Error loading the statuses of %s: %v", conversationID, err)
+		return
+	}
+
+	bySender := make(map[ids.UserID]map[ids.MessageID]string)
+	for _, st := range statuses {
+		if st.SenderID == readerID {
+			continue
+		}
+		if bySender[st.SenderID] == nil {
+			bySender[st.SenderID] = make(map[ids.MessageID]string)
+		}
+		bySender[st.SenderID][st.ID] = st.Status
+	}
+
+	for senderID, list := range bySender {
+		data, err := json.Marshal(StatusEvent{
+			eventHeader: eventHeader{EventStatus, conversationID},
+			Statuses:    list,
+		})
+		if err != nil {
+			log.Printf("Error encoding a status event: %v", err)
+			return
+		}
+		h.deliver([]ids.UserID{senderID}, data)
+	}
+}
+
+// publishDelivered pushes the statuses of the conversations a user just received
+func (h *Handler) publishDelivered(ctx context.Context, userID ids.UserID, conversations []database.ConversationPreview) {
+	for _, c := range conversations {
+		h.publishStatuses(ctx, c.ID, userID)
+	}
+}
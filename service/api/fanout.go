@@ -0,0 +1,97 @@
+/*
+Receipt fanout worker.
+
+In a group larger than database.FanoutThreshold, sending a message does
+not insert its receipts: SendMessage answers at once and the worker
+inserts them in batches (see service/database/fanout.go). Messages are
+handed to the worker as they are sent; a sweep also picks up every
+pending message from the database, so that fanouts dropped from a full
+queue or interrupted by a restart complete too.
+*/
+package api
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"wasatext/service/database"
+	"wasatext/service/ids"
+)
+
+const (
+	fanoutBatchSize     = 500              // receipts inserted per transaction
+	fanoutQueueSize     = 1024             // messages waiting for the worker
+	fanoutSweepInterval = 30 * time.Second // how often pending messages are looked up
+)
+
+// fanoutWorker inserts the receipts of the messages of large groups
+type fanoutWorker struct {
+	db    database.AppDatabase
+	queue chan ids.MessageID
+}
+
+// newFanoutWorker returns the worker; New starts it
+func newFanoutWorker(db database.AppDatabase) *fanoutWorker {
+	return &fanoutWorker{db: db, queue: make(chan ids.MessageID, fanoutQueueSize)}
+}
+
+// enqueue hands a message whose fanout is pending to the worker. When
+// the queue is full the message is left to the next sweep.
+func (fw *fanoutWorker) enqueue(msg *database.Message) {
+	if msg == nil || !msg.FanoutPending {
+		return
+	}
+	select {
+	case fw.queue <- msg.ID:
+	default:
+	}
+}
+
+// run works until ctx is cancelled. The fanout under way is completed
+// first; the messages still queued are left to the sweep of the next
+// run, as they are pending in the database.
+func (fw *fanoutWorker) run(ctx context.Context) {
+	ticker := time.NewTicker(fanoutSweepInterval)
+	defer ticker.Stop()
+
+	work := context.WithoutCancel(ctx)
+	fw.sweep(work) // resume what a previous run left
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-fw.queue:
+			fw.fanOut(work, id)
+		case <-ticker.C:
+			fw.sweep(work)
+		}
+	}
+}
+
+// sweep completes the fanout of every pending message
+func (fw *fanoutWorker) sweep(ctx context.Context) {
+	pending, err := fw.db.PendingFanouts(ctx)
+	if err != nil {
+		log.Printf("Error listing pending fanouts: %v", err)
+		return
+	}
+	for _, id := range pending {
+		fw.fanOut(ctx, id)
+	}
+}
+
+// fanOut inserts the receipts of one message, batch by batch; on error
+// the message stays pending for the next sweep
+func (fw *fanoutWorker) fanOut(ctx context.Context, id ids.MessageID) {
+	for {
+		done, err := fw.db.FanOutReceipts(ctx, id, fanoutBatchSize)
+		if err != nil {
+			log.Printf("Error fanning out the receipts of %s: %v", id, err)
+			return
+		}
+		if done {
+			return
+		}
+	}
+}
@@ -0,0 +1,317 @@
+/*
+Group governance API handlers.
+
+The group admin can put a group under governance: its members then vote
+on renaming it and on removing members, instead of anyone renaming it
+and nobody being removable. A proposal passes when more than half of
+the members approve it, and the change is made right away; it is
+rejected when half of them or more disapprove (see
+database/governance.go). Proposals and their outcome leave a system
+message in the conversation.
+
+This file contains:
+- setGroupGovernance: Put a group under governance or take it out (admin only)
+- createProposal: Propose to remove a member or to rename the group
+- getProposals: The proposals of a group, open ones first
+- voteOnProposal: Approve or reject a proposal
+*/
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"wasatext/service/database"
+	"wasatext/service/ids"
+)
+
+// SetGovernanceRequest is the body for PUT /groups/{groupId}/governance
+type SetGovernanceRequest struct {
+	Enabled bool `json:"enabled"`
+}
+
+// CreateProposalRequest is the body for POST /groups/{groupId}/proposals
+type CreateProposalRequest struct {
+	Kind   string `json:"kind"`             // remove_member, rename
+	UserID string `json:"userId,omitempty"` // the member to remove
+	Name   string `json:"name,omitempty"`   // the new name
+}
+
+// VoteRequest is the body for PUT /groups/{groupId}/proposals/{proposalId}/vote
+type VoteRequest struct {
+	Approve bool `json:"approve"`
+}
+
+// ProposalResponse represents a proposal in API responses
+type ProposalResponse struct {
+	ProposalID   int64      `json:"proposalId"`
+	Kind         string     `json:"kind"`
+	UserID       ids.UserID `json:"userId,omitempty"`
+	UserName     string     `json:"userName,omitempty"`
+	Name         string     `json:"name,omitempty"`
+	ProposedBy   ids.UserID `json:"proposedBy"`
+	ProposerName string     `json:"proposerName"`
+	CreatedAt    string     `json:"createdAt"`
+	Status       string     `json:"status"` // open, passed, rejected, cancelled
+	ResolvedAt   string     `json:"resolvedAt,omitempty"`
+	Approvals    int        `json:"approvals"`
+	Rejections   int        `json:"rejections"`
+	Members      int        `json:"members"`
+	MyVote       *bool      `json:"myVote,omitempty"`
+}
+
+// proposalResponse converts a proposal to its response format
+func proposalResponse(p *database.Proposal) ProposalResponse {
+	response := ProposalResponse{
+		ProposalID:   p.ID,
+		Kind:         p.Kind,
+		UserID:       p.TargetUserID,
+		UserName:     p.TargetUserName,
+		Name:         p.Name,
+		ProposedBy:   p.ProposedBy,
+		ProposerName: p.ProposerName,
+		CreatedAt:    p.CreatedAt.UTC().Format(time.RFC3339),
+		Status:       p.Status,
+		Approvals:    p.Approvals,
+		Rejections:   p.Rejections,
+		Members:      p.Members,
+		MyVote:       p.MyVote,
+	}
+	if p.ResolvedAt != nil {
+		response.ResolvedAt = p.ResolvedAt.UTC().Format(time.RFC3339)
+	}
+	return response
+}
+
+// proposalChange describes the change of a proposal, for the notices
+func proposalChange(p *database.Proposal) string {
+	if p.Kind == database.ProposalRename {
+		return "rename the group to " + p.Name
+	}
+	return "remove " + p.TargetUserName
+}
+
+/*
+SetGroupGovernance handles PUT /groups/{groupId}/governance
+operationId: setGroupGovernance
+
+Puts a group under governance or takes it out, which cancels its open
+proposals. Only the group admin can do this; a channel cannot be
+governed (409).
+*/
+func (h *Handler) SetGroupGovernance(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get group ID from URL and check the admin
+	groupID, ok := pathGroupID(w, r)
+	if !ok {
+		return
+	}
+	if !h.requireGroupAdmin(r.Context(), w, groupID, authUserID, "change the governance of the group") {
+		return
+	}
+
+	// Step 3: Parse request body
+	var req SetGovernanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Step 4: Change the governance and tell the members
+	group, err := h.db.GetGroup(r.Context(), groupID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := h.db.SetGroupGovernance(r.Context(), groupID, req.Enabled); err != nil {
+		writeError(w, err)
+		return
+	}
+	if group.Governance != req.Enabled {
+		notice := "The members now vote on renaming the group and removing members"
+		if !req.Enabled {
+			notice = "The members no longer vote on renaming the group and removing members"
+		}
+		h.postGroupNotice(r.Context(), groupID, authUserID, notice, nil)
+	}
+
+	// Step 5: Return the group
+	if group, err = h.db.GetGroup(r.Context(), groupID); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, h.groupResponse(group))
+}
+
+/*
+CreateProposal handles POST /groups/{groupId}/proposals
+operationId: createProposal
+
+Proposes to remove a member (kind remove_member, with their userId) or
+to rename a governed group (kind rename, with the name). The proposer
+approves it, so in a small enough group it passes at once.
+*/
+func (h *Handler) CreateProposal(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get group ID from URL
+	groupID, ok := pathGroupID(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 3: Parse and validate the request body
+	var req CreateProposalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	var targetID ids.UserID
+	switch req.Kind {
+	case database.ProposalRemoveMember:
+		userID, err := ids.ParseUserID(req.UserID)
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+		targetID = userID
+	case database.ProposalRename:
+		if req.Name == "" {
+			http.Error(w, "Group name is required", http.StatusBadRequest)
+			return
+		}
+	default:
+		writeError(w, database.ErrInvalidProposal)
+		return
+	}
+
+	// Step 4: Put it to the vote and tell the members
+	proposal, err := h.db.CreateProposal(r.Context(), groupID, authUserID, req.Kind, targetID, req.Name)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if proposal.Status == database.ProposalOpen {
+		h.postGroupNotice(r.Context(), groupID, authUserID, proposal.ProposerName+" proposes to "+proposalChange(proposal)+": members, cast your vote", nil)
+	} else {
+		h.postDecisionNotice(r.Context(), authUserID, proposal)
+	}
+
+	// Step 5: Return the proposal
+	writeJSON(w, http.StatusCreated, proposalResponse(proposal))
+}
+
+/*
+GetProposals handles GET /groups/{groupId}/proposals
+operationId: getProposals
+
+Returns the proposals of a group to its members, the open ones first,
+then the newest, with the votes so far and your own.
+*/
+func (h *Handler) GetProposals(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get group ID from URL
+	groupID, ok := pathGroupID(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 3: Get the proposals
+	proposals, err := h.db.GetProposals(r.Context(), groupID, authUserID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 4: Convert to response format
+	response := make([]ProposalResponse, 0, len(proposals))
+	for i := range proposals {
+		response = append(response, proposalResponse(&proposals[i]))
+	}
+	writePage(w, r, response)
+}
+
+/*
+VoteOnProposal handles PUT /groups/{groupId}/proposals/{proposalId}/vote
+operationId: voteOnProposal
+
+Approves or rejects an open proposal; voting again changes your vote.
+The vote that makes a majority decides the proposal, and a passed one
+is applied at once. A decided proposal takes no more votes (409).
+*/
+func (h *Handler) VoteOnProposal(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get the group and proposal IDs from URL
+	groupID, ok := pathGroupID(w, r)
+	if !ok {
+		return
+	}
+	proposalID, err := strconv.ParseInt(mux.Vars(r)["proposalId"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid proposal ID", http.StatusBadRequest)
+		return
+	}
+
+	// Step 3: Parse request body
+	var req VoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Step 4: Vote, and tell the members if this decided it
+	proposal, err := h.db.VoteOnProposal(r.Context(), groupID, proposalID, authUserID, req.Approve)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if proposal.Status != database.ProposalOpen {
+		h.infof("Proposal %d of group %s %s", proposal.ID, groupID, proposal.Status)
+		h.postDecisionNotice(r.Context(), authUserID, proposal)
+	}
+
+	// Step 5: Return the proposal
+	writeJSON(w, http.StatusOK, proposalResponse(proposal))
+}
+
+// postDecisionNotice tells the members of the group how a proposal was
+// decided
+func (h *Handler) postDecisionNotice(ctx context.Context, senderID ids.UserID, p *database.Proposal) {
+	if p.Status == database.ProposalRejected {
+		h.postGroupNotice(ctx, p.GroupID, senderID, "The members rejected the proposal to "+proposalChange(p), nil)
+		return
+	}
+	notice := &database.Notice{Kind: database.NoticeGroupRenamed, Name: p.Name}
+	if p.Kind == database.ProposalRemoveMember {
+		notice = &database.Notice{Kind: database.NoticeMemberRemoved, UserID: p.TargetUserID}
+	}
+	h.postGroupNotice(ctx, p.GroupID, senderID, "The members voted to "+proposalChange(p), notice)
+}
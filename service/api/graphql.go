@@ -0,0 +1,386 @@
+/*
+GraphQL API handler.
+
+GET /conversations/{id} returns everything about a conversation: its
+members, and every message with its reactions, mentions, reply preview,
+... A client that shows a list of chats with their last few messages
+fetches far more than it shows. POST /graphql lets it ask for the
+fields it needs instead, e.g. the conversations, their last 20 messages
+and the reactions to them in one request:
+
+	{ conversations { name messages(last: 20) { senderName content comments { emoticon } } } }
+
+The GraphQL API only reads: users, conversations, messages and
+reactions, as the user sees them through the REST API, whose response
+types the resolvers return (see service/graphql). The REST API changes
+data.
+
+This file contains:
+- graphql: Run a GraphQL query
+- getGraphQLSchema: The GraphQL schema
+*/
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"wasatext/service/database"
+	"wasatext/service/graphql"
+	"wasatext/service/ids"
+)
+
+// maxGraphQLRequestSize is the largest GraphQL request, in bytes
+const maxGraphQLRequestSize = 64 << 10
+
+// defaultGraphQLMessages is how many messages Conversation.messages
+// returns when the query does not say
+const defaultGraphQLMessages = 20
+
+// graphqlConversation is a conversation of the user, as GET /conversations
+// lists it, resolved by the Conversation type
+type graphqlConversation struct {
+	ConversationPreviewResponse
+	userID ids.UserID
+}
+
+/*
+GraphQL handles POST /graphql and GET /graphql
+operationId: graphql (POST), graphqlGet (GET)
+
+Runs a GraphQL query, posted as JSON ({query, operationName, variables})
+or given in the query string. A query that does not validate is answered
+with 400 and its errors; a query that ran is answered with 200, its data
+and the errors met resolving its fields.
+*/
+func (h *Handler) GraphQL(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	if getUserIDFromAuth(r) == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Read the request
+	var req graphql.Request
+	if r.Method == http.MethodGet {
+		query := r.URL.Query()
+		req.Query = query.Get("query")
+		req.OperationName = query.Get("operationName")
+		if variables := query.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				http.Error(w, "Invalid variables", http.StatusBadRequest)
+				return
+			}
+		}
+	} else if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxGraphQLRequestSize)).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Query == "" {
+		http.Error(w, "Missing query", http.StatusBadRequest)
+		return
+	}
+
+	// Step 3: Run the query
+	response := h.graphql.Execute(r.Context(), req)
+	status := http.StatusOK
+	if !response.Ran() {
+		status = http.StatusBadRequest
+	}
+	writeJSON(w, status, response)
+}
+
+/*
+GetGraphQLSchema handles GET /graphql/schema
+operationId: getGraphQLSchema
+
+Returns the schema of the GraphQL API in the GraphQL schema language,
+for the clients and tools that do not introspect it.
+*/
+func (h *Handler) GetGraphQLSchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte(h.graphql.String()))
+}
+
+// graphqlSchema returns the schema of the GraphQL API; the resolvers read
+// the user from the context, like the handlers
+func (h *Handler) graphqlSchema() *graphql.Schema {
+	user := &graphql.Object{Name: "User", Description: "A user of the workspace"}
+	conversation := &graphql.Object{Name: "Conversation", Description: "A conversation of the user"}
+	message := &graphql.Object{Name: "Message", Description: "A message of a conversation"}
+	replyPreview := &graphql.Object{Name: "ReplyPreview", Description: "The message a message replies to"}
+	mention := &graphql.Object{Name: "Mention", Description: "A participant a message mentions with @name"}
+	comment := &graphql.Object{Name: "Comment", Description: "A reaction to a message"}
+	reactionCount := &graphql.Object{Name: "ReactionCount", Description: "How many users reacted to a message with an emoticon"}
+
+	str := graphql.NonNullOf(graphql.String)
+	id := graphql.NonNullOf(graphql.ID)
+	boolean := graphql.NonNullOf(graphql.Boolean)
+	listOf := func(t graphql.Type) graphql.Type {
+		return graphql.NonNullOf(graphql.ListOf(graphql.NonNullOf(t)))
+	}
+
+	user.Fields = []*graphql.Field{
+		{Name: "identifier", Type: id},
+		{Name: "name", Type: str},
+		{Name: "about", Type: graphql.String, Description: "The status line of the profile"},
+		{Name: "hasPhoto", Type: boolean},
+		{Name: "photoUrl", Type: graphql.String, Description: "Signed and short-lived"},
+		{Name: "bot", Type: boolean, Description: "A bot account"},
+		{Name: "deactivated", Type: boolean},
+		{Name: "role", Type: graphql.String, Description: "admin or member, for the members of a group"},
+		{Name: "online", Type: boolean},
+		{Name: "lastSeen", Type: graphql.String, Description: "The last heartbeat, if known and not hidden"},
+	}
+	conversation.Fields = []*graphql.Field{
+		{Name: "conversationId", Type: id},
+		{Name: "isGroup", Type: boolean},
+		{Name: "isChannel", Type: boolean},
+		{Name: "name", Type: str},
+		{Name: "hasPhoto", Type: boolean},
+		{Name: "photoUrl", Type: graphql.String, Description: "Signed and short-lived"},
+		{Name: "lastMessageTimestamp", Type: graphql.String},
+		{Name: "lastMessagePreview", Type: graphql.String},
+		{Name: "lastMessageIsPhoto", Type: boolean},
+		{Name: "blocked", Type: boolean, Description: "A direct conversation with a user the user blocked"},
+		{Name: "deactivated", Type: boolean, Description: "A direct conversation with a user who deactivated their account"},
+		{Name: "members", Type: listOf(user), Resolve: h.resolveMembers},
+		{
+			Name:        "messages",
+			Type:        listOf(message),
+			Description: "The latest messages, the newest first; before pages back from a message",
+			Args: []*graphql.Arg{
+				{Name: "last", Type: graphql.Int, Default: defaultGraphQLMessages},
+				{Name: "before", Type: graphql.ID},
+			},
+			Resolve: h.resolveMessages,
+		},
+	}
+	message.Fields = []*graphql.Field{
+		{Name: "messageId", Type: id},
+		{Name: "senderId", Type: id},
+		{Name: "senderName", Type: str},
+		{Name: "sender", Type: user, Description: "null when the sender is gone", Resolve: h.resolveSender},
+		{Name: "content", Type: graphql.String},
+		{Name: "language", Type: graphql.String, Description: "Detected in the text (ISO 639-1)"},
+		{Name: "hasPhoto", Type: boolean},
+		{Name: "photoUrl", Type: graphql.String, Description: "Signed and short-lived"},
+		{Name: "processingState", Type: graphql.String, Description: "pending, ready or failed, for a photo"},
+		{Name: "timestamp", Type: str},
+		{Name: "status", Type: str, Description: "sent, received or read"},
+		{Name: "replyTo", Type: graphql.ID},
+		{Name: "replyPreview", Type: replyPreview},
+		{Name: "system", Type: boolean, Description: "A notice about the conversation"},
+		{Name: "edited", Type: boolean},
+		{Name: "editedAt", Type: graphql.String},
+		{Name: "viaHook", Type: boolean},
+		{Name: "deleted", Type: boolean},
+		{Name: "pinned", Type: boolean},
+		{Name: "mentions", Type: listOf(mention)},
+		{Name: "comments", Type: listOf(comment)},
+		{Name: "reactionCounts", Type: listOf(reactionCount), Description: "The comments counted by emoticon, the most used first"},
+	}
+	replyPreview.Fields = []*graphql.Field{
+		{Name: "kind", Type: str, Description: "message, or unavailable when it was deleted"},
+		{Name: "senderName", Type: graphql.String},
+		{Name: "content", Type: graphql.String, Description: "The beginning of the text"},
+		{Name: "hasPhoto", Type: boolean},
+	}
+	mention.Fields = []*graphql.Field{
+		{Name: "userId", Type: id},
+		{Name: "userName", Type: str},
+	}
+	comment.Fields = []*graphql.Field{
+		{Name: "userId", Type: id},
+		{Name: "userName", Type: str},
+		{Name: "emoticon", Type: str},
+	}
+	reactionCount.Fields = []*graphql.Field{
+		{Name: "emoticon", Type: str},
+		{Name: "count", Type: graphql.NonNullOf(graphql.Int)},
+	}
+
+	query := &graphql.Object{Name: "Query", Fields: []*graphql.Field{
+		{Name: "me", Type: graphql.NonNullOf(user), Description: "The user", Resolve: h.resolveMe},
+		{
+			Name:        "user",
+			Type:        user,
+			Description: "A user of the workspace",
+			Args:        []*graphql.Arg{{Name: "id", Type: id}},
+			Resolve:     h.resolveUser,
+		},
+		{
+			Name:        "conversations",
+			Type:        listOf(conversation),
+			Description: "The conversations of the user, the most recent first",
+			Resolve:     h.resolveConversations,
+		},
+		{
+			Name:        "conversation",
+			Type:        conversation,
+			Description: "A conversation of the user",
+			Args:        []*graphql.Arg{{Name: "id", Type: id}},
+			Resolve:     h.resolveConversation,
+		},
+	}}
+	return &graphql.Schema{Query: query}
+}
+
+// graphqlUserID returns the user a query runs for
+func graphqlUserID(ctx context.Context) ids.UserID {
+	userID, _ := ctx.Value(authUserKey{}).(ids.UserID)
+	return userID
+}
+
+// graphqlError returns the error of a resolver as the client sees it, like
+// writeError: a domain error keeps its message, anything else is logged
+// and hidden
+func graphqlError(err error) error {
+	var domainErr *database.Error
+	if errors.As(err, &domainErr) {
+		return errors.New(capitalize(domainErr.Message))
+	}
+	log.Printf("Internal error: %v", err)
+	return errors.New("Internal server error")
+}
+
+// resolveMe resolves Query.me
+func (h *Handler) resolveMe(ctx context.Context, _ any, _ map[string]any) (any, error) {
+	userID := graphqlUserID(ctx)
+	profile, err := h.userProfile(ctx, userID, userID)
+	if err != nil {
+		return nil, graphqlError(err)
+	}
+	return profile, nil
+}
+
+// resolveUser resolves Query.user
+func (h *Handler) resolveUser(ctx context.Context, _ any, args map[string]any) (any, error) {
+	userID, err := ids.ParseUserID(args["id"].(string))
+	if err != nil {
+		return nil, errors.New("Invalid user ID")
+	}
+	profile, err := h.userProfile(ctx, graphqlUserID(ctx), userID)
+	if err != nil {
+		return nil, graphqlError(err)
+	}
+	return profile, nil
+}
+
+// resolveConversations resolves Query.conversations; like
+// GET /conversations, it tells the senders their messages were received
+func (h *Handler) resolveConversations(ctx context.Context, _ any, _ map[string]any) (any, error) {
+	userID := graphqlUserID(ctx)
+	conversations, err := h.db.GetConversations(ctx, userID)
+	if err != nil {
+		return nil, graphqlError(err)
+	}
+	h.publishDelivered(ctx, userID, conversations)
+
+	response := make([]*graphqlConversation, 0, len(conversations))
+	for _, c := range conversations {
+		response = append(response, h.graphqlConversation(userID, c))
+	}
+	return response, nil
+}
+
+// resolveConversation resolves Query.conversation
+func (h *Handler) resolveConversation(ctx context.Context, _ any, args map[string]any) (any, error) {
+	conversationID, err := ids.ParseConversationID(args["id"].(string))
+	if err != nil {
+		return nil, errors.New("Invalid conversation ID")
+	}
+	userID := graphqlUserID(ctx)
+	conversations, err := h.db.GetConversations(ctx, userID)
+	if err != nil {
+		return nil, graphqlError(err)
+	}
+	for _, c := range conversations {
+		if c.ID == conversationID {
+			return h.graphqlConversation(userID, c), nil
+		}
+	}
+	return nil, graphqlError(database.ErrConversationNotFound)
+}
+
+// graphqlConversation converts a conversation of the list to the source
+// of the Conversation type, as GET /conversations does
+func (h *Handler) graphqlConversation(userID ids.UserID, c database.ConversationPreview) *graphqlConversation {
+	preview := ConversationPreviewResponse{
+		ConversationID:     c.ID,
+		IsGroup:            c.IsGroup,
+		IsChannel:          c.IsChannel,
+		Name:               c.Name,
+		HasPhoto:           c.PhotoID != "",
+		PhotoURL:           h.conversationPhotoURL(c.IsGroup, c.PhotoOwnerID, c.PhotoID),
+		LastMessagePreview: c.LastMessagePreview,
+		LastMessageIsPhoto: c.LastMessageIsPhoto,
+		Blocked:            c.Blocked,
+		Deactivated:        c.Deactivated,
+	}
+	if !c.LastMessageTime.IsZero() {
+		preview.LastMessageTime = c.LastMessageTime.Format("2006-01-02T15:04:05Z07:00")
+	}
+	return &graphqlConversation{ConversationPreviewResponse: preview, userID: userID}
+}
+
+// resolveMembers resolves Conversation.members
+func (h *Handler) resolveMembers(ctx context.Context, source any, _ map[string]any) (any, error) {
+	c := source.(*graphqlConversation)
+	conv, err := h.db.GetConversationPage(ctx, c.userID, c.ConversationID, "", 1)
+	if err != nil {
+		return nil, graphqlError(err)
+	}
+	return h.memberResponses(conv.Members), nil
+}
+
+// resolveMessages resolves Conversation.messages; the page size is
+// capped by the server configuration, like for GET /conversations/{id}
+func (h *Handler) resolveMessages(ctx context.Context, source any, args map[string]any) (any, error) {
+	c := source.(*graphqlConversation)
+	last, _ := args["last"].(int)
+	if last < 1 {
+		return nil, errors.New("Invalid last: it must be at least 1")
+	}
+	if maxMessages := h.config().MaxConversationMessages; maxMessages > 0 && last > maxMessages {
+		return nil, fmt.Errorf("Invalid last: at most %d messages are returned", maxMessages)
+	}
+	var before ids.MessageID
+	if value, ok := args["before"].(string); ok {
+		var err error
+		if before, err = ids.ParseMessageID(value); err != nil {
+			return nil, errors.New("Invalid before: not a message ID")
+		}
+	}
+
+	conv, err := h.db.GetConversationPage(ctx, c.userID, c.ConversationID, before, last)
+	if errors.Is(err, database.ErrMessageNotFound) {
+		return nil, errors.New("Invalid before: message not found")
+	}
+	if err != nil {
+		return nil, graphqlError(err)
+	}
+	messages := make([]MessageResponse, 0, len(conv.Messages))
+	for _, msg := range conv.Messages {
+		messages = append(messages, h.messageResponse(msg))
+	}
+	return messages, nil
+}
+
+// resolveSender resolves Message.sender: null rather than an error for a
+// sender who is gone, the message keeps their name
+func (h *Handler) resolveSender(ctx context.Context, source any, _ map[string]any) (any, error) {
+	msg := source.(MessageResponse)
+	profile, err := h.userProfile(ctx, graphqlUserID(ctx), msg.SenderID)
+	if errors.Is(err, database.ErrUserNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, graphqlError(err)
+	}
+	return profile, nil
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"wasatext/service/database"
+	"wasatext/service/database/mock"
+	"wasatext/service/ids"
+)
+
+// postGraphQL calls GraphQL as a user, as the router would
+func postGraphQL(h *Handler, userID ids.UserID, query string, variables map[string]any) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(map[string]any{"query": query, "variables": variables})
+	r := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+	if userID != "" {
+		r = r.WithContext(context.WithValue(r.Context(), authUserKey{}, userID))
+	}
+	w := httptest.NewRecorder()
+	h.GraphQL(w, r)
+	return w
+}
+
+// graphqlDB returns a mock with alice and bob in a direct conversation,
+// where bob wrote to alice, then a user since deleted wrote too
+func graphqlDB(alice, bob database.User) (*mock.AppDatabaseMock, ids.ConversationID) {
+	conversationID := ids.ConversationID("c0ffee00-0000-4000-8000-000000000000")
+	gone := mock.NewUser("carol").Build()
+	older := mock.NewMessage(bob).Content("hi alice").Comment(alice, "👍").At(time.Date(2024, 3, 9, 14, 0, 0, 0, time.UTC)).Build()
+	newer := mock.NewMessage(gone).Content("bye").At(time.Date(2024, 3, 9, 15, 0, 0, 0, time.UTC)).Build()
+
+	db := mock.New().WithUsers(alice, bob)
+	db.GetConversationsFunc = func(context.Context, ids.UserID) ([]database.ConversationPreview, error) {
+		return []database.ConversationPreview{{ID: conversationID, Name: bob.Name, LastMessageTime: newer.Timestamp, LastMessagePreview: "bye"}}, nil
+	}
+	db.GetConversationPageFunc = func(_ context.Context, _ ids.UserID, id ids.ConversationID, _ ids.MessageID, limit int) (*database.Conversation, error) {
+		if id != conversationID {
+			return nil, database.ErrConversationNotFound
+		}
+		messages := []database.Message{newer, older}
+		return &database.Conversation{ID: id, Name: bob.Name, Members: []database.User{bob}, Messages: messages[:min(limit, len(messages))]}, nil
+	}
+	return db, conversationID
+}
+
+func TestGraphQL(t *testing.T) {
+	alice := mock.NewUser("alice").Build()
+	bob := mock.NewUser("bob").Build()
+	db, conversationID := graphqlDB(alice, bob)
+
+	w := postGraphQL(newMockHandler(db), alice.ID, `query ($bob: ID!) {
+		me { name }
+		user(id: $bob) { identifier name }
+		conversations {
+			conversationId name lastMessagePreview
+			members { name }
+			messages(last: 2) { content senderName sender { name } comments { userName emoticon } }
+		}
+	}`, map[string]any{"bob": string(bob.ID)})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	want := `{"data":{` +
+		`"me":{"name":"alice"},` +
+		`"user":{"identifier":"` + string(bob.ID) + `","name":"bob"},` +
+		`"conversations":[{"conversationId":"` + string(conversationID) + `","name":"bob","lastMessagePreview":"bye",` +
+		`"members":[{"name":"bob"}],` +
+		`"messages":[` +
+		`{"content":"bye","senderName":"carol","sender":null,"comments":[]},` +
+		`{"content":"hi alice","senderName":"bob","sender":{"name":"bob"},"comments":[{"userName":"alice","emoticon":"👍"}]}` +
+		`]}]}}`
+	if got := strings.TrimSpace(w.Body.String()); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	// One page for the members, one for the messages; nothing is fetched
+	// for the fields the query did not ask for
+	calls := db.GetConversationPageCalls()
+	if len(calls) != 2 || calls[0].Limit != 1 || calls[1].Limit != 2 {
+		t.Errorf("GetConversationPage calls = %+v, want a limit of 1, then 2", calls)
+	}
+	if n := len(db.GetConversationsCalls()); n != 1 {
+		t.Errorf("GetConversations called %d times, want once", n)
+	}
+}
+
+func TestGraphQLErrors(t *testing.T) {
+	alice := mock.NewUser("alice").Build()
+	bob := mock.NewUser("bob").Build()
+	stranger := mock.NewUser("stranger").Workspace("elsewhere").Build()
+
+	tests := []struct {
+		name    string
+		query   string
+		setup   func(db *mock.AppDatabaseMock)
+		status  int
+		message string // of the first error
+	}{
+		{"invalid query", "{ me { password } }", nil, http.StatusBadRequest, `Cannot query field "password" on type "User".`},
+		{"mutation", "mutation { me { name } }", nil, http.StatusBadRequest, "Only queries are supported, not mutations: use the REST API to change data."},
+		{"bad ID", `{ user(id: "nope") { name } }`, nil, http.StatusOK, "Invalid user ID"},
+		{"user of another workspace", `{ user(id: "` + string(stranger.ID) + `") { name } }`, nil, http.StatusOK, "User not found"},
+		{"unknown conversation", `{ conversation(id: "deadbeef-0000-4000-8000-000000000000") { name } }`, nil, http.StatusOK, "Conversation not found"},
+		{"no messages", "{ conversations { messages(last: 0) { content } } }", nil, http.StatusOK, "Invalid last: it must be at least 1"},
+		{"too many messages", "{ conversations { messages(last: 201) { content } } }", nil, http.StatusOK, "Invalid last: at most 200 messages are returned"},
+		{"internal error", "{ conversations { name } }", func(db *mock.AppDatabaseMock) {
+			db.GetConversationsFunc = func(context.Context, ids.UserID) ([]database.ConversationPreview, error) {
+				return nil, errors.New("disk I/O error")
+			}
+		}, http.StatusOK, "Internal server error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, _ := graphqlDB(alice, bob)
+			db.WithUsers(alice, bob, stranger)
+			if tt.setup != nil {
+				tt.setup(db)
+			}
+			w := postGraphQL(newMockHandler(db), alice.ID, tt.query, nil)
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			var response struct {
+				Errors []struct{ Message string }
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			if len(response.Errors) == 0 || response.Errors[0].Message != tt.message {
+				t.Errorf("errors %+v, want %q", response.Errors, tt.message)
+			}
+		})
+	}
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		db, _ := graphqlDB(alice, bob)
+		if w := postGraphQL(newMockHandler(db), "", "{ me { name } }", nil); w.Code != http.StatusUnauthorized {
+			t.Errorf("status %d, want %d", w.Code, http.StatusUnauthorized)
+		}
+	})
+}
@@ -0,0 +1,305 @@
+/*
+Group event API handlers.
+
+This file contains:
+- createEvent: Post an event in a group
+- setRSVP: Answer whether you go to an event
+- deleteRSVP: Withdraw your answer
+- getRSVPs: List the answers of the members
+
+An event is a message (its content is the title) with a start time and
+a place; the message carries an event summary wherever it is listed.
+An hour before the event, SendEventReminders posts a reminder in the
+group, replying to the event; the server runs it every minute.
+*/
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"wasatext/service/database"
+	"wasatext/service/events"
+	"wasatext/service/ids"
+)
+
+const (
+	maxEventTitleLength    = 100 // characters
+	maxEventLocationLength = 200 // characters
+
+	// eventReminderLead is how long before an event its reminder is
+	// posted; an event starting sooner than that gets none
+	eventReminderLead = time.Hour
+)
+
+// CreateEventRequest is the body for POST /conversations/{id}/events
+type CreateEventRequest struct {
+	Title    string `json:"title"`
+	StartsAt string `json:"startsAt"` // RFC 3339
+	Location string `json:"location,omitempty"`
+}
+
+// RSVPRequest is the body for PUT /messages/{id}/rsvp
+type RSVPRequest struct {
+	Response string `json:"response"` // going, maybe, declined
+}
+
+// EventResponse summarizes an event for a member
+type EventResponse struct {
+	StartsAt string `json:"startsAt"`
+	Location string `json:"location,omitempty"`
+	Going    int    `json:"going"`
+	Maybe    int    `json:"maybe"`
+	Declined int    `json:"declined"`
+	MyRSVP   string `json:"myRsvp,omitempty"` // your answer, if any
+}
+
+// RSVPResponse is the answer of a member
+type RSVPResponse struct {
+	UserID    ids.UserID `json:"userId"`
+	UserName  string     `json:"userName"`
+	Response  string     `json:"response"`
+	UpdatedAt string     `json:"updatedAt"`
+}
+
+// eventResponse converts an event to its response format, nil for none
+func eventResponse(e *database.Event) *EventResponse {
+	if e == nil {
+		return nil
+	}
+	return &EventResponse{
+		StartsAt: e.StartsAt.UTC().Format(time.RFC3339),
+		Location: e.Location,
+		Going:    e.Going,
+		Maybe:    e.Maybe,
+		Declined: e.Declined,
+		MyRSVP:   e.MyRSVP,
+	}
+}
+
+/*
+CreateEvent handles POST /conversations/{conversationId}/events
+operationId: createEvent
+
+Posts an event in a group, as a message whose content is the title.
+*/
+func (h *Handler) CreateEvent(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Parse and validate the event
+	conversationID, ok := pathConversationID(w, r)
+	if !ok {
+		return
+	}
+	var req CreateEventRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	title := strings.TrimSpace(req.Title)
+	if title == "" || utf8.RuneCountInString(title) > maxEventTitleLength {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Message: "title must be between 1 and " + strconv.Itoa(maxEventTitleLength) + " characters"})
+		return
+	}
+	location := strings.TrimSpace(req.Location)
+	if utf8.RuneCountInString(location) > maxEventLocationLength {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Message: "location must be at most " + strconv.Itoa(maxEventLocationLength) + " characters"})
+		return
+	}
+	startsAt, err := time.Parse(time.RFC3339, req.StartsAt)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Message: "startsAt must be an RFC 3339 time"})
+		return
+	}
+	now := time.Now()
+	if !startsAt.After(now) {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Message: "startsAt must be in the future"})
+		return
+	}
+	event := database.NewEvent{Title: title, StartsAt: startsAt, Location: location}
+	if remindAt := startsAt.Add(-eventReminderLead); remindAt.After(now) {
+		event.RemindAt = remindAt
+	}
+
+	// Step 3: Apply the anti-spam limits and the fair-use quotas
+	if !h.checkThrottle(r.Context(), w, authUserID) || !h.checkMessageFlood(r.Context(), w, authUserID, conversationID, title) ||
+		!h.checkUsage(r.Context(), w, authUserID, 1, 0) {
+		return
+	}
+
+	// Step 4: Create the event
+	msg, err := h.db.CreateEvent(r.Context(), conversationID, authUserID, event)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	h.recordUsage(r.Context(), authUserID, 1, 0)
+	h.fanout.enqueue(msg)
+	h.flagFilteredMessage(r.Context(), msg, conversationID)
+
+	// Step 5: Push and return the event message
+	response := MessageResponse{
+		MessageID:      msg.ID,
+		SenderID:       msg.SenderID,
+		SenderName:     msg.SenderName,
+		Content:        msg.Content,
+		Language:       msg.Language,
+		Timestamp:      msg.Timestamp.Format("2006-01-02T15:04:05Z07:00"),
+		Status:         msg.Status,
+		Event:          eventResponse(msg.Event),
+		Mentions:       mentionResponses(msg.Mentions),
+		Comments:       []CommentResponse{},
+		ReactionCounts: []ReactionCount{},
+	}
+	h.bus.Publish(r.Context(), events.MessageCreated{Message: *msg})
+	writeJSON(w, http.StatusCreated, response)
+}
+
+/*
+SetRSVP handles PUT /messages/{messageId}/rsvp
+operationId: setRSVP
+
+Records whether the user goes to the event, replacing their answer.
+*/
+func (h *Handler) SetRSVP(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Parse the answer
+	messageID, ok := pathMessageID(w, r)
+	if !ok {
+		return
+	}
+	var req RSVPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	switch req.Response {
+	case database.RSVPGoing, database.RSVPMaybe, database.RSVPDeclined:
+	default:
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Message: "response must be going, maybe or declined"})
+		return
+	}
+
+	// Step 3: Save it (this also checks the user can read the event)
+	event, err := h.db.SetRSVP(r.Context(), authUserID, messageID, req.Response)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 4: Return the event
+	writeJSON(w, http.StatusOK, eventResponse(event))
+}
+
+/*
+DeleteRSVP handles DELETE /messages/{messageId}/rsvp
+operationId: deleteRSVP
+
+Withdraws the user's answer to the event.
+*/
+func (h *Handler) DeleteRSVP(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Withdraw the answer
+	messageID, ok := pathMessageID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.db.DeleteRSVP(r.Context(), authUserID, messageID); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 3: Return success (204 No Content)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+GetRSVPs handles GET /messages/{messageId}/rsvps
+operationId: getRSVPs
+
+Lists the answers of the members to the event: going first, then maybe,
+then declined.
+*/
+func (h *Handler) GetRSVPs(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get the answers
+	messageID, ok := pathMessageID(w, r)
+	if !ok {
+		return
+	}
+	rsvps, err := h.db.GetRSVPs(r.Context(), authUserID, messageID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 3: Convert to response format and return
+	response := make([]RSVPResponse, 0, len(rsvps))
+	for _, rsvp := range rsvps {
+		response = append(response, RSVPResponse{
+			UserID:    rsvp.UserID,
+			UserName:  rsvp.UserName,
+			Response:  rsvp.Response,
+			UpdatedAt: rsvp.UpdatedAt.Format(time.RFC3339),
+		})
+	}
+	writePage(w, r, response)
+}
+
+/*
+SendEventReminders posts the reminders that are due, each as a system
+message in the group of its event, replying to it. The server runs it
+as a background job; the error is the first one met.
+*/
+func (h *Handler) SendEventReminders(ctx context.Context) error {
+	due, err := h.db.DueEventReminders(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, reminder := range due {
+		notice := "Reminder: " + reminder.Title + " starts " + reminder.StartsAt.UTC().Format("Mon 2 Jan 15:04 MST")
+		if reminder.Location != "" {
+			notice += ", at " + reminder.Location
+		}
+		msg, err := h.db.SendEventReminder(ctx, reminder, notice)
+		if err != nil {
+			return err
+		}
+		if msg == nil {
+			continue
+		}
+		log.Printf("Sent the reminder of event %s", reminder.MessageID)
+		h.fanout.enqueue(msg)
+		h.bus.Publish(ctx, events.MessageCreated{Message: *msg})
+	}
+	return nil
+}
@@ -1,24 +1,33 @@
 /*
 Group API handlers.
 
+Changes to the members and the name of a group leave a notice in its
+conversation, a system message such as "alice added bob" (see
+database/notices.go).
+
 This file contains:
 - createGroup: Create a new group
+- getGroup: The details of a group
 - addToGroup: Add a user to a group
 - leaveGroup: Leave a group
 - setGroupName: Change group name
+- setGroupDescription: Change group description
 - setGroupPhoto: Set group photo
 */
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"io"
+	"log"
 	"net/http"
+	"time"
+	"unicode/utf8"
 
 	"wasatext/service/database"
-
-	"github.com/gorilla/mux"
+	"wasatext/service/events"
+	"wasatext/service/ids"
 )
 
 // CreateGroupRequest is the body for POST /groups
@@ -37,12 +46,72 @@ type SetGroupNameRequest struct {
 	Name string `json:"name"`
 }
 
+// SetGroupDescriptionRequest is the body for PUT /groups/{groupId}/description
+type SetGroupDescriptionRequest struct {
+	Description string `json:"description"`
+}
+
+// maxGroupDescriptionLength is the longest group description, in characters
+const maxGroupDescriptionLength = 512
+
+// Roles of the group members
+const (
+	roleAdmin  = "admin"
+	roleMember = "member"
+)
+
 // GroupResponse represents a group in API responses
 type GroupResponse struct {
-	GroupID  string         `json:"groupId"`
-	Name     string         `json:"name"`
-	HasPhoto bool           `json:"hasPhoto"`
-	Members  []UserResponse `json:"members"`
+	GroupID     ids.GroupID    `json:"groupId"`
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Kind        string         `json:"kind"` // group, channel
+	Governance  bool           `json:"governance"`
+	HasPhoto    bool           `json:"hasPhoto"`
+	PhotoURL    string         `json:"photoUrl,omitempty"`
+	CreatedAt   string         `json:"createdAt,omitempty"`
+	Members     []UserResponse `json:"members"` // with their role
+}
+
+// NoticeResponse is what a group notice reports, for clients rendering
+// it their own way rather than showing its text
+type NoticeResponse struct {
+	Kind     string     `json:"kind"`               // member_added, member_joined, member_left, member_removed, group_renamed
+	UserID   ids.UserID `json:"userId,omitempty"`   // the member added or removed
+	UserName string     `json:"userName,omitempty"` // their current name
+	Name     string     `json:"name,omitempty"`     // the new name of the group
+}
+
+// noticeResponse converts a notice to its response format
+func noticeResponse(n *database.Notice) *NoticeResponse {
+	if n == nil {
+		return nil
+	}
+	return &NoticeResponse{Kind: n.Kind, UserID: n.UserID, UserName: n.UserName, Name: n.Name}
+}
+
+// groupResponse converts a group to its response format
+func (h *Handler) groupResponse(group *database.Group) GroupResponse {
+	response := GroupResponse{
+		GroupID:     group.ID,
+		Name:        group.Name,
+		Description: group.Description,
+		Kind:        group.Kind,
+		Governance:  group.Governance,
+		HasPhoto:    group.PhotoID != "",
+		PhotoURL:    h.photoURL(mediaGroup, string(group.ID), group.PhotoID),
+	}
+	if group.CreatedAt != nil {
+		response.CreatedAt = group.CreatedAt.UTC().Format(time.RFC3339)
+	}
+	response.Members = h.memberResponses(group.Members)
+	for i := range response.Members {
+		response.Members[i].Role = roleMember
+		if response.Members[i].Identifier == group.AdminID {
+			response.Members[i].Role = roleAdmin
+		}
+	}
+	return response
 }
 
 /*
@@ -73,31 +142,89 @@ func (h *Handler) CreateGroup(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Group name is required", http.StatusBadRequest)
 		return
 	}
+	memberIDs := make([]ids.UserID, 0, len(req.MemberIDs))
+	for _, value := range req.MemberIDs {
+		memberID, err := ids.ParseUserID(value)
+		if err != nil {
+			http.Error(w, "Invalid member ID", http.StatusBadRequest)
+			return
+		}
+		memberIDs = append(memberIDs, memberID)
+	}
 
-	// Step 4: Create the group
-	group, err := h.db.CreateGroup(req.Name, authUserID, req.MemberIDs)
+	// Step 4: Apply the anti-spam limits
+	if !h.checkNewConversationLimit(r.Context(), w, authUserID) {
+		return
+	}
+
+	// Step 5: Create the group
+	group, err := h.db.CreateGroup(r.Context(), req.Name, authUserID, memberIDs)
 	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
-	// Step 5: Convert to response format
-	response := GroupResponse{
-		GroupID:  group.ID,
-		Name:     group.Name,
-		HasPhoto: len(group.Photo) > 0,
+	// Step 6: Convert to response format
+	response := h.groupResponse(group)
+
+	// Step 7: Return the group
+	writeJSON(w, http.StatusCreated, response)
+}
+
+/*
+GetGroup handles GET /groups/{groupId}
+operationId: getGroup
+
+Returns the details of a group: its name, description, photo, creation
+time and members with their role. Users cannot see groups they are not
+part of (404), except channels, which anyone in their workspace can
+join.
+*/
+func (h *Handler) GetGroup(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get group ID from URL
+	groupID, ok := pathGroupID(w, r)
+	if !ok {
+		return
 	}
 
-	for _, m := range group.Members {
-		response.Members = append(response.Members, UserResponse{
-			Identifier: m.ID,
-			Name:       m.Name,
-			HasPhoto:   len(m.Photo) > 0,
-		})
+	// Step 3: Get the group
+	group, err := h.db.GetGroup(r.Context(), groupID)
+	if err != nil {
+		writeError(w, err)
+		return
 	}
 
-	// Step 6: Return the group
-	writeJSON(w, http.StatusCreated, response)
+	// Step 4: Check that the user may see it
+	isMember, err := h.db.IsGroupMember(r.Context(), groupID, authUserID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if !isMember {
+		visible := false
+		if group.Kind == database.GroupKindChannel {
+			user, err := h.db.GetUserByID(r.Context(), authUserID)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+			visible = user.WorkspaceID == group.WorkspaceID
+		}
+		if !visible {
+			writeError(w, database.ErrGroupNotFound)
+			return
+		}
+	}
+
+	// Step 5: Return the group
+	writeJSON(w, http.StatusOK, h.groupResponse(group))
 }
 
 /*
@@ -107,6 +234,9 @@ operationId: addToGroup
 From PDF:
 "Group members can add other users to the group, but users cannot
 join groups on their own or even see groups they aren't a part of."
+
+Channels are public: anyone in the workspace joins by adding themselves,
+and only the admin adds others (see channels.go).
 */
 func (h *Handler) AddToGroup(w http.ResponseWriter, r *http.Request) {
 	// Step 1: Check authentication
@@ -117,8 +247,10 @@ func (h *Handler) AddToGroup(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Step 2: Get group ID from URL
-	vars := mux.Vars(r)
-	groupID := vars["groupId"]
+	groupID, ok := pathGroupID(w, r)
+	if !ok {
+		return
+	}
 
 	// Step 3: Parse request body
 	var req AddToGroupRequest
@@ -126,28 +258,29 @@ func (h *Handler) AddToGroup(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	userID, err := ids.ParseUserID(req.UserID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
 
 	// Step 4: Add the user to the group
 	// The database function checks if the adder is a member
-	err := h.db.AddUserToGroup(groupID, req.UserID, authUserID)
-	if errors.Is(err, database.ErrGroupNotFound) {
-		http.Error(w, "Group not found", http.StatusNotFound)
-		return
-	}
-	if errors.Is(err, database.ErrNotGroupMember) {
-		http.Error(w, "Not a member of this group", http.StatusForbidden)
-		return
-	}
-	if errors.Is(err, database.ErrUserNotFound) {
-		http.Error(w, "User not found", http.StatusNotFound)
-		return
-	}
+	err = h.db.AddUserToGroup(r.Context(), groupID, userID, authUserID)
 	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
-	// Step 5: Return success (201 Created)
+	// Step 5: Tell the members
+	if userID == authUserID {
+		h.postMemberNotice(r.Context(), groupID, authUserID, database.NoticeMemberJoined, "")
+	} else {
+		h.postMemberNotice(r.Context(), groupID, authUserID, database.NoticeMemberAdded, userID)
+	}
+	h.bus.Publish(r.Context(), events.MemberJoined{GroupID: groupID, UserID: userID, AddedBy: authUserID})
+
+	// Step 6: Return success (201 Created)
 	w.WriteHeader(http.StatusCreated)
 }
 
@@ -167,25 +300,27 @@ func (h *Handler) LeaveGroup(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Step 2: Get group ID from URL
-	vars := mux.Vars(r)
-	groupID := vars["groupId"]
-
-	// Step 3: Remove the user from the group
-	err := h.db.RemoveUserFromGroup(groupID, authUserID)
-	if errors.Is(err, database.ErrGroupNotFound) {
-		http.Error(w, "Group not found", http.StatusNotFound)
+	groupID, ok := pathGroupID(w, r)
+	if !ok {
 		return
 	}
+
+	// Step 3: Remove the user from the group
+	// Users cannot see groups they are not part of, so this is a 404
+	err := h.db.RemoveUserFromGroup(r.Context(), groupID, authUserID)
 	if errors.Is(err, database.ErrNotGroupMember) {
 		http.Error(w, "Not a member of this group", http.StatusNotFound)
 		return
 	}
 	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
-	// Step 4: Return success (204 No Content)
+	// Step 4: Tell the members left
+	h.postMemberNotice(r.Context(), groupID, authUserID, database.NoticeMemberLeft, "")
+
+	// Step 5: Return success (204 No Content)
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -193,7 +328,9 @@ func (h *Handler) LeaveGroup(w http.ResponseWriter, r *http.Request) {
 SetGroupName handles PUT /groups/{groupId}/name
 operationId: setGroupName
 
-Allows group members to change the group name.
+Allows group members to change the group name
+(only the admin in a channel). A governed group is renamed by a vote of
+its members instead (409, see governance.go).
 */
 func (h *Handler) SetGroupName(w http.ResponseWriter, r *http.Request) {
 	// Step 1: Check authentication
@@ -204,17 +341,22 @@ func (h *Handler) SetGroupName(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Step 2: Get group ID from URL
-	vars := mux.Vars(r)
-	groupID := vars["groupId"]
+	groupID, ok := pathGroupID(w, r)
+	if !ok {
+		return
+	}
 
-	// Step 3: Check if user is a member
-	isMember, err := h.db.IsGroupMember(groupID, authUserID)
+	// Step 3: Check if user may edit the group
+	if !h.requireGroupEditor(r.Context(), w, groupID, authUserID) {
+		return
+	}
+	governed, err := h.db.IsGroupGoverned(r.Context(), groupID)
 	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
-	if !isMember {
-		http.Error(w, "Not a member of this group", http.StatusForbidden)
+	if governed {
+		writeError(w, database.ErrGroupGoverned)
 		return
 	}
 
@@ -231,25 +373,86 @@ func (h *Handler) SetGroupName(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Step 5: Update the group name
-	err = h.db.UpdateGroupName(groupID, req.Name)
-	if errors.Is(err, database.ErrGroupNotFound) {
-		http.Error(w, "Group not found", http.StatusNotFound)
-		return
-	}
+	err = h.db.UpdateGroupName(r.Context(), groupID, req.Name)
 	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
-	// Step 6: Return success
+	// Step 6: Tell the members
+	if user, err := h.db.GetUserByID(r.Context(), authUserID); err != nil {
+		log.Printf("Error getting user %s for the rename notice: %v", authUserID, err)
+	} else {
+		h.postGroupNotice(r.Context(), groupID, authUserID, user.Name+" renamed the group to "+req.Name,
+			&database.Notice{Kind: database.NoticeGroupRenamed, Name: req.Name})
+	}
+
+	// Step 7: Return success
 	w.WriteHeader(http.StatusOK)
 }
 
+/*
+SetGroupDescription handles PUT /groups/{groupId}/description
+operationId: setGroupDescription
+
+Allows group members to change the group description, up to
+maxGroupDescriptionLength characters (only the admin in a channel). An
+empty description removes it. Unlike the name, it is not put to the vote
+in a governed group.
+*/
+func (h *Handler) SetGroupDescription(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get group ID from URL
+	groupID, ok := pathGroupID(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 3: Check if user may edit the group
+	if !h.requireGroupEditor(r.Context(), w, groupID, authUserID) {
+		return
+	}
+
+	// Step 4: Parse and validate the request body
+	var req SetGroupDescriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if utf8.RuneCountInString(req.Description) > maxGroupDescriptionLength {
+		http.Error(w, "Group description too long", http.StatusBadRequest)
+		return
+	}
+
+	// Step 5: Update the group description
+	if err := h.db.UpdateGroupDescription(r.Context(), groupID, req.Description); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 6: Return the group
+	group, err := h.db.GetGroup(r.Context(), groupID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, h.groupResponse(group))
+}
+
 /*
 SetGroupPhoto handles PUT /groups/{groupId}/photo
 operationId: setGroupPhoto
 
-Allows group members to set the group photo.
+Allows group members to set the group photo
+(only the admin in a channel). The photo of a governed group is frozen
+like its name (409): it cannot be put to the vote, so its members take
+the group out of governance to change it.
 */
 func (h *Handler) SetGroupPhoto(w http.ResponseWriter, r *http.Request) {
 	// Step 1: Check authentication
@@ -260,43 +463,117 @@ func (h *Handler) SetGroupPhoto(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Step 2: Get group ID from URL
-	vars := mux.Vars(r)
-	groupID := vars["groupId"]
+	groupID, ok := pathGroupID(w, r)
+	if !ok {
+		return
+	}
 
-	// Step 3: Check if user is a member
-	isMember, err := h.db.IsGroupMember(groupID, authUserID)
+	// Step 3: Check if user may edit the group
+	if !h.requireGroupEditor(r.Context(), w, groupID, authUserID) {
+		return
+	}
+	governed, err := h.db.IsGroupGoverned(r.Context(), groupID)
 	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
-	if !isMember {
-		http.Error(w, "Not a member of this group", http.StatusForbidden)
+	if governed {
+		writeError(w, database.ErrGroupGoverned)
 		return
 	}
 
 	// Step 4: Read the photo from request body
-	photo, err := io.ReadAll(r.Body)
-	if err != nil {
-		http.Error(w, "Failed to read photo", http.StatusBadRequest)
+	photo, ok := h.readPhoto(w, r)
+	if !ok {
 		return
 	}
 
-	if len(photo) == 0 {
-		http.Error(w, "No photo provided", http.StatusBadRequest)
+	// Step 5: Apply the fair-use quotas
+	if !h.checkUsage(r.Context(), w, authUserID, 0, 1) {
 		return
 	}
 
-	// Step 5: Update the group photo
-	err = h.db.UpdateGroupPhoto(groupID, photo)
-	if errors.Is(err, database.ErrGroupNotFound) {
-		http.Error(w, "Group not found", http.StatusNotFound)
+	// Step 6: Update the group photo
+	err = h.db.UpdateGroupPhoto(r.Context(), groupID, photo)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	h.recordUsage(r.Context(), authUserID, 0, 1)
+	h.media.poke()
+
+	// Step 7: Return success
+	w.WriteHeader(http.StatusOK)
+}
+
+// postMemberNotice tells the members of a group that the actor joined
+// or left it, or added another user (userID, NoticeMemberAdded only). A
+// channel does not announce its subscribers, who can be many.
+func (h *Handler) postMemberNotice(ctx context.Context, groupID ids.GroupID, actorID ids.UserID, kind string, userID ids.UserID) {
+	group, err := h.db.GetGroup(ctx, groupID)
+	if err != nil {
+		log.Printf("Error getting group %s for a notice: %v", groupID, err)
+		return
+	}
+	if group.Kind == database.GroupKindChannel {
 		return
 	}
+	actor, err := h.db.GetUserByID(ctx, actorID)
 	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		log.Printf("Error getting user %s for a notice to group %s: %v", actorID, groupID, err)
 		return
 	}
 
-	// Step 6: Return success
-	w.WriteHeader(http.StatusOK)
+	var text string
+	switch kind {
+	case database.NoticeMemberJoined:
+		text = actor.Name + " joined"
+	case database.NoticeMemberLeft:
+		text = actor.Name + " left"
+	case database.NoticeMemberAdded:
+		user, err := h.db.GetUserByID(ctx, userID)
+		if err != nil {
+			log.Printf("Error getting user %s for a notice to group %s: %v", userID, groupID, err)
+			return
+		}
+		text = actor.Name + " added " + user.Name
+	}
+	h.postGroupNotice(ctx, groupID, actorID, text, &database.Notice{Kind: kind, UserID: userID})
+}
+
+// postGroupNotice sends a system message to a group and delivers it. The
+// change it reports is already made, so a failure is only logged.
+func (h *Handler) postGroupNotice(ctx context.Context, groupID ids.GroupID, senderID ids.UserID, text string, notice *database.Notice) {
+	msg, err := h.db.PostGroupNotice(ctx, groupID, senderID, text, notice)
+	if err != nil {
+		log.Printf("Error posting a notice to group %s: %v", groupID, err)
+		return
+	}
+	h.fanout.enqueue(msg)
+	h.bus.Publish(ctx, events.MessageCreated{Message: *msg})
+}
+
+// requireGroupEditor checks that the user may change the name and photo
+// of the group: any member of a group, only the admin of a channel.
+// It returns false when a response has already been written.
+func (h *Handler) requireGroupEditor(ctx context.Context, w http.ResponseWriter, groupID ids.GroupID, userID ids.UserID) bool {
+	group, err := h.db.GetGroup(ctx, groupID)
+	if err != nil {
+		writeError(w, err)
+		return false
+	}
+	if group.Kind == database.GroupKindChannel {
+		return h.requireGroupAdmin(ctx, w, groupID, userID, "edit the channel")
+	}
+
+	isMember, err := h.db.IsGroupMember(ctx, groupID, userID)
+	if err != nil {
+		writeError(w, err)
+		return false
+	}
+	if !isMember {
+		http.Error(w, "Not a member of this group", http.StatusForbidden)
+		return false
+	}
+	return true
 }
@@ -0,0 +1,351 @@
+/*
+gRPC API.
+
+Command-line tools and bots may prefer a typed protocol to REST: the
+server can also serve the WASAText service of doc/wasatext.proto over
+gRPC, on a second port (see cmd/webapi). The service mirrors a few REST
+operations:
+
+	GetConversations  GET /conversations
+	GetConversation   GET /conversations/{conversationId}
+	SendMessage       POST /conversations/{conversationId}/messages
+	StreamMessages    the message events of GET /ws
+
+The unary methods do not reimplement the operations: they run the REST
+request through the router, with the Authorization of the call, so that
+the authentication, rate limits, checks and API usage are those of the
+REST API, and turn its JSON response into the protobuf one (its error
+into a gRPC status). StreamMessages registers with the hub like a
+WebSocket.
+
+This file contains:
+- NewGRPCServer: The gRPC server of the API
+- streamMessages: The new messages, as they are sent
+*/
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"wasatext/service/grpc"
+	"wasatext/service/wasatextpb"
+)
+
+// grpcAPI serves the methods of the WASAText gRPC service
+type grpcAPI struct {
+	h      *Handler
+	router http.Handler // the REST API the unary methods run through
+}
+
+/*
+NewGRPCServer returns the gRPC server of the API. router is the REST
+API (see NewRouter), which its unary methods call: it must be served
+with HTTP/2, e.g. unencrypted (h2c) on a port of its own.
+*/
+func NewGRPCServer(h *Handler, router http.Handler) *grpc.Server {
+	g := &grpcAPI{h: h, router: router}
+	server := grpc.NewServer()
+	server.HandleUnary(wasatextpb.MethodGetConversations, g.getConversations)
+	server.HandleUnary(wasatextpb.MethodGetConversation, g.getConversation)
+	server.HandleUnary(wasatextpb.MethodSendMessage, g.sendMessage)
+	server.HandleStream(wasatextpb.MethodStreamMessages, g.streamMessages)
+	return server
+}
+
+// getConversations serves GetConversations with GET /conversations
+func (g *grpcAPI) getConversations(ctx context.Context, call *grpc.Call) (grpc.Marshaler, error) {
+	var req wasatextpb.GetConversationsRequest
+	if err := call.Decode(&req); err != nil {
+		return nil, err
+	}
+	query := url.Values{}
+	if req.Limit != 0 {
+		query.Set("limit", strconv.Itoa(int(req.Limit)))
+	}
+	if req.Cursor != "" {
+		query.Set("cursor", req.Cursor)
+	}
+	var page Page[ConversationPreviewResponse]
+	if err := g.rest(ctx, call, http.MethodGet, withQuery("/conversations", query), nil, &page); err != nil {
+		return nil, err
+	}
+
+	response := &wasatextpb.GetConversationsResponse{Total: int32(page.Total), NextCursor: page.NextCursor}
+	for _, c := range page.Items {
+		response.Conversations = append(response.Conversations, &wasatextpb.ConversationPreview{
+			ConversationID:       string(c.ConversationID),
+			IsGroup:              c.IsGroup,
+			IsChannel:            c.IsChannel,
+			Name:                 c.Name,
+			PhotoURL:             c.PhotoURL,
+			LastMessageTimestamp: c.LastMessageTime,
+			LastMessagePreview:   c.LastMessagePreview,
+			LastMessageIsPhoto:   c.LastMessageIsPhoto,
+			Blocked:              c.Blocked,
+		})
+	}
+	return response, nil
+}
+
+// getConversation serves GetConversation with GET /conversations/{conversationId}
+func (g *grpcAPI) getConversation(ctx context.Context, call *grpc.Call) (grpc.Marshaler, error) {
+	var req wasatextpb.GetConversationRequest
+	if err := call.Decode(&req); err != nil {
+		return nil, err
+	}
+	if req.ConversationID == "" {
+		return nil, grpc.Errorf(grpc.InvalidArgument, "The conversation_id is required")
+	}
+	query := url.Values{}
+	if req.Limit != 0 {
+		query.Set("limit", strconv.Itoa(int(req.Limit)))
+	}
+	if req.Before != "" {
+		query.Set("before", req.Before)
+	}
+	var conv ConversationResponse
+	target := withQuery("/conversations/"+url.PathEscape(req.ConversationID), query)
+	if err := g.rest(ctx, call, http.MethodGet, target, nil, &conv); err != nil {
+		return nil, err
+	}
+
+	response := &wasatextpb.Conversation{
+		ConversationID: string(conv.ConversationID),
+		IsGroup:        conv.IsGroup,
+		IsChannel:      conv.IsChannel,
+		Name:           conv.Name,
+		PhotoURL:       conv.PhotoURL,
+		HasMore:        conv.HasMore,
+		NextBefore:     string(conv.NextBefore),
+	}
+	for _, u := range conv.Members {
+		response.Members = append(response.Members, &wasatextpb.User{
+			Identifier: string(u.Identifier),
+			Name:       u.Name,
+			Role:       u.Role,
+			Online:     u.Online,
+			Bot:        u.Bot,
+		})
+	}
+	for _, m := range conv.Messages {
+		response.Messages = append(response.Messages, pbMessage(string(conv.ConversationID), m))
+	}
+	return response, nil
+}
+
+// sendMessage serves SendMessage with POST /conversations/{conversationId}/messages
+func (g *grpcAPI) sendMessage(ctx context.Context, call *grpc.Call) (grpc.Marshaler, error) {
+	var req wasatextpb.SendMessageRequest
+	if err := call.Decode(&req); err != nil {
+		return nil, err
+	}
+	if req.ConversationID == "" {
+		return nil, grpc.Errorf(grpc.InvalidArgument, "The conversation_id is required")
+	}
+	var sent MessageResponse
+	target := "/conversations/" + url.PathEscape(req.ConversationID) + "/messages"
+	body := SendMessageRequest{Content: req.Content, ReplyTo: req.ReplyTo}
+	if err := g.rest(ctx, call, http.MethodPost, target, body, &sent); err != nil {
+		return nil, err
+	}
+	return pbMessage(req.ConversationID, sent), nil
+}
+
+/*
+streamMessages serves StreamMessages: the message events of the user's
+conversations (of one, if the request names it), until the call is
+canceled. The stream is a client of the hub like a WebSocket, without a
+connection (conn is nil): a stream that falls behind is closed the same
+way, and ends with Unavailable.
+*/
+func (g *grpcAPI) streamMessages(ctx context.Context, call *grpc.Call, stream *grpc.ServerStream) error {
+	// Step 1: Check authentication
+	userID := g.h.sessionUser(ctx, getBearerToken(&http.Request{Header: call.Header}))
+	if userID == "" {
+		return grpc.Errorf(grpc.Unauthenticated, "Unauthorized")
+	}
+	user, err := g.h.db.GetUserByID(ctx, userID)
+	if err != nil {
+		return grpcStatus(err)
+	}
+	var req wasatextpb.StreamMessagesRequest
+	if err := call.Decode(&req); err != nil {
+		return err
+	}
+
+	// Step 2: Register with the hub until the call ends
+	client := &wsClient{
+		userID:   user.ID,
+		userName: user.Name,
+		send:     make(chan hubEvent, wsSendBuffer),
+		done:     make(chan struct{}),
+	}
+	g.h.hub.add(client)
+	defer g.h.hub.remove(client)
+	defer client.close()
+
+	// Step 3: Send the message events
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-client.done:
+			return grpc.Errorf(grpc.Unavailable, "The stream was closed, call again")
+		case sent := <-client.send:
+			var event MessageEvent
+			if err := json.Unmarshal(sent.data, &event); err != nil || event.Type != EventMessage {
+				continue
+			}
+			if req.ConversationID != "" && string(event.ConversationID) != req.ConversationID {
+				continue
+			}
+			if err := stream.Send(pbMessage(string(event.ConversationID), event.Message)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// rest runs a request of the REST API for a call, and decodes its JSON
+// response into response; a failed request returns its gRPC status
+func (g *grpcAPI) rest(ctx context.Context, call *grpc.Call, method, target string, body, response any) error {
+	var reader io.Reader = http.NoBody
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	r, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return grpc.Errorf(grpc.InvalidArgument, "%v", err)
+	}
+	r.RemoteAddr = call.RemoteAddr
+	r.Header.Set("Authorization", call.Header.Get("Authorization"))
+	r.Header.Set("User-Agent", call.Header.Get("User-Agent"))
+	if body != nil {
+		r.Header.Set("Content-Type", "application/json")
+	}
+
+	recorder := &restRecorder{header: make(http.Header), status: http.StatusOK}
+	g.router.ServeHTTP(recorder, r)
+	if recorder.status >= 300 {
+		return recorder.statusError()
+	}
+	if err := json.Unmarshal(recorder.body.Bytes(), response); err != nil {
+		return fmt.Errorf("decoding the response of %s %s: %w", method, target, err)
+	}
+	return nil
+}
+
+// withQuery appends a query string to a path
+func withQuery(path string, query url.Values) string {
+	if len(query) == 0 {
+		return path
+	}
+	return path + "?" + query.Encode()
+}
+
+// restRecorder keeps the response of a REST request run for a gRPC call
+type restRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+	wrote  bool
+}
+
+func (rr *restRecorder) Header() http.Header {
+	return rr.header
+}
+
+func (rr *restRecorder) WriteHeader(status int) {
+	if !rr.wrote {
+		rr.status, rr.wrote = status, true
+	}
+}
+
+func (rr *restRecorder) Write(data []byte) (int, error) {
+	rr.wrote = true
+	return rr.body.Write(data)
+}
+
+// grpcCodeForStatus is the gRPC code of each HTTP status of a failed
+// REST request; the other 4xx are FailedPrecondition, the other 5xx
+// Internal
+var grpcCodeForStatus = map[int]grpc.Code{
+	http.StatusBadRequest:            grpc.InvalidArgument,
+	http.StatusUnauthorized:          grpc.Unauthenticated,
+	http.StatusForbidden:             grpc.PermissionDenied,
+	http.StatusNotFound:              grpc.NotFound,
+	http.StatusGone:                  grpc.NotFound,
+	http.StatusConflict:              grpc.FailedPrecondition,
+	http.StatusRequestEntityTooLarge: grpc.InvalidArgument,
+	http.StatusTooManyRequests:       grpc.ResourceExhausted,
+	http.StatusNotImplemented:        grpc.Unimplemented,
+	http.StatusServiceUnavailable:    grpc.Unavailable,
+}
+
+// statusError turns a failed REST response into a gRPC status, with the
+// message of its ErrorResponse or its plain text body
+func (rr *restRecorder) statusError() error {
+	code, ok := grpcCodeForStatus[rr.status]
+	if !ok {
+		code = grpc.Internal
+		if rr.status < 500 {
+			code = grpc.FailedPrecondition
+		}
+	}
+	message := strings.TrimSpace(rr.body.String())
+	if strings.HasPrefix(rr.header.Get("Content-Type"), "application/json") {
+		var body ErrorResponse
+		if err := json.Unmarshal(rr.body.Bytes(), &body); err == nil && body.Message != "" {
+			message = body.Message
+		}
+	}
+	if message == "" {
+		message = http.StatusText(rr.status)
+	}
+	return grpc.Errorf(code, "%s", message)
+}
+
+// grpcStatus answers a failed database call like writeError, as a gRPC status
+func grpcStatus(err error) error {
+	recorder := &restRecorder{header: make(http.Header), status: http.StatusOK}
+	writeError(recorder, err)
+	return recorder.statusError()
+}
+
+// pbMessage is a message of the REST API as a protobuf message
+func pbMessage(conversationID string, m MessageResponse) *wasatextpb.Message {
+	message := &wasatextpb.Message{
+		MessageID:      string(m.MessageID),
+		ConversationID: conversationID,
+		SenderID:       string(m.SenderID),
+		SenderName:     m.SenderName,
+		Content:        m.Content,
+		PhotoURL:       m.PhotoURL,
+		Timestamp:      m.Timestamp,
+		Status:         m.Status,
+		ReplyTo:        string(m.ReplyTo),
+		Edited:         m.Edited,
+		Deleted:        m.Deleted,
+		System:         m.System,
+	}
+	for _, c := range m.Comments {
+		message.Comments = append(message.Comments, &wasatextpb.Comment{
+			UserID:   string(c.UserID),
+			UserName: c.UserName,
+			Emoticon: c.Emoticon,
+		})
+	}
+	return message
+}
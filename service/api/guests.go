@@ -0,0 +1,216 @@
+/*
+Guest access API handlers.
+
+A group admin can mint guest tokens for public demo rooms. A guest
+sends the token as "Authorization: Bearer <token>" and can only read
+that one group's conversation: guests have no profile and cannot send
+messages, comment or see the member list.
+
+This file contains:
+- createGuestToken: Mint a guest token for a group (group admin only)
+- revokeGuestToken: Revoke a guest token (group admin only)
+- getGuestConversation: Read the conversation a guest token is for
+*/
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"wasatext/service/database"
+	"wasatext/service/ids"
+
+	"github.com/gorilla/mux"
+)
+
+// maxGuestTokenHours caps the lifetime of a guest token (30 days)
+const maxGuestTokenHours = 30 * 24
+
+// CreateGuestTokenRequest is the (optional) body for POST /groups/{groupId}/guest-tokens
+type CreateGuestTokenRequest struct {
+	ExpiresInHours int `json:"expiresInHours,omitempty"` // 0 means the token never expires
+}
+
+// GuestTokenResponse is a freshly minted guest token
+type GuestTokenResponse struct {
+	Token          string             `json:"token"`
+	GroupID        ids.GroupID        `json:"groupId"`
+	ConversationID ids.ConversationID `json:"conversationId"`
+	ExpiresAt      string             `json:"expiresAt,omitempty"`
+}
+
+// GuestConversationResponse is a group conversation as a guest sees it
+type GuestConversationResponse struct {
+	ConversationID ids.ConversationID `json:"conversationId"`
+	GroupID        ids.GroupID        `json:"groupId"`
+	Name           string             `json:"name"`
+	Messages       []MessageResponse  `json:"messages"`
+}
+
+// requireGroupAdmin checks that the user is the admin of the group;
+// action completes "Only the group admin can ..." in the 403 message.
+// It returns false when a response has already been written.
+func (h *Handler) requireGroupAdmin(ctx context.Context, w http.ResponseWriter, groupID ids.GroupID, userID ids.UserID, action string) bool {
+	adminID, err := h.db.GetGroupAdmin(ctx, groupID)
+	if err != nil {
+		writeError(w, err)
+		return false
+	}
+	if adminID == "" || adminID != userID {
+		http.Error(w, "Only the group admin can "+action, http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+/*
+CreateGuestToken handles POST /groups/{groupId}/guest-tokens
+operationId: createGuestToken
+
+Mints a guest token for the group conversation. Only the group admin
+(the user who created the group) can do this.
+*/
+func (h *Handler) CreateGuestToken(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check the feature and authentication
+	if !h.requireFeature(w, FeatureGuestAccess) {
+		return
+	}
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Check that the user is the group admin
+	groupID, ok := pathGroupID(w, r)
+	if !ok {
+		return
+	}
+	if !h.requireGroupAdmin(r.Context(), w, groupID, authUserID, "manage guest tokens") {
+		return
+	}
+
+	// Step 3: Parse the optional request body
+	var req CreateGuestTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.ExpiresInHours < 0 || req.ExpiresInHours > maxGuestTokenHours {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{
+			Message: "expiresInHours must be between 0 and 720",
+		})
+		return
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresInHours > 0 {
+		t := time.Now().Add(time.Duration(req.ExpiresInHours) * time.Hour)
+		expiresAt = &t
+	}
+
+	// Step 4: Mint the token
+	gt, err := h.db.CreateGuestToken(r.Context(), groupID, authUserID, expiresAt)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 5: Return the token
+	response := GuestTokenResponse{
+		Token:          gt.Token,
+		GroupID:        gt.GroupID,
+		ConversationID: gt.ConversationID,
+	}
+	if gt.ExpiresAt != nil {
+		response.ExpiresAt = gt.ExpiresAt.Format(time.RFC3339)
+	}
+	writeJSON(w, http.StatusCreated, response)
+}
+
+/*
+RevokeGuestToken handles DELETE /groups/{groupId}/guest-tokens/{token}
+operationId: revokeGuestToken
+
+Revokes a guest token. Only the group admin can do this.
+*/
+func (h *Handler) RevokeGuestToken(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Check that the user is the group admin
+	groupID, ok := pathGroupID(w, r)
+	if !ok {
+		return
+	}
+	if !h.requireGroupAdmin(r.Context(), w, groupID, authUserID, "manage guest tokens") {
+		return
+	}
+
+	// Step 3: Revoke the token
+	err := h.db.RevokeGuestToken(r.Context(), groupID, mux.Vars(r)["token"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 4: Return success (204 No Content)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+GetGuestConversation handles GET /guest/conversation
+operationId: getGuestConversation
+
+Returns the group conversation the guest token was minted for.
+*/
+func (h *Handler) GetGuestConversation(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check the feature and the guest token
+	if !h.requireFeature(w, FeatureGuestAccess) {
+		return
+	}
+	token := getBearerToken(r)
+	if !database.IsGuestToken(token) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	gt, err := h.db.GetGuestToken(r.Context(), token)
+	if errors.Is(err, database.ErrGuestTokenNotFound) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 2: Get the conversation
+	conv, err := h.db.GetGuestConversation(r.Context(), gt.GroupID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 3: Convert to response format
+	response := GuestConversationResponse{
+		ConversationID: conv.ID,
+		GroupID:        gt.GroupID,
+		Name:           conv.Name,
+		Messages:       []MessageResponse{},
+	}
+	for _, msg := range conv.Messages {
+		response.Messages = append(response.Messages, h.messageResponse(msg))
+	}
+
+	// Step 4: Return the conversation
+	writeJSON(w, http.StatusOK, response)
+}
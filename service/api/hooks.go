@@ -0,0 +1,283 @@
+/*
+Inbound webhook API handlers.
+
+A participant creates a webhook for a conversation and hands its URL to
+an external system (CI, monitoring, ...), which then posts messages with
+POST /hooks/{hookToken} and no other credential. The messages are shown
+under the name of the hook. Each hook has its own rate limit, so a noisy
+system cannot flood the conversation.
+
+This file contains:
+- createHook: Create a webhook for a conversation
+- listHooks: List the webhooks the user created in a conversation
+- deleteHook: Delete a webhook
+- postHook: Post a message through a webhook
+*/
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"wasatext/service/database"
+	"wasatext/service/events"
+	"wasatext/service/ids"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// defaultHookRate and maxHookRate bound the messages per minute of a hook
+	defaultHookRate = 30
+	maxHookRate     = 600
+
+	maxHookNameLength    = 64
+	maxHookMessageLength = 4000
+)
+
+// CreateHookRequest is the body for POST /conversations/{id}/hooks
+type CreateHookRequest struct {
+	Name          string `json:"name"`
+	RatePerMinute int    `json:"ratePerMinute,omitempty"` // 0 means defaultHookRate
+}
+
+// HookResponse is a webhook as its creator sees it
+type HookResponse struct {
+	Token          string             `json:"token"`
+	URL            string             `json:"url"` // path to post to
+	ConversationID ids.ConversationID `json:"conversationId"`
+	Name           string             `json:"name"`
+	RatePerMinute  int                `json:"ratePerMinute"`
+	CreatedAt      string             `json:"createdAt"`
+}
+
+// PostHookRequest is the body for POST /hooks/{hookToken}
+type PostHookRequest struct {
+	Title string `json:"title,omitempty"` // optional first line
+	Text  string `json:"text"`
+}
+
+// PostHookResponse is the answer to POST /hooks/{hookToken}
+type PostHookResponse struct {
+	MessageID ids.MessageID `json:"messageId"`
+}
+
+// hookLimiters holds the rate limiter of every hook that posted, by token
+type hookLimiters struct {
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+}
+
+// allow reports whether a hook may post now; a hook may post a minute's
+// worth of messages at once, then at its steady rate
+func (hl *hookLimiters) allow(hook *database.Hook) bool {
+	hl.mu.Lock()
+	defer hl.mu.Unlock()
+	if hl.limiters == nil {
+		hl.limiters = make(map[string]*rate.Limiter)
+	}
+	lim, ok := hl.limiters[hook.Token]
+	if !ok {
+		lim = rate.NewLimiter(rate.Every(time.Minute/time.Duration(hook.RatePerMinute)), hook.RatePerMinute)
+		hl.limiters[hook.Token] = lim
+	}
+	return lim.Allow()
+}
+
+// forget drops the limiter of a deleted hook
+func (hl *hookLimiters) forget(token string) {
+	hl.mu.Lock()
+	defer hl.mu.Unlock()
+	delete(hl.limiters, token)
+}
+
+/*
+CreateHook handles POST /conversations/{conversationId}/hooks
+operationId: createHook
+
+Creates a webhook posting into the conversation. Any participant can do
+this; in a channel, only the hooks of the channel admin can post.
+*/
+func (h *Handler) CreateHook(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check the feature and authentication
+	if !h.requireFeature(w, FeatureWebhooks) {
+		return
+	}
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get conversation ID from URL
+	conversationID, ok := pathConversationID(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 3: Parse request body
+	var req CreateHookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > maxHookNameLength {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Message: "name must be between 1 and 64 characters"})
+		return
+	}
+	if req.RatePerMinute == 0 {
+		req.RatePerMinute = defaultHookRate
+	}
+	if req.RatePerMinute < 1 || req.RatePerMinute > maxHookRate {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Message: "ratePerMinute must be between 1 and 600"})
+		return
+	}
+
+	// Step 4: Create the hook (this also checks the user is a participant)
+	hook, err := h.db.CreateHook(r.Context(), conversationID, authUserID, req.Name, req.RatePerMinute)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	h.infof("Webhook %q created in %s by %s", hook.Name, conversationID, authUserID)
+	writeJSON(w, http.StatusCreated, hookResponse(hook))
+}
+
+/*
+ListHooks handles GET /conversations/{conversationId}/hooks
+operationId: listHooks
+
+Returns the webhooks the user created in the conversation, with their
+tokens.
+*/
+func (h *Handler) ListHooks(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get conversation ID from URL
+	conversationID, ok := pathConversationID(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 3: Get the hooks
+	hooks, err := h.db.ListHooks(r.Context(), conversationID, authUserID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response := []HookResponse{}
+	for i := range hooks {
+		response = append(response, hookResponse(&hooks[i]))
+	}
+	writePage(w, r, response)
+}
+
+/*
+DeleteHook handles DELETE /conversations/{conversationId}/hooks/{hookToken}
+operationId: deleteHook
+
+Deletes a webhook. Only its creator can do this; its messages stay.
+*/
+func (h *Handler) DeleteHook(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get conversation ID from URL
+	conversationID, ok := pathConversationID(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 3: Delete the hook
+	token := mux.Vars(r)["hookToken"]
+	if err := h.db.DeleteHook(r.Context(), conversationID, authUserID, token); err != nil {
+		writeError(w, err)
+		return
+	}
+	h.hookLimiters.forget(token)
+
+	// Step 4: Return success (204 No Content)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+PostHook handles POST /hooks/{hookToken}
+operationId: postHook
+
+Posts a text message through a webhook. The token is the only
+credential. An optional title becomes the first line of the message.
+*/
+func (h *Handler) PostHook(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check the feature and the hook
+	if !h.requireFeature(w, FeatureWebhooks) {
+		return
+	}
+	hook, err := h.db.GetHook(r.Context(), mux.Vars(r)["hookToken"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 2: Apply the rate limit of the hook
+	if !h.hookLimiters.allow(hook) {
+		writeTooManyRequests(w, time.Now().Add(time.Minute/time.Duration(hook.RatePerMinute)),
+			"This webhook is posting too fast, slow down")
+		return
+	}
+
+	// Step 3: Parse request body
+	var req PostHookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	content := strings.TrimSpace(req.Text)
+	if title := strings.TrimSpace(req.Title); title != "" {
+		content = strings.TrimSpace(title + "\n" + content)
+	}
+	if content == "" || len(content) > maxHookMessageLength {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Message: "text must be between 1 and 4000 characters"})
+		return
+	}
+
+	// Step 4: Post the message
+	msg, err := h.db.PostHookMessage(r.Context(), hook, content)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	h.fanout.enqueue(msg)
+	h.flagFilteredMessage(r.Context(), msg, hook.ConversationID)
+
+	// Step 5: Push it to the participants and answer
+	h.bus.Publish(r.Context(), events.MessageCreated{Message: *msg})
+	writeJSON(w, http.StatusCreated, PostHookResponse{MessageID: msg.ID})
+}
+
+// hookResponse converts a hook to its response format
+func hookResponse(hook *database.Hook) HookResponse {
+	return HookResponse{
+		Token:          hook.Token,
+		URL:            "/hooks/" + hook.Token,
+		ConversationID: hook.ConversationID,
+		Name:           hook.Name,
+		RatePerMinute:  hook.RatePerMinute,
+		CreatedAt:      hook.CreatedAt.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,245 @@
+/*
+Chat import API handlers.
+
+An admin moves a group from WhatsApp by uploading its export (see
+service/chatimport): the history becomes a new group of a workspace,
+its messages with their original times and its photos. The senders are
+matched to the users of the workspace by name, and get a placeholder
+account when there is none (see database/imports.go). Attachments that
+are not photos, or too large, are imported as a line naming them.
+
+This file contains:
+- importWhatsAppChat: Import a WhatsApp chat export as a group
+*/
+package api
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"wasatext/service/chatimport"
+	"wasatext/service/database"
+	"wasatext/service/ids"
+	"wasatext/service/imaging"
+)
+
+// maxImportSize is the largest chat export accepted, in bytes
+const maxImportSize = 512 << 20
+
+// minUserNameLength and maxUserNameLength bound a user name, in bytes
+const (
+	minUserNameLength = 3
+	maxUserNameLength = 16
+)
+
+// ImportResponse is the response of POST /admin/imports/whatsapp
+type ImportResponse struct {
+	Group              GroupResponse          `json:"group"`
+	Users              []ImportedUserResponse `json:"users"` // the senders, in the order of their first message
+	Messages           int                    `json:"messages"`
+	Photos             int                    `json:"photos"`
+	SkippedAttachments []string               `json:"skippedAttachments"` // named in their message instead
+}
+
+// ImportedUserResponse is a sender of an imported chat
+type ImportedUserResponse struct {
+	Sender     string     `json:"sender"` // as written in the export
+	Identifier ids.UserID `json:"identifier"`
+	Name       string     `json:"name"`
+	Created    bool       `json:"created"` // a placeholder account was created
+}
+
+/*
+ImportWhatsAppChat handles POST /admin/imports/whatsapp
+operationId: importWhatsAppChat
+
+Imports the body, a WhatsApp export (the zip, or the transcript alone),
+as a new group of ?workspace= (the default workspace if omitted) named
+?name=, by default the name of the chat when the export tells it. The
+times of the transcript are read in ?timezone= (an IANA name, UTC if
+omitted), the time zone of the phone that exported it. The first
+sender is the admin of the group.
+*/
+func (h *Handler) ImportWhatsAppChat(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check admin authentication
+	if !h.isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Step 2: Read the parameters
+	query := r.URL.Query()
+	workspaceID := query.Get("workspace")
+	if workspaceID == "" {
+		workspaceID = database.DefaultWorkspaceID
+	}
+	loc := time.UTC
+	if tz := query.Get("timezone"); tz != "" {
+		var err error
+		if loc, err = time.LoadLocation(tz); err != nil {
+			writeJSON(w, http.StatusBadRequest, ErrorResponse{Message: "Unknown time zone " + strconv.Quote(tz)})
+			return
+		}
+	}
+
+	// Step 3: Spool the export to a file, as a zip is read from its end
+	file, err := os.CreateTemp("", "wasatext-import-*")
+	if err != nil {
+		log.Printf("Error creating an import file: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer func() {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+	}()
+	size, err := io.Copy(file, http.MaxBytesReader(w, r.Body, maxImportSize))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, "Export too large (at most "+strconv.Itoa(maxImportSize)+" bytes)", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to read the export", http.StatusBadRequest)
+		return
+	}
+
+	// Step 4: Parse it, a zip or a bare transcript
+	export, err := openExport(file, size, loc)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Message: err.Error()})
+		return
+	}
+	name := query.Get("name")
+	if name == "" {
+		name = export.Chat.Name
+	}
+	if name == "" {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Message: "name is required: the export does not tell the name of the chat"})
+		return
+	}
+
+	// Step 5: Turn the senders into user names and the messages into
+	// WASAText messages
+	senders := export.Chat.Senders()
+	names := make(map[string]string, len(senders))
+	taken := map[string]bool{}
+	chat := database.ImportedChat{WorkspaceID: workspaceID, Name: name}
+	for _, sender := range senders {
+		names[sender] = importUserName(sender, taken)
+		chat.Members = append(chat.Members, names[sender])
+	}
+	response := ImportResponse{SkippedAttachments: []string{}}
+	for _, m := range export.Chat.Messages {
+		msg := database.ImportedMessage{Sender: names[m.Sender], Content: m.Text, Timestamp: m.Time}
+		if m.Attachment != "" {
+			if msg.Photo = h.importPhoto(export, m.Attachment); msg.Photo != nil {
+				response.Photos++
+			} else {
+				response.SkippedAttachments = append(response.SkippedAttachments, m.Attachment)
+				msg.Content = strings.TrimSpace("Attachment: " + m.Attachment + "\n" + m.Text)
+			}
+		}
+		if msg.Content == "" && msg.Photo == nil {
+			continue
+		}
+		chat.Messages = append(chat.Messages, msg)
+	}
+
+	// Step 6: Import the chat
+	result, err := h.db.ImportChat(r.Context(), chat)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if response.Photos > 0 {
+		h.media.poke()
+	}
+
+	// Step 7: Return what was created
+	created := map[string]bool{}
+	for _, n := range result.Created {
+		created[n] = true
+	}
+	for _, sender := range senders {
+		response.Users = append(response.Users, ImportedUserResponse{
+			Sender:     sender,
+			Identifier: result.Members[names[sender]],
+			Name:       names[sender],
+			Created:    created[names[sender]],
+		})
+	}
+	response.Group = h.groupResponse(result.Group)
+	response.Messages = result.Messages
+	h.infof("WhatsApp chat imported as group %s (%d messages, %d accounts created)", result.Group.ID, result.Messages, len(result.Created))
+	writeJSON(w, http.StatusCreated, response)
+}
+
+// openExport reads an uploaded export: a zip, or else a bare transcript
+func openExport(file *os.File, size int64, loc *time.Location) (*chatimport.Export, error) {
+	magic := make([]byte, 4)
+	if _, err := file.ReadAt(magic, 0); err == nil && string(magic) == "PK\x03\x04" {
+		return chatimport.OpenWhatsApp(file, size, loc)
+	}
+	chat, err := chatimport.ParseWhatsApp(io.NewSectionReader(file, 0, size), loc)
+	if err != nil {
+		return nil, err
+	}
+	return &chatimport.Export{Chat: chat}, nil
+}
+
+// importPhoto returns an attachment of an export that is a photo WASAText
+// takes, ready to store; nil for any other
+func (h *Handler) importPhoto(export *chatimport.Export, name string) []byte {
+	data, err := export.Media(name, h.config().MaxPhotoSize)
+	if err != nil {
+		return nil
+	}
+	info, err := imaging.Check(data, h.config().MaxPhotoDimension)
+	if err != nil {
+		return nil
+	}
+	if data, err = imaging.StripMetadata(data, info.Format); err != nil {
+		return nil
+	}
+	return data
+}
+
+/*
+importUserName turns the name of a sender into a user name (3 to 16
+bytes), not in taken yet, and adds it to taken. WhatsApp writes "~ Name"
+for the senders who are not in the contacts of the exporter; the tilde
+is dropped.
+*/
+func importUserName(sender string, taken map[string]bool) string {
+	base := strings.TrimSpace(strings.TrimLeft(sender, "~ \u00a0\u202f"))
+	for len(base) < minUserNameLength {
+		base += "_"
+	}
+
+	name := truncateBytes(base, maxUserNameLength)
+	for n := 2; taken[name]; n++ {
+		suffix := strconv.Itoa(n)
+		name = truncateBytes(base, maxUserNameLength-len(suffix)) + suffix
+	}
+	taken[name] = true
+	return name
+}
+
+// truncateBytes cuts s to at most n bytes, on a character boundary
+func truncateBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,88 @@
+/*
+Tamper-evidence of the messages (admin).
+
+Every change to a message is appended to the hash chain of its
+conversation (see database/integrity.go). When a moderation decision is
+disputed, an admin checks that nobody altered the conversation outside
+the application, and that an exported transcript is the conversation as
+it was: the head it prints must be the head reported here, or an
+earlier head of the same chain.
+
+This file contains:
+- verifyConversation: Walk the chain of a conversation
+*/
+package api
+
+import (
+	"net/http"
+
+	"wasatext/service/ids"
+)
+
+// IntegrityResponse is the body of GET /admin/conversations/{conversationId}/integrity
+type IntegrityResponse struct {
+	ConversationID ids.ConversationID         `json:"conversationId"`
+	Valid          bool                       `json:"valid"`
+	Entries        int                        `json:"entries"`
+	Messages       int                        `json:"messages"`
+	Head           string                     `json:"head"` // "" for a conversation without messages
+	Problems       []IntegrityProblemResponse `json:"problems"`
+}
+
+// IntegrityProblemResponse is something the verification found wrong
+type IntegrityProblemResponse struct {
+	Seq       int64         `json:"seq,omitempty"` // absent for a message the chain does not know
+	MessageID ids.MessageID `json:"messageId"`
+	Problem   string        `json:"problem"` // hash_mismatch, altered, missing, unrecorded
+}
+
+/*
+VerifyConversation handles GET /admin/conversations/{conversationId}/integrity
+operationId: verifyConversation
+
+Checks the hash chain of a conversation and the messages against it, and
+returns its head and every problem found.
+*/
+func (h *Handler) VerifyConversation(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check admin authentication
+	if !h.isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Step 2: Get the conversation ID
+	conversationID, ok := pathConversationID(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 3: Walk the chain
+	report, err := h.db.VerifyConversation(r.Context(), conversationID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if !report.Valid {
+		h.infof("Conversation %s failed its integrity check (%d problems)", conversationID, len(report.Problems))
+	}
+
+	// Step 4: Convert to response format
+	response := IntegrityResponse{
+		ConversationID: report.ConversationID,
+		Valid:          report.Valid,
+		Entries:        report.Entries,
+		Messages:       report.Messages,
+		Head:           report.Head,
+		Problems:       make([]IntegrityProblemResponse, 0, len(report.Problems)),
+	}
+	for _, p := range report.Problems {
+		response.Problems = append(response.Problems, IntegrityProblemResponse{
+			Seq:       p.Seq,
+			MessageID: p.MessageID,
+			Problem:   p.Problem,
+		})
+	}
+
+	// Step 5: Return the report
+	writeJSON(w, http.StatusOK, response)
+}
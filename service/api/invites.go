@@ -0,0 +1,293 @@
+/*
+Invite API handlers.
+
+With the inviteOnly feature on, POST /session only creates an account
+when it is given an invite code. Admins mint any number of codes for any
+workspace; users mint codes for their own workspace, up to
+Config.InviteQuota. Codes can be used once.
+
+This file contains:
+- createInvite / listMyInvites / revokeMyInvite: A user's invites
+- createAdminInvite / listInvites / revokeInvite: Invite management for admins
+*/
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"wasatext/service/database"
+
+	"github.com/gorilla/mux"
+)
+
+// maxInviteHours caps the lifetime of an invite (30 days)
+const maxInviteHours = 720
+
+// CreateInviteRequest is the (optional) body for POST /invites and POST /admin/invites
+type CreateInviteRequest struct {
+	Workspace      string `json:"workspace,omitempty"`      // admins only; defaults to the default workspace
+	ExpiresInHours int    `json:"expiresInHours,omitempty"` // 0 means the invite never expires
+}
+
+// InviteResponse is an invite code
+type InviteResponse struct {
+	Code      string `json:"code"`
+	Workspace string `json:"workspace"`
+	CreatedAt string `json:"createdAt"`
+	ExpiresAt string `json:"expiresAt,omitempty"`
+	Used      bool   `json:"used"`
+	UsedAt    string `json:"usedAt,omitempty"`
+}
+
+// InviteListResponse is the body of GET /invites
+type InviteListResponse struct {
+	Invites   []InviteResponse `json:"invites"`
+	Remaining int              `json:"remaining"` // invites the user can still mint
+}
+
+/*
+CreateInvite handles POST /invites
+operationId: createInvite
+
+Mints an invite code for the user's workspace, within the quota.
+*/
+func (h *Handler) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := h.db.GetUserByID(r.Context(), authUserID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 2: Parse the request
+	expiresAt, _, ok := parseInviteRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 3: Check the quota
+	invites, err := h.db.ListInvites(r.Context(), authUserID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if len(invites) >= h.config().InviteQuota {
+		writeJSON(w, http.StatusForbidden, ErrorResponse{
+			Message: "Invite quota reached (" + strconv.Itoa(h.config().InviteQuota) + " invites)",
+		})
+		return
+	}
+
+	// Step 4: Mint the invite
+	inv, err := h.db.CreateInvite(r.Context(), user.WorkspaceID, authUserID, expiresAt)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 5: Return the invite
+	writeJSON(w, http.StatusCreated, inviteResponse(*inv))
+}
+
+/*
+ListMyInvites handles GET /invites
+operationId: listMyInvites
+
+Returns the invites the user minted and how many are left.
+*/
+func (h *Handler) ListMyInvites(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if _, err := h.db.GetUserByID(r.Context(), authUserID); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 2: Get the invites
+	invites, err := h.db.ListInvites(r.Context(), authUserID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 3: Return them
+	response := InviteListResponse{
+		Invites:   inviteResponses(invites),
+		Remaining: max(h.config().InviteQuota-len(invites), 0),
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+/*
+RevokeMyInvite handles DELETE /invites/{code}
+operationId: revokeMyInvite
+
+Deletes one of the user's unused invites, which frees its quota.
+*/
+func (h *Handler) RevokeMyInvite(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Revoke the invite
+	if err := h.db.RevokeInvite(r.Context(), normalizeInviteCode(mux.Vars(r)["code"]), authUserID); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 3: Return success (204 No Content)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+CreateAdminInvite handles POST /admin/invites
+operationId: createAdminInvite
+
+Mints an invite code for any workspace, without quota.
+*/
+func (h *Handler) CreateAdminInvite(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check the admin token
+	if !h.isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Step 2: Parse the request
+	expiresAt, workspaceID, ok := parseInviteRequest(w, r)
+	if !ok {
+		return
+	}
+	if workspaceID == "" {
+		workspaceID = database.DefaultWorkspaceID
+	}
+
+	// Step 3: Mint the invite
+	inv, err := h.db.CreateInvite(r.Context(), workspaceID, "", expiresAt)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 4: Return the invite
+	h.infof("Invite created for workspace %s", workspaceID)
+	writeJSON(w, http.StatusCreated, inviteResponse(*inv))
+}
+
+/*
+ListInvites handles GET /admin/invites
+operationId: listInvites
+
+Returns every invite, newest first.
+*/
+func (h *Handler) ListInvites(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check the admin token
+	if !h.isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Step 2: Get the invites
+	invites, err := h.db.ListInvites(r.Context(), "")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 3: Return them
+	writePage(w, r, inviteResponses(invites))
+}
+
+/*
+RevokeInvite handles DELETE /admin/invites/{code}
+operationId: revokeInvite
+
+Deletes any unused invite.
+*/
+func (h *Handler) RevokeInvite(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check the admin token
+	if !h.isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Step 2: Revoke the invite
+	if err := h.db.RevokeInvite(r.Context(), normalizeInviteCode(mux.Vars(r)["code"]), ""); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 3: Return success (204 No Content)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseInviteRequest reads the optional body of an invite request.
+// It answers 400 and returns false when the body is invalid.
+func parseInviteRequest(w http.ResponseWriter, r *http.Request) (*time.Time, string, bool) {
+	var req CreateInviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return nil, "", false
+	}
+	if req.ExpiresInHours < 0 || req.ExpiresInHours > maxInviteHours {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{
+			Message: "expiresInHours must be between 0 and " + strconv.Itoa(maxInviteHours),
+		})
+		return nil, "", false
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresInHours > 0 {
+		t := time.Now().Add(time.Duration(req.ExpiresInHours) * time.Hour)
+		expiresAt = &t
+	}
+	return expiresAt, req.Workspace, true
+}
+
+// normalizeInviteCode accepts codes typed in lower case or with spaces around
+func normalizeInviteCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// inviteResponse converts an invite to its response format
+func inviteResponse(inv database.Invite) InviteResponse {
+	response := InviteResponse{
+		Code:      inv.Code,
+		Workspace: inv.WorkspaceID,
+		CreatedAt: inv.CreatedAt.Format(time.RFC3339),
+		Used:      inv.UsedAt != nil,
+	}
+	if inv.ExpiresAt != nil {
+		response.ExpiresAt = inv.ExpiresAt.Format(time.RFC3339)
+	}
+	if inv.UsedAt != nil {
+		response.UsedAt = inv.UsedAt.Format(time.RFC3339)
+	}
+	return response
+}
+
+// inviteResponses converts a list of invites
+func inviteResponses(invites []database.Invite) []InviteResponse {
+	response := []InviteResponse{}
+	for _, inv := range invites {
+		response = append(response, inviteResponse(inv))
+	}
+	return response
+}
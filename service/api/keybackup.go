@@ -0,0 +1,137 @@
+/*
+Encrypted key backup API handlers.
+
+A client encrypting end to end can back up its keys, wrapped with a
+passphrase on the device, and fetch them back after logging in on a new
+device to restore its history. The server stores the backup as it is
+and never sees the passphrase or the keys (see database/keybackup.go).
+
+This file contains:
+- getMyKeyBackup: The user's key backup
+- putMyKeyBackup: Store it, replacing the previous one
+- deleteMyKeyBackup: Delete it
+*/
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"wasatext/service/database"
+)
+
+// PutKeyBackupRequest is the body for PUT /users/me/key-backup
+type PutKeyBackupRequest struct {
+	Data     []byte `json:"data"`               // base64
+	Replaces *int64 `json:"replaces,omitempty"` // the version replaced, 0 for none
+}
+
+// KeyBackupResponse represents the key backup in API responses
+type KeyBackupResponse struct {
+	Data      []byte `json:"data,omitempty"` // base64
+	Version   int64  `json:"version"`
+	UpdatedAt string `json:"updatedAt"`
+}
+
+/*
+GetMyKeyBackup handles GET /users/me/key-backup
+operationId: getMyKeyBackup
+
+Returns the user's key backup as it was stored; 404 if there is none.
+*/
+func (h *Handler) GetMyKeyBackup(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get the backup
+	backup, err := h.db.GetKeyBackup(r.Context(), authUserID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 3: Return it, never cached on the way
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, KeyBackupResponse{
+		Data:      backup.Data,
+		Version:   backup.Version,
+		UpdatedAt: backup.UpdatedAt.UTC().Format(time.RFC3339),
+	})
+}
+
+/*
+PutMyKeyBackup handles PUT /users/me/key-backup
+operationId: putMyKeyBackup
+
+Stores the user's key backup, up to database.MaxKeyBackupSize bytes
+(413 past that), in place of the previous one. With replaces, the upload only goes through
+if the stored backup has that version (0: none yet), else 409: another
+device backed up in between, and the client should fetch that backup
+first rather than lose its keys.
+*/
+func (h *Handler) PutMyKeyBackup(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Parse request body; the backup is base64, a third larger
+	var req PutKeyBackupRequest
+	r.Body = http.MaxBytesReader(w, r.Body, database.MaxKeyBackupSize*4/3+1024)
+	err := json.NewDecoder(r.Body).Decode(&req)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) || len(req.Data) > database.MaxKeyBackupSize {
+		http.Error(w, "Key backup too large (at most "+strconv.Itoa(database.MaxKeyBackupSize)+" bytes)", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Step 3: Store it
+	backup, err := h.db.PutKeyBackup(r.Context(), authUserID, req.Data, req.Replaces)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 4: Return the new version, without the data the client has
+	writeJSON(w, http.StatusOK, KeyBackupResponse{
+		Version:   backup.Version,
+		UpdatedAt: backup.UpdatedAt.UTC().Format(time.RFC3339),
+	})
+}
+
+/*
+DeleteMyKeyBackup handles DELETE /users/me/key-backup
+operationId: deleteMyKeyBackup
+
+Deletes the user's key backup; 404 if there is none.
+*/
+func (h *Handler) DeleteMyKeyBackup(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Delete it
+	if err := h.db.DeleteKeyBackup(r.Context(), authUserID); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 3: Return success (204 No Content)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,445 @@
+/*
+Matrix bridge.
+
+A deployment can bridge conversations to the rooms of a Matrix
+homeserver, so that its users talk with existing Matrix communities.
+The admin configures the bridge (matrix in the configuration):
+
+  - the homeserver and the access token of a Matrix account of the
+    bridge, which joins the bridged rooms (the bridge does not join
+    them itself);
+  - a bot account of WASAText (see bots.go), added to the bridged
+    conversations, which posts what is said in the rooms;
+  - the rooms, each mapped to one conversation, both ways or one way
+    only (MatrixRoom.Direction).
+
+The text and photos sent in a bridged conversation are sent to its room
+as "name: text" and m.image; the text messages, notices, emotes and
+images of the room are posted in the conversation by the bot, under the
+display name of their Matrix sender. Edits, reactions and other kinds
+of message are not relayed, nor are the messages of the bridge itself
+on either side. The bridge only relays what happens while it runs: it
+starts syncing the rooms from the moment it starts.
+
+This file contains the two workers of the bridge: runMatrixSync, which
+relays from Matrix, and runMatrixRelay, which relays to Matrix the
+messages relayToMatrix hands it.
+*/
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"wasatext/service/events"
+	"wasatext/service/ids"
+	"wasatext/service/imaging"
+	"wasatext/service/matrix"
+)
+
+const (
+	matrixSyncTimeout  = 30 * time.Second // how long a sync waits for new events
+	matrixIdleInterval = time.Minute      // how often an unconfigured bridge looks at the configuration again
+	matrixRetryDelay   = 10 * time.Second // the delay after a failure, doubled for each next retry of a message
+	matrixAttempts     = 3                // tries of a message sent to Matrix
+	matrixQueueSize    = 256              // messages waiting to be sent to Matrix
+)
+
+// Directions of a bridged room
+const (
+	MatrixBoth       = "both"       // the default
+	MatrixToMatrix   = "toMatrix"   // only the messages of the conversation are relayed
+	MatrixFromMatrix = "fromMatrix" // only the messages of the room are relayed
+)
+
+// MatrixConfig sets up the Matrix bridge
+type MatrixConfig struct {
+	// Homeserver is the base URL of the homeserver, e.g.
+	// https://matrix.example.org. The bridge is off when it is empty,
+	// the default.
+	Homeserver string
+
+	// AccessToken authenticates the Matrix account of the bridge
+	AccessToken string
+
+	// BotID is the bot account that posts the messages of the rooms
+	BotID ids.UserID
+
+	// Rooms are the bridged rooms
+	Rooms []MatrixRoom
+}
+
+// MatrixRoom maps a Matrix room to a conversation
+type MatrixRoom struct {
+	ConversationID ids.ConversationID
+	RoomID         string // e.g. !abc123:example.org
+	Direction      string // MatrixBoth, MatrixToMatrix or MatrixFromMatrix
+}
+
+// enabled reports whether the bridge is on
+func (c MatrixConfig) enabled() bool {
+	return c.Homeserver != "" && c.AccessToken != "" && c.BotID != "" && len(c.Rooms) > 0
+}
+
+// roomOf returns the room a conversation is relayed to, if any
+func (c MatrixConfig) roomOf(conversationID ids.ConversationID) (string, bool) {
+	for _, room := range c.Rooms {
+		if room.ConversationID == conversationID && room.Direction != MatrixFromMatrix {
+			return room.RoomID, true
+		}
+	}
+	return "", false
+}
+
+// conversationOf returns the conversation a room is relayed to, if any
+func (c MatrixConfig) conversationOf(roomID string) (ids.ConversationID, bool) {
+	for _, room := range c.Rooms {
+		if room.RoomID == roomID && room.Direction != MatrixToMatrix {
+			return room.ConversationID, true
+		}
+	}
+	return "", false
+}
+
+// Validate checks the bridged rooms: valid IDs and directions, and no
+// conversation or room bridged twice
+func (c MatrixConfig) Validate() error {
+	if c.Homeserver != "" && !strings.HasPrefix(c.Homeserver, "https://") && !strings.HasPrefix(c.Homeserver, "http://") {
+		return errors.New("the homeserver must be an http(s) URL")
+	}
+	conversations := make(map[ids.ConversationID]bool)
+	rooms := make(map[string]bool)
+	for _, room := range c.Rooms {
+		if _, err := ids.ParseConversationID(string(room.ConversationID)); err != nil {
+			return fmt.Errorf("invalid conversation ID %q", room.ConversationID)
+		}
+		if !strings.HasPrefix(room.RoomID, "!") || !strings.Contains(room.RoomID, ":") {
+			return fmt.Errorf("invalid room ID %q (e.g. !abc123:example.org)", room.RoomID)
+		}
+		switch room.Direction {
+		case "", MatrixBoth, MatrixToMatrix, MatrixFromMatrix:
+		default:
+			return fmt.Errorf("invalid direction %q of room %s", room.Direction, room.RoomID)
+		}
+		if conversations[room.ConversationID] || rooms[room.RoomID] {
+			return fmt.Errorf("conversation %s or room %s is bridged twice", room.ConversationID, room.RoomID)
+		}
+		conversations[room.ConversationID], rooms[room.RoomID] = true, true
+	}
+	return nil
+}
+
+// matrixOutgoing is a message on its way to a room
+type matrixOutgoing struct {
+	roomID string
+	msg    MessageResponse
+}
+
+// matrixBridge is the state of the bridge: the Matrix session of the
+// configured account, and the messages to send
+type matrixBridge struct {
+	queue chan matrixOutgoing
+
+	mu      sync.Mutex
+	account string            // the homeserver, token and bot of the session
+	client  *matrix.Client    // nil until the first session
+	self    string            // the Matrix user ID of the bridge
+	since   string            // where the next sync starts
+	names   map[string]string // display names, by room and user
+}
+
+func newMatrixBridge() *matrixBridge {
+	return &matrixBridge{queue: make(chan matrixOutgoing, matrixQueueSize)}
+}
+
+// matrixSession returns the client of the configured account, and its
+// user ID; a new account starts a new session, synced from now on
+func (h *Handler) matrixSession(ctx context.Context, cfg MatrixConfig) (*matrix.Client, string, error) {
+	mb := h.matrix
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	account := cfg.Homeserver + "\x00" + cfg.AccessToken + "\x00" + string(cfg.BotID)
+	if mb.client != nil && mb.account == account {
+		return mb.client, mb.self, nil
+	}
+
+	bot, err := h.db.GetUserByID(ctx, cfg.BotID)
+	if err != nil {
+		return nil, "", fmt.Errorf("the bot of the bridge: %w", err)
+	}
+	if !bot.Bot {
+		return nil, "", fmt.Errorf("user %s is not a bot account", cfg.BotID)
+	}
+	client := matrix.NewClient(cfg.Homeserver, cfg.AccessToken)
+	self, err := client.WhoAmI(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	mb.account, mb.client, mb.self, mb.since = account, client, self, ""
+	mb.names = make(map[string]string)
+	h.infof("Matrix bridge connected to %s as %s", cfg.Homeserver, self)
+	return client, self, nil
+}
+
+/*
+runMatrixSync relays the messages of the bridged rooms to their
+conversations until ctx is cancelled. It long-polls the homeserver;
+the configuration is read again before each sync, so a reload changes
+the rooms and the account.
+*/
+func (h *Handler) runMatrixSync(ctx context.Context) {
+	for ctx.Err() == nil {
+		cfg := h.config().Matrix
+		if !cfg.enabled() {
+			matrixPause(ctx, matrixIdleInterval)
+			continue
+		}
+		client, self, err := h.matrixSession(ctx, cfg)
+		if err != nil {
+			log.Printf("Matrix bridge: %v", err)
+			matrixPause(ctx, matrixRetryDelay)
+			continue
+		}
+
+		h.matrix.mu.Lock()
+		since := h.matrix.since
+		h.matrix.mu.Unlock()
+		resp, err := client.Sync(ctx, since, matrixSyncTimeout)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Matrix bridge: sync failed: %v", err)
+				matrixPause(ctx, matrixRetryDelay)
+			}
+			continue
+		}
+
+		for roomID, room := range resp.Rooms.Join {
+			conversationID, ok := cfg.conversationOf(roomID)
+			if !ok {
+				continue
+			}
+			if room.Timeline.Limited {
+				log.Printf("Matrix bridge: %s had more new events than a sync returns, some were not relayed", roomID)
+			}
+			for _, event := range room.Timeline.Events {
+				if event.Type != matrix.EventMessage || event.Sender == self {
+					continue
+				}
+				if err := h.relayFromMatrix(ctx, client, cfg.BotID, conversationID, roomID, event); err != nil {
+					log.Printf("Matrix bridge: event %s of %s not relayed: %v", event.EventID, roomID, err)
+				}
+			}
+		}
+
+		h.matrix.mu.Lock()
+		if h.matrix.client == client {
+			h.matrix.since = resp.NextBatch
+		}
+		h.matrix.mu.Unlock()
+	}
+}
+
+// relayFromMatrix posts a message of a room in its conversation, as the bot
+func (h *Handler) relayFromMatrix(ctx context.Context, client *matrix.Client, botID ids.UserID, conversationID ids.ConversationID, roomID string, event matrix.Event) error {
+	var content matrix.MessageContent
+	if err := json.Unmarshal(event.Content, &content); err != nil {
+		return err
+	}
+	if content.RelatesTo != nil && content.RelatesTo.RelType == "m.replace" {
+		return nil // an edit
+	}
+	name := h.matrixDisplayName(ctx, client, roomID, event.Sender)
+
+	body := strings.TrimSpace(content.Body)
+	var text string
+	var photo []byte
+	switch content.MsgType {
+	case matrix.MsgText, matrix.MsgNotice:
+		text = name + ": " + body
+	case matrix.MsgEmote:
+		text = "* " + name + " " + body
+	case matrix.MsgImage:
+		data, err := client.Download(ctx, content.URL, h.config().MaxPhotoSize)
+		if err != nil {
+			return err
+		}
+		info, err := imaging.Check(data, h.config().MaxPhotoDimension)
+		if err == nil {
+			data, err = imaging.StripMetadata(data, info.Format)
+		}
+		if err != nil {
+			return err
+		}
+		text, photo = name+" sent a photo", data
+	default:
+		return nil // files, videos, locations, ... are not relayed
+	}
+	if body == "" && photo == nil {
+		return nil
+	}
+	text = truncateBytes(text, maxBotMessageLength)
+
+	// The bot must still take part in the conversation
+	if _, err := h.db.GetConversation(ctx, botID, conversationID); err != nil {
+		return err
+	}
+	_, err := h.createBotMessage(ctx, botID, conversationID, text, photo, nil)
+	return err
+}
+
+// matrixDisplayName returns the display name of a member of a room,
+// remembered for the session
+func (h *Handler) matrixDisplayName(ctx context.Context, client *matrix.Client, roomID, userID string) string {
+	key := roomID + "\x00" + userID
+	h.matrix.mu.Lock()
+	name, ok := h.matrix.names[key]
+	h.matrix.mu.Unlock()
+	if ok {
+		return name
+	}
+	name, err := client.DisplayName(ctx, roomID, userID)
+	if err != nil {
+		return userID // asked again next time
+	}
+	h.matrix.mu.Lock()
+	if h.matrix.client == client {
+		h.matrix.names[key] = name
+	}
+	h.matrix.mu.Unlock()
+	return name
+}
+
+// relayToMatrix hands a new message of a bridged conversation to
+// runMatrixRelay; the messages of the bot and the notices are not
+// relayed. When the queue is full the message is dropped.
+func (h *Handler) relayToMatrix(_ context.Context, e events.MessageCreated) {
+	cfg := h.config().Matrix
+	if !cfg.enabled() || e.Message.SenderID == cfg.BotID || e.Message.System {
+		return
+	}
+	roomID, ok := cfg.roomOf(e.Message.ConversationID)
+	if !ok {
+		return
+	}
+	msg := h.messageResponse(e.Message)
+	select {
+	case h.matrix.queue <- matrixOutgoing{roomID: roomID, msg: msg}:
+	default:
+		log.Printf("Matrix bridge: queue full, message %s not relayed to %s", msg.MessageID, roomID)
+	}
+}
+
+// runMatrixRelay sends the queued messages to their rooms until ctx is
+// cancelled; the messages still queued are dropped
+func (h *Handler) runMatrixRelay(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case out := <-h.matrix.queue:
+			if err := h.sendToMatrix(ctx, out); err != nil && ctx.Err() == nil {
+				log.Printf("Matrix bridge: message %s not relayed to %s: %v", out.msg.MessageID, out.roomID, err)
+			}
+		}
+	}
+}
+
+// sendToMatrix sends a message to a room: its text, then its photo. The
+// transaction IDs come from the message, so a retry is not sent twice.
+func (h *Handler) sendToMatrix(ctx context.Context, out matrixOutgoing) error {
+	client, _, err := h.matrixSession(ctx, h.config().Matrix)
+	if err != nil {
+		return err
+	}
+	txnID := "wasatext-" + string(out.msg.MessageID)
+
+	if out.msg.Content != "" {
+		text := matrix.MessageContent{MsgType: matrix.MsgText, Body: out.msg.SenderName + ": " + out.msg.Content}
+		if err := matrixRetry(ctx, func() error {
+			_, err := client.SendMessage(ctx, out.roomID, txnID, text)
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+	if !out.msg.HasPhoto {
+		return nil
+	}
+
+	msg, err := h.db.GetMessage(ctx, out.msg.MessageID)
+	if err != nil {
+		return err
+	}
+	photo, err := h.db.GetPhoto(ctx, msg.PhotoID)
+	if err != nil {
+		return err
+	}
+	info, err := imaging.Check(photo, 0)
+	if err != nil {
+		return err
+	}
+	image := matrix.MessageContent{
+		MsgType: matrix.MsgImage,
+		Body:    "photo." + info.Format,
+		Info:    &matrix.ImageInfo{MimeType: "image/" + info.Format, Size: len(photo), Width: info.Width, Height: info.Height},
+	}
+	if out.msg.Content == "" {
+		image.Body = out.msg.SenderName + " sent a photo"
+	}
+	return matrixRetry(ctx, func() error {
+		if image.URL == "" {
+			uri, err := client.Upload(ctx, image.Info.MimeType, "photo."+info.Format, photo)
+			if err != nil {
+				return err
+			}
+			image.URL = uri
+		}
+		_, err := client.SendMessage(ctx, out.roomID, txnID+"-photo", image)
+		return err
+	})
+}
+
+// matrixRetry calls send up to matrixAttempts times, waiting between
+// the tries as long as the homeserver asks; the errors of the request
+// itself (4xx) are not retried
+func matrixRetry(ctx context.Context, send func() error) error {
+	delay := matrixRetryDelay
+	for attempt := 1; ; attempt++ {
+		err := send()
+		if err == nil || attempt == matrixAttempts {
+			return err
+		}
+		var matrixErr *matrix.Error
+		if errors.As(err, &matrixErr) {
+			switch {
+			case matrixErr.Status == 429 && matrixErr.RetryAfter > 0:
+				delay = time.Duration(matrixErr.RetryAfter) * time.Millisecond
+			case matrixErr.Status < 500 && matrixErr.Status != 429:
+				return err
+			}
+		}
+		if !matrixPause(ctx, delay) {
+			return ctx.Err()
+		}
+		delay *= 2
+	}
+}
+
+// matrixPause waits for d, or until ctx is cancelled; it reports whether
+// it waited the whole time
+func matrixPause(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
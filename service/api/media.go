@@ -0,0 +1,274 @@
+/*
+Signed media URLs.
+
+Photos are served by GET /media/{mediaId}. An <img> tag cannot send the
+Authorization header, so instead of the bearer token that route takes a
+short-lived signature: API responses carry a photoUrl such as
+
+	/media/user-<uuid>?exp=1760000000&sig=...
+
+next to hasPhoto. Only someone who was shown the photo gets the URL, and
+the URL stops working at exp. The signature is an HMAC-SHA256 of the
+media ID and the expiry, keyed with Config.MediaURLSecret (or with a
+random key per process when it is not set).
+
+The expiry is rounded to a window of MediaURLTTL, so the same photo gets
+the same URL for a while and the browser can cache it. The same
+signature also opens GET /media/{mediaId}/status (see processing.go).
+*/
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"wasatext/service/database"
+	"wasatext/service/ids"
+
+	"github.com/gorilla/mux"
+)
+
+// Kinds of media, the prefix of a media ID
+const (
+	mediaUser     = "user"     // profile photo
+	mediaGroup    = "group"    // group photo
+	mediaMessage  = "message"  // photo message
+	mediaBranding = "branding" // the logo, by its own ID so a new logo gets a new URL
+)
+
+// DefaultMediaURLTTL is the default lifetime of a signed media URL
+const DefaultMediaURLTTL = 10 * time.Minute
+
+// newMediaKey returns a random signing key, used when no secret is configured
+func newMediaKey() []byte {
+	key := make([]byte, sha256.Size)
+	if _, err := rand.Read(key); err != nil {
+		panic("api: cannot generate the media signing key: " + err.Error())
+	}
+	return key
+}
+
+// mediaSigningKey returns the key signing the media URLs
+func (h *Handler) mediaSigningKey() []byte {
+	if secret := h.config().MediaURLSecret; secret != "" {
+		return []byte(secret)
+	}
+	return h.mediaKey
+}
+
+// mediaSignature signs a media ID with its expiry (Unix seconds)
+func (h *Handler) mediaSignature(mediaID string, exp int64) []byte {
+	mac := hmac.New(sha256.New, h.mediaSigningKey())
+	mac.Write([]byte(mediaID + "." + strconv.FormatInt(exp, 10)))
+	return mac.Sum(nil)
+}
+
+/*
+photoURL returns the signed URL of a photo, or "" when there is no
+photo. The URL is valid for one to two MediaURLTTL: the expiry is the
+end of the next window.
+*/
+func (h *Handler) photoURL(kind, id, photoID string) string {
+	if photoID == "" || id == "" {
+		return ""
+	}
+
+	ttl := h.config().MediaURLTTL
+	if ttl <= 0 {
+		ttl = DefaultMediaURLTTL
+	}
+	exp := time.Now().Truncate(ttl).Add(2 * ttl).Unix()
+
+	mediaID := kind + "-" + id
+	sig := base64.RawURLEncoding.EncodeToString(h.mediaSignature(mediaID, exp))
+	return "/media/" + mediaID + "?exp=" + strconv.FormatInt(exp, 10) + "&sig=" + sig
+}
+
+// conversationPhotoURL returns the signed URL of a conversation photo:
+// the group photo, or the other user's photo in a direct conversation
+func (h *Handler) conversationPhotoURL(isGroup bool, ownerID, photoID string) string {
+	if isGroup {
+		return h.photoURL(mediaGroup, ownerID, photoID)
+	}
+	return h.photoURL(mediaUser, ownerID, photoID)
+}
+
+/*
+GetMedia handles GET /media/{mediaId}
+operationId: getMedia
+
+Serves a photo through a signed URL taken from an API response. No
+Authorization header: the signature is the credential. An invalid or
+expired signature is answered with 403.
+*/
+func (h *Handler) GetMedia(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check the signature and the expiry
+	mediaID, remaining, ok := h.checkMediaLink(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 2: Load the photo in the quality asked for
+	photo, err := h.loadPhoto(r.Context(), mediaID, photoQuality(r))
+	if errors.Is(err, errNoPhoto) {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 3: Return the photo. The browser may keep it until the link expires.
+	w.Header().Set("Cache-Control", "private, max-age="+strconv.Itoa(int(remaining.Seconds())))
+	writePhoto(w, photo)
+}
+
+// MediaStatusResponse is the body of GET /media/{mediaId}/status
+type MediaStatusResponse struct {
+	ProcessingState string `json:"processingState"` // pending, ready or failed
+}
+
+/*
+GetMediaStatus handles GET /media/{mediaId}/status
+operationId: getMediaStatus
+
+Tells whether a photo is still being processed, through the same
+signed URL as GET /media/{mediaId} (exp and sig). A client polls it to
+show a spinner until the renditions of a new photo are ready.
+*/
+func (h *Handler) GetMediaStatus(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check the signature and the expiry
+	mediaID, _, ok := h.checkMediaLink(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 2: Find the photo
+	photoID, err := h.mediaPhotoID(r.Context(), mediaID)
+	if errors.Is(err, errNoPhoto) {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 3: Return its state
+	state, err := h.db.GetMediaState(r.Context(), photoID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, MediaStatusResponse{ProcessingState: state})
+}
+
+// checkMediaLink checks the signature and the expiry of a signed media
+// URL and returns its media ID and how long it is still valid. It
+// answers 403 itself when the link is not valid.
+func (h *Handler) checkMediaLink(w http.ResponseWriter, r *http.Request) (string, time.Duration, bool) {
+	mediaID := mux.Vars(r)["mediaId"]
+	exp, err := strconv.ParseInt(r.URL.Query().Get("exp"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid media link", http.StatusForbidden)
+		return "", 0, false
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(r.URL.Query().Get("sig"))
+	if err != nil || !hmac.Equal(sig, h.mediaSignature(mediaID, exp)) {
+		http.Error(w, "Invalid media link", http.StatusForbidden)
+		return "", 0, false
+	}
+	remaining := time.Until(time.Unix(exp, 0))
+	if remaining <= 0 {
+		http.Error(w, "Media link expired", http.StatusForbidden)
+		return "", 0, false
+	}
+	return mediaID, remaining, true
+}
+
+// errNoPhoto is returned by loadPhoto when the media has no photo (anymore)
+var errNoPhoto = errors.New("no photo")
+
+// loadPhoto returns the photo a media ID refers to, in the given quality
+func (h *Handler) loadPhoto(ctx context.Context, mediaID, quality string) ([]byte, error) {
+	photoID, err := h.mediaPhotoID(ctx, mediaID)
+	if err != nil {
+		return nil, err
+	}
+	photo, err := h.db.GetPhotoRendition(ctx, photoID, quality)
+	if err != nil {
+		return nil, photoError(err)
+	}
+	return photo, nil
+}
+
+// mediaPhotoID returns the ID of the photo a media ID refers to
+func (h *Handler) mediaPhotoID(ctx context.Context, mediaID string) (string, error) {
+	kind, id, _ := strings.Cut(mediaID, "-")
+
+	var photoID string
+	switch kind {
+	case mediaUser:
+		userID, err := ids.ParseUserID(id)
+		if err != nil {
+			return "", errNoPhoto
+		}
+		user, err := h.db.GetUserByID(ctx, userID)
+		if err != nil {
+			return "", photoError(err)
+		}
+		photoID = user.PhotoID
+	case mediaGroup:
+		groupID, err := ids.ParseGroupID(id)
+		if err != nil {
+			return "", errNoPhoto
+		}
+		group, err := h.db.GetGroup(ctx, groupID)
+		if err != nil {
+			return "", photoError(err)
+		}
+		photoID = group.PhotoID
+	case mediaMessage:
+		messageID, err := ids.ParseMessageID(id)
+		if err != nil {
+			return "", errNoPhoto
+		}
+		msg, err := h.db.GetMessage(ctx, messageID)
+		if err != nil {
+			return "", photoError(err)
+		}
+		photoID = msg.PhotoID
+	case mediaBranding:
+		branding, err := h.db.GetBranding(ctx)
+		if err != nil {
+			return "", err
+		}
+		if id != branding.LogoID {
+			return "", errNoPhoto
+		}
+		photoID = branding.LogoID
+	}
+
+	if photoID == "" {
+		return "", errNoPhoto
+	}
+	return photoID, nil
+}
+
+// photoError turns "the owner of the photo is gone" into errNoPhoto
+func photoError(err error) error {
+	var domainErr *database.Error
+	if errors.As(err, &domainErr) && domainErr.Code == database.CodeNotFound {
+		return errNoPhoto
+	}
+	return err
+}
@@ -0,0 +1,80 @@
+/*
+Mention API handlers.
+
+This file contains:
+- getMyMentions: List the messages mentioning the user
+
+A message mentions the participants named after an @ in its text
+("@alice"), found when it is sent or edited; every message response
+lists them in mentions, so that clients highlight them. GET /mentions
+is the inbox of the messages mentioning the user, newest first, page by
+page like the message search.
+*/
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"wasatext/service/database"
+	"wasatext/service/ids"
+)
+
+/*
+GetMyMentions handles GET /mentions
+operationId: getMyMentions
+
+Lists the messages mentioning the user that they can still read, newest
+first, in the format of the message search.
+*/
+func (h *Handler) GetMyMentions(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Parse the page
+	limit := defaultSearchResults
+	if value := r.URL.Query().Get("limit"); value != "" {
+		requested, err := strconv.Atoi(value)
+		if err != nil || requested < 1 || requested > maxSearchResults {
+			http.Error(w, "Invalid limit (1 to "+strconv.Itoa(maxSearchResults)+")", http.StatusBadRequest)
+			return
+		}
+		limit = requested
+	}
+	var before ids.MessageID
+	if value := r.URL.Query().Get("before"); value != "" {
+		var err error
+		if before, err = ids.ParseMessageID(value); err != nil {
+			http.Error(w, "Invalid before: not a message ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Step 3: Get the mentions, asking for one extra to know whether there are more
+	results, err := h.db.GetMentions(r.Context(), authUserID, before, limit+1)
+	if errors.Is(err, database.ErrMessageNotFound) {
+		http.Error(w, "Invalid before: message not found", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 4: Convert to response format and return
+	response := SearchResponse{Results: []SearchResultResponse{}}
+	if len(results) > limit {
+		results = results[:limit]
+		response.HasMore = true
+		response.NextBefore = results[limit-1].Message.ID
+	}
+	for _, result := range results {
+		response.Results = append(response.Results, h.searchResultResponse(result))
+	}
+	writeJSON(w, http.StatusOK, response)
+}
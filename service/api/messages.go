@@ -4,20 +4,31 @@ Message API handlers.
 This file contains:
 - sendMessage: Send a new message
 - forwardMessage: Forward a message to another conversation
+- editMessage: Edit the text of a sent message
 - deleteMessage: Delete a sent message
-- commentMessage: Add a reaction to a message
-- uncommentMessage: Remove a reaction from a message
+- commentMessage: Add a reaction to a message (several per user, one per emoticon)
+- uncommentMessage: Remove the reactions of the user from a message
+- uncommentMessageEmoticon: Remove one reaction from a message
+- getReactions: Get the reactions of several messages at once
+- getMessageReceipts: Who received and read a message, and when
+- reportMessage: Report a message to the moderators
 */
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
+	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"wasatext/service/database"
+	"wasatext/service/events"
+	"wasatext/service/ids"
 
 	"github.com/gorilla/mux"
 )
@@ -26,6 +37,7 @@ import (
 type SendMessageRequest struct {
 	Content string `json:"content,omitempty"`
 	ReplyTo string `json:"replyTo,omitempty"`
+	SendAt  string `json:"sendAt,omitempty"` // RFC 3339; in the future, the message is scheduled (see scheduled.go)
 }
 
 // ForwardMessageRequest is the body for POST /conversations/{id}/messages/{msgId}/forward
@@ -33,6 +45,44 @@ type ForwardMessageRequest struct {
 	TargetConversationID string `json:"targetConversationId"`
 }
 
+// EditMessageRequest is the body for PUT /conversations/{id}/messages/{msgId}
+type EditMessageRequest struct {
+	Content string `json:"content"`
+}
+
+// ReportMessageRequest is the body for POST /conversations/{id}/messages/{msgId}/reports
+type ReportMessageRequest struct {
+	Reason string `json:"reason"`
+}
+
+// maxFormOverhead is the room left for the rest of a photo message form
+const maxFormOverhead = 64 << 10
+
+// maxReactionBatch is how many messages GET /conversations/{id}/reactions takes at once
+const maxReactionBatch = 100
+
+// ReactionsResponse is the body of GET /conversations/{id}/reactions
+type ReactionsResponse struct {
+	Reactions map[ids.MessageID][]CommentResponse `json:"reactions"` // by message ID
+	Counts    map[ids.MessageID][]ReactionCount   `json:"counts"`    // the reactions counted by emoticon, by message ID
+}
+
+// ReceiptResponse is one recipient in GET /conversations/{id}/messages/{msgId}/receipts
+type ReceiptResponse struct {
+	UserID      ids.UserID `json:"userId"`
+	UserName    string     `json:"userName"`
+	Status      string     `json:"status"` // sent, received, read
+	DeliveredAt string     `json:"deliveredAt,omitempty"`
+	ReadAt      string     `json:"readAt,omitempty"`
+}
+
+// MessageReceiptsResponse is the body of GET /conversations/{id}/messages/{msgId}/receipts
+type MessageReceiptsResponse struct {
+	MessageID ids.MessageID     `json:"messageId"`
+	Status    string            `json:"status"` // read only once every recipient read the message
+	Receipts  []ReceiptResponse `json:"receipts"`
+}
+
 // CommentRequest is the body for POST /conversations/{id}/messages/{msgId}/comments
 type CommentRequest struct {
 	Emoticon string `json:"emoticon"`
@@ -44,7 +94,8 @@ operationId: sendMessage
 
 From PDF:
 "The user can send a new message, reply to an existing one..."
-Messages can be text or photo/GIF.
+Messages can be text or photo/GIF. A text message with a sendAt in the
+future is scheduled instead, and answered with 202 Accepted.
 */
 func (h *Handler) SendMessage(w http.ResponseWriter, r *http.Request) {
 	// Step 1: Check authentication
@@ -55,17 +106,15 @@ func (h *Handler) SendMessage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Step 2: Get conversation ID from URL
-	vars := mux.Vars(r)
-	conversationID := vars["conversationId"]
-
-	// Step 3: Check if user is part of this conversation
-	_, err := h.db.GetConversation(authUserID, conversationID)
-	if errors.Is(err, database.ErrConversationNotFound) {
-		http.Error(w, "Conversation not found", http.StatusNotFound)
+	conversationID, ok := pathConversationID(w, r)
+	if !ok {
 		return
 	}
+
+	// Step 3: Check if user is part of this conversation
+	_, err := h.db.GetConversation(r.Context(), authUserID, conversationID)
 	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -74,24 +123,50 @@ func (h *Handler) SendMessage(w http.ResponseWriter, r *http.Request) {
 
 	var content string
 	var photo []byte
-	var replyTo *string
+	var replyTo *ids.MessageID
+	var sendAt *time.Time
 
 	if strings.Contains(contentType, "multipart/form-data") {
-		// Photo/GIF upload
-		err := r.ParseMultipartForm(10 << 20) // 10 MB max
+		// Photo/GIF upload; the form around the photo gets some room
+		maxSize := h.config().MaxPhotoSize
+		r.Body = http.MaxBytesReader(w, r.Body, maxSize+maxFormOverhead)
+		err := r.ParseMultipartForm(maxSize)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.photoReadError(w, err)
+			return
+		}
 		if err != nil {
 			http.Error(w, "Failed to parse form", http.StatusBadRequest)
 			return
 		}
 
-		file, _, err := r.FormFile("photo")
+		file, header, err := r.FormFile("photo")
 		if err == nil { //nolint:goerr113
 			defer file.Close()
+			if header.Size > maxSize {
+				h.photoReadError(w, &http.MaxBytesError{Limit: maxSize})
+				return
+			}
 			photo, _ = io.ReadAll(file)
+			if len(photo) > 0 {
+				if photo, ok = h.checkPhoto(w, photo); !ok {
+					return
+				}
+			}
 		}
 
 		if replyToVal := r.FormValue("replyTo"); replyToVal != "" {
-			replyTo = &replyToVal
+			replyToID, err := ids.ParseMessageID(replyToVal)
+			if err != nil {
+				http.Error(w, "Invalid replyTo", http.StatusBadRequest)
+				return
+			}
+			replyTo = &replyToID
+		}
+		if r.FormValue("sendAt") != "" {
+			writeJSON(w, http.StatusBadRequest, ErrorResponse{Message: "only text messages can be scheduled"})
+			return
 		}
 	} else {
 		// JSON text message
@@ -102,7 +177,15 @@ func (h *Handler) SendMessage(w http.ResponseWriter, r *http.Request) {
 		}
 		content = req.Content
 		if req.ReplyTo != "" {
-			replyTo = &req.ReplyTo
+			replyToID, err := ids.ParseMessageID(req.ReplyTo)
+			if err != nil {
+				http.Error(w, "Invalid replyTo", http.StatusBadRequest)
+				return
+			}
+			replyTo = &replyToID
+		}
+		if sendAt, ok = h.parseSendAt(w, req.SendAt); !ok {
+			return
 		}
 	}
 
@@ -112,28 +195,61 @@ func (h *Handler) SendMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Step 6: Create the message
-	msg, err := h.db.CreateMessage(conversationID, authUserID, content, photo, replyTo)
-	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	// Step 6: Apply the anti-spam limits and the fair-use quotas
+	uploads := 0
+	if len(photo) > 0 {
+		uploads = 1
+	}
+	if !h.checkThrottle(r.Context(), w, authUserID) || !h.checkMessageFlood(r.Context(), w, authUserID, conversationID, content) ||
+		!h.checkUsage(r.Context(), w, authUserID, 1, uploads) {
 		return
 	}
 
-	// Step 7: Return the created message
-	response := MessageResponse{
-		MessageID:  msg.ID,
-		SenderID:   msg.SenderID,
-		SenderName: msg.SenderName,
-		Content:    msg.Content,
-		HasPhoto:   len(msg.Photo) > 0,
-		Timestamp:  msg.Timestamp.Format("2006-01-02T15:04:05Z07:00"),
-		Status:     msg.Status,
+	// Step 7: Schedule the message for later, or create it
+	if sendAt != nil {
+		scheduled, err := h.db.ScheduleMessage(r.Context(), conversationID, authUserID, content, replyTo, *sendAt)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		h.recordUsage(r.Context(), authUserID, 1, uploads)
+		h.scheduler.poke()
+		writeJSON(w, http.StatusAccepted, scheduledMessageResponse(*scheduled))
+		return
 	}
-
-	if msg.ReplyTo != nil {
-		response.ReplyTo = *msg.ReplyTo
+	msg, err := h.db.CreateMessage(r.Context(), conversationID, authUserID, content, photo, replyTo)
+	if err != nil {
+		writeError(w, err)
+		return
 	}
+	h.recordUsage(r.Context(), authUserID, 1, uploads)
+	h.fanout.enqueue(msg)
+	if msg.PhotoID != "" {
+		h.media.poke()
+	}
+
+	// Step 8: Queue the message for moderation if it trips the word filter
+	h.flagFilteredMessage(r.Context(), msg, conversationID)
 
+	// Step 9: Return the created message
+	response := MessageResponse{
+		MessageID:      msg.ID,
+		SenderID:       msg.SenderID,
+		SenderName:     msg.SenderName,
+		Content:        msg.Content,
+		Language:       msg.Language,
+		HasPhoto:       msg.PhotoID != "",
+		PhotoURL:       h.photoURL(mediaMessage, string(msg.ID), msg.PhotoID),
+		PhotoState:     msg.PhotoState,
+		Timestamp:      msg.Timestamp.Format("2006-01-02T15:04:05Z07:00"),
+		Status:         msg.Status,
+		Mentions:       mentionResponses(msg.Mentions),
+		Comments:       []CommentResponse{},
+		ReactionCounts: []ReactionCount{},
+	}
+	response.ReplyTo, response.Reply = replyFields(*msg)
+
+	h.bus.Publish(r.Context(), events.MessageCreated{Message: *msg})
 	writeJSON(w, http.StatusCreated, response)
 }
 
@@ -153,29 +269,29 @@ func (h *Handler) ForwardMessage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Step 2: Get IDs from URL
-	vars := mux.Vars(r)
-	conversationID := vars["conversationId"]
-	messageID := vars["messageId"]
-
-	// Step 3: Check if user is part of source conversation
-	_, err := h.db.GetConversation(authUserID, conversationID)
-	if errors.Is(err, database.ErrConversationNotFound) {
-		http.Error(w, "Conversation not found", http.StatusNotFound)
+	conversationID, ok := pathConversationID(w, r)
+	if !ok {
 		return
 	}
-	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	messageID, ok := pathMessageID(w, r)
+	if !ok {
 		return
 	}
 
-	// Step 4: Get the message to forward
-	originalMsg, err := h.db.GetMessage(messageID)
-	if errors.Is(err, database.ErrMessageNotFound) {
-		http.Error(w, "Message not found", http.StatusNotFound)
+	// Step 3: Check if user is part of source conversation
+	_, err := h.db.GetConversation(r.Context(), authUserID, conversationID)
+	if err != nil {
+		writeError(w, err)
 		return
 	}
+
+	// Step 4: Get the message to forward (a tombstone has nothing to forward)
+	originalMsg, err := h.db.GetMessage(r.Context(), messageID)
+	if err == nil && originalMsg.Deleted {
+		err = database.ErrMessageNotFound
+	}
 	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -186,46 +302,163 @@ func (h *Handler) ForwardMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	targetID, err := ids.ParseConversationID(req.TargetConversationID)
+	if err != nil {
+		http.Error(w, "Invalid target conversation ID", http.StatusBadRequest)
+		return
+	}
+
 	// Step 6: Check if user is part of target conversation
-	_, err = h.db.GetConversation(authUserID, req.TargetConversationID)
-	if errors.Is(err, database.ErrConversationNotFound) {
-		http.Error(w, "Target conversation not found", http.StatusNotFound)
+	_, err = h.db.GetConversation(r.Context(), authUserID, targetID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 7: Apply the anti-spam limits and the fair-use quotas
+	if !h.checkThrottle(r.Context(), w, authUserID) || !h.checkMessageFlood(r.Context(), w, authUserID, targetID, originalMsg.Content) ||
+		!h.checkUsage(r.Context(), w, authUserID, 1, 0) {
 		return
 	}
+
+	// Step 8: Create a new message in the target conversation
+	// (forwarding creates a copy, of the photo too)
+	var photo []byte
+	if originalMsg.PhotoID != "" {
+		if photo, err = h.db.GetPhoto(r.Context(), originalMsg.PhotoID); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	msg, err := h.db.CreateMessage(r.Context(), targetID, authUserID, originalMsg.Content, photo, nil)
 	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
+	h.recordUsage(r.Context(), authUserID, 1, 0)
+	h.fanout.enqueue(msg)
+	if msg.PhotoID != "" {
+		h.media.poke()
+	}
 
-	// Step 7: Create a new message in the target conversation
-	// (forwarding creates a copy)
-	msg, err := h.db.CreateMessage(req.TargetConversationID, authUserID, originalMsg.Content, originalMsg.Photo, nil)
+	// Step 9: Return the forwarded message
+	response := MessageResponse{
+		MessageID:      msg.ID,
+		SenderID:       msg.SenderID,
+		SenderName:     msg.SenderName,
+		Content:        msg.Content,
+		Language:       msg.Language,
+		HasPhoto:       msg.PhotoID != "",
+		PhotoURL:       h.photoURL(mediaMessage, string(msg.ID), msg.PhotoID),
+		PhotoState:     msg.PhotoState,
+		Timestamp:      msg.Timestamp.Format("2006-01-02T15:04:05Z07:00"),
+		Status:         msg.Status,
+		Mentions:       mentionResponses(msg.Mentions),
+		Comments:       []CommentResponse{},
+		ReactionCounts: []ReactionCount{},
+	}
+
+	h.bus.Publish(r.Context(), events.MessageCreated{Message: *msg})
+	writeJSON(w, http.StatusCreated, response)
+}
+
+/*
+EditMessage handles PUT /conversations/{conversationId}/messages/{messageId}
+operationId: editMessage
+
+The sender can change the text of their own text messages; the message
+is then marked as edited. Photos cannot be edited.
+*/
+func (h *Handler) EditMessage(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get IDs from URL
+	conversationID, ok := pathConversationID(w, r)
+	if !ok {
+		return
+	}
+	messageID, ok := pathMessageID(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 3: Check the message is in this conversation
+	original, err := h.db.GetMessage(r.Context(), messageID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if original.ConversationID != conversationID {
+		writeError(w, database.ErrMessageNotFound)
+		return
+	}
+
+	// Step 4: Parse request body
+	var req EditMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Content) == "" {
+		http.Error(w, "Message must have content", http.StatusBadRequest)
+		return
+	}
+
+	// Step 5: Update the text
+	msg, err := h.db.UpdateMessageContent(r.Context(), messageID, authUserID, req.Content)
 	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
-	// Step 8: Return the forwarded message
+	// Step 6: Queue the new text for moderation if it trips the word filter
+	h.flagFilteredMessage(r.Context(), msg, conversationID)
+
+	// Step 7: Return the edited message
 	response := MessageResponse{
 		MessageID:  msg.ID,
 		SenderID:   msg.SenderID,
 		SenderName: msg.SenderName,
 		Content:    msg.Content,
-		HasPhoto:   len(msg.Photo) > 0,
+		Language:   msg.Language,
 		Timestamp:  msg.Timestamp.Format("2006-01-02T15:04:05Z07:00"),
 		Status:     msg.Status,
-	}
-
-	writeJSON(w, http.StatusCreated, response)
+		Edited:     true,
+		EditedAt:   editedAt(*msg),
+		Mentions:   mentionResponses(msg.Mentions),
+		Pinned:     msg.Pinned,
+		Comments:   commentResponses(msg.Comments),
+	}
+	response.ReactionCounts = reactionCounts(response.Comments)
+	response.ReplyTo, response.Reply = replyFields(*msg)
+
+	h.publish(r.Context(), conversationID, MessageEvent{
+		eventHeader: eventHeader{EventMessageEdited, conversationID},
+		Message:     response,
+	})
+	writeJSON(w, http.StatusOK, response)
 }
 
+// Scopes of a deletion
+const (
+	DeleteScopeEveryone = "everyone" // the default
+	DeleteScopeMe       = "me"
+)
+
 /*
 DeleteMessage handles DELETE /conversations/{conversationId}/messages/{messageId}
 operationId: deleteMessage
 
 From PDF:
 "The user can... delete any sent messages."
-Only the sender can delete their own messages.
+With scope=everyone (the default) only the sender can delete their own
+messages; a message replied to stays as a tombstone, pushed as an edit.
+With scope=me any message the user can see is hidden from them only.
 */
 func (h *Handler) DeleteMessage(w http.ResponseWriter, r *http.Request) {
 	// Step 1: Check authentication
@@ -235,29 +468,83 @@ func (h *Handler) DeleteMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Step 2: Get IDs from URL
-	vars := mux.Vars(r)
-	messageID := vars["messageId"]
+	// Step 2: Get IDs from URL and the scope
+	messageID, ok := pathMessageID(w, r)
+	if !ok {
+		return
+	}
+	scope := r.URL.Query().Get("scope")
+	if scope == "" {
+		scope = DeleteScopeEveryone
+	}
+	if scope != DeleteScopeEveryone && scope != DeleteScopeMe {
+		http.Error(w, "scope must be me or everyone", http.StatusBadRequest)
+		return
+	}
 
-	// Step 3: Delete the message
-	err := h.db.DeleteMessage(messageID, authUserID)
-	if errors.Is(err, database.ErrMessageNotFound) {
-		http.Error(w, "Message not found", http.StatusNotFound)
+	// Step 3: Deleting for oneself changes nothing for the others
+	if scope == DeleteScopeMe {
+		if err := h.db.DeleteMessageForMe(r.Context(), authUserID, messageID); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
 		return
 	}
-	if errors.Is(err, database.ErrNotMessageOwner) {
-		http.Error(w, "Cannot delete messages sent by others", http.StatusForbidden)
+
+	// Step 4: Find the conversation to notify
+	msg, err := h.db.GetMessage(r.Context(), messageID)
+	if err != nil {
+		writeError(w, err)
 		return
 	}
+
+	// Step 5: Delete the message
+	tombstone, err := h.db.DeleteMessage(r.Context(), messageID, authUserID)
 	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
-	// Step 4: Return success (204 No Content)
+	// Step 6: Tell the others, then return success (204 No Content)
+	if tombstone {
+		h.publishTombstone(r.Context(), msg.ConversationID, messageID)
+	} else {
+		h.publish(r.Context(), msg.ConversationID, MessageDeletedEvent{
+			eventHeader: eventHeader{EventMessageDeleted, msg.ConversationID},
+			MessageID:   messageID,
+		})
+	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// publishTombstone pushes a message deleted for everyone as an edit, so
+// clients replace it with its tombstone
+func (h *Handler) publishTombstone(ctx context.Context, conversationID ids.ConversationID, messageID ids.MessageID) {
+	msg, err := h.db.GetMessage(ctx, messageID)
+	if err != nil {
+		log.Printf("Error loading deleted message %s: %v", messageID, err)
+		return
+	}
+	response := MessageResponse{
+		MessageID:      msg.ID,
+		SenderID:       msg.SenderID,
+		SenderName:     msg.SenderName,
+		Content:        msg.Content,
+		Timestamp:      msg.Timestamp.Format("2006-01-02T15:04:05Z07:00"),
+		Status:         msg.Status,
+		Deleted:        true,
+		Mentions:       mentionResponses(msg.Mentions),
+		Comments:       []CommentResponse{},
+		ReactionCounts: []ReactionCount{},
+	}
+	response.ReplyTo, response.Reply = replyFields(*msg)
+	h.publish(ctx, conversationID, MessageEvent{
+		eventHeader: eventHeader{EventMessageEdited, conversationID},
+		Message:     response,
+	})
+}
+
 /*
 CommentMessage handles POST /conversations/{conversationId}/messages/{messageId}/comments
 operationId: commentMessage
@@ -274,18 +561,19 @@ func (h *Handler) CommentMessage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Step 2: Get IDs from URL
-	vars := mux.Vars(r)
-	conversationID := vars["conversationId"]
-	messageID := vars["messageId"]
-
-	// Step 3: Check if user is part of this conversation
-	_, err := h.db.GetConversation(authUserID, conversationID)
-	if errors.Is(err, database.ErrConversationNotFound) {
-		http.Error(w, "Conversation not found", http.StatusNotFound)
+	conversationID, ok := pathConversationID(w, r)
+	if !ok {
+		return
+	}
+	messageID, ok := pathMessageID(w, r)
+	if !ok {
 		return
 	}
+
+	// Step 3: Check if user is part of this conversation
+	_, err := h.db.GetConversation(r.Context(), authUserID, conversationID)
 	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -302,28 +590,182 @@ func (h *Handler) CommentMessage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Step 5: Add the comment
-	err = h.db.AddComment(messageID, authUserID, req.Emoticon)
-	if errors.Is(err, database.ErrMessageNotFound) {
-		http.Error(w, "Message not found", http.StatusNotFound)
-		return
-	}
+	err = h.db.AddComment(r.Context(), messageID, authUserID, req.Emoticon)
 	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
 	// Step 6: Return success (201 Created)
+	h.bus.Publish(r.Context(), events.ReactionAdded{ConversationID: conversationID, MessageID: messageID, UserID: authUserID, Emoticon: req.Emoticon})
 	w.WriteHeader(http.StatusCreated)
 }
 
+/*
+GetReactions handles GET /conversations/{conversationId}/reactions
+operationId: getReactions
+
+Returns the reactions of a batch of messages (?messageIds=a,b,c), so a
+client that only renders the visible messages can load their reactions
+lazily with one request. Messages that are not in the conversation are
+left out of the result.
+*/
+func (h *Handler) GetReactions(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get the conversation ID and the message IDs
+	conversationID, ok := pathConversationID(w, r)
+	if !ok {
+		return
+	}
+
+	var messageIDs []ids.MessageID
+	seen := make(map[ids.MessageID]bool)
+	for _, value := range strings.Split(r.URL.Query().Get("messageIds"), ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		messageID, err := ids.ParseMessageID(value)
+		if err != nil {
+			http.Error(w, "Invalid message ID", http.StatusBadRequest)
+			return
+		}
+		if !seen[messageID] {
+			seen[messageID] = true
+			messageIDs = append(messageIDs, messageID)
+		}
+	}
+	if len(messageIDs) == 0 {
+		http.Error(w, "messageIds is required", http.StatusBadRequest)
+		return
+	}
+	if len(messageIDs) > maxReactionBatch {
+		http.Error(w, "Too many message IDs (at most "+strconv.Itoa(maxReactionBatch)+")", http.StatusBadRequest)
+		return
+	}
+
+	// Step 3: Get the reactions (this also checks the user is a participant)
+	comments, err := h.db.GetComments(r.Context(), authUserID, conversationID, messageIDs)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 4: Convert to response format
+	response := ReactionsResponse{
+		Reactions: make(map[ids.MessageID][]CommentResponse, len(comments)),
+		Counts:    make(map[ids.MessageID][]ReactionCount, len(comments)),
+	}
+	for messageID, list := range comments {
+		reactions := commentResponses(list)
+		response.Reactions[messageID] = reactions
+		response.Counts[messageID] = reactionCounts(reactions)
+	}
+
+	// Step 5: Return the reactions
+	writeJSON(w, http.StatusOK, response)
+}
+
+/*
+GetMessageReceipts handles GET /conversations/{conversationId}/messages/{messageId}/receipts
+operationId: getMessageReceipts
+
+Lists who received and read a message, and when. Only the sender can
+see this. Recipients who hide their read receipts in the conversation
+never show as read.
+*/
+func (h *Handler) GetMessageReceipts(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get IDs from URL
+	conversationID, ok := pathConversationID(w, r)
+	if !ok {
+		return
+	}
+	messageID, ok := pathMessageID(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 3: Get the receipts (this also checks the user sent the message)
+	receipts, err := h.db.GetMessageReceipts(r.Context(), authUserID, conversationID, messageID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 4: Convert to response format; the overall status only moves
+	// on once every recipient is there
+	response := MessageReceiptsResponse{MessageID: messageID, Receipts: []ReceiptResponse{}}
+	delivered, read := len(receipts) > 0, len(receipts) > 0
+	for _, rc := range receipts {
+		item := ReceiptResponse{UserID: rc.UserID, UserName: rc.UserName, Status: "sent"}
+		if rc.DeliveredAt != nil {
+			item.Status = "received"
+			item.DeliveredAt = rc.DeliveredAt.Format(time.RFC3339)
+		}
+		if rc.ReadAt != nil {
+			item.Status = "read"
+			item.ReadAt = rc.ReadAt.Format(time.RFC3339)
+		}
+		delivered = delivered && rc.DeliveredAt != nil
+		read = read && rc.ReadAt != nil
+		response.Receipts = append(response.Receipts, item)
+	}
+	switch {
+	case read:
+		response.Status = "read"
+	case delivered:
+		response.Status = "received"
+	default:
+		response.Status = "sent"
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
 /*
 UncommentMessage handles DELETE /conversations/{conversationId}/messages/{messageId}/comments
 operationId: uncommentMessage
 
 From PDF:
 "...and delete their reactions at any time (a.k.a. uncomment)."
+All the reactions of the user to the message go.
 */
 func (h *Handler) UncommentMessage(w http.ResponseWriter, r *http.Request) {
+	h.uncomment(w, r, "")
+}
+
+/*
+UncommentMessageEmoticon handles DELETE /conversations/{conversationId}/messages/{messageId}/comments/{emoticon}
+operationId: uncommentMessageEmoticon
+
+Removes one reaction of the user to a message, keeping their others.
+*/
+func (h *Handler) UncommentMessageEmoticon(w http.ResponseWriter, r *http.Request) {
+	// mux has decoded the emoticon in the path
+	emoticon := mux.Vars(r)["emoticon"]
+	if emoticon == "" {
+		http.Error(w, "Emoticon is required", http.StatusBadRequest)
+		return
+	}
+	h.uncomment(w, r, emoticon)
+}
+
+// uncomment removes the reactions of the user to a message with an
+// emoticon, or all of them when emoticon is ""
+func (h *Handler) uncomment(w http.ResponseWriter, r *http.Request, emoticon string) {
 	// Step 1: Check authentication
 	authUserID := getUserIDFromAuth(r)
 	if authUserID == "" {
@@ -332,20 +774,91 @@ func (h *Handler) UncommentMessage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Step 2: Get message ID from URL
-	vars := mux.Vars(r)
-	messageID := vars["messageId"]
-
-	// Step 3: Remove the comment
-	err := h.db.RemoveComment(messageID, authUserID)
-	if errors.Is(err, database.ErrCommentNotFound) {
-		http.Error(w, "Comment not found", http.StatusNotFound)
+	messageID, ok := pathMessageID(w, r)
+	if !ok {
 		return
 	}
+
+	// Step 3: Remove the comment
+	err := h.db.RemoveComment(r.Context(), messageID, authUserID, emoticon)
 	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
 	// Step 4: Return success (204 No Content)
+	if msg, err := h.db.GetMessage(r.Context(), messageID); err == nil {
+		h.bus.Publish(r.Context(), events.ReactionRemoved{ConversationID: msg.ConversationID, MessageID: messageID, UserID: authUserID, Emoticon: emoticon})
+	}
 	w.WriteHeader(http.StatusNoContent)
 }
+
+/*
+ReportMessage handles POST /conversations/{conversationId}/messages/{messageId}/reports
+operationId: reportMessage
+
+Puts a message on the admin moderation queue.
+*/
+func (h *Handler) ReportMessage(w http.ResponseWriter, r *http.Request) {
+	if !h.requireFeature(w, FeatureMessageReports) {
+		return
+	}
+
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get IDs from URL
+	conversationID, ok := pathConversationID(w, r)
+	if !ok {
+		return
+	}
+	messageID, ok := pathMessageID(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 3: Check if user is part of this conversation
+	_, err := h.db.GetConversation(r.Context(), authUserID, conversationID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 4: Get the reported message
+	msg, err := h.db.GetMessage(r.Context(), messageID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if msg.SenderID == authUserID {
+		http.Error(w, "Cannot report your own message", http.StatusBadRequest)
+		return
+	}
+
+	// Step 5: Parse the request
+	var req ReportMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Reason == "" || len(req.Reason) > 500 {
+		http.Error(w, "Reason must be between 1 and 500 characters", http.StatusBadRequest)
+		return
+	}
+
+	// Step 6: Add the report to the moderation queue
+	_, err = h.db.CreateModerationItem(r.Context(), database.ModerationSourceReport, msg.SenderID, &msg.ID, &authUserID, req.Reason)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 7: Return success (201 Created)
+	w.WriteHeader(http.StatusCreated)
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"wasatext/service/database"
+	"wasatext/service/database/mock"
+	"wasatext/service/ids"
+
+	"github.com/gorilla/mux"
+)
+
+// newMockHandler returns a Handler over a mock database, without the
+// background workers: it can only serve requests
+func newMockHandler(db database.AppDatabase) *Handler {
+	h := &Handler{db: db, hub: newHub(), backplane: newBackplane()}
+	cfg := DefaultConfig()
+	h.cfg.Store(&cfg)
+	h.graphql = h.graphqlSchema()
+	return h
+}
+
+// deleteMessage calls DeleteMessage as a user, as the router would
+func deleteMessage(h *Handler, userID ids.UserID, msg database.Message, scope string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodDelete, "/conversations/"+string(msg.ConversationID)+"/messages/"+string(msg.ID)+"?scope="+scope, nil)
+	r = mux.SetURLVars(r, map[string]string{"conversationId": string(msg.ConversationID), "messageId": string(msg.ID)})
+	r = r.WithContext(context.WithValue(r.Context(), authUserKey{}, userID))
+	w := httptest.NewRecorder()
+	h.DeleteMessage(w, r)
+	return w
+}
+
+func TestDeleteMessage(t *testing.T) {
+	alice := mock.NewUser("alice").Build()
+	bob := mock.NewUser("bob").Build()
+	hi := mock.NewMessage(alice).Content("hi").Build()
+	hi.ConversationID = ids.ConversationID("c0ffee00-0000-4000-8000-000000000000")
+
+	t.Run("for everyone", func(t *testing.T) {
+		db := mock.New().WithUsers(alice, bob).WithMessages(hi)
+		db.DeleteMessageFunc = func(_ context.Context, messageID ids.MessageID, userID ids.UserID) (bool, error) {
+			return false, nil
+		}
+		if w := deleteMessage(newMockHandler(db), alice.ID, hi, DeleteScopeEveryone); w.Code != http.StatusNoContent {
+			t.Fatalf("status %d, want %d: %s", w.Code, http.StatusNoContent, w.Body)
+		}
+		calls := db.DeleteMessageCalls()
+		if len(calls) != 1 || calls[0].MessageID != hi.ID || calls[0].UserID != alice.ID {
+			t.Errorf("DeleteMessage calls = %+v, want one for %s by %s", calls, hi.ID, alice.ID)
+		}
+	})
+
+	t.Run("someone else's", func(t *testing.T) {
+		db := mock.New().WithUsers(alice, bob).WithMessages(hi)
+		db.DeleteMessageFunc = func(context.Context, ids.MessageID, ids.UserID) (bool, error) {
+			return false, database.ErrNotMessageOwner
+		}
+		if w := deleteMessage(newMockHandler(db), bob.ID, hi, DeleteScopeEveryone); w.Code != http.StatusForbidden {
+			t.Errorf("status %d, want %d: %s", w.Code, http.StatusForbidden, w.Body)
+		}
+	})
+
+	t.Run("unknown message", func(t *testing.T) {
+		db := mock.New().WithUsers(alice).WithMessages()
+		if w := deleteMessage(newMockHandler(db), alice.ID, hi, DeleteScopeEveryone); w.Code != http.StatusNotFound {
+			t.Errorf("status %d, want %d: %s", w.Code, http.StatusNotFound, w.Body)
+		}
+		if calls := db.DeleteMessageCalls(); len(calls) != 0 {
+			t.Errorf("DeleteMessage called %d times for an unknown message", len(calls))
+		}
+	})
+
+	t.Run("for me", func(t *testing.T) {
+		db := mock.New().WithUsers(alice, bob).WithMessages(hi)
+		db.DeleteMessageForMeFunc = func(context.Context, ids.UserID, ids.MessageID) error {
+			return nil
+		}
+		if w := deleteMessage(newMockHandler(db), bob.ID, hi, DeleteScopeMe); w.Code != http.StatusNoContent {
+			t.Fatalf("status %d, want %d: %s", w.Code, http.StatusNoContent, w.Body)
+		}
+		if calls := db.DeleteMessageForMeCalls(); len(calls) != 1 || calls[0].UserID != bob.ID {
+			t.Errorf("DeleteMessageForMe calls = %+v, want one by %s", calls, bob.ID)
+		}
+		if calls := db.DeleteMessageCalls(); len(calls) != 0 {
+			t.Errorf("deleting for oneself deleted for everyone")
+		}
+	})
+}
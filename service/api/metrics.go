@@ -0,0 +1,81 @@
+/*
+Metrics for monitoring.
+
+GET /admin/metrics answers in the Prometheus text format, so that a
+Prometheus server (with the admin token as its bearer token) or any
+compatible agent can scrape it. It covers the database files: their
+sizes, the pages, and the WAL checkpoints the server ran (see
+database.Checkpoint). A write-ahead log that keeps growing between
+checkpoints, or checkpoints that stay busy, mean long transactions are
+holding it.
+*/
+package api
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// metric is one sample with its help text
+type metric struct {
+	name  string
+	kind  string // gauge or counter
+	help  string
+	value float64
+}
+
+/*
+GetMetrics handles GET /admin/metrics
+operationId: getMetrics
+
+Returns the database metrics in the Prometheus text format.
+*/
+func (h *Handler) GetMetrics(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check admin authentication
+	if !h.isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Step 2: Read the database stats
+	stats, err := h.db.Stats(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var lastCheckpoint float64
+	if !stats.LastCheckpoint.IsZero() {
+		lastCheckpoint = float64(stats.LastCheckpoint.Unix())
+	}
+	metrics := []metric{
+		{"wasatext_db_file_bytes", "gauge", "Size of the database file.", float64(stats.FileSize)},
+		{"wasatext_db_wal_bytes", "gauge", "Size of the write-ahead log file.", float64(stats.WALSize)},
+		{"wasatext_db_page_size_bytes", "gauge", "Size of a database page.", float64(stats.PageSize)},
+		{"wasatext_db_pages", "gauge", "Pages of the database.", float64(stats.PageCount)},
+		{"wasatext_db_free_pages", "gauge", "Unused pages of the database, released by the maintenance.", float64(stats.FreePages)},
+		{"wasatext_db_checkpoints_total", "counter", "WAL checkpoints run since the start.", float64(stats.Checkpoints)},
+		{"wasatext_db_checkpoints_busy_total", "counter", "WAL checkpoints that could not truncate the log.", float64(stats.BusyCheckpoints)},
+		{"wasatext_db_last_checkpoint_timestamp_seconds", "gauge", "When the last WAL checkpoint ran, 0 before the first.", lastCheckpoint},
+	}
+
+	// Step 3: Write them
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if err := writeMetrics(w, metrics); err != nil {
+		log.Printf("Error writing the metrics: %v", err)
+	}
+}
+
+// writeMetrics writes samples in the Prometheus text format
+func writeMetrics(w io.Writer, metrics []metric) error {
+	for _, m := range metrics {
+		_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %s\n", m.name, m.help, m.name, m.kind, m.name, strconv.FormatFloat(m.value, 'f', -1, 64))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
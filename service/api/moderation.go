@@ -0,0 +1,52 @@
+/*
+Automatic moderation flags.
+
+Besides user reports and spam detections, two automatic sources feed
+the admin moderation queue:
+  - the word filter flags messages containing configured words
+    (the message is still delivered, an admin decides what to do);
+  - honeypot accounts are never advertised to real users, so anyone
+    starting a conversation with one is almost certainly a bot.
+*/
+package api
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"wasatext/service/database"
+	"wasatext/service/ids"
+)
+
+// flagFilteredMessage queues a message that contains a filtered word
+func (h *Handler) flagFilteredMessage(ctx context.Context, msg *database.Message, conversationID ids.ConversationID) {
+	content := strings.ToLower(msg.Content)
+	for _, word := range h.config().FilterWords {
+		if word == "" || !strings.Contains(content, strings.ToLower(word)) {
+			continue
+		}
+
+		messageID := msg.ID
+		reason := "contains filtered word \"" + word + "\" (conversation " + string(conversationID) + ")"
+		if _, err := h.db.CreateModerationItem(ctx, database.ModerationSourceFilter, msg.SenderID, &messageID, nil, reason); err != nil {
+			log.Printf("Error flagging message %s: %v", msg.ID, err)
+		}
+		return
+	}
+}
+
+// flagHoneypotContact queues a user who contacted a honeypot account
+func (h *Handler) flagHoneypotContact(ctx context.Context, userID ids.UserID, target *database.User) {
+	for _, name := range h.config().HoneypotUsers {
+		if !strings.EqualFold(name, target.Name) {
+			continue
+		}
+
+		reason := "started a conversation with honeypot account " + target.Name
+		if _, err := h.db.CreateModerationItem(ctx, database.ModerationSourceHoneypot, userID, nil, nil, reason); err != nil {
+			log.Printf("Error flagging honeypot contact by %s: %v", userID, err)
+		}
+		return
+	}
+}
@@ -0,0 +1,161 @@
+/*
+Message note API handlers.
+
+This file contains:
+- setMessageNote: Attach a private note to a message, or replace it
+- deleteMessageNote: Remove the note from a message
+- getMyNotes: List the notes of the user
+
+Notes are private: only the user who wrote one ever sees it.
+*/
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"wasatext/service/database"
+	"wasatext/service/ids"
+)
+
+// maxNoteLength is the longest note, in characters
+const maxNoteLength = 1000
+
+// SetNoteRequest is the body for POST /messages/{id}/note
+type SetNoteRequest struct {
+	Note string `json:"note"`
+}
+
+// NoteResponse is a note with the message it is on
+type NoteResponse struct {
+	MessageID      ids.MessageID      `json:"messageId"`
+	ConversationID ids.ConversationID `json:"conversationId"`
+	SenderID       ids.UserID         `json:"senderId"`
+	SenderName     string             `json:"senderName"`
+	Snippet        string             `json:"snippet"`
+	HasPhoto       bool               `json:"hasPhoto"`
+	Timestamp      string             `json:"timestamp"`
+	Note           string             `json:"note"`
+	CreatedAt      string             `json:"createdAt"`
+	UpdatedAt      string             `json:"updatedAt"`
+}
+
+// noteResponse converts a note to its response format
+func noteResponse(n database.MessageNote) NoteResponse {
+	return NoteResponse{
+		MessageID:      n.Message.ID,
+		ConversationID: n.Message.ConversationID,
+		SenderID:       n.Message.SenderID,
+		SenderName:     n.Message.SenderName,
+		Snippet:        n.Message.Content,
+		HasPhoto:       n.Message.PhotoID != "",
+		Timestamp:      n.Message.Timestamp.Format(time.RFC3339),
+		Note:           n.Note,
+		CreatedAt:      n.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:      n.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+/*
+SetMessageNote handles POST /messages/{messageId}/note
+operationId: setMessageNote
+
+Attaches a private note to a message the user can read, replacing the
+note already there.
+*/
+func (h *Handler) SetMessageNote(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Parse the message ID and the note
+	messageID, ok := pathMessageID(w, r)
+	if !ok {
+		return
+	}
+	var req SetNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	note := strings.TrimSpace(req.Note)
+	if note == "" || utf8.RuneCountInString(note) > maxNoteLength {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Message: "note must be between 1 and " + strconv.Itoa(maxNoteLength) + " characters"})
+		return
+	}
+
+	// Step 3: Save the note (this also checks the user can read the message)
+	saved, err := h.db.SetMessageNote(r.Context(), authUserID, messageID, note)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 4: Return the note
+	writeJSON(w, http.StatusOK, noteResponse(*saved))
+}
+
+/*
+DeleteMessageNote handles DELETE /messages/{messageId}/note
+operationId: deleteMessageNote
+
+Removes the user's note from a message.
+*/
+func (h *Handler) DeleteMessageNote(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Delete the note
+	messageID, ok := pathMessageID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.db.DeleteMessageNote(r.Context(), authUserID, messageID); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 3: Return success (204 No Content)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+GetMyNotes handles GET /users/me/notes
+operationId: getMyNotes
+
+Lists the notes of the user on the messages they can still read, the
+last written first.
+*/
+func (h *Handler) GetMyNotes(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get the notes
+	notes, err := h.db.GetMessageNotes(r.Context(), authUserID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 3: Convert to response format and return
+	response := make([]NoteResponse, 0, len(notes))
+	for _, n := range notes {
+		response = append(response, noteResponse(n))
+	}
+	writePage(w, r, response)
+}
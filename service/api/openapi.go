@@ -0,0 +1,139 @@
+/*
+The OpenAPI specification.
+
+doc/api.yaml describes every route, request body and response. The
+server embeds the copy it was built from and serves it, with a browsable
+view:
+
+  - GET /openapi.yaml returns the specification;
+  - GET /api/docs renders it with Swagger UI (loaded from a CDN).
+
+The specification is written by hand, next to the handlers. So that it
+cannot drift from them unnoticed, CheckOpenAPI compares its operations
+with the routes registered on the router; the server warns at startup
+about every difference.
+*/
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"wasatext/doc"
+
+	"github.com/gorilla/mux"
+)
+
+// apiDocsPage renders /openapi.yaml with Swagger UI
+const apiDocsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>WASAText API</title>
+<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+<script>SwaggerUIBundle({url: "../openapi.yaml", dom_id: "#swagger-ui"});</script>
+</body>
+</html>
+`
+
+/*
+GetOpenAPI handles GET /openapi.yaml
+operationId: getOpenAPI
+
+Returns the OpenAPI specification of the API. No authentication.
+*/
+func (h *Handler) GetOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(doc.OpenAPI)
+}
+
+/*
+GetAPIDocs handles GET /api/docs
+operationId: getAPIDocs
+
+Returns a page browsing the OpenAPI specification. No authentication.
+*/
+func (h *Handler) GetAPIDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(apiDocsPage))
+}
+
+// Lines of the paths section of the specification
+var (
+	specPathLine      = regexp.MustCompile(`^  (/\S*):\s*$`)
+	specOperationLine = regexp.MustCompile(`^    (get|put|post|delete|patch|head):\s*$`)
+)
+
+// specOperations returns the operations of the specification, as
+// "METHOD /path". The file is YAML, but the paths section only needs
+// its indentation to be read.
+func specOperations(spec []byte) map[string]bool {
+	operations := make(map[string]bool)
+	inPaths := false
+	path := ""
+	scanner := bufio.NewScanner(bytes.NewReader(spec))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line != "" && line[0] != ' ' && line[0] != '#' {
+			inPaths = strings.TrimSpace(line) == "paths:"
+			continue
+		}
+		if !inPaths {
+			continue
+		}
+		if m := specPathLine.FindStringSubmatch(line); m != nil {
+			path = m[1]
+		} else if m := specOperationLine.FindStringSubmatch(line); m != nil && path != "" {
+			operations[strings.ToUpper(m[1])+" "+path] = true
+		}
+	}
+	return operations
+}
+
+// CheckOpenAPI compares the routes of the router with the operations of
+// the embedded specification, returning the differences, sorted
+func CheckOpenAPI(router *mux.Router) []string {
+	documented := specOperations(doc.OpenAPI)
+	routed := make(map[string]bool)
+	var problems []string
+
+	_ = router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil // the web UI files, any method
+		}
+		for _, method := range methods {
+			if method == http.MethodOptions {
+				continue
+			}
+			operation := method + " " + path
+			routed[operation] = true
+			if !documented[operation] {
+				problems = append(problems, operation+" is not in the OpenAPI specification")
+			}
+		}
+		return nil
+	})
+	for operation := range documented {
+		if !routed[operation] {
+			problems = append(problems, operation+" is in the OpenAPI specification but not routed")
+		}
+	}
+
+	sort.Strings(problems)
+	return problems
+}
@@ -0,0 +1,96 @@
+/*
+List envelope.
+
+Every list endpoint answers with the same envelope:
+
+	{"items": [...], "nextCursor": "...", "total": 42}
+
+items is never null, total counts the whole list. A client pages with
+?limit=N (1 to maxPageSize) and sends nextCursor back as ?cursor= to get
+the next page; nextCursor is left out on the last page. Without limit
+the whole list comes in one page. Cursors are opaque: they hold a
+position in the list, so an item added or removed between two pages
+can shift the next one.
+*/
+package api
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strconv"
+)
+
+// maxPageSize is the largest limit of a list page
+const maxPageSize = 200
+
+// Page is the envelope of a list response
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"nextCursor,omitempty"`
+	Total      int    `json:"total"`
+}
+
+// writePage writes the page of items asked for by ?limit= and ?cursor=,
+// or answers 400 when they are invalid
+func writePage[T any](w http.ResponseWriter, r *http.Request, items []T) {
+	offset, limit, ok := parsePageParams(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, newPage(items, offset, limit))
+}
+
+// newPage cuts the page of limit items (0 means all) at offset out of items
+func newPage[T any](items []T, offset, limit int) Page[T] {
+	page := Page[T]{Items: []T{}, Total: len(items)}
+	if offset >= len(items) {
+		return page
+	}
+	end := len(items)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+		page.NextCursor = encodeCursor(end)
+	}
+	page.Items = append(page.Items, items[offset:end]...)
+	return page
+}
+
+// parsePageParams reads ?cursor= and ?limit=; it answers 400 itself
+func parsePageParams(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	offset := 0
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		var ok bool
+		if offset, ok = decodeCursor(cursor); !ok {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return 0, 0, false
+		}
+	}
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxPageSize {
+			http.Error(w, "limit must be between 1 and "+strconv.Itoa(maxPageSize), http.StatusBadRequest)
+			return 0, 0, false
+		}
+		limit = n
+	}
+	return offset, limit, true
+}
+
+// encodeCursor turns a position in a list into an opaque cursor
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("o" + strconv.Itoa(offset)))
+}
+
+// decodeCursor returns the position a cursor holds
+func decodeCursor(cursor string) (int, bool) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(raw) < 2 || raw[0] != 'o' {
+		return 0, false
+	}
+	offset, err := strconv.Atoi(string(raw[1:]))
+	if err != nil || offset < 0 {
+		return 0, false
+	}
+	return offset, true
+}
@@ -0,0 +1,317 @@
+/*
+Authenticated photo endpoints.
+
+Responses only carry hasPhoto and a signed photoUrl (see media.go). A
+client that holds a session token can also fetch the photos directly:
+
+	GET /users/{userId}/photo
+	GET /groups/{groupId}/photo
+	GET /conversations/{conversationId}/messages/{messageId}/photo
+
+with the same access rules as the rest of the API: users of the same
+workspace, members of the group (anyone in the workspace for a channel),
+participants of the conversation. The bytes come with an ETag, so a
+client revalidates a profile or group photo with If-None-Match and gets
+304 until it changes. Photos of messages never change and are cached
+for good.
+
+Every photo endpoint, GET /media/{mediaId} included, takes
+?quality=original|high|medium|thumb: the smaller renditions are JPEGs
+scaled down for slow connections and small screens, the thumbnails to
+320 pixels for the conversation view. ?size=thumb|full is the same as
+?quality=thumb|original.
+
+Uploaded photos are checked before they are stored (see
+service/imaging): only JPEG, PNG, GIF and WebP images are accepted,
+whatever their content type claims, and anything else or a file that
+does not decode is answered with 415. A photo over Config.MaxPhotoSize
+bytes, or wider or higher than Config.MaxPhotoDimension pixels, is
+answered with 413. The EXIF and other metadata of the accepted photos,
+the GPS position among them, are stripped (the orientation is kept).
+*/
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"wasatext/service/database"
+	"wasatext/service/imaging"
+)
+
+// Cache policies of the photo endpoints
+const (
+	photoCacheRevalidate = "private, no-cache"                    // profile and group photos can be replaced
+	photoCacheImmutable  = "private, max-age=31536000, immutable" // message photos cannot
+)
+
+/*
+GetUserPhoto handles GET /users/{userId}/photo
+operationId: getUserPhoto
+
+Returns the profile photo of a user of the requester's workspace.
+*/
+func (h *Handler) GetUserPhoto(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get the user ID from the URL
+	userID, ok := pathUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 3: Users of other workspaces do not exist for the requester
+	me, err := h.db.GetUserByID(r.Context(), authUserID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	user, err := h.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user.WorkspaceID != me.WorkspaceID {
+		writeError(w, database.ErrUserNotFound)
+		return
+	}
+
+	// Step 4: Return the photo
+	h.servePhoto(w, r, user.PhotoID, photoCacheRevalidate)
+}
+
+/*
+GetGroupPhoto handles GET /groups/{groupId}/photo
+operationId: getGroupPhoto
+
+Returns the photo of a group the requester is a member of, or of any
+channel of the requester's workspace.
+*/
+func (h *Handler) GetGroupPhoto(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get the group ID from the URL
+	groupID, ok := pathGroupID(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 3: Check that the requester may see the group
+	group, err := h.db.GetGroup(r.Context(), groupID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	me, err := h.db.GetUserByID(r.Context(), authUserID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if group.WorkspaceID != me.WorkspaceID {
+		writeError(w, database.ErrGroupNotFound)
+		return
+	}
+	if group.Kind != database.GroupKindChannel {
+		isMember, err := h.db.IsGroupMember(r.Context(), groupID, authUserID)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if !isMember {
+			http.Error(w, "Not a member of this group", http.StatusForbidden)
+			return
+		}
+	}
+
+	// Step 4: Return the photo
+	h.servePhoto(w, r, group.PhotoID, photoCacheRevalidate)
+}
+
+/*
+GetMessagePhoto handles GET /conversations/{conversationId}/messages/{messageId}/photo
+operationId: getMessagePhoto
+
+Returns the photo of a photo message to a participant of its
+conversation. It does not mark anything as read.
+*/
+func (h *Handler) GetMessagePhoto(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get IDs from URL
+	conversationID, ok := pathConversationID(w, r)
+	if !ok {
+		return
+	}
+	messageID, ok := pathMessageID(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 3: Check that the requester takes part in the conversation
+	participants, err := h.db.GetParticipants(r.Context(), conversationID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if !slices.Contains(participants, authUserID) {
+		writeError(w, database.ErrConversationNotFound)
+		return
+	}
+
+	// Step 4: Check the message is in this conversation
+	msg, err := h.db.GetMessage(r.Context(), messageID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if msg.ConversationID != conversationID {
+		writeError(w, database.ErrMessageNotFound)
+		return
+	}
+
+	// Step 5: Return the photo
+	h.servePhoto(w, r, msg.PhotoID, photoCacheImmutable)
+}
+
+/*
+servePhoto writes a photo, in the quality asked for, with its content
+type, an ETag and the given Cache-Control. A photo never changes under
+its media ID (a new photo gets a new one), so the ID and the quality
+are the ETag: a request whose If-None-Match holds it gets 304 without
+reading the blob. No photo is answered with 404.
+*/
+func (h *Handler) servePhoto(w http.ResponseWriter, r *http.Request, photoID string, cacheControl string) {
+	if photoID == "" {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	quality := photoQuality(r)
+	if !database.ValidPhotoQuality(quality) {
+		writeError(w, database.ErrInvalidPhotoQuality)
+		return
+	}
+	etag := `"` + photoID + `"`
+	if quality != database.PhotoQualityOriginal {
+		etag = `"` + photoID + "-" + quality + `"`
+	}
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", cacheControl)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	photo, err := h.db.GetPhotoRendition(r.Context(), photoID, quality)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writePhoto(w, photo)
+}
+
+// photoSizes are the values of ?size= and their quality
+var photoSizes = map[string]string{
+	"thumb": database.PhotoQualityThumb,
+	"full":  database.PhotoQualityOriginal,
+}
+
+// photoQuality returns the quality a photo is asked for, with ?quality=
+// or ?size=, the original by default
+func photoQuality(r *http.Request) string {
+	query := r.URL.Query()
+	if quality := query.Get("quality"); quality != "" {
+		return quality
+	}
+	if size := query.Get("size"); size != "" {
+		if quality, ok := photoSizes[size]; ok {
+			return quality
+		}
+		return size // not a quality either, so refused
+	}
+	return database.PhotoQualityOriginal
+}
+
+// writePhoto writes the bytes of a photo with its sniffed content type
+func writePhoto(w http.ResponseWriter, photo []byte) {
+	w.Header().Set("Content-Type", http.DetectContentType(photo))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(photo)
+}
+
+// etagMatches reports whether an If-None-Match header lists the ETag
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag || candidate == "W/"+etag {
+			return true
+		}
+	}
+	return false
+}
+
+// readPhoto reads an uploaded photo from the request body; it answers
+// 400 or 413 itself
+func (h *Handler) readPhoto(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	photo, err := io.ReadAll(http.MaxBytesReader(w, r.Body, h.config().MaxPhotoSize))
+	if err != nil {
+		h.photoReadError(w, err)
+		return nil, false
+	}
+	if len(photo) == 0 {
+		http.Error(w, "No photo provided", http.StatusBadRequest)
+		return nil, false
+	}
+	return h.checkPhoto(w, photo)
+}
+
+// checkPhoto validates an uploaded photo and strips its metadata; it
+// answers 413 or 415 itself
+func (h *Handler) checkPhoto(w http.ResponseWriter, photo []byte) ([]byte, bool) {
+	maxDimension := h.config().MaxPhotoDimension
+	info, err := imaging.Check(photo, maxDimension)
+	if err == nil {
+		photo, err = imaging.StripMetadata(photo, info.Format)
+	}
+	switch {
+	case err == nil:
+		return photo, true
+	case errors.Is(err, imaging.ErrTooLarge):
+		http.Error(w, "Photo too large (at most "+strconv.Itoa(maxDimension)+" pixels wide and high)", http.StatusRequestEntityTooLarge)
+	case errors.Is(err, imaging.ErrUnsupportedFormat):
+		http.Error(w, "Unsupported photo format (send a JPEG, PNG, GIF or WebP image)", http.StatusUnsupportedMediaType)
+	default:
+		http.Error(w, "Malformed photo (it cannot be decoded)", http.StatusUnsupportedMediaType)
+	}
+	return nil, false
+}
+
+// photoReadError answers a failed photo upload: 413 past the size limit
+func (h *Handler) photoReadError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, "Photo too large (at most "+strconv.FormatInt(h.config().MaxPhotoSize, 10)+" bytes)", http.StatusRequestEntityTooLarge)
+		return
+	}
+	http.Error(w, "Failed to read photo", http.StatusBadRequest)
+}
@@ -0,0 +1,152 @@
+/*
+Pinned message API handlers.
+
+This file contains:
+- pinMessage: Pin a message in its conversation
+- unpinMessage: Unpin it
+- getPins: The pinned messages of a conversation
+
+In a group or a channel only the group admin pins messages; in a direct
+conversation either participant does (see database/pins.go). Pinned
+messages carry pinned: true wherever they are listed, and every change
+is pushed to the participants as a pin event.
+*/
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"wasatext/service/ids"
+)
+
+// PinResponse is a pinned message
+type PinResponse struct {
+	Message      MessageResponse `json:"message"`
+	PinnedBy     ids.UserID      `json:"pinnedBy"`
+	PinnedByName string          `json:"pinnedByName"`
+	PinnedAt     string          `json:"pinnedAt"`
+}
+
+/*
+PinMessage handles PUT /conversations/{conversationId}/messages/{messageId}/pin
+operationId: pinMessage
+
+Pins a message; pinning it again changes nothing. A conversation holds at
+most database.MaxPinnedMessages pins (409 past that).
+*/
+func (h *Handler) PinMessage(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get IDs from URL
+	conversationID, ok := pathConversationID(w, r)
+	if !ok {
+		return
+	}
+	messageID, ok := pathMessageID(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 3: Pin it (this also checks the user may)
+	added, err := h.db.PinMessage(r.Context(), authUserID, conversationID, messageID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 4: Tell the participants, then return success (204 No Content)
+	if added {
+		h.publish(r.Context(), conversationID, PinEvent{
+			eventHeader: eventHeader{EventPin, conversationID},
+			MessageID:   messageID,
+			Pinned:      true,
+		})
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+UnpinMessage handles DELETE /conversations/{conversationId}/messages/{messageId}/pin
+operationId: unpinMessage
+
+Unpins a message; 404 if it is not pinned.
+*/
+func (h *Handler) UnpinMessage(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get IDs from URL
+	conversationID, ok := pathConversationID(w, r)
+	if !ok {
+		return
+	}
+	messageID, ok := pathMessageID(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 3: Unpin it (this also checks the user may)
+	if err := h.db.UnpinMessage(r.Context(), authUserID, conversationID, messageID); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 4: Tell the participants, then return success (204 No Content)
+	h.publish(r.Context(), conversationID, PinEvent{
+		eventHeader: eventHeader{EventPin, conversationID},
+		MessageID:   messageID,
+		Pinned:      false,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+GetPins handles GET /conversations/{conversationId}/pins
+operationId: getPins
+
+Returns the pinned messages of a conversation, the latest pinned first.
+Messages the user cleared or deleted for themselves are left out.
+*/
+func (h *Handler) GetPins(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get conversation ID from URL
+	conversationID, ok := pathConversationID(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 3: Get the pins
+	pins, err := h.db.GetPins(r.Context(), authUserID, conversationID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 4: Convert to response format
+	response := make([]PinResponse, 0, len(pins))
+	for _, pin := range pins {
+		response = append(response, PinResponse{
+			Message:      h.messageResponse(pin.Message),
+			PinnedBy:     pin.PinnedBy,
+			PinnedByName: pin.PinnedByName,
+			PinnedAt:     pin.PinnedAt.UTC().Format(time.RFC3339),
+		})
+	}
+	writePage(w, r, response)
+}
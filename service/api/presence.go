@@ -0,0 +1,320 @@
+/*
+Presence.
+
+Clients tell the server the user is around with POST /presence/heartbeat
+every presenceHeartbeat; an open WebSocket does the same with every
+frame it reads, the pongs to the server pings included. GET /presence
+answers who of a list of users is online, and the users of searches and
+member lists carry it too. Heartbeats are frequent, so presence lives in
+memory: only the time of the last heartbeat of each user is saved, every
+presenceSave, so that lastSeen outlives presenceRetention and restarts.
+Presence only covers the clients of this server instance, even when
+the events cross instances (see backplane.go).
+
+A user can hide their presence (PUT /users/me/privacy): they then appear
+offline, and never seen, to everyone.
+*/
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"wasatext/service/database"
+	"wasatext/service/ids"
+)
+
+const (
+	presenceHeartbeat = 30 * time.Second      // how often clients are asked to send a heartbeat
+	presenceTTL       = 2 * presenceHeartbeat // a user is online this long after the last heartbeat
+	presenceRetention = time.Hour             // lastSeen is kept this long after the last heartbeat
+	maxPresenceBatch  = 100                   // user IDs in one GET /presence
+	presencePrune     = presenceRetention / 4 // how often forgotten users are dropped
+	presenceSave      = time.Minute           // how often the last heartbeats are saved
+)
+
+// UserPresence is the presence of one user
+type UserPresence struct {
+	Online   bool   `json:"online"`
+	LastSeen string `json:"lastSeen,omitempty"` // last heartbeat, within the last hour
+}
+
+// PresenceResponse is the response of GET /presence
+type PresenceResponse struct {
+	Presence map[ids.UserID]UserPresence `json:"presence"`
+}
+
+// HeartbeatResponse is the response of POST /presence/heartbeat
+type HeartbeatResponse struct {
+	IntervalSeconds int `json:"intervalSeconds"` // send the next heartbeat within this
+}
+
+// UserPrivacyRequest is the body for PUT /users/me/privacy
+type UserPrivacyRequest struct {
+	HidePresence *bool `json:"hidePresence"`
+}
+
+// UserPrivacyResponse is what the user shares with everyone
+type UserPrivacyResponse struct {
+	HidePresence bool `json:"hidePresence"`
+}
+
+// presenceMap holds the last heartbeat of every user seen lately
+type presenceMap struct {
+	mu       sync.Mutex
+	lastSeen map[ids.UserID]time.Time
+	unsaved  map[ids.UserID]time.Time // heartbeats not saved to the database yet
+	pruned   time.Time
+}
+
+// touch records a heartbeat of a user
+func (pm *presenceMap) touch(userID ids.UserID, now time.Time) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	if pm.lastSeen == nil {
+		pm.lastSeen = make(map[ids.UserID]time.Time)
+	}
+	if pm.unsaved == nil {
+		pm.unsaved = make(map[ids.UserID]time.Time)
+	}
+	pm.lastSeen[userID] = now
+	pm.unsaved[userID] = now
+
+	if now.Sub(pm.pruned) >= presencePrune {
+		for id, seen := range pm.lastSeen {
+			if now.Sub(seen) > presenceRetention {
+				delete(pm.lastSeen, id)
+			}
+		}
+		pm.pruned = now
+	}
+}
+
+// clear forgets every heartbeat
+func (pm *presenceMap) clear() {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.lastSeen = nil
+	pm.unsaved = nil
+}
+
+// takeUnsaved returns the heartbeats not saved yet, and forgets them
+func (pm *presenceMap) takeUnsaved() map[ids.UserID]time.Time {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	unsaved := pm.unsaved
+	pm.unsaved = nil
+	return unsaved
+}
+
+// get returns the presence of a user
+func (pm *presenceMap) get(userID ids.UserID, now time.Time) UserPresence {
+	pm.mu.Lock()
+	seen, ok := pm.lastSeen[userID]
+	pm.mu.Unlock()
+	if !ok || now.Sub(seen) > presenceRetention {
+		return UserPresence{}
+	}
+	return UserPresence{
+		Online:   now.Sub(seen) <= presenceTTL,
+		LastSeen: seen.UTC().Format(time.RFC3339),
+	}
+}
+
+// userPresence returns the presence of a user as the others see it: from
+// the heartbeats, or the last one saved for the users not seen lately
+func (h *Handler) userPresence(user database.User, now time.Time) UserPresence {
+	if user.HidePresence {
+		return UserPresence{}
+	}
+	p := h.presence.get(user.ID, now)
+	if p.LastSeen == "" && user.LastSeen != nil {
+		p.LastSeen = user.LastSeen.UTC().Format(time.RFC3339)
+	}
+	return p
+}
+
+// runPresence saves the last heartbeats every presenceSave until ctx is
+// cancelled, then a last time
+func (h *Handler) runPresence(ctx context.Context) {
+	ticker := time.NewTicker(presenceSave)
+	defer ticker.Stop()
+
+	work := context.WithoutCancel(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			h.savePresence(work)
+			return
+		case <-ticker.C:
+			h.savePresence(work)
+		}
+	}
+}
+
+// savePresence saves the heartbeats received since the last save; on
+// error they are lost, the next heartbeats replace them
+func (h *Handler) savePresence(ctx context.Context) {
+	unsaved := h.presence.takeUnsaved()
+	if len(unsaved) == 0 {
+		return
+	}
+	if err := h.db.RecordLastSeen(ctx, unsaved); err != nil {
+		log.Printf("Error saving the last-seen times: %v", err)
+	}
+}
+
+/*
+SendHeartbeat handles POST /presence/heartbeat
+operationId: sendHeartbeat
+
+Marks the user online for presenceTTL. Clients with an open WebSocket
+do not need it.
+*/
+func (h *Handler) SendHeartbeat(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Record the heartbeat and tell when the next one is due
+	h.presence.touch(authUserID, time.Now())
+	writeJSON(w, http.StatusOK, HeartbeatResponse{IntervalSeconds: int(presenceHeartbeat.Seconds())})
+}
+
+/*
+GetPresence handles GET /presence
+operationId: getPresence
+
+Returns the presence of up to maxPresenceBatch users, given as
+?userIds=a,b,c. Users of other workspaces, unknown users and users
+hiding their presence are reported offline.
+*/
+func (h *Handler) GetPresence(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Parse the user IDs
+	var userIDs []ids.UserID
+	seen := make(map[ids.UserID]bool)
+	for _, value := range strings.Split(r.URL.Query().Get("userIds"), ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		userID, err := ids.ParseUserID(value)
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+		if !seen[userID] {
+			seen[userID] = true
+			userIDs = append(userIDs, userID)
+		}
+	}
+	if len(userIDs) == 0 {
+		http.Error(w, "userIds is required", http.StatusBadRequest)
+		return
+	}
+	if len(userIDs) > maxPresenceBatch {
+		http.Error(w, "Too many user IDs (at most "+strconv.Itoa(maxPresenceBatch)+")", http.StatusBadRequest)
+		return
+	}
+
+	// Step 3: Look up the presence, hiding the other workspaces
+	me, err := h.db.GetUserByID(r.Context(), authUserID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	now := time.Now()
+	response := PresenceResponse{Presence: make(map[ids.UserID]UserPresence, len(userIDs))}
+	for _, userID := range userIDs {
+		user, err := h.db.GetUserByID(r.Context(), userID)
+		if err != nil && !errors.Is(err, database.ErrUserNotFound) {
+			writeError(w, err)
+			return
+		}
+		if err != nil || user.WorkspaceID != me.WorkspaceID {
+			response.Presence[userID] = UserPresence{}
+			continue
+		}
+		response.Presence[userID] = h.userPresence(*user, now)
+	}
+
+	// Step 4: Return the presence
+	writeJSON(w, http.StatusOK, response)
+}
+
+/*
+GetMyPrivacy handles GET /users/me/privacy
+operationId: getMyPrivacy
+
+Returns what the user shares with everyone.
+*/
+func (h *Handler) GetMyPrivacy(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get the settings
+	privacy, err := h.db.GetUserPrivacy(r.Context(), authUserID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, UserPrivacyResponse{HidePresence: privacy.HidePresence})
+}
+
+/*
+SetMyPrivacy handles PUT /users/me/privacy
+operationId: setMyPrivacy
+
+Changes what the user shares with everyone. With hidePresence, the
+others see them offline and never seen, wherever presence is shown.
+*/
+func (h *Handler) SetMyPrivacy(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Parse the request; the setting is required
+	var req UserPrivacyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.HidePresence == nil {
+		http.Error(w, "hidePresence is required", http.StatusBadRequest)
+		return
+	}
+
+	// Step 3: Save the settings
+	privacy := database.UserPrivacy{HidePresence: *req.HidePresence}
+	if err := h.db.SetUserPrivacy(r.Context(), authUserID, privacy); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 4: Return the new settings
+	writeJSON(w, http.StatusOK, UserPrivacyResponse{HidePresence: privacy.HidePresence})
+}
@@ -0,0 +1,143 @@
+/*
+Media processing worker.
+
+New photos are stored as they are uploaded and processed afterwards:
+the worker makes their renditions (see service/database/processing.go)
+so that clients asking for a thumbnail get it at once. Until then a
+photo message has processingState pending, and GET /media/{mediaId}/status
+tells a client when to swap its spinner for the photo. Uploads wake the
+worker up; a periodic sweep also picks up what a restart interrupted.
+
+With OCR configured, the worker then reads the text of the message
+photos for the search (see service/database/phototext.go). Turning it
+on reads the photos sent before too, batch by batch.
+*/
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"wasatext/service/database"
+	"wasatext/service/ocr"
+)
+
+// mediaSweepInterval is how often pending photos are looked up
+const mediaSweepInterval = 30 * time.Second
+
+// photoTextBatchSize is how many photos are read between two lookups
+const photoTextBatchSize = 20
+
+// OCRConfig sets up the reading of the text of the message photos
+type OCRConfig struct {
+	// Command runs the OCR program: the photo goes to its standard
+	// input, the text comes from its standard output (see ocr.Command).
+	// OCR is off when it is empty, the default.
+	Command []string
+
+	// Timeout is how long the program may take on one photo
+	Timeout time.Duration
+}
+
+// engine returns the OCR engine, nil when OCR is off
+func (c OCRConfig) engine() ocr.Engine {
+	if len(c.Command) == 0 {
+		return nil
+	}
+	return &ocr.Command{Path: c.Command[0], Args: c.Command[1:], Timeout: c.Timeout}
+}
+
+// mediaWorker processes the photos waiting to be processed
+type mediaWorker struct {
+	db     database.AppDatabase
+	config func() *Config
+	wake   chan struct{}
+}
+
+// newMediaWorker returns the worker; New starts it
+func newMediaWorker(db database.AppDatabase, config func() *Config) *mediaWorker {
+	return &mediaWorker{db: db, config: config, wake: make(chan struct{}, 1)}
+}
+
+// poke tells the worker a photo was uploaded. A worker already awake
+// picks it up in the same sweep.
+func (mw *mediaWorker) poke() {
+	select {
+	case mw.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run works until ctx is cancelled, after completing the sweep under
+// way; what is left stays pending for the next run
+func (mw *mediaWorker) run(ctx context.Context) {
+	ticker := time.NewTicker(mediaSweepInterval)
+	defer ticker.Stop()
+
+	work := context.WithoutCancel(ctx)
+	for {
+		mw.sweep(work)
+		select {
+		case <-ctx.Done():
+			return
+		case <-mw.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// sweep processes every pending photo; on error a photo stays pending
+// for the next sweep
+func (mw *mediaWorker) sweep(ctx context.Context) {
+	pending, err := mw.db.PendingMedia(ctx)
+	if err != nil {
+		log.Printf("Error listing pending media: %v", err)
+		return
+	}
+	for _, id := range pending {
+		if err := mw.db.ProcessMedia(ctx, id); err != nil {
+			log.Printf("Error processing photo %s: %v", id, err)
+		}
+	}
+
+	if engine := mw.config().OCR.engine(); engine != nil {
+		mw.readPhotoText(ctx, engine)
+	}
+}
+
+// readPhotoText reads the text of the photos not read yet. A photo the
+// engine fails on is recorded without text; when the engine itself is
+// unavailable, the rest waits for the next sweep.
+func (mw *mediaWorker) readPhotoText(ctx context.Context, engine ocr.Engine) {
+	for {
+		pending, err := mw.db.PendingPhotoText(ctx, photoTextBatchSize)
+		if err != nil {
+			log.Printf("Error listing the photos to read: %v", err)
+			return
+		}
+		for _, id := range pending {
+			photo, err := mw.db.GetPhoto(ctx, id)
+			if err != nil {
+				log.Printf("Error loading photo %s: %v", id, err)
+				return
+			}
+			text, err := engine.Extract(ctx, photo)
+			if err != nil && !errors.Is(err, ocr.ErrUnreadable) {
+				log.Printf("Error reading the text of photo %s: %v", id, err)
+				return
+			}
+			if err != nil {
+				log.Printf("Photo %s could not be read: %v", id, err)
+			}
+			if err := mw.db.SetPhotoText(ctx, id, text); err != nil {
+				log.Printf("Error saving the text of photo %s: %v", id, err)
+				return
+			}
+		}
+		if len(pending) < photoTextBatchSize {
+			return
+		}
+	}
+}
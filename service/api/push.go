@@ -0,0 +1,423 @@
+/*
+Push notification API handlers.
+
+With VAPID keys configured, users hear of new messages while none of
+their clients is connected: a browser subscribes with the public key of
+GET /push/vapid-key, registers its subscription, and each new message
+is then pushed to the participants who are offline (no WebSocket, no
+recent heartbeat; see presence.go), unless they muted the conversation.
+The notification carries the beginning of the message, as the push
+service only sees it encrypted. Sending, retries and the cleanup of
+dead subscriptions are done by service/notifications in the background.
+
+This file contains:
+- getVAPIDKey: The public key to subscribe with
+- createPushSubscription: Register a browser
+- getMyPushSubscriptions: The user's registered browsers
+- deletePushSubscription: Unregister one
+- getConversationMute: Whether the user muted a conversation
+- muteConversation: Mute it, for good or until a time
+- unmuteConversation: Unmute it
+*/
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"wasatext/service/database"
+	"wasatext/service/events"
+	"wasatext/service/ids"
+	"wasatext/service/notifications"
+
+	"github.com/gorilla/mux"
+)
+
+// pushPreviewLength is how much of the text of a message a notification
+// shows, in runes
+const pushPreviewLength = 200
+
+// PushConfig sets up the push notifications
+type PushConfig struct {
+	// VAPIDPrivateKey identifies the server to the push services: the
+	// P-256 private key in base64url, as web-push tools generate it.
+	// Push notifications are off when it is empty, the default.
+	VAPIDPrivateKey string
+
+	// Subject is the mailto: or https: URL the push services can contact
+	// about the server
+	Subject string
+}
+
+// keys returns the VAPID keys, nil when push notifications are off
+func (c PushConfig) keys() *notifications.VAPID {
+	if c.VAPIDPrivateKey == "" {
+		return nil
+	}
+	keys, err := notifications.NewVAPID(c.VAPIDPrivateKey, c.Subject)
+	if err != nil {
+		log.Printf("Push notifications off: %v", err)
+		return nil
+	}
+	return keys
+}
+
+// VAPIDKeyResponse is the response of GET /push/vapid-key
+type VAPIDKeyResponse struct {
+	PublicKey string `json:"publicKey"` // the applicationServerKey, base64url
+}
+
+// PushSubscriptionRequest is the body for POST /push/subscriptions, the
+// JSON of a browser PushSubscription
+type PushSubscriptionRequest struct {
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+}
+
+// PushSubscriptionResponse represents a push subscription in API responses
+type PushSubscriptionResponse struct {
+	SubscriptionID int64  `json:"subscriptionId"`
+	Endpoint       string `json:"endpoint"`
+	CreatedAt      string `json:"createdAt"`
+}
+
+// MuteRequest is the body for PUT /conversations/{conversationId}/mute
+type MuteRequest struct {
+	Until string `json:"until,omitempty"` // RFC 3339; muted until unmuted when empty
+}
+
+// MuteResponse is whether the user muted a conversation
+type MuteResponse struct {
+	Muted bool   `json:"muted"`
+	Until string `json:"until,omitempty"`
+}
+
+// PushPayload is what a push notification carries, for the service
+// worker of the client to show
+type PushPayload struct {
+	Type           string             `json:"type"` // message
+	ConversationID ids.ConversationID `json:"conversationId"`
+	MessageID      ids.MessageID      `json:"messageId"`
+	SenderName     string             `json:"senderName"`
+	Preview        string             `json:"preview,omitempty"` // the beginning of the text
+	HasPhoto       bool               `json:"hasPhoto,omitempty"`
+	Timestamp      string             `json:"timestamp"`
+}
+
+// requirePush answers 404 when push notifications are off
+func (h *Handler) requirePush(w http.ResponseWriter) (*notifications.VAPID, bool) {
+	keys := h.config().Push.keys()
+	if keys == nil {
+		http.Error(w, "Push notifications are not configured", http.StatusNotFound)
+		return nil, false
+	}
+	return keys, true
+}
+
+/*
+GetVAPIDKey handles GET /push/vapid-key
+operationId: getVAPIDKey
+
+Returns the public key browsers subscribe with; 404 while push
+notifications are off.
+*/
+func (h *Handler) GetVAPIDKey(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Return the key
+	keys, ok := h.requirePush(w)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, VAPIDKeyResponse{PublicKey: keys.PublicKey()})
+}
+
+/*
+CreatePushSubscription handles POST /push/subscriptions
+operationId: createPushSubscription
+
+Registers a browser of the user, with the subscription it got from
+PushManager.subscribe(). Registering the same endpoint again updates it.
+*/
+func (h *Handler) CreatePushSubscription(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if _, ok := h.requirePush(w); !ok {
+		return
+	}
+
+	// Step 2: Parse and check the subscription
+	var req PushSubscriptionRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 8<<10)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	sub := notifications.Subscription{Endpoint: req.Endpoint, P256dh: req.Keys.P256dh, Auth: req.Keys.Auth}
+	if err := sub.Validate(); err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Message: "Invalid subscription: " + err.Error()})
+		return
+	}
+
+	// Step 3: Store it
+	stored, err := h.db.AddPushSubscription(r.Context(), authUserID, sub.Endpoint, sub.P256dh, sub.Auth)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 4: Return it (201 Created)
+	writeJSON(w, http.StatusCreated, pushSubscriptionResponse(*stored))
+}
+
+/*
+GetMyPushSubscriptions handles GET /push/subscriptions
+operationId: getMyPushSubscriptions
+
+Returns the browsers the user receives notifications on, the oldest
+first.
+*/
+func (h *Handler) GetMyPushSubscriptions(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get the subscriptions
+	subs, err := h.db.GetPushSubscriptions(r.Context(), authUserID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 3: Convert to response format
+	response := make([]PushSubscriptionResponse, 0, len(subs))
+	for _, sub := range subs {
+		response = append(response, pushSubscriptionResponse(sub))
+	}
+	writePage(w, r, response)
+}
+
+/*
+DeletePushSubscription handles DELETE /push/subscriptions/{subscriptionId}
+operationId: deletePushSubscription
+
+Stops the notifications to a browser of the user, e.g. on logout.
+*/
+func (h *Handler) DeletePushSubscription(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Parse the subscription ID
+	subscriptionID, err := strconv.ParseInt(mux.Vars(r)["subscriptionId"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid subscription ID", http.StatusBadRequest)
+		return
+	}
+
+	// Step 3: Delete it
+	if err := h.db.DeletePushSubscription(r.Context(), authUserID, subscriptionID); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 4: Return success (204 No Content)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+GetConversationMute handles GET /conversations/{conversationId}/mute
+operationId: getConversationMute
+
+Returns whether the user muted the conversation.
+*/
+func (h *Handler) GetConversationMute(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get conversation ID from URL
+	conversationID, ok := pathConversationID(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 3: Get the mute (this also checks the user is a participant)
+	mute, err := h.db.GetMute(r.Context(), authUserID, conversationID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, muteResponse(*mute))
+}
+
+/*
+MuteConversation handles PUT /conversations/{conversationId}/mute
+operationId: muteConversation
+
+Mutes the conversation for the user: its new messages send them no push
+notification, until the given time or until unmuted. Muting again
+replaces the time.
+*/
+func (h *Handler) MuteConversation(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get conversation ID from URL
+	conversationID, ok := pathConversationID(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 3: Parse request body; an empty one mutes until unmuted
+	var req MuteRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	mute := database.Mute{Muted: true}
+	if req.Until != "" {
+		until, err := time.Parse(time.RFC3339, req.Until)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, ErrorResponse{Message: "until must be an RFC 3339 time"})
+			return
+		}
+		mute.Until = &until
+	}
+
+	// Step 4: Mute it (this also checks the user is a participant)
+	if err := h.db.SetMute(r.Context(), authUserID, conversationID, mute); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, muteResponse(mute))
+}
+
+/*
+UnmuteConversation handles DELETE /conversations/{conversationId}/mute
+operationId: unmuteConversation
+
+Unmutes the conversation for the user; unmuting a conversation that is
+not muted changes nothing.
+*/
+func (h *Handler) UnmuteConversation(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get conversation ID from URL
+	conversationID, ok := pathConversationID(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 3: Unmute it (this also checks the user is a participant)
+	if err := h.db.SetMute(r.Context(), authUserID, conversationID, database.Mute{}); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 4: Return success (204 No Content)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+pushMessage notifies the offline participants of a new message, on every
+browser they registered, unless they muted its conversation. Notices
+are not pushed: they are about the group, not for the attention of its
+members.
+*/
+func (h *Handler) pushMessage(ctx context.Context, e events.MessageCreated) {
+	msg := e.Message
+	if msg.System || !h.push.Enabled() {
+		return
+	}
+
+	now := time.Now()
+	conversationID := msg.ConversationID
+	subs, err := h.db.PushRecipients(ctx, conversationID, msg.SenderID, now)
+	if err != nil {
+		log.Printf("Error listing the push subscriptions for %s: %v", conversationID, err)
+		return
+	}
+	offline := make([]notifications.Subscription, 0, len(subs))
+	for _, sub := range subs {
+		if !h.presence.get(sub.UserID, now).Online {
+			offline = append(offline, notifications.Subscription{Endpoint: sub.Endpoint, P256dh: sub.P256dh, Auth: sub.Auth})
+		}
+	}
+	if len(offline) == 0 {
+		return
+	}
+
+	preview := []rune(msg.Content)
+	if len(preview) > pushPreviewLength {
+		preview = append(preview[:pushPreviewLength-1], '…')
+	}
+	payload, err := json.Marshal(PushPayload{
+		Type:           "message",
+		ConversationID: conversationID,
+		MessageID:      msg.ID,
+		SenderName:     msg.SenderName,
+		Preview:        string(preview),
+		HasPhoto:       msg.PhotoID != "",
+		Timestamp:      msg.Timestamp.Format("2006-01-02T15:04:05Z07:00"),
+	})
+	if err != nil {
+		log.Printf("Error encoding a push notification: %v", err)
+		return
+	}
+	h.push.Notify(offline, payload)
+}
+
+// pushSubscriptionResponse converts a push subscription to its response
+func pushSubscriptionResponse(sub database.PushSubscription) PushSubscriptionResponse {
+	return PushSubscriptionResponse{
+		SubscriptionID: sub.ID,
+		Endpoint:       sub.Endpoint,
+		CreatedAt:      sub.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// muteResponse converts a mute to its response
+func muteResponse(mute database.Mute) MuteResponse {
+	response := MuteResponse{Muted: mute.Muted}
+	if mute.Until != nil {
+		response.Until = mute.Until.UTC().Format(time.RFC3339)
+	}
+	return response
+}
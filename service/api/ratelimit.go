@@ -0,0 +1,190 @@
+/*
+Request rate limits.
+
+RateLimitMiddleware gives every client a token bucket per class of
+route: a client can send Burst requests at once, then PerMinute
+requests a minute. Authenticated clients are counted by user, the
+others by address, so that a user keeps their limits across devices
+and anonymous clients cannot get around them by not logging in.
+
+The classes are:
+
+	login  POST /session, stricter: it is how accounts are created
+	admin  the admin endpoints without the admin token, as strict: the
+	       token would otherwise be guessed at full speed
+	read   GET requests
+	write  the other requests
+
+A request over the limit is answered 429 with Retry-After, the seconds
+until a token is back. The web UI files and the admin endpoints called
+with the admin token are not limited; webhooks are limited by address
+on top of their own limit (see hooks.go). The limits follow the
+configuration reloads.
+*/
+package api
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/time/rate"
+)
+
+// Route classes of the rate limits
+const (
+	RateClassLogin = "login"
+	RateClassAdmin = "admin"
+	RateClassRead  = "read"
+	RateClassWrite = "write"
+)
+
+// RateLimit is the token bucket of a route class
+type RateLimit struct {
+	PerMinute int // steady requests per minute, 0 for no limit
+	Burst     int // requests at once
+}
+
+// RateLimitConfig holds the request rate limits
+type RateLimitConfig struct {
+	Enabled bool
+	Classes map[string]RateLimit // by route class
+}
+
+// DefaultRateLimitConfig returns the limits used when none are configured
+func DefaultRateLimitConfig() RateLimitConfig {
+	return RateLimitConfig{
+		Enabled: true,
+		Classes: map[string]RateLimit{
+			RateClassLogin: {PerMinute: 10, Burst: 5},
+			RateClassAdmin: {PerMinute: 10, Burst: 5},
+			RateClassRead:  {PerMinute: 600, Burst: 120},
+			RateClassWrite: {PerMinute: 120, Burst: 30},
+		},
+	}
+}
+
+// rateLimiterIdle is how long a bucket is kept unused; a bucket idle
+// that long is full again anyway
+const rateLimiterIdle = 10 * time.Minute
+
+// rateLimiters holds the bucket of every client, by class and client
+type rateLimiters struct {
+	mu        sync.Mutex
+	limiters  map[string]*clientLimiter
+	lastSweep time.Time
+}
+
+// clientLimiter is the bucket of a client in a class
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	limit    RateLimit // the settings the limiter was made with
+	lastSeen time.Time
+}
+
+// reserve takes a token from the bucket of a client, returning how long
+// to wait for one when there is none left (the token is not taken then)
+func (rl *rateLimiters) reserve(key string, limit RateLimit, now time.Time) time.Duration {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if rl.limiters == nil {
+		rl.limiters = make(map[string]*clientLimiter)
+	}
+	if now.Sub(rl.lastSweep) > rateLimiterIdle {
+		for k, cl := range rl.limiters {
+			if now.Sub(cl.lastSeen) > rateLimiterIdle {
+				delete(rl.limiters, k)
+			}
+		}
+		rl.lastSweep = now
+	}
+
+	every := rate.Every(time.Minute / time.Duration(limit.PerMinute))
+	cl, ok := rl.limiters[key]
+	if !ok {
+		cl = &clientLimiter{limiter: rate.NewLimiter(every, limit.Burst), limit: limit}
+		rl.limiters[key] = cl
+	} else if cl.limit != limit {
+		// The configuration was reloaded
+		cl.limiter.SetLimitAt(now, every)
+		cl.limiter.SetBurstAt(now, limit.Burst)
+		cl.limit = limit
+	}
+	cl.lastSeen = now
+
+	reservation := cl.limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return time.Minute // a burst of 0 lets nothing through
+	}
+	delay := reservation.DelayFrom(now)
+	if delay > 0 {
+		reservation.CancelAt(now)
+	}
+	return delay
+}
+
+// RateLimitMiddleware applies the rate limit of the route class to the
+// client of each request
+func (h *Handler) RateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := h.config().RateLimit
+		class := rateClass(r)
+		if class == RateClassAdmin && h.isAdmin(r) {
+			class = ""
+		}
+		limit, ok := cfg.Classes[class]
+		if !cfg.Enabled || class == "" || !ok || limit.PerMinute <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		now := time.Now()
+		if delay := h.rateLimiters.reserve(class+":"+rateClient(r), limit, now); delay > 0 {
+			writeTooManyRequests(w, now.Add(delay), "Too many requests, slow down")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateClass returns the route class of a request, "" for the routes
+// that are not limited; the caller exempts the admin class when the
+// request has the admin token
+func rateClass(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		template, _ := route.GetPathTemplate()
+		if template == "/" {
+			return ""
+		}
+		if strings.HasPrefix(template, "/admin/") {
+			return RateClassAdmin
+		}
+		if template == "/session" && r.Method == http.MethodPost {
+			return RateClassLogin
+		}
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		return RateClassRead
+	case http.MethodOptions:
+		return ""
+	default:
+		return RateClassWrite
+	}
+}
+
+// rateClient names the client a request is counted against: its user,
+// or its address when unauthenticated
+func rateClient(r *http.Request) string {
+	if userID := getUserIDFromAuth(r); userID != "" {
+		return "user:" + string(userID)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "address:" + host
+}
@@ -0,0 +1,24 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestAdminRateLimit(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.AdminToken = "admin-token"
+	burst := cfg.RateLimit.Classes[RateClassAdmin].Burst
+	s := newTestServerWithConfig(t, cfg)
+
+	// Guessing the token is throttled by address...
+	for range burst {
+		s.request(http.MethodGet, "/admin/purges", "guess", nil, http.StatusForbidden)
+	}
+	s.request(http.MethodGet, "/admin/purges", "guess", nil, http.StatusTooManyRequests)
+
+	// ...while the admin token goes through
+	for range burst + 1 {
+		s.request(http.MethodGet, "/admin/purges", cfg.AdminToken, nil, http.StatusOK)
+	}
+}
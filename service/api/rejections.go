@@ -0,0 +1,370 @@
+/*
+Log of the rejected requests.
+
+With Config.RejectionLog.Path set, every API request answered 400, 401,
+403 or 429 is appended to that file as a line of JSON: when, the route
+(its template, without the IDs), the status, a reason code and the
+reason, the user agent, and an anonymized sample of the payload. It
+tells operators and instructors why clients fail validation without
+keeping what users wrote:
+
+  - the payload keeps the structure of the JSON body, its keys and
+    booleans, but every string becomes "string(length)" and every number
+    "number"; other bodies are only described by their type and size;
+  - the client is a pseudonym, a salted hash of the bearer token (or of
+    the address when there is none), so the rejections of one client
+    can be told apart; the salt changes at each start.
+
+The file is rotated when it grows past MaxSize: it becomes path.1,
+path.1 becomes path.2, and so on, and only MaxFiles rotated files are
+kept. The settings are read at each rejection, so a configuration
+reload moves or resizes the log.
+*/
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+)
+
+// RejectionLogConfig holds the settings of the rejection log
+type RejectionLogConfig struct {
+	Path     string // the log file, "" turns the log off
+	MaxSize  int64  // bytes before the file is rotated
+	MaxFiles int    // rotated files kept
+}
+
+// Defaults of the rejection log
+const (
+	DefaultRejectionLogMaxSize  = 10 << 20
+	DefaultRejectionLogMaxFiles = 5
+)
+
+const (
+	// maxRejectionSample is the largest request body sampled; larger
+	// ones are only described
+	maxRejectionSample = 4 << 10
+
+	// maxRejectionReason caps the reason and the user agent, in characters
+	maxRejectionReason = 200
+)
+
+// rejectedStatuses are the statuses logged, with their default reason code
+var rejectedStatuses = map[int]string{
+	http.StatusBadRequest:      "bad_request",
+	http.StatusUnauthorized:    "unauthorized",
+	http.StatusForbidden:       "forbidden",
+	http.StatusTooManyRequests: "rate_limited",
+}
+
+// rejection is a line of the rejection log
+type rejection struct {
+	Time      time.Time `json:"time"`
+	Method    string    `json:"method"`
+	Route     string    `json:"route"`
+	Status    int       `json:"status"`
+	Code      string    `json:"code"`
+	Reason    string    `json:"reason"`
+	Client    string    `json:"client"`
+	UserAgent string    `json:"userAgent,omitempty"`
+	Payload   any       `json:"payload,omitempty"`
+}
+
+// rejectionLog is the open log file, reopened when the path changes
+type rejectionLog struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+	size int64
+	salt []byte
+}
+
+func newRejectionLog() *rejectionLog {
+	salt := make([]byte, 16)
+	_, _ = rand.Read(salt)
+	return &rejectionLog{salt: salt}
+}
+
+// RejectionLogMiddleware logs the requests rejected by the handlers
+func (h *Handler) RejectionLogMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.config().RejectionLog.Path == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		sample := &bodySample{ReadCloser: r.Body}
+		r.Body = sample
+		recorder := &rejectionRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+		if _, ok := rejectedStatuses[recorder.status]; !ok {
+			return
+		}
+
+		entry := rejection{
+			Time:      time.Now(),
+			Method:    r.Method,
+			Route:     r.URL.Path,
+			Status:    recorder.status,
+			Client:    h.rejections.pseudonym(r),
+			UserAgent: truncateRunes(r.UserAgent(), maxRejectionReason),
+			Payload:   anonymizedPayload(r, sample),
+		}
+		if route := mux.CurrentRoute(r); route != nil {
+			if template, err := route.GetPathTemplate(); err == nil {
+				entry.Route = template
+			}
+		}
+		entry.Code, entry.Reason = rejectionReason(recorder)
+		if err := h.rejections.write(h.config().RejectionLog, entry); err != nil {
+			log.Printf("Error writing the rejection log: %v", err)
+		}
+	})
+}
+
+// rejectionReason reads the reason code and the reason of a rejection
+// from its body: the code and message of an ErrorResponse, or the text
+// of http.Error
+func rejectionReason(recorder *rejectionRecorder) (code, reason string) {
+	code = rejectedStatuses[recorder.status]
+	var body ErrorResponse
+	if err := json.Unmarshal(recorder.body, &body); err == nil {
+		if body.Code != "" {
+			code = body.Code
+		}
+		reason = body.Message
+	} else {
+		reason = string(bytes.TrimSpace(recorder.body))
+	}
+	return code, truncateRunes(reason, maxRejectionReason)
+}
+
+// anonymizedPayload describes the body of a request without its values
+func anonymizedPayload(r *http.Request, sample *bodySample) any {
+	if sample.read == 0 && len(sample.data) == 0 {
+		return nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/json" && mediaType != "" {
+		return mediaType + ", " + strconv.FormatInt(max(r.ContentLength, sample.read), 10) + " bytes"
+	}
+	if sample.read > maxRejectionSample {
+		return "JSON over " + strconv.Itoa(maxRejectionSample) + " bytes"
+	}
+	var value any
+	if err := json.Unmarshal(sample.data, &value); err != nil {
+		return "invalid JSON, " + strconv.FormatInt(sample.read, 10) + " bytes"
+	}
+	return anonymize(value)
+}
+
+// anonymize replaces the strings and numbers of a JSON value by their kind
+func anonymize(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			v[key] = anonymize(item)
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = anonymize(item)
+		}
+		return v
+	case string:
+		return "string(" + strconv.Itoa(utf8.RuneCountInString(v)) + ")"
+	case float64:
+		return "number"
+	default: // booleans and null
+		return v
+	}
+}
+
+// truncateRunes cuts s to at most n characters
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "…"
+}
+
+// pseudonym names the client of a request without identifying it
+func (l *rejectionLog) pseudonym(r *http.Request) string {
+	client := "token:" + getBearerToken(r)
+	if client == "token:" {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		client = "address:" + host
+	}
+	sum := sha256.Sum256(append(append([]byte{}, l.salt...), client...))
+	return hex.EncodeToString(sum[:6])
+}
+
+// write appends an entry, rotating the file first if it is full
+func (l *rejectionLog) write(cfg RejectionLogConfig, entry rejection) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if cfg.Path != l.path {
+		l.closeLocked()
+	}
+	if l.file != nil && cfg.MaxSize > 0 && l.size > 0 && l.size+int64(len(line)) > cfg.MaxSize {
+		if err := l.rotateLocked(cfg.MaxFiles); err != nil {
+			return err
+		}
+	}
+	if l.file == nil {
+		if err := l.openLocked(cfg.Path); err != nil {
+			return err
+		}
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	return err
+}
+
+func (l *rejectionLog) openLocked(path string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	l.path, l.file, l.size = path, file, info.Size()
+	return nil
+}
+
+// rotateLocked shifts the rotated files, dropping those past maxFiles,
+// and leaves the log closed to be reopened empty
+func (l *rejectionLog) rotateLocked(maxFiles int) error {
+	path := l.path
+	l.closeLocked()
+
+	// The oldest files go, including those left by a larger maxFiles
+	for i := max(maxFiles, 1); ; i++ {
+		err := os.Remove(path + "." + strconv.Itoa(i))
+		if errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for i := maxFiles - 1; i >= 1; i-- {
+		err := os.Rename(path+"."+strconv.Itoa(i), path+"."+strconv.Itoa(i+1))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if maxFiles > 0 {
+		return os.Rename(path, path+".1")
+	}
+	return os.Remove(path)
+}
+
+// close closes the log file; the next rejection reopens it
+func (l *rejectionLog) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closeLocked()
+}
+
+func (l *rejectionLog) closeLocked() {
+	if l.file == nil {
+		return
+	}
+	if err := l.file.Close(); err != nil {
+		log.Printf("Error closing the rejection log: %v", err)
+	}
+	l.path, l.file, l.size = "", nil, 0
+}
+
+// bodySample keeps the start of a request body as the handler reads it
+type bodySample struct {
+	io.ReadCloser
+	data []byte
+	read int64 // bytes read in all
+}
+
+func (b *bodySample) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if room := maxRejectionSample - len(b.data); room > 0 {
+		b.data = append(b.data, p[:min(n, room)]...)
+	}
+	b.read += int64(n)
+	return n, err
+}
+
+// rejectionRecorder records the status of a response and the start of
+// its body when it is a rejection
+type rejectionRecorder struct {
+	http.ResponseWriter
+	status int
+	body   []byte
+}
+
+func (rr *rejectionRecorder) WriteHeader(status int) {
+	if rr.status == 0 {
+		rr.status = status
+	}
+	rr.ResponseWriter.WriteHeader(status)
+}
+
+func (rr *rejectionRecorder) Write(p []byte) (int, error) {
+	if rr.status == 0 {
+		rr.status = http.StatusOK
+	}
+	if _, ok := rejectedStatuses[rr.status]; ok {
+		if room := maxRejectionSample - len(rr.body); room > 0 {
+			rr.body = append(rr.body, p[:min(len(p), room)]...)
+		}
+	}
+	return rr.ResponseWriter.Write(p)
+}
+
+// Flush and Hijack pass through, for the streamed exports and /ws
+
+func (rr *rejectionRecorder) Flush() {
+	if flusher, ok := rr.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (rr *rejectionRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := rr.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("hijacking not supported")
+	}
+	return hijacker.Hijack()
+}
+
+func (rr *rejectionRecorder) Unwrap() http.ResponseWriter {
+	return rr.ResponseWriter
+}
@@ -0,0 +1,212 @@
+/*
+Message reminder API handlers.
+
+This file contains:
+- setMessageReminder: Ask to be reminded of a message at a given time
+- deleteMessageReminder: Cancel a reminder, or dismiss a delivered one
+- getMyReminders: List the reminders of the user
+
+Reminders are private, like notes. SendMessageReminders, which the
+server runs every minute, delivers the due ones: the user gets a
+reminder event on their WebSockets, and the reminder stays in
+GET /users/me/reminders, marked delivered, until they dismiss it.
+*/
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"wasatext/service/database"
+	"wasatext/service/ids"
+)
+
+// maxReminderDelay is how far ahead a reminder can be set
+const maxReminderDelay = 365 * 24 * time.Hour
+
+// SetReminderRequest is the body for POST /messages/{id}/remind
+type SetReminderRequest struct {
+	RemindAt string `json:"remindAt"` // RFC 3339
+}
+
+// ReminderResponse is a reminder with the message it is about
+type ReminderResponse struct {
+	MessageID      ids.MessageID      `json:"messageId"`
+	ConversationID ids.ConversationID `json:"conversationId"`
+	SenderID       ids.UserID         `json:"senderId"`
+	SenderName     string             `json:"senderName"`
+	Snippet        string             `json:"snippet"`
+	HasPhoto       bool               `json:"hasPhoto"`
+	Timestamp      string             `json:"timestamp"`
+	RemindAt       string             `json:"remindAt"`
+	DeliveredAt    string             `json:"deliveredAt,omitempty"` // empty until delivered
+	CreatedAt      string             `json:"createdAt"`
+}
+
+// ReminderEvent is pushed to a user when one of their reminders is due
+type ReminderEvent struct {
+	eventHeader
+	Reminder ReminderResponse `json:"reminder"`
+}
+
+// reminderResponse converts a reminder to its response format
+func reminderResponse(rm database.MessageReminder) ReminderResponse {
+	response := ReminderResponse{
+		MessageID:      rm.Message.ID,
+		ConversationID: rm.Message.ConversationID,
+		SenderID:       rm.Message.SenderID,
+		SenderName:     rm.Message.SenderName,
+		Snippet:        rm.Message.Content,
+		HasPhoto:       rm.Message.PhotoID != "",
+		Timestamp:      rm.Message.Timestamp.Format(time.RFC3339),
+		RemindAt:       rm.RemindAt.Format(time.RFC3339),
+		CreatedAt:      rm.CreatedAt.Format(time.RFC3339),
+	}
+	if rm.DeliveredAt != nil {
+		response.DeliveredAt = rm.DeliveredAt.Format(time.RFC3339)
+	}
+	return response
+}
+
+/*
+SetMessageReminder handles POST /messages/{messageId}/remind
+operationId: setMessageReminder
+
+Sets a reminder on a message the user can read, replacing the one
+already there.
+*/
+func (h *Handler) SetMessageReminder(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Parse the message ID and the time
+	messageID, ok := pathMessageID(w, r)
+	if !ok {
+		return
+	}
+	var req SetReminderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	remindAt, err := time.Parse(time.RFC3339, req.RemindAt)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Message: "remindAt must be an RFC 3339 time"})
+		return
+	}
+	now := time.Now()
+	if !remindAt.After(now) || remindAt.After(now.Add(maxReminderDelay)) {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Message: "remindAt must be in the future, within a year"})
+		return
+	}
+
+	// Step 3: Save the reminder (this also checks the user can read the message)
+	saved, err := h.db.SetMessageReminder(r.Context(), authUserID, messageID, remindAt)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 4: Return the reminder
+	writeJSON(w, http.StatusOK, reminderResponse(*saved))
+}
+
+/*
+DeleteMessageReminder handles DELETE /messages/{messageId}/remind
+operationId: deleteMessageReminder
+
+Cancels the user's reminder on a message, or dismisses it once delivered.
+*/
+func (h *Handler) DeleteMessageReminder(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Delete the reminder
+	messageID, ok := pathMessageID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.db.DeleteMessageReminder(r.Context(), authUserID, messageID); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 3: Return success (204 No Content)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+GetMyReminders handles GET /users/me/reminders
+operationId: getMyReminders
+
+Lists the reminders of the user on the messages they can still read:
+the delivered ones first, the last delivered first, then the pending
+ones, the soonest first.
+*/
+func (h *Handler) GetMyReminders(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get the reminders
+	reminders, err := h.db.GetMessageReminders(r.Context(), authUserID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 3: Convert to response format and return
+	response := make([]ReminderResponse, 0, len(reminders))
+	for _, rm := range reminders {
+		response = append(response, reminderResponse(rm))
+	}
+	writePage(w, r, response)
+}
+
+/*
+SendMessageReminders delivers the reminders that are due: each is
+marked delivered and pushed to its user's WebSockets. The server runs
+it as a background job; the error is the first one met.
+*/
+func (h *Handler) SendMessageReminders(ctx context.Context) error {
+	now := time.Now()
+	due, err := h.db.DueMessageReminders(ctx, now)
+	if err != nil {
+		return err
+	}
+	for _, rm := range due {
+		delivered, err := h.db.MarkMessageReminderDelivered(ctx, rm.UserID, rm.Message.ID, now)
+		if err != nil {
+			return err
+		}
+		if !delivered {
+			continue
+		}
+		rm.DeliveredAt = &now
+
+		data, err := json.Marshal(ReminderEvent{
+			eventHeader: eventHeader{EventReminder, rm.Message.ConversationID},
+			Reminder:    reminderResponse(rm),
+		})
+		if err != nil {
+			log.Printf("Error encoding a reminder: %v", err)
+			continue
+		}
+		h.deliver([]ids.UserID{rm.UserID}, data)
+	}
+	return nil
+}
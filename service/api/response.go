@@ -0,0 +1,75 @@
+/*
+Response shaping.
+
+A list in a response body is always a JSON array, never null: the
+builders here start from an empty slice, so a message without reactions
+or a group without members still encodes as [].
+*/
+package api
+
+import (
+	"sort"
+	"time"
+
+	"wasatext/service/database"
+)
+
+// commentResponses converts the reactions of a message
+func commentResponses(comments []database.Comment) []CommentResponse {
+	response := make([]CommentResponse, 0, len(comments))
+	for _, c := range comments {
+		response = append(response, CommentResponse{
+			UserID:   c.UserID,
+			UserName: c.UserName,
+			Emoticon: c.Emoticon,
+		})
+	}
+	return response
+}
+
+// mentionResponses converts the mentions of a message
+func mentionResponses(mentions []database.Mention) []MentionResponse {
+	response := make([]MentionResponse, 0, len(mentions))
+	for _, m := range mentions {
+		response = append(response, MentionResponse{UserID: m.UserID, UserName: m.UserName})
+	}
+	return response
+}
+
+// reactionCounts counts the reactions of a message by emoticon, the most
+// used first, then the first used
+func reactionCounts(comments []CommentResponse) []ReactionCount {
+	counts := []ReactionCount{}
+	index := make(map[string]int)
+	for _, c := range comments {
+		i, ok := index[c.Emoticon]
+		if !ok {
+			i = len(counts)
+			index[c.Emoticon] = i
+			counts = append(counts, ReactionCount{Emoticon: c.Emoticon})
+		}
+		counts[i].Count++
+	}
+	sort.SliceStable(counts, func(i, j int) bool { return counts[i].Count > counts[j].Count })
+	return counts
+}
+
+// memberResponses converts the members of a conversation or group
+func (h *Handler) memberResponses(members []database.User) []UserResponse {
+	response := make([]UserResponse, 0, len(members))
+	now := time.Now()
+	for _, m := range members {
+		presence := h.userPresence(m, now)
+		response = append(response, UserResponse{
+			Identifier:  m.ID,
+			Name:        m.Name,
+			About:       m.About,
+			HasPhoto:    m.PhotoID != "",
+			PhotoURL:    h.photoURL(mediaUser, string(m.ID), m.PhotoID),
+			Deactivated: m.Deactivated,
+			Online:      presence.Online,
+			LastSeen:    presence.LastSeen,
+		})
+	}
+	return response
+}
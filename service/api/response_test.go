@@ -0,0 +1,60 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// assertArrays fails the test unless each field of a JSON object is an
+// array, possibly empty, and not null
+func assertArrays(t *testing.T, what string, object json.RawMessage, fields ...string) {
+	t.Helper()
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(object, &values); err != nil {
+		t.Fatalf("%s: decoding %s: %v", what, object, err)
+	}
+	for _, field := range fields {
+		value, ok := values[field]
+		if !ok || len(value) == 0 || value[0] != '[' {
+			t.Errorf("%s: %s is %s, want an array", what, field, value)
+		}
+	}
+}
+
+func TestEmptyListsAreArrays(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.login("alice")
+
+	for _, path := range []string{
+		"/users?search=nobody",
+		"/conversations",
+	} {
+		assertArrays(t, path, s.request(http.MethodGet, path, alice.Token, nil, http.StatusOK), "items")
+	}
+}
+
+func TestMessageWithoutCommentsHasEmptyArrays(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.login("alice")
+	bob := s.login("bobby")
+
+	var started struct {
+		ConversationID string `json:"conversationId"`
+	}
+	s.decode(s.request(http.MethodPost, "/conversations", alice.Token, StartConversationRequest{UserID: string(bob.Identifier)}, http.StatusCreated), &started)
+	path := "/conversations/" + started.ConversationID
+	assertArrays(t, "empty conversation", s.request(http.MethodGet, path, alice.Token, nil, http.StatusOK), "messages")
+
+	sent := s.request(http.MethodPost, path+"/messages", alice.Token, SendMessageRequest{Content: "hello"}, http.StatusCreated)
+	assertArrays(t, "sent message", sent, "comments", "reactionCounts", "mentions")
+
+	var conversation struct {
+		Messages []json.RawMessage `json:"messages"`
+	}
+	s.decode(s.request(http.MethodGet, path, bob.Token, nil, http.StatusOK), &conversation)
+	if len(conversation.Messages) != 1 {
+		t.Fatalf("got %d messages, want 1", len(conversation.Messages))
+	}
+	assertArrays(t, "listed message", conversation.Messages[0], "comments", "reactionCounts", "mentions")
+}
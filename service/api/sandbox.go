@@ -0,0 +1,262 @@
+/*
+Developer sandbox.
+
+With Config.Sandbox.Enabled the server helps frontend developers test
+their loading and failure states:
+
+  - SandboxMiddleware delays every API request by Latency (plus up to
+    LatencyJitter) and fails a share ErrorRate of them with 503. A single
+    request can ask for its own delay and failure with the X-Sandbox-Delay
+    ("2s") and X-Sandbox-Status ("500") headers.
+  - POST /sandbox/reset wipes the database and seeds the fixtures of
+    sandboxFixtures, returning a session token for each fixture user.
+
+Never enable the sandbox on a server holding real data: anyone can reset
+it. When it is off, the middleware does nothing and /sandbox/reset
+answers 404.
+*/
+package api
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"wasatext/service/database"
+	"wasatext/service/ids"
+
+	"github.com/gorilla/mux"
+)
+
+// SandboxConfig holds the developer sandbox settings
+type SandboxConfig struct {
+	Enabled bool
+	// Every API request waits Latency plus a random part of LatencyJitter
+	Latency       time.Duration
+	LatencyJitter time.Duration
+	// ErrorRate is the share of API requests failed with 503, from 0 to 1
+	ErrorRate float64
+}
+
+// Headers overriding the sandbox settings for one request
+const (
+	headerSandboxDelay  = "X-Sandbox-Delay"
+	headerSandboxStatus = "X-Sandbox-Status"
+)
+
+// maxSandboxDelay caps the delay a request can ask for
+const maxSandboxDelay = time.Minute
+
+// codeSandboxFault is the code of the failures injected by the sandbox
+const codeSandboxFault = "sandbox_fault"
+
+// sandboxUser is a fixture user
+type sandboxUser struct {
+	name string
+	key  string // how the messages below refer to the user
+}
+
+// sandboxMessage is a fixture message, sent by the user with that key
+type sandboxMessage struct {
+	from    string
+	content string
+}
+
+// sandboxFixtures is the data POST /sandbox/reset seeds: three users, a
+// direct conversation and a group, each with a short history
+var sandboxFixtures = struct {
+	users  []sandboxUser
+	direct []sandboxMessage // between the first two users
+	group  string
+	chat   []sandboxMessage // in the group of all users
+}{
+	users: []sandboxUser{{"alice", "a"}, {"bob", "b"}, {"carol", "c"}},
+	direct: []sandboxMessage{
+		{"a", "Hi Bob, did you see the new designs?"},
+		{"b", "Not yet, sending them over?"},
+		{"a", "They are in the team group"},
+	},
+	group: "Design team",
+	chat: []sandboxMessage{
+		{"c", "Welcome to the design team!"},
+		{"a", "Here are the new mockups"},
+		{"b", "Looks great 👍"},
+	},
+}
+
+// SandboxUserResponse is a fixture user, ready to use
+type SandboxUserResponse struct {
+	Identifier ids.UserID `json:"identifier"`
+	Name       string     `json:"name"`
+	Token      string     `json:"token"`
+}
+
+// SandboxResetResponse is the response of POST /sandbox/reset
+type SandboxResetResponse struct {
+	Users []SandboxUserResponse `json:"users"`
+}
+
+/*
+SandboxMiddleware injects the latency and the failures of the sandbox
+into the API requests. The sandbox endpoints themselves and the web UI
+(served on "/" by cmd/webapi) are left alone.
+*/
+func (h *Handler) SandboxMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sandbox := h.config().Sandbox
+		if !sandbox.Enabled || !sandboxApplies(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Step 1: Wait, unless the client gives up first
+		delay := sandbox.Latency
+		if sandbox.LatencyJitter > 0 {
+			delay += rand.N(sandbox.LatencyJitter)
+		}
+		if value := r.Header.Get(headerSandboxDelay); value != "" {
+			d, err := time.ParseDuration(value)
+			if err != nil || d < 0 || d > maxSandboxDelay {
+				writeJSON(w, http.StatusBadRequest, ErrorResponse{Message: headerSandboxDelay + " must be a duration between 0s and 1m"})
+				return
+			}
+			delay = d
+		}
+		if delay > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-r.Context().Done():
+				timer.Stop()
+				return
+			}
+		}
+
+		// Step 2: Fail if asked to, or by chance
+		status := 0
+		if sandbox.ErrorRate > 0 && rand.Float64() < sandbox.ErrorRate {
+			status = http.StatusServiceUnavailable
+		}
+		if value := r.Header.Get(headerSandboxStatus); value != "" {
+			s, err := strconv.Atoi(value)
+			if err != nil || s < 400 || s > 599 {
+				writeJSON(w, http.StatusBadRequest, ErrorResponse{Message: headerSandboxStatus + " must be an error status between 400 and 599"})
+				return
+			}
+			status = s
+		}
+		if status != 0 {
+			writeJSON(w, status, ErrorResponse{Message: "Failure injected by the sandbox", Code: codeSandboxFault})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sandboxApplies reports whether SandboxMiddleware acts on a request
+func sandboxApplies(r *http.Request) bool {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return true
+	}
+	template, _ := route.GetPathTemplate()
+	return template != "/" && !strings.HasPrefix(template, "/sandbox/")
+}
+
+/*
+ResetSandbox handles POST /sandbox/reset
+operationId: resetSandbox
+
+Wipes the database and seeds the fixtures. Every session ends, so the
+response carries a fresh token for each fixture user. Only available
+with the sandbox enabled.
+*/
+func (h *Handler) ResetSandbox(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check the sandbox is enabled
+	if !h.config().Sandbox.Enabled {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	// Step 2: Wipe the database and the presence
+	if err := h.db.ResetData(r.Context()); err != nil {
+		writeError(w, err)
+		return
+	}
+	h.presence.clear()
+
+	// Step 3: Seed the fixtures
+	users, err := seedSandbox(r.Context(), h.db)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	h.infof("Sandbox reset to the fixtures")
+	writeJSON(w, http.StatusOK, SandboxResetResponse{Users: users})
+}
+
+// seedSandbox creates sandboxFixtures in an empty database
+func seedSandbox(ctx context.Context, db database.AppDatabase) ([]SandboxUserResponse, error) {
+	fx := sandboxFixtures
+	users := make([]SandboxUserResponse, 0, len(fx.users))
+	userIDs := make(map[string]ids.UserID, len(fx.users))
+	for _, u := range fx.users {
+		id, err := db.CreateUser(ctx, database.DefaultWorkspaceID, u.name)
+		if err != nil {
+			return nil, err
+		}
+		token, err := db.CreateSession(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		userIDs[u.key] = id
+		users = append(users, SandboxUserResponse{Identifier: id, Name: u.name, Token: token})
+	}
+
+	direct, err := db.GetOrCreateDirectConversation(ctx, userIDs[fx.users[0].key], userIDs[fx.users[1].key])
+	if err != nil {
+		return nil, err
+	}
+	if err := seedSandboxMessages(ctx, db, direct, userIDs, fx.direct); err != nil {
+		return nil, err
+	}
+
+	var members []ids.UserID
+	for _, u := range fx.users[1:] {
+		members = append(members, userIDs[u.key])
+	}
+	creator := userIDs[fx.users[0].key]
+	group, err := db.CreateGroup(ctx, fx.group, creator, members)
+	if err != nil {
+		return nil, err
+	}
+	// The conversation of the group is the one listed with the group as photo owner
+	conversations, err := db.GetConversations(ctx, creator)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range conversations {
+		if c.IsGroup && c.PhotoOwnerID == string(group.ID) {
+			if err := seedSandboxMessages(ctx, db, c.ID, userIDs, fx.chat); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return users, nil
+}
+
+// seedSandboxMessages sends fixture messages, oldest first
+func seedSandboxMessages(ctx context.Context, db database.AppDatabase, conversationID ids.ConversationID, userIDs map[string]ids.UserID, messages []sandboxMessage) error {
+	for _, m := range messages {
+		if _, err := db.CreateMessage(ctx, conversationID, userIDs[m.from], m.content, nil, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+/*
+Scheduled message API handlers.
+
+This file contains:
+- getMyScheduledMessages: List the messages the user scheduled
+- cancelScheduledMessage: Drop a scheduled message before it is sent
+
+A text message posted with a sendAt in the future is scheduled instead
+of sent (see SendMessage): it waits in a queue until sendAt, then the
+scheduler sends it as its sender, and the participants get it like any
+other message. The scheduler sleeps until the next message is due,
+woken up when one is scheduled; it reads the time from the clock of
+the Handler (see NewWithClock).
+
+The limits of the sender are applied when the message is scheduled;
+whether they can still post there is checked again when it is sent. A
+message that cannot be sent anymore is dropped.
+*/
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"wasatext/service/database"
+	"wasatext/service/events"
+	"wasatext/service/globaltime"
+	"wasatext/service/ids"
+
+	"github.com/gorilla/mux"
+)
+
+// maxScheduleDelay is how far ahead a message can be scheduled
+const maxScheduleDelay = 365 * 24 * time.Hour
+
+// schedulerSweepInterval is the longest the scheduler sleeps, so that
+// the messages of a changed clock or database are not missed for long
+const schedulerSweepInterval = time.Minute
+
+// ScheduledMessageResponse is a message waiting to be sent
+type ScheduledMessageResponse struct {
+	ScheduledMessageID int64              `json:"scheduledMessageId"`
+	ConversationID     ids.ConversationID `json:"conversationId"`
+	Content            string             `json:"content"`
+	ReplyTo            ids.MessageID      `json:"replyTo,omitempty"`
+	SendAt             string             `json:"sendAt"`
+	CreatedAt          string             `json:"createdAt"`
+}
+
+// scheduledMessageResponse converts a scheduled message to its response format
+func scheduledMessageResponse(sm database.ScheduledMessage) ScheduledMessageResponse {
+	response := ScheduledMessageResponse{
+		ScheduledMessageID: sm.ID,
+		ConversationID:     sm.ConversationID,
+		Content:            sm.Content,
+		SendAt:             sm.SendAt.Format(time.RFC3339),
+		CreatedAt:          sm.CreatedAt.Format(time.RFC3339),
+	}
+	if sm.ReplyTo != nil {
+		response.ReplyTo = *sm.ReplyTo
+	}
+	return response
+}
+
+/*
+GetMyScheduledMessages handles GET /users/me/scheduled-messages
+operationId: getMyScheduledMessages
+
+Lists the messages the user scheduled and that were not sent yet, the
+next to be sent first.
+*/
+func (h *Handler) GetMyScheduledMessages(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get the scheduled messages
+	scheduled, err := h.db.GetScheduledMessages(r.Context(), authUserID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 3: Convert to response format and return
+	response := make([]ScheduledMessageResponse, 0, len(scheduled))
+	for _, sm := range scheduled {
+		response = append(response, scheduledMessageResponse(sm))
+	}
+	writePage(w, r, response)
+}
+
+/*
+CancelScheduledMessage handles DELETE /users/me/scheduled-messages/{scheduledMessageId}
+operationId: cancelScheduledMessage
+
+Drops a message the user scheduled, before it is sent.
+*/
+func (h *Handler) CancelScheduledMessage(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Parse the scheduled message ID
+	scheduledID, err := strconv.ParseInt(mux.Vars(r)["scheduledMessageId"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid scheduled message ID", http.StatusBadRequest)
+		return
+	}
+
+	// Step 3: Drop it
+	if err := h.db.CancelScheduledMessage(r.Context(), authUserID, scheduledID); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 4: Return success (204 No Content)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseSendAt checks the sendAt of a message: nil when the message is to
+// be sent now (no sendAt, or one already past), false after answering
+// the request when it is invalid
+func (h *Handler) parseSendAt(w http.ResponseWriter, value string) (*time.Time, bool) {
+	if value == "" {
+		return nil, true
+	}
+	sendAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Message: "sendAt must be an RFC 3339 time"})
+		return nil, false
+	}
+	now := h.clock.Now()
+	if !sendAt.After(now) {
+		return nil, true
+	}
+	if sendAt.After(now.Add(maxScheduleDelay)) {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Message: "sendAt must be within a year"})
+		return nil, false
+	}
+	return &sendAt, true
+}
+
+// sendScheduledMessage sends a message taken from the queue, as SendMessage
+// would have: the sender must still be in the conversation
+func (h *Handler) sendScheduledMessage(ctx context.Context, sm database.ScheduledMessage) error {
+	if _, err := h.db.GetConversation(ctx, sm.SenderID, sm.ConversationID); err != nil {
+		return err
+	}
+	msg, err := h.db.CreateMessage(ctx, sm.ConversationID, sm.SenderID, sm.Content, nil, sm.ReplyTo)
+	if err != nil {
+		return err
+	}
+	h.fanout.enqueue(msg)
+	h.flagFilteredMessage(ctx, msg, sm.ConversationID)
+	h.bus.Publish(ctx, events.MessageCreated{Message: *msg})
+	return nil
+}
+
+// messageScheduler sends the scheduled messages when they are due
+type messageScheduler struct {
+	db      database.AppDatabase
+	clock   globaltime.Time
+	deliver func(ctx context.Context, sm database.ScheduledMessage) error
+	wake    chan struct{}
+}
+
+// newMessageScheduler returns the scheduler; New starts it
+func newMessageScheduler(db database.AppDatabase, clock globaltime.Time, deliver func(ctx context.Context, sm database.ScheduledMessage) error) *messageScheduler {
+	return &messageScheduler{db: db, clock: clock, deliver: deliver, wake: make(chan struct{}, 1)}
+}
+
+// poke tells the scheduler a message was scheduled, so that it sleeps
+// until the right time
+func (ms *messageScheduler) poke() {
+	select {
+	case ms.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run sends the due messages until ctx is cancelled, after completing
+// the sweep under way
+func (ms *messageScheduler) run(ctx context.Context) {
+	work := context.WithoutCancel(ctx)
+	for {
+		timer := time.NewTimer(ms.sweep(work))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-ms.wake:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// sweep sends every due message and returns how long to sleep until the
+// next one. A message is taken from the queue before it is sent, so it
+// is sent at most once.
+func (ms *messageScheduler) sweep(ctx context.Context) time.Duration {
+	due, err := ms.db.DueScheduledMessages(ctx, ms.clock.Now())
+	if err != nil {
+		log.Printf("Error listing the scheduled messages: %v", err)
+		return schedulerSweepInterval
+	}
+	for _, sm := range due {
+		taken, err := ms.db.TakeScheduledMessage(ctx, sm.ID)
+		if err != nil {
+			log.Printf("Error taking scheduled message %d: %v", sm.ID, err)
+			continue
+		}
+		if !taken {
+			continue
+		}
+		if err := ms.deliver(ctx, sm); err != nil {
+			log.Printf("Scheduled message %d of %s dropped: %v", sm.ID, sm.SenderID, err)
+		}
+	}
+
+	next, err := ms.db.NextScheduledMessage(ctx)
+	if err != nil {
+		log.Printf("Error looking up the next scheduled message: %v", err)
+		return schedulerSweepInterval
+	}
+	if next == nil {
+		return schedulerSweepInterval
+	}
+	return max(min(next.Sub(ms.clock.Now()), schedulerSweepInterval), 0)
+}
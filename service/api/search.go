@@ -0,0 +1,166 @@
+/*
+Message search API handlers.
+
+This file contains:
+- searchMessages: Search the messages of all your conversations
+- searchConversationMessages: Search the messages of one conversation
+
+Both take ?q= (every word must appear, the last one may be the start of
+a word) and return the newest matches first, page by page with ?before=
+like GET /conversations/{conversationId}. ?lang= keeps the messages
+written in one language, as detected when they were sent.
+*/
+package api
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"wasatext/service/database"
+	"wasatext/service/ids"
+	"wasatext/service/langdetect"
+)
+
+const (
+	defaultSearchResults = 20
+	maxSearchResults     = 50
+	maxSearchQueryLength = 200
+)
+
+// SearchResultResponse is a message matching a search, with its conversation
+type SearchResultResponse struct {
+	ConversationID   ids.ConversationID `json:"conversationId"`
+	ConversationName string             `json:"conversationName"`
+	IsGroup          bool               `json:"isGroup"`
+	MessageID        ids.MessageID      `json:"messageId"`
+	SenderID         ids.UserID         `json:"senderId"`
+	SenderName       string             `json:"senderName"`
+	Content          string             `json:"content"`
+	Language         string             `json:"language,omitempty"`
+	HasPhoto         bool               `json:"hasPhoto"`
+	PhotoURL         string             `json:"photoUrl,omitempty"`
+	Timestamp        string             `json:"timestamp"`
+	System           bool               `json:"system,omitempty"`
+	Edited           bool               `json:"edited"`
+	ViaHook          bool               `json:"viaHook,omitempty"`
+}
+
+// SearchResponse is the response of the message searches
+type SearchResponse struct {
+	Results    []SearchResultResponse `json:"results"`
+	HasMore    bool                   `json:"hasMore"`
+	NextBefore ids.MessageID          `json:"nextBefore,omitempty"` // "before" value for the next page
+}
+
+/*
+SearchMessages handles GET /messages/search
+operationId: searchMessages
+
+Searches the messages of every conversation the user takes part in.
+*/
+func (h *Handler) SearchMessages(w http.ResponseWriter, r *http.Request) {
+	h.searchMessages(w, r, "")
+}
+
+/*
+SearchConversationMessages handles GET /conversations/{conversationId}/messages/search
+operationId: searchConversationMessages
+
+Searches the messages of one conversation of the user.
+*/
+func (h *Handler) SearchConversationMessages(w http.ResponseWriter, r *http.Request) {
+	conversationID, ok := pathConversationID(w, r)
+	if !ok {
+		return
+	}
+	h.searchMessages(w, r, conversationID)
+}
+
+// searchMessages runs a search, in one conversation when conversationID is not empty
+func (h *Handler) searchMessages(w http.ResponseWriter, r *http.Request, conversationID ids.ConversationID) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Parse the query and the page
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" || utf8.RuneCountInString(query) > maxSearchQueryLength {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Message: "q must be between 1 and 200 characters"})
+		return
+	}
+	limit := defaultSearchResults
+	if value := r.URL.Query().Get("limit"); value != "" {
+		requested, err := strconv.Atoi(value)
+		if err != nil || requested < 1 || requested > maxSearchResults {
+			http.Error(w, "Invalid limit (1 to "+strconv.Itoa(maxSearchResults)+")", http.StatusBadRequest)
+			return
+		}
+		limit = requested
+	}
+	var before ids.MessageID
+	if value := r.URL.Query().Get("before"); value != "" {
+		var err error
+		if before, err = ids.ParseMessageID(value); err != nil {
+			http.Error(w, "Invalid before: not a message ID", http.StatusBadRequest)
+			return
+		}
+	}
+	language := r.URL.Query().Get("lang")
+	if language != "" && !slices.Contains(langdetect.Languages(), language) {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Message: "lang must be one of " + strings.Join(langdetect.Languages(), ", ")})
+		return
+	}
+
+	// Step 3: Search, asking for one extra result to know whether there are more
+	results, err := h.db.SearchMessages(r.Context(), authUserID, conversationID, query, language, before, limit+1)
+	if errors.Is(err, database.ErrMessageNotFound) {
+		http.Error(w, "Invalid before: message not found", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 4: Convert to response format
+	response := SearchResponse{Results: []SearchResultResponse{}}
+	if len(results) > limit {
+		results = results[:limit]
+		response.HasMore = true
+		response.NextBefore = results[limit-1].Message.ID
+	}
+	for _, result := range results {
+		response.Results = append(response.Results, h.searchResultResponse(result))
+	}
+
+	// Step 5: Return the results
+	writeJSON(w, http.StatusOK, response)
+}
+
+// searchResultResponse converts a message found, with its conversation
+func (h *Handler) searchResultResponse(result database.SearchResult) SearchResultResponse {
+	msg := result.Message
+	return SearchResultResponse{
+		ConversationID:   msg.ConversationID,
+		ConversationName: result.ConversationName,
+		IsGroup:          result.IsGroup,
+		MessageID:        msg.ID,
+		SenderID:         msg.SenderID,
+		SenderName:       msg.SenderName,
+		Content:          msg.Content,
+		Language:         msg.Language,
+		HasPhoto:         msg.PhotoID != "",
+		PhotoURL:         h.photoURL(mediaMessage, string(msg.ID), msg.PhotoID),
+		Timestamp:        msg.Timestamp.Format("2006-01-02T15:04:05Z07:00"),
+		System:           msg.System,
+		Edited:           msg.EditedAt != nil,
+		ViaHook:          msg.ViaHook,
+	}
+}
@@ -0,0 +1,155 @@
+/*
+Anti-spam heuristics.
+
+Three rules protect the messaging endpoints:
+- New accounts may only start a limited number of conversations per hour.
+- Sending the same text to many conversations in a short time is a flood.
+- A flood automatically throttles the sender for a while.
+
+Every detection is stored as a spam event with a score, so admins can
+see who is misbehaving (GET /admin/spam).
+*/
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"wasatext/service/database"
+	"wasatext/service/ids"
+)
+
+// SpamConfig holds the thresholds of the anti-spam heuristics
+type SpamConfig struct {
+	// Accounts younger than this count as new
+	NewAccountAge time.Duration
+	// How many conversations a new account may start per hour
+	MaxNewConversationsPerHour int
+	// Sending the same text to FloodThreshold conversations
+	// within FloodWindow is a flood
+	FloodWindow    time.Duration
+	FloodThreshold int
+	// How long a flooding user is blocked from sending
+	ThrottleDuration time.Duration
+}
+
+// DefaultSpamConfig returns the thresholds used when none are configured
+func DefaultSpamConfig() SpamConfig {
+	return SpamConfig{
+		NewAccountAge:              24 * time.Hour,
+		MaxNewConversationsPerHour: 10,
+		FloodWindow:                10 * time.Minute,
+		FloodThreshold:             5,
+		ThrottleDuration:           15 * time.Minute,
+	}
+}
+
+// Spam event kinds and their scores
+const (
+	spamKindConversationBurst = "conversation_burst"
+	spamKindMessageFlood      = "message_flood"
+
+	spamScoreConversationBurst = 5
+	spamScoreMessageFlood      = 10
+)
+
+// checkThrottle rejects the request if the user is currently throttled.
+// It returns false when a response has already been written.
+func (h *Handler) checkThrottle(ctx context.Context, w http.ResponseWriter, userID ids.UserID) bool {
+	throttle, err := h.db.GetThrottle(ctx, userID)
+	if err != nil {
+		writeError(w, err)
+		return false
+	}
+	if throttle == nil {
+		return true
+	}
+	if throttle.Reason == database.ThrottleReasonBanned {
+		writeJSON(w, http.StatusForbidden, ErrorResponse{Message: "This account has been banned"})
+		return false
+	}
+
+	writeTooManyRequests(w, throttle.Until, "You are temporarily blocked from sending: "+throttle.Reason)
+	return false
+}
+
+// checkNewConversationLimit enforces the hourly conversation limit for new accounts.
+// It returns false when a response has already been written.
+func (h *Handler) checkNewConversationLimit(ctx context.Context, w http.ResponseWriter, userID ids.UserID) bool {
+	if !h.checkThrottle(ctx, w, userID) {
+		return false
+	}
+
+	cfg := h.config().Spam
+	user, err := h.db.GetUserByID(ctx, userID)
+	if err != nil {
+		writeError(w, err)
+		return false
+	}
+
+	// Older accounts (and those from before creation times were tracked) are trusted
+	if user.CreatedAt.IsZero() || time.Since(user.CreatedAt) >= cfg.NewAccountAge {
+		return true
+	}
+
+	count, err := h.db.CountNewConversations(ctx, userID, time.Now().Add(-time.Hour))
+	if err != nil {
+		writeError(w, err)
+		return false
+	}
+	if count < cfg.MaxNewConversationsPerHour {
+		return true
+	}
+
+	h.recordSpam(ctx, userID, spamKindConversationBurst, strconv.Itoa(count)+" conversations in the last hour", spamScoreConversationBurst)
+	writeTooManyRequests(w, time.Now().Add(time.Hour), "New accounts can only start a limited number of conversations per hour")
+	return false
+}
+
+// checkMessageFlood detects the same text being sent to many conversations.
+// A flood throttles the sender. It returns false when a response has
+// already been written.
+func (h *Handler) checkMessageFlood(ctx context.Context, w http.ResponseWriter, userID ids.UserID, conversationID ids.ConversationID, content string) bool {
+	if content == "" {
+		return true
+	}
+
+	cfg := h.config().Spam
+	count, err := h.db.CountDuplicateMessages(ctx, userID, content, conversationID, time.Now().Add(-cfg.FloodWindow))
+	if err != nil {
+		writeError(w, err)
+		return false
+	}
+
+	// Including the conversation this message goes to
+	if count+1 < cfg.FloodThreshold {
+		return true
+	}
+
+	until := time.Now().Add(cfg.ThrottleDuration)
+	h.recordSpam(ctx, userID, spamKindMessageFlood, "identical message sent to "+strconv.Itoa(count+1)+" conversations", spamScoreMessageFlood)
+	if err := h.db.ThrottleUser(ctx, userID, until, "message flood"); err != nil {
+		log.Printf("Error throttling user %s: %v", userID, err)
+	}
+
+	writeTooManyRequests(w, until, "Too many identical messages, you are temporarily blocked from sending")
+	return false
+}
+
+// recordSpam stores a spam event; failures are only logged
+// because they must not change the response
+func (h *Handler) recordSpam(ctx context.Context, userID ids.UserID, kind, detail string, score int) {
+	if err := h.db.RecordSpamEvent(ctx, userID, kind, detail, score); err != nil {
+		log.Printf("Error recording spam event for %s: %v", userID, err)
+	}
+}
+
+// writeTooManyRequests writes a 429 response with a Retry-After header
+func writeTooManyRequests(w http.ResponseWriter, until time.Time, message string) {
+	seconds := int(time.Until(until).Seconds()) + 1
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	writeJSON(w, http.StatusTooManyRequests, ErrorResponse{Message: message})
+}
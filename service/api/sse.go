@@ -0,0 +1,144 @@
+/*
+Real-time events over Server-Sent Events.
+
+GET /events is the fallback of GET /ws for the clients that cannot keep
+a WebSocket open, e.g. behind a proxy that does not forward upgrades:
+the same events, from the same hub, as a text/event-stream that an
+EventSource reads. It only goes one way; clients send typing indicators
+and acks through the WebSocket, or not at all.
+
+Every event has an id, and an EventSource that reconnects sends the
+last one it got as Last-Event-ID: the stream then starts with the
+events of the user it missed. The hub keeps the last eventReplaySize
+events for eventReplayWindow after a user's last client went away; when
+the ID is older than that, or from before a restart, the stream starts
+with a resync event instead, and the client fetches GET /conversations
+again. A new stream opens with the ID of the last event sent, without
+data, so that a client resumes from there even if no event came.
+
+This file contains:
+- EventStream: The events of the user's conversations, as SSE
+*/
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// sseRetry is how long an EventSource waits before reconnecting
+const sseRetry = 3 * time.Second
+
+// errClientClosed ends a stream the hub closed
+var errClientClosed = errors.New("closed by the server")
+
+// resyncEvent tells a resuming client that it missed events
+var resyncEvent = []byte(`{"type":"` + EventResync + `"}`)
+
+/*
+EventStream handles GET /events
+operationId: eventStream
+
+Streams the events of the user's conversations as Server-Sent Events
+until the client goes away, starting with those it missed since
+Last-Event-ID.
+*/
+func (h *Handler) EventStream(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication (header, or ?token= for EventSource)
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		authUserID = h.sessionUser(r.Context(), r.URL.Query().Get("token"))
+	}
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := h.db.GetUserByID(r.Context(), authUserID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 2: Register the client, with the events it missed. An
+	// EventSource cannot set Last-Event-ID on its first connection, so
+	// it may also be given as ?lastEventId=.
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("lastEventId")
+	}
+	client := &wsClient{
+		userID:   user.ID,
+		userName: user.Name,
+		send:     make(chan hubEvent, wsSendBuffer),
+		done:     make(chan struct{}),
+	}
+	missed, lastID, resumed := h.hub.addResuming(client, lastEventID)
+	defer h.hub.remove(client)
+	defer client.close()
+	h.presence.touch(user.ID, time.Now())
+	h.debugf("Event stream opened for %s", user.ID)
+
+	// Step 3: Start the stream
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // nginx would buffer the stream
+	w.WriteHeader(http.StatusOK)
+
+	// A client that resumes keeps its ID until the missed events
+	// replace it: were the stream cut during the replay, it would
+	// otherwise skip the rest
+	opening := fmt.Sprintf("retry: %d\n", sseRetry.Milliseconds())
+	if lastEventID == "" || !resumed {
+		opening += "id: " + lastID + "\n"
+	}
+	_ = rc.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	_, err = fmt.Fprint(w, opening+": connected\n\n")
+	if err == nil && !resumed {
+		_, err = fmt.Fprintf(w, "data: %s\n\n", resyncEvent)
+	}
+	for _, event := range missed {
+		if err != nil {
+			break
+		}
+		err = writeSSE(w, event)
+	}
+	if err == nil {
+		err = rc.Flush()
+	}
+
+	// Step 4: Send the events and the pings until the client goes away
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+	for err == nil {
+		select {
+		case <-r.Context().Done():
+			err = r.Context().Err()
+		case <-client.done:
+			err = errClientClosed
+		case event := <-client.send:
+			_ = rc.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			err = writeSSE(w, event)
+		case <-ticker.C:
+			// A comment, which the client ignores, keeps proxies from
+			// closing the stream as idle
+			_ = rc.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			_, err = fmt.Fprint(w, ": ping\n\n")
+			if err == nil {
+				h.presence.touch(user.ID, time.Now())
+			}
+		}
+		if err == nil {
+			err = rc.Flush()
+		}
+	}
+	h.debugf("Event stream closed for %s", user.ID)
+}
+
+// writeSSE writes an event with its ID; its JSON holds no newline
+func writeSSE(w http.ResponseWriter, event hubEvent) error {
+	_, err := fmt.Fprintf(w, "id: %s\ndata: %s\n\n", event.id, event.data)
+	return err
+}
@@ -0,0 +1,136 @@
+/*
+Conversation statistics API handlers.
+
+This file contains:
+- getReactionStats: Rank the emoticons, reactors and messages of a conversation
+
+The statistics are computed on request by aggregate queries, for group
+"wrapped"-style summaries over a date range.
+*/
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"wasatext/service/ids"
+)
+
+const (
+	defaultStatsEntries = 5
+	maxStatsEntries     = 50
+)
+
+// EmoticonCountResponse is an emoticon and how many times it was used
+type EmoticonCountResponse struct {
+	Emoticon string `json:"emoticon"`
+	Count    int    `json:"count"`
+}
+
+// ReactorCountResponse is a user and how many reactions they gave
+type ReactorCountResponse struct {
+	UserID   ids.UserID `json:"userId"`
+	UserName string     `json:"userName"`
+	Count    int        `json:"count"`
+}
+
+// ReactedMessageResponse is a message and how many reactions it got
+type ReactedMessageResponse struct {
+	MessageID  ids.MessageID `json:"messageId"`
+	SenderID   ids.UserID    `json:"senderId"`
+	SenderName string        `json:"senderName"`
+	Snippet    string        `json:"snippet"`
+	HasPhoto   bool          `json:"hasPhoto"`
+	Timestamp  string        `json:"timestamp"`
+	Reactions  int           `json:"reactions"`
+}
+
+// ReactionStatsResponse is the body of GET /conversations/{id}/stats/reactions
+type ReactionStatsResponse struct {
+	From      string                   `json:"from,omitempty"`
+	To        string                   `json:"to,omitempty"`
+	Total     int                      `json:"total"`
+	Emoticons []EmoticonCountResponse  `json:"emoticons"`
+	Reactors  []ReactorCountResponse   `json:"reactors"`
+	Messages  []ReactedMessageResponse `json:"messages"`
+}
+
+/*
+GetReactionStats handles GET /conversations/{conversationId}/stats/reactions
+operationId: getReactionStats
+
+Ranks the most used emoticons, the users reacting the most and the most
+reacted messages. "from" and "to" (a date or an RFC 3339 time, as in
+the admin exports) select the messages sent in that range; "limit" is
+the length of each ranking.
+*/
+func (h *Handler) GetReactionStats(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Parse the conversation, the range and the limit
+	conversationID, ok := pathConversationID(w, r)
+	if !ok {
+		return
+	}
+	from, to, err := parseReportRange(r)
+	if err != nil || (!from.IsZero() && !to.IsZero() && !from.Before(to)) {
+		http.Error(w, "Invalid date range", http.StatusBadRequest)
+		return
+	}
+	limit := defaultStatsEntries
+	if value := r.URL.Query().Get("limit"); value != "" {
+		requested, err := strconv.Atoi(value)
+		if err != nil || requested < 1 || requested > maxStatsEntries {
+			http.Error(w, "Invalid limit (1 to "+strconv.Itoa(maxStatsEntries)+")", http.StatusBadRequest)
+			return
+		}
+		limit = requested
+	}
+
+	// Step 3: Compute the statistics (this also checks the user is a participant)
+	stats, err := h.db.GetReactionStats(r.Context(), authUserID, conversationID, from, to, limit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 4: Convert to response format
+	response := ReactionStatsResponse{
+		Total:     stats.Total,
+		Emoticons: make([]EmoticonCountResponse, 0, len(stats.Emoticons)),
+		Reactors:  make([]ReactorCountResponse, 0, len(stats.Reactors)),
+		Messages:  make([]ReactedMessageResponse, 0, len(stats.Messages)),
+	}
+	if !from.IsZero() {
+		response.From = from.Format(time.RFC3339)
+	}
+	if !to.IsZero() {
+		response.To = to.Format(time.RFC3339)
+	}
+	for _, e := range stats.Emoticons {
+		response.Emoticons = append(response.Emoticons, EmoticonCountResponse{Emoticon: e.Emoticon, Count: e.Count})
+	}
+	for _, rc := range stats.Reactors {
+		response.Reactors = append(response.Reactors, ReactorCountResponse{UserID: rc.UserID, UserName: rc.UserName, Count: rc.Count})
+	}
+	for _, m := range stats.Messages {
+		response.Messages = append(response.Messages, ReactedMessageResponse{
+			MessageID:  m.ID,
+			SenderID:   m.SenderID,
+			SenderName: m.SenderName,
+			Snippet:    m.Content,
+			HasPhoto:   m.HasPhoto,
+			Timestamp:  m.Timestamp.Format(time.RFC3339),
+			Reactions:  m.Reactions,
+		})
+	}
+
+	// Step 5: Return the statistics
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,146 @@
+/*
+Public status page.
+
+GET /status tells anyone, without logging in, whether the server is up:
+its version and uptime, the messages sent in the last hour and the
+health of its components. It is meant for a public status page or a
+quick smoke test, so the report is built at most every
+statusCacheDuration and served from memory in between: polling it costs
+the database nothing more. Like every other route it is rate limited
+(as a read, by address; see ratelimit.go).
+
+The components are:
+
+	database           answers queries
+	scheduledMessages  no scheduled message is overdue, i.e. the scheduler
+	                   keeps up (see scheduled.go)
+	backplane          subscribed to the Redis channel of the events, with
+	                   a backplane only (see backplane.go)
+*/
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// statusCacheDuration is how long a status report is served
+const statusCacheDuration = 30 * time.Second
+
+// statusCheckTimeout bounds the database queries of a status report, so
+// that a stuck database shows up as unhealthy instead of hanging
+const statusCheckTimeout = 2 * time.Second
+
+// scheduledLateness is how late a scheduled message can be before the
+// scheduler is reported unhealthy
+const scheduledLateness = 2 * schedulerSweepInterval
+
+// Overall status of the server
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded" // a component is unhealthy
+)
+
+// StatusResponse is the public status report
+type StatusResponse struct {
+	Status           string          `json:"status"` // ok, degraded
+	Version          string          `json:"version"`
+	StartedAt        string          `json:"startedAt"`
+	UptimeSeconds    int64           `json:"uptimeSeconds"`
+	MessagesLastHour int             `json:"messagesLastHour"`
+	Components       map[string]bool `json:"components"`
+	CheckedAt        string          `json:"checkedAt"`
+}
+
+// statusCache keeps the last status report
+type statusCache struct {
+	mu       sync.Mutex
+	report   StatusResponse
+	checked  time.Time
+	database bool // the database was healthy at the last check
+}
+
+/*
+GetStatus handles GET /status
+operationId: getStatus
+
+Returns the status report, at most statusCacheDuration old. No
+authentication: it holds nothing a visitor may not see. It answers 503
+while the database is unhealthy, since nothing else works then.
+*/
+func (h *Handler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Get the report, building a new one if it is too old
+	report, healthy := h.status.get(func() (StatusResponse, bool) {
+		return h.checkStatus(r.Context())
+	})
+
+	// Step 2: Return it, cached on the way as long as here
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(statusCacheDuration/time.Second)))
+	code := http.StatusOK
+	if !healthy {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, report)
+}
+
+// get returns the last report, or a new one from check when it is
+// older than statusCacheDuration. Concurrent requests wait for the same
+// check instead of each running their own.
+func (c *statusCache) get(check func() (StatusResponse, bool)) (StatusResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.checked.IsZero() || time.Since(c.checked) >= statusCacheDuration {
+		c.report, c.database = check()
+		c.checked = time.Now()
+	}
+	return c.report, c.database
+}
+
+// checkStatus builds a status report and reports whether the database is
+// healthy
+func (h *Handler) checkStatus(ctx context.Context) (StatusResponse, bool) {
+	// The report is shared, so a client going away must not spoil it
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), statusCheckTimeout)
+	defer cancel()
+
+	now := time.Now()
+	report := StatusResponse{
+		Status:        StatusOK,
+		Version:       APIVersion,
+		StartedAt:     h.started.UTC().Format(time.RFC3339),
+		UptimeSeconds: int64(now.Sub(h.started) / time.Second),
+		Components:    map[string]bool{},
+		CheckedAt:     now.UTC().Format(time.RFC3339),
+	}
+
+	// The database, through the count of the last hour
+	count, err := h.db.CountMessagesSince(ctx, now.Add(-time.Hour))
+	if err != nil {
+		log.Printf("Status: error counting the messages: %v", err)
+	}
+	report.MessagesLastHour = count
+	report.Components["database"] = err == nil
+
+	// The scheduler, through the next scheduled message
+	next, err := h.db.NextScheduledMessage(ctx)
+	if err != nil {
+		log.Printf("Status: error looking up the next scheduled message: %v", err)
+	}
+	report.Components["scheduledMessages"] = err == nil && (next == nil || next.After(h.clock.Now().Add(-scheduledLateness)))
+
+	// The event backplane, when there is one
+	if h.config().Backplane.enabled() {
+		report.Components["backplane"] = h.backplane.subscribed.Load()
+	}
+
+	for _, healthy := range report.Components {
+		if !healthy {
+			report.Status = StatusDegraded
+		}
+	}
+	return report, report.Components["database"]
+}
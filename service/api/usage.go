@@ -0,0 +1,212 @@
+/*
+Usage quotas and fair-use throttling.
+
+Every user has soft daily limits (UTC days) on the messages they send
+and the photos they upload. A soft limit does not block: past it, the
+user must wait between two actions, and the wait doubles every
+ThrottleStep actions over the limit, up to MaxThrottleDelay. A request
+that comes too early is answered with 429, a Retry-After header and a
+UsageLimitResponse saying which limit was reached and when it resets.
+
+Users see their counters with GET /users/me/usage.
+*/
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"wasatext/service/database"
+	"wasatext/service/ids"
+)
+
+// UsageConfig holds the soft daily limits
+type UsageConfig struct {
+	// Soft limits per UTC day; 0 means no limit
+	DailyMessages int
+	DailyUploads  int
+	// Past a limit the user waits ThrottleDelay between two actions;
+	// the delay doubles every ThrottleStep actions, up to MaxThrottleDelay
+	ThrottleDelay    time.Duration
+	ThrottleStep     int
+	MaxThrottleDelay time.Duration
+}
+
+// DefaultUsageConfig returns the limits used when none are configured
+func DefaultUsageConfig() UsageConfig {
+	return UsageConfig{
+		DailyMessages:    1000,
+		DailyUploads:     100,
+		ThrottleDelay:    5 * time.Second,
+		ThrottleStep:     10,
+		MaxThrottleDelay: 10 * time.Minute,
+	}
+}
+
+// Kinds of usage
+const (
+	UsageMessages = "messages"
+	UsageUploads  = "uploads"
+)
+
+// codeUsageLimit is the code of the 429 answered past a soft limit
+const codeUsageLimit = "usage_limit"
+
+// UsageCounter is one counter in GET /users/me/usage
+type UsageCounter struct {
+	Used          int    `json:"used"`
+	Limit         int    `json:"limit"`                   // 0 means no limit
+	Throttled     bool   `json:"throttled"`               // over the limit: actions are slowed down
+	NextAllowedAt string `json:"nextAllowedAt,omitempty"` // when throttled and still waiting
+}
+
+// UsageResponse is the body of GET /users/me/usage
+type UsageResponse struct {
+	Day      string       `json:"day"`      // YYYY-MM-DD, UTC
+	ResetsAt string       `json:"resetsAt"` // the counters start over at the next UTC midnight
+	Messages UsageCounter `json:"messages"`
+	Uploads  UsageCounter `json:"uploads"`
+}
+
+// UsageLimitResponse is the body of a 429 answered past a soft limit
+type UsageLimitResponse struct {
+	Message    string `json:"message"`
+	Code       string `json:"code"`       // usage_limit
+	Kind       string `json:"kind"`       // messages, uploads
+	Used       int    `json:"used"`       // today, before this request
+	Limit      int    `json:"limit"`      // the soft daily limit
+	RetryAfter int    `json:"retryAfter"` // seconds, also in the Retry-After header
+	ResetsAt   string `json:"resetsAt"`
+}
+
+/*
+GetMyUsage handles GET /users/me/usage
+operationId: getMyUsage
+
+Returns what the user sent and uploaded today, the soft limits and
+whether the user is being slowed down.
+*/
+func (h *Handler) GetMyUsage(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if _, err := h.db.GetUserByID(r.Context(), authUserID); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 2: Get today's counters
+	now := time.Now()
+	usage, err := h.db.GetUsage(r.Context(), authUserID, database.UsageDay(now))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 3: Compare them with the limits
+	cfg := h.config().Usage
+	writeJSON(w, http.StatusOK, UsageResponse{
+		Day:      usage.Day,
+		ResetsAt: usageReset(now).Format(time.RFC3339),
+		Messages: usageCounter(cfg, usage.Messages, cfg.DailyMessages, usage.LastMessageAt, now),
+		Uploads:  usageCounter(cfg, usage.Uploads, cfg.DailyUploads, usage.LastUploadAt, now),
+	})
+}
+
+// usageCounter describes one counter, as of now
+func usageCounter(cfg UsageConfig, used, limit int, last, now time.Time) UsageCounter {
+	counter := UsageCounter{Used: used, Limit: limit}
+	if limit > 0 && used >= limit {
+		counter.Throttled = true
+		if next := last.Add(throttleDelay(cfg, used-limit+1)); next.After(now) {
+			counter.NextAllowedAt = next.Format(time.RFC3339)
+		}
+	}
+	return counter
+}
+
+/*
+checkUsage applies the soft daily limits before the user sends messages
+and uploads photos. It returns false when a 429 has already been
+written. Call recordUsage once the action succeeded.
+*/
+func (h *Handler) checkUsage(ctx context.Context, w http.ResponseWriter, userID ids.UserID, messages, uploads int) bool {
+	cfg := h.config().Usage
+	if (messages == 0 || cfg.DailyMessages == 0) && (uploads == 0 || cfg.DailyUploads == 0) {
+		return true
+	}
+
+	now := time.Now()
+	usage, err := h.db.GetUsage(ctx, userID, database.UsageDay(now))
+	if err != nil {
+		writeError(w, err)
+		return false
+	}
+
+	if messages > 0 && !h.checkUsageLimit(w, UsageMessages, usage.Messages, cfg.DailyMessages, usage.LastMessageAt, now) {
+		return false
+	}
+	if uploads > 0 && !h.checkUsageLimit(w, UsageUploads, usage.Uploads, cfg.DailyUploads, usage.LastUploadAt, now) {
+		return false
+	}
+	return true
+}
+
+// checkUsageLimit answers 429 when the user is over one limit and has
+// not waited long enough since the last action of that kind
+func (h *Handler) checkUsageLimit(w http.ResponseWriter, kind string, used, limit int, last, now time.Time) bool {
+	if limit == 0 || used < limit {
+		return true
+	}
+
+	next := last.Add(throttleDelay(h.config().Usage, used-limit+1))
+	if !next.After(now) {
+		return true
+	}
+
+	retryAfter := int(next.Sub(now).Seconds()) + 1
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	writeJSON(w, http.StatusTooManyRequests, UsageLimitResponse{
+		Message: "You reached today's fair-use limit of " + strconv.Itoa(limit) + " " + kind +
+			"; you can go on at a slower pace until the limit resets",
+		Code:       codeUsageLimit,
+		Kind:       kind,
+		Used:       used,
+		Limit:      limit,
+		RetryAfter: retryAfter,
+		ResetsAt:   usageReset(now).Format(time.RFC3339),
+	})
+	return false
+}
+
+// throttleDelay is the wait before the over-th action past a limit
+func throttleDelay(cfg UsageConfig, over int) time.Duration {
+	step := max(cfg.ThrottleStep, 1)
+	delay := cfg.ThrottleDelay
+	for i := step; i < over && delay < cfg.MaxThrottleDelay; i += step {
+		delay *= 2
+	}
+	if cfg.MaxThrottleDelay > 0 {
+		delay = min(delay, cfg.MaxThrottleDelay)
+	}
+	return delay
+}
+
+// usageReset is when the counters of the day of now start over
+func usageReset(now time.Time) time.Time {
+	return now.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+}
+
+// recordUsage counts messages and uploads that succeeded; failures are
+// only logged because they must not change the response
+func (h *Handler) recordUsage(ctx context.Context, userID ids.UserID, messages, uploads int) {
+	if err := h.db.RecordUsage(ctx, userID, messages, uploads, time.Now()); err != nil {
+		log.Printf("Error recording usage for %s: %v", userID, err)
+	}
+}
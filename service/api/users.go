@@ -6,29 +6,40 @@ This file contains:
 - setMyUserName: Change username
 - setMyPhoto: Set profile photo
 - searchUsers: Search for users
+- getUserProfile: Get the profile of a user
+- deleteMyAccount: Delete the user's own account
+- deactivateMyAccount: Deactivate the user's own account until the next login
+- getMyWarnings: List moderation warnings received
 */
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"io"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"wasatext/service/database"
-
-	"github.com/gorilla/mux"
+	"wasatext/service/ids"
 )
 
 // LoginRequest is the body for POST /session
 type LoginRequest struct {
-	Name string `json:"name"`
+	Name       string `json:"name"`
+	Workspace  string `json:"workspace,omitempty"`  // defaults to the default workspace
+	InviteCode string `json:"inviteCode,omitempty"` // needed for a new account in invite-only mode
 }
 
 // LoginResponse is the response for POST /session
 type LoginResponse struct {
-	Identifier string `json:"identifier"`
+	Identifier ids.UserID `json:"identifier"`
+	Workspace  string     `json:"workspace"`
+	Token      string     `json:"token"` // the bearer token of the new session
 }
 
 // UsernameRequest is the body for PUT /users/{userId}/username
@@ -36,16 +47,44 @@ type UsernameRequest struct {
 	Name string `json:"name"`
 }
 
+// AboutRequest is the body for PUT /users/{userId}/about
+type AboutRequest struct {
+	About string `json:"about"`
+}
+
+// AboutResponse is the response of PUT /users/{userId}/about
+type AboutResponse struct {
+	About string `json:"about"`
+}
+
+// maxAboutLength is the longest about text, in characters
+const maxAboutLength = 139
+
 // UserResponse represents a user in API responses
 type UserResponse struct {
-	Identifier string `json:"identifier"`
-	Name       string `json:"name"`
-	HasPhoto   bool   `json:"hasPhoto,omitempty"`
+	Identifier  ids.UserID `json:"identifier"`
+	Name        string     `json:"name"`
+	About       string     `json:"about,omitempty"` // the status line of the profile
+	HasPhoto    bool       `json:"hasPhoto,omitempty"`
+	PhotoURL    string     `json:"photoUrl,omitempty"`
+	Blocked     bool       `json:"blocked,omitempty"`     // blocked by the requester (searchUsers)
+	Bot         bool       `json:"bot,omitempty"`         // a bot account (getUserProfile, see bots.go)
+	Deactivated bool       `json:"deactivated,omitempty"` // deactivated until they log in again (members)
+	Role        string     `json:"role,omitempty"`        // admin, member (group members)
+	Online      bool       `json:"online"`                // see presence.go
+	LastSeen    string     `json:"lastSeen,omitempty"`    // the last heartbeat, if known and not hidden
+}
+
+// WarningResponse is a moderation warning
+type WarningResponse struct {
+	Reason    string `json:"reason"`
+	CreatedAt string `json:"createdAt"`
 }
 
 // ErrorResponse is used for error messages
 type ErrorResponse struct {
 	Message string `json:"message"`
+	Code    string `json:"code,omitempty"` // domain error code, see errors.go
 }
 
 /*
@@ -57,6 +96,7 @@ From PDF:
 If the user exists, the user identifier is returned."
 
 This is the SIMPLIFIED login - no password required!
+Logging in to a deactivated account reactivates it.
 */
 func (h *Handler) DoLogin(w http.ResponseWriter, r *http.Request) {
 	// Step 1: Parse the request body
@@ -74,20 +114,68 @@ func (h *Handler) DoLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Step 3: Create or get the user
-	userID, err := h.db.CreateUser(req.Name)
+	// Step 3: Create or get the user in the chosen workspace.
+	// In invite-only mode a new account uses up an invite code.
+	workspaceID := req.Workspace
+	if workspaceID == "" {
+		workspaceID = database.DefaultWorkspaceID
+	}
+	_, err := h.db.GetUserByName(r.Context(), workspaceID, req.Name)
+	registered := errors.Is(err, database.ErrUserNotFound)
+	var userID ids.UserID
+	if h.featureEnabled(FeatureInviteOnly) {
+		userID, err = h.db.RegisterWithInvite(r.Context(), workspaceID, req.Name, normalizeInviteCode(req.InviteCode))
+	} else {
+		userID, err = h.db.CreateUser(r.Context(), workspaceID, req.Name)
+	}
 	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
-	// Step 4: Return the user identifier
+	if registered {
+		h.emitWebhook(r.Context(), WebhookUserRegistered, "", "", userID, UserRegisteredData{Identifier: userID, Name: req.Name, Workspace: workspaceID})
+	}
+
+	// Step 4: Open a session; its token authenticates the next requests
+	token, err := h.db.CreateSession(r.Context(), userID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 5: Return the user identifier and the token
 	// Status 201 as specified in PDF
 	writeJSON(w, http.StatusCreated, LoginResponse{
 		Identifier: userID,
+		Workspace:  workspaceID,
+		Token:      token,
 	})
 }
 
+/*
+DoLogout handles DELETE /session
+operationId: doLogout
+
+Closes the session of the bearer token, which stops working at once.
+*/
+func (h *Handler) DoLogout(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	if getUserIDFromAuth(r) == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Close the session
+	if err := h.db.DeleteSession(r.Context(), getBearerToken(r)); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 3: Return success (204 No Content)
+	w.WriteHeader(http.StatusNoContent)
+}
+
 /*
 SetMyUserName handles PUT /users/{userId}/username
 operationId: setMyUserName
@@ -105,8 +193,10 @@ func (h *Handler) SetMyUserName(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Step 2: Get the user ID from the URL
-	vars := mux.Vars(r)
-	userID := vars["userId"]
+	userID, ok := pathUserID(w, r)
+	if !ok {
+		return
+	}
 
 	// Step 3: Make sure user is updating their own name
 	if authUserID != userID {
@@ -130,15 +220,9 @@ func (h *Handler) SetMyUserName(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Step 6: Update the username
-	err := h.db.UpdateUserName(userID, req.Name)
-	if errors.Is(err, database.ErrUsernameTaken) {
-		writeJSON(w, http.StatusConflict, ErrorResponse{
-			Message: "Username already taken",
-		})
-		return
-	}
+	err := h.db.UpdateUserName(r.Context(), userID, req.Name)
 	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -146,6 +230,57 @@ func (h *Handler) SetMyUserName(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+/*
+SetMyAbout handles PUT /users/{userId}/about
+operationId: setMyAbout
+
+Sets the status line of the user's profile ("Available", "At the gym"),
+up to maxAboutLength characters; an empty one clears it.
+*/
+func (h *Handler) SetMyAbout(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get the user ID from the URL
+	userID, ok := pathUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 3: Make sure user is updating their own profile
+	if authUserID != userID {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 4: Parse and validate the request body
+	var req AboutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	about := strings.TrimSpace(req.About)
+	if utf8.RuneCountInString(about) > maxAboutLength {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{
+			Message: "About must be at most " + strconv.Itoa(maxAboutLength) + " characters",
+		})
+		return
+	}
+
+	// Step 5: Update it
+	if err := h.db.UpdateUserAbout(r.Context(), userID, about); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 6: Return the text as saved
+	writeJSON(w, http.StatusOK, AboutResponse{About: about})
+}
+
 /*
 SetMyPhoto handles PUT /users/{userId}/photo
 operationId: setMyPhoto
@@ -161,8 +296,10 @@ func (h *Handler) SetMyPhoto(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Step 2: Get the user ID from URL
-	vars := mux.Vars(r)
-	userID := vars["userId"]
+	userID, ok := pathUserID(w, r)
+	if !ok {
+		return
+	}
 
 	// Step 3: Make sure user is updating their own photo
 	if authUserID != userID {
@@ -171,29 +308,26 @@ func (h *Handler) SetMyPhoto(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Step 4: Read the photo from request body
-	photo, err := io.ReadAll(r.Body)
-	if err != nil {
-		http.Error(w, "Failed to read photo", http.StatusBadRequest)
+	photo, ok := h.readPhoto(w, r)
+	if !ok {
 		return
 	}
 
-	if len(photo) == 0 {
-		http.Error(w, "No photo provided", http.StatusBadRequest)
+	// Step 5: Apply the fair-use quotas
+	if !h.checkUsage(r.Context(), w, userID, 0, 1) {
 		return
 	}
 
-	// Step 5: Update the photo in database
-	err = h.db.UpdateUserPhoto(userID, photo)
-	if errors.Is(err, database.ErrUserNotFound) {
-		http.Error(w, "User not found", http.StatusNotFound)
-		return
-	}
+	// Step 6: Update the photo in database
+	err := h.db.UpdateUserPhoto(r.Context(), userID, photo)
 	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
+	h.recordUsage(r.Context(), userID, 0, 1)
+	h.media.poke()
 
-	// Step 6: Return success
+	// Step 7: Return success
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -204,6 +338,7 @@ operationId: searchUsers (not in required list but needed for PDF requirement)
 From PDF:
 "The user can search for other users via the username and see all
 the existing WASAText usernames."
+Only users of the requester's workspace are returned.
 */
 func (h *Handler) SearchUsers(w http.ResponseWriter, r *http.Request) {
 	// Step 1: Check authentication
@@ -217,24 +352,208 @@ func (h *Handler) SearchUsers(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query().Get("search")
 
 	// Step 3: Search for users
-	users, err := h.db.SearchUsers(query)
+	users, err := h.db.SearchUsers(r.Context(), authUserID, query)
 	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
 	// Step 4: Convert to response format
-	var response []UserResponse
+	response := []UserResponse{}
+	now := time.Now()
 	for _, u := range users {
+		presence := h.userPresence(u, now)
 		response = append(response, UserResponse{
 			Identifier: u.ID,
 			Name:       u.Name,
-			HasPhoto:   len(u.Photo) > 0,
+			About:      u.About,
+			HasPhoto:   u.PhotoID != "",
+			PhotoURL:   h.photoURL(mediaUser, string(u.ID), u.PhotoID),
+			Blocked:    u.Blocked,
+			Online:     presence.Online,
+			LastSeen:   presence.LastSeen,
 		})
 	}
 
 	// Step 5: Return the users
-	writeJSON(w, http.StatusOK, response)
+	writePage(w, r, response)
+}
+
+/*
+GetUserProfile handles GET /users/{userId}
+operationId: getUserProfile
+
+Returns the profile of a user of the requester's workspace, for showing
+the details of a member: the presence follows the privacy of the user,
+as everywhere else.
+*/
+func (h *Handler) GetUserProfile(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get the user ID from the URL
+	userID, ok := pathUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 3: Return the profile
+	profile, err := h.userProfile(r.Context(), authUserID, userID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, profile)
+}
+
+// userProfile returns the profile of a user as another user sees it; the
+// users of other workspaces do not exist for them
+func (h *Handler) userProfile(ctx context.Context, authUserID, userID ids.UserID) (*UserResponse, error) {
+	me, err := h.db.GetUserByID(ctx, authUserID)
+	if err != nil {
+		return nil, err
+	}
+	user, err := h.db.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.WorkspaceID != me.WorkspaceID {
+		return nil, database.ErrUserNotFound
+	}
+	presence := h.userPresence(*user, time.Now())
+	return &UserResponse{
+		Identifier: user.ID,
+		Name:       user.Name,
+		About:      user.About,
+		HasPhoto:   user.PhotoID != "",
+		PhotoURL:   h.photoURL(mediaUser, string(user.ID), user.PhotoID),
+		Online:     presence.Online,
+		LastSeen:   presence.LastSeen,
+		Bot:        user.Bot,
+	}, nil
+}
+
+/*
+DeleteMyAccount handles DELETE /users/{userId}
+operationId: deleteMyAccount
+
+Deletion happens in two phases: the account is marked as deleted right
+away (it can no longer log in or be found), and the purge job removes
+the personal data once the retention window has passed.
+*/
+func (h *Handler) DeleteMyAccount(w http.ResponseWriter, r *http.Request) {
+	if !h.requireFeature(w, FeatureAccountDeletion) {
+		return
+	}
+
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get the user ID from URL
+	userID, ok := pathUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 3: Make sure user is deleting their own account
+	if authUserID != userID {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 4: Mark the account as deleted
+	err := h.db.DeleteUser(r.Context(), userID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 5: Return success (204 No Content)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+DeactivateMyAccount handles POST /users/me/deactivate
+operationId: deactivateMyAccount
+
+Deactivates the user's account until they log in again: their sessions
+and WebSockets are closed, the others no longer find them in the search
+and see the account deactivated in their conversations. Unlike deleting
+the account, nothing is lost.
+*/
+func (h *Handler) DeactivateMyAccount(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Deactivate the account, which closes its sessions
+	if err := h.db.DeactivateUser(r.Context(), authUserID); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 3: Close its WebSockets, so that no event reaches it anymore
+	h.hub.disconnect(authUserID)
+
+	// Step 4: Return success (204 No Content)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+GetMyWarnings handles GET /users/{userId}/warnings
+operationId: getMyWarnings
+
+Returns the warnings moderators gave to the user, most recent first.
+*/
+func (h *Handler) GetMyWarnings(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get the user ID from URL
+	userID, ok := pathUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 3: Users can only see their own warnings
+	if authUserID != userID {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 4: Get the warnings
+	warnings, err := h.db.GetUserWarnings(r.Context(), userID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 5: Convert to response format
+	response := []WarningResponse{}
+	for _, warning := range warnings {
+		response = append(response, WarningResponse{
+			Reason:    warning.Reason,
+			CreatedAt: warning.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		})
+	}
+
+	// Step 6: Return the warnings
+	writePage(w, r, response)
 }
 
 // writeJSON is a helper to write JSON responses
@@ -0,0 +1,639 @@
+/*
+Outbound webhook API handlers.
+
+Integrations are told of what happens by webhooks: URLs the server posts
+a JSON event to, signed with the secret of the webhook (see
+database/webhooks.go). The admin of a group registers webhooks for the
+events of the group; the admin of the server registers global ones, for
+every group and for the events of no group.
+
+Events:
+
+	message.created     a new message (conversationId, message: same as
+	                    in the conversation)
+	group.member_added  a user was added to a group, or joined a channel
+	                    (groupId, userId, addedBy)
+	user.registered     an account was created (identifier, name,
+	                    workspace; global webhooks only)
+
+The first two are queued by subscribers of the event bus (see
+service/events), whatever the handler that created the message or
+added the member.
+
+Every POST carries {"event", "createdAt", "data"} and the headers
+X-WASAText-Event, X-WASAText-Delivery (the delivery ID, the same for
+every attempt) and X-WASAText-Signature: "t=<unix time>,v1=<hex
+HMAC-SHA256 of <unix time>.<body> keyed with the secret>". A delivery
+that does not get a 2xx answer within webhookTimeout is tried again
+after 1, 2, 4, ... minutes, up to maxWebhookAttempts times. The
+deliveries are kept for webhookLogRetention as the log of the webhook.
+
+The webhooks of a group, and of a bot (see bots.go), post to public https
+URLs only: the server does not connect to its own network on behalf of a
+group admin.
+
+This file contains:
+- createGroupWebhook: Register a webhook for the events of a group
+- listGroupWebhooks: List the webhooks of a group
+- deleteGroupWebhook: Delete a webhook of a group
+- getGroupWebhookDeliveries: The last deliveries of a webhook of a group
+- createWebhook: Register a global webhook (admin)
+- listWebhooks: List the global webhooks (admin)
+- deleteWebhook: Delete a global webhook (admin)
+- getWebhookDeliveries: The last deliveries of a global webhook (admin)
+*/
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"wasatext/service/database"
+	"wasatext/service/events"
+	"wasatext/service/globaltime"
+	"wasatext/service/ids"
+	"wasatext/service/publicnet"
+
+	"github.com/gorilla/mux"
+)
+
+// Events webhooks are told of
+const (
+	WebhookMessageCreated = "message.created"
+	WebhookMemberAdded    = "group.member_added"
+	WebhookUserRegistered = "user.registered"
+)
+
+// groupWebhookEvents are the events of a group; global webhooks also
+// take the others
+var (
+	groupWebhookEvents  = []string{WebhookMessageCreated, WebhookMemberAdded}
+	globalWebhookEvents = []string{WebhookMessageCreated, WebhookMemberAdded, WebhookUserRegistered}
+)
+
+const (
+	webhookTimeout      = 10 * time.Second   // how long a webhook may take to answer
+	webhookRetryDelay   = time.Minute        // the delay before the first retry, doubled for each next one
+	maxWebhookAttempts  = 8                  // attempts of a delivery before it is given up
+	webhookSenders      = 4                  // deliveries posted at once
+	webhookBatch        = 100                // deliveries taken per sweep
+	webhookSweep        = time.Minute        // the longest the dispatcher sleeps
+	webhookLogRetention = 7 * 24 * time.Hour // how long finished deliveries are kept
+	webhookLogLimit     = 100                // deliveries listed in the log
+	maxWebhookURLLength = 2048
+	maxWebhookError     = 200 // characters of the error kept for the log
+)
+
+// CreateWebhookRequest is the body of POST /groups/{groupId}/webhooks and
+// POST /admin/webhooks
+type CreateWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+// WebhookResponse is a webhook
+type WebhookResponse struct {
+	WebhookID int64       `json:"webhookId"`
+	GroupID   ids.GroupID `json:"groupId,omitempty"` // none for a global webhook
+	URL       string      `json:"url"`
+	Events    []string    `json:"events"`
+	Secret    string      `json:"secret,omitempty"` // only when it is created
+	CreatedAt string      `json:"createdAt"`
+}
+
+// WebhookDeliveryResponse is a delivery of the log of a webhook
+type WebhookDeliveryResponse struct {
+	DeliveryID    int64  `json:"deliveryId"`
+	Event         string `json:"event"`
+	State         string `json:"state"` // pending, delivered, failed
+	Attempts      int    `json:"attempts"`
+	LastStatus    int    `json:"lastStatus,omitempty"` // the HTTP status of the last attempt
+	LastError     string `json:"lastError,omitempty"`
+	CreatedAt     string `json:"createdAt"`
+	NextAttemptAt string `json:"nextAttemptAt,omitempty"`
+	FinishedAt    string `json:"finishedAt,omitempty"`
+}
+
+// WebhookPayload is the body of every webhook POST
+type WebhookPayload struct {
+	Event     string `json:"event"`
+	CreatedAt string `json:"createdAt"`
+	Data      any    `json:"data"`
+}
+
+// MessageCreatedData is the data of message.created
+type MessageCreatedData struct {
+	ConversationID ids.ConversationID `json:"conversationId"`
+	Message        MessageResponse    `json:"message"`
+}
+
+// MemberAddedData is the data of group.member_added
+type MemberAddedData struct {
+	GroupID ids.GroupID `json:"groupId"`
+	UserID  ids.UserID  `json:"userId"`
+	AddedBy ids.UserID  `json:"addedBy"` // the user themselves when they joined a channel
+}
+
+// UserRegisteredData is the data of user.registered
+type UserRegisteredData struct {
+	Identifier ids.UserID `json:"identifier"`
+	Name       string     `json:"name"`
+	Workspace  string     `json:"workspace"`
+}
+
+// webhookResponse converts a webhook to its response format
+func webhookResponse(wh database.Webhook) WebhookResponse {
+	return WebhookResponse{
+		WebhookID: wh.ID,
+		GroupID:   wh.GroupID,
+		URL:       wh.URL,
+		Events:    wh.Events,
+		CreatedAt: wh.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+/*
+CreateGroupWebhook handles POST /groups/{groupId}/webhooks
+operationId: createGroupWebhook
+
+Registers a webhook for events of the group, by its admin. The secret
+that signs the deliveries is only returned here.
+*/
+func (h *Handler) CreateGroupWebhook(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check that the user is the group admin
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	groupID, ok := pathGroupID(w, r)
+	if !ok {
+		return
+	}
+	if !h.requireGroupAdmin(r.Context(), w, groupID, authUserID, "manage webhooks") {
+		return
+	}
+
+	// Step 2: Register the webhook
+	h.createWebhook(w, r, groupID, authUserID)
+}
+
+/*
+ListGroupWebhooks handles GET /groups/{groupId}/webhooks
+operationId: listGroupWebhooks
+
+Lists the webhooks of the group, for its admin.
+*/
+func (h *Handler) ListGroupWebhooks(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check that the user is the group admin
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	groupID, ok := pathGroupID(w, r)
+	if !ok {
+		return
+	}
+	if !h.requireGroupAdmin(r.Context(), w, groupID, authUserID, "manage webhooks") {
+		return
+	}
+
+	// Step 2: List the webhooks
+	h.listWebhooks(w, r, groupID)
+}
+
+/*
+DeleteGroupWebhook handles DELETE /groups/{groupId}/webhooks/{webhookId}
+operationId: deleteGroupWebhook
+
+Deletes a webhook of the group, with its pending deliveries.
+*/
+func (h *Handler) DeleteGroupWebhook(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check that the user is the group admin
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	groupID, ok := pathGroupID(w, r)
+	if !ok {
+		return
+	}
+	if !h.requireGroupAdmin(r.Context(), w, groupID, authUserID, "manage webhooks") {
+		return
+	}
+
+	// Step 2: Delete the webhook
+	h.deleteWebhook(w, r, groupID)
+}
+
+/*
+GetGroupWebhookDeliveries handles GET /groups/{groupId}/webhooks/{webhookId}/deliveries
+operationId: getGroupWebhookDeliveries
+
+Returns the last deliveries of a webhook of the group, the most recent
+first: what was sent, how it went, and when the next attempt is.
+*/
+func (h *Handler) GetGroupWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check that the user is the group admin
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	groupID, ok := pathGroupID(w, r)
+	if !ok {
+		return
+	}
+	if !h.requireGroupAdmin(r.Context(), w, groupID, authUserID, "manage webhooks") {
+		return
+	}
+
+	// Step 2: Return the log
+	h.getWebhookDeliveries(w, r, groupID)
+}
+
+/*
+CreateWebhook handles POST /admin/webhooks
+operationId: createWebhook
+
+Registers a global webhook, told of the events of every group and
+workspace, and of the new accounts.
+*/
+func (h *Handler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	if !h.isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	h.createWebhook(w, r, "", "")
+}
+
+/*
+ListWebhooks handles GET /admin/webhooks
+operationId: listWebhooks
+
+Lists the global webhooks.
+*/
+func (h *Handler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	if !h.isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	h.listWebhooks(w, r, "")
+}
+
+/*
+DeleteWebhook handles DELETE /admin/webhooks/{webhookId}
+operationId: deleteWebhook
+
+Deletes a global webhook, with its pending deliveries.
+*/
+func (h *Handler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	if !h.isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	h.deleteWebhook(w, r, "")
+}
+
+/*
+GetWebhookDeliveries handles GET /admin/webhooks/{webhookId}/deliveries
+operationId: getWebhookDeliveries
+
+Returns the last deliveries of a global webhook, the most recent first.
+*/
+func (h *Handler) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	if !h.isAdmin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	h.getWebhookDeliveries(w, r, "")
+}
+
+// createWebhook registers a webhook of a group, or a global one for
+// groupID ""
+func (h *Handler) createWebhook(w http.ResponseWriter, r *http.Request, groupID ids.GroupID, createdBy ids.UserID) {
+	// Step 1: Parse and validate the request
+	var req CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	allowed := globalWebhookEvents
+	if groupID != "" {
+		allowed = groupWebhookEvents
+	}
+	events, ok := checkWebhookRequest(w, req, groupID != "", allowed)
+	if !ok {
+		return
+	}
+
+	// Step 2: Register the webhook
+	webhook, err := h.db.CreateWebhook(r.Context(), groupID, createdBy, req.URL, events)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 3: Return it with its secret
+	response := webhookResponse(*webhook)
+	response.Secret = webhook.Secret
+	writeJSON(w, http.StatusCreated, response)
+}
+
+// checkWebhookRequest validates the URL, an https one if public, and
+// returns the events without duplicates; it answers 400 itself
+func checkWebhookRequest(w http.ResponseWriter, req CreateWebhookRequest, public bool, allowed []string) ([]string, bool) {
+	u, err := url.Parse(req.URL)
+	validScheme := u != nil && (u.Scheme == "https" || (u.Scheme == "http" && !public))
+	if err != nil || !validScheme || u.Host == "" || len(req.URL) > maxWebhookURLLength {
+		message := "url must be an http or https URL"
+		if public {
+			message = "url must be an https URL"
+		}
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Message: message})
+		return nil, false
+	}
+	var events []string
+	for _, event := range req.Events {
+		if !slices.Contains(allowed, event) {
+			writeJSON(w, http.StatusBadRequest, ErrorResponse{Message: "Unknown event " + strconv.Quote(event)})
+			return nil, false
+		}
+		if !slices.Contains(events, event) {
+			events = append(events, event)
+		}
+	}
+	if len(events) == 0 {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Message: "events must name at least one event"})
+		return nil, false
+	}
+	return events, true
+}
+
+// listWebhooks lists the webhooks of a group, or the global ones for groupID ""
+func (h *Handler) listWebhooks(w http.ResponseWriter, r *http.Request, groupID ids.GroupID) {
+	webhooks, err := h.db.ListWebhooks(r.Context(), groupID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	response := make([]WebhookResponse, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		response = append(response, webhookResponse(webhook))
+	}
+	writePage(w, r, response)
+}
+
+// deleteWebhook deletes a webhook of a group, or a global one for groupID ""
+func (h *Handler) deleteWebhook(w http.ResponseWriter, r *http.Request, groupID ids.GroupID) {
+	webhookID, ok := pathWebhookID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.db.DeleteWebhook(r.Context(), groupID, webhookID); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getWebhookDeliveries returns the log of a webhook of a group, or of a
+// global one for groupID ""
+func (h *Handler) getWebhookDeliveries(w http.ResponseWriter, r *http.Request, groupID ids.GroupID) {
+	webhookID, ok := pathWebhookID(w, r)
+	if !ok {
+		return
+	}
+	deliveries, err := h.db.GetWebhookDeliveries(r.Context(), groupID, webhookID, webhookLogLimit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writePage(w, r, webhookDeliveryResponses(deliveries))
+}
+
+// webhookDeliveryResponses converts deliveries to their response format
+func webhookDeliveryResponses(deliveries []database.WebhookDelivery) []WebhookDeliveryResponse {
+	response := make([]WebhookDeliveryResponse, 0, len(deliveries))
+	for _, d := range deliveries {
+		item := WebhookDeliveryResponse{
+			DeliveryID: d.ID,
+			Event:      d.Event,
+			State:      d.State,
+			Attempts:   d.Attempts,
+			LastStatus: d.LastStatus,
+			LastError:  d.LastError,
+			CreatedAt:  d.CreatedAt.UTC().Format(time.RFC3339),
+		}
+		if d.NextAttemptAt != nil {
+			item.NextAttemptAt = d.NextAttemptAt.UTC().Format(time.RFC3339)
+		}
+		if d.FinishedAt != nil {
+			item.FinishedAt = d.FinishedAt.UTC().Format(time.RFC3339)
+		}
+		response = append(response, item)
+	}
+	return response
+}
+
+// pathWebhookID reads {webhookId}; it answers 400 itself
+func pathWebhookID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	webhookID, err := strconv.ParseInt(mux.Vars(r)["webhookId"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return webhookID, true
+}
+
+/*
+emitWebhook queues an event raised by actor for the webhooks told of it:
+the global ones, those of the group (groupID, or else the group of
+conversationID) and those of its bots but actor. Errors are logged: the
+action that raised the event happened anyway.
+*/
+func (h *Handler) emitWebhook(ctx context.Context, event string, groupID ids.GroupID, conversationID ids.ConversationID, actor ids.UserID, data any) {
+	now := h.clock.Now()
+	payload, err := json.Marshal(WebhookPayload{Event: event, CreatedAt: now.UTC().Format(time.RFC3339), Data: data})
+	if err != nil {
+		log.Printf("Error encoding a %s webhook event: %v", event, err)
+		return
+	}
+	queued, err := h.db.QueueWebhookEvent(context.WithoutCancel(ctx), event, groupID, conversationID, actor, payload, now)
+	if err != nil {
+		log.Printf("Error queueing a %s webhook event: %v", event, err)
+		return
+	}
+	if queued > 0 {
+		h.webhooks.poke()
+	}
+}
+
+// emitMessageCreated and emitMemberAdded tell the webhooks of a new
+// message and of a new member of a group
+func (h *Handler) emitMessageCreated(ctx context.Context, e events.MessageCreated) {
+	msg := h.messageResponse(e.Message)
+	h.emitWebhook(ctx, WebhookMessageCreated, "", e.Message.ConversationID, msg.SenderID, MessageCreatedData{ConversationID: e.Message.ConversationID, Message: msg})
+}
+
+func (h *Handler) emitMemberAdded(ctx context.Context, e events.MemberJoined) {
+	h.emitWebhook(ctx, WebhookMemberAdded, e.GroupID, "", e.AddedBy, MemberAddedData{GroupID: e.GroupID, UserID: e.UserID, AddedBy: e.AddedBy})
+}
+
+// signWebhook returns the X-WASAText-Signature of a body sent at a time
+func signWebhook(secret string, body []byte, at time.Time) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookDispatcher posts the deliveries when they are due
+type webhookDispatcher struct {
+	db        database.AppDatabase
+	clock     globaltime.Time
+	client    *http.Client // for the global webhooks
+	public    *http.Client // for the webhooks of groups and bots, public addresses only
+	wake      chan struct{}
+	lastPrune time.Time
+}
+
+// newWebhookDispatcher returns the dispatcher; New starts it
+func newWebhookDispatcher(db database.AppDatabase, clock globaltime.Time) *webhookDispatcher {
+	return &webhookDispatcher{
+		db:     db,
+		clock:  clock,
+		client: &http.Client{Timeout: webhookTimeout, CheckRedirect: publicnet.NoRedirect},
+		public: publicnet.NewClient(webhookTimeout),
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// poke tells the dispatcher deliveries were queued
+func (wd *webhookDispatcher) poke() {
+	select {
+	case wd.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run posts the due deliveries until ctx is cancelled, after completing
+// the sweep under way
+func (wd *webhookDispatcher) run(ctx context.Context) {
+	work := context.WithoutCancel(ctx)
+	for {
+		timer := time.NewTimer(wd.sweep(work))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-wd.wake:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// sweep posts the due deliveries, webhookSenders at a time, and returns
+// how long to sleep until the next one; once an hour it also drops the
+// deliveries past webhookLogRetention
+func (wd *webhookDispatcher) sweep(ctx context.Context) time.Duration {
+	now := wd.clock.Now()
+	if now.Sub(wd.lastPrune) >= time.Hour {
+		wd.lastPrune = now
+		if _, err := wd.db.PruneWebhookDeliveries(ctx, now.Add(-webhookLogRetention)); err != nil {
+			log.Printf("Error pruning the webhook deliveries: %v", err)
+		}
+	}
+
+	due, err := wd.db.DueWebhookDeliveries(ctx, now, webhookBatch)
+	if err != nil {
+		log.Printf("Error listing the webhook deliveries: %v", err)
+		return webhookSweep
+	}
+	var wg sync.WaitGroup
+	senders := make(chan struct{}, webhookSenders)
+	for _, d := range due {
+		senders <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-senders }()
+			attempt := wd.send(ctx, d)
+			if err := wd.db.RecordWebhookAttempt(ctx, d.ID, attempt); err != nil {
+				log.Printf("Error recording webhook delivery %d: %v", d.ID, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if len(due) == webhookBatch {
+		return 0 // more are due
+	}
+
+	next, err := wd.db.NextWebhookDelivery(ctx)
+	if err != nil {
+		log.Printf("Error looking up the next webhook delivery: %v", err)
+		return webhookSweep
+	}
+	if next == nil {
+		return webhookSweep
+	}
+	return max(min(next.Sub(wd.clock.Now()), webhookSweep), 0)
+}
+
+// send posts one delivery and tells what became of it
+func (wd *webhookDispatcher) send(ctx context.Context, d database.WebhookDelivery) database.WebhookAttempt {
+	now := wd.clock.Now()
+	attempt := database.WebhookAttempt{State: database.DeliveryDelivered, At: now}
+
+	client := wd.client
+	if d.GroupID != "" || d.BotID != "" {
+		client = wd.public
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err == nil {
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "WASAText-Webhook/1")
+		req.Header.Set("X-WASAText-Event", d.Event)
+		req.Header.Set("X-WASAText-Delivery", strconv.FormatInt(d.ID, 10))
+		req.Header.Set("X-WASAText-Signature", signWebhook(d.Secret, d.Payload, now))
+		var resp *http.Response
+		if resp, err = client.Do(req); err == nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			_ = resp.Body.Close()
+			attempt.Status = resp.StatusCode
+			if resp.StatusCode < 200 || resp.StatusCode > 299 {
+				err = errors.New(resp.Status)
+			}
+		}
+	}
+	if err == nil {
+		return attempt
+	}
+
+	// Try again later, unless it was the last attempt
+	attempt.Error = truncateRunes(err.Error(), maxWebhookError)
+	if d.Attempts+1 >= maxWebhookAttempts {
+		attempt.State = database.DeliveryFailed
+		return attempt
+	}
+	next := now.Add(webhookRetryDelay << d.Attempts)
+	attempt.State, attempt.NextAttemptAt = database.DeliveryPending, &next
+	return attempt
+}
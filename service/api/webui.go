@@ -0,0 +1,130 @@
+/*
+Configuration of the embedded web frontend.
+
+The frontend is built once and served as is by every deployment. What
+differs between deployments (where the API is, which features are on,
+how large a photo may be, the name shown to users) is read from
+GET /config.js, loaded by index.html before the application:
+
+	window.__WASATEXT_CONFIG__ = {"apiBaseUrl": "...", ...};
+
+The branding is, from the weakest: the webui settings of the
+configuration file, what the admin set through /admin/branding (see
+branding.go), and with ?workspace= the branding configured for that
+workspace. The script is never cached, so a
+configuration reload reaches the next page load.
+*/
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"wasatext/service/database"
+)
+
+// WebUIConfig holds what the embedded frontend is told
+type WebUIConfig struct {
+	// APIBaseURL is where the frontend sends its requests;
+	// empty means the origin serving the page
+	APIBaseURL string
+
+	// Branding is shown to every workspace without its own
+	Branding Branding
+
+	// WorkspaceBranding overrides Branding by workspace ID; empty
+	// fields keep the default value
+	WorkspaceBranding map[string]Branding
+}
+
+// Branding holds the strings the frontend shows to users
+type Branding struct {
+	AppName string
+	Tagline string
+}
+
+// DefaultAppName is the application name when none is configured
+const DefaultAppName = "WASAText"
+
+// DefaultMaxPhotoSize is the default size limit of an uploaded photo
+const DefaultMaxPhotoSize = 10 << 20 // 10 MB
+
+// DefaultMaxPhotoDimension is the default limit of the width and the
+// height of an uploaded photo, in pixels
+const DefaultMaxPhotoDimension = 8192
+
+// WebUIConfigResponse is the configuration given to the frontend
+type WebUIConfigResponse struct {
+	APIBaseURL        string          `json:"apiBaseUrl,omitempty"`
+	Features          map[string]bool `json:"features"`
+	MaxPhotoSize      int64           `json:"maxPhotoSize"`                // bytes
+	MaxPhotoDimension int             `json:"maxPhotoDimension,omitempty"` // pixels
+	AppName           string          `json:"appName"`
+	Tagline           string          `json:"tagline,omitempty"`
+	AccentColor       string          `json:"accentColor,omitempty"`
+	LogoURL           string          `json:"logoUrl,omitempty"` // signed, short-lived (see media.go)
+}
+
+// webUIConfig builds the frontend configuration for a workspace ("" for
+// none), given the branding set by the admin
+func (h *Handler) webUIConfig(workspaceID string, admin database.Branding) WebUIConfigResponse {
+	cfg := h.config()
+
+	branding := cfg.WebUI.Branding
+	if admin.AppName != "" {
+		branding.AppName = admin.AppName
+	}
+	if override, ok := cfg.WebUI.WorkspaceBranding[workspaceID]; ok {
+		if override.AppName != "" {
+			branding.AppName = override.AppName
+		}
+		if override.Tagline != "" {
+			branding.Tagline = override.Tagline
+		}
+	}
+	if branding.AppName == "" {
+		branding.AppName = DefaultAppName
+	}
+
+	features := make(map[string]bool, len(defaultFeatures))
+	for name := range defaultFeatures {
+		features[name] = h.featureEnabled(name)
+	}
+
+	return WebUIConfigResponse{
+		APIBaseURL:        cfg.WebUI.APIBaseURL,
+		Features:          features,
+		MaxPhotoSize:      cfg.MaxPhotoSize,
+		MaxPhotoDimension: cfg.MaxPhotoDimension,
+		AppName:           branding.AppName,
+		Tagline:           branding.Tagline,
+		AccentColor:       admin.AccentColor,
+		LogoURL:           h.photoURL(mediaBranding, admin.LogoID, admin.LogoID),
+	}
+}
+
+/*
+GetWebUIConfig handles GET /config.js
+operationId: getWebUIConfig
+
+Returns a script setting window.__WASATEXT_CONFIG__ for the frontend.
+No authentication: it holds nothing a visitor may not see.
+*/
+func (h *Handler) GetWebUIConfig(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Build the configuration of the workspace asked for
+	branding, err := h.db.GetBranding(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	data, err := json.Marshal(h.webUIConfig(r.URL.Query().Get("workspace"), *branding))
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Step 2: Return it as a script
+	w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	_, _ = w.Write([]byte("window.__WASATEXT_CONFIG__ = " + string(data) + ";\n"))
+}
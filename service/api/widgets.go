@@ -0,0 +1,226 @@
+/*
+Widget token API handlers.
+
+A participant can mint widget tokens to embed a read-only view of a
+conversation in another site, such as a support chat. The widget sends
+the token as "Authorization: Bearer <token>" and acts as the user who
+minted it, but AuthMiddleware only lets it through to the routes in
+widgetRoutes, with GET, for its own conversation; anything else is
+answered with 403.
+
+This file contains:
+- createWidgetToken: Mint a widget token for a conversation
+- listWidgetTokens: List the widget tokens you minted for a conversation
+- revokeWidgetToken: Revoke one of them
+*/
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"wasatext/service/database"
+	"wasatext/service/ids"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	maxWidgetTokenNameLength = 64
+	maxWidgetTokenHours      = 365 * 24 // one year
+)
+
+// widgetRoutes are the routes a widget token may read, all scoped to
+// the {conversationId} of the token
+var widgetRoutes = map[string]bool{
+	"/conversations/{conversationId}":                            true,
+	"/conversations/{conversationId}/reactions":                  true,
+	"/conversations/{conversationId}/messages/{messageId}/photo": true,
+}
+
+// CreateWidgetTokenRequest is the body for POST /conversations/{id}/widget-tokens
+type CreateWidgetTokenRequest struct {
+	Name           string `json:"name"`
+	ExpiresInHours int    `json:"expiresInHours,omitempty"` // 0 means the token never expires
+}
+
+// WidgetTokenResponse is a widget token as its creator sees it
+type WidgetTokenResponse struct {
+	Token          string             `json:"token"`
+	ConversationID ids.ConversationID `json:"conversationId"`
+	Name           string             `json:"name"`
+	CreatedAt      string             `json:"createdAt"`
+	ExpiresAt      string             `json:"expiresAt,omitempty"`
+}
+
+// widgetTokenResponse converts a widget token to its response format
+func widgetTokenResponse(wt *database.WidgetToken) WidgetTokenResponse {
+	response := WidgetTokenResponse{
+		Token:          wt.Token,
+		ConversationID: wt.ConversationID,
+		Name:           wt.Name,
+		CreatedAt:      wt.CreatedAt.Format(time.RFC3339),
+	}
+	if wt.ExpiresAt != nil {
+		response.ExpiresAt = wt.ExpiresAt.Format(time.RFC3339)
+	}
+	return response
+}
+
+/*
+widgetUser resolves a widget token for AuthMiddleware. It returns the
+user the token acts as, or "" when the token is not valid (or the
+feature is off) and the request goes on unauthenticated. ok is false
+when the request is out of the token's scope and the 403 has been
+written.
+*/
+func (h *Handler) widgetUser(w http.ResponseWriter, r *http.Request, token string) (userID ids.UserID, ok bool) {
+	if !h.featureEnabled(FeatureWidgetTokens) {
+		return "", true
+	}
+	wt, err := h.db.GetWidgetToken(r.Context(), token)
+	if err != nil {
+		if !errors.Is(err, database.ErrWidgetTokenNotFound) {
+			log.Printf("Error resolving a widget token: %v", err)
+		}
+		return "", true
+	}
+
+	route := mux.CurrentRoute(r)
+	template := ""
+	if route != nil {
+		template, _ = route.GetPathTemplate()
+	}
+	if r.Method != http.MethodGet || !widgetRoutes[template] ||
+		mux.Vars(r)["conversationId"] != string(wt.ConversationID) {
+		http.Error(w, "Widget tokens can only read their own conversation", http.StatusForbidden)
+		return "", false
+	}
+	return wt.CreatedBy, true
+}
+
+/*
+CreateWidgetToken handles POST /conversations/{conversationId}/widget-tokens
+operationId: createWidgetToken
+
+Mints a widget token reading the conversation on behalf of the user.
+Any participant can do this.
+*/
+func (h *Handler) CreateWidgetToken(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check the feature and authentication
+	if !h.requireFeature(w, FeatureWidgetTokens) {
+		return
+	}
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get conversation ID from URL
+	conversationID, ok := pathConversationID(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 3: Parse request body
+	var req CreateWidgetTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > maxWidgetTokenNameLength {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Message: "name must be between 1 and 64 characters"})
+		return
+	}
+	if req.ExpiresInHours < 0 || req.ExpiresInHours > maxWidgetTokenHours {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Message: "expiresInHours must be between 0 and 8760"})
+		return
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresInHours > 0 {
+		t := time.Now().Add(time.Duration(req.ExpiresInHours) * time.Hour)
+		expiresAt = &t
+	}
+
+	// Step 4: Mint the token (this also checks the user is a participant)
+	wt, err := h.db.CreateWidgetToken(r.Context(), conversationID, authUserID, req.Name, expiresAt)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	h.infof("Widget token %q minted for %s by %s", wt.Name, conversationID, authUserID)
+	writeJSON(w, http.StatusCreated, widgetTokenResponse(wt))
+}
+
+/*
+ListWidgetTokens handles GET /conversations/{conversationId}/widget-tokens
+operationId: listWidgetTokens
+
+Returns the widget tokens the user minted for the conversation, expired
+ones included.
+*/
+func (h *Handler) ListWidgetTokens(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get conversation ID from URL
+	conversationID, ok := pathConversationID(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 3: Get the tokens
+	tokens, err := h.db.ListWidgetTokens(r.Context(), conversationID, authUserID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response := []WidgetTokenResponse{}
+	for i := range tokens {
+		response = append(response, widgetTokenResponse(&tokens[i]))
+	}
+	writePage(w, r, response)
+}
+
+/*
+RevokeWidgetToken handles DELETE /conversations/{conversationId}/widget-tokens/{widgetToken}
+operationId: revokeWidgetToken
+
+Revokes a widget token. Only its creator can do this.
+*/
+func (h *Handler) RevokeWidgetToken(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Check authentication
+	authUserID := getUserIDFromAuth(r)
+	if authUserID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Step 2: Get conversation ID from URL
+	conversationID, ok := pathConversationID(w, r)
+	if !ok {
+		return
+	}
+
+	// Step 3: Revoke the token
+	if err := h.db.DeleteWidgetToken(r.Context(), conversationID, authUserID, mux.Vars(r)["widgetToken"]); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 4: Return success (204 No Content)
+	w.WriteHeader(http.StatusNoContent)
+}
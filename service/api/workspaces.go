@@ -0,0 +1,49 @@
+/*
+Workspace API handlers.
+
+A workspace is an independent community on the same server. Users pick
+one at login; everything they see afterwards (users, conversations,
+groups) is limited to that workspace.
+
+This file contains:
+- listWorkspaces: List the workspaces to choose from at login
+*/
+package api
+
+import (
+	"net/http"
+)
+
+// WorkspaceResponse represents a workspace in API responses
+type WorkspaceResponse struct {
+	WorkspaceID string `json:"workspaceId"`
+	Name        string `json:"name"`
+}
+
+/*
+ListWorkspaces handles GET /workspaces
+operationId: listWorkspaces
+
+Returns every workspace, for the selector on the login page.
+No authentication is needed since it is used before logging in.
+*/
+func (h *Handler) ListWorkspaces(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Get the workspaces
+	workspaces, err := h.db.ListWorkspaces(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Step 2: Convert to response format
+	response := []WorkspaceResponse{}
+	for _, ws := range workspaces {
+		response = append(response, WorkspaceResponse{
+			WorkspaceID: ws.ID,
+			Name:        ws.Name,
+		})
+	}
+
+	// Step 3: Return the workspaces
+	writePage(w, r, response)
+}
@@ -0,0 +1,325 @@
+/*
+Package chatimport reads chat histories exported by other messengers, for
+moving a group to WASAText.
+
+WhatsApp exports a chat (Export chat, in the menu of the chat) as a zip
+holding the transcript and, when media are included, the attached
+files. The transcript is _chat.txt on iOS and "WhatsApp Chat with
+<name>.txt" on Android; every message starts a line with its date, time
+and sender:
+
+	[25/12/2023, 18:04:51] Maria: Merry Christmas!
+	25/12/23, 18:04 - Maria: Merry Christmas!
+
+and the lines that do not start like that continue the message above.
+The date order (day or month first) and the 12 or 24-hour clock follow
+the locale of the phone: the order is guessed from the dates of the
+whole transcript, the day first when nothing tells. The times carry no
+time zone, they are read in the one given. WhatsApp's own notices
+(encryption, members added, ...) are left out: on Android they have a
+date but no sender, on iOS the name of the group as sender and a text
+starting with a left-to-right mark, which iOS only puts otherwise
+before attachments and media left out of the export (and Android before
+attachments).
+*/
+package chatimport
+
+import (
+	"archive/zip"
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotWhatsApp is returned for a file that holds no WhatsApp transcript
+var ErrNotWhatsApp = errors.New("chatimport: not a WhatsApp chat export")
+
+// ErrMediaNotFound is returned for an attachment missing from the export
+var ErrMediaNotFound = errors.New("chatimport: attachment not in the export")
+
+// maxLineLength is the longest line of a transcript, in bytes
+const maxLineLength = 1 << 20
+
+// Chat is a transcript
+type Chat struct {
+	Name     string // the name of the chat, "" when the export does not tell
+	Messages []Message
+}
+
+// Message is a message of a transcript
+type Message struct {
+	Time       time.Time
+	Sender     string // as the phone of the exporter showed it: a contact name or a phone number
+	Text       string // "" for an attachment without caption
+	Attachment string // the file name of the attached media, "" if none
+}
+
+// Senders returns the names of the senders, in the order of their first message
+func (c *Chat) Senders() []string {
+	var senders []string
+	seen := map[string]bool{}
+	for _, m := range c.Messages {
+		if !seen[m.Sender] {
+			seen[m.Sender] = true
+			senders = append(senders, m.Sender)
+		}
+	}
+	return senders
+}
+
+/*
+headerPattern matches the start of a message: the date, the time with
+optional seconds and AM/PM, in brackets (iOS) or followed by a dash
+(Android), and the rest of the line. Newer exports put a narrow
+no-break space before AM/PM.
+*/
+var headerPattern = regexp.MustCompile(
+	`^\[?(\d{1,4})[./-](\d{1,2})[./-](\d{1,4}),?[ \x{a0}](\d{1,2})[:.](\d{2})(?:[:.](\d{2}))?` +
+		`(?:[ \x{a0}\x{202f}]?([AaPp])\.?[ \x{a0}\x{202f}]?[Mm]\.?)?(?:\] | - )(.*)$`,
+)
+
+// attachedIOS and attachedAndroid match an attachment: "<attached:
+// NAME>" on iOS, "NAME (file attached)" on Android
+var (
+	attachedIOS     = regexp.MustCompile(`^<attached: ([^>]+)>(.*)$`)
+	attachedAndroid = regexp.MustCompile(`^(.+?) \(file attached\)$`)
+)
+
+// directionMarks are the invisible marks WhatsApp puts before notices and attachments
+const directionMarks = "\u200e\u200f\ufeff"
+
+// rawMessage is a message whose date is not read yet: its order depends
+// on the whole transcript
+type rawMessage struct {
+	date   [3]int // as written
+	hour   int    // 0-23
+	minute int
+	second int
+	sender string
+	lines  []string
+}
+
+/*
+ParseWhatsApp reads a WhatsApp transcript, its times in loc. It returns
+ErrNotWhatsApp when no line starts a message.
+*/
+func ParseWhatsApp(r io.Reader, loc *time.Location) (*Chat, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineLength)
+
+	var raws []rawMessage
+	notice := false // the lines continue a notice, left out with it
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		match := headerPattern.FindStringSubmatch(strings.TrimLeft(line, directionMarks))
+		if match == nil {
+			if len(raws) > 0 && !notice {
+				last := &raws[len(raws)-1]
+				last.lines = append(last.lines, line)
+			}
+			continue
+		}
+
+		rest := strings.TrimLeft(match[8], directionMarks)
+		sender, text, ok := strings.Cut(rest, ": ")
+		if !ok || sender == "" || isNotice(text) {
+			notice = true
+			continue
+		}
+		notice = false
+
+		raw := rawMessage{sender: strings.TrimSpace(strings.Trim(sender, directionMarks)), lines: []string{text}}
+		for i := range raw.date {
+			raw.date[i], _ = strconv.Atoi(match[1+i])
+		}
+		raw.hour, _ = strconv.Atoi(match[4])
+		raw.minute, _ = strconv.Atoi(match[5])
+		if match[6] != "" {
+			raw.second, _ = strconv.Atoi(match[6])
+		}
+		switch strings.ToLower(match[7]) {
+		case "a":
+			raw.hour %= 12
+		case "p":
+			raw.hour = raw.hour%12 + 12
+		}
+		raws = append(raws, raw)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(raws) == 0 {
+		return nil, ErrNotWhatsApp
+	}
+
+	dayFirst := guessDayFirst(raws)
+	chat := Chat{Messages: make([]Message, 0, len(raws))}
+	for _, raw := range raws {
+		t, err := raw.time(dayFirst, loc)
+		if err != nil {
+			return nil, err
+		}
+		chat.Messages = append(chat.Messages, raw.message(t))
+	}
+	return &chat, nil
+}
+
+// isNotice tells whether the text of an iOS message is a notice: it
+// starts with a left-to-right mark, and is no attachment (newer Android
+// exports mark theirs too)
+func isNotice(text string) bool {
+	rest, marked := strings.CutPrefix(text, "\u200e")
+	if !marked {
+		return false
+	}
+	return !attachedIOS.MatchString(rest) && !attachedAndroid.MatchString(rest) && !strings.HasSuffix(rest, " omitted")
+}
+
+// guessDayFirst tells whether the dates put the day before the month: a
+// first number over 12 is a day, a second one is
+func guessDayFirst(raws []rawMessage) bool {
+	for _, raw := range raws {
+		if raw.date[0] > 31 {
+			continue // year first
+		}
+		if raw.date[0] > 12 {
+			return true
+		}
+		if raw.date[1] > 12 {
+			return false
+		}
+	}
+	return true
+}
+
+// time reads the date and time of a message
+func (raw rawMessage) time(dayFirst bool, loc *time.Location) (time.Time, error) {
+	var year, month, day int
+	switch {
+	case raw.date[0] > 31:
+		year, month, day = raw.date[0], raw.date[1], raw.date[2]
+	case dayFirst:
+		day, month, year = raw.date[0], raw.date[1], raw.date[2]
+	default:
+		month, day, year = raw.date[0], raw.date[1], raw.date[2]
+	}
+	if year < 100 {
+		year += 2000
+	}
+
+	t := time.Date(year, time.Month(month), day, raw.hour, raw.minute, raw.second, 0, loc)
+	if t.Month() != time.Month(month) || t.Day() != day || raw.hour > 23 || raw.minute > 59 || raw.second > 59 {
+		return time.Time{}, fmt.Errorf("chatimport: invalid date %d/%d/%d %02d:%02d", raw.date[0], raw.date[1], raw.date[2], raw.hour, raw.minute)
+	}
+	return t, nil
+}
+
+// message returns the message, its attachment apart from its text
+func (raw rawMessage) message(t time.Time) Message {
+	m := Message{Time: t, Sender: raw.sender}
+	first := strings.TrimLeft(raw.lines[0], directionMarks)
+	if match := attachedIOS.FindStringSubmatch(first); match != nil {
+		m.Attachment = match[1]
+		raw.lines[0] = strings.TrimSpace(match[2])
+	} else if match := attachedAndroid.FindStringSubmatch(first); match != nil {
+		m.Attachment = match[1]
+		raw.lines[0] = ""
+	}
+	m.Text = strings.TrimSpace(strings.Join(raw.lines, "\n"))
+	return m
+}
+
+// Export is a WhatsApp export: the transcript and the attached media
+type Export struct {
+	Chat  *Chat
+	media map[string]*zip.File // by file name
+}
+
+/*
+OpenWhatsApp reads a WhatsApp export, a zip of size bytes, its times in
+loc. The transcript is _chat.txt or "WhatsApp Chat with <name>.txt", or
+else the only text file of the zip. It returns ErrNotWhatsApp when the
+zip has none.
+*/
+func OpenWhatsApp(r io.ReaderAt, size int64, loc *time.Location) (*Export, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotWhatsApp, err)
+	}
+
+	export := Export{media: map[string]*zip.File{}}
+	var transcript *zip.File
+	var texts []*zip.File
+	for _, f := range archive.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		name := path.Base(f.Name)
+		export.media[name] = f
+		switch {
+		case name == "_chat.txt" || chatName(name) != "":
+			transcript = f
+		case strings.HasSuffix(name, ".txt"):
+			texts = append(texts, f)
+		}
+	}
+	if transcript == nil && len(texts) == 1 {
+		transcript = texts[0]
+	}
+	if transcript == nil {
+		return nil, ErrNotWhatsApp
+	}
+	delete(export.media, path.Base(transcript.Name))
+
+	file, err := transcript.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if export.Chat, err = ParseWhatsApp(file, loc); err != nil {
+		return nil, err
+	}
+	export.Chat.Name = chatName(path.Base(transcript.Name))
+	return &export, nil
+}
+
+// chatName returns the name of the chat in the file name of an Android
+// transcript ("WhatsApp Chat with Family.txt"), "" for any other
+func chatName(file string) string {
+	name, ok := strings.CutPrefix(strings.TrimSuffix(file, ".txt"), "WhatsApp Chat with ")
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(name)
+}
+
+// Media returns an attached file, or ErrMediaNotFound; a file over
+// maxSize bytes is an error as well
+func (e *Export) Media(name string, maxSize int64) ([]byte, error) {
+	f, ok := e.media[name]
+	if !ok {
+		return nil, ErrMediaNotFound
+	}
+	if f.UncompressedSize64 > uint64(maxSize) {
+		return nil, fmt.Errorf("chatimport: %s is over %d bytes", name, maxSize)
+	}
+	file, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("chatimport: %s is over %d bytes", name, maxSize)
+	}
+	return data, nil
+}
@@ -0,0 +1,253 @@
+package chatimport
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testLocation = time.FixedZone("CET", 60*60)
+
+// at is a time of testLocation
+func at(year int, month time.Month, day, hour, minute, second int) time.Time {
+	return time.Date(year, month, day, hour, minute, second, 0, testLocation)
+}
+
+func TestParseWhatsApp(t *testing.T) {
+	christmas := at(2023, time.December, 25, 18, 4, 0)
+	tests := []struct {
+		name       string
+		transcript string
+		want       []Message
+	}{
+		{
+			name:       "iOS, seconds",
+			transcript: "[25/12/2023, 18:04:51] Maria: Merry Christmas!\n",
+			want:       []Message{{Time: at(2023, time.December, 25, 18, 4, 51), Sender: "Maria", Text: "Merry Christmas!"}},
+		},
+		{
+			name:       "Android, two-digit year",
+			transcript: "25/12/23, 18:04 - Maria: Merry Christmas!\n",
+			want:       []Message{{Time: christmas, Sender: "Maria", Text: "Merry Christmas!"}},
+		},
+		{
+			name:       "Android, month first and 12-hour clock",
+			transcript: "12/25/23, 6:04 PM - Maria: Merry Christmas!\n",
+			want:       []Message{{Time: christmas, Sender: "Maria", Text: "Merry Christmas!"}},
+		},
+		{
+			name:       "iOS, narrow no-break space before PM",
+			transcript: "[12/25/23, 6:04:00\u202fPM] Maria: Merry Christmas!\n",
+			want:       []Message{{Time: christmas, Sender: "Maria", Text: "Merry Christmas!"}},
+		},
+		{
+			name:       "dotted a.m. and p.m.",
+			transcript: "25/12/2023, 12:30 a.m. - Maria: Late\n25/12/2023, 12:30 p.m. - Luca: Lunch\n",
+			want: []Message{
+				{Time: at(2023, time.December, 25, 0, 30, 0), Sender: "Maria", Text: "Late"},
+				{Time: at(2023, time.December, 25, 12, 30, 0), Sender: "Luca", Text: "Lunch"},
+			},
+		},
+		{
+			name:       "dots, day first",
+			transcript: "25.12.23, 18:04 - Maria: Frohe Weihnachten\n",
+			want:       []Message{{Time: christmas, Sender: "Maria", Text: "Frohe Weihnachten"}},
+		},
+		{
+			name:       "year first",
+			transcript: "2023-12-25, 18:04 - Maria: Merry Christmas!\n",
+			want:       []Message{{Time: christmas, Sender: "Maria", Text: "Merry Christmas!"}},
+		},
+		{
+			name:       "order told by a later date",
+			transcript: "01/02/2024, 10:00 - Maria: First\n01/13/2024, 10:00 - Maria: Second\n",
+			want: []Message{
+				{Time: at(2024, time.January, 2, 10, 0, 0), Sender: "Maria", Text: "First"},
+				{Time: at(2024, time.January, 13, 10, 0, 0), Sender: "Maria", Text: "Second"},
+			},
+		},
+		{
+			name:       "ambiguous order, day first",
+			transcript: "01/02/2024, 10:00 - Maria: First\n",
+			want:       []Message{{Time: at(2024, time.February, 1, 10, 0, 0), Sender: "Maria", Text: "First"}},
+		},
+		{
+			name: "multi-line message",
+			transcript: "25/12/23, 18:04 - Maria: Shopping list:\r\n" +
+				"- eggs\r\n" +
+				"\r\n" +
+				"Note: 12:30 is too late\r\n" +
+				"25/12/23, 18:05 - Luca: Ok\r\n",
+			want: []Message{
+				{Time: christmas, Sender: "Maria", Text: "Shopping list:\n- eggs\n\nNote: 12:30 is too late"},
+				{Time: at(2023, time.December, 25, 18, 5, 0), Sender: "Luca", Text: "Ok"},
+			},
+		},
+		{
+			name: "Android notices",
+			transcript: "25/12/23, 18:00 - Messages and calls are end-to-end encrypted. Tap to learn more.\n" +
+				"25/12/23, 18:01 - Maria created group \"Family\"\n" +
+				"25/12/23, 18:02 - Maria added Luca\n" +
+				"25/12/23, 18:04 - Maria: Merry Christmas!\n",
+			want: []Message{{Time: christmas, Sender: "Maria", Text: "Merry Christmas!"}},
+		},
+		{
+			name: "iOS notices and their lines",
+			transcript: "[25/12/2023, 18:00:00] Family: \u200eMessages and calls are end-to-end encrypted.\n" +
+				"No one outside of this chat can read them.\n" +
+				"\u200e[25/12/2023, 18:01:00] Family: \u200eMaria added Luca\n" +
+				"[25/12/2023, 18:04:00] Maria: Merry Christmas!\n",
+			want: []Message{{Time: christmas, Sender: "Maria", Text: "Merry Christmas!"}},
+		},
+		{
+			name: "iOS attachments",
+			transcript: "\u200e[25/12/2023, 18:04:00] Maria: \u200e<attached: 00000012-PHOTO-2023-12-25-18-04-00.jpg>\n" +
+				"[25/12/2023, 18:05:00] Luca: \u200e<attached: 00000013-AUDIO-2023-12-25-18-05-00.opus> Listen\n",
+			want: []Message{
+				{Time: christmas, Sender: "Maria", Attachment: "00000012-PHOTO-2023-12-25-18-04-00.jpg"},
+				{Time: at(2023, time.December, 25, 18, 5, 0), Sender: "Luca", Text: "Listen", Attachment: "00000013-AUDIO-2023-12-25-18-05-00.opus"},
+			},
+		},
+		{
+			name: "Android attachments, with a caption on the next line",
+			transcript: "25/12/23, 18:04 - Maria: \u200eIMG-20231225-WA0001.jpg (file attached)\n" +
+				"The tree\n" +
+				"25/12/23, 18:05 - Luca: Family photo.pdf (file attached)\n",
+			want: []Message{
+				{Time: christmas, Sender: "Maria", Text: "The tree", Attachment: "IMG-20231225-WA0001.jpg"},
+				{Time: at(2023, time.December, 25, 18, 5, 0), Sender: "Luca", Attachment: "Family photo.pdf"},
+			},
+		},
+		{
+			name:       "sender with a phone number",
+			transcript: "25/12/23, 18:04 - +39 333 123 4567: Who is this?\n",
+			want:       []Message{{Time: christmas, Sender: "+39 333 123 4567", Text: "Who is this?"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chat, err := ParseWhatsApp(strings.NewReader(tt.transcript), testLocation)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(chat.Messages, tt.want) {
+				t.Errorf("got\n%+v\nwant\n%+v", chat.Messages, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWhatsAppErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		transcript string
+		want       error // nil for any error
+	}{
+		{"empty", "", ErrNotWhatsApp},
+		{"no message", "Just some notes\nwith no dates\n", ErrNotWhatsApp},
+		{"only notices", "25/12/23, 18:00 - Messages and calls are end-to-end encrypted.\n", ErrNotWhatsApp},
+		{"no such day", "30/02/2024, 10:00 - Maria: Hi\n", nil},
+		{"no such hour", "25/12/2023, 25:00 - Maria: Hi\n", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseWhatsApp(strings.NewReader(tt.transcript), testLocation)
+			if err == nil || (tt.want != nil && !errors.Is(err, tt.want)) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSenders(t *testing.T) {
+	chat, err := ParseWhatsApp(strings.NewReader(
+		"25/12/23, 18:04 - Maria: Hi\n25/12/23, 18:05 - Luca: Hi\n25/12/23, 18:06 - Maria: Dinner?\n25/12/23, 18:07 - Anna: Yes\n",
+	), testLocation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := chat.Senders(), []string{"Maria", "Luca", "Anna"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// testZip returns a zip of the files, by name
+func testZip(t *testing.T, files map[string]string) *bytes.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestOpenWhatsApp(t *testing.T) {
+	r := testZip(t, map[string]string{
+		"WhatsApp Chat with Family.txt": "25/12/23, 18:04 - Maria: IMG-20231225-WA0001.jpg (file attached)\n",
+		"IMG-20231225-WA0001.jpg":       "jpeg data",
+	})
+	export, err := OpenWhatsApp(r, r.Size(), testLocation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if export.Chat.Name != "Family" || len(export.Chat.Messages) != 1 {
+		t.Fatalf("got chat %q with %d messages", export.Chat.Name, len(export.Chat.Messages))
+	}
+
+	data, err := export.Media(export.Chat.Messages[0].Attachment, 1<<20)
+	if err != nil || string(data) != "jpeg data" {
+		t.Errorf("got %q %v, want the photo", data, err)
+	}
+	if _, err := export.Media("IMG-20231225-WA0001.jpg", 4); err == nil {
+		t.Error("a file over the size was read")
+	}
+	if _, err := export.Media("WhatsApp Chat with Family.txt", 1<<20); !errors.Is(err, ErrMediaNotFound) {
+		t.Errorf("the transcript: got %v, want ErrMediaNotFound", err)
+	}
+}
+
+func TestOpenWhatsAppTranscript(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		chat  string // the name of the chat
+		err   error
+	}{
+		{"iOS", map[string]string{"_chat.txt": "[25/12/2023, 18:04:51] Maria: Hi\n", "notes.txt": "x"}, "", nil},
+		{"the only text file", map[string]string{"chat.txt": "25/12/23, 18:04 - Maria: Hi\n", "a.jpg": "x"}, "", nil},
+		{"in a folder", map[string]string{"Family/WhatsApp Chat with Family.txt": "25/12/23, 18:04 - Maria: Hi\n"}, "Family", nil},
+		{"no transcript", map[string]string{"a.jpg": "x"}, "", ErrNotWhatsApp},
+		{"two text files", map[string]string{"a.txt": "x", "b.txt": "y"}, "", ErrNotWhatsApp},
+		{"not a transcript", map[string]string{"_chat.txt": "hello"}, "", ErrNotWhatsApp},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := testZip(t, tt.files)
+			export, err := OpenWhatsApp(r, r.Size(), testLocation)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+			if err == nil && export.Chat.Name != tt.chat {
+				t.Errorf("chat %q, want %q", export.Chat.Name, tt.chat)
+			}
+		})
+	}
+
+	if _, err := OpenWhatsApp(strings.NewReader("not a zip"), 9, testLocation); !errors.Is(err, ErrNotWhatsApp) {
+		t.Errorf("not a zip: got %v, want ErrNotWhatsApp", err)
+	}
+}
@@ -0,0 +1,164 @@
+/*
+Database operations for the API usage analytics.
+
+The API samples the requests of the users (see service/api/apiusage.go)
+and adds them here in batches: one row per user, day (UTC) and route,
+the method and the route template without the IDs. The counts are
+estimates, as each sampled request counts for the sampling rate. Rows
+older than the retention are deleted as new ones are added.
+*/
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+
+	"wasatext/service/ids"
+)
+
+// APIUsageKey is the row a sampled request is counted in
+type APIUsageKey struct {
+	UserID ids.UserID
+	Day    string // YYYY-MM-DD, UTC
+	Method string
+	Route  string // the route template, e.g. /conversations/{conversationId}
+}
+
+// APIUsage is the estimated requests of a user to a route on a day
+type APIUsage struct {
+	APIUsageKey
+	Requests int
+}
+
+// APIUsageTotal is the estimated requests of all users to a route
+type APIUsageTotal struct {
+	Method   string
+	Route    string
+	Requests int
+	Users    int // users who called it
+}
+
+// APIUser is a user and their estimated requests
+type APIUser struct {
+	UserID   ids.UserID
+	UserName string
+	Requests int
+}
+
+/*
+RecordAPIUsage adds a batch of request counts and deletes the rows of
+the days before oldestDay (YYYY-MM-DD, "" to keep them all), in one
+transaction.
+*/
+func (db *appdbimpl) RecordAPIUsage(ctx context.Context, counts map[APIUsageKey]int, oldestDay string) error {
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+	}()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO api_usage (user_id, day, method, route, requests)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (user_id, day, method, route) DO UPDATE SET
+			requests = requests + excluded.requests
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for key, requests := range counts {
+		if _, err := stmt.ExecContext(ctx, key.UserID, key.Day, key.Method, key.Route, requests); err != nil {
+			return err
+		}
+	}
+
+	if oldestDay != "" {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM api_usage WHERE day < ?", oldestDay); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetAPIUsage returns the rows of a user since a day (YYYY-MM-DD), by
+// day then route
+func (db *appdbimpl) GetAPIUsage(ctx context.Context, userID ids.UserID, since string) ([]APIUsage, error) {
+	rows, err := db.db.QueryContext(ctx, `
+		SELECT day, method, route, requests FROM api_usage
+		WHERE user_id = ? AND day >= ?
+		ORDER BY day, route, method
+	`, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usage := []APIUsage{}
+	for rows.Next() {
+		row := APIUsage{APIUsageKey: APIUsageKey{UserID: userID}}
+		if err := rows.Scan(&row.Day, &row.Method, &row.Route, &row.Requests); err != nil {
+			return nil, err
+		}
+		usage = append(usage, row)
+	}
+	return usage, rows.Err()
+}
+
+// GetAPIUsageTotals returns the requests of all users to each route
+// since a day, the most requested first
+func (db *appdbimpl) GetAPIUsageTotals(ctx context.Context, since string) ([]APIUsageTotal, error) {
+	rows, err := db.db.QueryContext(ctx, `
+		SELECT method, route, SUM(requests), COUNT(DISTINCT user_id) FROM api_usage
+		WHERE day >= ?
+		GROUP BY method, route
+		ORDER BY SUM(requests) DESC, route, method
+	`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := []APIUsageTotal{}
+	for rows.Next() {
+		var total APIUsageTotal
+		if err := rows.Scan(&total.Method, &total.Route, &total.Requests, &total.Users); err != nil {
+			return nil, err
+		}
+		totals = append(totals, total)
+	}
+	return totals, rows.Err()
+}
+
+// GetTopAPIUsers returns the users who sent the most requests since a
+// day, at most limit of them
+func (db *appdbimpl) GetTopAPIUsers(ctx context.Context, since string, limit int) ([]APIUser, error) {
+	rows, err := db.db.QueryContext(ctx, `
+		SELECT a.user_id, COALESCE(u.name, ''), SUM(a.requests) FROM api_usage a
+		LEFT JOIN users u ON u.id = a.user_id
+		WHERE a.day >= ?
+		GROUP BY a.user_id
+		ORDER BY SUM(a.requests) DESC, a.user_id
+		LIMIT ?
+	`, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []APIUser{}
+	for rows.Next() {
+		var user APIUser
+		if err := rows.Scan(&user.UserID, &user.UserName, &user.Requests); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
@@ -0,0 +1,176 @@
+/*
+Benchmarks of the hot paths: listing the conversations of a user who has
+a thousand of them, opening a conversation of ten thousand messages, and
+sending a message. They share a database seeded once, on the first
+benchmark run. Pull requests that claim a performance change include a
+comparison of them on main and on the branch:
+
+	go test -tags sqlite_fts5 -run '^$' -bench . -count 6 ./service/database > old.txt   # on main
+	go test -tags sqlite_fts5 -run '^$' -bench . -count 6 ./service/database > new.txt   # on the branch
+	benchstat old.txt new.txt
+*/
+package database
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"wasatext/service/ids"
+	"wasatext/service/storage"
+)
+
+// Size of the seeded data
+const (
+	seedConversations = 1000  // conversations of the busy user
+	seedMessages      = 10000 // messages in the long conversation
+)
+
+// benchFixtures is the seeded database and the IDs the benchmarks use
+type benchFixtures struct {
+	db               AppDatabase
+	busyUser         ids.UserID         // has seedConversations conversations
+	longConversation ids.ConversationID // has seedMessages messages
+	sendConversation ids.ConversationID // where the messages are sent
+}
+
+var (
+	benchOnce sync.Once
+	benchDir  string // removed by TestMain
+	bench     benchFixtures
+	benchErr  error
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if bench.db != nil {
+		_ = bench.db.Close()
+	}
+	if benchDir != "" {
+		if err := os.RemoveAll(benchDir); err != nil {
+			log.Printf("Error removing %s: %v", benchDir, err)
+		}
+	}
+	os.Exit(code)
+}
+
+// benchDatabase returns the seeded database, seeding it on the first call
+func benchDatabase(b *testing.B) benchFixtures {
+	b.Helper()
+	benchOnce.Do(func() {
+		benchDir, benchErr = os.MkdirTemp("", "wasatext-benchmark")
+		if benchErr == nil {
+			bench, benchErr = seedBenchmark(benchDir)
+		}
+	})
+	if benchErr != nil {
+		b.Fatalf("seeding: %v", benchErr)
+	}
+	return bench
+}
+
+/*
+seedBenchmark fills a new database with the benchmark data.
+
+Durability does not matter here, so the seeding connection turns off
+synchronous writes; the benchmarks reopen the file with the same
+settings as the server.
+*/
+func seedBenchmark(dir string) (benchFixtures, error) {
+	var fx benchFixtures
+	ctx := context.Background()
+	dbPath := filepath.Join(dir, "benchmark.db")
+	blobs, err := storage.NewFileStore(filepath.Join(dir, "media"))
+	if err != nil {
+		return fx, err
+	}
+
+	db, err := New(dbPath+"?_synchronous=OFF", blobs)
+	if err != nil {
+		return fx, err
+	}
+	fx.busyUser, err = db.CreateUser(ctx, DefaultWorkspaceID, "busy")
+	if err != nil {
+		return fx, err
+	}
+
+	// One conversation with a single message per contact
+	var longContact ids.UserID
+	for i := 0; i < seedConversations; i++ {
+		contact, err := db.CreateUser(ctx, DefaultWorkspaceID, "contact"+strconv.Itoa(i))
+		if err != nil {
+			return fx, err
+		}
+		conversationID, err := db.GetOrCreateDirectConversation(ctx, fx.busyUser, contact)
+		if err != nil {
+			return fx, err
+		}
+		if _, err := db.CreateMessage(ctx, conversationID, contact, "hello", nil, nil); err != nil {
+			return fx, err
+		}
+
+		switch i {
+		case 0:
+			fx.longConversation, longContact = conversationID, contact
+		case 1:
+			fx.sendConversation = conversationID
+		}
+	}
+
+	// A long history, with both sides talking
+	for i := 1; i < seedMessages; i++ {
+		sender := fx.busyUser
+		if i%2 == 0 {
+			sender = longContact
+		}
+		if _, err := db.CreateMessage(ctx, fx.longConversation, sender, "message "+strconv.Itoa(i), nil, nil); err != nil {
+			return fx, err
+		}
+	}
+	if err := db.Close(); err != nil {
+		return fx, err
+	}
+
+	fx.db, err = New(dbPath, blobs)
+	return fx, err
+}
+
+func BenchmarkGetConversations1k(b *testing.B) {
+	fx := benchDatabase(b)
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := fx.db.GetConversations(ctx, fx.busyUser); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetConversation10k(b *testing.B) {
+	fx := benchDatabase(b)
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := fx.db.GetConversation(ctx, fx.busyUser, fx.longConversation); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSendMessage(b *testing.B) {
+	fx := benchDatabase(b)
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := fx.db.CreateMessage(ctx, fx.sendConversation, fx.busyUser, "benchmark message "+strconv.Itoa(i), nil, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+/*
+Database operations for blocked users.
+
+A user can block any other user of their workspace. The blocked user can
+then neither start a direct conversation with them nor send messages in
+the one they already share (checkCanPost); groups are not affected.
+Blocking is one way and silent: the blocked user is not told, only
+refused.
+*/
+package database
+
+import (
+	"context"
+	"time"
+
+	"wasatext/service/ids"
+)
+
+// BlockUser blocks another user of the user's workspace; blocking a
+// user twice is not an error
+func (db *appdbimpl) BlockUser(ctx context.Context, userID, blockedID ids.UserID) error {
+	if userID == blockedID {
+		return ErrCannotBlockSelf
+	}
+	user, err := db.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	blocked, err := db.GetUserByID(ctx, blockedID)
+	if err != nil {
+		return err
+	}
+	if user.WorkspaceID != blocked.WorkspaceID {
+		return withID(ErrUserNotFound, blockedID)
+	}
+
+	_, err = db.db.ExecContext(ctx,
+		"INSERT OR IGNORE INTO user_blocks (blocker_id, blocked_id, created_at) VALUES (?, ?, ?)",
+		userID, blockedID, time.Now(),
+	)
+	return err
+}
+
+// UnblockUser lifts a block; unblocking a user who is not blocked is
+// not an error
+func (db *appdbimpl) UnblockUser(ctx context.Context, userID, blockedID ids.UserID) error {
+	_, err := db.db.ExecContext(ctx, "DELETE FROM user_blocks WHERE blocker_id = ? AND blocked_id = ?", userID, blockedID)
+	return err
+}
+
+// hasBlocked tells whether blockerID blocked blockedID
+func hasBlocked(ctx context.Context, q queryRower, blockerID, blockedID ids.UserID) (bool, error) {
+	var blocked bool
+	err := q.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM user_blocks WHERE blocker_id = ? AND blocked_id = ?)",
+		blockerID, blockedID,
+	).Scan(&blocked)
+	return blocked, err
+}
+
+// blockedInConversation tells whether the other user of a direct
+// conversation blocked userID
+func blockedInConversation(ctx context.Context, q queryRower, conversationID ids.ConversationID, userID ids.UserID) (bool, error) {
+	var blocked bool
+	err := q.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM user_blocks b
+			JOIN conversation_participants cp ON cp.user_id = b.blocker_id
+			JOIN conversations c ON c.id = cp.conversation_id
+			WHERE cp.conversation_id = ? AND c.is_group = 0 AND b.blocked_id = ?
+		)
+	`, conversationID, userID).Scan(&blocked)
+	return blocked, err
+}
@@ -0,0 +1,318 @@
+/*
+Database operations for bot accounts.
+
+A bot is an account of a workspace run by a program (a reminder bot, a
+bridge to another chat, ...) rather than a person: the admin of the
+server creates it, and users add it to their groups or start a
+conversation with it like with anyone. It cannot log in: it
+authenticates with its own token, which only works for the bot API, and
+is stored hashed like the session tokens. It is told of the events of
+its conversations through a webhook of its own (see webhooks.go).
+*/
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"wasatext/service/ids"
+)
+
+// botTokenPrefix marks bot tokens, like the session and hook tokens
+const botTokenPrefix = "bot-"
+
+// Bot is a bot account
+type Bot struct {
+	ID          ids.UserID
+	WorkspaceID string
+	Name        string
+	Token       string // only when created or its token is reset
+	CreatedAt   time.Time
+	WebhookURL  string // "" when it has no webhook
+}
+
+// IsBotToken reports whether a bearer token is a bot token
+func IsBotToken(token string) bool {
+	return len(token) > len(botTokenPrefix) && token[:len(botTokenPrefix)] == botTokenPrefix
+}
+
+// newBotToken returns a new bot token; bot tokens are bearer
+// credentials, so they must not be guessable
+func newBotToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return botTokenPrefix + hex.EncodeToString(buf), nil
+}
+
+// CreateBot creates a bot account in a workspace and returns it with its token
+func (db *appdbimpl) CreateBot(ctx context.Context, workspaceID, name string) (*Bot, error) {
+	if _, err := db.GetWorkspace(ctx, workspaceID); err != nil {
+		return nil, err
+	}
+	token, err := newBotToken()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+	}()
+
+	var taken bool
+	err = tx.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM users WHERE workspace_id = ? AND name = ?)",
+		workspaceID, name,
+	).Scan(&taken)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, withID(ErrUsernameTaken, name)
+	}
+
+	id, err := newUserID(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	bot := Bot{ID: id, WorkspaceID: workspaceID, Name: name, Token: token, CreatedAt: time.Now()}
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO users (id, workspace_id, name, created_at) VALUES (?, ?, ?, ?)",
+		id, workspaceID, name, bot.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO bots (user_id, token_hash, created_at) VALUES (?, ?, ?)",
+		id, hashSessionToken(token), bot.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &bot, nil
+}
+
+// ListBots returns the bots of every workspace, the oldest first
+func (db *appdbimpl) ListBots(ctx context.Context) ([]Bot, error) {
+	rows, err := db.db.QueryContext(ctx, `
+		SELECT u.id, u.workspace_id, u.name, b.created_at, COALESCE(w.url, '')
+		FROM bots b
+		JOIN users u ON u.id = b.user_id
+		LEFT JOIN outbound_webhooks w ON w.bot_id = b.user_id
+		WHERE u.purged_at IS NULL
+		ORDER BY b.created_at, u.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bots []Bot
+	for rows.Next() {
+		var b Bot
+		if err := rows.Scan(&b.ID, &b.WorkspaceID, &b.Name, &b.CreatedAt, &b.WebhookURL); err != nil {
+			return nil, err
+		}
+		bots = append(bots, b)
+	}
+	return bots, rows.Err()
+}
+
+/*
+DeleteBot deletes a bot: its account is deleted like a user's (see
+DeleteUser), its token stops working and its webhook is removed with
+its deliveries.
+*/
+func (db *appdbimpl) DeleteBot(ctx context.Context, botID ids.UserID) error {
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+	}()
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM bots WHERE user_id = ?", botID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return withID(ErrBotNotFound, botID)
+	}
+	if err := deleteBotWebhook(ctx, tx, botID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE users SET purged_at = ? WHERE id = ? AND purged_at IS NULL", time.Now(), botID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ResetBotToken gives a bot a new token, and returns it; the old one
+// stops working at once
+func (db *appdbimpl) ResetBotToken(ctx context.Context, botID ids.UserID) (string, error) {
+	token, err := newBotToken()
+	if err != nil {
+		return "", err
+	}
+	result, err := db.db.ExecContext(ctx, "UPDATE bots SET token_hash = ? WHERE user_id = ?", hashSessionToken(token), botID)
+	if err != nil {
+		return "", err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return "", err
+	}
+	if rowsAffected == 0 {
+		return "", withID(ErrBotNotFound, botID)
+	}
+	return token, nil
+}
+
+// GetBotByToken returns the bot a token belongs to; unknown tokens and
+// the tokens of banned bots give ErrBotNotFound
+func (db *appdbimpl) GetBotByToken(ctx context.Context, token string) (ids.UserID, error) {
+	if !IsBotToken(token) {
+		return "", ErrBotNotFound
+	}
+	var botID ids.UserID
+	err := db.db.QueryRowContext(ctx, `
+		SELECT b.user_id FROM bots b
+		JOIN users u ON u.id = b.user_id
+		WHERE b.token_hash = ? AND u.purged_at IS NULL AND u.banned_at IS NULL
+	`, hashSessionToken(token)).Scan(&botID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrBotNotFound
+	}
+	return botID, err
+}
+
+// SetBotWebhook gives a bot a webhook, replacing the one it had with its
+// deliveries, and returns it with its secret
+func (db *appdbimpl) SetBotWebhook(ctx context.Context, botID ids.UserID, url string, events []string) (*Webhook, error) {
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+	}()
+
+	if err := deleteBotWebhook(ctx, tx, botID); err != nil {
+		return nil, err
+	}
+	webhook := Webhook{
+		BotID:     botID,
+		URL:       url,
+		Secret:    secret,
+		Events:    events,
+		CreatedBy: botID,
+		CreatedAt: time.Now(),
+	}
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO outbound_webhooks (bot_id, url, secret, events, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, botID, url, secret, strings.Join(events, " "), botID, webhook.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if webhook.ID, err = result.LastInsertId(); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// GetBotWebhook returns the webhook of a bot, or ErrWebhookNotFound
+func (db *appdbimpl) GetBotWebhook(ctx context.Context, botID ids.UserID) (*Webhook, error) {
+	var w Webhook
+	var events string
+	err := db.db.QueryRowContext(ctx,
+		"SELECT id, bot_id, url, secret, events, created_at FROM outbound_webhooks WHERE bot_id = ?",
+		botID,
+	).Scan(&w.ID, &w.BotID, &w.URL, &w.Secret, &events, &w.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, withID(ErrWebhookNotFound, botID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	w.Events = strings.Fields(events)
+	w.CreatedBy = botID
+	return &w, nil
+}
+
+// DeleteBotWebhook removes the webhook of a bot, with its deliveries
+func (db *appdbimpl) DeleteBotWebhook(ctx context.Context, botID ids.UserID) error {
+	if _, err := db.GetBotWebhook(ctx, botID); err != nil {
+		return err
+	}
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+	}()
+	if err := deleteBotWebhook(ctx, tx, botID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetBotWebhookDeliveries returns the last deliveries of the webhook of
+// a bot, the most recent first
+func (db *appdbimpl) GetBotWebhookDeliveries(ctx context.Context, botID ids.UserID, limit int) ([]WebhookDelivery, error) {
+	webhook, err := db.GetBotWebhook(ctx, botID)
+	if err != nil {
+		return nil, err
+	}
+	return db.webhookLog(ctx, webhook.ID, limit)
+}
+
+// deleteBotWebhook removes the webhook of a bot, if any, with its deliveries
+func deleteBotWebhook(ctx context.Context, tx *sql.Tx, botID ids.UserID) error {
+	_, err := tx.ExecContext(ctx,
+		"DELETE FROM webhook_deliveries WHERE webhook_id IN (SELECT id FROM outbound_webhooks WHERE bot_id = ?)",
+		botID,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM outbound_webhooks WHERE bot_id = ?", botID)
+	return err
+}
@@ -0,0 +1,241 @@
+/*
+Database operations for channels.
+
+A channel is a group with another kind: its history, members and photo
+are those of the group, only the permissions change. In a group every
+member posts and adds others; in a channel only the group admin (its
+creator) posts and adds subscribers, and anyone in the workspace can
+join on their own. Turning a group into a channel and back keeps
+everything and leaves a system message in the conversation.
+
+The admin can also publish a channel as an Atom feed, readable by
+anyone who has its URL; the feed is off until the admin turns it on.
+*/
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"wasatext/service/ids"
+)
+
+// Kinds of group
+const (
+	GroupKindGroup   = "group"
+	GroupKindChannel = "channel"
+)
+
+// Channel is a public channel as listed to the users of its workspace
+type Channel struct {
+	ID          ids.GroupID
+	Name        string
+	PhotoID     string
+	Subscribers int
+	Joined      bool // the user listing the channels is a subscriber
+	PublicFeed  bool // the latest messages are published as an Atom feed
+}
+
+// ChannelFeed is what the Atom feed of a channel shows
+type ChannelFeed struct {
+	ID       ids.GroupID
+	Name     string
+	Messages []Message // newest first
+}
+
+/*
+SetGroupKind turns a group into a channel or a channel into a group.
+The system message with the notice is sent by actorID, or by the group
+admin when an admin of the server (actorID "") makes the change. It is
+returned, or nil when the group already had that kind.
+*/
+func (db *appdbimpl) SetGroupKind(ctx context.Context, groupID ids.GroupID, kind string, actorID ids.UserID, notice string) (*Message, error) {
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+	}()
+
+	// Step 1: Get the group, its conversation and its admin
+	var current string
+	var conversationID ids.ConversationID
+	var adminID sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		SELECT g.kind, c.id, c.created_by
+		FROM groups g
+		JOIN conversations c ON c.group_id = g.id AND c.is_group = 1
+		WHERE g.id = ?
+	`, groupID).Scan(&current, &conversationID, &adminID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, withID(ErrGroupNotFound, groupID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if current == kind {
+		return nil, nil
+	}
+
+	// Step 2: A channel is run by the group admin
+	if !adminID.Valid || adminID.String == "" {
+		return nil, withID(ErrChannelNeedsAdmin, groupID)
+	}
+	senderID := actorID
+	if senderID == "" {
+		senderID = ids.UserID(adminID.String)
+	}
+
+	// Step 3: Change the kind
+	if _, err := tx.ExecContext(ctx, "UPDATE groups SET kind = ? WHERE id = ?", kind, groupID); err != nil {
+		return nil, err
+	}
+
+	// Step 4: Tell the members, like any other message
+	id, err := newMessageID(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	timestamp := time.Now()
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO messages (id, conversation_id, sender_id, content, timestamp, system)
+		VALUES (?, ?, ?, ?, ?, 1)
+	`, id, conversationID, senderID, notice, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	if err := chainMessage(ctx, tx, id); err != nil {
+		return nil, err
+	}
+	if _, err := insertReceipts(ctx, tx, id, conversationID, senderID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return db.GetMessage(ctx, id)
+}
+
+// ListChannels returns the channels of the user's workspace, by name
+func (db *appdbimpl) ListChannels(ctx context.Context, userID ids.UserID) ([]Channel, error) {
+	rows, err := db.db.QueryContext(ctx, `
+		SELECT g.id, g.name, g.photo_id,
+			(SELECT COUNT(*) FROM group_members gm WHERE gm.group_id = g.id),
+			EXISTS (SELECT 1 FROM group_members gm WHERE gm.group_id = g.id AND gm.user_id = ?),
+			g.public_feed
+		FROM groups g
+		WHERE g.kind = ? AND g.deleted_at IS NULL
+		AND g.workspace_id = (SELECT workspace_id FROM users WHERE id = ?)
+		ORDER BY g.name
+	`, userID, GroupKindChannel, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var channels []Channel
+	for rows.Next() {
+		var ch Channel
+		var photo sql.NullString
+		if err := rows.Scan(&ch.ID, &ch.Name, &photo, &ch.Subscribers, &ch.Joined, &ch.PublicFeed); err != nil {
+			return nil, err
+		}
+		if photo.Valid {
+			ch.PhotoID = photo.String
+		}
+		channels = append(channels, ch)
+	}
+
+	return channels, rows.Err()
+}
+
+// SetChannelFeed publishes the Atom feed of a channel or takes it down
+func (db *appdbimpl) SetChannelFeed(ctx context.Context, groupID ids.GroupID, public bool) error {
+	var kind string
+	err := db.db.QueryRowContext(ctx, "SELECT kind FROM groups WHERE id = ?", groupID).Scan(&kind)
+	if errors.Is(err, sql.ErrNoRows) {
+		return withID(ErrGroupNotFound, groupID)
+	}
+	if err != nil {
+		return err
+	}
+	if kind != GroupKindChannel {
+		return withID(ErrNotChannel, groupID)
+	}
+
+	_, err = db.db.ExecContext(ctx, "UPDATE groups SET public_feed = ? WHERE id = ?", public, groupID)
+	return err
+}
+
+/*
+GetChannelFeed returns the latest limit messages of a channel for its
+Atom feed, system notices and deleted messages left out. A group, or a channel whose feed is
+not public, is reported as not found: the feed has no other access check.
+*/
+func (db *appdbimpl) GetChannelFeed(ctx context.Context, groupID ids.GroupID, limit int) (*ChannelFeed, error) {
+	feed := ChannelFeed{ID: groupID}
+	var conversationID ids.ConversationID
+	err := db.db.QueryRowContext(ctx, `
+		SELECT g.name, c.id
+		FROM groups g
+		JOIN conversations c ON c.group_id = g.id AND c.is_group = 1
+		WHERE g.id = ? AND g.kind = ? AND g.public_feed = 1 AND g.deleted_at IS NULL
+	`, groupID, GroupKindChannel).Scan(&feed.Name, &conversationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, withID(ErrGroupNotFound, groupID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	messages, _, err := db.getConversationMessagesPage(ctx, conversationID, "", time.Time{}, "", limit)
+	if err != nil {
+		return nil, err
+	}
+	for _, msg := range messages {
+		if !msg.System && !msg.Deleted {
+			feed.Messages = append(feed.Messages, msg)
+		}
+	}
+
+	return &feed, nil
+}
+
+// checkCanPost returns ErrNotChannelAdmin when the conversation is a
+// channel that the user does not run
+func checkCanPost(ctx context.Context, q queryRower, conversationID ids.ConversationID, userID ids.UserID) error {
+	var kind, adminID sql.NullString
+	err := q.QueryRowContext(ctx, `
+		SELECT g.kind, c.created_by
+		FROM conversations c
+		LEFT JOIN groups g ON c.group_id = g.id
+		WHERE c.id = ?
+	`, conversationID).Scan(&kind, &adminID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return withID(ErrConversationNotFound, conversationID)
+	}
+	if err != nil {
+		return err
+	}
+
+	if kind.String == GroupKindChannel && adminID.String != string(userID) {
+		return withID(ErrNotChannelAdmin, conversationID)
+	}
+
+	// Nor does a user who blocked the sender, in a direct conversation
+	blocked, err := blockedInConversation(ctx, q, conversationID, userID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return withID(ErrBlocked, conversationID)
+	}
+	return nil
+}
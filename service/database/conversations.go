@@ -6,20 +6,35 @@ This file handles getting conversation lists and conversation details.
 package database
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"log"
+	"strings"
+	"time"
 
-	"github.com/gofrs/uuid"
+	"wasatext/service/ids"
 )
 
+// notCleared keeps the messages m the participant cp has neither cleared
+// (see ClearConversation) nor deleted for themselves (see
+// DeleteMessageForMe); every query showing messages to a user applies it
+const notCleared = "(cp.cleared_before IS NULL OR m.timestamp >= cp.cleared_before)" +
+	" AND m.id NOT IN (SELECT message_id FROM message_deletions WHERE user_id = cp.user_id)"
+
 // GetConversations returns all conversations for a user, sorted by latest message
-func (db *appdbimpl) GetConversations(userID string) ([]ConversationPreview, error) {
+func (db *appdbimpl) GetConversations(ctx context.Context, userID ids.UserID) ([]ConversationPreview, error) {
+	// Fetching the list is what delivers new messages to this user
+	if err := db.markMessagesAsDelivered(ctx, userID); err != nil {
+		return nil, err
+	}
+
 	// Query for all conversations the user is part of
-	rows, err := db.db.Query(`
+	rows, err := db.db.QueryContext(ctx, `
 		SELECT 
 			c.id,
 			c.is_group,
+			COALESCE(g.kind = 'channel', 0),
 			CASE 
 				WHEN c.is_group = 1 THEN g.name
 				ELSE (SELECT u.name FROM users u 
@@ -27,20 +42,32 @@ func (db *appdbimpl) GetConversations(userID string) ([]ConversationPreview, err
 					  WHERE cp2.conversation_id = c.id AND cp2.user_id != ?)
 			END as name,
 			CASE 
-				WHEN c.is_group = 1 THEN g.photo
-				ELSE (SELECT u.photo FROM users u 
+				WHEN c.is_group = 1 THEN g.photo_id
+				ELSE (SELECT u.photo_id FROM users u 
 					  JOIN conversation_participants cp2 ON u.id = cp2.user_id 
 					  WHERE cp2.conversation_id = c.id AND cp2.user_id != ?)
 			END as photo,
-			(SELECT m.timestamp FROM messages m WHERE m.conversation_id = c.id ORDER BY m.timestamp DESC LIMIT 1) as last_msg_time,
-			(SELECT m.content FROM messages m WHERE m.conversation_id = c.id ORDER BY m.timestamp DESC LIMIT 1) as last_msg_preview,
-			(SELECT CASE WHEN m.photo IS NOT NULL THEN 1 ELSE 0 END FROM messages m WHERE m.conversation_id = c.id ORDER BY m.timestamp DESC LIMIT 1) as last_msg_is_photo
+			CASE
+				WHEN c.is_group = 1 THEN g.id
+				ELSE (SELECT cp2.user_id FROM conversation_participants cp2
+					  WHERE cp2.conversation_id = c.id AND cp2.user_id != ?)
+			END as photo_owner,
+			(SELECT m.timestamp FROM messages m WHERE m.conversation_id = c.id AND `+notCleared+` ORDER BY m.timestamp DESC LIMIT 1) as last_msg_time,
+			(SELECT CASE WHEN m.deleted_at IS NOT NULL THEN '`+DeletedMessageText+`' ELSE m.content END FROM messages m WHERE m.conversation_id = c.id AND `+notCleared+` ORDER BY m.timestamp DESC LIMIT 1) as last_msg_preview,
+			(SELECT CASE WHEN m.photo_id IS NOT NULL THEN 1 ELSE 0 END FROM messages m WHERE m.conversation_id = c.id AND `+notCleared+` ORDER BY m.timestamp DESC LIMIT 1) as last_msg_is_photo,
+			c.is_group = 0 AND EXISTS (SELECT 1 FROM user_blocks b
+				JOIN conversation_participants cp2 ON cp2.user_id = b.blocked_id
+				WHERE b.blocker_id = cp.user_id AND cp2.conversation_id = c.id AND cp2.user_id != cp.user_id) as blocked,
+			c.is_group = 0 AND EXISTS (SELECT 1 FROM users u
+				JOIN conversation_participants cp2 ON cp2.user_id = u.id
+				WHERE cp2.conversation_id = c.id AND cp2.user_id != cp.user_id AND u.deactivated_at IS NOT NULL) as deactivated
 		FROM conversations c
 		JOIN conversation_participants cp ON c.id = cp.conversation_id
 		LEFT JOIN groups g ON c.group_id = g.id
 		WHERE cp.user_id = ?
+		AND c.workspace_id = (SELECT workspace_id FROM users WHERE id = ?)
 		ORDER BY last_msg_time DESC NULLS LAST
-	`, userID, userID, userID)
+	`, userID, userID, userID, userID, userID)
 
 	if err != nil {
 		return nil, err
@@ -50,7 +77,7 @@ func (db *appdbimpl) GetConversations(userID string) ([]ConversationPreview, err
 	var conversations []ConversationPreview
 	for rows.Next() {
 		var conv ConversationPreview
-		var photo sql.NullString
+		var photo, photoOwner sql.NullString
 		var lastMsgTime sql.NullTime
 		var lastMsgPreview sql.NullString
 		var lastMsgIsPhoto sql.NullBool
@@ -58,18 +85,23 @@ func (db *appdbimpl) GetConversations(userID string) ([]ConversationPreview, err
 		if err := rows.Scan(
 			&conv.ID,
 			&conv.IsGroup,
+			&conv.IsChannel,
 			&conv.Name,
 			&photo,
+			&photoOwner,
 			&lastMsgTime,
 			&lastMsgPreview,
 			&lastMsgIsPhoto,
+			&conv.Blocked,
+			&conv.Deactivated,
 		); err != nil {
 			return nil, err
 		}
 
 		if photo.Valid {
-			conv.Photo = []byte(photo.String)
+			conv.PhotoID = photo.String
 		}
+		conv.PhotoOwnerID = photoOwner.String
 		if lastMsgTime.Valid {
 			conv.LastMessageTime = lastMsgTime.Time
 		}
@@ -87,33 +119,34 @@ func (db *appdbimpl) GetConversations(userID string) ([]ConversationPreview, err
 }
 
 // GetConversation returns a full conversation with all messages
-func (db *appdbimpl) GetConversation(userID, conversationID string) (*Conversation, error) {
-	// First, check if user is a participant
-	var count int
-	err := db.db.QueryRow(
-		"SELECT COUNT(*) FROM conversation_participants WHERE conversation_id = ? AND user_id = ?",
-		conversationID, userID,
-	).Scan(&count)
+func (db *appdbimpl) GetConversation(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID) (*Conversation, error) {
+	return db.GetConversationPage(ctx, userID, conversationID, "", 0)
+}
 
-	if err != nil {
+/*
+GetConversationPage returns a conversation with one page of messages:
+at most limit messages (0 means no limit), newest first, older than the
+message beforeID (empty means start from the newest). HasMore tells
+whether older messages are left.
+*/
+func (db *appdbimpl) GetConversationPage(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID, beforeID ids.MessageID, limit int) (*Conversation, error) {
+	// First, check if user is a participant
+	if err := db.checkParticipant(ctx, userID, conversationID); err != nil {
 		return nil, err
 	}
-	if count == 0 {
-		return nil, ErrConversationNotFound
-	}
 
 	// Get conversation info
 	var conv Conversation
 	var isGroup bool
 	var groupID sql.NullString
 
-	err = db.db.QueryRow(
+	err := db.db.QueryRowContext(ctx,
 		"SELECT id, is_group, group_id FROM conversations WHERE id = ?",
 		conversationID,
 	).Scan(&conv.ID, &isGroup, &groupID)
 
 	if errors.Is(err, sql.ErrNoRows) {
-		return nil, ErrConversationNotFound
+		return nil, withID(ErrConversationNotFound, conversationID)
 	}
 	if err != nil {
 		return nil, err
@@ -123,113 +156,374 @@ func (db *appdbimpl) GetConversation(userID, conversationID string) (*Conversati
 
 	// Get name and photo based on type
 	if isGroup && groupID.Valid {
-		group, err := db.GetGroup(groupID.String)
+		group, err := db.GetGroup(ctx, ids.GroupID(groupID.String))
 		if err != nil {
 			return nil, err
 		}
 		conv.Name = group.Name
-		conv.Photo = group.Photo
+		conv.IsChannel = group.Kind == GroupKindChannel
+		conv.PhotoID = group.PhotoID
+		conv.PhotoOwnerID = string(group.ID)
 		conv.Members = group.Members
 	} else {
 		// Direct conversation - get the other user
 		var otherUser User
 		var photo sql.NullString
+		var lastSeen sql.NullTime
 
-		err = db.db.QueryRow(`
-			SELECT u.id, u.name, u.photo 
+		err = db.db.QueryRowContext(ctx, `
+			SELECT u.id, u.name, u.photo_id, u.deactivated_at IS NOT NULL, u.last_seen, u.hide_presence, u.about
 			FROM users u 
 			JOIN conversation_participants cp ON u.id = cp.user_id 
 			WHERE cp.conversation_id = ? AND cp.user_id != ?
-		`, conversationID, userID).Scan(&otherUser.ID, &otherUser.Name, &photo)
+		`, conversationID, userID).Scan(&otherUser.ID, &otherUser.Name, &photo, &otherUser.Deactivated, &lastSeen, &otherUser.HidePresence, &otherUser.About)
 
 		if err == nil {
 			conv.Name = otherUser.Name
+			conv.PhotoOwnerID = string(otherUser.ID)
 			if photo.Valid {
-				conv.Photo = []byte(photo.String)
-				otherUser.Photo = conv.Photo
+				conv.PhotoID = photo.String
+				otherUser.PhotoID = conv.PhotoID
 			}
+			otherUser.readLastSeen(lastSeen)
 			conv.Members = []User{otherUser}
 		}
 	}
 
+	// The messages the user cleared are left out
+	var clearedBefore sql.NullTime
+	err = db.db.QueryRowContext(ctx,
+		"SELECT cleared_before FROM conversation_participants WHERE conversation_id = ? AND user_id = ?",
+		conversationID, userID,
+	).Scan(&clearedBefore)
+	if err != nil {
+		return nil, err
+	}
+	if clearedBefore.Valid {
+		conv.ClearedBefore = &clearedBefore.Time
+	}
+
 	// Get messages in reverse chronological order (as per PDF)
-	messages, err := db.getConversationMessages(conversationID)
+	messages, hasMore, err := db.getConversationMessagesPage(ctx, conversationID, userID, clearedBefore.Time, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	conv.Messages = messages
+	conv.HasMore = hasMore
+
+	// The language most of the latest messages are written in
+	err = db.db.QueryRowContext(ctx, `
+		SELECT language FROM (
+			SELECT language FROM messages
+			WHERE conversation_id = ? AND language IS NOT NULL
+			ORDER BY timestamp DESC LIMIT ?
+		)
+		GROUP BY language ORDER BY COUNT(*) DESC, language LIMIT 1
+	`, conversationID, conversationLanguageSample).Scan(&conv.Language)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	return &conv, nil
+}
+
+// conversationLanguageSample is how many of the latest messages with a
+// language tell the language of a conversation
+const conversationLanguageSample = 100
+
+// checkParticipant returns ErrConversationNotFound unless the user is a
+// participant of the conversation (in their own workspace)
+func (db *appdbimpl) checkParticipant(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID) error {
+	var count int
+	err := db.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM conversation_participants cp
+		JOIN conversations c ON cp.conversation_id = c.id
+		WHERE cp.conversation_id = ? AND cp.user_id = ?
+		AND c.workspace_id = (SELECT workspace_id FROM users WHERE id = ?)
+	`, conversationID, userID, userID).Scan(&count)
+
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return withID(ErrConversationNotFound, conversationID)
+	}
+	return nil
+}
+
+/*
+GetConversationArchive returns a full conversation for archiving, with
+every participant and message. It is meant for admin exports: there is
+no participant check and nothing is marked as read, and conversations
+deleted by an admin are included. A direct conversation is named after
+both participants.
+*/
+func (db *appdbimpl) GetConversationArchive(ctx context.Context, conversationID ids.ConversationID) (*Conversation, error) {
+	var conv Conversation
+	var groupID sql.NullString
+
+	err := db.db.QueryRowContext(ctx,
+		"SELECT id, is_group, group_id FROM conversations WHERE id = ?",
+		conversationID,
+	).Scan(&conv.ID, &conv.IsGroup, &groupID)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, withID(ErrConversationNotFound, conversationID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the participants
+	rows, err := db.db.QueryContext(ctx, `
+		SELECT u.id, u.name, u.photo_id
+		FROM users u
+		JOIN (
+			SELECT conversation_id, user_id FROM conversation_participants
+			UNION ALL SELECT conversation_id, user_id FROM deleted_conversation_participants
+		) cp ON u.id = cp.user_id
+		WHERE cp.conversation_id = ?
+		ORDER BY u.name
+	`, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var user User
+		var photo sql.NullString
+
+		if err := rows.Scan(&user.ID, &user.Name, &photo); err != nil {
+			return nil, err
+		}
+		if photo.Valid {
+			user.PhotoID = photo.String
+		}
+
+		conv.Members = append(conv.Members, user)
+		names = append(names, user.Name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Get name and photo based on type
+	if conv.IsGroup && groupID.Valid {
+		// Not GetGroup, which leaves deleted groups out
+		var photo sql.NullString
+		err := db.db.QueryRowContext(ctx, "SELECT name, photo_id FROM groups WHERE id = ?", groupID.String).Scan(&conv.Name, &photo)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, withID(ErrGroupNotFound, groupID.String)
+		}
+		if err != nil {
+			return nil, err
+		}
+		conv.PhotoID = photo.String
+		conv.PhotoOwnerID = groupID.String
+	} else {
+		conv.Name = strings.Join(names, " & ")
+	}
+
+	messages, err := db.getConversationMessages(ctx, conversationID)
 	if err != nil {
 		return nil, err
 	}
 	conv.Messages = messages
 
-	// Mark conversation as read (this updates message status)
-	_ = db.MarkConversationAsRead(conversationID, userID)
+	// The hashes prove the archive was not altered (see integrity.go)
+	if err := db.attachHashes(ctx, &conv); err != nil {
+		return nil, err
+	}
 
 	return &conv, nil
 }
 
+// replyPreviewLength is how many characters of the replied-to message a reply shows
+const replyPreviewLength = 100
+
+// getReplyPreview returns the preview of the message replyTo as a reply
+// in the conversation shows it: unavailable unless it is still there and
+// was not deleted for everyone
+func (db *appdbimpl) getReplyPreview(ctx context.Context, conversationID ids.ConversationID, replyTo ids.MessageID) (*ReplyPreview, error) {
+	var reply ReplyPreview
+	var sender, content sql.NullString
+	err := db.db.QueryRowContext(ctx, `
+		SELECT COALESCE(r.hook_name, ru.name), substr(r.content, 1, ?), r.photo_id IS NOT NULL
+		FROM messages r
+		LEFT JOIN users ru ON r.sender_id = ru.id
+		WHERE r.id = ? AND r.conversation_id = ? AND r.deleted_at IS NULL
+	`, replyPreviewLength, replyTo, conversationID).Scan(&sender, &content, &reply.HasPhoto)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &reply, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	reply.Available = true
+	reply.SenderName = sender.String
+	reply.Content = content.String
+	return &reply, nil
+}
+
 // getConversationMessages retrieves all messages for a conversation
-func (db *appdbimpl) getConversationMessages(conversationID string) ([]Message, error) {
-	rows, err := db.db.Query(`
-		SELECT m.id, m.sender_id, u.name, m.content, m.photo, m.timestamp, m.status, m.reply_to
+func (db *appdbimpl) getConversationMessages(ctx context.Context, conversationID ids.ConversationID) ([]Message, error) {
+	messages, _, err := db.getConversationMessagesPage(ctx, conversationID, "", time.Time{}, "", 0)
+	return messages, err
+}
+
+/*
+getConversationMessagesPage retrieves up to limit messages (0 means all)
+older than the message beforeID, newest first, and whether older ones
+are left. Only the messages sent since then are considered, all of them
+when since is zero, and the messages viewerID deleted for themselves are
+left out; a reply to a message not shown shows it unavailable.
+*/
+func (db *appdbimpl) getConversationMessagesPage(ctx context.Context, conversationID ids.ConversationID, viewerID ids.UserID, since time.Time, beforeID ids.MessageID, limit int) ([]Message, bool, error) {
+	// The cursor must be a message of this conversation
+	var before time.Time
+	if beforeID != "" {
+		err := db.db.QueryRowContext(ctx,
+			"SELECT timestamp FROM messages WHERE id = ? AND conversation_id = ?",
+			beforeID, conversationID,
+		).Scan(&before)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, false, withID(ErrMessageNotFound, beforeID)
+		}
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	// Ask for one extra message to know whether there are more;
+	// a negative LIMIT means no limit in SQLite
+	queryLimit := -1
+	if limit > 0 {
+		queryLimit = limit + 1
+	}
+
+	// The replied-to message is only shown when it is still in this conversation
+	// and the viewer can see it
+	const deletedForViewer = "SELECT message_id FROM message_deletions WHERE user_id = ?"
+	rows, err := db.db.QueryContext(ctx, `
+		SELECT m.id, m.sender_id, COALESCE(m.hook_name, u.name), m.content, COALESCE(m.language, ''), m.photo_id, m.timestamp, `+messageStatusSQL+`, m.reply_to, m.system, m.edited_at,
+			m.hook_name IS NOT NULL, m.deleted_at IS NOT NULL, COALESCE(md.processing_state, ''),
+			EXISTS (SELECT 1 FROM message_pins p WHERE p.message_id = m.id), `+noticeColumns+`,
+			r.id IS NOT NULL, COALESCE(r.hook_name, ru.name), substr(r.content, 1, ?), r.photo_id IS NOT NULL
 		FROM messages m
 		JOIN users u ON m.sender_id = u.id
-		WHERE m.conversation_id = ?
-		ORDER BY m.timestamp DESC
-	`, conversationID)
+		LEFT JOIN media md ON md.id = m.photo_id`+noticeUsers+`
+		LEFT JOIN messages r ON r.id = m.reply_to AND r.conversation_id = m.conversation_id AND (? OR r.timestamp >= ?)
+			AND r.deleted_at IS NULL AND r.id NOT IN (`+deletedForViewer+`)
+		LEFT JOIN users ru ON r.sender_id = ru.id
+		WHERE m.conversation_id = ? AND (? OR m.timestamp >= ?) AND m.id NOT IN (`+deletedForViewer+`)
+		AND (? = '' OR m.timestamp < ? OR (m.timestamp = ? AND m.id < ?))
+		ORDER BY m.timestamp DESC, m.id DESC
+		LIMIT ?
+	`, replyPreviewLength, since.IsZero(), since, viewerID, conversationID, since.IsZero(), since, viewerID,
+		beforeID, before, before, beforeID, queryLimit)
 
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 	defer rows.Close()
 
 	var messages []Message
 	for rows.Next() {
-		var msg Message
+		msg := Message{ConversationID: conversationID}
 		var content sql.NullString
 		var photo sql.NullString
 		var replyTo sql.NullString
+		var reply ReplyPreview
+		var replySender, replyContent sql.NullString
+		var editedAt sql.NullTime
+		var notice Notice
 
 		if err := rows.Scan(
 			&msg.ID,
 			&msg.SenderID,
 			&msg.SenderName,
 			&content,
+			&msg.Language,
 			&photo,
 			&msg.Timestamp,
 			&msg.Status,
 			&replyTo,
+			&msg.System,
+			&editedAt,
+			&msg.ViaHook,
+			&msg.Deleted,
+			&msg.PhotoState,
+			&msg.Pinned,
+			&notice.Kind,
+			&notice.UserID,
+			&notice.UserName,
+			&notice.Name,
+			&reply.Available,
+			&replySender,
+			&replyContent,
+			&reply.HasPhoto,
 		); err != nil {
-			return nil, err
+			return nil, false, err
 		}
 
 		if content.Valid {
 			msg.Content = content.String
 		}
+		if msg.Deleted {
+			msg.Content = DeletedMessageText
+		}
 		if photo.Valid {
-			msg.Photo = []byte(photo.String)
+			msg.PhotoID = photo.String
 		}
 		if replyTo.Valid {
-			msg.ReplyTo = &replyTo.String
+			replyToID := ids.MessageID(replyTo.String)
+			msg.ReplyTo = &replyToID
+			reply.SenderName = replySender.String
+			reply.Content = replyContent.String
+			msg.Reply = &reply
+		}
+		if editedAt.Valid {
+			msg.EditedAt = &editedAt.Time
 		}
+		msg.Notice = notice.orNil()
 
 		// Get comments for this message
-		comments, err := db.getMessageComments(msg.ID)
+		comments, err := db.getMessageComments(ctx, msg.ID)
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
 		msg.Comments = comments
+		if msg.Mentions, err = db.getMessageMentions(ctx, msg.ID); err != nil {
+			return nil, false, err
+		}
 
 		messages = append(messages, msg)
 	}
 
-	return messages, rows.Err()
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	if err := db.attachEvents(ctx, viewerID, messages); err != nil {
+		return nil, false, err
+	}
+
+	if limit > 0 && len(messages) > limit {
+		return messages[:limit], true, nil
+	}
+	return messages, false, nil
 }
 
 // getMessageComments retrieves all comments (reactions) on a message
-func (db *appdbimpl) getMessageComments(messageID string) ([]Comment, error) {
-	rows, err := db.db.Query(`
+func (db *appdbimpl) getMessageComments(ctx context.Context, messageID ids.MessageID) ([]Comment, error) {
+	rows, err := db.db.QueryContext(ctx, `
 		SELECT c.user_id, u.name, c.emoticon
 		FROM comments c
 		JOIN users u ON c.user_id = u.id
 		WHERE c.message_id = ?
+		ORDER BY c.created_at, c.rowid
 	`, messageID)
 
 	if err != nil {
@@ -250,10 +544,42 @@ func (db *appdbimpl) getMessageComments(messageID string) ([]Comment, error) {
 }
 
 // GetOrCreateDirectConversation gets or creates a direct conversation between two users
-func (db *appdbimpl) GetOrCreateDirectConversation(userID, otherUserID string) (string, error) {
+// Both users must belong to the same workspace.
+func (db *appdbimpl) GetOrCreateDirectConversation(ctx context.Context, userID, otherUserID ids.UserID) (ids.ConversationID, error) {
+	user, err := db.GetUserByID(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	otherUser, err := db.GetUserByID(ctx, otherUserID)
+	if err != nil {
+		return "", err
+	}
+	if user.WorkspaceID != otherUser.WorkspaceID {
+		return "", withID(ErrUserNotFound, otherUserID)
+	}
+	blocked, err := hasBlocked(ctx, db.db, otherUserID, userID)
+	if err != nil {
+		return "", err
+	}
+	if blocked {
+		return "", withID(ErrBlocked, otherUserID)
+	}
+
+	// The lookup and the insert share one (immediate) transaction, so two
+	// users starting the same conversation at once cannot create it twice
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+	}()
+
 	// Check if conversation already exists
-	var convID string
-	err := db.db.QueryRow(`
+	var convID ids.ConversationID
+	err = tx.QueryRowContext(ctx, `
 		SELECT cp1.conversation_id 
 		FROM conversation_participants cp1
 		JOIN conversation_participants cp2 ON cp1.conversation_id = cp2.conversation_id
@@ -270,42 +596,32 @@ func (db *appdbimpl) GetOrCreateDirectConversation(userID, otherUserID string) (
 	}
 
 	// Create new conversation
-	id, err := uuid.NewV4()
-	if err != nil {
-		return "", err
-	}
-
-	tx, err := db.db.Begin()
+	id, err := newConversationID(ctx, tx)
 	if err != nil {
 		return "", err
 	}
-	defer func() {
-		if rbErr := tx.Rollback(); rbErr != nil {
-			log.Printf("Error rolling back transaction: %v", rbErr)
-		}
-	}()
 
 	// Create conversation
-	_, err = tx.Exec(
-		"INSERT INTO conversations (id, is_group) VALUES (?, 0)",
-		id.String(),
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO conversations (id, workspace_id, is_group, created_by, created_at) VALUES (?, ?, 0, ?, ?)",
+		id, user.WorkspaceID, userID, time.Now(),
 	)
 	if err != nil {
 		return "", err
 	}
 
 	// Add both participants
-	_, err = tx.Exec(
+	_, err = tx.ExecContext(ctx,
 		"INSERT INTO conversation_participants (conversation_id, user_id) VALUES (?, ?)",
-		id.String(), userID,
+		id, userID,
 	)
 	if err != nil {
 		return "", err
 	}
 
-	_, err = tx.Exec(
+	_, err = tx.ExecContext(ctx,
 		"INSERT INTO conversation_participants (conversation_id, user_id) VALUES (?, ?)",
-		id.String(), otherUserID,
+		id, otherUserID,
 	)
 	if err != nil {
 		return "", err
@@ -315,13 +631,37 @@ func (db *appdbimpl) GetOrCreateDirectConversation(userID, otherUserID string) (
 		return "", err
 	}
 
-	return id.String(), nil
+	return id, nil
 }
 
-// MarkConversationAsRead marks all messages in a conversation as read for a user
-func (db *appdbimpl) MarkConversationAsRead(conversationID, userID string) error {
+/*
+MarkConversationAsRead marks the messages of a conversation as read for
+a participant: all of them, or only those up to the message upToID (in
+the order of the conversation pages) when it is not empty, so that the
+messages that arrived after what the user saw stay unread.
+*/
+func (db *appdbimpl) MarkConversationAsRead(ctx context.Context, conversationID ids.ConversationID, userID ids.UserID, upToID ids.MessageID) error {
+	if err := db.checkParticipant(ctx, userID, conversationID); err != nil {
+		return err
+	}
+
+	// The last message read must be in this conversation
+	var upTo time.Time
+	if upToID != "" {
+		err := db.db.QueryRowContext(ctx,
+			"SELECT timestamp FROM messages WHERE id = ? AND conversation_id = ?",
+			upToID, conversationID,
+		).Scan(&upTo)
+		if errors.Is(err, sql.ErrNoRows) {
+			return withID(ErrMessageNotFound, upToID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
 	// Update the last_read_time for this user
-	_, err := db.db.Exec(`
+	_, err := db.db.ExecContext(ctx, `
 		UPDATE conversation_participants 
 		SET last_read_time = CURRENT_TIMESTAMP 
 		WHERE conversation_id = ? AND user_id = ?
@@ -330,13 +670,257 @@ func (db *appdbimpl) MarkConversationAsRead(conversationID, userID string) error
 		return err
 	}
 
-	// Update message status to 'read' for messages sent by others
-	// This is simplified - in real app, you'd track per-user read status
-	_, err = db.db.Exec(`
-		UPDATE messages 
-		SET status = 'read' 
-		WHERE conversation_id = ? AND sender_id != ? AND status != 'read'
-	`, conversationID, userID)
+	// Mark this user's receipts as read (reading implies delivery).
+	// A user who does not share read receipts here only confirms delivery,
+	// so the senders never see "read".
+	_, err = db.db.ExecContext(ctx, `
+		UPDATE message_receipts
+		SET delivered_at = COALESCE(delivered_at, CURRENT_TIMESTAMP),
+			read_at = CASE WHEN (
+				SELECT share_read_receipts FROM conversation_participants
+				WHERE conversation_id = ? AND user_id = ?
+			) = 0 THEN NULL ELSE CURRENT_TIMESTAMP END
+		WHERE user_id = ? AND read_at IS NULL
+		AND message_id IN (
+			SELECT id FROM messages WHERE conversation_id = ?
+			AND (? = '' OR timestamp < ? OR (timestamp = ? AND id <= ?))
+		)
+	`, conversationID, userID, userID, conversationID, upToID, upTo, upTo, upToID)
+
+	return err
+}
+
+// GetPrivacySettings returns what a participant shares in a conversation
+func (db *appdbimpl) GetPrivacySettings(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID) (*PrivacySettings, error) {
+	if err := db.checkParticipant(ctx, userID, conversationID); err != nil {
+		return nil, err
+	}
+
+	var settings PrivacySettings
+	err := db.db.QueryRowContext(ctx, `
+		SELECT share_typing, share_read_receipts FROM conversation_participants
+		WHERE conversation_id = ? AND user_id = ?
+	`, conversationID, userID).Scan(&settings.TypingIndicators, &settings.ReadReceipts)
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// SetPrivacySettings changes what a participant shares in a conversation
+func (db *appdbimpl) SetPrivacySettings(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID, settings PrivacySettings) error {
+	if err := db.checkParticipant(ctx, userID, conversationID); err != nil {
+		return err
+	}
+
+	_, err := db.db.ExecContext(ctx, `
+		UPDATE conversation_participants
+		SET share_typing = ?, share_read_receipts = ?
+		WHERE conversation_id = ? AND user_id = ?
+	`, settings.TypingIndicators, settings.ReadReceipts, conversationID, userID)
+	return err
+}
+
+/*
+GetConversationPermissions returns what a participant may do in a
+conversation, by the same rules the operations enforce (checkCanPost,
+AddUserToGroup, DeleteMessage, and the group admin checks of the API):
+
+  - in a direct conversation, send only, unless the other participant
+    blocked the user;
+  - in a group, every member sends, renames, sets the photo, adds others
+    and leaves; only the group admin (its creator) manages the group;
+  - in a governed group, nobody renames it or sets its photo directly:
+    a rename is put to the vote;
+  - in a channel, only the admin sends, renames, sets the photo and adds
+    subscribers; subscribers can only leave.
+
+Nobody deletes the messages of others: only server admins remove them,
+through moderation.
+*/
+func (db *appdbimpl) GetConversationPermissions(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID) (*ConversationPermissions, error) {
+	if err := db.checkParticipant(ctx, userID, conversationID); err != nil {
+		return nil, err
+	}
+
+	var isGroup bool
+	var groupID, kind, adminID sql.NullString
+	err := db.db.QueryRowContext(ctx, `
+		SELECT c.is_group, c.group_id, g.kind, c.created_by
+		FROM conversations c
+		LEFT JOIN groups g ON c.group_id = g.id
+		WHERE c.id = ?
+	`, conversationID).Scan(&isGroup, &groupID, &kind, &adminID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sending follows the very check of SendMessage and ForwardMessage
+	send := true
+	switch err := checkCanPost(ctx, db.db, conversationID, userID); {
+	case errors.Is(err, ErrNotChannelAdmin), errors.Is(err, ErrBlocked):
+		send = false
+	case err != nil:
+		return nil, err
+	}
+
+	if !isGroup {
+		return &ConversationPermissions{Send: send}, nil
+	}
+	// Groups created before the creator was recorded have no admin
+	isAdmin := adminID.Valid && adminID.String == string(userID)
+	editor := kind.String != GroupKindChannel || isAdmin
+	// The members of a governed group vote on its name instead
+	governed, err := db.IsGroupGoverned(ctx, ids.GroupID(groupID.String))
+	if err != nil {
+		return nil, err
+	}
+	return &ConversationPermissions{
+		Send:        send,
+		Rename:      editor && !governed,
+		SetPhoto:    editor && !governed,
+		AddMembers:  editor,
+		Leave:       true,
+		ManageGroup: isAdmin,
+	}, nil
+}
+
+/*
+GetForwardTargets returns the conversations a user may forward a message
+into: those they can post in by the rules of checkCanPost, so neither
+the channels they do not run nor the direct conversations where the
+other user blocked them. The message must be one they can read, and not
+deleted for everyone.
+*/
+func (db *appdbimpl) GetForwardTargets(ctx context.Context, userID ids.UserID, messageID ids.MessageID) (map[ids.ConversationID]bool, error) {
+	var deleted bool
+	err := db.db.QueryRowContext(ctx, `
+		SELECT m.deleted_at IS NOT NULL FROM messages m`+visibleMessages+` WHERE m.id = ?
+	`, userID, messageID).Scan(&deleted)
+	if errors.Is(err, sql.ErrNoRows) || deleted {
+		return nil, withID(ErrMessageNotFound, messageID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.db.QueryContext(ctx, "SELECT conversation_id FROM conversation_participants WHERE user_id = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var conversationIDs []ids.ConversationID
+	for rows.Next() {
+		var id ids.ConversationID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		conversationIDs = append(conversationIDs, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	targets := make(map[ids.ConversationID]bool, len(conversationIDs))
+	for _, id := range conversationIDs {
+		err := checkCanPost(ctx, db.db, id, userID)
+		if errors.Is(err, ErrNotChannelAdmin) || errors.Is(err, ErrBlocked) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		targets[id] = true
+	}
+	return targets, nil
+}
 
+/*
+ClearConversation hides from a participant the messages sent before the
+given time, or all of them when before is zero; the others keep the
+whole history. A later time than now means now: messages still to come
+are never hidden. Clearing only goes forward: a time earlier than the
+last clear changes nothing. It returns when the history now starts.
+*/
+func (db *appdbimpl) ClearConversation(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID, before time.Time) (time.Time, error) {
+	if err := db.checkParticipant(ctx, userID, conversationID); err != nil {
+		return time.Time{}, err
+	}
+
+	// Messages are timestamped in local time, and compared as text
+	now := time.Now()
+	if before.IsZero() || before.After(now) {
+		before = now
+	}
+	before = before.Local()
+
+	_, err := db.db.ExecContext(ctx, `
+		UPDATE conversation_participants SET cleared_before = ?
+		WHERE conversation_id = ? AND user_id = ?
+		AND (cleared_before IS NULL OR cleared_before < ?)
+	`, before, conversationID, userID, before)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var clearedBefore time.Time
+	err = db.db.QueryRowContext(ctx,
+		"SELECT cleared_before FROM conversation_participants WHERE conversation_id = ? AND user_id = ?",
+		conversationID, userID,
+	).Scan(&clearedBefore)
+	return clearedBefore, err
+}
+
+// markMessagesAsDelivered marks every pending receipt of a user as delivered
+func (db *appdbimpl) markMessagesAsDelivered(ctx context.Context, userID ids.UserID) error {
+	_, err := db.db.ExecContext(ctx, `
+		UPDATE message_receipts
+		SET delivered_at = CURRENT_TIMESTAMP
+		WHERE user_id = ? AND delivered_at IS NULL
+	`, userID)
 	return err
 }
+
+/*
+MarkMessageDelivered marks the receipt of one message as delivered to a
+user, as soon as their client acknowledges it (see the ack event of
+the API). It reports whether the receipt changed: nothing changes when
+the message was already delivered, is not in the conversation, or its
+receipts are still being fanned out.
+*/
+func (db *appdbimpl) MarkMessageDelivered(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID, messageID ids.MessageID) (bool, error) {
+	res, err := db.db.ExecContext(ctx, `
+		UPDATE message_receipts
+		SET delivered_at = CURRENT_TIMESTAMP
+		WHERE user_id = ? AND message_id = ? AND delivered_at IS NULL
+		AND message_id IN (SELECT id FROM messages WHERE conversation_id = ?)
+	`, userID, messageID, conversationID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// GetParticipants returns the IDs of the participants of a conversation
+func (db *appdbimpl) GetParticipants(ctx context.Context, conversationID ids.ConversationID) ([]ids.UserID, error) {
+	rows, err := db.db.QueryContext(ctx,
+		"SELECT user_id FROM conversation_participants WHERE conversation_id = ?",
+		conversationID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var participants []ids.UserID
+	for rows.Next() {
+		var id ids.UserID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		participants = append(participants, id)
+	}
+
+	return participants, rows.Err()
+}
@@ -0,0 +1,117 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"wasatext/service/ids"
+)
+
+// The tests below are meant for go test -race: they send from many
+// goroutines at once over the shared *sql.DB
+
+func TestConcurrentDirectConversationCreation(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	alice := newTestUser(t, db, "alice")
+	bob := newTestUser(t, db, "bob")
+
+	// Both users start the conversation at once, from several clients each
+	const starts = 16
+	results := make([]ids.ConversationID, starts)
+	errs := make([]error, starts)
+	var wg sync.WaitGroup
+	for i := range starts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			from, to := alice, bob
+			if i%2 == 1 {
+				from, to = bob, alice
+			}
+			results[i], errs[i] = db.GetOrCreateDirectConversation(ctx, from, to)
+		}()
+	}
+	wg.Wait()
+
+	for i := range starts {
+		if errs[i] != nil {
+			t.Fatalf("start %d: %v", i, errs[i])
+		}
+		if results[i] != results[0] {
+			t.Fatalf("start %d got conversation %s, start 0 got %s", i, results[i], results[0])
+		}
+	}
+}
+
+func TestConcurrentSends(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	const senders, messagesEach = 8, 10
+
+	users := make([]ids.UserID, senders)
+	for i := range users {
+		users[i] = newTestUser(t, db, fmt.Sprintf("user%d", i))
+	}
+	group, err := db.CreateGroup(ctx, "group", users[0], users[1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	conversation, err := db.GetOrCreateDirectConversation(ctx, users[0], users[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	groupConversation := groupConversationID(t, db, group.ID)
+
+	// Every user sends to the group while the first two also talk directly
+	var wg sync.WaitGroup
+	errs := make(chan error, senders*messagesEach*2)
+	for i, sender := range users {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range messagesEach {
+				if _, err := db.CreateMessage(ctx, groupConversation, sender, fmt.Sprintf("group %d/%d", i, j), nil, nil); err != nil {
+					errs <- fmt.Errorf("group send of %s: %w", sender, err)
+				}
+				if i > 1 {
+					continue
+				}
+				if _, err := db.CreateMessage(ctx, conversation, sender, fmt.Sprintf("direct %d/%d", i, j), nil, nil); err != nil {
+					errs <- fmt.Errorf("direct send of %s: %w", sender, err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	for conversationID, want := range map[ids.ConversationID]int{
+		groupConversation: senders * messagesEach,
+		conversation:      2 * messagesEach,
+	} {
+		report, err := db.VerifyConversation(ctx, conversationID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Messages != want || !report.Valid {
+			t.Errorf("conversation %s: %d messages (want %d), valid chain %v: %+v", conversationID, report.Messages, want, report.Valid, report.Problems)
+		}
+	}
+}
+
+// groupConversationID returns the conversation of a group
+func groupConversationID(t testing.TB, db AppDatabase, groupID ids.GroupID) ids.ConversationID {
+	t.Helper()
+	var conversationID ids.ConversationID
+	err := db.(*appdbimpl).db.QueryRow("SELECT id FROM conversations WHERE group_id = ?", groupID).Scan(&conversationID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conversationID
+}
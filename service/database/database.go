@@ -8,6 +8,9 @@ A database is like a filing cabinet that stores all your app's data:
 - Groups (name, members, photo)
 - Conversations (who is chatting with whom)
 
+The bytes of the photos are not in the database itself: they go to a
+storage.BlobStore, and the database refers to them by ID (media.go).
+
 We use SQLite because:
 - It's simple (just one file)
 - No need to install a separate database server
@@ -16,48 +19,290 @@ We use SQLite because:
 package database
 
 import (
+	"context"
 	"database/sql"
-	"errors"
+	"strings"
+	"sync"
 	"time"
 
+	"wasatext/service/ids"
+	"wasatext/service/storage"
+
 	_ "github.com/mattn/go-sqlite3" // SQLite driver
 )
 
 // AppDatabase is the interface for all database operations.
 // An interface is like a contract - it says WHAT methods must exist.
+//
+// Every method but Close takes the context of the request first: when
+// the client goes away or the request times out, the query is
+// interrupted instead of running to the end for nobody.
+//
+// The mock in service/database/mock is generated from it; run
+// "go generate ./service/database" after changing the interface.
+//
+//go:generate go run github.com/matryer/moq@v0.5.3 -out mock/appdatabase_moq.go -pkg mock . AppDatabase
 type AppDatabase interface {
 	// User operations
-	CreateUser(name string) (string, error)
-	GetUserByName(name string) (*User, error)
-	GetUserByID(id string) (*User, error)
-	UpdateUserName(userID, newName string) error
-	UpdateUserPhoto(userID string, photo []byte) error
-	SearchUsers(query string) ([]User, error)
+	CreateUser(ctx context.Context, workspaceID, name string) (ids.UserID, error)
+	GetUserByName(ctx context.Context, workspaceID, name string) (*User, error)
+	GetUserByID(ctx context.Context, id ids.UserID) (*User, error)
+	UpdateUserName(ctx context.Context, userID ids.UserID, newName string) error
+	UpdateUserAbout(ctx context.Context, userID ids.UserID, about string) error
+	UpdateUserPhoto(ctx context.Context, userID ids.UserID, photo []byte) error
+	SearchUsers(ctx context.Context, requesterID ids.UserID, query string) ([]User, error)
+	DeleteUser(ctx context.Context, userID ids.UserID) error
+	DeactivateUser(ctx context.Context, userID ids.UserID) error
+
+	// Presence operations (see presence.go)
+	RecordLastSeen(ctx context.Context, seen map[ids.UserID]time.Time) error
+	GetUserPrivacy(ctx context.Context, userID ids.UserID) (*UserPrivacy, error)
+	SetUserPrivacy(ctx context.Context, userID ids.UserID, privacy UserPrivacy) error
+
+	// Account purge operations
+	PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time) ([]PurgeRecord, error)
+	GetPurgeLog(ctx context.Context) ([]PurgeRecord, error)
+
+	// Anti-spam operations
+	CountNewConversations(ctx context.Context, userID ids.UserID, since time.Time) (int, error)
+	CountDuplicateMessages(ctx context.Context, senderID ids.UserID, content string, excludeConversationID ids.ConversationID, since time.Time) (int, error)
+	RecordSpamEvent(ctx context.Context, userID ids.UserID, kind, detail string, score int) error
+	ThrottleUser(ctx context.Context, userID ids.UserID, until time.Time, reason string) error
+	GetThrottle(ctx context.Context, userID ids.UserID) (*Throttle, error)
+	GetSpamScores(ctx context.Context) ([]SpamScore, error)
+
+	// Usage quota operations
+	GetUsage(ctx context.Context, userID ids.UserID, day string) (*Usage, error)
+	RecordUsage(ctx context.Context, userID ids.UserID, messages, uploads int, at time.Time) error
+
+	// API usage analytics operations
+	RecordAPIUsage(ctx context.Context, counts map[APIUsageKey]int, oldestDay string) error
+	GetAPIUsage(ctx context.Context, userID ids.UserID, since string) ([]APIUsage, error)
+	GetAPIUsageTotals(ctx context.Context, since string) ([]APIUsageTotal, error)
+	GetTopAPIUsers(ctx context.Context, since string, limit int) ([]APIUser, error)
+
+	// Moderation operations
+	CreateModerationItem(ctx context.Context, source string, userID ids.UserID, messageID *ids.MessageID, reporterID *ids.UserID, reason string) (int64, error)
+	GetModerationQueue(ctx context.Context, includeResolved bool) ([]ModerationItem, error)
+	ResolveModerationItem(ctx context.Context, itemID int64, action, note string) error
+	GetModerationAudit(ctx context.Context) ([]ModerationAuditEntry, error)
+	GetUserWarnings(ctx context.Context, userID ids.UserID) ([]Warning, error)
+
+	// Soft deletion operations (admin)
+	SoftDeleteConversation(ctx context.Context, conversationID ids.ConversationID, purgeAt *time.Time, note string) error
+	SoftDeleteGroup(ctx context.Context, groupID ids.GroupID, purgeAt *time.Time, note string) error
+	RestoreConversation(ctx context.Context, conversationID ids.ConversationID, note string) error
+	RestoreGroup(ctx context.Context, groupID ids.GroupID, note string) error
+	GetDeletedConversations(ctx context.Context) ([]DeletedConversation, error)
+	GetDeletionAudit(ctx context.Context) ([]DeletionAuditEntry, error)
+	PurgeDeletedConversations(ctx context.Context, before time.Time) ([]PurgedConversation, error)
+
+	// Integrity operations (see integrity.go)
+	VerifyConversation(ctx context.Context, conversationID ids.ConversationID) (*IntegrityReport, error)
 
 	// Conversation operations
-	GetConversations(userID string) ([]ConversationPreview, error)
-	GetConversation(userID, conversationID string) (*Conversation, error)
-	GetOrCreateDirectConversation(userID, otherUserID string) (string, error)
+	GetConversations(ctx context.Context, userID ids.UserID) ([]ConversationPreview, error)
+	GetConversation(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID) (*Conversation, error)
+	GetConversationPage(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID, beforeID ids.MessageID, limit int) (*Conversation, error)
+	GetOrCreateDirectConversation(ctx context.Context, userID, otherUserID ids.UserID) (ids.ConversationID, error)
+	GetConversationArchive(ctx context.Context, conversationID ids.ConversationID) (*Conversation, error)
+	GetPrivacySettings(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID) (*PrivacySettings, error)
+	SetPrivacySettings(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID, settings PrivacySettings) error
+	GetConversationPermissions(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID) (*ConversationPermissions, error)
+	GetForwardTargets(ctx context.Context, userID ids.UserID, messageID ids.MessageID) (map[ids.ConversationID]bool, error)
+	GetParticipants(ctx context.Context, conversationID ids.ConversationID) ([]ids.UserID, error)
+	ClearConversation(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID, before time.Time) (time.Time, error)
+
+	// Media operations
+	GetPhoto(ctx context.Context, photoID string) ([]byte, error)
+	GetPhotoRendition(ctx context.Context, photoID, quality string) ([]byte, error)
+	ProcessMedia(ctx context.Context, photoID string) error
+	GetMediaState(ctx context.Context, photoID string) (string, error)
+	PendingMedia(ctx context.Context) ([]string, error)
+	PendingPhotoText(ctx context.Context, limit int) ([]string, error)
+	SetPhotoText(ctx context.Context, photoID, text string) error
 
 	// Message operations
-	CreateMessage(conversationID, senderID, content string, photo []byte, replyTo *string) (*Message, error)
-	GetMessage(messageID string) (*Message, error)
-	DeleteMessage(messageID, userID string) error
-	UpdateMessageStatus(messageID, status string) error
-	MarkConversationAsRead(conversationID, userID string) error
+	CreateMessage(ctx context.Context, conversationID ids.ConversationID, senderID ids.UserID, content string, photo []byte, replyTo *ids.MessageID) (*Message, error)
+	GetMessage(ctx context.Context, messageID ids.MessageID) (*Message, error)
+	DeleteMessage(ctx context.Context, messageID ids.MessageID, userID ids.UserID) (bool, error)
+	DeleteMessageForMe(ctx context.Context, userID ids.UserID, messageID ids.MessageID) error
+	UpdateMessageContent(ctx context.Context, messageID ids.MessageID, userID ids.UserID, content string) (*Message, error)
+	MarkConversationAsRead(ctx context.Context, conversationID ids.ConversationID, userID ids.UserID, upToID ids.MessageID) error
+	MarkMessageDelivered(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID, messageID ids.MessageID) (bool, error)
+	GetMessageStatuses(ctx context.Context, conversationID ids.ConversationID, limit int) ([]MessageStatus, error)
+	GetMessageReceipts(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID, messageID ids.MessageID) ([]Receipt, error)
+	FanOutReceipts(ctx context.Context, messageID ids.MessageID, batchSize int) (bool, error)
+	PendingFanouts(ctx context.Context) ([]ids.MessageID, error)
+	CountMessagesSince(ctx context.Context, since time.Time) (int, error)
+
+	// Group event operations
+	CreateEvent(ctx context.Context, conversationID ids.ConversationID, senderID ids.UserID, event NewEvent) (*Message, error)
+	SetRSVP(ctx context.Context, userID ids.UserID, messageID ids.MessageID, response string) (*Event, error)
+	DeleteRSVP(ctx context.Context, userID ids.UserID, messageID ids.MessageID) error
+	GetRSVPs(ctx context.Context, userID ids.UserID, messageID ids.MessageID) ([]RSVP, error)
+	DueEventReminders(ctx context.Context, now time.Time) ([]EventReminder, error)
+	SendEventReminder(ctx context.Context, reminder EventReminder, notice string) (*Message, error)
 
 	// Comment (reaction) operations
-	AddComment(messageID, userID, emoticon string) error
-	RemoveComment(messageID, userID string) error
+	AddComment(ctx context.Context, messageID ids.MessageID, userID ids.UserID, emoticon string) error
+	RemoveComment(ctx context.Context, messageID ids.MessageID, userID ids.UserID, emoticon string) error
+	GetComments(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID, messageIDs []ids.MessageID) (map[ids.MessageID][]Comment, error)
+	GetReactionStats(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID, from, to time.Time, limit int) (*ReactionStats, error)
+
+	// Mention operations (see mentions.go)
+	GetMentions(ctx context.Context, userID ids.UserID, beforeID ids.MessageID, limit int) ([]SearchResult, error)
+
+	// Pinned message operations (see pins.go)
+	PinMessage(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID, messageID ids.MessageID) (bool, error)
+	UnpinMessage(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID, messageID ids.MessageID) error
+	GetPins(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID) ([]Pin, error)
 
 	// Group operations
-	CreateGroup(name string, creatorID string, memberIDs []string) (*Group, error)
-	GetGroup(groupID string) (*Group, error)
-	AddUserToGroup(groupID, userID, adderID string) error
-	RemoveUserFromGroup(groupID, userID string) error
-	UpdateGroupName(groupID, name string) error
-	UpdateGroupPhoto(groupID string, photo []byte) error
-	IsGroupMember(groupID, userID string) (bool, error)
+	CreateGroup(ctx context.Context, name string, creatorID ids.UserID, memberIDs []ids.UserID) (*Group, error)
+	GetGroup(ctx context.Context, groupID ids.GroupID) (*Group, error)
+	AddUserToGroup(ctx context.Context, groupID ids.GroupID, userID, adderID ids.UserID) error
+	RemoveUserFromGroup(ctx context.Context, groupID ids.GroupID, userID ids.UserID) error
+	UpdateGroupName(ctx context.Context, groupID ids.GroupID, name string) error
+	UpdateGroupDescription(ctx context.Context, groupID ids.GroupID, description string) error
+	UpdateGroupPhoto(ctx context.Context, groupID ids.GroupID, photo []byte) error
+	IsGroupMember(ctx context.Context, groupID ids.GroupID, userID ids.UserID) (bool, error)
+
+	// Governance operations (see governance.go)
+	IsGroupGoverned(ctx context.Context, groupID ids.GroupID) (bool, error)
+	SetGroupGovernance(ctx context.Context, groupID ids.GroupID, enabled bool) error
+	CreateProposal(ctx context.Context, groupID ids.GroupID, proposerID ids.UserID, kind string, targetID ids.UserID, name string) (*Proposal, error)
+	GetProposals(ctx context.Context, groupID ids.GroupID, userID ids.UserID) ([]Proposal, error)
+	VoteOnProposal(ctx context.Context, groupID ids.GroupID, proposalID int64, userID ids.UserID, approve bool) (*Proposal, error)
+
+	// Group notice operations (see notices.go)
+	PostGroupNotice(ctx context.Context, groupID ids.GroupID, senderID ids.UserID, text string, notice *Notice) (*Message, error)
+
+	// Channel operations
+	SetGroupKind(ctx context.Context, groupID ids.GroupID, kind string, actorID ids.UserID, notice string) (*Message, error)
+	ListChannels(ctx context.Context, userID ids.UserID) ([]Channel, error)
+	SetChannelFeed(ctx context.Context, groupID ids.GroupID, public bool) error
+	GetChannelFeed(ctx context.Context, groupID ids.GroupID, limit int) (*ChannelFeed, error)
+
+	// Guest access operations
+	GetGroupAdmin(ctx context.Context, groupID ids.GroupID) (ids.UserID, error)
+	CreateGuestToken(ctx context.Context, groupID ids.GroupID, createdBy ids.UserID, expiresAt *time.Time) (*GuestToken, error)
+	GetGuestToken(ctx context.Context, token string) (*GuestToken, error)
+	RevokeGuestToken(ctx context.Context, groupID ids.GroupID, token string) error
+	GetGuestConversation(ctx context.Context, groupID ids.GroupID) (*Conversation, error)
+
+	// Report operations
+	ExportUsers(ctx context.Context, from, to time.Time, fn func(UserReportRow) error) error
+	ExportActivity(ctx context.Context, from, to time.Time, fn func(ActivityReportRow) error) error
+
+	// Maintenance operations
+	RunMaintenance(ctx context.Context) (*MaintenanceReport, error)
+	Checkpoint(ctx context.Context) (*CheckpointReport, error)
+	Stats(ctx context.Context) (*Stats, error)
+
+	// Note operations
+	SetMessageNote(ctx context.Context, userID ids.UserID, messageID ids.MessageID, note string) (*MessageNote, error)
+	DeleteMessageNote(ctx context.Context, userID ids.UserID, messageID ids.MessageID) error
+	GetMessageNotes(ctx context.Context, userID ids.UserID) ([]MessageNote, error)
+
+	// Reminder operations
+	SetMessageReminder(ctx context.Context, userID ids.UserID, messageID ids.MessageID, remindAt time.Time) (*MessageReminder, error)
+	DeleteMessageReminder(ctx context.Context, userID ids.UserID, messageID ids.MessageID) error
+	GetMessageReminders(ctx context.Context, userID ids.UserID) ([]MessageReminder, error)
+	DueMessageReminders(ctx context.Context, now time.Time) ([]MessageReminder, error)
+	MarkMessageReminderDelivered(ctx context.Context, userID ids.UserID, messageID ids.MessageID, now time.Time) (bool, error)
+
+	// Scheduled message operations (see scheduled.go)
+	ScheduleMessage(ctx context.Context, conversationID ids.ConversationID, senderID ids.UserID, content string, replyTo *ids.MessageID, sendAt time.Time) (*ScheduledMessage, error)
+	GetScheduledMessages(ctx context.Context, userID ids.UserID) ([]ScheduledMessage, error)
+	CancelScheduledMessage(ctx context.Context, userID ids.UserID, scheduledID int64) error
+	DueScheduledMessages(ctx context.Context, now time.Time) ([]ScheduledMessage, error)
+	NextScheduledMessage(ctx context.Context) (*time.Time, error)
+	TakeScheduledMessage(ctx context.Context, scheduledID int64) (bool, error)
+
+	// Block operations
+	BlockUser(ctx context.Context, userID, blockedID ids.UserID) error
+	UnblockUser(ctx context.Context, userID, blockedID ids.UserID) error
+
+	// Settings operations (see settings.go)
+	GetBranding(ctx context.Context) (*Branding, error)
+	SetBranding(ctx context.Context, appName, accentColor string) (*Branding, error)
+	SetBrandingLogo(ctx context.Context, logo []byte) (*Branding, error)
+	DeleteBrandingLogo(ctx context.Context) error
+
+	// Key backup operations (see keybackup.go)
+	GetKeyBackup(ctx context.Context, userID ids.UserID) (*KeyBackup, error)
+	PutKeyBackup(ctx context.Context, userID ids.UserID, data []byte, replaces *int64) (*KeyBackup, error)
+	DeleteKeyBackup(ctx context.Context, userID ids.UserID) error
+
+	// Push notification operations (see push.go)
+	AddPushSubscription(ctx context.Context, userID ids.UserID, endpoint, p256dh, auth string) (*PushSubscription, error)
+	GetPushSubscriptions(ctx context.Context, userID ids.UserID) ([]PushSubscription, error)
+	DeletePushSubscription(ctx context.Context, userID ids.UserID, subscriptionID int64) error
+	DropPushSubscription(ctx context.Context, endpoint string) error
+	PushRecipients(ctx context.Context, conversationID ids.ConversationID, senderID ids.UserID, now time.Time) ([]PushSubscription, error)
+	GetMute(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID) (*Mute, error)
+	SetMute(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID, mute Mute) error
+
+	// Outbound webhook operations (see webhooks.go)
+	CreateWebhook(ctx context.Context, groupID ids.GroupID, createdBy ids.UserID, url string, events []string) (*Webhook, error)
+	ListWebhooks(ctx context.Context, groupID ids.GroupID) ([]Webhook, error)
+	DeleteWebhook(ctx context.Context, groupID ids.GroupID, webhookID int64) error
+	GetWebhookDeliveries(ctx context.Context, groupID ids.GroupID, webhookID int64, limit int) ([]WebhookDelivery, error)
+	QueueWebhookEvent(ctx context.Context, event string, groupID ids.GroupID, conversationID ids.ConversationID, actor ids.UserID, payload []byte, now time.Time) (int64, error)
+	DueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]WebhookDelivery, error)
+	NextWebhookDelivery(ctx context.Context) (*time.Time, error)
+	RecordWebhookAttempt(ctx context.Context, deliveryID int64, attempt WebhookAttempt) error
+	PruneWebhookDeliveries(ctx context.Context, before time.Time) (int64, error)
+
+	// Bot account operations (see bots.go)
+	CreateBot(ctx context.Context, workspaceID, name string) (*Bot, error)
+	ListBots(ctx context.Context) ([]Bot, error)
+	DeleteBot(ctx context.Context, botID ids.UserID) error
+	ResetBotToken(ctx context.Context, botID ids.UserID) (string, error)
+	GetBotByToken(ctx context.Context, token string) (ids.UserID, error)
+	SetBotWebhook(ctx context.Context, botID ids.UserID, url string, events []string) (*Webhook, error)
+	GetBotWebhook(ctx context.Context, botID ids.UserID) (*Webhook, error)
+	DeleteBotWebhook(ctx context.Context, botID ids.UserID) error
+	GetBotWebhookDeliveries(ctx context.Context, botID ids.UserID, limit int) ([]WebhookDelivery, error)
+
+	// Chat import operations (see imports.go)
+	ImportChat(ctx context.Context, chat ImportedChat) (*ImportResult, error)
+
+	// Search operations
+	SearchMessages(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID, query, language string, beforeID ids.MessageID, limit int) ([]SearchResult, error)
+
+	// Sandbox operations (see sandbox.go)
+	ResetData(ctx context.Context) error
+
+	// Session operations
+	CreateSession(ctx context.Context, userID ids.UserID) (string, error)
+	GetSessionUser(ctx context.Context, token string) (ids.UserID, error)
+	DeleteSession(ctx context.Context, token string) error
+
+	// Webhook operations
+	CreateHook(ctx context.Context, conversationID ids.ConversationID, createdBy ids.UserID, name string, ratePerMinute int) (*Hook, error)
+	ListHooks(ctx context.Context, conversationID ids.ConversationID, createdBy ids.UserID) ([]Hook, error)
+	GetHook(ctx context.Context, token string) (*Hook, error)
+	DeleteHook(ctx context.Context, conversationID ids.ConversationID, createdBy ids.UserID, token string) error
+	PostHookMessage(ctx context.Context, hook *Hook, content string) (*Message, error)
+
+	// Widget token operations
+	CreateWidgetToken(ctx context.Context, conversationID ids.ConversationID, createdBy ids.UserID, name string, expiresAt *time.Time) (*WidgetToken, error)
+	ListWidgetTokens(ctx context.Context, conversationID ids.ConversationID, createdBy ids.UserID) ([]WidgetToken, error)
+	GetWidgetToken(ctx context.Context, token string) (*WidgetToken, error)
+	DeleteWidgetToken(ctx context.Context, conversationID ids.ConversationID, createdBy ids.UserID, token string) error
+
+	// Invite operations
+	CreateInvite(ctx context.Context, workspaceID string, createdBy ids.UserID, expiresAt *time.Time) (*Invite, error)
+	ListInvites(ctx context.Context, createdBy ids.UserID) ([]Invite, error)
+	RevokeInvite(ctx context.Context, code string, createdBy ids.UserID) error
+	RegisterWithInvite(ctx context.Context, workspaceID, name, code string) (ids.UserID, error)
+
+	// Workspace operations
+	ListWorkspaces(ctx context.Context) ([]Workspace, error)
+	GetWorkspace(ctx context.Context, id string) (*Workspace, error)
+	CreateWorkspace(ctx context.Context, id, name string) (*Workspace, error)
 
 	// Cleanup
 	Close() error
@@ -65,69 +310,180 @@ type AppDatabase interface {
 
 // User represents a WASAText user
 type User struct {
-	ID    string
-	Name  string
-	Photo []byte
+	ID           ids.UserID
+	WorkspaceID  string
+	Name         string
+	PhotoID      string     // media ID of the photo, "" if none (see media.go)
+	CreatedAt    time.Time  // zero for accounts created before it was tracked
+	Blocked      bool       // blocked by the requester (SearchUsers only)
+	Deactivated  bool       // deactivated until they log in again (members only, see DeactivateUser)
+	LastSeen     *time.Time // the last heartbeat saved, nil if never (see presence.go)
+	HidePresence bool       // the user hides their presence from everyone
+	About        string     // the status line of the profile, "" when none
+	Bot          bool       // a bot account (GetUserByID only, see bots.go)
 }
 
 // Group represents a WASAText group
 type Group struct {
-	ID      string
-	Name    string
-	Photo   []byte
-	Members []User
+	ID          ids.GroupID
+	WorkspaceID string
+	Name        string
+	Description string // the topic of the group, "" when none
+	Kind        string // GroupKindGroup or GroupKindChannel
+	PhotoID     string
+	Governance  bool       // the members vote on renames and removals
+	AdminID     ids.UserID // the creator, "" for groups older than it was recorded
+	CreatedAt   *time.Time // nil for groups created before it was recorded
+	Members     []User
 }
 
 // Message represents a message in a conversation
 type Message struct {
-	ID         string
-	SenderID   string
+	ID             ids.MessageID
+	ConversationID ids.ConversationID
+	SenderID       ids.UserID
+	SenderName     string
+	Content        string
+	Language       string // detected in Content (ISO 639-1, see langdetect), "" when unknown
+	PhotoID        string
+	Timestamp      time.Time
+	Status         string // "sent", "received", "read" (derived from message_receipts)
+	ReplyTo        *ids.MessageID
+	Reply          *ReplyPreview // the message ReplyTo refers to
+	System         bool          // a notice about the conversation (e.g. it became a channel)
+	EditedAt       *time.Time    // when the sender last edited the text, nil if never
+	ViaHook        bool          // posted through a webhook; SenderName is the hook's name
+	FanoutPending  bool          // its receipts are still being inserted (see fanout.go)
+	Deleted        bool          // deleted for everyone, kept as a tombstone (see DeleteMessage)
+	PhotoState     string        // processing state of the photo (see processing.go), "" without one
+	Event          *Event        // the event the message announces, nil for most (see groupevents.go)
+	Hash           string        // of its last entry in the hash chain (GetConversationArchive only, see integrity.go)
+	Mentions       []Mention     // the participants its text mentions (see mentions.go)
+	Pinned         bool          // pinned in its conversation (see pins.go)
+	Notice         *Notice       // what a group notice reports, nil for the other messages (see notices.go)
+	Comments       []Comment
+}
+
+// Receipt is where a message stands for one of its recipients
+type Receipt struct {
+	UserID      ids.UserID
+	UserName    string
+	DeliveredAt *time.Time // nil until the recipient fetched the message
+	ReadAt      *time.Time // nil until read, and always nil if the recipient hides read receipts
+}
+
+// MessageStatus is the status of a message, as its sender sees it
+type MessageStatus struct {
+	ID       ids.MessageID
+	SenderID ids.UserID
+	Status   string
+}
+
+// ReplyPreview summarizes the message a reply refers to
+type ReplyPreview struct {
+	Available  bool // false when that message was deleted or is not in the conversation
 	SenderName string
-	Content    string
-	Photo      []byte
-	Timestamp  time.Time
-	Status     string // "sent", "received", "read"
-	ReplyTo    *string
-	Comments   []Comment
+	Content    string // the first replyPreviewLength characters
+	HasPhoto   bool
 }
 
 // Comment represents a reaction on a message
 type Comment struct {
-	UserID   string
+	UserID   ids.UserID
 	UserName string
 	Emoticon string
 }
 
 // ConversationPreview is used for the conversation list
 type ConversationPreview struct {
-	ID                 string
+	ID                 ids.ConversationID
 	IsGroup            bool
+	IsChannel          bool
 	Name               string
-	Photo              []byte
+	PhotoID            string
+	PhotoOwnerID       string // the group (IsGroup) or the other user the photo belongs to
 	LastMessageTime    time.Time
 	LastMessagePreview string
 	LastMessageIsPhoto bool
+	Blocked            bool // a direct conversation with a user the user blocked
+	Deactivated        bool // a direct conversation with a user who deactivated their account
 }
 
 // Conversation contains full conversation details with messages
 type Conversation struct {
-	ID       string
-	IsGroup  bool
-	Name     string
-	Photo    []byte
-	Members  []User
-	Messages []Message
+	ID           ids.ConversationID
+	IsGroup      bool
+	IsChannel    bool
+	Name         string
+	PhotoID      string
+	PhotoOwnerID string // the group (IsGroup) or the other user the photo belongs to
+	Members      []User
+	Messages     []Message
+	HasMore      bool   // older messages exist beyond those in Messages
+	Language     string // of most of the latest messages, "" when unknown
+	// ClearedBefore hides the earlier messages from the user, nil if never cleared
+	ClearedBefore *time.Time
+	// ChainHead is the hash of the last entry of the hash chain (GetConversationArchive only)
+	ChainHead string
+}
+
+// PrivacySettings are what a user shares with the others in one conversation
+type PrivacySettings struct {
+	TypingIndicators bool // the others see when the user is typing
+	ReadReceipts     bool // the others see when the user has read their messages
+}
+
+// ConversationPermissions are what a participant may do in one conversation
+type ConversationPermissions struct {
+	Send                 bool // send and forward messages
+	DeleteOthersMessages bool // delete messages sent by others
+	Rename               bool // change the group name
+	SetPhoto             bool // change the group photo
+	AddMembers           bool // add others to the group
+	Leave                bool // leave the group
+	ManageGroup          bool // change the kind, guest tokens and feed of the group
 }
 
 // appdbimpl implements the AppDatabase interface
 type appdbimpl struct {
-	db *sql.DB
+	db    *sql.DB
+	blobs storage.BlobStore // the bytes of the photos
+
+	// maintenance allows only one RunMaintenance or Checkpoint at a time
+	maintenance sync.Mutex
+
+	// path is the database file, "" for an in-memory database; its
+	// write-ahead log is path-wal
+	path string
+
+	// checkpoints counts the Checkpoint runs
+	checkpoints checkpointCounters
 }
 
-// New creates a new database connection and initializes tables
-func New(filepath string) (AppDatabase, error) {
+/*
+connectionOptions are appended to the database file name.
+
+SQLite allows a single writer at a time. In WAL mode readers are not
+blocked while a message is being written, and immediate transactions
+take the write lock when they begin: concurrent senders then wait their
+turn (up to the busy timeout) instead of failing with "database is
+locked" when a transaction tries to upgrade from reading to writing.
+*/
+const connectionOptions = "_journal_mode=WAL&_txlock=immediate&_busy_timeout=5000"
+
+// New creates a new database connection and initializes tables.
+// Photos are kept in blobs.
+func New(filepath string, blobs storage.BlobStore) (AppDatabase, error) {
+	// Opening the database is not part of any request
+	ctx := context.Background()
+
+	separator := "?"
+	if strings.Contains(filepath, "?") {
+		separator = "&"
+	}
+
 	// Open SQLite database (creates file if it doesn't exist)
-	db, err := sql.Open("sqlite3", filepath)
+	db, err := sql.Open("sqlite3", filepath+separator+connectionOptions)
 	if err != nil {
 		return nil, err
 	}
@@ -137,18 +493,35 @@ func New(filepath string) (AppDatabase, error) {
 		return nil, err
 	}
 
+	// The message search needs FTS5, which is compiled in with a build tag
+	if err := checkFTS5(ctx, db); err != nil {
+		return nil, err
+	}
+
 	// Create tables if they don't exist
-	if err := createTables(db); err != nil {
+	if err := createTables(ctx, db); err != nil {
 		return nil, err
 	}
 
-	return &appdbimpl{db: db}, nil
+	// Upgrade older databases to the current schema
+	if err := runMigrations(db); err != nil {
+		return nil, err
+	}
+
+	// Move the photos of older databases to the blob store
+	adb := &appdbimpl{db: db, blobs: blobs, path: databaseFile(filepath)}
+	if err := adb.moveBlobs(ctx); err != nil {
+		return nil, err
+	}
+
+	return adb, nil
 }
 
-// createTables sets up all the database tables
-func createTables(db *sql.DB) error {
+// createTables sets up the original database tables.
+// Later schema changes live in migrations.go.
+func createTables(ctx context.Context, db *sql.DB) error {
 	// Users table
-	_, err := db.Exec(`
+	_, err := db.ExecContext(ctx, `
 		CREATE TABLE IF NOT EXISTS users (
 			id TEXT PRIMARY KEY,
 			name TEXT UNIQUE NOT NULL,
@@ -160,7 +533,7 @@ func createTables(db *sql.DB) error {
 	}
 
 	// Groups table
-	_, err = db.Exec(`
+	_, err = db.ExecContext(ctx, `
 		CREATE TABLE IF NOT EXISTS groups (
 			id TEXT PRIMARY KEY,
 			name TEXT NOT NULL,
@@ -172,7 +545,7 @@ func createTables(db *sql.DB) error {
 	}
 
 	// Group members table (links users to groups)
-	_, err = db.Exec(`
+	_, err = db.ExecContext(ctx, `
 		CREATE TABLE IF NOT EXISTS group_members (
 			group_id TEXT NOT NULL,
 			user_id TEXT NOT NULL,
@@ -186,7 +559,7 @@ func createTables(db *sql.DB) error {
 	}
 
 	// Conversations table
-	_, err = db.Exec(`
+	_, err = db.ExecContext(ctx, `
 		CREATE TABLE IF NOT EXISTS conversations (
 			id TEXT PRIMARY KEY,
 			is_group BOOLEAN NOT NULL DEFAULT 0,
@@ -199,7 +572,7 @@ func createTables(db *sql.DB) error {
 	}
 
 	// Conversation participants (for direct messages)
-	_, err = db.Exec(`
+	_, err = db.ExecContext(ctx, `
 		CREATE TABLE IF NOT EXISTS conversation_participants (
 			conversation_id TEXT NOT NULL,
 			user_id TEXT NOT NULL,
@@ -214,7 +587,7 @@ func createTables(db *sql.DB) error {
 	}
 
 	// Messages table
-	_, err = db.Exec(`
+	_, err = db.ExecContext(ctx, `
 		CREATE TABLE IF NOT EXISTS messages (
 			id TEXT PRIMARY KEY,
 			conversation_id TEXT NOT NULL,
@@ -234,7 +607,7 @@ func createTables(db *sql.DB) error {
 	}
 
 	// Comments (reactions) table
-	_, err = db.Exec(`
+	_, err = db.ExecContext(ctx, `
 		CREATE TABLE IF NOT EXISTS comments (
 			message_id TEXT NOT NULL,
 			user_id TEXT NOT NULL,
@@ -255,15 +628,3 @@ func createTables(db *sql.DB) error {
 func (db *appdbimpl) Close() error {
 	return db.db.Close()
 }
-
-// Common errors
-var (
-	ErrUserNotFound         = errors.New("user not found")
-	ErrUsernameTaken        = errors.New("username already taken")
-	ErrGroupNotFound        = errors.New("group not found")
-	ErrNotGroupMember       = errors.New("not a member of this group")
-	ErrConversationNotFound = errors.New("conversation not found")
-	ErrMessageNotFound      = errors.New("message not found")
-	ErrNotMessageOwner      = errors.New("cannot delete messages sent by others")
-	ErrCommentNotFound      = errors.New("comment not found")
-)
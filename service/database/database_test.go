@@ -0,0 +1,37 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"wasatext/service/ids"
+	"wasatext/service/storage"
+)
+
+// newTestDB returns a database in a temporary directory, closed at the
+// end of the test
+func newTestDB(t testing.TB) AppDatabase {
+	t.Helper()
+	dir := t.TempDir()
+	blobs, err := storage.NewFileStore(filepath.Join(dir, "blobs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := New(filepath.Join(dir, "wasatext.db"), blobs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+// newTestUser creates a user of the default workspace
+func newTestUser(t testing.TB, db AppDatabase, name string) ids.UserID {
+	t.Helper()
+	userID, err := db.CreateUser(context.Background(), DefaultWorkspaceID, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return userID
+}
@@ -0,0 +1,110 @@
+/*
+Diagnosis of a database file, before the server starts.
+
+Diagnose looks at the file without changing it: it is not created,
+migrated nor moved to WAL mode, and the write probe is rolled back. It
+lets "webapi --diagnose" tell a deployment problem (a read-only volume,
+a database migrated by a newer build, a binary built without FTS5)
+before the server takes traffic.
+*/
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"strings"
+)
+
+// Diagnosis is what Diagnose found out about a database file
+type Diagnosis struct {
+	Exists          bool     // false for a file the server will create
+	SchemaVersion   int      // of the file; 0 for a new file
+	LatestVersion   int      // the version this build migrates to
+	FTS5            bool     // SQLite was built with FTS5, needed by the search
+	WriteError      string   // why the write probe failed, "" when it passed
+	IntegrityErrors []string // found by PRAGMA quick_check
+}
+
+// LatestSchemaVersion is the schema version this build migrates to
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+/*
+Diagnose checks a database file: its schema version against the one of
+this build, that SQLite has FTS5, that the file is sound (a quick check)
+and that a write transaction goes through. A file that does not exist
+yet is only reported as such. The error is for a file that cannot be
+opened or read at all.
+*/
+func Diagnose(ctx context.Context, filepath string) (*Diagnosis, error) {
+	d := Diagnosis{LatestVersion: LatestSchemaVersion(), IntegrityErrors: []string{}}
+
+	name := databaseFile(filepath)
+	if name != "" {
+		if _, err := os.Stat(name); errors.Is(err, os.ErrNotExist) {
+			return &d, nil
+		}
+	}
+	d.Exists = true
+
+	// No journal mode here: switching to WAL would write to the file
+	separator := "?"
+	if strings.Contains(filepath, "?") {
+		separator = "&"
+	}
+	db, err := sql.Open("sqlite3", filepath+separator+"_txlock=immediate&_busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if err := db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&d.SchemaVersion); err != nil {
+		return nil, err
+	}
+	d.FTS5 = checkFTS5(ctx, db) == nil
+
+	rows, err := db.QueryContext(ctx, "PRAGMA quick_check")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var result string
+		if err := rows.Scan(&result); err != nil {
+			return nil, err
+		}
+		if result != "ok" {
+			d.IntegrityErrors = append(d.IntegrityErrors, result)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := writeProbe(ctx, db); err != nil {
+		d.WriteError = err.Error()
+	}
+	return &d, nil
+}
+
+// writeProbe writes to the database in a transaction it rolls back
+func writeProbe(ctx context.Context, db *sql.DB) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, query := range []string{
+		"CREATE TABLE diagnose_probe (value INTEGER)",
+		"INSERT INTO diagnose_probe (value) VALUES (1)",
+	} {
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
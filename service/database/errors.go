@@ -0,0 +1,126 @@
+/*
+Domain errors of the database layer.
+
+Every failure a caller is expected to handle is an *Error. Its Code
+says what kind of failure it is (the API turns codes into HTTP statuses
+in one place), and its ID names the entity involved when it is known.
+
+The Err values below are the kinds of failure. The database returns
+them wrapped with the entity ID, so always compare with errors.Is:
+
+	if errors.Is(err, database.ErrUserNotFound) { ... }
+*/
+package database
+
+// ErrorCode classifies a domain error
+type ErrorCode string
+
+// Error codes
+const (
+	CodeNotFound  ErrorCode = "not_found"
+	CodeConflict  ErrorCode = "conflict"
+	CodeForbidden ErrorCode = "forbidden"
+	CodeInvalid   ErrorCode = "invalid"
+)
+
+// Error is a domain error
+type Error struct {
+	Code    ErrorCode
+	Message string
+	ID      string // the entity the error is about, "" when unknown
+
+	kind *Error // the Err value this error is an instance of
+}
+
+// Error returns the message, followed by the entity ID when there is one
+func (e *Error) Error() string {
+	if e.ID == "" {
+		return e.Message
+	}
+	return e.Message + ": " + e.ID
+}
+
+// Is reports whether the error is an instance of target, so that
+// errors.Is(err, ErrUserNotFound) matches whatever the user ID
+func (e *Error) Is(target error) bool {
+	return e.kind != nil && e.kind == target
+}
+
+// newError defines a kind of domain error
+func newError(code ErrorCode, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// withID returns an error of the given kind about the entity id
+func withID[T ~string](kind *Error, id T) error {
+	return &Error{Code: kind.Code, Message: kind.Message, ID: string(id), kind: kind}
+}
+
+// Domain errors
+var (
+	ErrUserNotFound             = newError(CodeNotFound, "user not found")
+	ErrUsernameTaken            = newError(CodeConflict, "username already taken")
+	ErrUserDeleted              = newError(CodeForbidden, "user account has been deleted")
+	ErrUserBanned               = newError(CodeForbidden, "user account has been banned")
+	ErrGroupNotFound            = newError(CodeNotFound, "group not found")
+	ErrNotGroupMember           = newError(CodeForbidden, "not a member of this group")
+	ErrConversationNotFound     = newError(CodeNotFound, "conversation not found")
+	ErrMessageNotFound          = newError(CodeNotFound, "message not found")
+	ErrNotMessageOwner          = newError(CodeForbidden, "cannot delete messages sent by others")
+	ErrNotMessageEditor         = newError(CodeForbidden, "cannot edit messages sent by others")
+	ErrNotMessageSender         = newError(CodeForbidden, "only the sender can see the receipts of a message")
+	ErrMessageNotEditable       = newError(CodeInvalid, "only text messages can be edited")
+	ErrCommentNotFound          = newError(CodeNotFound, "comment not found")
+	ErrWorkspaceNotFound        = newError(CodeNotFound, "workspace not found")
+	ErrWorkspaceExists          = newError(CodeConflict, "workspace already exists")
+	ErrGuestTokenNotFound       = newError(CodeNotFound, "guest token not found")
+	ErrNotChannelAdmin          = newError(CodeForbidden, "only the channel admin can do this")
+	ErrChannelNeedsAdmin        = newError(CodeConflict, "the group has no admin to run the channel")
+	ErrNotChannel               = newError(CodeConflict, "the group is not a channel")
+	ErrInviteRequired           = newError(CodeForbidden, "an invite code is required to create an account")
+	ErrInviteInvalid            = newError(CodeForbidden, "invite code is invalid, expired or already used")
+	ErrInviteNotFound           = newError(CodeNotFound, "invite not found or already used")
+	ErrHookNotFound             = newError(CodeNotFound, "webhook not found")
+	ErrSessionNotFound          = newError(CodeNotFound, "session not found")
+	ErrWidgetTokenNotFound      = newError(CodeNotFound, "widget token not found")
+	ErrPhotoNotFound            = newError(CodeNotFound, "photo not found")
+	ErrNoteNotFound             = newError(CodeNotFound, "note not found")
+	ErrReminderNotFound         = newError(CodeNotFound, "reminder not found")
+	ErrCannotBlockSelf          = newError(CodeInvalid, "cannot block yourself")
+	ErrBlocked                  = newError(CodeForbidden, "this user does not accept your messages")
+	ErrInvalidPhotoQuality      = newError(CodeInvalid, "quality must be original, high, medium or thumb (size thumb or full)")
+	ErrEventNotFound            = newError(CodeNotFound, "event not found")
+	ErrEventNotInGroup          = newError(CodeInvalid, "events can only be created in groups")
+	ErrConversationDeleted      = newError(CodeConflict, "conversation already deleted")
+	ErrConversationNotDeleted   = newError(CodeConflict, "conversation not deleted")
+	ErrDirectConversationExists = newError(CodeConflict, "the participants have started another direct conversation")
+	ErrScheduledMessageNotFound = newError(CodeNotFound, "scheduled message not found")
+	ErrTooManyScheduled         = newError(CodeConflict, "too many scheduled messages")
+	ErrChannelGoverned          = newError(CodeConflict, "a channel is run by its admin, not by votes")
+	ErrGroupNotGoverned         = newError(CodeConflict, "the group is not under governance")
+	ErrGroupGoverned            = newError(CodeConflict, "the members of the group vote on this change")
+	ErrProposalNotFound         = newError(CodeNotFound, "proposal not found")
+	ErrProposalOpen             = newError(CodeConflict, "the same change is already being voted on")
+	ErrProposalDecided          = newError(CodeConflict, "the proposal has already been decided")
+	ErrInvalidProposal          = newError(CodeInvalid, "kind must be remove_member or rename")
+	ErrRemoveSelf               = newError(CodeInvalid, "cannot propose to remove yourself: leave the group")
+	ErrNotPinner                = newError(CodeForbidden, "only the group admin can pin messages")
+	ErrPinNotFound              = newError(CodeNotFound, "the message is not pinned")
+	ErrTooManyPins              = newError(CodeConflict, "too many pinned messages: unpin one first")
+	ErrKeyBackupNotFound        = newError(CodeNotFound, "no key backup stored")
+	ErrKeyBackupConflict        = newError(CodeConflict, "the key backup was replaced in the meantime")
+	ErrInvalidKeyBackup         = newError(CodeInvalid, "the key backup must be 1 byte to 64 KiB")
+	ErrPushSubscriptionNotFound = newError(CodeNotFound, "push subscription not found")
+	ErrTooManyPushSubscriptions = newError(CodeConflict, "too many push subscriptions: delete one first")
+	ErrInvalidMute              = newError(CodeInvalid, "a conversation can only be muted until a time in the future")
+	ErrEmptyImport              = newError(CodeInvalid, "the imported chat has no message")
+	ErrWebhookNotFound          = newError(CodeNotFound, "webhook not found")
+	ErrTooManyWebhooks          = newError(CodeConflict, "too many webhooks: delete one first")
+	ErrBotNotFound              = newError(CodeNotFound, "bot not found")
+	ErrBotAccount               = newError(CodeForbidden, "bot accounts cannot log in")
+
+	ErrModerationItemNotFound  = newError(CodeNotFound, "moderation item not found")
+	ErrModerationItemResolved  = newError(CodeConflict, "moderation item already resolved")
+	ErrNoMessageToModerate     = newError(CodeInvalid, "moderation item has no message")
+	ErrInvalidModerationAction = newError(CodeInvalid, "invalid moderation action")
+)
@@ -0,0 +1,133 @@
+/*
+Database operations for the receipt fanout.
+
+Every message has a receipt per recipient (see messageStatusSQL). For a
+conversation with up to FanoutThreshold participants the receipts are
+inserted with the message. Past that, the message is only marked as
+pending and FanOutReceipts inserts the receipts later, batch by batch,
+so that sending to a large group does not wait for thousands of rows.
+The inserts are idempotent: a batch that ran twice, or a fanout resumed
+after a restart, does not duplicate anything. A pending message shows
+as "sent" until all of its receipts exist.
+*/
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+
+	"wasatext/service/ids"
+)
+
+// FanoutThreshold is the number of participants above which the
+// receipts of a message are inserted in the background
+const FanoutThreshold = 256
+
+// txExecer is what insertReceipts needs from a transaction
+type txExecer interface {
+	queryRower
+	execer
+}
+
+// insertReceipts creates a pending receipt for every participant of the
+// conversation but the sender, or marks the message as pending when the
+// conversation is large. It reports whether the fanout is pending.
+func insertReceipts(ctx context.Context, tx txExecer, messageID ids.MessageID, conversationID ids.ConversationID, senderID ids.UserID) (bool, error) {
+	var participants int
+	err := tx.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM conversation_participants WHERE conversation_id = ?",
+		conversationID,
+	).Scan(&participants)
+	if err != nil {
+		return false, err
+	}
+
+	if participants > FanoutThreshold {
+		_, err = tx.ExecContext(ctx, "UPDATE messages SET fanout_pending = 1 WHERE id = ?", messageID)
+		return true, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO message_receipts (message_id, user_id)
+		SELECT ?, user_id FROM conversation_participants
+		WHERE conversation_id = ? AND user_id != ?
+	`, messageID, conversationID, senderID)
+	return false, err
+}
+
+/*
+FanOutReceipts inserts up to batchSize missing receipts of a pending
+message and reports whether the fanout is done. A message that was
+deleted in the meantime is done.
+*/
+func (db *appdbimpl) FanOutReceipts(ctx context.Context, messageID ids.MessageID, batchSize int) (bool, error) {
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+	}()
+
+	var conversationID ids.ConversationID
+	var senderID ids.UserID
+	var pending bool
+	err = tx.QueryRowContext(ctx,
+		"SELECT conversation_id, sender_id, fanout_pending FROM messages WHERE id = ?",
+		messageID,
+	).Scan(&conversationID, &senderID, &pending)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !pending) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT OR IGNORE INTO message_receipts (message_id, user_id)
+		SELECT ?, cp.user_id FROM conversation_participants cp
+		WHERE cp.conversation_id = ? AND cp.user_id != ?
+		AND NOT EXISTS (SELECT 1 FROM message_receipts r WHERE r.message_id = ? AND r.user_id = cp.user_id)
+		LIMIT ?
+	`, messageID, conversationID, senderID, messageID, batchSize)
+	if err != nil {
+		return false, err
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	done := inserted < int64(batchSize)
+	if done {
+		if _, err := tx.ExecContext(ctx, "UPDATE messages SET fanout_pending = 0 WHERE id = ?", messageID); err != nil {
+			return false, err
+		}
+	}
+
+	return done, tx.Commit()
+}
+
+// PendingFanouts returns the messages whose receipts are not all inserted yet, oldest first
+func (db *appdbimpl) PendingFanouts(ctx context.Context) ([]ids.MessageID, error) {
+	rows, err := db.db.QueryContext(ctx, "SELECT id FROM messages WHERE fanout_pending = 1 ORDER BY timestamp")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pending []ids.MessageID
+	for rows.Next() {
+		var id ids.MessageID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		pending = append(pending, id)
+	}
+
+	return pending, rows.Err()
+}
@@ -0,0 +1,406 @@
+/*
+Database operations for group governance.
+
+By default any member renames a group and nobody removes anyone. The
+group admin can put a group under governance: then renaming it, or
+removing a member, is a proposal the members vote on. A proposal passes
+once more than half of the current members approve it, and its action
+runs in the same transaction as the deciding vote; it is rejected once
+half of them or more disapprove. The proposer approves by proposing,
+and members leaving the group take back their votes on open proposals.
+
+Taking a group out of governance cancels its open proposals. Channels
+are run by their admin and cannot be governed.
+*/
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"wasatext/service/ids"
+)
+
+// Kinds of proposal
+const (
+	ProposalRemoveMember = "remove_member"
+	ProposalRename       = "rename"
+)
+
+// Statuses of a proposal
+const (
+	ProposalOpen      = "open"
+	ProposalPassed    = "passed"
+	ProposalRejected  = "rejected"
+	ProposalCancelled = "cancelled"
+)
+
+// Proposal is a change of a governed group put to the vote of its members
+type Proposal struct {
+	ID             int64
+	GroupID        ids.GroupID
+	Kind           string     // ProposalRemoveMember or ProposalRename
+	TargetUserID   ids.UserID // the member to remove
+	TargetUserName string
+	Name           string // the new name
+	ProposedBy     ids.UserID
+	ProposerName   string
+	CreatedAt      time.Time
+	Status         string
+	ResolvedAt     *time.Time
+	Approvals      int
+	Rejections     int
+	Members        int   // the members who decide an open proposal
+	MyVote         *bool // the vote of the member listing, nil if none
+}
+
+// proposalSQL selects the proposals for a member (the first argument);
+// the caller adds the condition
+const proposalSQL = `
+	SELECT p.id, p.group_id, p.kind, COALESCE(p.target_user_id, ''), COALESCE(tu.name, ''), COALESCE(p.new_name, ''),
+		p.proposed_by, pu.name, p.created_at, p.status, p.resolved_at,
+		(SELECT COUNT(*) FROM group_votes v WHERE v.proposal_id = p.id AND v.approve = 1),
+		(SELECT COUNT(*) FROM group_votes v WHERE v.proposal_id = p.id AND v.approve = 0),
+		(SELECT COUNT(*) FROM group_members gm WHERE gm.group_id = p.group_id),
+		(SELECT v.approve FROM group_votes v WHERE v.proposal_id = p.id AND v.user_id = ?)
+	FROM group_proposals p
+	JOIN users pu ON pu.id = p.proposed_by
+	LEFT JOIN users tu ON tu.id = p.target_user_id`
+
+// scanProposal reads a row selected by proposalSQL
+func scanProposal(row interface{ Scan(...interface{}) error }) (*Proposal, error) {
+	var p Proposal
+	var resolvedAt sql.NullTime
+	var myVote sql.NullBool
+	if err := row.Scan(
+		&p.ID, &p.GroupID, &p.Kind, &p.TargetUserID, &p.TargetUserName, &p.Name,
+		&p.ProposedBy, &p.ProposerName, &p.CreatedAt, &p.Status, &resolvedAt,
+		&p.Approvals, &p.Rejections, &p.Members, &myVote,
+	); err != nil {
+		return nil, err
+	}
+	if resolvedAt.Valid {
+		p.ResolvedAt = &resolvedAt.Time
+	}
+	if myVote.Valid {
+		p.MyVote = &myVote.Bool
+	}
+	return &p, nil
+}
+
+// getProposal retrieves a proposal of a group for a member
+func getProposal(ctx context.Context, q rowQuerier, groupID ids.GroupID, proposalID int64, userID ids.UserID) (*Proposal, error) {
+	p, err := scanProposal(q.QueryRowContext(ctx, proposalSQL+" WHERE p.id = ? AND p.group_id = ?", userID, proposalID, groupID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, withID(ErrProposalNotFound, strconv.FormatInt(proposalID, 10))
+	}
+	return p, err
+}
+
+// IsGroupGoverned reports whether the members vote on the changes of a group
+func (db *appdbimpl) IsGroupGoverned(ctx context.Context, groupID ids.GroupID) (bool, error) {
+	var governed bool
+	err := db.db.QueryRowContext(ctx,
+		"SELECT governance = 1 AND kind = ? FROM groups WHERE id = ? AND deleted_at IS NULL",
+		GroupKindGroup, groupID,
+	).Scan(&governed)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, withID(ErrGroupNotFound, groupID)
+	}
+	return governed, err
+}
+
+// SetGroupGovernance puts a group under governance or takes it out,
+// cancelling its open proposals
+func (db *appdbimpl) SetGroupGovernance(ctx context.Context, groupID ids.GroupID, enabled bool) error {
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+	}()
+
+	var kind string
+	err = tx.QueryRowContext(ctx, "SELECT kind FROM groups WHERE id = ? AND deleted_at IS NULL", groupID).Scan(&kind)
+	if errors.Is(err, sql.ErrNoRows) {
+		return withID(ErrGroupNotFound, groupID)
+	}
+	if err != nil {
+		return err
+	}
+	if enabled && kind == GroupKindChannel {
+		return withID(ErrChannelGoverned, groupID)
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE groups SET governance = ? WHERE id = ?", enabled, groupID); err != nil {
+		return err
+	}
+	if !enabled {
+		_, err = tx.ExecContext(ctx,
+			"UPDATE group_proposals SET status = ?, resolved_at = ? WHERE group_id = ? AND status = ?",
+			ProposalCancelled, time.Now(), groupID, ProposalOpen,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+/*
+CreateProposal puts a change of a governed group to the vote: the
+removal of targetID (ProposalRemoveMember) or the new name
+(ProposalRename). Only members propose, the member to remove must be in
+the group, and a change already open cannot be proposed twice (409).
+The proposer's approval is recorded, which passes the proposal at once
+in a group of one.
+*/
+func (db *appdbimpl) CreateProposal(ctx context.Context, groupID ids.GroupID, proposerID ids.UserID, kind string, targetID ids.UserID, name string) (*Proposal, error) {
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+	}()
+
+	// Step 1: The group must be governed, and the proposer a member
+	if err := checkGoverned(ctx, tx, groupID, proposerID); err != nil {
+		return nil, err
+	}
+
+	// Step 2: Check the change
+	var target, newName interface{}
+	switch kind {
+	case ProposalRemoveMember:
+		if targetID == proposerID {
+			return nil, ErrRemoveSelf
+		}
+		var isMember bool
+		err := tx.QueryRowContext(ctx,
+			"SELECT EXISTS (SELECT 1 FROM group_members WHERE group_id = ? AND user_id = ?)",
+			groupID, targetID,
+		).Scan(&isMember)
+		if err != nil {
+			return nil, err
+		}
+		if !isMember {
+			return nil, withID(ErrUserNotFound, targetID)
+		}
+		target = targetID
+	case ProposalRename:
+		newName = name
+	default:
+		return nil, ErrInvalidProposal
+	}
+	var open bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM group_proposals
+			WHERE group_id = ? AND status = ? AND kind = ? AND (kind = ? OR target_user_id = ?))
+	`, groupID, ProposalOpen, kind, ProposalRename, targetID).Scan(&open)
+	if err != nil {
+		return nil, err
+	}
+	if open {
+		return nil, withID(ErrProposalOpen, groupID)
+	}
+
+	// Step 3: Record it with the proposer's approval
+	now := time.Now()
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO group_proposals (group_id, kind, target_user_id, new_name, proposed_by, created_at, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, groupID, kind, target, newName, proposerID, now, ProposalOpen)
+	if err != nil {
+		return nil, err
+	}
+	proposalID, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	p, err := castVote(ctx, tx, groupID, proposalID, proposerID, true, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// GetProposals returns the proposals of a group to a member: the open
+// ones first, then the newest
+func (db *appdbimpl) GetProposals(ctx context.Context, groupID ids.GroupID, userID ids.UserID) ([]Proposal, error) {
+	isMember, err := db.IsGroupMember(ctx, groupID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, withID(ErrNotGroupMember, groupID)
+	}
+
+	rows, err := db.db.QueryContext(ctx, proposalSQL+`
+		WHERE p.group_id = ?
+		ORDER BY p.status = 'open' DESC, p.created_at DESC, p.id DESC
+	`, userID, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	proposals := []Proposal{}
+	for rows.Next() {
+		p, err := scanProposal(rows)
+		if err != nil {
+			return nil, err
+		}
+		proposals = append(proposals, *p)
+	}
+	return proposals, rows.Err()
+}
+
+/*
+VoteOnProposal records (or changes) the vote of a member on an open
+proposal, and decides it once a majority is reached: a passed proposal
+removes the member or renames the group before the vote is committed.
+The proposal is returned with its new status; a decided one takes no
+more votes (409).
+*/
+func (db *appdbimpl) VoteOnProposal(ctx context.Context, groupID ids.GroupID, proposalID int64, userID ids.UserID, approve bool) (*Proposal, error) {
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+	}()
+
+	if err := checkGoverned(ctx, tx, groupID, userID); err != nil {
+		return nil, err
+	}
+	p, err := castVote(ctx, tx, groupID, proposalID, userID, approve, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// checkGoverned checks that a group is governed and the user one of its
+// members
+func checkGoverned(ctx context.Context, tx *sql.Tx, groupID ids.GroupID, userID ids.UserID) error {
+	var governed, isMember bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT governance = 1 AND kind = ?,
+			EXISTS (SELECT 1 FROM group_members WHERE group_id = g.id AND user_id = ?)
+		FROM groups g WHERE id = ? AND deleted_at IS NULL
+	`, GroupKindGroup, userID, groupID).Scan(&governed, &isMember)
+	if errors.Is(err, sql.ErrNoRows) {
+		return withID(ErrGroupNotFound, groupID)
+	}
+	if err != nil {
+		return err
+	}
+	if !isMember {
+		return withID(ErrNotGroupMember, groupID)
+	}
+	if !governed {
+		return withID(ErrGroupNotGoverned, groupID)
+	}
+	return nil
+}
+
+// castVote records a vote on an open proposal and decides it if the
+// votes make a majority of the current members
+func castVote(ctx context.Context, tx *sql.Tx, groupID ids.GroupID, proposalID int64, userID ids.UserID, approve bool, now time.Time) (*Proposal, error) {
+	p, err := getProposal(ctx, tx, groupID, proposalID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if p.Status != ProposalOpen {
+		return nil, withID(ErrProposalDecided, strconv.FormatInt(proposalID, 10))
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO group_votes (proposal_id, user_id, approve, created_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (proposal_id, user_id) DO UPDATE SET approve = excluded.approve, created_at = excluded.created_at
+	`, proposalID, userID, approve, now)
+	if err != nil {
+		return nil, err
+	}
+	if p, err = getProposal(ctx, tx, groupID, proposalID, userID); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case 2*p.Approvals > p.Members:
+		p.Status = ProposalPassed
+		if err := applyProposal(ctx, tx, p); err != nil {
+			return nil, err
+		}
+	case 2*p.Rejections >= p.Members:
+		p.Status = ProposalRejected
+	default:
+		return p, nil
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE group_proposals SET status = ?, resolved_at = ? WHERE id = ?",
+		p.Status, now, proposalID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	p.ResolvedAt = &now
+	return p, nil
+}
+
+// applyProposal makes the change a proposal passed
+func applyProposal(ctx context.Context, tx *sql.Tx, p *Proposal) error {
+	if p.Kind == ProposalRename {
+		_, err := tx.ExecContext(ctx, "UPDATE groups SET name = ? WHERE id = ?", p.Name, p.GroupID)
+		return err
+	}
+
+	// A member who left meanwhile is already out
+	_, err := tx.ExecContext(ctx,
+		"DELETE FROM group_members WHERE group_id = ? AND user_id = ?",
+		p.GroupID, p.TargetUserID,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM conversation_participants WHERE user_id = ?
+		AND conversation_id = (SELECT id FROM conversations WHERE group_id = ?)
+	`, p.TargetUserID, p.GroupID)
+	if err != nil {
+		return err
+	}
+	return dropOpenVotes(ctx, tx, p.GroupID, p.TargetUserID)
+}
+
+// dropOpenVotes withdraws the votes of a member leaving a group from its
+// open proposals: only the members decide
+func dropOpenVotes(ctx context.Context, q execer, groupID ids.GroupID, userID ids.UserID) error {
+	_, err := q.ExecContext(ctx, `
+		DELETE FROM group_votes WHERE user_id = ?
+		AND proposal_id IN (SELECT id FROM group_proposals WHERE group_id = ? AND status = ?)
+	`, userID, groupID, ProposalOpen)
+	return err
+}
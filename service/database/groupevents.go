@@ -0,0 +1,359 @@
+/*
+Database operations for group events.
+
+An event is a message of a group (its text is the title) with a start
+time and a place, kept in group_events. The members answer whether they
+go (event_rsvps); only the answers of the current members count. An
+event goes away with its message, deleted or turned into a tombstone
+(triggers, see migrateGroupEvents), and its answers with it.
+
+An event may have a reminder time: once it has passed, the reminder is
+posted in the group as a system message replying to the event (see
+DueEventReminders and SendEventReminder), so it reaches the members
+like any other message.
+*/
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"wasatext/service/ids"
+)
+
+// Answers to an event
+const (
+	RSVPGoing    = "going"
+	RSVPMaybe    = "maybe"
+	RSVPDeclined = "declined"
+)
+
+// NewEvent describes an event to create
+type NewEvent struct {
+	Title    string
+	StartsAt time.Time
+	Location string
+	RemindAt time.Time // zero for no reminder
+}
+
+// Event is an event of a group, as one member sees it
+type Event struct {
+	StartsAt time.Time
+	Location string
+	Going    int
+	Maybe    int
+	Declined int
+	MyRSVP   string // the member's answer, "" if none
+}
+
+// RSVP is the answer of a member to an event
+type RSVP struct {
+	UserID    ids.UserID
+	UserName  string
+	Response  string
+	UpdatedAt time.Time
+}
+
+// EventReminder is an event whose reminder is due
+type EventReminder struct {
+	MessageID      ids.MessageID
+	ConversationID ids.ConversationID
+	CreatorID      ids.UserID
+	Title          string
+	StartsAt       time.Time
+	Location       string
+}
+
+// eventSQL selects the events of the messages, as the user (the first
+// parameter) sees them; the caller adds the condition on e
+const eventSQL = `
+	SELECT e.message_id, e.starts_at, e.location,
+		COUNT(CASE WHEN r.response = '` + RSVPGoing + `' THEN 1 END),
+		COUNT(CASE WHEN r.response = '` + RSVPMaybe + `' THEN 1 END),
+		COUNT(CASE WHEN r.response = '` + RSVPDeclined + `' THEN 1 END),
+		COALESCE(MAX(CASE WHEN r.user_id = ? THEN r.response END), '')
+	FROM group_events e
+	LEFT JOIN conversation_participants rp ON rp.conversation_id = e.conversation_id
+	LEFT JOIN event_rsvps r ON r.message_id = e.message_id AND r.user_id = rp.user_id`
+
+/*
+CreateEvent posts an event in a group: a message whose text is the
+title, and the event attached to it. Only the participants may post,
+and only the admin in a channel.
+*/
+func (db *appdbimpl) CreateEvent(ctx context.Context, conversationID ids.ConversationID, senderID ids.UserID, event NewEvent) (*Message, error) {
+	if err := db.checkParticipant(ctx, senderID, conversationID); err != nil {
+		return nil, err
+	}
+	id, err := newMessageID(ctx, db.db)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+	}()
+
+	var isGroup bool
+	err = tx.QueryRowContext(ctx, "SELECT is_group FROM conversations WHERE id = ?", conversationID).Scan(&isGroup)
+	if err != nil {
+		return nil, err
+	}
+	if !isGroup {
+		return nil, withID(ErrEventNotInGroup, conversationID)
+	}
+	if err := checkCanPost(ctx, tx, conversationID, senderID); err != nil {
+		return nil, err
+	}
+
+	// Times are stored in the local time zone, like time.Now(), so that
+	// they compare as text
+	var remindAt interface{}
+	if !event.RemindAt.IsZero() {
+		remindAt = event.RemindAt.Local()
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO messages (id, conversation_id, sender_id, content, timestamp, language)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, id, conversationID, senderID, event.Title, time.Now(), messageLanguage(event.Title))
+	if err != nil {
+		return nil, err
+	}
+	if err := chainMessage(ctx, tx, id); err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO group_events (message_id, conversation_id, starts_at, location, remind_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, id, conversationID, event.StartsAt.Local(), event.Location, remindAt)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := insertReceipts(ctx, tx, id, conversationID, senderID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return db.GetMessage(ctx, id)
+}
+
+// attachEvents sets the Event of the messages that announce one, as
+// viewerID sees it ("" for no one)
+func (db *appdbimpl) attachEvents(ctx context.Context, viewerID ids.UserID, messages []Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	args := make([]interface{}, 0, len(messages)+1)
+	args = append(args, viewerID)
+	index := make(map[ids.MessageID]int, len(messages))
+	for i, msg := range messages {
+		args = append(args, msg.ID)
+		index[msg.ID] = i
+	}
+	placeholders := strings.Repeat(", ?", len(messages))[2:]
+
+	rows, err := db.db.QueryContext(ctx, eventSQL+`
+		WHERE e.message_id IN (`+placeholders+`)
+		GROUP BY e.message_id
+	`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var messageID ids.MessageID
+		var e Event
+		if err := rows.Scan(&messageID, &e.StartsAt, &e.Location, &e.Going, &e.Maybe, &e.Declined, &e.MyRSVP); err != nil {
+			return err
+		}
+		messages[index[messageID]].Event = &e
+	}
+	return rows.Err()
+}
+
+// checkEventVisible fails unless messageID announces an event the user
+// can read
+func (db *appdbimpl) checkEventVisible(ctx context.Context, userID ids.UserID, messageID ids.MessageID) error {
+	var visible bool
+	err := db.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM messages m`+visibleMessages+`
+			JOIN group_events e ON e.message_id = m.id WHERE m.id = ?)
+	`, userID, messageID).Scan(&visible)
+	if err != nil {
+		return err
+	}
+	if !visible {
+		return withID(ErrEventNotFound, messageID)
+	}
+	return nil
+}
+
+// getEvent returns an event as the user sees it
+func (db *appdbimpl) getEvent(ctx context.Context, userID ids.UserID, messageID ids.MessageID) (*Event, error) {
+	var id ids.MessageID
+	var e Event
+	err := db.db.QueryRowContext(ctx, eventSQL+`
+		WHERE e.message_id = ?
+		GROUP BY e.message_id
+	`, userID, messageID).Scan(&id, &e.StartsAt, &e.Location, &e.Going, &e.Maybe, &e.Declined, &e.MyRSVP)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, withID(ErrEventNotFound, messageID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// SetRSVP records the answer of the user to an event they can read,
+// replacing their previous one
+func (db *appdbimpl) SetRSVP(ctx context.Context, userID ids.UserID, messageID ids.MessageID, response string) (*Event, error) {
+	if err := db.checkEventVisible(ctx, userID, messageID); err != nil {
+		return nil, err
+	}
+	_, err := db.db.ExecContext(ctx, `
+		INSERT INTO event_rsvps (message_id, user_id, response, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (message_id, user_id) DO UPDATE SET response = excluded.response, updated_at = excluded.updated_at
+	`, messageID, userID, response, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return db.getEvent(ctx, userID, messageID)
+}
+
+// DeleteRSVP withdraws the answer of the user to an event; withdrawing
+// no answer is not an error
+func (db *appdbimpl) DeleteRSVP(ctx context.Context, userID ids.UserID, messageID ids.MessageID) error {
+	if err := db.checkEventVisible(ctx, userID, messageID); err != nil {
+		return err
+	}
+	_, err := db.db.ExecContext(ctx, "DELETE FROM event_rsvps WHERE message_id = ? AND user_id = ?", messageID, userID)
+	return err
+}
+
+// GetRSVPs returns the answers of the members to an event the user can
+// read, by answer then by name
+func (db *appdbimpl) GetRSVPs(ctx context.Context, userID ids.UserID, messageID ids.MessageID) ([]RSVP, error) {
+	if err := db.checkEventVisible(ctx, userID, messageID); err != nil {
+		return nil, err
+	}
+	rows, err := db.db.QueryContext(ctx, `
+		SELECT r.user_id, u.name, r.response, r.updated_at
+		FROM event_rsvps r
+		JOIN group_events e ON e.message_id = r.message_id
+		JOIN conversation_participants rp ON rp.conversation_id = e.conversation_id AND rp.user_id = r.user_id
+		JOIN users u ON u.id = r.user_id
+		WHERE r.message_id = ?
+		ORDER BY CASE r.response WHEN ? THEN 0 WHEN ? THEN 1 ELSE 2 END, u.name
+	`, messageID, RSVPGoing, RSVPMaybe)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rsvps := []RSVP{}
+	for rows.Next() {
+		var r RSVP
+		if err := rows.Scan(&r.UserID, &r.UserName, &r.Response, &r.UpdatedAt); err != nil {
+			return nil, err
+		}
+		rsvps = append(rsvps, r)
+	}
+	return rsvps, rows.Err()
+}
+
+// DueEventReminders returns the events whose reminder time has passed
+// by now, that have not started and whose reminder was not sent yet,
+// the earliest first
+func (db *appdbimpl) DueEventReminders(ctx context.Context, now time.Time) ([]EventReminder, error) {
+	rows, err := db.db.QueryContext(ctx, `
+		SELECT e.message_id, e.conversation_id, m.sender_id, COALESCE(m.content, ''), e.starts_at, e.location
+		FROM group_events e
+		JOIN messages m ON m.id = e.message_id
+		WHERE e.reminded_at IS NULL AND e.remind_at <= ? AND e.starts_at > ?
+		ORDER BY e.remind_at
+	`, now, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var due []EventReminder
+	for rows.Next() {
+		var r EventReminder
+		if err := rows.Scan(&r.MessageID, &r.ConversationID, &r.CreatorID, &r.Title, &r.StartsAt, &r.Location); err != nil {
+			return nil, err
+		}
+		due = append(due, r)
+	}
+	return due, rows.Err()
+}
+
+/*
+SendEventReminder posts the reminder of an event in its group, on
+behalf of the user who created the event: a system message with the
+given notice, replying to the event. It returns nil when the reminder
+was sent already (or the event is gone), so it is never sent twice.
+*/
+func (db *appdbimpl) SendEventReminder(ctx context.Context, reminder EventReminder, notice string) (*Message, error) {
+	id, err := newMessageID(ctx, db.db)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+	}()
+
+	now := time.Now()
+	result, err := tx.ExecContext(ctx,
+		"UPDATE group_events SET reminded_at = ? WHERE message_id = ? AND reminded_at IS NULL",
+		now, reminder.MessageID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO messages (id, conversation_id, sender_id, content, timestamp, reply_to, system)
+		VALUES (?, ?, ?, ?, ?, ?, 1)
+	`, id, reminder.ConversationID, reminder.CreatorID, notice, now, reminder.MessageID)
+	if err != nil {
+		return nil, err
+	}
+	if err := chainMessage(ctx, tx, id); err != nil {
+		return nil, err
+	}
+	if _, err := insertReceipts(ctx, tx, id, reminder.ConversationID, reminder.CreatorID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return db.GetMessage(ctx, id)
+}
@@ -6,23 +6,40 @@ This file handles creating groups, adding/removing members, etc.
 package database
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"log"
+	"time"
 
-	"github.com/gofrs/uuid"
+	"wasatext/service/ids"
 )
 
 // CreateGroup creates a new group and adds the creator and initial members
-func (db *appdbimpl) CreateGroup(name string, creatorID string, memberIDs []string) (*Group, error) {
+// The group lives in the creator's workspace; every member must belong to it.
+func (db *appdbimpl) CreateGroup(ctx context.Context, name string, creatorID ids.UserID, memberIDs []ids.UserID) (*Group, error) {
+	creator, err := db.GetUserByID(ctx, creatorID)
+	if err != nil {
+		return nil, err
+	}
+	for _, memberID := range memberIDs {
+		member, err := db.GetUserByID(ctx, memberID)
+		if err != nil {
+			return nil, err
+		}
+		if member.WorkspaceID != creator.WorkspaceID {
+			return nil, withID(ErrUserNotFound, member.ID)
+		}
+	}
+
 	// Generate group ID
-	id, err := uuid.NewV4()
+	id, err := newGroupID(ctx, db.db)
 	if err != nil {
 		return nil, err
 	}
 
 	// Start a transaction (all or nothing - if one step fails, roll back all)
-	tx, err := db.db.Begin()
+	tx, err := db.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -33,37 +50,40 @@ func (db *appdbimpl) CreateGroup(name string, creatorID string, memberIDs []stri
 	}()
 
 	// Create the group
-	_, err = tx.Exec("INSERT INTO groups (id, name) VALUES (?, ?)", id.String(), name)
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO groups (id, workspace_id, name) VALUES (?, ?, ?)",
+		id, creator.WorkspaceID, name,
+	)
 	if err != nil {
 		return nil, err
 	}
 
 	// Create a conversation for this group
-	convID, err := uuid.NewV4()
+	convID, err := newConversationID(ctx, tx)
 	if err != nil {
 		return nil, err
 	}
-	_, err = tx.Exec(
-		"INSERT INTO conversations (id, is_group, group_id) VALUES (?, 1, ?)",
-		convID.String(), id.String(),
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO conversations (id, workspace_id, is_group, group_id, created_by, created_at) VALUES (?, ?, 1, ?, ?, ?)",
+		convID, creator.WorkspaceID, id, creatorID, time.Now(),
 	)
 	if err != nil {
 		return nil, err
 	}
 
 	// Add the creator as a member
-	_, err = tx.Exec(
+	_, err = tx.ExecContext(ctx,
 		"INSERT INTO group_members (group_id, user_id) VALUES (?, ?)",
-		id.String(), creatorID,
+		id, creatorID,
 	)
 	if err != nil {
 		return nil, err
 	}
 
 	// Add creator to conversation participants
-	_, err = tx.Exec(
+	_, err = tx.ExecContext(ctx,
 		"INSERT INTO conversation_participants (conversation_id, user_id) VALUES (?, ?)",
-		convID.String(), creatorID,
+		convID, creatorID,
 	)
 	if err != nil {
 		return nil, err
@@ -75,17 +95,17 @@ func (db *appdbimpl) CreateGroup(name string, creatorID string, memberIDs []stri
 			continue // Skip if already added
 		}
 
-		_, err = tx.Exec(
+		_, err = tx.ExecContext(ctx,
 			"INSERT INTO group_members (group_id, user_id) VALUES (?, ?)",
-			id.String(), memberID,
+			id, memberID,
 		)
 		if err != nil {
 			return nil, err
 		}
 
-		_, err = tx.Exec(
+		_, err = tx.ExecContext(ctx,
 			"INSERT INTO conversation_participants (conversation_id, user_id) VALUES (?, ?)",
-			convID.String(), memberID,
+			convID, memberID,
 		)
 		if err != nil {
 			return nil, err
@@ -98,34 +118,41 @@ func (db *appdbimpl) CreateGroup(name string, creatorID string, memberIDs []stri
 	}
 
 	// Return the created group
-	return db.GetGroup(id.String())
+	return db.GetGroup(ctx, id)
 }
 
 // GetGroup retrieves a group by ID with all its members
-func (db *appdbimpl) GetGroup(groupID string) (*Group, error) {
+func (db *appdbimpl) GetGroup(ctx context.Context, groupID ids.GroupID) (*Group, error) {
 	var group Group
-	var photo sql.NullString
+	var photo, adminID sql.NullString
+	var createdAt sql.NullTime
 
-	// Get group info
-	err := db.db.QueryRow(
-		"SELECT id, name, photo FROM groups WHERE id = ?",
-		groupID,
-	).Scan(&group.ID, &group.Name, &photo)
+	// Get group info, with the admin and creation time of its conversation
+	err := db.db.QueryRowContext(ctx, `
+		SELECT g.id, g.workspace_id, g.name, g.description, g.kind, g.photo_id, g.governance = 1, c.created_by, c.created_at
+		FROM groups g
+		LEFT JOIN conversations c ON c.group_id = g.id AND c.is_group = 1
+		WHERE g.id = ? AND g.deleted_at IS NULL
+	`, groupID).Scan(&group.ID, &group.WorkspaceID, &group.Name, &group.Description, &group.Kind, &photo, &group.Governance, &adminID, &createdAt)
 
 	if errors.Is(err, sql.ErrNoRows) {
-		return nil, ErrGroupNotFound
+		return nil, withID(ErrGroupNotFound, groupID)
 	}
 	if err != nil {
 		return nil, err
 	}
 
 	if photo.Valid {
-		group.Photo = []byte(photo.String)
+		group.PhotoID = photo.String
+	}
+	group.AdminID = ids.UserID(adminID.String)
+	if createdAt.Valid {
+		group.CreatedAt = &createdAt.Time
 	}
 
 	// Get group members
-	rows, err := db.db.Query(`
-		SELECT u.id, u.name, u.photo 
+	rows, err := db.db.QueryContext(ctx, `
+		SELECT u.id, u.name, u.photo_id, u.deactivated_at IS NOT NULL, u.last_seen, u.hide_presence, u.about
 		FROM users u 
 		JOIN group_members gm ON u.id = gm.user_id 
 		WHERE gm.group_id = ?
@@ -138,14 +165,16 @@ func (db *appdbimpl) GetGroup(groupID string) (*Group, error) {
 	for rows.Next() {
 		var user User
 		var userPhoto sql.NullString
+		var lastSeen sql.NullTime
 
-		if err := rows.Scan(&user.ID, &user.Name, &userPhoto); err != nil {
+		if err := rows.Scan(&user.ID, &user.Name, &userPhoto, &user.Deactivated, &lastSeen, &user.HidePresence, &user.About); err != nil {
 			return nil, err
 		}
 
 		if userPhoto.Valid {
-			user.Photo = []byte(userPhoto.String)
+			user.PhotoID = userPhoto.String
 		}
+		user.readLastSeen(lastSeen)
 
 		group.Members = append(group.Members, user)
 	}
@@ -154,26 +183,47 @@ func (db *appdbimpl) GetGroup(groupID string) (*Group, error) {
 }
 
 // AddUserToGroup adds a user to a group
-// Only existing group members can add others (enforced in API layer)
-func (db *appdbimpl) AddUserToGroup(groupID, userID, adderID string) error {
-	// Check if adder is a member
-	isMember, err := db.IsGroupMember(groupID, adderID)
+// Only existing group members can add others; in a channel only the
+// admin adds subscribers, but anyone can join on their own
+func (db *appdbimpl) AddUserToGroup(ctx context.Context, groupID ids.GroupID, userID, adderID ids.UserID) error {
+	group, err := db.GetGroup(ctx, groupID)
 	if err != nil {
 		return err
 	}
-	if !isMember {
-		return ErrNotGroupMember
+
+	// Check if adder may add the user
+	if group.Kind == GroupKindChannel {
+		if adderID != userID {
+			adminID, err := db.GetGroupAdmin(ctx, groupID)
+			if err != nil {
+				return err
+			}
+			if adminID != adderID {
+				return withID(ErrNotChannelAdmin, groupID)
+			}
+		}
+	} else {
+		isMember, err := db.IsGroupMember(ctx, groupID, adderID)
+		if err != nil {
+			return err
+		}
+		if !isMember {
+			return withID(ErrNotGroupMember, groupID)
+		}
 	}
 
-	// Check if user to add exists
-	_, err = db.GetUserByID(userID)
+	// Check if user to add exists in the group's workspace
+	user, err := db.GetUserByID(ctx, userID)
 	if err != nil {
 		return err
 	}
+	if user.WorkspaceID != group.WorkspaceID {
+		return withID(ErrUserNotFound, userID)
+	}
 
 	// Get the conversation ID for this group
-	var convID string
-	err = db.db.QueryRow(
+	var convID ids.ConversationID
+	err = db.db.QueryRowContext(ctx,
 		"SELECT id FROM conversations WHERE group_id = ?",
 		groupID,
 	).Scan(&convID)
@@ -182,7 +232,7 @@ func (db *appdbimpl) AddUserToGroup(groupID, userID, adderID string) error {
 	}
 
 	// Add to group_members
-	_, err = db.db.Exec(
+	_, err = db.db.ExecContext(ctx,
 		"INSERT OR IGNORE INTO group_members (group_id, user_id) VALUES (?, ?)",
 		groupID, userID,
 	)
@@ -191,7 +241,7 @@ func (db *appdbimpl) AddUserToGroup(groupID, userID, adderID string) error {
 	}
 
 	// Add to conversation_participants
-	_, err = db.db.Exec(
+	_, err = db.db.ExecContext(ctx,
 		"INSERT OR IGNORE INTO conversation_participants (conversation_id, user_id) VALUES (?, ?)",
 		convID, userID,
 	)
@@ -200,19 +250,19 @@ func (db *appdbimpl) AddUserToGroup(groupID, userID, adderID string) error {
 }
 
 // RemoveUserFromGroup removes a user from a group (for leaving)
-func (db *appdbimpl) RemoveUserFromGroup(groupID, userID string) error {
+func (db *appdbimpl) RemoveUserFromGroup(ctx context.Context, groupID ids.GroupID, userID ids.UserID) error {
 	// Check if user is a member
-	isMember, err := db.IsGroupMember(groupID, userID)
+	isMember, err := db.IsGroupMember(ctx, groupID, userID)
 	if err != nil {
 		return err
 	}
 	if !isMember {
-		return ErrNotGroupMember
+		return withID(ErrNotGroupMember, groupID)
 	}
 
 	// Get the conversation ID for this group
-	var convID string
-	err = db.db.QueryRow(
+	var convID ids.ConversationID
+	err = db.db.QueryRowContext(ctx,
 		"SELECT id FROM conversations WHERE group_id = ?",
 		groupID,
 	).Scan(&convID)
@@ -221,7 +271,7 @@ func (db *appdbimpl) RemoveUserFromGroup(groupID, userID string) error {
 	}
 
 	// Remove from group_members
-	_, err = db.db.Exec(
+	_, err = db.db.ExecContext(ctx,
 		"DELETE FROM group_members WHERE group_id = ? AND user_id = ?",
 		groupID, userID,
 	)
@@ -230,17 +280,21 @@ func (db *appdbimpl) RemoveUserFromGroup(groupID, userID string) error {
 	}
 
 	// Remove from conversation_participants
-	_, err = db.db.Exec(
+	_, err = db.db.ExecContext(ctx,
 		"DELETE FROM conversation_participants WHERE conversation_id = ? AND user_id = ?",
 		convID, userID,
 	)
+	if err != nil {
+		return err
+	}
 
-	return err
+	// Take back the user's votes on the open proposals (see governance.go)
+	return dropOpenVotes(ctx, db.db, groupID, userID)
 }
 
 // UpdateGroupName changes the group's name
-func (db *appdbimpl) UpdateGroupName(groupID, name string) error {
-	result, err := db.db.Exec(
+func (db *appdbimpl) UpdateGroupName(ctx context.Context, groupID ids.GroupID, name string) error {
+	result, err := db.db.ExecContext(ctx,
 		"UPDATE groups SET name = ? WHERE id = ?",
 		name, groupID,
 	)
@@ -253,17 +307,17 @@ func (db *appdbimpl) UpdateGroupName(groupID, name string) error {
 		return err
 	}
 	if rowsAffected == 0 {
-		return ErrGroupNotFound
+		return withID(ErrGroupNotFound, groupID)
 	}
 
 	return nil
 }
 
-// UpdateGroupPhoto sets or updates the group's photo
-func (db *appdbimpl) UpdateGroupPhoto(groupID string, photo []byte) error {
-	result, err := db.db.Exec(
-		"UPDATE groups SET photo = ? WHERE id = ?",
-		photo, groupID,
+// UpdateGroupDescription changes the group's description; "" removes it
+func (db *appdbimpl) UpdateGroupDescription(ctx context.Context, groupID ids.GroupID, description string) error {
+	result, err := db.db.ExecContext(ctx,
+		"UPDATE groups SET description = ? WHERE id = ? AND deleted_at IS NULL",
+		description, groupID,
 	)
 	if err != nil {
 		return err
@@ -274,16 +328,29 @@ func (db *appdbimpl) UpdateGroupPhoto(groupID string, photo []byte) error {
 		return err
 	}
 	if rowsAffected == 0 {
-		return ErrGroupNotFound
+		return withID(ErrGroupNotFound, groupID)
+	}
+
+	return nil
+}
+
+// UpdateGroupPhoto sets or updates the group's photo
+func (db *appdbimpl) UpdateGroupPhoto(ctx context.Context, groupID ids.GroupID, photo []byte) error {
+	found, err := db.replacePhoto(ctx, "groups", "id = ?", groupID, photo)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return withID(ErrGroupNotFound, groupID)
 	}
 
 	return nil
 }
 
 // IsGroupMember checks if a user is a member of a group
-func (db *appdbimpl) IsGroupMember(groupID, userID string) (bool, error) {
+func (db *appdbimpl) IsGroupMember(ctx context.Context, groupID ids.GroupID, userID ids.UserID) (bool, error) {
 	var count int
-	err := db.db.QueryRow(
+	err := db.db.QueryRowContext(ctx,
 		"SELECT COUNT(*) FROM group_members WHERE group_id = ? AND user_id = ?",
 		groupID, userID,
 	).Scan(&count)
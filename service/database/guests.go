@@ -0,0 +1,181 @@
+/*
+Database operations for guest access.
+
+A group admin (the user who created the group) can mint guest tokens.
+A guest token is not a user: it has no profile and cannot send anything,
+it only allows reading the conversation of the group it was minted for.
+Tokens may expire and can be revoked at any time.
+*/
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"wasatext/service/ids"
+)
+
+// guestTokenPrefix marks guest tokens so they never look like a user ID
+const guestTokenPrefix = "guest-"
+
+// GuestToken grants read-only access to one group conversation
+type GuestToken struct {
+	Token          string
+	GroupID        ids.GroupID
+	ConversationID ids.ConversationID
+	CreatedBy      ids.UserID
+	CreatedAt      time.Time
+	ExpiresAt      *time.Time // nil means the token never expires
+}
+
+/*
+GetGroupAdmin returns the ID of the group admin, i.e. its creator.
+Groups created before the creator was recorded have no admin and
+an empty ID is returned.
+*/
+func (db *appdbimpl) GetGroupAdmin(ctx context.Context, groupID ids.GroupID) (ids.UserID, error) {
+	var createdBy sql.NullString
+	err := db.db.QueryRowContext(ctx,
+		"SELECT created_by FROM conversations WHERE group_id = ? AND is_group = 1",
+		groupID,
+	).Scan(&createdBy)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", withID(ErrGroupNotFound, groupID)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return ids.UserID(createdBy.String), nil
+}
+
+// IsGuestToken reports whether a bearer token is a guest token
+func IsGuestToken(token string) bool {
+	return len(token) > len(guestTokenPrefix) && token[:len(guestTokenPrefix)] == guestTokenPrefix
+}
+
+// CreateGuestToken mints a new guest token for a group
+func (db *appdbimpl) CreateGuestToken(ctx context.Context, groupID ids.GroupID, createdBy ids.UserID, expiresAt *time.Time) (*GuestToken, error) {
+	// Guest tokens are bearer credentials, so they must not be guessable
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+
+	gt := GuestToken{
+		Token:     guestTokenPrefix + hex.EncodeToString(buf),
+		GroupID:   groupID,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	}
+
+	_, err := db.db.ExecContext(ctx,
+		"INSERT INTO guest_tokens (token, group_id, created_by, created_at, expires_at) VALUES (?, ?, ?, ?, ?)",
+		gt.Token, gt.GroupID, gt.CreatedBy, gt.CreatedAt, gt.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.db.QueryRowContext(ctx,
+		"SELECT id FROM conversations WHERE group_id = ? AND is_group = 1",
+		groupID,
+	).Scan(&gt.ConversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &gt, nil
+}
+
+// GetGuestToken finds a usable guest token.
+// Revoked and expired tokens are reported as not found.
+func (db *appdbimpl) GetGuestToken(ctx context.Context, token string) (*GuestToken, error) {
+	var gt GuestToken
+	var expiresAt sql.NullTime
+
+	err := db.db.QueryRowContext(ctx, `
+		SELECT gt.token, gt.group_id, c.id, gt.created_by, gt.created_at, gt.expires_at
+		FROM guest_tokens gt
+		JOIN conversations c ON c.group_id = gt.group_id AND c.is_group = 1
+		WHERE gt.token = ? AND gt.revoked_at IS NULL AND c.deleted_at IS NULL
+	`, token).Scan(&gt.Token, &gt.GroupID, &gt.ConversationID, &gt.CreatedBy, &gt.CreatedAt, &expiresAt)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrGuestTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if expiresAt.Valid {
+		if time.Now().After(expiresAt.Time) {
+			return nil, ErrGuestTokenNotFound
+		}
+		gt.ExpiresAt = &expiresAt.Time
+	}
+
+	return &gt, nil
+}
+
+// RevokeGuestToken revokes a guest token of a group
+func (db *appdbimpl) RevokeGuestToken(ctx context.Context, groupID ids.GroupID, token string) error {
+	result, err := db.db.ExecContext(ctx,
+		"UPDATE guest_tokens SET revoked_at = ? WHERE token = ? AND group_id = ? AND revoked_at IS NULL",
+		time.Now(), token, groupID,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrGuestTokenNotFound
+	}
+
+	return nil
+}
+
+/*
+GetGuestConversation returns a group conversation as a guest sees it:
+the group name and the messages, without the member list. A guest is
+not a participant, so nothing is ever marked as read for them.
+*/
+func (db *appdbimpl) GetGuestConversation(ctx context.Context, groupID ids.GroupID) (*Conversation, error) {
+	group, err := db.GetGroup(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	conv := Conversation{
+		IsGroup: true,
+		Name:    group.Name,
+	}
+	err = db.db.QueryRowContext(ctx,
+		"SELECT id FROM conversations WHERE group_id = ? AND is_group = 1",
+		groupID,
+	).Scan(&conv.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrConversationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := db.getConversationMessages(ctx, conv.ID)
+	if err != nil {
+		return nil, err
+	}
+	conv.Messages = messages
+
+	return &conv, nil
+}
@@ -0,0 +1,183 @@
+/*
+Database operations for inbound webhooks.
+
+A participant can create a webhook for a conversation. External systems
+(CI, monitoring, ...) then post into the conversation with the hook
+token alone, without an account of their own: the messages are sent on
+behalf of the user who created the hook and are shown under the name of
+the hook. A hook stops working when its creator leaves the conversation
+and can be deleted by its creator at any time.
+*/
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"wasatext/service/ids"
+)
+
+// hookTokenPrefix marks webhook tokens so they never look like a user ID
+const hookTokenPrefix = "hook-"
+
+// Hook lets an external system post into one conversation
+type Hook struct {
+	Token          string
+	ConversationID ids.ConversationID
+	Name           string // shown as the sender of its messages
+	RatePerMinute  int
+	CreatedBy      ids.UserID
+	CreatedAt      time.Time
+}
+
+// CreateHook creates a webhook for a conversation the user takes part in
+func (db *appdbimpl) CreateHook(ctx context.Context, conversationID ids.ConversationID, createdBy ids.UserID, name string, ratePerMinute int) (*Hook, error) {
+	if err := db.checkParticipant(ctx, createdBy, conversationID); err != nil {
+		return nil, err
+	}
+
+	// Hook tokens are bearer credentials, so they must not be guessable
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+
+	hook := Hook{
+		Token:          hookTokenPrefix + hex.EncodeToString(buf),
+		ConversationID: conversationID,
+		Name:           name,
+		RatePerMinute:  ratePerMinute,
+		CreatedBy:      createdBy,
+		CreatedAt:      time.Now(),
+	}
+	_, err := db.db.ExecContext(ctx,
+		"INSERT INTO hooks (token, conversation_id, name, rate_per_minute, created_by, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		hook.Token, hook.ConversationID, hook.Name, hook.RatePerMinute, hook.CreatedBy, hook.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &hook, nil
+}
+
+// ListHooks returns the webhooks a user created in a conversation, oldest first
+func (db *appdbimpl) ListHooks(ctx context.Context, conversationID ids.ConversationID, createdBy ids.UserID) ([]Hook, error) {
+	if err := db.checkParticipant(ctx, createdBy, conversationID); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.db.QueryContext(ctx, `
+		SELECT token, conversation_id, name, rate_per_minute, created_by, created_at
+		FROM hooks
+		WHERE conversation_id = ? AND created_by = ?
+		ORDER BY created_at
+	`, conversationID, createdBy)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hooks []Hook
+	for rows.Next() {
+		var hook Hook
+		if err := rows.Scan(&hook.Token, &hook.ConversationID, &hook.Name, &hook.RatePerMinute, &hook.CreatedBy, &hook.CreatedAt); err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+
+	return hooks, rows.Err()
+}
+
+// GetHook finds a webhook by token. Hooks whose creator left the
+// conversation are reported as not found.
+func (db *appdbimpl) GetHook(ctx context.Context, token string) (*Hook, error) {
+	var hook Hook
+	err := db.db.QueryRowContext(ctx, `
+		SELECT h.token, h.conversation_id, h.name, h.rate_per_minute, h.created_by, h.created_at
+		FROM hooks h
+		JOIN conversation_participants cp ON cp.conversation_id = h.conversation_id AND cp.user_id = h.created_by
+		WHERE h.token = ?
+	`, token).Scan(&hook.Token, &hook.ConversationID, &hook.Name, &hook.RatePerMinute, &hook.CreatedBy, &hook.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrHookNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &hook, nil
+}
+
+// DeleteHook deletes a webhook of a conversation (only its creator can)
+func (db *appdbimpl) DeleteHook(ctx context.Context, conversationID ids.ConversationID, createdBy ids.UserID, token string) error {
+	result, err := db.db.ExecContext(ctx,
+		"DELETE FROM hooks WHERE token = ? AND conversation_id = ? AND created_by = ?",
+		token, conversationID, createdBy,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrHookNotFound
+	}
+
+	return nil
+}
+
+// PostHookMessage sends a text message through a webhook, on behalf of
+// its creator and under the name of the hook
+func (db *appdbimpl) PostHookMessage(ctx context.Context, hook *Hook, content string) (*Message, error) {
+	id, err := newMessageID(ctx, db.db)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+	}()
+
+	// A hook posts in a channel only when the channel admin created it
+	if err := checkCanPost(ctx, tx, hook.ConversationID, hook.CreatedBy); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO messages (id, conversation_id, sender_id, content, timestamp, hook_name, language)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, id, hook.ConversationID, hook.CreatedBy, content, time.Now(), hook.Name, messageLanguage(content))
+	if err != nil {
+		return nil, err
+	}
+	if err := chainMessage(ctx, tx, id); err != nil {
+		return nil, err
+	}
+	if err := insertMentions(ctx, tx, id, hook.ConversationID, hook.CreatedBy, content); err != nil {
+		return nil, err
+	}
+	if _, err := insertReceipts(ctx, tx, id, hook.ConversationID, hook.CreatedBy); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return db.GetMessage(ctx, id)
+}
@@ -0,0 +1,69 @@
+/*
+Generation of new identifiers.
+
+UUIDs never collide in practice, but short IDs (see ids.SetFormat) have
+about 65 random bits: the new* functions below check that a short ID is
+not taken yet, and draw another one when it is. The primary keys would
+refuse a duplicate anyway; the check turns that failure into a retry.
+*/
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"wasatext/service/ids"
+)
+
+// maxIDAttempts is how many short IDs are drawn before giving up
+const maxIDAttempts = 5
+
+// errIDsExhausted is returned when every short ID drawn was taken
+var errIDsExhausted = errors.New("could not generate an unused identifier")
+
+// rowQuerier is a *sql.DB or a *sql.Tx
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// newID generates an ID with generate until the query (with the ID as
+// its only parameter) reports it unused
+func newID[T ~string](ctx context.Context, q rowQuerier, takenSQL string, generate func() (T, error)) (T, error) {
+	for attempt := 0; attempt < maxIDAttempts; attempt++ {
+		id, err := generate()
+		if err != nil || ids.CurrentFormat() == ids.FormatUUID {
+			return id, err
+		}
+		var taken bool
+		if err := q.QueryRowContext(ctx, takenSQL, id).Scan(&taken); err != nil {
+			return "", err
+		}
+		if !taken {
+			return id, nil
+		}
+	}
+	return "", errIDsExhausted
+}
+
+// newUserID generates an unused user ID
+func newUserID(ctx context.Context, q rowQuerier) (ids.UserID, error) {
+	return newID(ctx, q, "SELECT EXISTS (SELECT 1 FROM users WHERE id = ?)", ids.NewUserID)
+}
+
+// newGroupID generates an unused group ID
+func newGroupID(ctx context.Context, q rowQuerier) (ids.GroupID, error) {
+	return newID(ctx, q, "SELECT EXISTS (SELECT 1 FROM groups WHERE id = ?)", ids.NewGroupID)
+}
+
+// newConversationID generates an unused conversation ID
+func newConversationID(ctx context.Context, q rowQuerier) (ids.ConversationID, error) {
+	return newID(ctx, q, "SELECT EXISTS (SELECT 1 FROM conversations WHERE id = ?)", ids.NewConversationID)
+}
+
+// newMessageID generates a message ID unused by the messages and by the
+// hash chains, which remember deleted messages
+func newMessageID(ctx context.Context, q rowQuerier) (ids.MessageID, error) {
+	return newID(ctx, q, `SELECT EXISTS (SELECT 1 FROM messages WHERE id = ?1)
+		OR EXISTS (SELECT 1 FROM message_chain WHERE message_id = ?1)`, ids.NewMessageID)
+}
@@ -0,0 +1,193 @@
+/*
+Database operations for chat histories imported from other messengers.
+
+An import creates a group holding the history of a chat, its messages
+with their original times (see service/chatimport). The senders become
+its members: a sender whose name is taken in the workspace is that
+user, any other gets a placeholder account, deactivated until someone
+logs in with its name. Imported messages have no receipts, they were
+delivered elsewhere: they are not unread for anyone.
+*/
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"wasatext/service/ids"
+)
+
+// ImportedChat is a chat history to import
+type ImportedChat struct {
+	WorkspaceID string
+	Name        string   // the name of the group
+	Members     []string // user names; the first one is the admin of the group
+	Messages    []ImportedMessage
+}
+
+// ImportedMessage is a message of an imported chat
+type ImportedMessage struct {
+	Sender    string // one of the members
+	Content   string
+	Photo     []byte // nil if none
+	Timestamp time.Time
+}
+
+// ImportResult is what an import created
+type ImportResult struct {
+	Group    *Group
+	Members  map[string]ids.UserID // by name
+	Created  []string              // the names of the placeholder accounts created
+	Messages int
+}
+
+/*
+ImportChat creates the group of an imported chat, with its members and
+its messages, in one transaction: nothing is left of a failed import. A
+member whose account is banned or deleted fails it.
+*/
+func (db *appdbimpl) ImportChat(ctx context.Context, chat ImportedChat) (*ImportResult, error) {
+	if _, err := db.GetWorkspace(ctx, chat.WorkspaceID); err != nil {
+		return nil, err
+	}
+	if len(chat.Members) == 0 {
+		return nil, ErrEmptyImport
+	}
+
+	// The photos go to the blob store first, and are dropped again if
+	// the import fails
+	var stored []*storedPhoto
+	committed := false
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+		if !committed {
+			for _, p := range stored {
+				db.dropPhoto(p)
+			}
+		}
+	}()
+
+	// Step 1: Find or create the members
+	now := time.Now()
+	result := ImportResult{Members: map[string]ids.UserID{}}
+	var members []ids.UserID
+	for _, name := range chat.Members {
+		if _, ok := result.Members[name]; ok {
+			continue
+		}
+		id, err := existingUserID(ctx, tx, chat.WorkspaceID, name)
+		if err != nil {
+			return nil, err
+		}
+		if id == "" {
+			if id, err = newUserID(ctx, tx); err != nil {
+				return nil, err
+			}
+			_, err = tx.ExecContext(ctx,
+				"INSERT INTO users (id, workspace_id, name, created_at, deactivated_at) VALUES (?, ?, ?, ?, ?)",
+				id, chat.WorkspaceID, name, now, now,
+			)
+			if err != nil {
+				return nil, err
+			}
+			result.Created = append(result.Created, name)
+		}
+		result.Members[name] = id
+		members = append(members, id)
+	}
+
+	// Step 2: Create the group, its conversation dated from the first
+	// message
+	groupID, err := newGroupID(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO groups (id, workspace_id, name) VALUES (?, ?, ?)",
+		groupID, chat.WorkspaceID, chat.Name,
+	)
+	if err != nil {
+		return nil, err
+	}
+	convID, err := newConversationID(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	createdAt := now
+	if len(chat.Messages) > 0 {
+		createdAt = chat.Messages[0].Timestamp.Local()
+	}
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO conversations (id, workspace_id, is_group, group_id, created_by, created_at) VALUES (?, ?, 1, ?, ?, ?)",
+		convID, chat.WorkspaceID, groupID, members[0], createdAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	for _, userID := range members {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO group_members (group_id, user_id) VALUES (?, ?)", groupID, userID); err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO conversation_participants (conversation_id, user_id) VALUES (?, ?)", convID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Step 3: Insert the messages with their times, stored in the local
+	// time zone like time.Now() so that they compare as text
+	for _, m := range chat.Messages {
+		senderID, ok := result.Members[m.Sender]
+		if !ok {
+			return nil, withID(ErrUserNotFound, m.Sender)
+		}
+		id, err := newMessageID(ctx, tx)
+		if err != nil {
+			return nil, err
+		}
+
+		content := sql.NullString{String: m.Content, Valid: m.Content != ""}
+		var photoID sql.NullString
+		if m.Photo != nil {
+			p, err := db.putPhoto(m.Photo)
+			if err != nil {
+				return nil, err
+			}
+			stored = append(stored, p)
+			if err := insertMedia(ctx, tx, p); err != nil {
+				return nil, err
+			}
+			photoID = sql.NullString{String: p.id, Valid: true}
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO messages (id, conversation_id, sender_id, content, photo_id, timestamp, language)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, id, convID, senderID, content, photoID, m.Timestamp.Local(), messageLanguage(m.Content))
+		if err != nil {
+			return nil, err
+		}
+		if err := chainMessage(ctx, tx, id); err != nil {
+			return nil, err
+		}
+		result.Messages++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	committed = true
+
+	if result.Group, err = db.GetGroup(ctx, groupID); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
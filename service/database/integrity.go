@@ -0,0 +1,326 @@
+/*
+Database operations for the hash chain of the messages.
+
+Every change to a message (sent, edited, deleted) is appended to
+message_chain, the chain of its conversation: the entry holds a digest
+of the message as it is now, and its hash covers the hash of the entry
+before it. Changing or removing any entry changes every hash after it,
+and changing a message without appending an entry no longer matches its
+digest, so VerifyConversation can tell when the messages of a
+conversation were tampered with outside the application. The head (the
+hash of the last entry) printed in an export proves the transcript was
+not altered since.
+
+The entries are appended in the transaction that changes the message
+(chainMessage), so the chain and the messages cannot disagree. They go
+away only with their conversation, when it is purged.
+*/
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"wasatext/service/ids"
+)
+
+// Chain entry actions
+const (
+	ChainActionCreate = "create"
+	ChainActionEdit   = "edit"
+	ChainActionDelete = "delete" // deleted for everyone, or purged with its sender
+)
+
+// Integrity problems
+const (
+	IntegrityHashMismatch = "hash_mismatch" // the entry does not hash to its stored hash
+	IntegrityAltered      = "altered"       // the message does not match its last entry
+	IntegrityMissing      = "missing"       // the message is gone, but its last entry is not a deletion
+	IntegrityUnrecorded   = "unrecorded"    // the message has no entry at all
+)
+
+// IntegrityReport is the result of checking the hash chain of a conversation
+type IntegrityReport struct {
+	ConversationID ids.ConversationID
+	Valid          bool   // no problem was found
+	Entries        int    // in the chain
+	Messages       int    // in the conversation, tombstones included
+	Head           string // the hash of the last entry, "" for an empty chain
+	Problems       []IntegrityProblem
+}
+
+// IntegrityProblem is something VerifyConversation found wrong
+type IntegrityProblem struct {
+	Seq       int64 // the entry involved, 0 for a message without one
+	MessageID ids.MessageID
+	Problem   string // one of the Integrity constants
+}
+
+// chainTx is what chainMessage needs of a transaction
+type chainTx interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// messageStateSQL selects what the digest of a message covers. It
+// expects the messages table to be aliased as "m".
+const messageStateSQL = `m.conversation_id, m.sender_id, m.timestamp, COALESCE(m.content, ''), COALESCE(m.photo_id, ''),
+	COALESCE(m.reply_to, ''), COALESCE(m.hook_name, ''), m.deleted_at IS NOT NULL`
+
+// messageState is a row of messageStateSQL
+type messageState struct {
+	conversationID ids.ConversationID
+	senderID       ids.UserID
+	timestamp      time.Time
+	content        string
+	photoID        string
+	replyTo        string
+	hookName       string
+	deleted        bool
+}
+
+func (s *messageState) scan(row rowScanner, extra ...interface{}) error {
+	return row.Scan(append([]interface{}{&s.conversationID, &s.senderID, &s.timestamp, &s.content,
+		&s.photoID, &s.replyTo, &s.hookName, &s.deleted}, extra...)...)
+}
+
+// digest hashes the message as it is; a tombstone has the empty digest
+func (s *messageState) digest() string {
+	if s.deleted {
+		return ""
+	}
+	return hashHex(string(s.conversationID), string(s.senderID), s.timestamp.UTC().Format(time.RFC3339Nano),
+		s.content, s.photoID, s.replyTo, s.hookName)
+}
+
+// chainHash is the hash of an entry, which covers the hash before it
+// ("" for the first entry of a conversation)
+func chainHash(prev string, seq int64, messageID ids.MessageID, action, digest string) string {
+	return hashHex(prev, strconv.FormatInt(seq, 10), string(messageID), action, digest)
+}
+
+// hashHex returns the hex SHA-256 of the fields, separated by NUL bytes
+func hashHex(fields ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+/*
+chainMessage appends the current state of a message to the chain of its
+conversation, inside the transaction that changed it: a create entry for
+a message the chain does not know yet, a delete entry for a tombstone or
+a message no longer there, an edit entry otherwise. Nothing is appended
+for a message neither in the table nor in the chain.
+*/
+func chainMessage(ctx context.Context, tx chainTx, messageID ids.MessageID) error {
+	var state messageState
+	err := state.scan(tx.QueryRowContext(ctx, "SELECT "+messageStateSQL+" FROM messages m WHERE m.id = ?", messageID))
+	gone := errors.Is(err, sql.ErrNoRows)
+	if err != nil && !gone {
+		return err
+	}
+
+	// The last entry of the message, if it has one
+	var lastAction string
+	lastErr := tx.QueryRowContext(ctx,
+		"SELECT conversation_id, action FROM message_chain WHERE message_id = ? ORDER BY seq DESC LIMIT 1",
+		messageID,
+	).Scan(&state.conversationID, &lastAction)
+	recorded := !errors.Is(lastErr, sql.ErrNoRows)
+	if lastErr != nil && recorded {
+		return lastErr
+	}
+
+	action := ChainActionEdit
+	switch {
+	case gone && !recorded:
+		return nil
+	case gone || state.deleted:
+		if lastAction == ChainActionDelete {
+			return nil
+		}
+		action, state.deleted = ChainActionDelete, true
+	case !recorded:
+		action = ChainActionCreate
+	}
+	return appendChain(ctx, tx, state.conversationID, messageID, action, state.digest(), time.Now())
+}
+
+// appendChain appends an entry to the chain of a conversation
+func appendChain(ctx context.Context, tx chainTx, conversationID ids.ConversationID, messageID ids.MessageID, action, digest string, now time.Time) error {
+	var seq int64
+	var prev string
+	err := tx.QueryRowContext(ctx,
+		"SELECT seq, hash FROM message_chain WHERE conversation_id = ? ORDER BY seq DESC LIMIT 1",
+		conversationID,
+	).Scan(&seq, &prev)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	seq++
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO message_chain (conversation_id, seq, message_id, action, digest, hash, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, conversationID, seq, messageID, action, digest, chainHash(prev, seq, messageID, action, digest), now)
+	return err
+}
+
+/*
+VerifyConversation walks the hash chain of a conversation, checking that
+every entry hashes to its stored hash, then that every message matches
+its last entry. It reports what it found rather than stopping at the
+first problem; soft-deleted conversations are checked too.
+*/
+func (db *appdbimpl) VerifyConversation(ctx context.Context, conversationID ids.ConversationID) (*IntegrityReport, error) {
+	var exists bool
+	err := db.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM conversations WHERE id = ?)", conversationID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, withID(ErrConversationNotFound, conversationID)
+	}
+
+	report := IntegrityReport{ConversationID: conversationID, Problems: []IntegrityProblem{}}
+
+	// Step 1: Walk the chain, keeping the last entry of every message
+	type lastEntry struct {
+		seq            int64
+		action, digest string
+	}
+	last := make(map[ids.MessageID]lastEntry)
+	rows, err := db.db.QueryContext(ctx,
+		"SELECT seq, message_id, action, digest, hash FROM message_chain WHERE conversation_id = ? ORDER BY seq",
+		conversationID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var seq int64
+		var messageID ids.MessageID
+		var action, digest, hash string
+		if err := rows.Scan(&seq, &messageID, &action, &digest, &hash); err != nil {
+			return nil, err
+		}
+		if chainHash(report.Head, seq, messageID, action, digest) != hash {
+			report.Problems = append(report.Problems, IntegrityProblem{Seq: seq, MessageID: messageID, Problem: IntegrityHashMismatch})
+		}
+		report.Head = hash
+		report.Entries++
+		last[messageID] = lastEntry{seq, action, digest}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Step 2: Compare every message with its last entry
+	rows, err = db.db.QueryContext(ctx,
+		"SELECT "+messageStateSQL+", m.id FROM messages m WHERE m.conversation_id = ? ORDER BY m.timestamp, m.id",
+		conversationID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var state messageState
+		var messageID ids.MessageID
+		if err := state.scan(rows, &messageID); err != nil {
+			return nil, err
+		}
+		report.Messages++
+		entry, ok := last[messageID]
+		delete(last, messageID)
+		switch {
+		case !ok:
+			report.Problems = append(report.Problems, IntegrityProblem{MessageID: messageID, Problem: IntegrityUnrecorded})
+		case state.deleted != (entry.action == ChainActionDelete) || state.digest() != entry.digest:
+			report.Problems = append(report.Problems, IntegrityProblem{Seq: entry.seq, MessageID: messageID, Problem: IntegrityAltered})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Step 3: What is left in the chain is no longer in the table
+	var missing []IntegrityProblem
+	for messageID, entry := range last {
+		if entry.action != ChainActionDelete {
+			missing = append(missing, IntegrityProblem{Seq: entry.seq, MessageID: messageID, Problem: IntegrityMissing})
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool { return missing[i].Seq < missing[j].Seq })
+	report.Problems = append(report.Problems, missing...)
+
+	report.Valid = len(report.Problems) == 0
+	return &report, nil
+}
+
+// attachHashes sets the Hash of the messages of an archived conversation
+// and its ChainHead
+func (db *appdbimpl) attachHashes(ctx context.Context, conv *Conversation) error {
+	rows, err := db.db.QueryContext(ctx, `
+		SELECT c.message_id, c.hash
+		FROM message_chain c
+		WHERE c.conversation_id = ?
+		AND c.seq = (SELECT MAX(l.seq) FROM message_chain l WHERE l.conversation_id = c.conversation_id AND l.message_id = c.message_id)
+	`, conv.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	hashes := make(map[ids.MessageID]string)
+	for rows.Next() {
+		var messageID ids.MessageID
+		var hash string
+		if err := rows.Scan(&messageID, &hash); err != nil {
+			return err
+		}
+		hashes[messageID] = hash
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for i := range conv.Messages {
+		conv.Messages[i].Hash = hashes[conv.Messages[i].ID]
+	}
+
+	err = db.db.QueryRowContext(ctx,
+		"SELECT hash FROM message_chain WHERE conversation_id = ? ORDER BY seq DESC LIMIT 1",
+		conv.ID,
+	).Scan(&conv.ChainHead)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	return err
+}
+
+// collectMessageIDs returns the message IDs a query selects
+func collectMessageIDs(ctx context.Context, q querier, query string, args ...interface{}) ([]ids.MessageID, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messageIDs []ids.MessageID
+	for rows.Next() {
+		var id ids.MessageID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		messageIDs = append(messageIDs, id)
+	}
+	return messageIDs, rows.Err()
+}
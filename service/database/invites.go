@@ -0,0 +1,189 @@
+/*
+Database operations for invites.
+
+When the server runs in invite-only mode, a new account can only be
+created with an invite code. Codes are minted by the admins or by
+existing users (within a quota), belong to one workspace and can be
+used once. Existing users keep logging in without a code.
+*/
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"log"
+	"time"
+
+	"wasatext/service/ids"
+)
+
+// Invite allows creating one account in a workspace
+type Invite struct {
+	Code        string
+	WorkspaceID string
+	CreatedBy   ids.UserID // "" for invites minted by an admin
+	CreatedAt   time.Time
+	ExpiresAt   *time.Time // nil means the invite never expires
+	UsedBy      ids.UserID // "" while the invite is unused
+	UsedAt      *time.Time
+}
+
+// CreateInvite mints an invite code for a workspace. createdBy is "" for an admin.
+func (db *appdbimpl) CreateInvite(ctx context.Context, workspaceID string, createdBy ids.UserID, expiresAt *time.Time) (*Invite, error) {
+	if _, err := db.GetWorkspace(ctx, workspaceID); err != nil {
+		return nil, err
+	}
+
+	// Codes are typed by people, so they are short but not guessable
+	buf := make([]byte, 10)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+
+	inv := Invite{
+		Code:        base32.StdEncoding.EncodeToString(buf),
+		WorkspaceID: workspaceID,
+		CreatedBy:   createdBy,
+		CreatedAt:   time.Now(),
+		ExpiresAt:   expiresAt,
+	}
+
+	var creator interface{}
+	if createdBy != "" {
+		creator = createdBy
+	}
+	_, err := db.db.ExecContext(ctx,
+		"INSERT INTO invites (code, workspace_id, created_by, created_at, expires_at) VALUES (?, ?, ?, ?, ?)",
+		inv.Code, inv.WorkspaceID, creator, inv.CreatedAt, inv.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &inv, nil
+}
+
+// ListInvites returns the invites minted by a user, newest first.
+// An empty createdBy returns every invite (for the admins).
+func (db *appdbimpl) ListInvites(ctx context.Context, createdBy ids.UserID) ([]Invite, error) {
+	rows, err := db.db.QueryContext(ctx, `
+		SELECT code, workspace_id, created_by, created_at, expires_at, used_by, used_at
+		FROM invites
+		WHERE ? = '' OR created_by = ?
+		ORDER BY created_at DESC
+	`, createdBy, createdBy)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invites []Invite
+	for rows.Next() {
+		var inv Invite
+		var creator, usedBy sql.NullString
+		var expiresAt, usedAt sql.NullTime
+		if err := rows.Scan(&inv.Code, &inv.WorkspaceID, &creator, &inv.CreatedAt, &expiresAt, &usedBy, &usedAt); err != nil {
+			return nil, err
+		}
+		inv.CreatedBy = ids.UserID(creator.String)
+		inv.UsedBy = ids.UserID(usedBy.String)
+		if expiresAt.Valid {
+			inv.ExpiresAt = &expiresAt.Time
+		}
+		if usedAt.Valid {
+			inv.UsedAt = &usedAt.Time
+		}
+		invites = append(invites, inv)
+	}
+
+	return invites, rows.Err()
+}
+
+// RevokeInvite deletes an unused invite. A user can only revoke their
+// own invites; an empty createdBy (an admin) can revoke any.
+func (db *appdbimpl) RevokeInvite(ctx context.Context, code string, createdBy ids.UserID) error {
+	result, err := db.db.ExecContext(ctx,
+		"DELETE FROM invites WHERE code = ? AND used_at IS NULL AND (? = '' OR created_by = ?)",
+		code, createdBy, createdBy,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrInviteNotFound
+	}
+	return nil
+}
+
+/*
+RegisterWithInvite logs a user in like CreateUser, but a new account is
+only created with a valid invite code for the workspace, which is then
+used up. The check, the new account and the use of the code share one
+transaction, so a code cannot be used twice.
+*/
+func (db *appdbimpl) RegisterWithInvite(ctx context.Context, workspaceID, name, code string) (ids.UserID, error) {
+	// The workspace must exist
+	if _, err := db.GetWorkspace(ctx, workspaceID); err != nil {
+		return "", err
+	}
+
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+	}()
+
+	// Existing users log in without a code
+	existingID, err := existingUserID(ctx, tx, workspaceID, name)
+	if err != nil || existingID != "" {
+		return existingID, err
+	}
+	if code == "" {
+		return "", ErrInviteRequired
+	}
+
+	// Create the account and use the code
+	id, err := newUserID(ctx, tx)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE invites SET used_by = ?, used_at = ?
+		WHERE code = ? AND workspace_id = ? AND used_at IS NULL
+		AND (expires_at IS NULL OR expires_at > ?)
+	`, id, now, code, workspaceID, now)
+	if err != nil {
+		return "", err
+	}
+	used, err := result.RowsAffected()
+	if err != nil {
+		return "", err
+	}
+	if used == 0 {
+		return "", ErrInviteInvalid
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO users (id, workspace_id, name, created_at) VALUES (?, ?, ?, ?)",
+		id, workspaceID, name, now,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return id, tx.Commit()
+}
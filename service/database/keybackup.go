@@ -0,0 +1,107 @@
+/*
+Database operations for encrypted key backups.
+
+A client that encrypts messages end to end keeps its keys on the device.
+To restore its history on a new device, it can store its keys here,
+wrapped with a passphrase the server never sees: the backup is an
+opaque blob, stored and returned as it is. Each upload bumps its
+version, so that a device replacing an older backup than the one stored
+is told instead of overwriting it.
+*/
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"wasatext/service/ids"
+)
+
+// MaxKeyBackupSize is the largest key backup a user can store, in bytes
+const MaxKeyBackupSize = 64 << 10
+
+// KeyBackup is the encrypted key backup of a user
+type KeyBackup struct {
+	Data      []byte // opaque to the server
+	Version   int64  // 1 for the first upload, then one more for each
+	UpdatedAt time.Time
+}
+
+// GetKeyBackup returns the key backup of the user
+func (db *appdbimpl) GetKeyBackup(ctx context.Context, userID ids.UserID) (*KeyBackup, error) {
+	var b KeyBackup
+	err := db.db.QueryRowContext(ctx,
+		"SELECT data, version, updated_at FROM key_backups WHERE user_id = ?",
+		userID,
+	).Scan(&b.Data, &b.Version, &b.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, withID(ErrKeyBackupNotFound, userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+/*
+PutKeyBackup stores the key backup of the user in place of the previous
+one. If replaces is set, it is the version the client means to replace
+(0 when it expects no backup), and a different stored version is a
+conflict (409): another device uploaded in between.
+*/
+func (db *appdbimpl) PutKeyBackup(ctx context.Context, userID ids.UserID, data []byte, replaces *int64) (*KeyBackup, error) {
+	if len(data) == 0 || len(data) > MaxKeyBackupSize {
+		return nil, ErrInvalidKeyBackup
+	}
+
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+	}()
+
+	var current int64
+	err = tx.QueryRowContext(ctx, "SELECT version FROM key_backups WHERE user_id = ?", userID).Scan(&current)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if replaces != nil && *replaces != current {
+		return nil, withID(ErrKeyBackupConflict, userID)
+	}
+
+	b := KeyBackup{Data: data, Version: current + 1, UpdatedAt: time.Now()}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO key_backups (user_id, data, version, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET data = excluded.data, version = excluded.version, updated_at = excluded.updated_at
+	`, userID, b.Data, b.Version, b.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// DeleteKeyBackup deletes the key backup of the user
+func (db *appdbimpl) DeleteKeyBackup(ctx context.Context, userID ids.UserID) error {
+	result, err := db.db.ExecContext(ctx, "DELETE FROM key_backups WHERE user_id = ?", userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return withID(ErrKeyBackupNotFound, userID)
+	}
+	return nil
+}
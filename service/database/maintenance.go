@@ -0,0 +1,275 @@
+/*
+Database maintenance: integrity check, search index repair and vacuum,
+WAL checkpoints and size monitoring.
+
+Incremental vacuum only works once the file uses auto_vacuum=INCREMENTAL,
+and switching an existing file to it needs one full VACUUM. The first
+maintenance run on an older database therefore does a full VACUUM;
+every later run only releases the free pages.
+
+In WAL mode the writes go to the write-ahead log (the -wal file) first.
+SQLite copies them back into the database file by itself, but only
+truncates the log when asked: on a busy server that is never idle, the
+log keeps the size of its largest burst. Checkpoint copies everything
+back and truncates it; the server runs it periodically.
+*/
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SQLite auto_vacuum modes (PRAGMA auto_vacuum)
+const autoVacuumIncremental = 2
+
+// Vacuum modes reported by RunMaintenance
+const (
+	VacuumModeFull        = "full"
+	VacuumModeIncremental = "incremental"
+)
+
+// MaintenanceReport describes one maintenance run
+type MaintenanceReport struct {
+	StartedAt       time.Time
+	Duration        time.Duration
+	IntegrityErrors []string // empty when the integrity check passed
+	VacuumMode      string   // "full" or "incremental"
+	SizeBefore      int64    // bytes
+	SizeAfter       int64    // bytes
+	FreePagesBefore int64
+	FreePagesAfter  int64
+	// SearchIndexRepaired counts the search index entries dropped or added
+	// to match the messages; 0 when the index was in sync
+	SearchIndexRepaired int64
+}
+
+// RunMaintenance checks the integrity of the database file, repairs the
+// search index and vacuums the file. Integrity problems are reported, not
+// returned as an error.
+func (db *appdbimpl) RunMaintenance(ctx context.Context) (*MaintenanceReport, error) {
+	// The scheduled job and the admin endpoint may overlap
+	db.maintenance.Lock()
+	defer db.maintenance.Unlock()
+
+	report := MaintenanceReport{StartedAt: time.Now()}
+
+	var err error
+	if report.SizeBefore, report.FreePagesBefore, err = db.fileSize(ctx); err != nil {
+		return nil, err
+	}
+
+	// Step 1: Integrity check (returns a single "ok" row when all is well)
+	rows, err := db.db.QueryContext(ctx, "PRAGMA integrity_check")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return nil, err
+		}
+		if line != "ok" {
+			report.IntegrityErrors = append(report.IntegrityErrors, line)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Step 2: Repair the search index
+	if report.SearchIndexRepaired, err = db.repairSearchIndex(ctx); err != nil {
+		return nil, err
+	}
+
+	// Step 3: Vacuum - a full one the first time, to switch to incremental
+	var autoVacuum int
+	if err := db.db.QueryRowContext(ctx, "PRAGMA auto_vacuum").Scan(&autoVacuum); err != nil {
+		return nil, err
+	}
+	if autoVacuum == autoVacuumIncremental {
+		report.VacuumMode = VacuumModeIncremental
+		if _, err := db.db.ExecContext(ctx, "PRAGMA incremental_vacuum"); err != nil {
+			return nil, err
+		}
+	} else {
+		report.VacuumMode = VacuumModeFull
+		if _, err := db.db.ExecContext(ctx, "PRAGMA auto_vacuum = INCREMENTAL"); err != nil {
+			return nil, err
+		}
+		if _, err := db.db.ExecContext(ctx, "VACUUM"); err != nil {
+			return nil, err
+		}
+	}
+
+	if report.SizeAfter, report.FreePagesAfter, err = db.fileSize(ctx); err != nil {
+		return nil, err
+	}
+
+	report.Duration = time.Since(report.StartedAt)
+	return &report, nil
+}
+
+// fileSize returns the size of the database file in bytes and its number of free pages
+func (db *appdbimpl) fileSize(ctx context.Context) (int64, int64, error) {
+	var pageCount, pageSize, freePages int64
+	if err := db.db.QueryRowContext(ctx, "PRAGMA page_count").Scan(&pageCount); err != nil {
+		return 0, 0, err
+	}
+	if err := db.db.QueryRowContext(ctx, "PRAGMA page_size").Scan(&pageSize); err != nil {
+		return 0, 0, err
+	}
+	if err := db.db.QueryRowContext(ctx, "PRAGMA freelist_count").Scan(&freePages); err != nil {
+		return 0, 0, err
+	}
+	return pageCount * pageSize, freePages, nil
+}
+
+// repairSearchIndex runs repairSearchIndex in a transaction
+func (db *appdbimpl) repairSearchIndex(ctx context.Context) (int64, error) {
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+	}()
+
+	repaired, err := repairSearchIndex(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+	return repaired, tx.Commit()
+}
+
+// CheckpointReport describes one WAL checkpoint
+type CheckpointReport struct {
+	// Busy is set when readers or writers kept the checkpoint from
+	// copying the whole log; the log is then not truncated
+	Busy               bool
+	LogFrames          int64 // pages in the log
+	CheckpointedFrames int64 // of which copied into the database file
+	WALSizeBefore      int64 // bytes
+	WALSizeAfter       int64 // bytes
+}
+
+// Stats describes the database files, for monitoring
+type Stats struct {
+	FileSize  int64 // bytes of the database file
+	WALSize   int64 // bytes of the write-ahead log
+	PageSize  int64 // bytes
+	PageCount int64
+	FreePages int64
+
+	Checkpoints     int64     // Checkpoint runs since the start
+	BusyCheckpoints int64     // of which Busy
+	LastCheckpoint  time.Time // zero before the first
+}
+
+// checkpointCounters sum up the Checkpoint runs
+type checkpointCounters struct {
+	mu    sync.Mutex
+	count int64
+	busy  int64
+	last  time.Time
+}
+
+// Checkpoint copies the write-ahead log into the database file and
+// truncates it (PRAGMA wal_checkpoint(TRUNCATE))
+func (db *appdbimpl) Checkpoint(ctx context.Context) (*CheckpointReport, error) {
+	db.maintenance.Lock()
+	defer db.maintenance.Unlock()
+
+	var report CheckpointReport
+	var err error
+	if report.WALSizeBefore, err = db.walSize(); err != nil {
+		return nil, err
+	}
+	var busy int
+	err = db.db.QueryRowContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)").Scan(&busy, &report.LogFrames, &report.CheckpointedFrames)
+	if err != nil {
+		return nil, err
+	}
+	report.Busy = busy != 0
+	if report.WALSizeAfter, err = db.walSize(); err != nil {
+		return nil, err
+	}
+
+	db.checkpoints.mu.Lock()
+	defer db.checkpoints.mu.Unlock()
+	db.checkpoints.count++
+	if report.Busy {
+		db.checkpoints.busy++
+	}
+	db.checkpoints.last = time.Now()
+	return &report, nil
+}
+
+// Stats returns the sizes of the database files and the checkpoints run
+func (db *appdbimpl) Stats(ctx context.Context) (*Stats, error) {
+	var stats Stats
+	if err := db.db.QueryRowContext(ctx, "PRAGMA page_count").Scan(&stats.PageCount); err != nil {
+		return nil, err
+	}
+	if err := db.db.QueryRowContext(ctx, "PRAGMA page_size").Scan(&stats.PageSize); err != nil {
+		return nil, err
+	}
+	if err := db.db.QueryRowContext(ctx, "PRAGMA freelist_count").Scan(&stats.FreePages); err != nil {
+		return nil, err
+	}
+	var err error
+	if stats.FileSize, err = fileBytes(db.path); err != nil {
+		return nil, err
+	}
+	if stats.WALSize, err = db.walSize(); err != nil {
+		return nil, err
+	}
+
+	db.checkpoints.mu.Lock()
+	defer db.checkpoints.mu.Unlock()
+	stats.Checkpoints, stats.BusyCheckpoints, stats.LastCheckpoint = db.checkpoints.count, db.checkpoints.busy, db.checkpoints.last
+	return &stats, nil
+}
+
+// walSize returns the size of the write-ahead log in bytes
+func (db *appdbimpl) walSize() (int64, error) {
+	if db.path == "" {
+		return 0, nil
+	}
+	return fileBytes(db.path + "-wal")
+}
+
+// fileBytes returns the size of a file, 0 when there is none
+func fileBytes(path string) (int64, error) {
+	if path == "" {
+		return 0, nil
+	}
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// databaseFile returns the file of a database name given to New, without
+// its options, or "" for an in-memory database
+func databaseFile(name string) string {
+	name, options, _ := strings.Cut(name, "?")
+	name = strings.TrimPrefix(name, "file:")
+	if name == "" || name == ":memory:" || strings.Contains(options, "mode=memory") {
+		return ""
+	}
+	return name
+}
@@ -0,0 +1,427 @@
+/*
+Database operations for media.
+
+Photos (of users, groups and messages, and the logo) are kept in a storage.BlobStore;
+the photo_id column of their owner refers to a row of the media table,
+which holds the SHA-256 hash and the size of the bytes. Storing a photo
+writes the blob first and then, in the owner's transaction, the media
+row and the reference: a failed transaction deletes the blob again.
+Photos whose owner is deleted or gets a new photo are released: the
+media row and the blob go away once nothing refers to them.
+
+Photos are also served in smaller renditions (high, medium and thumb,
+see GetPhotoRendition). A rendition is made the first time it is asked
+for, kept in the blob store like the photo and recorded in the
+media_renditions table; it goes away with its photo.
+
+Databases from before the media store kept the bytes in the photo
+columns; moveBlobs moves them to the store when the database is opened.
+*/
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"wasatext/service/imaging"
+	"wasatext/service/storage"
+)
+
+// moveBlobsBatch is how many photos moveBlobs moves per transaction
+const moveBlobsBatch = 100
+
+// storedPhoto is a photo written to the blob store, not yet recorded
+type storedPhoto struct {
+	id   string
+	hash string
+	size int
+}
+
+// putPhoto writes the bytes of a photo to the blob store under a new ID.
+// The caller records it with insertMedia, or drops it on failure.
+func (db *appdbimpl) putPhoto(photo []byte) (*storedPhoto, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(photo)
+	p := storedPhoto{id: hex.EncodeToString(buf), hash: hex.EncodeToString(sum[:]), size: len(photo)}
+
+	if err := db.blobs.Put(p.id, photo); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// dropPhoto deletes the blob of a photo that could not be recorded
+func (db *appdbimpl) dropPhoto(p *storedPhoto) {
+	if p == nil {
+		return
+	}
+	if err := db.blobs.Delete(p.id); err != nil {
+		log.Printf("Error deleting unused blob %s: %v", p.id, err)
+	}
+}
+
+// insertMedia records a stored photo in the media table, waiting to be
+// processed (see ProcessMedia)
+func insertMedia(ctx context.Context, tx execer, p *storedPhoto) error {
+	_, err := tx.ExecContext(ctx,
+		"INSERT INTO media (id, sha256, size, created_at, processing_state) VALUES (?, ?, ?, ?, ?)",
+		p.id, p.hash, p.size, time.Now(), MediaPending,
+	)
+	return err
+}
+
+// GetPhoto returns the bytes of a photo, checked against its hash
+func (db *appdbimpl) GetPhoto(ctx context.Context, photoID string) ([]byte, error) {
+	var hash string
+	err := db.db.QueryRowContext(ctx, "SELECT sha256 FROM media WHERE id = ?", photoID).Scan(&hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, withID(ErrPhotoNotFound, photoID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return db.getBlob(photoID, hash)
+}
+
+// getBlob reads a blob of the store and checks it against its hash
+func (db *appdbimpl) getBlob(blobID, hash string) ([]byte, error) {
+	photo, err := db.blobs.Get(blobID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("the blob %s is missing", blobID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(photo)
+	if hex.EncodeToString(sum[:]) != hash {
+		return nil, fmt.Errorf("the blob %s does not match its hash", blobID)
+	}
+	return photo, nil
+}
+
+// Qualities a photo is served in
+const (
+	PhotoQualityOriginal = "original"
+	PhotoQualityHigh     = "high"
+	PhotoQualityMedium   = "medium"
+	PhotoQualityThumb    = "thumb"
+)
+
+// renditionSizes is the longest side of each rendition, in pixels
+var renditionSizes = map[string]int{
+	PhotoQualityHigh:   1600,
+	PhotoQualityMedium: 800,
+	PhotoQualityThumb:  320,
+}
+
+// ValidPhotoQuality reports whether a photo can be served in quality
+func ValidPhotoQuality(quality string) bool {
+	_, ok := renditionSizes[quality]
+	return ok || quality == PhotoQualityOriginal
+}
+
+/*
+GetPhotoRendition returns a photo in the given quality: the original,
+or a JPEG scaled down to the size of the rendition. Renditions are made
+on first request, unless the photo was processed before (see
+ProcessMedia), and kept; a photo already small enough is its own
+rendition, and one that cannot be decoded is served as it is.
+*/
+func (db *appdbimpl) GetPhotoRendition(ctx context.Context, photoID, quality string) ([]byte, error) {
+	if quality == PhotoQualityOriginal {
+		return db.GetPhoto(ctx, photoID)
+	}
+	if !ValidPhotoQuality(quality) {
+		return nil, withID(ErrInvalidPhotoQuality, quality)
+	}
+	photo, err := db.rendition(ctx, photoID, quality)
+	if errors.Is(err, errUndecodable) {
+		return db.GetPhoto(ctx, photoID)
+	}
+	return photo, err
+}
+
+// errUndecodable is returned by rendition for a photo that is not an image it can scale
+var errUndecodable = errors.New("the photo cannot be decoded")
+
+// rendition returns a rendition of a photo, making it if needed
+func (db *appdbimpl) rendition(ctx context.Context, photoID, quality string) ([]byte, error) {
+	size := renditionSizes[quality]
+
+	// Step 1: Serve the rendition made before
+	var blobID, hash string
+	err := db.db.QueryRowContext(ctx,
+		"SELECT blob_id, sha256 FROM media_renditions WHERE media_id = ? AND quality = ?",
+		photoID, quality,
+	).Scan(&blobID, &hash)
+	if err == nil {
+		return db.getBlob(blobID, hash)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	// Step 2: Make it from the original
+	photo, err := db.GetPhoto(ctx, photoID)
+	if err != nil {
+		return nil, err
+	}
+	fits, err := imaging.Fits(photo, size)
+	if err != nil {
+		return nil, errUndecodable
+	}
+	if fits {
+		sum := sha256.Sum256(photo)
+		_, err := db.insertRendition(ctx, photoID, quality, &storedPhoto{id: photoID, hash: hex.EncodeToString(sum[:]), size: len(photo)})
+		return photo, err
+	}
+	rendition, err := imaging.Fit(photo, size)
+	if err != nil {
+		return nil, errUndecodable
+	}
+	stored, err := db.putPhoto(rendition)
+	if err != nil {
+		return nil, err
+	}
+	inserted, err := db.insertRendition(ctx, photoID, quality, stored)
+	if !inserted {
+		db.dropPhoto(stored)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rendition, nil
+}
+
+// insertRendition records a rendition of a photo. It reports false when
+// another request recorded one first or the photo is gone meanwhile.
+func (db *appdbimpl) insertRendition(ctx context.Context, photoID, quality string, p *storedPhoto) (bool, error) {
+	result, err := db.db.ExecContext(ctx, `
+		INSERT OR IGNORE INTO media_renditions (media_id, quality, blob_id, sha256, size, created_at)
+		SELECT ?, ?, ?, ?, ?, ? WHERE EXISTS (SELECT 1 FROM media WHERE id = ?)
+	`, photoID, quality, p.id, p.hash, p.size, time.Now(), photoID)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+/*
+releasePhotos deletes the given photos, with their renditions and
+text, if nothing refers to them anymore. It is called after the owners
+were deleted or got a new photo; errors are only logged, at worst a
+blob is left behind.
+*/
+func (db *appdbimpl) releasePhotos(ctx context.Context, photoIDs ...string) {
+	for _, id := range photoIDs {
+		if id == "" {
+			continue
+		}
+		result, err := db.db.ExecContext(ctx, `
+			DELETE FROM media WHERE id = ?
+			AND NOT EXISTS (SELECT 1 FROM users WHERE photo_id = ?)
+			AND NOT EXISTS (SELECT 1 FROM groups WHERE photo_id = ?)
+			AND NOT EXISTS (SELECT 1 FROM messages WHERE photo_id = ?)
+			AND NOT EXISTS (SELECT 1 FROM settings WHERE key = '`+settingLogo+`' AND value = ?)
+		`, id, id, id, id, id)
+		if err != nil {
+			log.Printf("Error releasing photo %s: %v", id, err)
+			continue
+		}
+		if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+			continue
+		}
+		renditions, err := collectPhotoIDs(ctx, db.db, "SELECT blob_id FROM media_renditions WHERE media_id = ? AND blob_id != media_id", id)
+		if err != nil {
+			log.Printf("Error releasing the renditions of photo %s: %v", id, err)
+		}
+		if _, err := db.db.ExecContext(ctx, "DELETE FROM media_renditions WHERE media_id = ?", id); err != nil {
+			log.Printf("Error releasing the renditions of photo %s: %v", id, err)
+		}
+		if _, err := db.db.ExecContext(ctx, "DELETE FROM photo_text WHERE media_id = ?", id); err != nil {
+			log.Printf("Error releasing the text of photo %s: %v", id, err)
+		}
+		for _, blobID := range append(renditions, id) {
+			if err := db.blobs.Delete(blobID); err != nil {
+				log.Printf("Error deleting the blob %s of photo %s: %v", blobID, id, err)
+			}
+		}
+	}
+}
+
+/*
+replacePhoto stores photo as the photo of the row of table matching
+where (with the single argument ownerID), and releases the previous
+one. It reports false when there is no such row.
+*/
+func (db *appdbimpl) replacePhoto(ctx context.Context, table, where string, ownerID interface{}, photo []byte) (bool, error) {
+	p, err := db.putPhoto(photo)
+	if err != nil {
+		return false, err
+	}
+	committed := false
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		db.dropPhoto(p)
+		return false, err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+		if !committed {
+			db.dropPhoto(p)
+		}
+	}()
+
+	var previous sql.NullString
+	err = tx.QueryRowContext(ctx, "SELECT photo_id FROM "+table+" WHERE "+where, ownerID).Scan(&previous)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := insertMedia(ctx, tx, p); err != nil {
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE "+table+" SET photo_id = ? WHERE "+where, p.id, ownerID); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	committed = true
+	db.releasePhotos(ctx, previous.String)
+	return true, nil
+}
+
+// querier can list rows, on a connection or in a transaction
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// collectPhotoIDs returns the photo IDs a query selects, NULLs left out
+func collectPhotoIDs(ctx context.Context, q querier, query string, args ...interface{}) ([]string, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var photoIDs []string
+	for rows.Next() {
+		var id sql.NullString
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		if id.Valid {
+			photoIDs = append(photoIDs, id.String)
+		}
+	}
+	return photoIDs, rows.Err()
+}
+
+// moveBlobs moves the photos still kept in the photo columns to the
+// blob store, batch by batch
+func (db *appdbimpl) moveBlobs(ctx context.Context) error {
+	moved := 0
+	for _, table := range []string{"users", "groups", "messages"} {
+		for {
+			n, err := db.moveBlobBatch(ctx, table)
+			if err != nil {
+				return fmt.Errorf("moving the photos of %s: %w", table, err)
+			}
+			moved += n
+			if n < moveBlobsBatch {
+				break
+			}
+		}
+	}
+	if moved > 0 {
+		log.Printf("Moved %d photos from the database to the media store; database maintenance reclaims the space", moved)
+	}
+	return nil
+}
+
+// moveBlobBatch moves up to moveBlobsBatch photos of a table and returns how many
+func (db *appdbimpl) moveBlobBatch(ctx context.Context, table string) (int, error) {
+	rows, err := db.db.QueryContext(ctx, "SELECT id, photo FROM "+table+" WHERE photo IS NOT NULL LIMIT ?", moveBlobsBatch)
+	if err != nil {
+		return 0, err
+	}
+	type legacyPhoto struct {
+		ownerID string
+		photo   []byte
+	}
+	var batch []legacyPhoto
+	for rows.Next() {
+		var p legacyPhoto
+		if err := rows.Scan(&p.ownerID, &p.photo); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		batch = append(batch, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var stored []*storedPhoto
+	committed := false
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+		if !committed {
+			for _, p := range stored {
+				db.dropPhoto(p)
+			}
+		}
+	}()
+
+	for _, legacy := range batch {
+		// An empty BLOB never counted as a photo
+		if len(legacy.photo) == 0 {
+			if _, err := tx.ExecContext(ctx, "UPDATE "+table+" SET photo = NULL WHERE id = ?", legacy.ownerID); err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		p, err := db.putPhoto(legacy.photo)
+		if err != nil {
+			return 0, err
+		}
+		stored = append(stored, p)
+		if err := insertMedia(ctx, tx, p); err != nil {
+			return 0, err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE "+table+" SET photo_id = ?, photo = NULL WHERE id = ?", p.id, legacy.ownerID); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	committed = true
+	return len(batch), nil
+}
@@ -0,0 +1,178 @@
+/*
+Database operations for mentions.
+
+A message mentions a user with their name after an @ ("@alice"). The
+mentions are found when the message is sent or its text edited, and
+kept in mentions, so that users list the messages mentioning them
+(GetMentions) without searching every text. Only the participants of
+the conversation can be mentioned, and senders do not mention
+themselves: any other @name stays plain text.
+*/
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"wasatext/service/ids"
+)
+
+// Lengths of the names a mention can refer to, as for the usernames
+const (
+	minMentionName = 3
+	maxMentionName = 16
+)
+
+// mentionPattern finds the @name candidates of a text; mentionedNames
+// drops those inside a word or an address
+var mentionPattern = regexp.MustCompile(`@([A-Za-z0-9_-]+)`)
+
+// Mention is a user a message mentions
+type Mention struct {
+	UserID   ids.UserID
+	UserName string
+}
+
+// mentionedNames returns the distinct names mentioned in a text, in the
+// order they appear
+func mentionedNames(content string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatchIndex(content, -1) {
+		// "name@example.com" or "@@name" mention nobody
+		if start := match[0]; start > 0 && isNameByte(content[start-1]) {
+			continue
+		}
+		name := content[match[2]:match[3]]
+		if len(name) < minMentionName || len(name) > maxMentionName || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+// isNameByte reports whether b can come before an @ that is not a
+// mention: a character of a name or an address
+func isNameByte(b byte) bool {
+	return b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || strings.IndexByte("@._-", b) >= 0
+}
+
+// insertMentions records the participants a new text of a message
+// mentions, replacing what its previous text mentioned
+func insertMentions(ctx context.Context, tx *sql.Tx, messageID ids.MessageID, conversationID ids.ConversationID, senderID ids.UserID, content string) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM mentions WHERE message_id = ?", messageID); err != nil {
+		return err
+	}
+	names := mentionedNames(content)
+	if len(names) == 0 {
+		return nil
+	}
+
+	args := []interface{}{messageID, conversationID, senderID}
+	for _, name := range names {
+		args = append(args, name)
+	}
+	_, err := tx.ExecContext(ctx, `
+		INSERT OR IGNORE INTO mentions (message_id, user_id)
+		SELECT ?, u.id FROM users u
+		JOIN conversation_participants cp ON cp.user_id = u.id AND cp.conversation_id = ?
+		WHERE u.id != ? AND u.purged_at IS NULL AND u.name IN (?`+strings.Repeat(", ?", len(names)-1)+`)
+	`, args...)
+	return err
+}
+
+// getMessageMentions retrieves the users a message mentions, by name
+func (db *appdbimpl) getMessageMentions(ctx context.Context, messageID ids.MessageID) ([]Mention, error) {
+	rows, err := db.db.QueryContext(ctx, `
+		SELECT mn.user_id, u.name
+		FROM mentions mn
+		JOIN users u ON u.id = mn.user_id
+		WHERE mn.message_id = ?
+		ORDER BY u.name
+	`, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mentions := []Mention{}
+	for rows.Next() {
+		var mention Mention
+		if err := rows.Scan(&mention.UserID, &mention.UserName); err != nil {
+			return nil, err
+		}
+		mentions = append(mentions, mention)
+	}
+	return mentions, rows.Err()
+}
+
+/*
+GetMentions returns the messages mentioning the user, newest first,
+among those they can still read: not deleted, nor cleared or deleted
+for them. At most limit results are returned; beforeID continues after
+the last result of the previous page.
+*/
+func (db *appdbimpl) GetMentions(ctx context.Context, userID ids.UserID, beforeID ids.MessageID, limit int) ([]SearchResult, error) {
+	// The page starts after the given message
+	var before time.Time
+	if beforeID != "" {
+		err := db.db.QueryRowContext(ctx, "SELECT timestamp FROM messages WHERE id = ?", beforeID).Scan(&before)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, withID(ErrMessageNotFound, beforeID)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := db.db.QueryContext(ctx, `
+		SELECT m.id, m.conversation_id, m.sender_id, COALESCE(m.hook_name, u.name), m.content, COALESCE(m.language, ''), m.photo_id,
+			m.timestamp, m.system, m.edited_at, m.hook_name IS NOT NULL, c.is_group,
+			CASE
+				WHEN c.is_group = 1 THEN g.name
+				ELSE (SELECT ou.name FROM users ou
+					  JOIN conversation_participants cp2 ON ou.id = cp2.user_id
+					  WHERE cp2.conversation_id = c.id AND cp2.user_id != ?)
+			END
+		FROM mentions mn
+		JOIN messages m ON m.id = mn.message_id AND m.deleted_at IS NULL`+visibleMessages+`
+		JOIN users u ON u.id = m.sender_id
+		LEFT JOIN groups g ON g.id = c.group_id
+		WHERE mn.user_id = ?
+		AND (? = '' OR m.timestamp < ? OR (m.timestamp = ? AND m.id < ?))
+		ORDER BY m.timestamp DESC, m.id DESC
+		LIMIT ?
+	`, userID, userID, userID, beforeID, before, before, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []SearchResult{}
+	for rows.Next() {
+		var r SearchResult
+		var content, photo, name sql.NullString
+		var editedAt sql.NullTime
+		if err := rows.Scan(
+			&r.Message.ID, &r.Message.ConversationID, &r.Message.SenderID, &r.Message.SenderName,
+			&content, &r.Message.Language, &photo, &r.Message.Timestamp, &r.Message.System, &editedAt,
+			&r.Message.ViaHook, &r.IsGroup, &name,
+		); err != nil {
+			return nil, err
+		}
+		r.Message.Content = content.String
+		r.Message.PhotoID = photo.String
+		if editedAt.Valid {
+			r.Message.EditedAt = &editedAt.Time
+		}
+		r.ConversationName = name.String
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
@@ -7,17 +7,21 @@ and adding/removing reactions.
 package database
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"log"
+	"strings"
 	"time"
 
-	"github.com/gofrs/uuid"
+	"wasatext/service/ids"
+	"wasatext/service/langdetect"
 )
 
 // CreateMessage creates a new message in a conversation
-func (db *appdbimpl) CreateMessage(conversationID, senderID, content string, photo []byte, replyTo *string) (*Message, error) {
+func (db *appdbimpl) CreateMessage(ctx context.Context, conversationID ids.ConversationID, senderID ids.UserID, content string, photo []byte, replyTo *ids.MessageID) (*Message, error) {
 	// Generate message ID
-	id, err := uuid.NewV4()
+	id, err := newMessageID(ctx, db.db)
 	if err != nil {
 		return nil, err
 	}
@@ -30,84 +34,202 @@ func (db *appdbimpl) CreateMessage(conversationID, senderID, content string, pho
 		contentVal = content
 	}
 
-	var photoVal interface{}
+	// The photo goes to the blob store first, and is dropped again if
+	// the message cannot be created
+	var stored *storedPhoto
+	var photoID sql.NullString
 	if photo != nil {
-		photoVal = photo
+		if stored, err = db.putPhoto(photo); err != nil {
+			return nil, err
+		}
+		photoID = sql.NullString{String: stored.id, Valid: true}
 	}
+	committed := false
 
 	var replyToVal interface{}
 	if replyTo != nil && *replyTo != "" {
 		replyToVal = *replyTo
 	}
 
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+		if !committed {
+			db.dropPhoto(stored)
+		}
+	}()
+
+	// Only the admin posts in a channel
+	if err := checkCanPost(ctx, tx, conversationID, senderID); err != nil {
+		return nil, err
+	}
+
 	// Insert the message
-	_, err = db.db.Exec(`
-		INSERT INTO messages (id, conversation_id, sender_id, content, photo, timestamp, status, reply_to)
-		VALUES (?, ?, ?, ?, ?, ?, 'sent', ?)
-	`, id.String(), conversationID, senderID, contentVal, photoVal, timestamp, replyToVal)
+	if stored != nil {
+		if err := insertMedia(ctx, tx, stored); err != nil {
+			return nil, err
+		}
+	}
+	language := messageLanguage(content)
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO messages (id, conversation_id, sender_id, content, photo_id, timestamp, reply_to, language)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, id, conversationID, senderID, contentVal, photoID, timestamp, replyToVal, language)
+	if err != nil {
+		return nil, err
+	}
+	if err := chainMessage(ctx, tx, id); err != nil {
+		return nil, err
+	}
+	if err := insertMentions(ctx, tx, id, conversationID, senderID, content); err != nil {
+		return nil, err
+	}
 
+	// Create a pending receipt for every other participant (later, in a large group)
+	fanoutPending, err := insertReceipts(ctx, tx, id, conversationID, senderID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Update message status to 'received' for other participants
-	// (In a real app, this would happen when they fetch their conversations)
-	go db.updateMessageStatusForRecipients(id.String(), conversationID, senderID)
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	committed = true
 
-	// Get sender name
-	sender, err := db.GetUserByID(senderID)
+	// Get sender name, and what the message replies to
+	sender, err := db.GetUserByID(ctx, senderID)
+	if err != nil {
+		return nil, err
+	}
+	var reply *ReplyPreview
+	if replyToVal != nil {
+		if reply, err = db.getReplyPreview(ctx, conversationID, *replyTo); err != nil {
+			return nil, err
+		}
+	}
+	var photoState string
+	if stored != nil {
+		photoState = MediaPending
+	}
+	mentions, err := db.getMessageMentions(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Message{
-		ID:         id.String(),
-		SenderID:   senderID,
-		SenderName: sender.Name,
-		Content:    content,
-		Photo:      photo,
-		Timestamp:  timestamp,
-		Status:     "sent",
-		ReplyTo:    replyTo,
-		Comments:   []Comment{},
+		ID:             id,
+		ConversationID: conversationID,
+		SenderID:       senderID,
+		SenderName:     sender.Name,
+		Content:        content,
+		Language:       language.String,
+		PhotoID:        photoID.String,
+		PhotoState:     photoState,
+		Timestamp:      timestamp,
+		Status:         "sent",
+		ReplyTo:        replyTo,
+		Reply:          reply,
+		FanoutPending:  fanoutPending,
+		Mentions:       mentions,
+		Comments:       []Comment{},
 	}, nil
 }
 
-// updateMessageStatusForRecipients marks message as received
-func (db *appdbimpl) updateMessageStatusForRecipients(messageID, conversationID, senderID string) {
-	// Check if all other participants have "seen" the message in their list
-	// For simplicity, we mark as received immediately
-	_, _ = db.db.Exec(
-		"UPDATE messages SET status = 'received' WHERE id = ?",
-		messageID,
-	)
+// messageLanguage detects the language of the text of a message (see
+// the langdetect package), NULL when it cannot be told
+func messageLanguage(content string) sql.NullString {
+	language := langdetect.Detect(content)
+	return sql.NullString{String: language, Valid: language != ""}
+}
+
+// messageStatusSQL derives a message's status from its receipts.
+// It expects the messages table to be aliased as "m".
+// A message is "received" once every recipient has it, and "read"
+// once every recipient has opened it. It stays "sent" while its
+// receipts are being inserted (see fanout.go).
+const messageStatusSQL = `
+	CASE
+		WHEN m.fanout_pending THEN 'sent'
+		WHEN NOT EXISTS (SELECT 1 FROM message_receipts r WHERE r.message_id = m.id) THEN 'sent'
+		WHEN NOT EXISTS (SELECT 1 FROM message_receipts r WHERE r.message_id = m.id AND r.read_at IS NULL) THEN 'read'
+		WHEN NOT EXISTS (SELECT 1 FROM message_receipts r WHERE r.message_id = m.id AND r.delivered_at IS NULL) THEN 'received'
+		ELSE 'sent'
+	END`
+
+// GetMessageStatuses returns the status of the latest limit messages of a conversation
+func (db *appdbimpl) GetMessageStatuses(ctx context.Context, conversationID ids.ConversationID, limit int) ([]MessageStatus, error) {
+	rows, err := db.db.QueryContext(ctx, `
+		SELECT m.id, m.sender_id, `+messageStatusSQL+`
+		FROM messages m
+		WHERE m.conversation_id = ?
+		ORDER BY m.timestamp DESC, m.id DESC
+		LIMIT ?
+	`, conversationID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var statuses []MessageStatus
+	for rows.Next() {
+		var st MessageStatus
+		if err := rows.Scan(&st.ID, &st.SenderID, &st.Status); err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, st)
+	}
+
+	return statuses, rows.Err()
 }
 
 // GetMessage retrieves a single message by ID
-func (db *appdbimpl) GetMessage(messageID string) (*Message, error) {
+func (db *appdbimpl) GetMessage(ctx context.Context, messageID ids.MessageID) (*Message, error) {
 	var msg Message
 	var content sql.NullString
 	var photo sql.NullString
 	var replyTo sql.NullString
+	var editedAt sql.NullTime
+	var notice Notice
 
-	err := db.db.QueryRow(`
-		SELECT m.id, m.sender_id, u.name, m.content, m.photo, m.timestamp, m.status, m.reply_to
+	err := db.db.QueryRowContext(ctx, `
+		SELECT m.id, m.conversation_id, m.sender_id, COALESCE(m.hook_name, u.name), m.content, COALESCE(m.language, ''), m.photo_id, m.timestamp, `+messageStatusSQL+`, m.reply_to, m.system, m.edited_at,
+			m.hook_name IS NOT NULL, m.fanout_pending, m.deleted_at IS NOT NULL, COALESCE(md.processing_state, ''),
+			EXISTS (SELECT 1 FROM message_pins p WHERE p.message_id = m.id), `+noticeColumns+`
 		FROM messages m
 		JOIN users u ON m.sender_id = u.id
+		LEFT JOIN media md ON md.id = m.photo_id`+noticeUsers+`
 		WHERE m.id = ?
 	`, messageID).Scan(
 		&msg.ID,
+		&msg.ConversationID,
 		&msg.SenderID,
 		&msg.SenderName,
 		&content,
+		&msg.Language,
 		&photo,
 		&msg.Timestamp,
 		&msg.Status,
 		&replyTo,
+		&msg.System,
+		&editedAt,
+		&msg.ViaHook,
+		&msg.FanoutPending,
+		&msg.Deleted,
+		&msg.PhotoState,
+		&msg.Pinned,
+		&notice.Kind,
+		&notice.UserID,
+		&notice.UserName,
+		&notice.Name,
 	)
 
 	if errors.Is(err, sql.ErrNoRows) {
-		return nil, ErrMessageNotFound
+		return nil, withID(ErrMessageNotFound, messageID)
 	}
 	if err != nil {
 		return nil, err
@@ -117,96 +239,346 @@ func (db *appdbimpl) GetMessage(messageID string) (*Message, error) {
 		msg.Content = content.String
 	}
 	if photo.Valid {
-		msg.Photo = []byte(photo.String)
+		msg.PhotoID = photo.String
+	}
+	if msg.Deleted {
+		msg.Content = DeletedMessageText
 	}
 	if replyTo.Valid {
-		msg.ReplyTo = &replyTo.String
+		replyToID := ids.MessageID(replyTo.String)
+		msg.ReplyTo = &replyToID
+		if msg.Reply, err = db.getReplyPreview(ctx, msg.ConversationID, replyToID); err != nil {
+			return nil, err
+		}
+	}
+	if editedAt.Valid {
+		msg.EditedAt = &editedAt.Time
 	}
+	msg.Notice = notice.orNil()
 
 	// Get comments
-	comments, err := db.getMessageComments(messageID)
+	comments, err := db.getMessageComments(ctx, messageID)
 	if err != nil {
 		return nil, err
 	}
 	msg.Comments = comments
+	if msg.Mentions, err = db.getMessageMentions(ctx, messageID); err != nil {
+		return nil, err
+	}
 
-	return &msg, nil
+	messages := []Message{msg}
+	if err := db.attachEvents(ctx, "", messages); err != nil {
+		return nil, err
+	}
+	return &messages[0], nil
 }
 
-// DeleteMessage deletes a message (only the sender can delete their own messages)
-func (db *appdbimpl) DeleteMessage(messageID, userID string) error {
+// DeletedMessageText stands in for the content of a message deleted for everyone
+const DeletedMessageText = "This message was deleted"
+
+/*
+DeleteMessage deletes a message for everyone (only the sender can delete
+their own messages) and reports whether it was kept as a tombstone. A
+message some reply refers to stays, without its text, photo, reactions
+and notes, so the replies keep their place; the others are deleted.
+*/
+func (db *appdbimpl) DeleteMessage(ctx context.Context, messageID ids.MessageID, userID ids.UserID) (bool, error) {
 	// First, check if the message exists and belongs to the user
-	var senderID string
-	err := db.db.QueryRow(
-		"SELECT sender_id FROM messages WHERE id = ?",
+	var senderID ids.UserID
+	var photoID sql.NullString
+	var replied bool
+	err := db.db.QueryRowContext(ctx,
+		"SELECT m.sender_id, m.photo_id, EXISTS (SELECT 1 FROM messages r WHERE r.reply_to = m.id) FROM messages m WHERE m.id = ? AND m.deleted_at IS NULL",
 		messageID,
-	).Scan(&senderID)
+	).Scan(&senderID, &photoID, &replied)
 
 	if errors.Is(err, sql.ErrNoRows) {
-		return ErrMessageNotFound
+		return false, withID(ErrMessageNotFound, messageID)
 	}
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	if senderID != userID {
-		return ErrNotMessageOwner
+		return false, withID(ErrNotMessageOwner, messageID)
 	}
 
-	// Delete all comments on this message first
-	_, err = db.db.Exec("DELETE FROM comments WHERE message_id = ?", messageID)
+	// The message, its chain entry and everything attached change together
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+	}()
+
+	// Delete all comments on this message first, its mentions and its pin
+	_, err = tx.ExecContext(ctx, "DELETE FROM comments WHERE message_id = ?", messageID)
+	if err != nil {
+		return false, err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM mentions WHERE message_id = ?", messageID)
+	if err != nil {
+		return false, err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM message_pins WHERE message_id = ?", messageID)
+	if err != nil {
+		return false, err
+	}
+
+	// Delete its receipts
+	_, err = tx.ExecContext(ctx, "DELETE FROM message_receipts WHERE message_id = ?", messageID)
+	if err != nil {
+		return false, err
+	}
+
+	// Delete the message, or empty it into a tombstone, then its photo
+	if replied {
+		_, err = tx.ExecContext(ctx, "DELETE FROM message_notes WHERE message_id = ?", messageID)
+		if err != nil {
+			return false, err
+		}
+		_, err = tx.ExecContext(ctx,
+			"UPDATE messages SET content = NULL, photo_id = NULL, edited_at = NULL, language = NULL, deleted_at = ? WHERE id = ?",
+			time.Now(), messageID,
+		)
+	} else {
+		_, err = tx.ExecContext(ctx, "DELETE FROM messages WHERE id = ?", messageID)
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := chainMessage(ctx, tx, messageID); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	db.releasePhotos(ctx, photoID.String)
+	return replied, nil
+}
+
+/*
+DeleteMessageForMe hides a message from the user only, whoever sent it;
+the others still see it. The user must be able to see the message.
+*/
+func (db *appdbimpl) DeleteMessageForMe(ctx context.Context, userID ids.UserID, messageID ids.MessageID) error {
+	var visible bool
+	err := db.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM messages m`+visibleMessages+` WHERE m.id = ?)
+	`, userID, messageID).Scan(&visible)
 	if err != nil {
 		return err
 	}
+	if !visible {
+		return withID(ErrMessageNotFound, messageID)
+	}
 
-	// Delete the message
-	_, err = db.db.Exec("DELETE FROM messages WHERE id = ?", messageID)
+	_, err = db.db.ExecContext(ctx,
+		"INSERT OR IGNORE INTO message_deletions (user_id, message_id, deleted_at) VALUES (?, ?, ?)",
+		userID, messageID, time.Now(),
+	)
 	return err
 }
 
-// UpdateMessageStatus updates the status of a message
-func (db *appdbimpl) UpdateMessageStatus(messageID, status string) error {
-	result, err := db.db.Exec(
-		"UPDATE messages SET status = ? WHERE id = ?",
-		status, messageID,
-	)
+/*
+GetMessageReceipts returns, for every recipient of a message, when it
+was delivered and read, by name. Only the sender of the message can see
+them.
+*/
+func (db *appdbimpl) GetMessageReceipts(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID, messageID ids.MessageID) ([]Receipt, error) {
+	if err := db.checkParticipant(ctx, userID, conversationID); err != nil {
+		return nil, err
+	}
+
+	var senderID ids.UserID
+	err := db.db.QueryRowContext(ctx,
+		"SELECT sender_id FROM messages WHERE id = ? AND conversation_id = ?",
+		messageID, conversationID,
+	).Scan(&senderID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, withID(ErrMessageNotFound, messageID)
+	}
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if senderID != userID {
+		return nil, withID(ErrNotMessageSender, messageID)
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	rows, err := db.db.QueryContext(ctx, `
+		SELECT r.user_id, u.name, r.delivered_at, r.read_at
+		FROM message_receipts r
+		JOIN users u ON r.user_id = u.id
+		WHERE r.message_id = ?
+		ORDER BY u.name
+	`, messageID)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if rowsAffected == 0 {
-		return ErrMessageNotFound
+	defer rows.Close()
+
+	var receipts []Receipt
+	for rows.Next() {
+		var rc Receipt
+		var deliveredAt, readAt sql.NullTime
+		if err := rows.Scan(&rc.UserID, &rc.UserName, &deliveredAt, &readAt); err != nil {
+			return nil, err
+		}
+		if deliveredAt.Valid {
+			rc.DeliveredAt = &deliveredAt.Time
+		}
+		if readAt.Valid {
+			rc.ReadAt = &readAt.Time
+		}
+		receipts = append(receipts, rc)
 	}
 
-	return nil
+	return receipts, rows.Err()
 }
 
-// AddComment adds a reaction (comment) to a message
-func (db *appdbimpl) AddComment(messageID, userID, emoticon string) error {
-	// Check if message exists
-	_, err := db.GetMessage(messageID)
+/*
+UpdateMessageContent replaces the text of a message and records when it
+was edited. Only the sender can edit, and only text messages: photos,
+system notices, webhook messages and tombstones keep their content.
+*/
+func (db *appdbimpl) UpdateMessageContent(ctx context.Context, messageID ids.MessageID, userID ids.UserID, content string) (*Message, error) {
+	// Step 1: Check the message exists, belongs to the user and is text
+	var senderID ids.UserID
+	var conversationID ids.ConversationID
+	var hasPhoto, system, viaHook, deleted bool
+	err := db.db.QueryRowContext(ctx,
+		"SELECT sender_id, conversation_id, photo_id IS NOT NULL, system, hook_name IS NOT NULL, deleted_at IS NOT NULL FROM messages WHERE id = ?",
+		messageID,
+	).Scan(&senderID, &conversationID, &hasPhoto, &system, &viaHook, &deleted)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, withID(ErrMessageNotFound, messageID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if senderID != userID {
+		return nil, withID(ErrNotMessageEditor, messageID)
+	}
+	if hasPhoto || system || viaHook || deleted {
+		return nil, withID(ErrMessageNotEditable, messageID)
+	}
+
+	// Step 2: Replace the text, and record it in the hash chain
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+	}()
+	_, err = tx.ExecContext(ctx,
+		"UPDATE messages SET content = ?, edited_at = ?, language = ? WHERE id = ?",
+		content, time.Now(), messageLanguage(content), messageID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := chainMessage(ctx, tx, messageID); err != nil {
+		return nil, err
+	}
+	if err := insertMentions(ctx, tx, messageID, conversationID, senderID, content); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return db.GetMessage(ctx, messageID)
+}
+
+// AddComment adds a reaction (comment) to a message. A user can react
+// with several distinct emoticons; the same emoticon twice counts once.
+func (db *appdbimpl) AddComment(ctx context.Context, messageID ids.MessageID, userID ids.UserID, emoticon string) error {
+	// Check if message exists (a tombstone takes no reactions)
+	msg, err := db.GetMessage(ctx, messageID)
 	if err != nil {
 		return err
 	}
+	if msg.Deleted {
+		return withID(ErrMessageNotFound, messageID)
+	}
 
-	// Insert or replace the comment (one reaction per user per message)
-	_, err = db.db.Exec(`
-		INSERT OR REPLACE INTO comments (message_id, user_id, emoticon)
-		VALUES (?, ?, ?)
-	`, messageID, userID, emoticon)
+	_, err = db.db.ExecContext(ctx, `
+		INSERT OR IGNORE INTO comments (message_id, user_id, emoticon, created_at)
+		VALUES (?, ?, ?, ?)
+	`, messageID, userID, emoticon, time.Now())
 
 	return err
 }
 
-// RemoveComment removes a user's reaction from a message
-func (db *appdbimpl) RemoveComment(messageID, userID string) error {
-	result, err := db.db.Exec(
-		"DELETE FROM comments WHERE message_id = ? AND user_id = ?",
-		messageID, userID,
+/*
+GetComments returns the reactions of several messages of a conversation
+in one query. Every requested message of the conversation has an entry,
+empty when nobody reacted; IDs of other messages are left out.
+*/
+func (db *appdbimpl) GetComments(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID, messageIDs []ids.MessageID) (map[ids.MessageID][]Comment, error) {
+	if err := db.checkParticipant(ctx, userID, conversationID); err != nil {
+		return nil, err
+	}
+
+	comments := make(map[ids.MessageID][]Comment, len(messageIDs))
+	if len(messageIDs) == 0 {
+		return comments, nil
+	}
+
+	args := make([]interface{}, 0, len(messageIDs)+1)
+	args = append(args, conversationID)
+	for _, id := range messageIDs {
+		args = append(args, id)
+	}
+	placeholders := strings.Repeat(", ?", len(messageIDs))[2:]
+
+	rows, err := db.db.QueryContext(ctx, `
+		SELECT m.id, c.user_id, u.name, c.emoticon
+		FROM messages m
+		LEFT JOIN comments c ON c.message_id = m.id
+		LEFT JOIN users u ON c.user_id = u.id
+		WHERE m.conversation_id = ? AND m.id IN (`+placeholders+`)
+		ORDER BY c.created_at, c.rowid
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var messageID ids.MessageID
+		var commentUserID, userName, emoticon sql.NullString
+		if err := rows.Scan(&messageID, &commentUserID, &userName, &emoticon); err != nil {
+			return nil, err
+		}
+
+		// A message without reactions comes back once, with NULLs
+		if !commentUserID.Valid {
+			comments[messageID] = []Comment{}
+			continue
+		}
+		comments[messageID] = append(comments[messageID], Comment{
+			UserID:   ids.UserID(commentUserID.String),
+			UserName: userName.String,
+			Emoticon: emoticon.String,
+		})
+	}
+
+	return comments, rows.Err()
+}
+
+// RemoveComment removes a user's reaction with an emoticon from a
+// message, or all their reactions to it when emoticon is ""
+func (db *appdbimpl) RemoveComment(ctx context.Context, messageID ids.MessageID, userID ids.UserID, emoticon string) error {
+	result, err := db.db.ExecContext(ctx,
+		"DELETE FROM comments WHERE message_id = ? AND user_id = ? AND (? = '' OR emoticon = ?)",
+		messageID, userID, emoticon, emoticon,
 	)
 	if err != nil {
 		return err
@@ -217,8 +589,16 @@ func (db *appdbimpl) RemoveComment(messageID, userID string) error {
 		return err
 	}
 	if rowsAffected == 0 {
-		return ErrCommentNotFound
+		return withID(ErrCommentNotFound, messageID)
 	}
 
 	return nil
 }
+
+// CountMessagesSince counts the messages sent since the given time, in
+// every conversation, for the status page
+func (db *appdbimpl) CountMessagesSince(ctx context.Context, since time.Time) (int, error) {
+	var count int
+	err := db.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM messages WHERE timestamp >= ?", since).Scan(&count)
+	return count, err
+}
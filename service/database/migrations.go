@@ -0,0 +1,1229 @@
+/*
+Database schema migrations.
+
+createTables sets up the original (version 0) schema. Every change made
+after that is a migration: a numbered step that runs once, inside a
+transaction, and bumps SQLite's user_version so it never runs again.
+
+To change the schema, append a new migration to the list - never edit
+one that has already shipped.
+*/
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"wasatext/service/langdetect"
+)
+
+// migration is a single schema upgrade step
+type migration struct {
+	version     int
+	description string
+	up          func(tx *sql.Tx) error
+}
+
+// migrations is the ordered list of schema upgrades
+var migrations = []migration{
+	{1, "per-recipient message receipts", migrateMessageReceipts},
+	{2, "two-phase account deletion", migrateAccountDeletion},
+	{3, "anti-spam tracking", migrateSpamTracking},
+	{4, "moderation queue", migrateModerationQueue},
+	{5, "workspaces", migrateWorkspaces},
+	{6, "guest tokens", migrateGuestTokens},
+	{7, "conversation privacy settings", migrateConversationPrivacy},
+	{8, "invites", migrateInvites},
+	{9, "channels", migrateChannels},
+	{10, "usage counters", migrateUsageCounters},
+	{11, "message edits", migrateMessageEdits},
+	{12, "webhooks", migrateHooks},
+	{13, "channel feeds", migrateChannelFeeds},
+	{14, "receipt fanout", migrateReceiptFanout},
+	{15, "sessions", migrateSessions},
+	{16, "widget tokens", migrateWidgetTokens},
+	{17, "media store", migrateMediaStore},
+	{18, "message search", migrateMessageSearch},
+	{19, "cleared conversations", migrateClearedConversations},
+	{20, "message notes", migrateMessageNotes},
+	{21, "message deletions", migrateMessageDeletions},
+	{22, "photo renditions", migratePhotoRenditions},
+	{23, "media processing", migrateMediaProcessing},
+	{24, "user blocks", migrateUserBlocks},
+	{25, "settings", migrateSettings},
+	{26, "photo text search", migratePhotoText},
+	{27, "group events", migrateGroupEvents},
+	{28, "message reminders", migrateMessageReminders},
+	{29, "message languages", migrateMessageLanguages},
+	{30, "API usage", migrateAPIUsage},
+	{31, "larger thumbnails", migrateThumbnailSize},
+	{32, "soft-deleted conversations", migrateSoftDeletion},
+	{33, "message hash chain", migrateMessageChain},
+	{34, "scheduled messages", migrateScheduledMessages},
+	{35, "several reactions per user", migrateMultipleReactions},
+	{36, "mentions", migrateMentions},
+	{37, "account deactivation", migrateAccountDeactivation},
+	{38, "group governance", migrateGroupGovernance},
+	{39, "pinned messages", migratePinnedMessages},
+	{40, "key backups", migrateKeyBackups},
+	{41, "group descriptions", migrateGroupDescriptions},
+	{42, "message time index", migrateMessageTimeIndex},
+	{43, "group notices", migrateGroupNotices},
+	{44, "push subscriptions and muted conversations", migratePushSubscriptions},
+	{45, "last seen", migrateLastSeen},
+	{46, "user about", migrateUserAbout},
+	{47, "outbound webhooks", migrateOutboundWebhooks},
+	{48, "bot accounts", migrateBots},
+	{49, "muted deleted conversations", migrateDeletedParticipantMutes},
+}
+
+// runMigrations applies every migration newer than the database's user_version
+func runMigrations(db *sql.DB) error {
+	var current int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&current); err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		if err := applyMigration(db, m); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.version, m.description, err)
+		}
+		log.Printf("Applied database migration %d: %s", m.version, m.description)
+	}
+
+	return nil
+}
+
+// applyMigration runs one migration and records its version atomically
+func applyMigration(db *sql.DB, m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+	}()
+
+	if err := m.up(tx); err != nil {
+		return err
+	}
+
+	// PRAGMA does not accept bound parameters
+	if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", m.version)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// hasColumn reports whether a table has a column with the given name
+func hasColumn(tx *sql.Tx, table, column string) (bool, error) {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var defaultVal sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultVal, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+
+	return false, rows.Err()
+}
+
+/*
+migrateMessageReceipts replaces the single messages.status column with
+one receipt row per recipient.
+
+The old column was shared by everybody in the conversation, so the best
+we can do is copy it onto every current recipient: "received" becomes a
+delivery, "read" becomes a delivery plus a read. That keeps the
+checkmarks users already see exactly as they were.
+*/
+func migrateMessageReceipts(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS message_receipts (
+			message_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			delivered_at DATETIME,
+			read_at DATETIME,
+			PRIMARY KEY (message_id, user_id),
+			FOREIGN KEY (message_id) REFERENCES messages(id),
+			FOREIGN KEY (user_id) REFERENCES users(id)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	hasStatus, err := hasColumn(tx, "messages", "status")
+	if err != nil {
+		return err
+	}
+	if !hasStatus {
+		return nil
+	}
+
+	// One receipt for every participant other than the sender
+	_, err = tx.Exec(`
+		INSERT OR IGNORE INTO message_receipts (message_id, user_id, delivered_at, read_at)
+		SELECT
+			m.id,
+			cp.user_id,
+			CASE WHEN m.status IN ('received', 'read') THEN m.timestamp END,
+			CASE WHEN m.status = 'read' THEN m.timestamp END
+		FROM messages m
+		JOIN conversation_participants cp ON cp.conversation_id = m.conversation_id
+		WHERE cp.user_id != m.sender_id
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("ALTER TABLE messages DROP COLUMN status")
+	return err
+}
+
+// migrateAccountDeletion adds the soft-delete marker and the purge report
+func migrateAccountDeletion(tx *sql.Tx) error {
+	_, err := tx.Exec("ALTER TABLE users ADD COLUMN purged_at DATETIME")
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		CREATE TABLE IF NOT EXISTS purge_log (
+			user_id TEXT PRIMARY KEY,
+			deleted_at DATETIME NOT NULL,
+			purged_at DATETIME NOT NULL,
+			messages_deleted INTEGER NOT NULL,
+			comments_deleted INTEGER NOT NULL,
+			media_deleted INTEGER NOT NULL,
+			FOREIGN KEY (user_id) REFERENCES users(id)
+		)
+	`)
+	return err
+}
+
+// migrateSpamTracking records account and conversation creation times
+// and adds the tables used by the anti-spam heuristics
+func migrateSpamTracking(tx *sql.Tx) error {
+	for _, query := range []string{
+		// Existing rows keep NULL, which counts as "old enough"
+		"ALTER TABLE users ADD COLUMN created_at DATETIME",
+		"ALTER TABLE conversations ADD COLUMN created_by TEXT REFERENCES users(id)",
+		"ALTER TABLE conversations ADD COLUMN created_at DATETIME",
+		`CREATE TABLE IF NOT EXISTS spam_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id TEXT NOT NULL,
+			kind TEXT NOT NULL,
+			detail TEXT NOT NULL DEFAULT '',
+			score INTEGER NOT NULL,
+			created_at DATETIME NOT NULL,
+			FOREIGN KEY (user_id) REFERENCES users(id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS user_throttles (
+			user_id TEXT PRIMARY KEY,
+			until DATETIME NOT NULL,
+			reason TEXT NOT NULL,
+			FOREIGN KEY (user_id) REFERENCES users(id)
+		)`,
+	} {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateModerationQueue adds the moderation queue, its audit log,
+// user warnings and bans
+func migrateModerationQueue(tx *sql.Tx) error {
+	for _, query := range []string{
+		"ALTER TABLE users ADD COLUMN banned_at DATETIME",
+		`CREATE TABLE IF NOT EXISTS moderation_items (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			source TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			message_id TEXT,
+			reporter_id TEXT,
+			reason TEXT NOT NULL DEFAULT '',
+			created_at DATETIME NOT NULL,
+			resolved_at DATETIME,
+			resolution TEXT,
+			FOREIGN KEY (user_id) REFERENCES users(id),
+			FOREIGN KEY (message_id) REFERENCES messages(id),
+			FOREIGN KEY (reporter_id) REFERENCES users(id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS moderation_audit (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			item_id INTEGER NOT NULL,
+			action TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			message_id TEXT,
+			note TEXT NOT NULL DEFAULT '',
+			created_at DATETIME NOT NULL,
+			FOREIGN KEY (item_id) REFERENCES moderation_items(id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS user_warnings (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id TEXT NOT NULL,
+			reason TEXT NOT NULL DEFAULT '',
+			created_at DATETIME NOT NULL,
+			FOREIGN KEY (user_id) REFERENCES users(id)
+		)`,
+	} {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+migrateWorkspaces scopes users, groups and conversations to a workspace.
+
+Everything that already exists moves to the "default" workspace.
+Usernames become unique per workspace instead of globally, which needs
+the users table to be rebuilt (SQLite cannot drop a UNIQUE constraint).
+*/
+func migrateWorkspaces(tx *sql.Tx) error {
+	for _, query := range []string{
+		`CREATE TABLE IF NOT EXISTS workspaces (
+			id TEXT PRIMARY KEY,
+			name TEXT UNIQUE NOT NULL,
+			created_at DATETIME NOT NULL
+		)`,
+		`INSERT OR IGNORE INTO workspaces (id, name, created_at)
+			VALUES ('` + DefaultWorkspaceID + `', 'Default', CURRENT_TIMESTAMP)`,
+		`CREATE TABLE users_new (
+			id TEXT PRIMARY KEY,
+			workspace_id TEXT NOT NULL DEFAULT '` + DefaultWorkspaceID + `',
+			name TEXT NOT NULL,
+			photo BLOB,
+			purged_at DATETIME,
+			created_at DATETIME,
+			banned_at DATETIME,
+			UNIQUE (workspace_id, name),
+			FOREIGN KEY (workspace_id) REFERENCES workspaces(id)
+		)`,
+		`INSERT INTO users_new (id, name, photo, purged_at, created_at, banned_at)
+			SELECT id, name, photo, purged_at, created_at, banned_at FROM users`,
+		"DROP TABLE users",
+		"ALTER TABLE users_new RENAME TO users",
+		"ALTER TABLE groups ADD COLUMN workspace_id TEXT NOT NULL DEFAULT '" + DefaultWorkspaceID + "' REFERENCES workspaces(id)",
+		"ALTER TABLE conversations ADD COLUMN workspace_id TEXT NOT NULL DEFAULT '" + DefaultWorkspaceID + "' REFERENCES workspaces(id)",
+	} {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateGuestTokens adds read-only guest tokens for group conversations
+func migrateGuestTokens(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS guest_tokens (
+			token TEXT PRIMARY KEY,
+			group_id TEXT NOT NULL,
+			created_by TEXT NOT NULL,
+			created_at DATETIME NOT NULL,
+			expires_at DATETIME,
+			revoked_at DATETIME,
+			FOREIGN KEY (group_id) REFERENCES groups(id),
+			FOREIGN KEY (created_by) REFERENCES users(id)
+		)
+	`)
+	return err
+}
+
+// migrateConversationPrivacy adds per-conversation typing and read receipt settings
+func migrateConversationPrivacy(tx *sql.Tx) error {
+	for _, query := range []string{
+		"ALTER TABLE conversation_participants ADD COLUMN share_typing BOOLEAN NOT NULL DEFAULT 1",
+		"ALTER TABLE conversation_participants ADD COLUMN share_read_receipts BOOLEAN NOT NULL DEFAULT 1",
+	} {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateInvites adds the invite codes of invite-only registration
+func migrateInvites(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS invites (
+			code TEXT PRIMARY KEY,
+			workspace_id TEXT NOT NULL,
+			created_by TEXT,
+			created_at DATETIME NOT NULL,
+			expires_at DATETIME,
+			used_by TEXT,
+			used_at DATETIME,
+			FOREIGN KEY (workspace_id) REFERENCES workspaces(id),
+			FOREIGN KEY (created_by) REFERENCES users(id),
+			FOREIGN KEY (used_by) REFERENCES users(id)
+		)
+	`)
+	return err
+}
+
+// migrateChannels adds the kind of a group (group or channel) and system messages
+func migrateChannels(tx *sql.Tx) error {
+	for _, query := range []string{
+		"ALTER TABLE groups ADD COLUMN kind TEXT NOT NULL DEFAULT 'group'",
+		"ALTER TABLE messages ADD COLUMN system BOOLEAN NOT NULL DEFAULT 0",
+	} {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateUsageCounters adds the daily counters of the usage quotas
+func migrateUsageCounters(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS usage_counters (
+			user_id TEXT NOT NULL,
+			day TEXT NOT NULL,
+			messages INTEGER NOT NULL DEFAULT 0,
+			uploads INTEGER NOT NULL DEFAULT 0,
+			last_message_at DATETIME,
+			last_upload_at DATETIME,
+			PRIMARY KEY (user_id, day),
+			FOREIGN KEY (user_id) REFERENCES users(id)
+		)
+	`)
+	return err
+}
+
+// migrateMessageEdits records when a message was last edited
+func migrateMessageEdits(tx *sql.Tx) error {
+	_, err := tx.Exec("ALTER TABLE messages ADD COLUMN edited_at DATETIME")
+	return err
+}
+
+// migrateHooks adds the inbound webhooks and the name their messages are shown under
+func migrateHooks(tx *sql.Tx) error {
+	for _, query := range []string{
+		`CREATE TABLE IF NOT EXISTS hooks (
+			token TEXT PRIMARY KEY,
+			conversation_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			rate_per_minute INTEGER NOT NULL,
+			created_by TEXT NOT NULL,
+			created_at DATETIME NOT NULL,
+			FOREIGN KEY (conversation_id) REFERENCES conversations(id),
+			FOREIGN KEY (created_by) REFERENCES users(id)
+		)`,
+		"ALTER TABLE messages ADD COLUMN hook_name TEXT",
+	} {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateChannelFeeds adds the switch that publishes a channel as an Atom feed
+func migrateChannelFeeds(tx *sql.Tx) error {
+	_, err := tx.Exec("ALTER TABLE groups ADD COLUMN public_feed BOOLEAN NOT NULL DEFAULT 0")
+	return err
+}
+
+// migrateReceiptFanout marks the messages whose receipts are still being inserted (see fanout.go)
+func migrateReceiptFanout(tx *sql.Tx) error {
+	_, err := tx.Exec("ALTER TABLE messages ADD COLUMN fanout_pending BOOLEAN NOT NULL DEFAULT 0")
+	return err
+}
+
+// migrateSessions adds the sessions opened by logging in (see sessions.go)
+func migrateSessions(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS sessions (
+			token_hash TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL,
+			created_at DATETIME NOT NULL,
+			FOREIGN KEY (user_id) REFERENCES users(id)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id)")
+	return err
+}
+
+// migrateWidgetTokens adds the tokens reading one conversation (see widgets.go)
+func migrateWidgetTokens(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS widget_tokens (
+			token TEXT PRIMARY KEY,
+			conversation_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			created_by TEXT NOT NULL,
+			created_at DATETIME NOT NULL,
+			expires_at DATETIME,
+			FOREIGN KEY (conversation_id) REFERENCES conversations(id),
+			FOREIGN KEY (created_by) REFERENCES users(id)
+		)
+	`)
+	return err
+}
+
+/*
+migrateMediaStore adds the media table and the photo_id columns (see
+media.go). The photo columns stay, empty once moveBlobs has moved their
+bytes to the blob store.
+*/
+func migrateMediaStore(tx *sql.Tx) error {
+	for _, query := range []string{
+		`CREATE TABLE IF NOT EXISTS media (
+			id TEXT PRIMARY KEY,
+			sha256 TEXT NOT NULL,
+			size INTEGER NOT NULL,
+			created_at DATETIME NOT NULL
+		)`,
+		"ALTER TABLE users ADD COLUMN photo_id TEXT REFERENCES media(id)",
+		"ALTER TABLE groups ADD COLUMN photo_id TEXT REFERENCES media(id)",
+		"ALTER TABLE messages ADD COLUMN photo_id TEXT REFERENCES media(id)",
+		"CREATE INDEX IF NOT EXISTS idx_messages_photo ON messages(photo_id) WHERE photo_id IS NOT NULL",
+	} {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+migrateMessageSearch adds the full-text index of the message texts (see
+search.go), fills it with the existing messages and adds the triggers
+keeping it in sync.
+
+The index is an FTS5 table whose rowids come from message_search_rows:
+the rowids of messages itself are not stable (VACUUM may renumber them,
+the table has no INTEGER PRIMARY KEY).
+*/
+func migrateMessageSearch(tx *sql.Tx) error {
+	// Index a message's text, if it has one
+	const insertRow = `
+		INSERT INTO message_search_rows (message_id)
+			SELECT new.id WHERE new.content IS NOT NULL AND new.content != '';
+		INSERT INTO message_search (rowid, content)
+			SELECT last_insert_rowid(), new.content WHERE new.content IS NOT NULL AND new.content != '';`
+	// Drop a message from the index
+	const deleteRow = `
+		DELETE FROM message_search WHERE rowid = (SELECT rowid FROM message_search_rows WHERE message_id = old.id);
+		DELETE FROM message_search_rows WHERE message_id = old.id;`
+
+	for _, query := range []string{
+		`CREATE TABLE IF NOT EXISTS message_search_rows (
+			rowid INTEGER PRIMARY KEY,
+			message_id TEXT UNIQUE NOT NULL
+		)`,
+		"CREATE VIRTUAL TABLE IF NOT EXISTS message_search USING fts5(content, tokenize = 'unicode61 remove_diacritics 2')",
+		`INSERT INTO message_search_rows (message_id)
+			SELECT id FROM messages WHERE content IS NOT NULL AND content != ''`,
+		`INSERT INTO message_search (rowid, content)
+			SELECT sr.rowid, m.content FROM message_search_rows sr JOIN messages m ON m.id = sr.message_id`,
+		"CREATE TRIGGER IF NOT EXISTS messages_search_insert AFTER INSERT ON messages BEGIN" + insertRow + " END",
+		"CREATE TRIGGER IF NOT EXISTS messages_search_update AFTER UPDATE OF content ON messages BEGIN" + deleteRow + insertRow + " END",
+		"CREATE TRIGGER IF NOT EXISTS messages_search_delete AFTER DELETE ON messages BEGIN" + deleteRow + " END",
+	} {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateClearedConversations adds when each participant last cleared a
+// conversation: the messages sent before are hidden from them only
+func migrateClearedConversations(tx *sql.Tx) error {
+	_, err := tx.Exec("ALTER TABLE conversation_participants ADD COLUMN cleared_before DATETIME")
+	return err
+}
+
+// migrateMessageNotes adds the private notes users attach to messages,
+// and the trigger dropping them with their message
+func migrateMessageNotes(tx *sql.Tx) error {
+	for _, query := range []string{
+		`CREATE TABLE IF NOT EXISTS message_notes (
+			user_id TEXT NOT NULL,
+			message_id TEXT NOT NULL,
+			note TEXT NOT NULL,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL,
+			PRIMARY KEY (user_id, message_id),
+			FOREIGN KEY (user_id) REFERENCES users(id),
+			FOREIGN KEY (message_id) REFERENCES messages(id)
+		)`,
+		"CREATE INDEX IF NOT EXISTS idx_message_notes_message ON message_notes(message_id)",
+		`CREATE TRIGGER IF NOT EXISTS messages_notes_delete AFTER DELETE ON messages BEGIN
+			DELETE FROM message_notes WHERE message_id = old.id;
+		END`,
+	} {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+migrateMessageDeletions adds when a message was deleted for everyone
+(it then stays as a tombstone for the replies to it), and the messages
+users deleted for themselves only, dropped with their message.
+*/
+func migrateMessageDeletions(tx *sql.Tx) error {
+	for _, query := range []string{
+		"ALTER TABLE messages ADD COLUMN deleted_at DATETIME",
+		`CREATE TABLE IF NOT EXISTS message_deletions (
+			user_id TEXT NOT NULL,
+			message_id TEXT NOT NULL,
+			deleted_at DATETIME NOT NULL,
+			PRIMARY KEY (user_id, message_id),
+			FOREIGN KEY (user_id) REFERENCES users(id),
+			FOREIGN KEY (message_id) REFERENCES messages(id)
+		)`,
+		"CREATE INDEX IF NOT EXISTS idx_message_deletions_message ON message_deletions(message_id)",
+		`CREATE TRIGGER IF NOT EXISTS messages_deletions_delete AFTER DELETE ON messages BEGIN
+			DELETE FROM message_deletions WHERE message_id = old.id;
+		END`,
+	} {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migratePhotoRenditions adds the smaller renditions of the photos,
+// made on first request; blob_id is the photo's own blob when it is
+// already small enough
+func migratePhotoRenditions(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS media_renditions (
+			media_id TEXT NOT NULL,
+			quality TEXT NOT NULL,
+			blob_id TEXT NOT NULL,
+			sha256 TEXT NOT NULL,
+			size INTEGER NOT NULL,
+			created_at DATETIME NOT NULL,
+			PRIMARY KEY (media_id, quality),
+			FOREIGN KEY (media_id) REFERENCES media(id)
+		)
+	`)
+	return err
+}
+
+// migrateMediaProcessing adds the processing state of the photos; those
+// stored so far are ready (see ProcessMedia)
+func migrateMediaProcessing(tx *sql.Tx) error {
+	_, err := tx.Exec("ALTER TABLE media ADD COLUMN processing_state TEXT NOT NULL DEFAULT 'ready'")
+	return err
+}
+
+// migrateUserBlocks adds the users each user blocked
+func migrateUserBlocks(tx *sql.Tx) error {
+	for _, query := range []string{
+		`CREATE TABLE IF NOT EXISTS user_blocks (
+			blocker_id TEXT NOT NULL,
+			blocked_id TEXT NOT NULL,
+			created_at DATETIME NOT NULL,
+			PRIMARY KEY (blocker_id, blocked_id),
+			FOREIGN KEY (blocker_id) REFERENCES users(id),
+			FOREIGN KEY (blocked_id) REFERENCES users(id)
+		)`,
+		"CREATE INDEX IF NOT EXISTS idx_user_blocks_blocked ON user_blocks(blocked_id)",
+	} {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateSettings adds the settings the admin changes through the API
+func migrateSettings(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS settings (
+			key TEXT PRIMARY KEY,
+			value TEXT NOT NULL,
+			updated_at DATETIME NOT NULL
+		)
+	`)
+	return err
+}
+
+/*
+migratePhotoText adds the text read from the message photos (see
+phototext.go) and its search index, an FTS5 table like message_search.
+The index follows photo_text through triggers; its rows have no text
+to index when the photo has none.
+*/
+func migratePhotoText(tx *sql.Tx) error {
+	for _, query := range []string{
+		`CREATE TABLE IF NOT EXISTS photo_text (
+			rowid INTEGER PRIMARY KEY,
+			media_id TEXT UNIQUE NOT NULL,
+			text TEXT NOT NULL,
+			created_at DATETIME NOT NULL,
+			FOREIGN KEY (media_id) REFERENCES media(id)
+		)`,
+		"CREATE VIRTUAL TABLE IF NOT EXISTS photo_text_search USING fts5(text, tokenize = 'unicode61 remove_diacritics 2')",
+		`CREATE TRIGGER IF NOT EXISTS photo_text_search_insert AFTER INSERT ON photo_text WHEN new.text != '' BEGIN
+			INSERT INTO photo_text_search (rowid, text) VALUES (new.rowid, new.text);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS photo_text_search_delete AFTER DELETE ON photo_text BEGIN
+			DELETE FROM photo_text_search WHERE rowid = old.rowid;
+		END`,
+	} {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+migrateGroupEvents adds the events of the groups, each attached to the
+message announcing it, and the answers of the members. Triggers drop an
+event with its message, when the message is deleted or becomes a
+tombstone, and the answers with their event.
+*/
+func migrateGroupEvents(tx *sql.Tx) error {
+	const deleteEvent = "DELETE FROM group_events WHERE message_id = old.id;"
+	for _, query := range []string{
+		`CREATE TABLE IF NOT EXISTS group_events (
+			message_id TEXT PRIMARY KEY,
+			conversation_id TEXT NOT NULL,
+			starts_at DATETIME NOT NULL,
+			location TEXT NOT NULL DEFAULT '',
+			remind_at DATETIME,
+			reminded_at DATETIME,
+			FOREIGN KEY (message_id) REFERENCES messages(id),
+			FOREIGN KEY (conversation_id) REFERENCES conversations(id)
+		)`,
+		"CREATE INDEX IF NOT EXISTS idx_group_events_remind ON group_events(remind_at) WHERE reminded_at IS NULL",
+		`CREATE TABLE IF NOT EXISTS event_rsvps (
+			message_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			response TEXT NOT NULL,
+			updated_at DATETIME NOT NULL,
+			PRIMARY KEY (message_id, user_id),
+			FOREIGN KEY (message_id) REFERENCES group_events(message_id),
+			FOREIGN KEY (user_id) REFERENCES users(id)
+		)`,
+		"CREATE INDEX IF NOT EXISTS idx_event_rsvps_user ON event_rsvps(user_id)",
+		"CREATE TRIGGER IF NOT EXISTS messages_events_delete AFTER DELETE ON messages BEGIN " + deleteEvent + " END",
+		"CREATE TRIGGER IF NOT EXISTS messages_events_tombstone AFTER UPDATE OF deleted_at ON messages WHEN new.deleted_at IS NOT NULL BEGIN " + deleteEvent + " END",
+		`CREATE TRIGGER IF NOT EXISTS group_events_rsvps_delete AFTER DELETE ON group_events BEGIN
+			DELETE FROM event_rsvps WHERE message_id = old.message_id;
+		END`,
+	} {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+migrateMessageReminders adds the reminders users set on messages, one
+per user and message. Triggers drop a reminder with its message, when
+the message is deleted or becomes a tombstone.
+*/
+func migrateMessageReminders(tx *sql.Tx) error {
+	const deleteReminders = "DELETE FROM message_reminders WHERE message_id = old.id;"
+	for _, query := range []string{
+		`CREATE TABLE IF NOT EXISTS message_reminders (
+			user_id TEXT NOT NULL,
+			message_id TEXT NOT NULL,
+			remind_at DATETIME NOT NULL,
+			delivered_at DATETIME,
+			created_at DATETIME NOT NULL,
+			PRIMARY KEY (user_id, message_id),
+			FOREIGN KEY (user_id) REFERENCES users(id),
+			FOREIGN KEY (message_id) REFERENCES messages(id)
+		)`,
+		"CREATE INDEX IF NOT EXISTS idx_message_reminders_message ON message_reminders(message_id)",
+		"CREATE INDEX IF NOT EXISTS idx_message_reminders_due ON message_reminders(remind_at) WHERE delivered_at IS NULL",
+		"CREATE TRIGGER IF NOT EXISTS messages_reminders_delete AFTER DELETE ON messages BEGIN " + deleteReminders + " END",
+		"CREATE TRIGGER IF NOT EXISTS messages_reminders_tombstone AFTER UPDATE OF deleted_at ON messages WHEN new.deleted_at IS NOT NULL BEGIN " + deleteReminders + " END",
+	} {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+migrateMessageLanguages adds the language detected in the text of each
+message (see messageLanguage), NULL when it could not be told, and
+detects it for the messages already there.
+*/
+func migrateMessageLanguages(tx *sql.Tx) error {
+	if _, err := tx.Exec("ALTER TABLE messages ADD COLUMN language TEXT"); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query("SELECT id, content FROM messages WHERE content IS NOT NULL AND system = 0")
+	if err != nil {
+		return err
+	}
+	detected := make(map[string]string)
+	for rows.Next() {
+		var id, content string
+		if err := rows.Scan(&id, &content); err != nil {
+			rows.Close()
+			return err
+		}
+		if language := langdetect.Detect(content); language != "" {
+			detected[id] = language
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, language := range detected {
+		if _, err := tx.Exec("UPDATE messages SET language = ? WHERE id = ?", language, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateAPIUsage adds the sampled request counts of the API usage
+// analytics, per user, day and route
+func migrateAPIUsage(tx *sql.Tx) error {
+	for _, query := range []string{
+		`CREATE TABLE IF NOT EXISTS api_usage (
+			user_id TEXT NOT NULL,
+			day TEXT NOT NULL,
+			method TEXT NOT NULL,
+			route TEXT NOT NULL,
+			requests INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (user_id, day, method, route),
+			FOREIGN KEY (user_id) REFERENCES users(id)
+		)`,
+		"CREATE INDEX IF NOT EXISTS idx_api_usage_day ON api_usage(day)",
+	} {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+migrateThumbnailSize retires the thumbnails made at 200 pixels, now that
+they are made at 320: they are kept under another quality, which no
+request asks for, so that their blobs still go away with their photo,
+and the thumbnails are made again on first request.
+*/
+func migrateThumbnailSize(tx *sql.Tx) error {
+	_, err := tx.Exec("UPDATE media_renditions SET quality = 'thumb-200' WHERE quality = 'thumb'")
+	return err
+}
+
+/*
+migrateSoftDeletion adds the conversations and groups an admin deleted
+and may restore (see softdelete.go). Their participants and members are
+moved to the deleted_ tables meanwhile, which have the same columns: a
+column added to conversation_participants or group_members must be
+added to its deleted_ table too, and to the columns softdelete.go moves.
+*/
+func migrateSoftDeletion(tx *sql.Tx) error {
+	for _, query := range []string{
+		"ALTER TABLE conversations ADD COLUMN deleted_at DATETIME",
+		"ALTER TABLE conversations ADD COLUMN purge_at DATETIME",
+		"ALTER TABLE groups ADD COLUMN deleted_at DATETIME",
+		`CREATE TABLE IF NOT EXISTS deleted_conversation_participants (
+			conversation_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			last_read_time DATETIME,
+			share_typing BOOLEAN NOT NULL DEFAULT 1,
+			share_read_receipts BOOLEAN NOT NULL DEFAULT 1,
+			cleared_before DATETIME,
+			PRIMARY KEY (conversation_id, user_id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS deleted_group_members (
+			group_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			PRIMARY KEY (group_id, user_id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS deletion_audit (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			action TEXT NOT NULL,
+			conversation_id TEXT NOT NULL,
+			group_id TEXT,
+			note TEXT NOT NULL DEFAULT '',
+			created_at DATETIME NOT NULL
+		)`,
+		"CREATE INDEX IF NOT EXISTS idx_conversations_purge_at ON conversations(purge_at)",
+	} {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+migrateMessageChain adds the hash chain of the messages of every
+conversation (see integrity.go), and chains the messages already there
+in the order they were sent: from then on, only changes made outside the
+application go unrecorded.
+*/
+func migrateMessageChain(tx *sql.Tx) error {
+	for _, query := range []string{
+		`CREATE TABLE IF NOT EXISTS message_chain (
+			conversation_id TEXT NOT NULL,
+			seq INTEGER NOT NULL,
+			message_id TEXT NOT NULL,
+			action TEXT NOT NULL,
+			digest TEXT NOT NULL,
+			hash TEXT NOT NULL,
+			created_at DATETIME NOT NULL,
+			PRIMARY KEY (conversation_id, seq)
+		)`,
+		"CREATE INDEX IF NOT EXISTS idx_message_chain_message ON message_chain(message_id)",
+	} {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	ctx := context.Background()
+	messageIDs, err := collectMessageIDs(ctx, tx, "SELECT id FROM messages ORDER BY timestamp, id")
+	if err != nil {
+		return err
+	}
+	for _, messageID := range messageIDs {
+		if err := chainMessage(ctx, tx, messageID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateScheduledMessages adds the queue of the messages scheduled to be
+// sent later (see scheduled.go)
+func migrateScheduledMessages(tx *sql.Tx) error {
+	for _, query := range []string{
+		`CREATE TABLE IF NOT EXISTS scheduled_messages (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			conversation_id TEXT NOT NULL,
+			sender_id TEXT NOT NULL,
+			content TEXT NOT NULL,
+			reply_to TEXT,
+			send_at DATETIME NOT NULL,
+			created_at DATETIME NOT NULL,
+			FOREIGN KEY (conversation_id) REFERENCES conversations(id),
+			FOREIGN KEY (sender_id) REFERENCES users(id)
+		)`,
+		"CREATE INDEX IF NOT EXISTS idx_scheduled_messages_send_at ON scheduled_messages(send_at)",
+		"CREATE INDEX IF NOT EXISTS idx_scheduled_messages_sender ON scheduled_messages(sender_id, send_at)",
+	} {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+migrateMultipleReactions lets a user react to a message with several
+distinct emoticons: the key of comments gains the emoticon, which needs
+the table to be rebuilt. The reactions already there keep no time.
+*/
+func migrateMultipleReactions(tx *sql.Tx) error {
+	for _, query := range []string{
+		`CREATE TABLE comments_new (
+			message_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			emoticon TEXT NOT NULL,
+			created_at DATETIME,
+			PRIMARY KEY (message_id, user_id, emoticon),
+			FOREIGN KEY (message_id) REFERENCES messages(id),
+			FOREIGN KEY (user_id) REFERENCES users(id)
+		)`,
+		`INSERT INTO comments_new (message_id, user_id, emoticon)
+			SELECT message_id, user_id, emoticon FROM comments`,
+		"DROP TABLE comments",
+		"ALTER TABLE comments_new RENAME TO comments",
+	} {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateMentions records the users each message mentions (see mentions.go)
+func migrateMentions(tx *sql.Tx) error {
+	for _, query := range []string{
+		`CREATE TABLE IF NOT EXISTS mentions (
+			message_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			PRIMARY KEY (message_id, user_id),
+			FOREIGN KEY (message_id) REFERENCES messages(id),
+			FOREIGN KEY (user_id) REFERENCES users(id)
+		)`,
+		"CREATE INDEX IF NOT EXISTS idx_mentions_user ON mentions(user_id)",
+	} {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateAccountDeactivation lets users deactivate their account until
+// they log in again (see DeactivateUser)
+func migrateAccountDeactivation(tx *sql.Tx) error {
+	_, err := tx.Exec("ALTER TABLE users ADD COLUMN deactivated_at DATETIME")
+	return err
+}
+
+// migrateGroupGovernance lets the members of a group vote on its changes
+// (see governance.go)
+func migrateGroupGovernance(tx *sql.Tx) error {
+	for _, query := range []string{
+		"ALTER TABLE groups ADD COLUMN governance INTEGER NOT NULL DEFAULT 0",
+		`CREATE TABLE IF NOT EXISTS group_proposals (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			group_id TEXT NOT NULL,
+			kind TEXT NOT NULL,
+			target_user_id TEXT,
+			new_name TEXT,
+			proposed_by TEXT NOT NULL,
+			created_at DATETIME NOT NULL,
+			status TEXT NOT NULL,
+			resolved_at DATETIME,
+			FOREIGN KEY (group_id) REFERENCES groups(id),
+			FOREIGN KEY (target_user_id) REFERENCES users(id),
+			FOREIGN KEY (proposed_by) REFERENCES users(id)
+		)`,
+		"CREATE INDEX IF NOT EXISTS idx_group_proposals_group ON group_proposals(group_id, status)",
+		`CREATE TABLE IF NOT EXISTS group_votes (
+			proposal_id INTEGER NOT NULL,
+			user_id TEXT NOT NULL,
+			approve INTEGER NOT NULL,
+			created_at DATETIME NOT NULL,
+			PRIMARY KEY (proposal_id, user_id),
+			FOREIGN KEY (proposal_id) REFERENCES group_proposals(id),
+			FOREIGN KEY (user_id) REFERENCES users(id)
+		)`,
+	} {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migratePinnedMessages lets conversations pin messages (see pins.go)
+func migratePinnedMessages(tx *sql.Tx) error {
+	for _, query := range []string{
+		`CREATE TABLE IF NOT EXISTS message_pins (
+			message_id TEXT PRIMARY KEY,
+			conversation_id TEXT NOT NULL,
+			pinned_by TEXT NOT NULL,
+			pinned_at DATETIME NOT NULL,
+			FOREIGN KEY (message_id) REFERENCES messages(id),
+			FOREIGN KEY (conversation_id) REFERENCES conversations(id),
+			FOREIGN KEY (pinned_by) REFERENCES users(id)
+		)`,
+		"CREATE INDEX IF NOT EXISTS idx_message_pins_conversation ON message_pins(conversation_id)",
+	} {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateKeyBackups stores the encrypted key backups of the users (see
+// keybackup.go)
+func migrateKeyBackups(tx *sql.Tx) error {
+	_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS key_backups (
+		user_id TEXT PRIMARY KEY,
+		data BLOB NOT NULL,
+		version INTEGER NOT NULL,
+		updated_at DATETIME NOT NULL,
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	)`)
+	return err
+}
+
+// migrateGroupDescriptions lets groups have a description
+func migrateGroupDescriptions(tx *sql.Tx) error {
+	_, err := tx.Exec("ALTER TABLE groups ADD COLUMN description TEXT NOT NULL DEFAULT ''")
+	return err
+}
+
+// migrateMessageTimeIndex indexes the messages by time, so that the
+// status page counts those of the last hour without a full scan
+func migrateMessageTimeIndex(tx *sql.Tx) error {
+	_, err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_messages_timestamp ON messages(timestamp)")
+	return err
+}
+
+// migrateGroupNotices records what the notices about the members and the
+// name of a group report (see notices.go)
+func migrateGroupNotices(tx *sql.Tx) error {
+	for _, query := range []string{
+		"ALTER TABLE messages ADD COLUMN notice_kind TEXT",
+		"ALTER TABLE messages ADD COLUMN notice_user_id TEXT REFERENCES users(id)",
+		"ALTER TABLE messages ADD COLUMN notice_name TEXT",
+	} {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migratePushSubscriptions stores the push subscriptions of the users and
+// lets participants mute conversations (see push.go)
+func migratePushSubscriptions(tx *sql.Tx) error {
+	for _, query := range []string{
+		`CREATE TABLE IF NOT EXISTS push_subscriptions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id TEXT NOT NULL,
+			endpoint TEXT NOT NULL UNIQUE,
+			p256dh TEXT NOT NULL,
+			auth TEXT NOT NULL,
+			created_at DATETIME NOT NULL,
+			FOREIGN KEY (user_id) REFERENCES users(id)
+		)`,
+		"CREATE INDEX IF NOT EXISTS idx_push_subscriptions_user ON push_subscriptions(user_id)",
+		"ALTER TABLE conversation_participants ADD COLUMN muted INTEGER NOT NULL DEFAULT 0",
+		"ALTER TABLE conversation_participants ADD COLUMN muted_until DATETIME",
+	} {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateLastSeen saves when the users were last seen, and lets them hide
+// it (see presence.go)
+func migrateLastSeen(tx *sql.Tx) error {
+	for _, query := range []string{
+		"ALTER TABLE users ADD COLUMN last_seen DATETIME",
+		"ALTER TABLE users ADD COLUMN hide_presence INTEGER NOT NULL DEFAULT 0",
+	} {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateUserAbout lets users have a status line on their profile
+func migrateUserAbout(tx *sql.Tx) error {
+	_, err := tx.Exec("ALTER TABLE users ADD COLUMN about TEXT NOT NULL DEFAULT ''")
+	return err
+}
+
+// migrateOutboundWebhooks adds the webhooks told of events, and the log of
+// their deliveries (see webhooks.go)
+func migrateOutboundWebhooks(tx *sql.Tx) error {
+	for _, query := range []string{
+		`CREATE TABLE IF NOT EXISTS outbound_webhooks (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			group_id TEXT,
+			url TEXT NOT NULL,
+			secret TEXT NOT NULL,
+			events TEXT NOT NULL,
+			created_by TEXT,
+			created_at DATETIME NOT NULL,
+			FOREIGN KEY (group_id) REFERENCES groups(id)
+		)`,
+		"CREATE INDEX IF NOT EXISTS idx_outbound_webhooks_group ON outbound_webhooks(group_id)",
+		`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			webhook_id INTEGER NOT NULL,
+			event TEXT NOT NULL,
+			payload BLOB NOT NULL,
+			state TEXT NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			next_attempt_at DATETIME,
+			last_status INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT NOT NULL DEFAULT '',
+			created_at DATETIME NOT NULL,
+			finished_at DATETIME,
+			FOREIGN KEY (webhook_id) REFERENCES outbound_webhooks(id)
+		)`,
+		"CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, id)",
+		"CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE state = 'pending'",
+	} {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateBots adds the bot accounts and their tokens, and the webhook of
+// a bot (see bots.go)
+func migrateBots(tx *sql.Tx) error {
+	for _, query := range []string{
+		`CREATE TABLE IF NOT EXISTS bots (
+			user_id TEXT PRIMARY KEY,
+			token_hash TEXT NOT NULL UNIQUE,
+			created_at DATETIME NOT NULL,
+			FOREIGN KEY (user_id) REFERENCES users(id)
+		)`,
+		"ALTER TABLE outbound_webhooks ADD COLUMN bot_id TEXT REFERENCES users(id)",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_outbound_webhooks_bot ON outbound_webhooks(bot_id) WHERE bot_id IS NOT NULL",
+	} {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateDeletedParticipantMutes adds to deleted_conversation_participants
+// the columns migratePushSubscriptions added to conversation_participants,
+// so that soft-deleting a conversation keeps the mutes of its participants
+func migrateDeletedParticipantMutes(tx *sql.Tx) error {
+	for _, query := range []string{
+		"ALTER TABLE deleted_conversation_participants ADD COLUMN muted INTEGER NOT NULL DEFAULT 0",
+		"ALTER TABLE deleted_conversation_participants ADD COLUMN muted_until DATETIME",
+	} {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+/*
+Package mock provides a mock of database.AppDatabase for handler tests.
+
+AppDatabaseMock (appdatabase_moq.go) is generated by moq: set the XxxFunc
+fields for the methods a test needs, then assert on XxxCalls(). The
+builders below create the users and messages a test feeds to the mock,
+starting from sensible defaults (fresh IDs, the current time) so a test
+only spells out what it cares about:
+
+	alice := mock.NewUser("alice").Build()
+	bob := mock.NewUser("bob").Build()
+	hi := mock.NewMessage(alice).Content("hi").Comment(bob, "👍").Build()
+
+	db := mock.New().WithUsers(alice, bob).WithMessages(hi)
+	db.DeleteMessageFunc = func(context.Context, ids.MessageID, ids.UserID) (bool, error) { return false, nil }
+*/
+package mock
+
+import (
+	"context"
+	"time"
+
+	"wasatext/service/database"
+	"wasatext/service/ids"
+)
+
+// New returns an empty mock: every method panics until it is set
+func New() *AppDatabaseMock {
+	return &AppDatabaseMock{}
+}
+
+/*
+WithUsers makes GetUserByID and GetUserByName answer from the given
+users. Unknown users give database.ErrUserNotFound, like the real
+database.
+*/
+func (mock *AppDatabaseMock) WithUsers(users ...database.User) *AppDatabaseMock {
+	mock.GetUserByIDFunc = func(_ context.Context, id ids.UserID) (*database.User, error) {
+		for _, u := range users {
+			if u.ID == id {
+				return &u, nil
+			}
+		}
+		return nil, database.ErrUserNotFound
+	}
+	mock.GetUserByNameFunc = func(_ context.Context, workspaceID, name string) (*database.User, error) {
+		for _, u := range users {
+			if u.WorkspaceID == workspaceID && u.Name == name {
+				return &u, nil
+			}
+		}
+		return nil, database.ErrUserNotFound
+	}
+	return mock
+}
+
+/*
+WithMessages makes GetMessage answer from the given messages. Unknown
+messages give database.ErrMessageNotFound, like the real database.
+*/
+func (mock *AppDatabaseMock) WithMessages(messages ...database.Message) *AppDatabaseMock {
+	mock.GetMessageFunc = func(_ context.Context, id ids.MessageID) (*database.Message, error) {
+		for _, m := range messages {
+			if m.ID == id {
+				return &m, nil
+			}
+		}
+		return nil, database.ErrMessageNotFound
+	}
+	return mock
+}
+
+// WithPhotos makes GetPhoto answer from the given bytes, by media ID.
+// Unknown IDs give database.ErrPhotoNotFound.
+func (mock *AppDatabaseMock) WithPhotos(photos map[string][]byte) *AppDatabaseMock {
+	mock.GetPhotoFunc = func(_ context.Context, photoID string) ([]byte, error) {
+		if photo, ok := photos[photoID]; ok {
+			return photo, nil
+		}
+		return nil, database.ErrPhotoNotFound
+	}
+	return mock
+}
+
+// UserBuilder builds a database.User
+type UserBuilder struct {
+	user database.User
+}
+
+// NewUser starts a user with a fresh ID in the default workspace
+func NewUser(name string) *UserBuilder {
+	return &UserBuilder{user: database.User{
+		ID:          must(ids.NewUserID()),
+		WorkspaceID: database.DefaultWorkspaceID,
+		Name:        name,
+		CreatedAt:   time.Now(),
+	}}
+}
+
+// ID sets the user ID
+func (b *UserBuilder) ID(id ids.UserID) *UserBuilder {
+	b.user.ID = id
+	return b
+}
+
+// Workspace sets the workspace of the user
+func (b *UserBuilder) Workspace(workspaceID string) *UserBuilder {
+	b.user.WorkspaceID = workspaceID
+	return b
+}
+
+// Photo sets the media ID of the profile photo (see WithPhotos)
+func (b *UserBuilder) Photo(photoID string) *UserBuilder {
+	b.user.PhotoID = photoID
+	return b
+}
+
+// CreatedAt sets when the account was created (to test new-account limits)
+func (b *UserBuilder) CreatedAt(t time.Time) *UserBuilder {
+	b.user.CreatedAt = t
+	return b
+}
+
+// Build returns the user
+func (b *UserBuilder) Build() database.User {
+	return b.user
+}
+
+// MessageBuilder builds a database.Message
+type MessageBuilder struct {
+	message database.Message
+}
+
+// NewMessage starts a message from the sender, sent now
+func NewMessage(sender database.User) *MessageBuilder {
+	return &MessageBuilder{message: database.Message{
+		ID:         must(ids.NewMessageID()),
+		SenderID:   sender.ID,
+		SenderName: sender.Name,
+		Timestamp:  time.Now(),
+		Status:     "sent",
+	}}
+}
+
+// ID sets the message ID
+func (b *MessageBuilder) ID(id ids.MessageID) *MessageBuilder {
+	b.message.ID = id
+	return b
+}
+
+// Content sets the text of the message
+func (b *MessageBuilder) Content(content string) *MessageBuilder {
+	b.message.Content = content
+	return b
+}
+
+// Photo sets the media ID of the photo of the message (see WithPhotos)
+func (b *MessageBuilder) Photo(photoID string) *MessageBuilder {
+	b.message.PhotoID = photoID
+	return b
+}
+
+// At sets when the message was sent
+func (b *MessageBuilder) At(t time.Time) *MessageBuilder {
+	b.message.Timestamp = t
+	return b
+}
+
+// Status sets the delivery status ("sent", "received" or "read")
+func (b *MessageBuilder) Status(status string) *MessageBuilder {
+	b.message.Status = status
+	return b
+}
+
+// ReplyTo makes the message a reply to another one
+func (b *MessageBuilder) ReplyTo(id ids.MessageID) *MessageBuilder {
+	b.message.ReplyTo = &id
+	return b
+}
+
+// Comment adds a reaction from a user
+func (b *MessageBuilder) Comment(user database.User, emoticon string) *MessageBuilder {
+	b.message.Comments = append(b.message.Comments, database.Comment{
+		UserID:   user.ID,
+		UserName: user.Name,
+		Emoticon: emoticon,
+	})
+	return b
+}
+
+// Build returns the message
+func (b *MessageBuilder) Build() database.Message {
+	return b.message
+}
+
+// must unwraps a freshly generated ID; generating one only fails when
+// the system has no randomness left, and then no test can go on
+func must[T any](id T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
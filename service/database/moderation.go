@@ -0,0 +1,330 @@
+/*
+Database operations for the moderation queue.
+
+User reports, spam detections, word filter hits and honeypot contacts
+all end up as items in one queue. An admin resolves an item with an
+action; the action, the resolution and the audit entry are written in
+a single transaction.
+*/
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"wasatext/service/ids"
+)
+
+// Moderation item sources
+const (
+	ModerationSourceReport   = "report"
+	ModerationSourceSpam     = "spam"
+	ModerationSourceFilter   = "filter"
+	ModerationSourceHoneypot = "honeypot"
+)
+
+// Moderation actions
+const (
+	ModerationActionDismiss       = "dismiss"
+	ModerationActionDeleteMessage = "delete_message"
+	ModerationActionWarn          = "warn"
+	ModerationActionBan           = "ban"
+)
+
+// ThrottleReasonBanned is the reason of the endless throttle placed on banned users
+const ThrottleReasonBanned = "banned"
+
+// bannedUntil is the throttle end used for banned users
+var bannedUntil = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// ModerationItem is one entry of the admin moderation queue
+type ModerationItem struct {
+	ID             int64
+	Source         string
+	UserID         ids.UserID // the user being flagged
+	UserName       string
+	MessageID      *ids.MessageID
+	MessageContent string
+	ReporterID     *ids.UserID
+	Reason         string
+	CreatedAt      time.Time
+	ResolvedAt     *time.Time
+	Resolution     string
+}
+
+// ModerationAuditEntry records one admin action
+type ModerationAuditEntry struct {
+	ID        int64
+	ItemID    int64
+	Action    string
+	UserID    ids.UserID
+	MessageID *ids.MessageID
+	Note      string
+	CreatedAt time.Time
+}
+
+// Warning is a moderation warning given to a user
+type Warning struct {
+	Reason    string
+	CreatedAt time.Time
+}
+
+// CreateModerationItem adds an item to the moderation queue
+func (db *appdbimpl) CreateModerationItem(ctx context.Context, source string, userID ids.UserID, messageID *ids.MessageID, reporterID *ids.UserID, reason string) (int64, error) {
+	return insertModerationItem(ctx, db.db, source, userID, messageID, reporterID, reason)
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// insertModerationItem adds an item to the queue using a connection or transaction
+func insertModerationItem(ctx context.Context, ex execer, source string, userID ids.UserID, messageID *ids.MessageID, reporterID *ids.UserID, reason string) (int64, error) {
+	result, err := ex.ExecContext(ctx, `
+		INSERT INTO moderation_items (source, user_id, message_id, reporter_id, reason, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, source, userID, messageID, reporterID, reason, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// GetModerationQueue returns the moderation items, oldest first.
+// Resolved items are only included when asked for.
+func (db *appdbimpl) GetModerationQueue(ctx context.Context, includeResolved bool) ([]ModerationItem, error) {
+	rows, err := db.db.QueryContext(ctx, `
+		SELECT mi.id, mi.source, mi.user_id, u.name, mi.message_id, m.content,
+			mi.reporter_id, mi.reason, mi.created_at, mi.resolved_at, mi.resolution
+		FROM moderation_items mi
+		JOIN users u ON mi.user_id = u.id
+		LEFT JOIN messages m ON mi.message_id = m.id
+		WHERE ? OR mi.resolved_at IS NULL
+		ORDER BY mi.created_at ASC
+	`, includeResolved)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []ModerationItem
+	for rows.Next() {
+		var item ModerationItem
+		var messageID, content, reporterID, resolution sql.NullString
+		var resolvedAt sql.NullTime
+
+		if err := rows.Scan(
+			&item.ID,
+			&item.Source,
+			&item.UserID,
+			&item.UserName,
+			&messageID,
+			&content,
+			&reporterID,
+			&item.Reason,
+			&item.CreatedAt,
+			&resolvedAt,
+			&resolution,
+		); err != nil {
+			return nil, err
+		}
+
+		if messageID.Valid {
+			id := ids.MessageID(messageID.String)
+			item.MessageID = &id
+		}
+		if content.Valid {
+			item.MessageContent = content.String
+		}
+		if reporterID.Valid {
+			id := ids.UserID(reporterID.String)
+			item.ReporterID = &id
+		}
+		if resolvedAt.Valid {
+			item.ResolvedAt = &resolvedAt.Time
+		}
+		if resolution.Valid {
+			item.Resolution = resolution.String
+		}
+
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+/*
+ResolveModerationItem applies an admin action to a queue item.
+
+The action itself, marking the item as resolved and writing the audit
+entry all happen in one transaction, so the queue never says an item
+was handled when the action did not go through.
+*/
+func (db *appdbimpl) ResolveModerationItem(ctx context.Context, itemID int64, action, note string) error {
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+	}()
+
+	var userID ids.UserID
+	var messageID sql.NullString
+	var resolvedAt sql.NullTime
+	err = tx.QueryRowContext(ctx,
+		"SELECT user_id, message_id, resolved_at FROM moderation_items WHERE id = ?",
+		itemID,
+	).Scan(&userID, &messageID, &resolvedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return withID(ErrModerationItemNotFound, strconv.FormatInt(itemID, 10))
+	}
+	if err != nil {
+		return err
+	}
+	if resolvedAt.Valid {
+		return withID(ErrModerationItemResolved, strconv.FormatInt(itemID, 10))
+	}
+
+	now := time.Now()
+	var released []string
+	switch action {
+	case ModerationActionDismiss:
+		// Nothing to do besides resolving the item
+
+	case ModerationActionDeleteMessage:
+		if !messageID.Valid {
+			return withID(ErrNoMessageToModerate, strconv.FormatInt(itemID, 10))
+		}
+		released, err = collectPhotoIDs(ctx, tx, "SELECT photo_id FROM messages WHERE id = ?", messageID.String)
+		if err != nil {
+			return err
+		}
+		for _, query := range []string{
+			"DELETE FROM comments WHERE message_id = ?",
+			"DELETE FROM mentions WHERE message_id = ?",
+			"DELETE FROM message_pins WHERE message_id = ?",
+			"DELETE FROM message_receipts WHERE message_id = ?",
+			"DELETE FROM messages WHERE id = ?",
+		} {
+			if _, err := tx.ExecContext(ctx, query, messageID.String); err != nil {
+				return err
+			}
+		}
+		if err := chainMessage(ctx, tx, ids.MessageID(messageID.String)); err != nil {
+			return err
+		}
+
+	case ModerationActionWarn:
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO user_warnings (user_id, reason, created_at) VALUES (?, ?, ?)",
+			userID, note, now,
+		)
+		if err != nil {
+			return err
+		}
+
+	case ModerationActionBan:
+		if _, err := tx.ExecContext(ctx, "UPDATE users SET banned_at = ? WHERE id = ?", now, userID); err != nil {
+			return err
+		}
+		// A throttle that never ends blocks all sending
+		_, err = tx.ExecContext(ctx,
+			"INSERT OR REPLACE INTO user_throttles (user_id, until, reason) VALUES (?, ?, ?)",
+			userID, bannedUntil, ThrottleReasonBanned,
+		)
+		if err != nil {
+			return err
+		}
+
+	default:
+		return withID(ErrInvalidModerationAction, action)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE moderation_items SET resolved_at = ?, resolution = ? WHERE id = ?",
+		now, action, itemID,
+	)
+	if err != nil {
+		return err
+	}
+
+	var auditMessageID interface{}
+	if messageID.Valid {
+		auditMessageID = messageID.String
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO moderation_audit (item_id, action, user_id, message_id, note, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, itemID, action, userID, auditMessageID, note, now)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	db.releasePhotos(ctx, released...)
+	return nil
+}
+
+// GetModerationAudit returns the audit log, most recent first
+func (db *appdbimpl) GetModerationAudit(ctx context.Context) ([]ModerationAuditEntry, error) {
+	rows, err := db.db.QueryContext(ctx, `
+		SELECT id, item_id, action, user_id, message_id, note, created_at
+		FROM moderation_audit
+		ORDER BY created_at DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []ModerationAuditEntry
+	for rows.Next() {
+		var e ModerationAuditEntry
+		var messageID sql.NullString
+
+		if err := rows.Scan(&e.ID, &e.ItemID, &e.Action, &e.UserID, &messageID, &e.Note, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		if messageID.Valid {
+			id := ids.MessageID(messageID.String)
+			e.MessageID = &id
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// GetUserWarnings returns the warnings a user received, most recent first
+func (db *appdbimpl) GetUserWarnings(ctx context.Context, userID ids.UserID) ([]Warning, error) {
+	rows, err := db.db.QueryContext(ctx,
+		"SELECT reason, created_at FROM user_warnings WHERE user_id = ? ORDER BY created_at DESC",
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var warnings []Warning
+	for rows.Next() {
+		var w Warning
+		if err := rows.Scan(&w.Reason, &w.CreatedAt); err != nil {
+			return nil, err
+		}
+		warnings = append(warnings, w)
+	}
+
+	return warnings, rows.Err()
+}
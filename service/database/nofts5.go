@@ -0,0 +1,9 @@
+//go:build !sqlite_fts5 && !libsqlite3
+
+package database
+
+// The SQLite driver compiles FTS5 in only with the sqlite_fts5 tag, and
+// without it the server would not start (see checkFTS5): the build stops
+// here instead, with the message below. A system SQLite (libsqlite3) is
+// checked when the database opens.
+const _ = "the message search needs SQLite's FTS5: build with -tags sqlite_fts5" / 0
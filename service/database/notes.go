@@ -0,0 +1,123 @@
+/*
+Database operations for message notes.
+
+A user can attach a private note to any message they can read, one note
+per message. Only its author ever sees a note. Notes go away with their
+message (a trigger on messages, see migrateMessageNotes) and with their
+author's account; the notes on messages the user can no longer read
+(after leaving a group, or clearing the conversation) are kept but not
+listed.
+*/
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"wasatext/service/ids"
+)
+
+// MessageNote is a private note of a user on a message
+type MessageNote struct {
+	Message   Message // without status, reply and comments; Content is cut to replyPreviewLength
+	Note      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// noteSQL selects the notes of a user (the first parameter, the second
+// being replyPreviewLength) on the messages they can read
+const noteSQL = `
+	SELECT m.id, m.conversation_id, m.sender_id, COALESCE(m.hook_name, u.name), substr(COALESCE(m.content, ''), 1, ?),
+		m.photo_id, m.timestamp, n.note, n.created_at, n.updated_at
+	FROM message_notes n
+	JOIN messages m ON m.id = n.message_id` + visibleMessages + `
+	JOIN users u ON u.id = m.sender_id
+	WHERE n.user_id = cp.user_id`
+
+// rowScanner is a *sql.Row or *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanNote scans a row of noteSQL
+func scanNote(row rowScanner) (*MessageNote, error) {
+	var n MessageNote
+	var photo sql.NullString
+	err := row.Scan(
+		&n.Message.ID, &n.Message.ConversationID, &n.Message.SenderID, &n.Message.SenderName, &n.Message.Content,
+		&photo, &n.Message.Timestamp, &n.Note, &n.CreatedAt, &n.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	n.Message.PhotoID = photo.String
+	return &n, nil
+}
+
+// SetMessageNote attaches a note to a message the user can read, or
+// replaces the note already there
+func (db *appdbimpl) SetMessageNote(ctx context.Context, userID ids.UserID, messageID ids.MessageID, note string) (*MessageNote, error) {
+	var visible bool
+	err := db.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM messages m`+visibleMessages+` WHERE m.id = ?)
+	`, userID, messageID).Scan(&visible)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return nil, withID(ErrMessageNotFound, messageID)
+	}
+
+	now := time.Now()
+	_, err = db.db.ExecContext(ctx, `
+		INSERT INTO message_notes (user_id, message_id, note, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (user_id, message_id) DO UPDATE SET note = excluded.note, updated_at = excluded.updated_at
+	`, userID, messageID, note, now, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanNote(db.db.QueryRowContext(ctx, noteSQL+" AND n.message_id = ?", replyPreviewLength, userID, messageID))
+}
+
+// DeleteMessageNote removes the note of a user on a message
+func (db *appdbimpl) DeleteMessageNote(ctx context.Context, userID ids.UserID, messageID ids.MessageID) error {
+	result, err := db.db.ExecContext(ctx,
+		"DELETE FROM message_notes WHERE user_id = ? AND message_id = ?",
+		userID, messageID,
+	)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return withID(ErrNoteNotFound, messageID)
+	}
+	return nil
+}
+
+// GetMessageNotes returns the notes of a user on the messages they can
+// read, the last written first
+func (db *appdbimpl) GetMessageNotes(ctx context.Context, userID ids.UserID) ([]MessageNote, error) {
+	rows, err := db.db.QueryContext(ctx, noteSQL+" ORDER BY n.updated_at DESC, m.id", replyPreviewLength, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notes := []MessageNote{}
+	for rows.Next() {
+		n, err := scanNote(rows)
+		if err != nil {
+			return nil, err
+		}
+		notes = append(notes, *n)
+	}
+	return notes, rows.Err()
+}
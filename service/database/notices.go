@@ -0,0 +1,111 @@
+/*
+Database operations for group notices.
+
+A notice is a system message in the conversation of a group, sent on
+behalf of the member who made the change it reports: "alice added bob",
+"bob left", "the group became a channel". Its text is written for the
+humans reading the history. Notices about the members and the name of
+the group also record what happened (Notice), so that a client can
+render them its own way, e.g. translated, with the current names of the
+users and links to their profiles.
+*/
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"wasatext/service/ids"
+)
+
+// Kinds of group notice
+const (
+	NoticeMemberAdded   = "member_added"   // the sender added UserID
+	NoticeMemberJoined  = "member_joined"  // the sender joined a channel
+	NoticeMemberLeft    = "member_left"    // the sender left
+	NoticeMemberRemoved = "member_removed" // UserID was removed by a vote
+	NoticeGroupRenamed  = "group_renamed"  // the group was renamed to Name
+)
+
+// Notice is what a notice reports
+type Notice struct {
+	Kind     string
+	UserID   ids.UserID // the member added or removed, "" for the others
+	UserName string     // their current name
+	Name     string     // the new name of the group (NoticeGroupRenamed)
+}
+
+// noticeColumns reads the notice of a message m (kind, user ID, user
+// name and name), with the current name of its user from noticeUsers
+const (
+	noticeColumns = "COALESCE(m.notice_kind, ''), COALESCE(m.notice_user_id, ''), COALESCE(nu.name, ''), COALESCE(m.notice_name, '')"
+	noticeUsers   = " LEFT JOIN users nu ON nu.id = m.notice_user_id"
+)
+
+// orNil returns the notice read with noticeColumns, nil for a message
+// that is not one
+func (n Notice) orNil() *Notice {
+	if n.Kind == "" {
+		return nil
+	}
+	return &n
+}
+
+/*
+PostGroupNotice sends a system message to the conversation of a group
+on behalf of a member, like the one telling the members a group became
+a channel. What it reports is recorded with it, if given.
+*/
+func (db *appdbimpl) PostGroupNotice(ctx context.Context, groupID ids.GroupID, senderID ids.UserID, text string, notice *Notice) (*Message, error) {
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+	}()
+
+	var conversationID ids.ConversationID
+	err = tx.QueryRowContext(ctx, "SELECT id FROM conversations WHERE group_id = ? AND is_group = 1", groupID).Scan(&conversationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, withID(ErrGroupNotFound, groupID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var kind, userID, name sql.NullString
+	if notice != nil {
+		kind = sql.NullString{String: notice.Kind, Valid: true}
+		userID = sql.NullString{String: string(notice.UserID), Valid: notice.UserID != ""}
+		name = sql.NullString{String: notice.Name, Valid: notice.Name != ""}
+	}
+
+	id, err := newMessageID(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO messages (id, conversation_id, sender_id, content, timestamp, system, notice_kind, notice_user_id, notice_name)
+		VALUES (?, ?, ?, ?, ?, 1, ?, ?, ?)
+	`, id, conversationID, senderID, text, time.Now(), kind, userID, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := chainMessage(ctx, tx, id); err != nil {
+		return nil, err
+	}
+	if _, err := insertReceipts(ctx, tx, id, conversationID, senderID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return db.GetMessage(ctx, id)
+}
@@ -0,0 +1,116 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"wasatext/service/ids"
+)
+
+// TestConversationPermissions checks the matrix against the rules the
+// operations enforce, for each kind of conversation and role
+func TestConversationPermissions(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	alice := newTestUser(t, db, "alice")
+	bob := newTestUser(t, db, "bob")
+
+	direct, err := db.GetOrCreateDirectConversation(ctx, alice, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	group, err := db.CreateGroup(ctx, "group", alice, []ids.UserID{bob})
+	if err != nil {
+		t.Fatal(err)
+	}
+	channel, err := db.CreateGroup(ctx, "channel", alice, []ids.UserID{bob})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.SetGroupKind(ctx, channel.ID, GroupKindChannel, alice, "channel"); err != nil {
+		t.Fatal(err)
+	}
+
+	member := ConversationPermissions{Send: true, Rename: true, SetPhoto: true, AddMembers: true, Leave: true}
+	admin := member
+	admin.ManageGroup = true
+	tests := []struct {
+		name         string
+		userID       ids.UserID
+		conversation ids.ConversationID
+		want         ConversationPermissions
+	}{
+		{"direct", alice, direct, ConversationPermissions{Send: true}},
+		{"group admin", alice, groupConversationID(t, db, group.ID), admin},
+		{"group member", bob, groupConversationID(t, db, group.ID), member},
+		{"channel admin", alice, groupConversationID(t, db, channel.ID), admin},
+		{"channel subscriber", bob, groupConversationID(t, db, channel.ID), ConversationPermissions{Leave: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := db.GetConversationPermissions(ctx, tt.userID, tt.conversation)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got != tt.want {
+				t.Errorf("got %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlockedUserCannotSend(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	alice := newTestUser(t, db, "alice")
+	bob := newTestUser(t, db, "bob")
+	direct, err := db.GetOrCreateDirectConversation(ctx, alice, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.BlockUser(ctx, alice, bob); err != nil {
+		t.Fatal(err)
+	}
+
+	// The blocked user can no longer send, the one who blocked still can
+	for _, tt := range []struct {
+		userID ids.UserID
+		send   bool
+	}{{bob, false}, {alice, true}} {
+		got, err := db.GetConversationPermissions(ctx, tt.userID, direct)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Send != tt.send {
+			t.Errorf("user %s: send = %v, want %v", tt.userID, got.Send, tt.send)
+		}
+	}
+}
+
+func TestGovernedGroupPermissions(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	alice := newTestUser(t, db, "alice")
+	bob := newTestUser(t, db, "bob")
+	group, err := db.CreateGroup(ctx, "group", alice, []ids.UserID{bob})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetGroupGovernance(ctx, group.ID, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// The name goes to the vote and the photo is frozen, for the admin too
+	for _, userID := range []ids.UserID{alice, bob} {
+		got, err := db.GetConversationPermissions(ctx, userID, groupConversationID(t, db, group.ID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Rename || got.SetPhoto {
+			t.Errorf("user %s: rename = %v, setPhoto = %v, want both false", userID, got.Rename, got.SetPhoto)
+		}
+		if !got.Send || !got.AddMembers {
+			t.Errorf("user %s: got %+v, want send and addMembers", userID, *got)
+		}
+	}
+}
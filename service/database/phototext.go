@@ -0,0 +1,40 @@
+/*
+Database operations for the text of the photos.
+
+When OCR is configured (see service/ocr), the text read from each
+message photo is kept in photo_text and indexed in photo_text_search,
+so SearchMessages also finds photos by what they show. A photo is read
+once it is processed (see processing.go) and only if it is ready: one
+that cannot be decoded is never read. A photo read without finding any
+text still gets its row, so it is not read again. The text goes with
+the photo when it is released.
+*/
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// PendingPhotoText returns up to limit message photos whose text was
+// not read yet, oldest first
+func (db *appdbimpl) PendingPhotoText(ctx context.Context, limit int) ([]string, error) {
+	return collectPhotoIDs(ctx, db.db, `
+		SELECT md.id FROM media md
+		WHERE md.processing_state = ?
+		AND EXISTS (SELECT 1 FROM messages WHERE photo_id = md.id)
+		AND NOT EXISTS (SELECT 1 FROM photo_text WHERE media_id = md.id)
+		ORDER BY md.created_at
+		LIMIT ?
+	`, MediaReady, limit)
+}
+
+// SetPhotoText records the text read from a photo ("" for none). A
+// photo whose text was recorded already, or that is gone, is left alone.
+func (db *appdbimpl) SetPhotoText(ctx context.Context, photoID, text string) error {
+	_, err := db.db.ExecContext(ctx, `
+		INSERT OR IGNORE INTO photo_text (media_id, text, created_at)
+		SELECT ?, ?, ? WHERE EXISTS (SELECT 1 FROM media WHERE id = ?)
+	`, photoID, text, time.Now(), photoID)
+	return err
+}
@@ -0,0 +1,183 @@
+/*
+Database operations for pinned messages.
+
+A conversation keeps a few messages pinned for all its participants,
+e.g. the rules of a group. In a group or a channel only the group admin
+pins and unpins them; in a direct conversation either participant does.
+A message deleted for everyone loses its pin; one the viewer cleared or
+deleted for themselves is left out of their pins.
+*/
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"wasatext/service/ids"
+)
+
+// MaxPinnedMessages is how many messages a conversation can have pinned
+const MaxPinnedMessages = 50
+
+// Pin is a message pinned in a conversation
+type Pin struct {
+	Message      Message
+	PinnedBy     ids.UserID
+	PinnedByName string
+	PinnedAt     time.Time
+}
+
+// checkCanPin checks that the user may pin messages in the conversation:
+// its group admin, or a participant of a direct conversation
+func (db *appdbimpl) checkCanPin(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID) error {
+	if err := db.checkParticipant(ctx, userID, conversationID); err != nil {
+		return err
+	}
+
+	var isGroup bool
+	var adminID sql.NullString
+	err := db.db.QueryRowContext(ctx,
+		"SELECT is_group, created_by FROM conversations WHERE id = ?",
+		conversationID,
+	).Scan(&isGroup, &adminID)
+	if err != nil {
+		return err
+	}
+	if isGroup && adminID.String != string(userID) {
+		return withID(ErrNotPinner, conversationID)
+	}
+	return nil
+}
+
+/*
+PinMessage pins a message of a conversation the user may pin in (see
+checkCanPin). Pinning a pinned message again changes nothing; past
+MaxPinnedMessages a message has to be unpinned first (409). It reports
+whether the message was not pinned yet.
+*/
+func (db *appdbimpl) PinMessage(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID, messageID ids.MessageID) (bool, error) {
+	if err := db.checkCanPin(ctx, userID, conversationID); err != nil {
+		return false, err
+	}
+
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+	}()
+
+	// Only the messages the user still sees can be pinned
+	var visible bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM messages m`+visibleMessages+`
+			WHERE m.id = ? AND m.conversation_id = ? AND m.deleted_at IS NULL)
+	`, userID, messageID, conversationID).Scan(&visible)
+	if err != nil {
+		return false, err
+	}
+	if !visible {
+		return false, withID(ErrMessageNotFound, messageID)
+	}
+
+	var already bool
+	var pinned int
+	err = tx.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM message_pins WHERE message_id = ?), COUNT(*)
+		FROM message_pins WHERE conversation_id = ?
+	`, messageID, conversationID).Scan(&already, &pinned)
+	if err != nil {
+		return false, err
+	}
+	if already {
+		return false, nil
+	}
+	if pinned >= MaxPinnedMessages {
+		return false, withID(ErrTooManyPins, conversationID)
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO message_pins (message_id, conversation_id, pinned_by, pinned_at) VALUES (?, ?, ?, ?)
+	`, messageID, conversationID, userID, time.Now())
+	if err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UnpinMessage unpins a message; only who may pin in the conversation
+// can (see checkCanPin)
+func (db *appdbimpl) UnpinMessage(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID, messageID ids.MessageID) error {
+	if err := db.checkCanPin(ctx, userID, conversationID); err != nil {
+		return err
+	}
+
+	result, err := db.db.ExecContext(ctx,
+		"DELETE FROM message_pins WHERE message_id = ? AND conversation_id = ?",
+		messageID, conversationID,
+	)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return withID(ErrPinNotFound, messageID)
+	}
+	return nil
+}
+
+// GetPins returns the messages pinned in a conversation that the user
+// sees, the latest pinned first
+func (db *appdbimpl) GetPins(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID) ([]Pin, error) {
+	if err := db.checkParticipant(ctx, userID, conversationID); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.db.QueryContext(ctx, `
+		SELECT p.message_id, p.pinned_by, u.name, p.pinned_at
+		FROM message_pins p
+		JOIN messages m ON m.id = p.message_id`+visibleMessages+`
+		JOIN users u ON u.id = p.pinned_by
+		WHERE p.conversation_id = ?
+		ORDER BY p.pinned_at DESC, p.message_id DESC
+	`, userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pins := []Pin{}
+	for rows.Next() {
+		var pin Pin
+		if err := rows.Scan(&pin.Message.ID, &pin.PinnedBy, &pin.PinnedByName, &pin.PinnedAt); err != nil {
+			return nil, err
+		}
+		pins = append(pins, pin)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	// At most MaxPinnedMessages, each with its replies and reactions
+	for i := range pins {
+		msg, err := db.GetMessage(ctx, pins[i].Message.ID)
+		if err != nil {
+			return nil, err
+		}
+		pins[i].Message = *msg
+	}
+	return pins, nil
+}
@@ -0,0 +1,94 @@
+/*
+Database operations for the last-seen times of the users.
+
+Presence itself is kept in memory by the API, from the heartbeats; the
+API saves the time of the last heartbeat of each user here every minute
+or so, so that lastSeen survives restarts and users not seen for long.
+A user can hide their presence: they then appear offline, and never
+seen, to everyone. A deleted account is never seen either.
+*/
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"wasatext/service/ids"
+)
+
+// UserPrivacy is what a user shares with everyone
+type UserPrivacy struct {
+	HidePresence bool // the others never see them online, nor when they were last seen
+}
+
+// RecordLastSeen saves the time of the last heartbeat of users; a time
+// older than the one saved is ignored, and so are deleted accounts
+func (db *appdbimpl) RecordLastSeen(ctx context.Context, seen map[ids.UserID]time.Time) error {
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+	}()
+
+	// The time is stored in the local time zone, like time.Now(), so that
+	// it compares as text
+	for userID, at := range seen {
+		_, err := tx.ExecContext(ctx,
+			"UPDATE users SET last_seen = ? WHERE id = ? AND purged_at IS NULL AND (last_seen IS NULL OR last_seen < ?)",
+			at.Local(), userID, at.Local(),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetUserPrivacy returns what the user shares with everyone
+func (db *appdbimpl) GetUserPrivacy(ctx context.Context, userID ids.UserID) (*UserPrivacy, error) {
+	var privacy UserPrivacy
+	err := db.db.QueryRowContext(ctx,
+		"SELECT hide_presence FROM users WHERE id = ? AND purged_at IS NULL",
+		userID,
+	).Scan(&privacy.HidePresence)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, withID(ErrUserNotFound, userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &privacy, nil
+}
+
+// SetUserPrivacy changes what the user shares with everyone
+func (db *appdbimpl) SetUserPrivacy(ctx context.Context, userID ids.UserID, privacy UserPrivacy) error {
+	result, err := db.db.ExecContext(ctx,
+		"UPDATE users SET hide_presence = ? WHERE id = ? AND purged_at IS NULL",
+		privacy.HidePresence, userID,
+	)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return withID(ErrUserNotFound, userID)
+	}
+	return nil
+}
+
+// readLastSeen sets the last-seen time of a user read from the database
+func (u *User) readLastSeen(lastSeen sql.NullTime) {
+	if lastSeen.Valid {
+		u.LastSeen = &lastSeen.Time
+	}
+}
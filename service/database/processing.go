@@ -0,0 +1,71 @@
+/*
+Database operations for the media processing.
+
+A new photo is stored at once, and processed afterwards: ProcessMedia
+makes its renditions (see GetPhotoRendition) in the background, so the
+first client asking for a thumbnail does not wait for the scaling. The
+processing state of a photo is pending until then, and ready, or failed
+for a photo that cannot be decoded (it is then served as it is in every
+quality). Photos from before media processing are ready: their
+renditions are made on first request.
+*/
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// Processing states of a photo
+const (
+	MediaPending = "pending"
+	MediaReady   = "ready"
+	MediaFailed  = "failed"
+)
+
+/*
+ProcessMedia makes every rendition of a pending photo and records the
+outcome. A photo that is gone or was processed already is left alone;
+on any other error the photo stays pending for the next sweep.
+*/
+func (db *appdbimpl) ProcessMedia(ctx context.Context, photoID string) error {
+	var state string
+	err := db.db.QueryRowContext(ctx, "SELECT processing_state FROM media WHERE id = ?", photoID).Scan(&state)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && state != MediaPending) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	state = MediaReady
+	for _, quality := range []string{PhotoQualityHigh, PhotoQualityMedium, PhotoQualityThumb} {
+		_, err := db.rendition(ctx, photoID, quality)
+		if errors.Is(err, errUndecodable) {
+			state = MediaFailed
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = db.db.ExecContext(ctx, "UPDATE media SET processing_state = ? WHERE id = ?", state, photoID)
+	return err
+}
+
+// GetMediaState returns the processing state of a photo
+func (db *appdbimpl) GetMediaState(ctx context.Context, photoID string) (string, error) {
+	var state string
+	err := db.db.QueryRowContext(ctx, "SELECT processing_state FROM media WHERE id = ?", photoID).Scan(&state)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", withID(ErrPhotoNotFound, photoID)
+	}
+	return state, err
+}
+
+// PendingMedia returns the photos waiting to be processed, oldest first
+func (db *appdbimpl) PendingMedia(ctx context.Context) ([]string, error) {
+	return collectPhotoIDs(ctx, db.db, "SELECT id FROM media WHERE processing_state = ? ORDER BY created_at", MediaPending)
+}
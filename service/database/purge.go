@@ -0,0 +1,237 @@
+/*
+Database operations for purging deleted accounts.
+
+Deleting an account only marks it (users.purged_at). Once the retention
+window has passed, PurgeDeletedUsers hard-deletes the personal data:
+messages, reactions, receipts, photos and group memberships. The user
+row itself is kept under an anonymous name so that direct conversations
+of the other participant keep working.
+*/
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"wasatext/service/ids"
+)
+
+// PurgeRecord describes one purged account, for the admin report
+type PurgeRecord struct {
+	UserID          ids.UserID
+	DeletedAt       time.Time
+	PurgedAt        time.Time
+	MessagesDeleted int64
+	CommentsDeleted int64
+	MediaDeleted    int64
+}
+
+// PurgeDeletedUsers hard-deletes every account deleted before the given time
+// that has not been purged yet, and returns what was removed.
+func (db *appdbimpl) PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time) ([]PurgeRecord, error) {
+	rows, err := db.db.QueryContext(ctx, `
+		SELECT id, purged_at FROM users
+		WHERE purged_at IS NOT NULL AND purged_at < ?
+		AND id NOT IN (SELECT user_id FROM purge_log)
+	`, deletedBefore)
+	if err != nil {
+		return nil, err
+	}
+
+	type pending struct {
+		id        ids.UserID
+		deletedAt time.Time
+	}
+	var users []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.id, &p.deletedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		users = append(users, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var records []PurgeRecord
+	for _, p := range users {
+		record, err := db.purgeUser(ctx, p.id, p.deletedAt)
+		if err != nil {
+			return records, err
+		}
+		records = append(records, *record)
+	}
+
+	return records, nil
+}
+
+// purgeUser removes one user's personal data in a single transaction
+func (db *appdbimpl) purgeUser(ctx context.Context, userID ids.UserID, deletedAt time.Time) (*PurgeRecord, error) {
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+	}()
+
+	record := PurgeRecord{UserID: userID, DeletedAt: deletedAt, PurgedAt: time.Now()}
+
+	// Count media before it goes away (message photos + profile photo)
+	err = tx.QueryRowContext(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM messages WHERE sender_id = ? AND photo_id IS NOT NULL) +
+			(SELECT COUNT(*) FROM users WHERE id = ? AND photo_id IS NOT NULL)
+	`, userID, userID).Scan(&record.MediaDeleted)
+	if err != nil {
+		return nil, err
+	}
+	released, err := collectPhotoIDs(ctx, tx, `
+		SELECT photo_id FROM messages WHERE sender_id = ?
+		UNION SELECT photo_id FROM users WHERE id = ?
+	`, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Everything attached to the user's messages
+	for _, query := range []string{
+		"DELETE FROM comments WHERE message_id IN (SELECT id FROM messages WHERE sender_id = ?)",
+		"DELETE FROM mentions WHERE message_id IN (SELECT id FROM messages WHERE sender_id = ?)",
+		"DELETE FROM message_pins WHERE message_id IN (SELECT id FROM messages WHERE sender_id = ?)",
+		"DELETE FROM message_receipts WHERE message_id IN (SELECT id FROM messages WHERE sender_id = ?)",
+	} {
+		if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	messageIDs, err := collectMessageIDs(ctx, tx, "SELECT id FROM messages WHERE sender_id = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+	result, err := tx.ExecContext(ctx, "DELETE FROM messages WHERE sender_id = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+	if record.MessagesDeleted, err = result.RowsAffected(); err != nil {
+		return nil, err
+	}
+	// The chains of their conversations record them as deleted
+	for _, messageID := range messageIDs {
+		if err := chainMessage(ctx, tx, messageID); err != nil {
+			return nil, err
+		}
+	}
+
+	result, err = tx.ExecContext(ctx, "DELETE FROM comments WHERE user_id = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+	if record.CommentsDeleted, err = result.RowsAffected(); err != nil {
+		return nil, err
+	}
+
+	// Receipts, mentions, notes, reminders, scheduled messages, messages deleted for the user, votes and the proposals to remove the user, blocks, group memberships (of deleted groups too; direct conversations are kept), usage counters, API usage, webhooks, widget tokens, the key backup and sessions
+	for _, query := range []string{
+		"DELETE FROM message_receipts WHERE user_id = ?",
+		"DELETE FROM mentions WHERE user_id = ?",
+		"DELETE FROM message_notes WHERE user_id = ?",
+		"DELETE FROM message_reminders WHERE user_id = ?",
+		"DELETE FROM scheduled_messages WHERE sender_id = ?",
+		"DELETE FROM message_deletions WHERE user_id = ?",
+		"DELETE FROM event_rsvps WHERE user_id = ?",
+		"DELETE FROM group_votes WHERE user_id = ?",
+		"DELETE FROM group_votes WHERE proposal_id IN (SELECT id FROM group_proposals WHERE target_user_id = ?)",
+		"DELETE FROM group_proposals WHERE target_user_id = ?",
+		"DELETE FROM user_blocks WHERE ? IN (blocker_id, blocked_id)",
+		`DELETE FROM conversation_participants WHERE user_id = ?
+			AND conversation_id IN (SELECT id FROM conversations WHERE is_group = 1)`,
+		"DELETE FROM group_members WHERE user_id = ?",
+		`DELETE FROM deleted_conversation_participants WHERE user_id = ?
+			AND conversation_id IN (SELECT id FROM conversations WHERE is_group = 1)`,
+		"DELETE FROM deleted_group_members WHERE user_id = ?",
+		"DELETE FROM usage_counters WHERE user_id = ?",
+		"DELETE FROM api_usage WHERE user_id = ?",
+		"DELETE FROM hooks WHERE created_by = ?",
+		"DELETE FROM widget_tokens WHERE created_by = ?",
+		"DELETE FROM key_backups WHERE user_id = ?",
+		"DELETE FROM push_subscriptions WHERE user_id = ?",
+		"DELETE FROM sessions WHERE user_id = ?",
+		"DELETE FROM webhook_deliveries WHERE webhook_id IN (SELECT id FROM outbound_webhooks WHERE bot_id = ?)",
+		"DELETE FROM outbound_webhooks WHERE bot_id = ?",
+		"DELETE FROM bots WHERE user_id = ?",
+	} {
+		if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Anonymize the account itself, bio and presence included; this also
+	// frees the username
+	anonymousName := "deleted-" + userID
+	if len(anonymousName) > 16 {
+		anonymousName = anonymousName[:16]
+	}
+	_, err = tx.ExecContext(ctx,
+		"UPDATE users SET name = ?, photo_id = NULL, about = '', last_seen = NULL, hide_presence = 0 WHERE id = ?",
+		anonymousName, userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO purge_log (user_id, deleted_at, purged_at, messages_deleted, comments_deleted, media_deleted)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, record.UserID, record.DeletedAt, record.PurgedAt, record.MessagesDeleted, record.CommentsDeleted, record.MediaDeleted)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	db.releasePhotos(ctx, released...)
+
+	return &record, nil
+}
+
+// GetPurgeLog returns every purged account, most recent first
+func (db *appdbimpl) GetPurgeLog(ctx context.Context) ([]PurgeRecord, error) {
+	rows, err := db.db.QueryContext(ctx, `
+		SELECT user_id, deleted_at, purged_at, messages_deleted, comments_deleted, media_deleted
+		FROM purge_log
+		ORDER BY purged_at DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []PurgeRecord
+	for rows.Next() {
+		var r PurgeRecord
+		if err := rows.Scan(
+			&r.UserID,
+			&r.DeletedAt,
+			&r.PurgedAt,
+			&r.MessagesDeleted,
+			&r.CommentsDeleted,
+			&r.MediaDeleted,
+		); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+
+	return records, rows.Err()
+}
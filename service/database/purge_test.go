@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"wasatext/service/ids"
+)
+
+// TestPurgeClearsProfile checks that the other participant of a direct
+// conversation sees nothing of a purged account but its anonymous name
+func TestPurgeClearsProfile(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	alice := newTestUser(t, db, "alice")
+	bob := newTestUser(t, db, "bob")
+	direct, err := db.GetOrCreateDirectConversation(ctx, alice, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.RecordLastSeen(ctx, map[ids.UserID]time.Time{bob: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetUserPrivacy(ctx, bob, UserPrivacy{HidePresence: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpdateUserAbout(ctx, bob, "Lives in Rome, works at the bakery"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpdateUserPhoto(ctx, bob, []byte("photo")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.DeleteUser(ctx, bob); err != nil {
+		t.Fatal(err)
+	}
+	// A heartbeat still in flight when the account was deleted
+	if err := db.RecordLastSeen(ctx, map[ids.UserID]time.Time{bob: time.Now().Add(time.Second)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.PurgeDeletedUsers(ctx, time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	conv, err := db.GetConversation(ctx, alice, direct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conv.Members) != 1 {
+		t.Fatalf("got %d members, want the other participant", len(conv.Members))
+	}
+	other := conv.Members[0]
+	if other.Name == "bob" || other.PhotoID != "" || other.About != "" {
+		t.Errorf("purged account: name = %q, photo = %q, about = %q, want none of the profile", other.Name, other.PhotoID, other.About)
+	}
+	if other.LastSeen != nil || other.HidePresence {
+		t.Errorf("purged account: lastSeen = %v, hidePresence = %v, want neither", other.LastSeen, other.HidePresence)
+	}
+}
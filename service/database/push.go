@@ -0,0 +1,202 @@
+/*
+Database operations for push subscriptions and muted conversations.
+
+A push subscription is where one browser of a user receives Web Push
+notifications (see service/notifications). A browser has a single
+endpoint, so registering an endpoint again replaces its keys, and moves
+it to the user registering it (someone else logged in on that browser).
+Subscriptions go away when their user deletes them, when the push
+service says they are gone, and with the account when it is purged.
+
+A participant can mute a conversation, for good or until a time: its
+new messages still arrive, but send them no notification.
+*/
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"wasatext/service/ids"
+)
+
+// MaxPushSubscriptions is how many browsers a user can receive
+// notifications on
+const MaxPushSubscriptions = 20
+
+// PushSubscription is a browser receiving the notifications of a user
+type PushSubscription struct {
+	ID        int64
+	UserID    ids.UserID
+	Endpoint  string
+	P256dh    string
+	Auth      string
+	CreatedAt time.Time
+}
+
+// Mute is whether a participant muted a conversation
+type Mute struct {
+	Muted bool
+	Until *time.Time // nil while muted until unmuted
+}
+
+// pushSubscriptionSQL selects the subscriptions; the caller adds the condition
+const pushSubscriptionSQL = `
+	SELECT p.id, p.user_id, p.endpoint, p.p256dh, p.auth, p.created_at
+	FROM push_subscriptions p`
+
+// queryPushSubscriptions runs pushSubscriptionSQL with the rest of the query
+func (db *appdbimpl) queryPushSubscriptions(ctx context.Context, query string, args ...interface{}) ([]PushSubscription, error) {
+	rows, err := db.db.QueryContext(ctx, pushSubscriptionSQL+query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []PushSubscription
+	for rows.Next() {
+		var s PushSubscription
+		if err := rows.Scan(&s.ID, &s.UserID, &s.Endpoint, &s.P256dh, &s.Auth, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		subs = append(subs, s)
+	}
+	return subs, rows.Err()
+}
+
+/*
+AddPushSubscription registers a browser of the user. An endpoint already
+registered keeps its ID and takes the new keys and user. A user has at
+most MaxPushSubscriptions (409 past that).
+*/
+func (db *appdbimpl) AddPushSubscription(ctx context.Context, userID ids.UserID, endpoint, p256dh, auth string) (*PushSubscription, error) {
+	var count int
+	err := db.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM push_subscriptions WHERE user_id = ? AND endpoint != ?",
+		userID, endpoint,
+	).Scan(&count)
+	if err != nil {
+		return nil, err
+	}
+	if count >= MaxPushSubscriptions {
+		return nil, withID(ErrTooManyPushSubscriptions, userID)
+	}
+
+	_, err = db.db.ExecContext(ctx, `
+		INSERT INTO push_subscriptions (user_id, endpoint, p256dh, auth, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (endpoint) DO UPDATE SET
+			user_id = excluded.user_id, p256dh = excluded.p256dh, auth = excluded.auth,
+			created_at = excluded.created_at
+	`, userID, endpoint, p256dh, auth, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	subs, err := db.queryPushSubscriptions(ctx, " WHERE p.endpoint = ?", endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if len(subs) == 0 {
+		return nil, withID(ErrPushSubscriptionNotFound, endpoint)
+	}
+	return &subs[0], nil
+}
+
+// GetPushSubscriptions returns the subscriptions of the user, the oldest first
+func (db *appdbimpl) GetPushSubscriptions(ctx context.Context, userID ids.UserID) ([]PushSubscription, error) {
+	return db.queryPushSubscriptions(ctx, " WHERE p.user_id = ? ORDER BY p.id", userID)
+}
+
+// DeletePushSubscription deletes a subscription of the user
+func (db *appdbimpl) DeletePushSubscription(ctx context.Context, userID ids.UserID, subscriptionID int64) error {
+	result, err := db.db.ExecContext(ctx,
+		"DELETE FROM push_subscriptions WHERE id = ? AND user_id = ?",
+		subscriptionID, userID,
+	)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return withID(ErrPushSubscriptionNotFound, strconv.FormatInt(subscriptionID, 10))
+	}
+	return nil
+}
+
+// DropPushSubscription deletes the subscription of an endpoint the push
+// service no longer knows, whoever it belongs to; an unknown endpoint is
+// not an error
+func (db *appdbimpl) DropPushSubscription(ctx context.Context, endpoint string) error {
+	_, err := db.db.ExecContext(ctx, "DELETE FROM push_subscriptions WHERE endpoint = ?", endpoint)
+	return err
+}
+
+/*
+PushRecipients returns the subscriptions to notify of a new message in a
+conversation: those of the participants other than the sender who have
+not muted it, on active accounts. Whether they are online is for the
+caller to tell.
+*/
+func (db *appdbimpl) PushRecipients(ctx context.Context, conversationID ids.ConversationID, senderID ids.UserID, now time.Time) ([]PushSubscription, error) {
+	return db.queryPushSubscriptions(ctx, `
+		JOIN conversation_participants cp ON cp.user_id = p.user_id AND cp.conversation_id = ?
+		JOIN users u ON u.id = p.user_id AND u.purged_at IS NULL AND u.deactivated_at IS NULL
+		WHERE p.user_id != ?
+		AND (cp.muted = 0 OR (cp.muted_until IS NOT NULL AND cp.muted_until <= ?))
+		ORDER BY p.id
+	`, conversationID, senderID, now)
+}
+
+// GetMute returns whether a participant muted a conversation; a mute that
+// ran out is reported as none
+func (db *appdbimpl) GetMute(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID) (*Mute, error) {
+	if err := db.checkParticipant(ctx, userID, conversationID); err != nil {
+		return nil, err
+	}
+
+	var muted bool
+	var until sql.NullTime
+	err := db.db.QueryRowContext(ctx, `
+		SELECT muted, muted_until FROM conversation_participants
+		WHERE conversation_id = ? AND user_id = ?
+	`, conversationID, userID).Scan(&muted, &until)
+	if err != nil {
+		return nil, err
+	}
+	if !muted || (until.Valid && !until.Time.After(time.Now())) {
+		return &Mute{}, nil
+	}
+	mute := Mute{Muted: true}
+	if until.Valid {
+		mute.Until = &until.Time
+	}
+	return &mute, nil
+}
+
+// SetMute mutes a conversation for a participant, or unmutes it
+func (db *appdbimpl) SetMute(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID, mute Mute) error {
+	if err := db.checkParticipant(ctx, userID, conversationID); err != nil {
+		return err
+	}
+	if mute.Until != nil && !mute.Until.After(time.Now()) {
+		return ErrInvalidMute
+	}
+
+	// The time is stored in the local time zone, like time.Now(), so that
+	// it compares as text
+	var until sql.NullTime
+	if mute.Muted && mute.Until != nil {
+		until = sql.NullTime{Time: mute.Until.Local(), Valid: true}
+	}
+	_, err := db.db.ExecContext(ctx, `
+		UPDATE conversation_participants SET muted = ?, muted_until = ?
+		WHERE conversation_id = ? AND user_id = ?
+	`, mute.Muted, until, conversationID, userID)
+	return err
+}
@@ -0,0 +1,167 @@
+/*
+Database operations for message reminders.
+
+A user can ask to be reminded of any message they can read at a given
+time, one reminder per message. Once the time has passed, the reminder
+is delivered (see DueMessageReminders and MarkMessageReminderDelivered)
+and stays listed until the user dismisses it. Reminders go away with
+their message (triggers, see migrateMessageReminders) and with their
+user's account; those on messages the user can no longer read are kept
+but neither listed nor delivered.
+*/
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"wasatext/service/ids"
+)
+
+// MessageReminder is a reminder of a user about a message
+type MessageReminder struct {
+	UserID      ids.UserID
+	Message     Message // without status, reply and comments; Content is cut to replyPreviewLength
+	RemindAt    time.Time
+	DeliveredAt *time.Time // nil until the reminder is delivered
+	CreatedAt   time.Time
+}
+
+// reminderSQL selects the reminders on messages their user can read
+// (the first parameter is replyPreviewLength); the caller adds the
+// condition on rm
+const reminderSQL = `
+	SELECT rm.user_id, m.id, m.conversation_id, m.sender_id, COALESCE(m.hook_name, u.name), substr(COALESCE(m.content, ''), 1, ?),
+		m.photo_id, m.timestamp, rm.remind_at, rm.delivered_at, rm.created_at
+	FROM message_reminders rm
+	JOIN messages m ON m.id = rm.message_id
+	JOIN conversation_participants cp ON cp.conversation_id = m.conversation_id AND cp.user_id = rm.user_id AND ` + notCleared + `
+	JOIN users cu ON cu.id = cp.user_id
+	JOIN conversations c ON c.id = m.conversation_id AND c.workspace_id = cu.workspace_id
+	JOIN users u ON u.id = m.sender_id`
+
+// scanReminder scans a row of reminderSQL
+func scanReminder(row rowScanner) (*MessageReminder, error) {
+	var rm MessageReminder
+	var photo sql.NullString
+	var delivered sql.NullTime
+	err := row.Scan(
+		&rm.UserID, &rm.Message.ID, &rm.Message.ConversationID, &rm.Message.SenderID, &rm.Message.SenderName, &rm.Message.Content,
+		&photo, &rm.Message.Timestamp, &rm.RemindAt, &delivered, &rm.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	rm.Message.PhotoID = photo.String
+	if delivered.Valid {
+		rm.DeliveredAt = &delivered.Time
+	}
+	return &rm, nil
+}
+
+// queryReminders runs reminderSQL with the rest of the query
+func (db *appdbimpl) queryReminders(ctx context.Context, query string, args ...interface{}) ([]MessageReminder, error) {
+	rows, err := db.db.QueryContext(ctx, reminderSQL+query, append([]interface{}{replyPreviewLength}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reminders := []MessageReminder{}
+	for rows.Next() {
+		rm, err := scanReminder(rows)
+		if err != nil {
+			return nil, err
+		}
+		reminders = append(reminders, *rm)
+	}
+	return reminders, rows.Err()
+}
+
+// SetMessageReminder sets a reminder on a message the user can read, or
+// replaces the one already there (delivered or not)
+func (db *appdbimpl) SetMessageReminder(ctx context.Context, userID ids.UserID, messageID ids.MessageID, remindAt time.Time) (*MessageReminder, error) {
+	var visible bool
+	err := db.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM messages m`+visibleMessages+` WHERE m.id = ?)
+	`, userID, messageID).Scan(&visible)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return nil, withID(ErrMessageNotFound, messageID)
+	}
+
+	// The time is stored in the local time zone, like time.Now(), so that
+	// it compares as text
+	_, err = db.db.ExecContext(ctx, `
+		INSERT INTO message_reminders (user_id, message_id, remind_at, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (user_id, message_id) DO UPDATE SET remind_at = excluded.remind_at, delivered_at = NULL, created_at = excluded.created_at
+	`, userID, messageID, remindAt.Local(), time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return scanReminder(db.db.QueryRowContext(ctx, reminderSQL+" WHERE rm.user_id = ? AND rm.message_id = ?", replyPreviewLength, userID, messageID))
+}
+
+// DeleteMessageReminder cancels the reminder of a user on a message, or
+// dismisses it once delivered
+func (db *appdbimpl) DeleteMessageReminder(ctx context.Context, userID ids.UserID, messageID ids.MessageID) error {
+	result, err := db.db.ExecContext(ctx,
+		"DELETE FROM message_reminders WHERE user_id = ? AND message_id = ?",
+		userID, messageID,
+	)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return withID(ErrReminderNotFound, messageID)
+	}
+	return nil
+}
+
+// GetMessageReminders returns the reminders of a user on the messages
+// they can read: the delivered ones first, the last delivered first,
+// then the pending ones, the soonest first
+func (db *appdbimpl) GetMessageReminders(ctx context.Context, userID ids.UserID) ([]MessageReminder, error) {
+	return db.queryReminders(ctx, `
+		WHERE rm.user_id = ?
+		ORDER BY rm.delivered_at IS NULL, rm.delivered_at DESC, rm.remind_at, m.id
+	`, userID)
+}
+
+// DueMessageReminders returns the reminders of every user that are due
+// by now and not delivered yet, the earliest first; those of deactivated
+// accounts wait for their user to come back
+func (db *appdbimpl) DueMessageReminders(ctx context.Context, now time.Time) ([]MessageReminder, error) {
+	return db.queryReminders(ctx, `
+		WHERE rm.delivered_at IS NULL AND rm.remind_at <= ?
+		AND rm.user_id NOT IN (SELECT id FROM users WHERE deactivated_at IS NOT NULL)
+		ORDER BY rm.remind_at
+	`, now)
+}
+
+// MarkMessageReminderDelivered records that the reminder of a user on a
+// message was delivered, reporting false when it is not due anymore
+// (delivered already, moved later or cancelled meanwhile)
+func (db *appdbimpl) MarkMessageReminderDelivered(ctx context.Context, userID ids.UserID, messageID ids.MessageID, now time.Time) (bool, error) {
+	result, err := db.db.ExecContext(ctx, `
+		UPDATE message_reminders SET delivered_at = ?
+		WHERE user_id = ? AND message_id = ? AND remind_at <= ? AND delivered_at IS NULL
+	`, now, userID, messageID, now)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
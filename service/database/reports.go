@@ -0,0 +1,139 @@
+/*
+Database operations for admin reports.
+
+The exports can cover every user and message on the server, so rows
+are handed to a callback one at a time instead of being collected in
+a slice: the caller can stream them out while the query is running.
+*/
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"wasatext/service/ids"
+)
+
+// UserReportRow is one user in the users export
+type UserReportRow struct {
+	ID           ids.UserID
+	WorkspaceID  string
+	Name         string
+	HasPhoto     bool
+	CreatedAt    *time.Time // nil for accounts created before it was tracked
+	DeletedAt    *time.Time
+	BannedAt     *time.Time
+	MessagesSent int
+}
+
+// ActivityReportRow is one sent message in the activity export (without its content)
+type ActivityReportRow struct {
+	MessageID      ids.MessageID
+	ConversationID ids.ConversationID
+	IsGroup        bool
+	WorkspaceID    string
+	UserID         ids.UserID
+	UserName       string
+	Timestamp      time.Time
+	HasPhoto       bool
+	IsReply        bool
+}
+
+/*
+ExportUsers calls fn for every user created in [from, to), oldest first.
+A zero from or to leaves that side of the range open. When a range is
+given, accounts without a creation time are left out.
+*/
+func (db *appdbimpl) ExportUsers(ctx context.Context, from, to time.Time, fn func(UserReportRow) error) error {
+	// Times are stored in local time, and compared as text
+	rows, err := db.db.QueryContext(ctx, `
+		SELECT u.id, u.workspace_id, u.name, u.photo_id IS NOT NULL,
+			u.created_at, u.purged_at, u.banned_at,
+			(SELECT COUNT(*) FROM messages m WHERE m.sender_id = u.id)
+		FROM users u
+		WHERE (? OR u.created_at >= ?) AND (? OR u.created_at < ?)
+		ORDER BY u.created_at, u.name
+	`, from.IsZero(), from.Local(), to.IsZero(), to.Local())
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row UserReportRow
+		var createdAt, deletedAt, bannedAt sql.NullTime
+
+		if err := rows.Scan(
+			&row.ID,
+			&row.WorkspaceID,
+			&row.Name,
+			&row.HasPhoto,
+			&createdAt,
+			&deletedAt,
+			&bannedAt,
+			&row.MessagesSent,
+		); err != nil {
+			return err
+		}
+
+		if createdAt.Valid {
+			row.CreatedAt = &createdAt.Time
+		}
+		if deletedAt.Valid {
+			row.DeletedAt = &deletedAt.Time
+		}
+		if bannedAt.Valid {
+			row.BannedAt = &bannedAt.Time
+		}
+
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// ExportActivity calls fn for every message sent in [from, to), oldest first.
+// A zero from or to leaves that side of the range open.
+func (db *appdbimpl) ExportActivity(ctx context.Context, from, to time.Time, fn func(ActivityReportRow) error) error {
+	// Messages are timestamped in local time, and compared as text
+	rows, err := db.db.QueryContext(ctx, `
+		SELECT m.id, m.conversation_id, c.is_group, c.workspace_id,
+			m.sender_id, u.name, m.timestamp, m.photo_id IS NOT NULL, m.reply_to IS NOT NULL
+		FROM messages m
+		JOIN conversations c ON m.conversation_id = c.id
+		JOIN users u ON m.sender_id = u.id
+		WHERE (? OR m.timestamp >= ?) AND (? OR m.timestamp < ?)
+		ORDER BY m.timestamp
+	`, from.IsZero(), from.Local(), to.IsZero(), to.Local())
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row ActivityReportRow
+
+		if err := rows.Scan(
+			&row.MessageID,
+			&row.ConversationID,
+			&row.IsGroup,
+			&row.WorkspaceID,
+			&row.UserID,
+			&row.UserName,
+			&row.Timestamp,
+			&row.HasPhoto,
+			&row.IsReply,
+		); err != nil {
+			return err
+		}
+
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
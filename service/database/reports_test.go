@@ -0,0 +1,48 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// inTimeZone runs the rest of the test with the local time zone of a
+// server ahead of UTC, where the times stored as local text and the
+// times in UTC no longer compare alike
+func inTimeZone(t *testing.T) {
+	t.Helper()
+	local := time.Local
+	time.Local = time.FixedZone("UTC+5", 5*60*60)
+	t.Cleanup(func() { time.Local = local })
+}
+
+func TestExportRangesInUTC(t *testing.T) {
+	inTimeZone(t)
+	ctx := context.Background()
+	db := newTestDB(t)
+	alice := newTestUser(t, db, "alice")
+	bob := newTestUser(t, db, "bob")
+	conversationID, err := db.GetOrCreateDirectConversation(ctx, alice, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sendTestMessage(t, db, conversationID, alice, "hello")
+
+	// The API parses the range in UTC
+	from, to := time.Now().UTC().Add(-time.Minute), time.Now().UTC().Add(time.Minute)
+
+	users := 0
+	if err := db.ExportUsers(ctx, from, to, func(UserReportRow) error { users++; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if users != 2 {
+		t.Errorf("exported %d users created in the last minute, want 2", users)
+	}
+	messages := 0
+	if err := db.ExportActivity(ctx, from, to, func(ActivityReportRow) error { messages++; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if messages != 1 {
+		t.Errorf("exported %d messages sent in the last minute, want 1", messages)
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Database operations for the developer sandbox.
+
+ResetData empties the database for POST /sandbox/reset, which then seeds
+the fixtures through the usual operations. The schema (and its
+user_version) is kept: only the rows go, and the photos with them.
+*/
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+)
+
+// ResetData deletes every row of every table, leaving only the default
+// workspace, and deletes the photos from the blob store
+func (db *appdbimpl) ResetData(ctx context.Context) error {
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+	}()
+
+	photoIDs, err := collectPhotoIDs(ctx, tx, "SELECT id FROM media UNION SELECT blob_id FROM media_renditions")
+	if err != nil {
+		return err
+	}
+
+	// Every table, so the tables of later migrations are not forgotten.
+	// The shadow tables of the search index are emptied with the index.
+	rows, err := tx.QueryContext(ctx, `
+		SELECT name FROM pragma_table_list
+		WHERE schema = 'main' AND type IN ('table', 'virtual') AND name NOT LIKE 'sqlite_%'
+	`)
+	if err != nil {
+		return err
+	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		tables = append(tables, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM "`+table+`"`); err != nil {
+			return err
+		}
+	}
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO workspaces (id, name, created_at) VALUES (?, 'Default', ?)",
+		DefaultWorkspaceID, time.Now(),
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	for _, id := range photoIDs {
+		if err := db.blobs.Delete(id); err != nil {
+			log.Printf("Error deleting the blob of photo %s: %v", id, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+/*
+Database operations for scheduled messages.
+
+A text message sent with a sendAt in the future is not created at once:
+it waits in scheduled_messages until its time comes, then the scheduler
+of the API takes it (TakeScheduledMessage) and sends it as its sender,
+like any other message. Until then its sender lists and cancels it.
+Scheduled messages go away with their sender's account and with their
+conversation, when they are purged.
+*/
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"wasatext/service/ids"
+)
+
+// MaxScheduledMessages is how many messages a user can have scheduled at once
+const MaxScheduledMessages = 100
+
+// ScheduledMessage is a message waiting to be sent
+type ScheduledMessage struct {
+	ID             int64
+	ConversationID ids.ConversationID
+	SenderID       ids.UserID
+	Content        string
+	ReplyTo        *ids.MessageID
+	SendAt         time.Time
+	CreatedAt      time.Time
+}
+
+// scheduledSQL selects the scheduled messages; the caller adds the condition
+const scheduledSQL = `
+	SELECT s.id, s.conversation_id, s.sender_id, s.content, s.reply_to, s.send_at, s.created_at
+	FROM scheduled_messages s`
+
+// scanScheduled scans a row of scheduledSQL
+func scanScheduled(row rowScanner) (*ScheduledMessage, error) {
+	var sm ScheduledMessage
+	var replyTo sql.NullString
+	err := row.Scan(&sm.ID, &sm.ConversationID, &sm.SenderID, &sm.Content, &replyTo, &sm.SendAt, &sm.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if replyTo.Valid {
+		id := ids.MessageID(replyTo.String)
+		sm.ReplyTo = &id
+	}
+	return &sm, nil
+}
+
+// queryScheduled runs scheduledSQL with the rest of the query
+func (db *appdbimpl) queryScheduled(ctx context.Context, query string, args ...interface{}) ([]ScheduledMessage, error) {
+	rows, err := db.db.QueryContext(ctx, scheduledSQL+query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scheduled := []ScheduledMessage{}
+	for rows.Next() {
+		sm, err := scanScheduled(rows)
+		if err != nil {
+			return nil, err
+		}
+		scheduled = append(scheduled, *sm)
+	}
+	return scheduled, rows.Err()
+}
+
+/*
+ScheduleMessage queues a text message to be sent in a conversation at
+sendAt. The caller checks the sender is a participant; what would stop
+the message now (a channel they do not run, a block) stops it here too,
+and it is checked again when the message is sent.
+*/
+func (db *appdbimpl) ScheduleMessage(ctx context.Context, conversationID ids.ConversationID, senderID ids.UserID, content string, replyTo *ids.MessageID, sendAt time.Time) (*ScheduledMessage, error) {
+	var replyToVal interface{}
+	if replyTo != nil && *replyTo != "" {
+		replyToVal = *replyTo
+	}
+
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+	}()
+
+	if err := checkCanPost(ctx, tx, conversationID, senderID); err != nil {
+		return nil, err
+	}
+	var pending int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM scheduled_messages WHERE sender_id = ?", senderID).Scan(&pending); err != nil {
+		return nil, err
+	}
+	if pending >= MaxScheduledMessages {
+		return nil, withID(ErrTooManyScheduled, senderID)
+	}
+
+	// The time is stored in the local time zone, like time.Now(), so that
+	// it compares as text
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO scheduled_messages (conversation_id, sender_id, content, reply_to, send_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, conversationID, senderID, content, replyToVal, sendAt.Local(), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	sm, err := scanScheduled(tx.QueryRowContext(ctx, scheduledSQL+" WHERE s.id = ?", id))
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return sm, nil
+}
+
+// GetScheduledMessages returns the messages a user has scheduled, the
+// next to be sent first
+func (db *appdbimpl) GetScheduledMessages(ctx context.Context, userID ids.UserID) ([]ScheduledMessage, error) {
+	return db.queryScheduled(ctx, " WHERE s.sender_id = ? ORDER BY s.send_at, s.id", userID)
+}
+
+// CancelScheduledMessage drops a message its sender scheduled, before it is sent
+func (db *appdbimpl) CancelScheduledMessage(ctx context.Context, userID ids.UserID, scheduledID int64) error {
+	result, err := db.db.ExecContext(ctx,
+		"DELETE FROM scheduled_messages WHERE id = ? AND sender_id = ?",
+		scheduledID, userID,
+	)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return withID(ErrScheduledMessageNotFound, strconv.FormatInt(scheduledID, 10))
+	}
+	return nil
+}
+
+// DueScheduledMessages returns the scheduled messages of every user that
+// are due by now, the earliest first; those of deleted accounts wait for
+// the purge, those of deactivated accounts for their user to come back
+func (db *appdbimpl) DueScheduledMessages(ctx context.Context, now time.Time) ([]ScheduledMessage, error) {
+	return db.queryScheduled(ctx, `
+		JOIN users u ON u.id = s.sender_id AND u.purged_at IS NULL AND u.deactivated_at IS NULL
+		WHERE s.send_at <= ?
+		ORDER BY s.send_at, s.id
+	`, now)
+}
+
+// NextScheduledMessage returns when the next scheduled message is due,
+// nil when none is scheduled
+func (db *appdbimpl) NextScheduledMessage(ctx context.Context) (*time.Time, error) {
+	var next time.Time
+	err := db.db.QueryRowContext(ctx, `
+		SELECT s.send_at FROM scheduled_messages s
+		JOIN users u ON u.id = s.sender_id AND u.purged_at IS NULL AND u.deactivated_at IS NULL
+		ORDER BY s.send_at LIMIT 1
+	`).Scan(&next)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &next, nil
+}
+
+// TakeScheduledMessage removes a due message from the queue before it is
+// sent, reporting false when it is not there anymore (cancelled, or taken
+// already)
+func (db *appdbimpl) TakeScheduledMessage(ctx context.Context, scheduledID int64) (bool, error) {
+	result, err := db.db.ExecContext(ctx, "DELETE FROM scheduled_messages WHERE id = ?", scheduledID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
@@ -0,0 +1,217 @@
+/*
+Database operations for the message search.
+
+The texts of the messages are indexed in message_search, an FTS5 table
+kept in sync by triggers on messages (see migrateMessageSearch). With
+OCR, the text read from the photos is searched too (see phototext.go):
+a photo message matches through its photo. A search only sees the
+conversations the user takes part in, and returns the newest matches
+first, page by page.
+
+A message in the index is one a participant can read: deleting a
+message drops it from the index, and what a user may see is decided by
+visibleMessages, which also leaves out the messages the user cleared.
+Anything else hiding messages later on (such as expiry) must go there
+too, or the search would show them.
+RunMaintenance repairs the index when it drifts from messages.
+
+FTS5 is not in the default build of the SQLite driver: the server must
+be built with -tags sqlite_fts5, or the package does not compile (see
+nofts5.go). New checks it too, for a system SQLite (-tags libsqlite3).
+*/
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"wasatext/service/ids"
+)
+
+// SearchResult is a message matching a search, with its conversation
+type SearchResult struct {
+	Message          Message // without status, reply and comments
+	ConversationName string  // the group, or the other user of a direct conversation
+	IsGroup          bool
+}
+
+// checkFTS5 fails when the SQLite library was built without FTS5
+func checkFTS5(ctx context.Context, db *sql.DB) error {
+	var enabled bool
+	if err := db.QueryRowContext(ctx, "SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&enabled); err != nil {
+		return err
+	}
+	if !enabled {
+		return errors.New("SQLite was built without FTS5, needed by the message search: build with -tags sqlite_fts5")
+	}
+	return nil
+}
+
+/*
+ftsQuery turns what the user typed into an FTS5 query: every word must
+appear, the last one possibly as a prefix (so results show up while
+typing). The words are quoted, so FTS5 operators are searched as text.
+*/
+func ftsQuery(query string) string {
+	words := strings.Fields(query)
+	for i, word := range words {
+		words[i] = `"` + strings.ReplaceAll(word, `"`, `""`) + `"`
+	}
+	if len(words) > 0 {
+		words[len(words)-1] += "*"
+	}
+	return strings.Join(words, " ")
+}
+
+/*
+visibleMessages joins m, the messages, to those the user (the single
+parameter) can read: the messages of the conversations they take part
+in, within their workspace, that they have not cleared. It names the
+conversation c.
+*/
+const visibleMessages = `
+		JOIN conversation_participants cp ON cp.conversation_id = m.conversation_id AND cp.user_id = ? AND ` + notCleared + `
+		JOIN users cu ON cu.id = cp.user_id
+		JOIN conversations c ON c.id = m.conversation_id AND c.workspace_id = cu.workspace_id`
+
+/*
+SearchMessages returns the messages whose text matches query, newest
+first, among the conversations the user takes part in, or only in
+conversationID when it is not empty, and only in the given language
+(see langdetect) when it is not empty. At most limit results are
+returned; beforeID continues after the last result of the previous
+page.
+*/
+func (db *appdbimpl) SearchMessages(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID, query, language string, beforeID ids.MessageID, limit int) ([]SearchResult, error) {
+	if conversationID != "" {
+		if err := db.checkParticipant(ctx, userID, conversationID); err != nil {
+			return nil, err
+		}
+	}
+	match := ftsQuery(query)
+	if match == "" {
+		return []SearchResult{}, nil
+	}
+
+	// The page starts after the given message
+	var before time.Time
+	if beforeID != "" {
+		err := db.db.QueryRowContext(ctx, "SELECT timestamp FROM messages WHERE id = ?", beforeID).Scan(&before)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, withID(ErrMessageNotFound, beforeID)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := db.db.QueryContext(ctx, `
+		SELECT m.id, m.conversation_id, m.sender_id, COALESCE(m.hook_name, u.name), m.content, COALESCE(m.language, ''), m.photo_id,
+			m.timestamp, m.system, m.edited_at, m.hook_name IS NOT NULL, c.is_group,
+			CASE
+				WHEN c.is_group = 1 THEN g.name
+				ELSE (SELECT ou.name FROM users ou
+					  JOIN conversation_participants cp2 ON ou.id = cp2.user_id
+					  WHERE cp2.conversation_id = c.id AND cp2.user_id != ?)
+			END
+		FROM messages m`+visibleMessages+`
+		JOIN users u ON u.id = m.sender_id
+		LEFT JOIN groups g ON g.id = c.group_id
+		WHERE m.id IN (
+			SELECT sr.message_id FROM message_search s
+			JOIN message_search_rows sr ON sr.rowid = s.rowid
+			WHERE message_search MATCH ?
+			UNION
+			SELECT pm.id FROM photo_text_search ps
+			JOIN photo_text pt ON pt.rowid = ps.rowid
+			JOIN messages pm ON pm.photo_id = pt.media_id
+			WHERE photo_text_search MATCH ?
+		)
+		AND (? = '' OR m.conversation_id = ?)
+		AND (? = '' OR m.language = ?)
+		AND (? = '' OR m.timestamp < ? OR (m.timestamp = ? AND m.id < ?))
+		ORDER BY m.timestamp DESC, m.id DESC
+		LIMIT ?
+	`, userID, userID, match, match, conversationID, conversationID, language, language, beforeID, before, before, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []SearchResult{}
+	for rows.Next() {
+		var r SearchResult
+		var content, photo, name sql.NullString
+		var editedAt sql.NullTime
+		if err := rows.Scan(
+			&r.Message.ID, &r.Message.ConversationID, &r.Message.SenderID, &r.Message.SenderName,
+			&content, &r.Message.Language, &photo, &r.Message.Timestamp, &r.Message.System, &editedAt,
+			&r.Message.ViaHook, &r.IsGroup, &name,
+		); err != nil {
+			return nil, err
+		}
+		r.Message.Content = content.String
+		r.Message.PhotoID = photo.String
+		if editedAt.Valid {
+			r.Message.EditedAt = &editedAt.Time
+		}
+		r.ConversationName = name.String
+		results = append(results, r)
+	}
+
+	return results, rows.Err()
+}
+
+/*
+repairSearchIndex brings the search index back in line with messages,
+should it have drifted (a write made without the triggers, a restored
+backup): entries of deleted messages, of messages without text or with
+an outdated text are dropped, and the messages missing are indexed. It
+returns the number of entries dropped and added.
+*/
+func repairSearchIndex(ctx context.Context, tx *sql.Tx) (int64, error) {
+	steps := []struct {
+		query string
+		count bool // whether the affected rows are entries dropped or added
+	}{
+		// Index entries without a row
+		{"DELETE FROM message_search WHERE rowid NOT IN (SELECT rowid FROM message_search_rows)", true},
+		// Rows whose message is gone, has no text or a changed one
+		{`CREATE TEMP TABLE stale_search_rows AS
+			SELECT sr.rowid FROM message_search_rows sr
+			LEFT JOIN messages m ON m.id = sr.message_id
+			LEFT JOIN message_search s ON s.rowid = sr.rowid
+			WHERE m.id IS NULL OR m.content IS NULL OR m.content = '' OR s.content IS NOT m.content`, false},
+		{"DELETE FROM message_search WHERE rowid IN (SELECT rowid FROM stale_search_rows)", false},
+		{"DELETE FROM message_search_rows WHERE rowid IN (SELECT rowid FROM stale_search_rows)", true},
+		{"DROP TABLE stale_search_rows", false},
+		// Messages with a text and no row
+		{`INSERT INTO message_search_rows (message_id)
+			SELECT id FROM messages
+			WHERE content IS NOT NULL AND content != ''
+			AND id NOT IN (SELECT message_id FROM message_search_rows)`, true},
+		{`INSERT INTO message_search (rowid, content)
+			SELECT sr.rowid, m.content FROM message_search_rows sr
+			JOIN messages m ON m.id = sr.message_id
+			WHERE sr.rowid NOT IN (SELECT rowid FROM message_search)`, false},
+	}
+
+	var repaired int64
+	for _, step := range steps {
+		res, err := tx.ExecContext(ctx, step.query)
+		if err != nil {
+			return 0, err
+		}
+		if step.count {
+			n, err := res.RowsAffected()
+			if err != nil {
+				return 0, err
+			}
+			repaired += n
+		}
+	}
+	return repaired, nil
+}
@@ -0,0 +1,156 @@
+package database
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"wasatext/service/ids"
+)
+
+// searchIDs returns the IDs of the messages a user finds with a query
+func searchIDs(t *testing.T, db AppDatabase, userID ids.UserID, query string) []ids.MessageID {
+	t.Helper()
+	results, err := db.SearchMessages(context.Background(), userID, "", query, "", "", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := make([]ids.MessageID, 0, len(results))
+	for _, r := range results {
+		found = append(found, r.Message.ID)
+	}
+	return found
+}
+
+// sendTestMessage sends a text message
+func sendTestMessage(t *testing.T, db AppDatabase, conversationID ids.ConversationID, senderID ids.UserID, content string) ids.MessageID {
+	t.Helper()
+	msg, err := db.CreateMessage(context.Background(), conversationID, senderID, content, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg.ID
+}
+
+func TestSearchHidesClearedMessages(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	alice := newTestUser(t, db, "alice")
+	bob := newTestUser(t, db, "bob")
+	conversationID, err := db.GetOrCreateDirectConversation(ctx, alice, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleared := sendTestMessage(t, db, conversationID, bob, "pineapple before")
+
+	if _, err := db.ClearConversation(ctx, alice, conversationID, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	kept := sendTestMessage(t, db, conversationID, bob, "pineapple after")
+
+	if found := searchIDs(t, db, alice, "pineapple"); !slices.Equal(found, []ids.MessageID{kept}) {
+		t.Errorf("alice found %v, want only %v", found, kept)
+	}
+	if found := searchIDs(t, db, bob, "pineapple"); len(found) != 2 || !slices.Contains(found, cleared) {
+		t.Errorf("bob, who did not clear, found %v, want both messages", found)
+	}
+}
+
+func TestSearchHidesMessagesDeletedForMe(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	alice := newTestUser(t, db, "alice")
+	bob := newTestUser(t, db, "bob")
+	conversationID, err := db.GetOrCreateDirectConversation(ctx, alice, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	messageID := sendTestMessage(t, db, conversationID, bob, "pineapple")
+
+	if err := db.DeleteMessageForMe(ctx, alice, messageID); err != nil {
+		t.Fatal(err)
+	}
+	if found := searchIDs(t, db, alice, "pineapple"); len(found) != 0 {
+		t.Errorf("alice found %v after deleting it for herself", found)
+	}
+	if found := searchIDs(t, db, bob, "pineapple"); !slices.Equal(found, []ids.MessageID{messageID}) {
+		t.Errorf("bob found %v, want %v", found, messageID)
+	}
+}
+
+func TestSearchHidesMessagesDeletedForEveryone(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	alice := newTestUser(t, db, "alice")
+	bob := newTestUser(t, db, "bob")
+	conversationID, err := db.GetOrCreateDirectConversation(ctx, alice, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	messageID := sendTestMessage(t, db, conversationID, bob, "pineapple")
+
+	if _, err := db.DeleteMessage(ctx, messageID, bob); err != nil {
+		t.Fatal(err)
+	}
+	for _, userID := range []ids.UserID{alice, bob} {
+		if found := searchIDs(t, db, userID, "pineapple"); len(found) != 0 {
+			t.Errorf("%s found %v after it was deleted for everyone", userID, found)
+		}
+	}
+}
+
+func TestSearchHidesGroupsLeft(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	alice := newTestUser(t, db, "alice")
+	bob := newTestUser(t, db, "bob")
+	group, err := db.CreateGroup(ctx, "group", alice, []ids.UserID{bob})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sendTestMessage(t, db, groupConversationID(t, db, group.ID), alice, "pineapple")
+	if found := searchIDs(t, db, bob, "pineapple"); len(found) != 1 {
+		t.Fatalf("bob found %v before leaving, want the message", found)
+	}
+
+	if err := db.RemoveUserFromGroup(ctx, group.ID, bob); err != nil {
+		t.Fatal(err)
+	}
+	if found := searchIDs(t, db, bob, "pineapple"); len(found) != 0 {
+		t.Errorf("bob found %v after leaving the group", found)
+	}
+}
+
+func TestSearchStaysInWorkspace(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	alice := newTestUser(t, db, "alice")
+	bob := newTestUser(t, db, "bob")
+	conversationID, err := db.GetOrCreateDirectConversation(ctx, alice, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sendTestMessage(t, db, conversationID, bob, "pineapple")
+
+	if _, err := db.CreateWorkspace(ctx, "other", "Other"); err != nil {
+		t.Fatal(err)
+	}
+	carol, err := db.CreateUser(ctx, "other", "carol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found := searchIDs(t, db, carol, "pineapple"); len(found) != 0 {
+		t.Errorf("a user of another workspace found %v", found)
+	}
+
+	// Even a participant row left across workspaces does not open the
+	// conversation to the search
+	_, err = db.(*appdbimpl).db.Exec("INSERT INTO conversation_participants (conversation_id, user_id) VALUES (?, ?)", conversationID, carol)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found := searchIDs(t, db, carol, "pineapple"); len(found) != 0 {
+		t.Errorf("a participant from another workspace found %v", found)
+	}
+}
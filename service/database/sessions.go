@@ -0,0 +1,113 @@
+/*
+Database operations for sessions.
+
+Logging in opens a session: the client gets a random, opaque token and
+sends it as its bearer token from then on. Only a SHA-256 hash of the
+token is stored, so a leaked database does not leak usable tokens.
+Logging out closes the session. Sessions of deleted and banned accounts
+no longer resolve, and are removed with the account when it is purged.
+Logging in to a deactivated account reactivates it.
+*/
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"wasatext/service/ids"
+)
+
+// sessionTokenPrefix marks session tokens, like the guest and hook tokens
+const sessionTokenPrefix = "session-"
+
+// IsSessionToken reports whether a bearer token is a session token
+func IsSessionToken(token string) bool {
+	return len(token) > len(sessionTokenPrefix) && token[:len(sessionTokenPrefix)] == sessionTokenPrefix
+}
+
+// hashSessionToken is how a token is stored
+func hashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateSession opens a session for a user, reactivating their account
+// if they deactivated it, and returns its token
+func (db *appdbimpl) CreateSession(ctx context.Context, userID ids.UserID) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := sessionTokenPrefix + hex.EncodeToString(buf)
+
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+	}()
+
+	if _, err := tx.ExecContext(ctx, "UPDATE users SET deactivated_at = NULL WHERE id = ?", userID); err != nil {
+		return "", err
+	}
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO sessions (token_hash, user_id, created_at) VALUES (?, ?, ?)",
+		hashSessionToken(token), userID, time.Now(),
+	)
+	if err != nil {
+		return "", err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// GetSessionUser returns the user a session token belongs to. Unknown
+// tokens and sessions of deleted or banned accounts give ErrSessionNotFound.
+func (db *appdbimpl) GetSessionUser(ctx context.Context, token string) (ids.UserID, error) {
+	if !IsSessionToken(token) {
+		return "", ErrSessionNotFound
+	}
+
+	var userID ids.UserID
+	err := db.db.QueryRowContext(ctx, `
+		SELECT s.user_id
+		FROM sessions s
+		JOIN users u ON s.user_id = u.id
+		WHERE s.token_hash = ? AND u.purged_at IS NULL AND u.banned_at IS NULL
+	`, hashSessionToken(token)).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrSessionNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return userID, nil
+}
+
+// DeleteSession closes a session
+func (db *appdbimpl) DeleteSession(ctx context.Context, token string) error {
+	result, err := db.db.ExecContext(ctx, "DELETE FROM sessions WHERE token_hash = ?", hashSessionToken(token))
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
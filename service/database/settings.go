@@ -0,0 +1,163 @@
+/*
+Database operations for the server settings.
+
+Settings the admin changes through the API (rather than in the
+configuration file) are kept in the settings table, one row per key. A
+setting without a row has its default value. So far the settings are
+the branding: application name, accent color and logo. The logo is a
+photo in the media store (see media.go), released when it is replaced.
+*/
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+)
+
+// Setting keys
+const (
+	settingAppName     = "branding.appName"
+	settingAccentColor = "branding.accentColor"
+	settingLogo        = "branding.logo" // media ID
+)
+
+// Branding is the look of the application set by the admin; empty
+// fields keep their default value
+type Branding struct {
+	AppName     string
+	AccentColor string // "#rrggbb"
+	LogoID      string // media ID of the logo
+}
+
+// GetBranding returns the branding set by the admin
+func (db *appdbimpl) GetBranding(ctx context.Context) (*Branding, error) {
+	rows, err := db.db.QueryContext(ctx, "SELECT key, value FROM settings WHERE key IN (?, ?, ?)",
+		settingAppName, settingAccentColor, settingLogo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var b Branding
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+		switch key {
+		case settingAppName:
+			b.AppName = value
+		case settingAccentColor:
+			b.AccentColor = value
+		case settingLogo:
+			b.LogoID = value
+		}
+	}
+	return &b, rows.Err()
+}
+
+// SetBranding sets the application name and the accent color; "" goes
+// back to the default
+func (db *appdbimpl) SetBranding(ctx context.Context, appName, accentColor string) (*Branding, error) {
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+	}()
+
+	if err := setSetting(ctx, tx, settingAppName, appName); err != nil {
+		return nil, err
+	}
+	if err := setSetting(ctx, tx, settingAccentColor, accentColor); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return db.GetBranding(ctx)
+}
+
+// SetBrandingLogo stores a new logo and releases the previous one
+func (db *appdbimpl) SetBrandingLogo(ctx context.Context, logo []byte) (*Branding, error) {
+	p, err := db.putPhoto(logo)
+	if err != nil {
+		return nil, err
+	}
+	committed := false
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		db.dropPhoto(p)
+		return nil, err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+		if !committed {
+			db.dropPhoto(p)
+		}
+	}()
+
+	previous, err := getSetting(ctx, tx, settingLogo)
+	if err != nil {
+		return nil, err
+	}
+	if err := insertMedia(ctx, tx, p); err != nil {
+		return nil, err
+	}
+	if err := setSetting(ctx, tx, settingLogo, p.id); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	committed = true
+	db.releasePhotos(ctx, previous)
+	return db.GetBranding(ctx)
+}
+
+// DeleteBrandingLogo removes the logo
+func (db *appdbimpl) DeleteBrandingLogo(ctx context.Context) error {
+	previous, err := getSetting(ctx, db.db, settingLogo)
+	if err != nil {
+		return err
+	}
+	if previous == "" {
+		return ErrPhotoNotFound
+	}
+	if err := setSetting(ctx, db.db, settingLogo, ""); err != nil {
+		return err
+	}
+	db.releasePhotos(ctx, previous)
+	return nil
+}
+
+// getSetting returns the value of a setting, "" if it has none
+func getSetting(ctx context.Context, q queryRower, key string) (string, error) {
+	var value string
+	err := q.QueryRowContext(ctx, "SELECT value FROM settings WHERE key = ?", key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return value, err
+}
+
+// setSetting sets the value of a setting; "" removes it
+func setSetting(ctx context.Context, ex execer, key, value string) error {
+	if value == "" {
+		_, err := ex.ExecContext(ctx, "DELETE FROM settings WHERE key = ?", key)
+		return err
+	}
+	_, err := ex.ExecContext(ctx, `
+		INSERT INTO settings (key, value, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at
+	`, key, value, time.Now())
+	return err
+}
@@ -0,0 +1,443 @@
+/*
+Database operations for the conversations and groups an admin deleted.
+
+Deleting a conversation only hides it: its participants are moved to
+deleted_conversation_participants, so that every query going through
+conversation_participants (the conversation list, the participant
+checks, search, webhooks, widgets...) no longer finds it, and it is
+marked with deleted_at and the time it is to be purged. A group and its
+conversation go together: deleting either moves the group members to
+deleted_group_members and marks the group too. Restoring moves them
+back. PurgeDeletedConversations hard-deletes those whose time has come,
+with their messages and everything attached. Each step is written to
+deletion_audit.
+*/
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"wasatext/service/ids"
+)
+
+// participantColumns are the columns moved between conversation_participants
+// and deleted_conversation_participants, named so that a column added to
+// one table but not yet to the other fails loudly here rather than
+// shifting the values of the rest
+const participantColumns = "conversation_id, user_id, last_read_time, share_typing, share_read_receipts, cleared_before, muted, muted_until"
+
+// Deletion audit actions
+const (
+	DeletionActionDelete  = "delete"
+	DeletionActionRestore = "restore"
+	DeletionActionPurge   = "purge"
+)
+
+// DeletedConversation is a conversation hidden by an admin
+type DeletedConversation struct {
+	ConversationID ids.ConversationID
+	GroupID        *ids.GroupID // for the conversation of a group
+	Name           string       // the group name, or the names of both participants
+	Participants   int
+	Messages       int
+	DeletedAt      time.Time
+	PurgeAt        *time.Time // nil when it is kept until restored
+}
+
+// DeletionAuditEntry records a deletion, restoration or purge
+type DeletionAuditEntry struct {
+	ID             int64
+	Action         string
+	ConversationID ids.ConversationID
+	GroupID        *ids.GroupID
+	Note           string
+	CreatedAt      time.Time
+}
+
+// PurgedConversation describes a conversation hard-deleted by PurgeDeletedConversations
+type PurgedConversation struct {
+	ConversationID  ids.ConversationID
+	GroupID         *ids.GroupID
+	MessagesDeleted int64
+}
+
+// deletionTarget is a conversation and its group, if any
+type deletionTarget struct {
+	conversationID ids.ConversationID
+	groupID        sql.NullString
+	deleted        bool
+}
+
+// SoftDeleteConversation hides a conversation, and its group if it has
+// one, until purgeAt (nil to keep it until restored)
+func (db *appdbimpl) SoftDeleteConversation(ctx context.Context, conversationID ids.ConversationID, purgeAt *time.Time, note string) error {
+	return db.softDelete(ctx, "SELECT id, group_id, deleted_at IS NOT NULL FROM conversations WHERE id = ?",
+		string(conversationID), withID(ErrConversationNotFound, conversationID), purgeAt, note)
+}
+
+// SoftDeleteGroup hides a group and its conversation until purgeAt (nil
+// to keep them until restored)
+func (db *appdbimpl) SoftDeleteGroup(ctx context.Context, groupID ids.GroupID, purgeAt *time.Time, note string) error {
+	return db.softDelete(ctx, "SELECT id, group_id, deleted_at IS NOT NULL FROM conversations WHERE group_id = ? AND is_group = 1",
+		string(groupID), withID(ErrGroupNotFound, groupID), purgeAt, note)
+}
+
+// RestoreConversation shows a deleted conversation again, and its group
+func (db *appdbimpl) RestoreConversation(ctx context.Context, conversationID ids.ConversationID, note string) error {
+	return db.restore(ctx, "SELECT id, group_id, deleted_at IS NOT NULL FROM conversations WHERE id = ?",
+		string(conversationID), withID(ErrConversationNotFound, conversationID), note)
+}
+
+// RestoreGroup shows a deleted group again, and its conversation
+func (db *appdbimpl) RestoreGroup(ctx context.Context, groupID ids.GroupID, note string) error {
+	return db.restore(ctx, "SELECT id, group_id, deleted_at IS NOT NULL FROM conversations WHERE group_id = ? AND is_group = 1",
+		string(groupID), withID(ErrGroupNotFound, groupID), note)
+}
+
+// findDeletionTarget reads the conversation a query selects, answering
+// notFound when there is none
+func findDeletionTarget(ctx context.Context, tx *sql.Tx, query, id string, notFound error) (*deletionTarget, error) {
+	var target deletionTarget
+	err := tx.QueryRowContext(ctx, query, id).Scan(&target.conversationID, &target.groupID, &target.deleted)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &target, nil
+}
+
+// softDelete hides the conversation a query selects, in one transaction
+func (db *appdbimpl) softDelete(ctx context.Context, query, id string, notFound error, purgeAt *time.Time, note string) error {
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+	}()
+
+	target, err := findDeletionTarget(ctx, tx, query, id, notFound)
+	if err != nil {
+		return err
+	}
+	if target.deleted {
+		return withID(ErrConversationDeleted, target.conversationID)
+	}
+
+	// Move the participants and the members out of sight
+	for _, query := range []string{
+		"INSERT INTO deleted_conversation_participants (" + participantColumns + ") SELECT " + participantColumns + " FROM conversation_participants WHERE conversation_id = ?",
+		"DELETE FROM conversation_participants WHERE conversation_id = ?",
+	} {
+		if _, err := tx.ExecContext(ctx, query, target.conversationID); err != nil {
+			return err
+		}
+	}
+	now := time.Now()
+	_, err = tx.ExecContext(ctx, "UPDATE conversations SET deleted_at = ?, purge_at = ? WHERE id = ?", now, purgeAt, target.conversationID)
+	if err != nil {
+		return err
+	}
+	if target.groupID.Valid {
+		for _, query := range []string{
+			"INSERT INTO deleted_group_members (group_id, user_id) SELECT group_id, user_id FROM group_members WHERE group_id = ?",
+			"DELETE FROM group_members WHERE group_id = ?",
+		} {
+			if _, err := tx.ExecContext(ctx, query, target.groupID.String); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE groups SET deleted_at = ? WHERE id = ?", now, target.groupID.String); err != nil {
+			return err
+		}
+	}
+
+	if err := insertDeletionAudit(ctx, tx, DeletionActionDelete, target, note, now); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// restore shows the conversation a query selects again, in one transaction
+func (db *appdbimpl) restore(ctx context.Context, query, id string, notFound error, note string) error {
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+	}()
+
+	target, err := findDeletionTarget(ctx, tx, query, id, notFound)
+	if err != nil {
+		return err
+	}
+	if !target.deleted {
+		return withID(ErrConversationNotDeleted, target.conversationID)
+	}
+
+	// Two users have one direct conversation: they may have started a
+	// new one meanwhile
+	if !target.groupID.Valid {
+		var other ids.ConversationID
+		err := tx.QueryRowContext(ctx, `
+			SELECT cp1.conversation_id
+			FROM deleted_conversation_participants d1
+			JOIN deleted_conversation_participants d2 ON d2.conversation_id = d1.conversation_id AND d2.user_id > d1.user_id
+			JOIN conversation_participants cp1 ON cp1.user_id = d1.user_id
+			JOIN conversation_participants cp2 ON cp2.conversation_id = cp1.conversation_id AND cp2.user_id = d2.user_id
+			JOIN conversations c ON c.id = cp1.conversation_id AND c.is_group = 0
+			WHERE d1.conversation_id = ?
+		`, target.conversationID).Scan(&other)
+		if err == nil {
+			return withID(ErrDirectConversationExists, other)
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+	}
+
+	// Move the participants and the members back
+	for _, query := range []string{
+		"INSERT INTO conversation_participants (" + participantColumns + ") SELECT " + participantColumns + " FROM deleted_conversation_participants WHERE conversation_id = ?",
+		"DELETE FROM deleted_conversation_participants WHERE conversation_id = ?",
+		"UPDATE conversations SET deleted_at = NULL, purge_at = NULL WHERE id = ?",
+	} {
+		if _, err := tx.ExecContext(ctx, query, target.conversationID); err != nil {
+			return err
+		}
+	}
+	if target.groupID.Valid {
+		for _, query := range []string{
+			"INSERT INTO group_members (group_id, user_id) SELECT group_id, user_id FROM deleted_group_members WHERE group_id = ?",
+			"DELETE FROM deleted_group_members WHERE group_id = ?",
+			"UPDATE groups SET deleted_at = NULL WHERE id = ?",
+		} {
+			if _, err := tx.ExecContext(ctx, query, target.groupID.String); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := insertDeletionAudit(ctx, tx, DeletionActionRestore, target, note, time.Now()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// insertDeletionAudit writes an audit entry for a conversation and its group
+func insertDeletionAudit(ctx context.Context, ex execer, action string, target *deletionTarget, note string, at time.Time) error {
+	var groupID interface{}
+	if target.groupID.Valid {
+		groupID = target.groupID.String
+	}
+	_, err := ex.ExecContext(ctx, `
+		INSERT INTO deletion_audit (action, conversation_id, group_id, note, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, action, target.conversationID, groupID, note, at)
+	return err
+}
+
+// GetDeletedConversations returns the conversations hidden by an admin,
+// the most recently deleted first
+func (db *appdbimpl) GetDeletedConversations(ctx context.Context) ([]DeletedConversation, error) {
+	rows, err := db.db.QueryContext(ctx, `
+		SELECT c.id, c.group_id,
+			COALESCE(g.name, (
+				SELECT group_concat(u.name, ', ') FROM deleted_conversation_participants d
+				JOIN users u ON u.id = d.user_id
+				WHERE d.conversation_id = c.id
+			), ''),
+			(SELECT COUNT(*) FROM deleted_conversation_participants d WHERE d.conversation_id = c.id),
+			(SELECT COUNT(*) FROM messages m WHERE m.conversation_id = c.id),
+			c.deleted_at, c.purge_at
+		FROM conversations c
+		LEFT JOIN groups g ON g.id = c.group_id
+		WHERE c.deleted_at IS NOT NULL
+		ORDER BY c.deleted_at DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	conversations := []DeletedConversation{}
+	for rows.Next() {
+		var dc DeletedConversation
+		var groupID sql.NullString
+		var purgeAt sql.NullTime
+		if err := rows.Scan(&dc.ConversationID, &groupID, &dc.Name, &dc.Participants, &dc.Messages, &dc.DeletedAt, &purgeAt); err != nil {
+			return nil, err
+		}
+		if groupID.Valid {
+			id := ids.GroupID(groupID.String)
+			dc.GroupID = &id
+		}
+		if purgeAt.Valid {
+			dc.PurgeAt = &purgeAt.Time
+		}
+		conversations = append(conversations, dc)
+	}
+	return conversations, rows.Err()
+}
+
+// GetDeletionAudit returns the deletions, restorations and purges, most
+// recent first
+func (db *appdbimpl) GetDeletionAudit(ctx context.Context) ([]DeletionAuditEntry, error) {
+	rows, err := db.db.QueryContext(ctx, `
+		SELECT id, action, conversation_id, group_id, note, created_at
+		FROM deletion_audit
+		ORDER BY created_at DESC, id DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []DeletionAuditEntry{}
+	for rows.Next() {
+		var e DeletionAuditEntry
+		var groupID sql.NullString
+		if err := rows.Scan(&e.ID, &e.Action, &e.ConversationID, &groupID, &e.Note, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		if groupID.Valid {
+			id := ids.GroupID(groupID.String)
+			e.GroupID = &id
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// PurgeDeletedConversations hard-deletes every deleted conversation, and
+// its group, whose purge time is before the given time
+func (db *appdbimpl) PurgeDeletedConversations(ctx context.Context, before time.Time) ([]PurgedConversation, error) {
+	rows, err := db.db.QueryContext(ctx, `
+		SELECT id, group_id, 1 FROM conversations
+		WHERE deleted_at IS NOT NULL AND purge_at IS NOT NULL AND purge_at < ?
+	`, before)
+	if err != nil {
+		return nil, err
+	}
+	var targets []deletionTarget
+	for rows.Next() {
+		var target deletionTarget
+		if err := rows.Scan(&target.conversationID, &target.groupID, &target.deleted); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		targets = append(targets, target)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var purged []PurgedConversation
+	for i := range targets {
+		record, err := db.purgeConversation(ctx, &targets[i])
+		if err != nil {
+			return purged, err
+		}
+		purged = append(purged, *record)
+	}
+	return purged, nil
+}
+
+// purgeConversation removes a deleted conversation, its group and
+// everything attached to them in a single transaction. The notes,
+// deletions, events and reminders of the messages go with them
+// (see the triggers in migrations.go).
+func (db *appdbimpl) purgeConversation(ctx context.Context, target *deletionTarget) (*PurgedConversation, error) {
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+	}()
+
+	record := PurgedConversation{ConversationID: target.conversationID}
+	released, err := collectPhotoIDs(ctx, tx, `
+		SELECT photo_id FROM messages WHERE conversation_id = ?
+		UNION SELECT photo_id FROM groups WHERE id = ?
+	`, target.conversationID, target.groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, query := range []string{
+		"DELETE FROM comments WHERE message_id IN (SELECT id FROM messages WHERE conversation_id = ?)",
+		"DELETE FROM mentions WHERE message_id IN (SELECT id FROM messages WHERE conversation_id = ?)",
+		"DELETE FROM message_pins WHERE conversation_id = ?",
+		"DELETE FROM message_receipts WHERE message_id IN (SELECT id FROM messages WHERE conversation_id = ?)",
+	} {
+		if _, err := tx.ExecContext(ctx, query, target.conversationID); err != nil {
+			return nil, err
+		}
+	}
+	result, err := tx.ExecContext(ctx, "DELETE FROM messages WHERE conversation_id = ?", target.conversationID)
+	if err != nil {
+		return nil, err
+	}
+	if record.MessagesDeleted, err = result.RowsAffected(); err != nil {
+		return nil, err
+	}
+
+	// Its hash chain, scheduled messages, webhooks, widget tokens, participants and the conversation itself
+	for _, query := range []string{
+		"DELETE FROM message_chain WHERE conversation_id = ?",
+		"DELETE FROM scheduled_messages WHERE conversation_id = ?",
+		"DELETE FROM hooks WHERE conversation_id = ?",
+		"DELETE FROM widget_tokens WHERE conversation_id = ?",
+		"DELETE FROM deleted_conversation_participants WHERE conversation_id = ?",
+		"DELETE FROM conversation_participants WHERE conversation_id = ?",
+		"DELETE FROM conversations WHERE id = ?",
+	} {
+		if _, err := tx.ExecContext(ctx, query, target.conversationID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Guest tokens, webhooks, proposals, members and the group itself
+	if target.groupID.Valid {
+		groupID := ids.GroupID(target.groupID.String)
+		record.GroupID = &groupID
+		for _, query := range []string{
+			"DELETE FROM guest_tokens WHERE group_id = ?",
+			"DELETE FROM webhook_deliveries WHERE webhook_id IN (SELECT id FROM outbound_webhooks WHERE group_id = ?)",
+			"DELETE FROM outbound_webhooks WHERE group_id = ?",
+			"DELETE FROM group_votes WHERE proposal_id IN (SELECT id FROM group_proposals WHERE group_id = ?)",
+			"DELETE FROM group_proposals WHERE group_id = ?",
+			"DELETE FROM deleted_group_members WHERE group_id = ?",
+			"DELETE FROM group_members WHERE group_id = ?",
+			"DELETE FROM groups WHERE id = ?",
+		} {
+			if _, err := tx.ExecContext(ctx, query, groupID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := insertDeletionAudit(ctx, tx, DeletionActionPurge, target, "", time.Now()); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	db.releasePhotos(ctx, released...)
+	return &record, nil
+}
@@ -0,0 +1,68 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"wasatext/service/ids"
+)
+
+func TestSoftDeleteKeepsParticipantSettings(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	alice := newTestUser(t, db, "alice")
+	bob := newTestUser(t, db, "bob")
+
+	conversationID, err := db.GetOrCreateDirectConversation(ctx, alice, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	until := time.Now().Add(time.Hour).Truncate(time.Second)
+	if err := db.SetMute(ctx, alice, conversationID, Mute{Muted: true, Until: &until}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.SoftDeleteConversation(ctx, conversationID, nil, "test"); err != nil {
+		t.Fatalf("deleting the conversation: %v", err)
+	}
+	if _, err := db.GetMute(ctx, alice, conversationID); err == nil {
+		t.Fatal("a participant of a deleted conversation can still read it")
+	}
+	if err := db.RestoreConversation(ctx, conversationID, "test"); err != nil {
+		t.Fatalf("restoring the conversation: %v", err)
+	}
+
+	mute, err := db.GetMute(ctx, alice, conversationID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !mute.Muted || mute.Until == nil || !mute.Until.Equal(until) {
+		t.Errorf("mute after restoring = %+v, want muted until %v", mute, until)
+	}
+}
+
+func TestSoftDeleteGroup(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	alice := newTestUser(t, db, "alice")
+	bob := newTestUser(t, db, "bob")
+
+	group, err := db.CreateGroup(ctx, "group", alice, []ids.UserID{bob})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SoftDeleteGroup(ctx, group.ID, nil, "test"); err != nil {
+		t.Fatalf("deleting the group: %v", err)
+	}
+	if err := db.RestoreGroup(ctx, group.ID, "test"); err != nil {
+		t.Fatalf("restoring the group: %v", err)
+	}
+	restored, err := db.GetGroup(ctx, group.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(restored.Members) != 2 {
+		t.Errorf("restored group has %d members, want 2", len(restored.Members))
+	}
+}
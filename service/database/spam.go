@@ -0,0 +1,171 @@
+/*
+Database operations for the anti-spam heuristics.
+
+The rules themselves (thresholds, scores, throttle length) live in the
+API layer; this file only stores and counts things for it.
+*/
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"wasatext/service/ids"
+)
+
+// Throttle is a temporary sending ban placed on a user
+type Throttle struct {
+	UserID ids.UserID
+	Until  time.Time
+	Reason string
+}
+
+// SpamScore summarizes a user's spam events for the admin view
+type SpamScore struct {
+	UserID         ids.UserID
+	UserName       string
+	Score          int
+	Events         int
+	LastEventAt    time.Time
+	ThrottledUntil *time.Time
+}
+
+// CountNewConversations counts the conversations a user started since the given time
+func (db *appdbimpl) CountNewConversations(ctx context.Context, userID ids.UserID, since time.Time) (int, error) {
+	var count int
+	err := db.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM conversations WHERE created_by = ? AND created_at >= ?",
+		userID, since,
+	).Scan(&count)
+	return count, err
+}
+
+// CountDuplicateMessages counts in how many other conversations
+// a user sent exactly this text since the given time
+func (db *appdbimpl) CountDuplicateMessages(ctx context.Context, senderID ids.UserID, content string, excludeConversationID ids.ConversationID, since time.Time) (int, error) {
+	var count int
+	err := db.db.QueryRowContext(ctx, `
+		SELECT COUNT(DISTINCT conversation_id) FROM messages
+		WHERE sender_id = ? AND content = ? AND conversation_id != ? AND timestamp >= ?
+	`, senderID, content, excludeConversationID, since).Scan(&count)
+	return count, err
+}
+
+// RecordSpamEvent stores one detection against a user
+// and puts it on the moderation queue
+func (db *appdbimpl) RecordSpamEvent(ctx context.Context, userID ids.UserID, kind, detail string, score int) error {
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+	}()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO spam_events (user_id, kind, detail, score, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, userID, kind, detail, score, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if _, err := insertModerationItem(ctx, tx, ModerationSourceSpam, userID, nil, nil, kind+": "+detail); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ThrottleUser blocks a user from sending until the given time.
+// An existing throttle is replaced.
+func (db *appdbimpl) ThrottleUser(ctx context.Context, userID ids.UserID, until time.Time, reason string) error {
+	_, err := db.db.ExecContext(ctx, `
+		INSERT OR REPLACE INTO user_throttles (user_id, until, reason)
+		VALUES (?, ?, ?)
+	`, userID, until, reason)
+	return err
+}
+
+// GetThrottle returns the user's active throttle, or nil if there is none
+func (db *appdbimpl) GetThrottle(ctx context.Context, userID ids.UserID) (*Throttle, error) {
+	t := Throttle{UserID: userID}
+	err := db.db.QueryRowContext(ctx,
+		"SELECT until, reason FROM user_throttles WHERE user_id = ? AND until > ?",
+		userID, time.Now(),
+	).Scan(&t.Until, &t.Reason)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// GetSpamScores returns every user with at least one spam event, worst first
+func (db *appdbimpl) GetSpamScores(ctx context.Context) ([]SpamScore, error) {
+	rows, err := db.db.QueryContext(ctx, `
+		SELECT
+			e.user_id,
+			u.name,
+			SUM(e.score) AS total,
+			COUNT(*),
+			MAX(e.created_at),
+			t.until
+		FROM spam_events e
+		JOIN users u ON e.user_id = u.id
+		LEFT JOIN user_throttles t ON t.user_id = e.user_id AND t.until > ?
+		GROUP BY e.user_id
+		ORDER BY total DESC
+	`, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var scores []SpamScore
+	for rows.Next() {
+		var s SpamScore
+		var lastEvent string
+		var throttledUntil sql.NullTime
+
+		if err := rows.Scan(&s.UserID, &s.UserName, &s.Score, &s.Events, &lastEvent, &throttledUntil); err != nil {
+			return nil, err
+		}
+
+		// MAX() loses the column type, so SQLite hands back the stored text
+		if s.LastEventAt, err = parseSQLiteTime(lastEvent); err != nil {
+			return nil, err
+		}
+		if throttledUntil.Valid {
+			s.ThrottledUntil = &throttledUntil.Time
+		}
+
+		scores = append(scores, s)
+	}
+
+	return scores, rows.Err()
+}
+
+// parseSQLiteTime parses a timestamp as stored by the sqlite3 driver
+func parseSQLiteTime(value string) (time.Time, error) {
+	for _, layout := range []string{
+		"2006-01-02 15:04:05.999999999-07:00",
+		"2006-01-02T15:04:05.999999999-07:00",
+		"2006-01-02 15:04:05.999999999",
+		"2006-01-02 15:04:05",
+	} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("unrecognized timestamp: " + value)
+}
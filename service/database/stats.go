@@ -0,0 +1,167 @@
+/*
+Database operations for the conversation statistics.
+
+The reaction statistics rank the emoticons, the users reacting and the
+messages reacted to in a conversation, for "wrapped"-style summaries.
+Reactions are not timestamped, so a date range selects the reactions to
+the messages sent in it. The messages the user cleared do not count.
+*/
+package database
+
+import (
+	"context"
+	"time"
+
+	"wasatext/service/ids"
+)
+
+// ReactionStats summarizes the reactions in a conversation
+type ReactionStats struct {
+	Total     int              // reactions in the range
+	Emoticons []EmoticonCount  // most used first
+	Reactors  []ReactorCount   // users reacting the most first
+	Messages  []ReactedMessage // most reacted to first
+}
+
+// EmoticonCount is how many times an emoticon was used
+type EmoticonCount struct {
+	Emoticon string
+	Count    int
+}
+
+// ReactorCount is how many reactions a user gave
+type ReactorCount struct {
+	UserID   ids.UserID
+	UserName string
+	Count    int
+}
+
+// ReactedMessage is a message with how many reactions it got
+type ReactedMessage struct {
+	ID         ids.MessageID
+	SenderID   ids.UserID
+	SenderName string
+	Content    string // the first replyPreviewLength characters
+	HasPhoto   bool
+	Timestamp  time.Time
+	Reactions  int
+}
+
+// reactionsInRange is the FROM clause of the reactions counted by
+// GetReactionStats; its arguments come from rangeArgs
+const reactionsInRange = `
+	FROM comments cm
+	JOIN messages m ON m.id = cm.message_id
+	LEFT JOIN users u ON u.id = cm.user_id
+	LEFT JOIN users s ON s.id = m.sender_id
+	JOIN conversation_participants cp ON cp.conversation_id = m.conversation_id AND cp.user_id = ?
+	WHERE m.conversation_id = ? AND ` + notCleared + `
+	AND (? OR m.timestamp >= ?) AND (? OR m.timestamp < ?)`
+
+// rangeArgs returns the arguments of reactionsInRange. Messages are
+// timestamped in local time, and compared as text.
+func rangeArgs(userID ids.UserID, conversationID ids.ConversationID, from, to time.Time) []interface{} {
+	return []interface{}{userID, conversationID, from.IsZero(), from.Local(), to.IsZero(), to.Local()}
+}
+
+/*
+GetReactionStats ranks the reactions to the messages of a conversation
+sent in [from, to); a zero from or to leaves that side of the range
+open. Each ranking keeps its first limit entries; ties go to the
+emoticon or name first in order, and to the newest message.
+*/
+func (db *appdbimpl) GetReactionStats(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID, from, to time.Time, limit int) (*ReactionStats, error) {
+	if err := db.checkParticipant(ctx, userID, conversationID); err != nil {
+		return nil, err
+	}
+
+	var stats ReactionStats
+	err := db.db.QueryRowContext(ctx, "SELECT COUNT(*)"+reactionsInRange, rangeArgs(userID, conversationID, from, to)...).Scan(&stats.Total)
+	if err != nil {
+		return nil, err
+	}
+	if stats.Emoticons, err = db.topEmoticons(ctx, userID, conversationID, from, to, limit); err != nil {
+		return nil, err
+	}
+	if stats.Reactors, err = db.topReactors(ctx, userID, conversationID, from, to, limit); err != nil {
+		return nil, err
+	}
+	if stats.Messages, err = db.topReactedMessages(ctx, userID, conversationID, from, to, limit); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// topEmoticons returns the emoticons used the most
+func (db *appdbimpl) topEmoticons(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID, from, to time.Time, limit int) ([]EmoticonCount, error) {
+	rows, err := db.db.QueryContext(ctx, `
+		SELECT cm.emoticon, COUNT(*) AS n`+reactionsInRange+`
+		GROUP BY cm.emoticon
+		ORDER BY n DESC, cm.emoticon
+		LIMIT ?
+	`, append(rangeArgs(userID, conversationID, from, to), limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	emoticons := []EmoticonCount{}
+	for rows.Next() {
+		var e EmoticonCount
+		if err := rows.Scan(&e.Emoticon, &e.Count); err != nil {
+			return nil, err
+		}
+		emoticons = append(emoticons, e)
+	}
+	return emoticons, rows.Err()
+}
+
+// topReactors returns the users who reacted the most
+func (db *appdbimpl) topReactors(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID, from, to time.Time, limit int) ([]ReactorCount, error) {
+	rows, err := db.db.QueryContext(ctx, `
+		SELECT cm.user_id, COALESCE(u.name, ''), COUNT(*) AS n`+reactionsInRange+`
+		GROUP BY cm.user_id
+		ORDER BY n DESC, u.name
+		LIMIT ?
+	`, append(rangeArgs(userID, conversationID, from, to), limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reactors := []ReactorCount{}
+	for rows.Next() {
+		var r ReactorCount
+		if err := rows.Scan(&r.UserID, &r.UserName, &r.Count); err != nil {
+			return nil, err
+		}
+		reactors = append(reactors, r)
+	}
+	return reactors, rows.Err()
+}
+
+// topReactedMessages returns the messages with the most reactions
+func (db *appdbimpl) topReactedMessages(ctx context.Context, userID ids.UserID, conversationID ids.ConversationID, from, to time.Time, limit int) ([]ReactedMessage, error) {
+	args := append([]interface{}{replyPreviewLength}, rangeArgs(userID, conversationID, from, to)...)
+	rows, err := db.db.QueryContext(ctx, `
+		SELECT m.id, m.sender_id, COALESCE(m.hook_name, s.name, ''), SUBSTR(COALESCE(m.content, ''), 1, ?),
+			m.photo_id IS NOT NULL, m.timestamp, COUNT(*) AS n`+reactionsInRange+`
+		GROUP BY m.id
+		ORDER BY n DESC, m.timestamp DESC, m.id DESC
+		LIMIT ?
+	`, append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []ReactedMessage{}
+	for rows.Next() {
+		var m ReactedMessage
+		if err := rows.Scan(&m.ID, &m.SenderID, &m.SenderName, &m.Content, &m.HasPhoto, &m.Timestamp, &m.Reactions); err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
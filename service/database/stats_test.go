@@ -0,0 +1,33 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestReactionStatsRangeInUTC(t *testing.T) {
+	inTimeZone(t)
+	ctx := context.Background()
+	db := newTestDB(t)
+	alice := newTestUser(t, db, "alice")
+	bob := newTestUser(t, db, "bob")
+	conversationID, err := db.GetOrCreateDirectConversation(ctx, alice, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	messageID := sendTestMessage(t, db, conversationID, alice, "hello")
+	if err := db.AddComment(ctx, messageID, bob, "👍"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The API parses the range in UTC
+	from, to := time.Now().UTC().Add(-time.Minute), time.Now().UTC().Add(time.Minute)
+	stats, err := db.GetReactionStats(ctx, alice, conversationID, from, to, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Total != 1 {
+		t.Errorf("counted %d reactions to the messages of the last minute, want 1", stats.Total)
+	}
+}
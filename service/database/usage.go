@@ -0,0 +1,75 @@
+/*
+Database operations for usage quotas.
+
+Every user has one row of counters per day (UTC): how many messages
+they sent and how many photos they uploaded, with the time of the last
+one of each. The API compares them with the soft daily limits.
+*/
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"wasatext/service/ids"
+)
+
+// Usage is what a user did on one day
+type Usage struct {
+	Day           string // YYYY-MM-DD, UTC
+	Messages      int
+	Uploads       int
+	LastMessageAt time.Time // zero when there was none that day
+	LastUploadAt  time.Time
+}
+
+// GetUsage returns the counters of a user for a day; all zero when the
+// user did nothing that day
+func (db *appdbimpl) GetUsage(ctx context.Context, userID ids.UserID, day string) (*Usage, error) {
+	usage := Usage{Day: day}
+	var lastMessage, lastUpload sql.NullTime
+
+	err := db.db.QueryRowContext(ctx, `
+		SELECT messages, uploads, last_message_at, last_upload_at
+		FROM usage_counters WHERE user_id = ? AND day = ?
+	`, userID, day).Scan(&usage.Messages, &usage.Uploads, &lastMessage, &lastUpload)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &usage, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	usage.LastMessageAt = lastMessage.Time
+	usage.LastUploadAt = lastUpload.Time
+	return &usage, nil
+}
+
+// RecordUsage adds messages and uploads made at a time to the counters of its day
+func (db *appdbimpl) RecordUsage(ctx context.Context, userID ids.UserID, messages, uploads int, at time.Time) error {
+	var lastMessage, lastUpload interface{}
+	if messages > 0 {
+		lastMessage = at
+	}
+	if uploads > 0 {
+		lastUpload = at
+	}
+
+	_, err := db.db.ExecContext(ctx, `
+		INSERT INTO usage_counters (user_id, day, messages, uploads, last_message_at, last_upload_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (user_id, day) DO UPDATE SET
+			messages = messages + excluded.messages,
+			uploads = uploads + excluded.uploads,
+			last_message_at = COALESCE(excluded.last_message_at, last_message_at),
+			last_upload_at = COALESCE(excluded.last_upload_at, last_upload_at)
+	`, userID, UsageDay(at), messages, uploads, lastMessage, lastUpload)
+	return err
+}
+
+// UsageDay is the day a time counts for (YYYY-MM-DD, UTC)
+func UsageDay(t time.Time) string {
+	return t.UTC().Format(time.DateOnly)
+}
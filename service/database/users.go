@@ -6,49 +6,91 @@ This file contains all the functions that interact with the users table.
 package database
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"log"
+	"time"
 
-	"github.com/gofrs/uuid"
+	"wasatext/service/ids"
 )
 
-// CreateUser creates a new user and returns their ID
+// CreateUser creates a new user in a workspace and returns their ID
 // If the user already exists, returns their existing ID
-func (db *appdbimpl) CreateUser(name string) (string, error) {
-	// First, check if user already exists
-	existingUser, err := db.GetUserByName(name)
-	if err == nil && existingUser != nil {
-		// User exists, return their ID (this is for login)
-		return existingUser.ID, nil
+func (db *appdbimpl) CreateUser(ctx context.Context, workspaceID, name string) (ids.UserID, error) {
+	// The workspace must exist
+	if _, err := db.GetWorkspace(ctx, workspaceID); err != nil {
+		return "", err
+	}
+
+	// First, check if user already exists (this is for login)
+	existingID, err := existingUserID(ctx, db.db, workspaceID, name)
+	if err != nil || existingID != "" {
+		return existingID, err
 	}
 
 	// Generate a new unique ID
-	id, err := uuid.NewV4()
+	id, err := newUserID(ctx, db.db)
 	if err != nil {
 		return "", err
 	}
 
 	// Insert the new user
-	_, err = db.db.Exec(
-		"INSERT INTO users (id, name) VALUES (?, ?)",
-		id.String(), name,
+	_, err = db.db.ExecContext(ctx,
+		"INSERT INTO users (id, workspace_id, name, created_at) VALUES (?, ?, ?, ?)",
+		id, workspaceID, name, time.Now(),
 	)
 	if err != nil {
 		return "", err
 	}
 
-	return id.String(), nil
+	return id, nil
+}
+
+// queryRower is satisfied by both *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
 }
 
-// GetUserByName finds a user by their username
-func (db *appdbimpl) GetUserByName(name string) (*User, error) {
+// existingUserID returns the ID of the user with this name, or "" when
+// there is none. Deleted and banned accounts, and bots, cannot log in.
+func existingUserID(ctx context.Context, q queryRower, workspaceID, name string) (ids.UserID, error) {
+	var id ids.UserID
+	var purgedAt, bannedAt sql.NullTime
+	var bot bool
+	err := q.QueryRowContext(ctx,
+		"SELECT id, purged_at, banned_at, EXISTS (SELECT 1 FROM bots WHERE user_id = users.id) FROM users WHERE workspace_id = ? AND name = ?",
+		workspaceID, name,
+	).Scan(&id, &purgedAt, &bannedAt, &bot)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if purgedAt.Valid {
+		return "", withID(ErrUserDeleted, id)
+	}
+	if bannedAt.Valid {
+		return "", withID(ErrUserBanned, id)
+	}
+	if bot {
+		return "", withID(ErrBotAccount, id)
+	}
+	return id, nil
+}
+
+// GetUserByName finds a user by their username within a workspace
+func (db *appdbimpl) GetUserByName(ctx context.Context, workspaceID, name string) (*User, error) {
 	var user User
 	var photo sql.NullString
+	var createdAt, lastSeen sql.NullTime
 
-	err := db.db.QueryRow(
-		"SELECT id, name, photo FROM users WHERE name = ?",
-		name,
-	).Scan(&user.ID, &user.Name, &photo)
+	err := db.db.QueryRowContext(ctx,
+		"SELECT id, workspace_id, name, photo_id, created_at, last_seen, hide_presence, about FROM users WHERE workspace_id = ? AND name = ? AND purged_at IS NULL",
+		workspaceID, name,
+	).Scan(&user.ID, &user.WorkspaceID, &user.Name, &photo, &createdAt, &lastSeen, &user.HidePresence, &user.About)
 
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrUserNotFound
@@ -58,48 +100,67 @@ func (db *appdbimpl) GetUserByName(name string) (*User, error) {
 	}
 
 	if photo.Valid {
-		user.Photo = []byte(photo.String)
+		user.PhotoID = photo.String
+	}
+	if createdAt.Valid {
+		user.CreatedAt = createdAt.Time
 	}
+	user.readLastSeen(lastSeen)
 
 	return &user, nil
 }
 
 // GetUserByID finds a user by their ID
-func (db *appdbimpl) GetUserByID(id string) (*User, error) {
+func (db *appdbimpl) GetUserByID(ctx context.Context, id ids.UserID) (*User, error) {
 	var user User
 	var photo sql.NullString
+	var createdAt, lastSeen sql.NullTime
 
-	err := db.db.QueryRow(
-		"SELECT id, name, photo FROM users WHERE id = ?",
+	err := db.db.QueryRowContext(ctx,
+		`SELECT id, workspace_id, name, photo_id, created_at, last_seen, hide_presence, about,
+			EXISTS (SELECT 1 FROM bots WHERE user_id = users.id)
+		FROM users WHERE id = ? AND purged_at IS NULL`,
 		id,
-	).Scan(&user.ID, &user.Name, &photo)
+	).Scan(&user.ID, &user.WorkspaceID, &user.Name, &photo, &createdAt, &lastSeen, &user.HidePresence, &user.About, &user.Bot)
 
 	if errors.Is(err, sql.ErrNoRows) {
-		return nil, ErrUserNotFound
+		return nil, withID(ErrUserNotFound, id)
 	}
 	if err != nil {
 		return nil, err
 	}
 
 	if photo.Valid {
-		user.Photo = []byte(photo.String)
+		user.PhotoID = photo.String
+	}
+	if createdAt.Valid {
+		user.CreatedAt = createdAt.Time
 	}
+	user.readLastSeen(lastSeen)
 
 	return &user, nil
 }
 
 // UpdateUserName changes a user's username
 // Returns error if the new name is already taken
-func (db *appdbimpl) UpdateUserName(userID, newName string) error {
-	// Check if name is already taken by another user
-	existingUser, err := db.GetUserByName(newName)
-	if err == nil && existingUser != nil && existingUser.ID != userID {
+func (db *appdbimpl) UpdateUserName(ctx context.Context, userID ids.UserID, newName string) error {
+	// Check if name is already taken by another user of the same workspace
+	// (names of deleted accounts stay reserved until they are purged)
+	var existingID ids.UserID
+	err := db.db.QueryRowContext(ctx, `
+		SELECT id FROM users
+		WHERE name = ? AND workspace_id = (SELECT workspace_id FROM users WHERE id = ?)
+	`, newName, userID).Scan(&existingID)
+	if err == nil && existingID != userID {
 		return ErrUsernameTaken
 	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
 
 	// Update the username
-	result, err := db.db.Exec(
-		"UPDATE users SET name = ? WHERE id = ?",
+	result, err := db.db.ExecContext(ctx,
+		"UPDATE users SET name = ? WHERE id = ? AND purged_at IS NULL",
 		newName, userID,
 	)
 	if err != nil {
@@ -112,48 +173,64 @@ func (db *appdbimpl) UpdateUserName(userID, newName string) error {
 		return err
 	}
 	if rowsAffected == 0 {
-		return ErrUserNotFound
+		return withID(ErrUserNotFound, userID)
 	}
 
 	return nil
 }
 
-// UpdateUserPhoto sets or updates a user's profile photo
-func (db *appdbimpl) UpdateUserPhoto(userID string, photo []byte) error {
-	result, err := db.db.Exec(
-		"UPDATE users SET photo = ? WHERE id = ?",
-		photo, userID,
+// UpdateUserAbout changes the status line of a user's profile
+func (db *appdbimpl) UpdateUserAbout(ctx context.Context, userID ids.UserID, about string) error {
+	result, err := db.db.ExecContext(ctx,
+		"UPDATE users SET about = ? WHERE id = ? AND purged_at IS NULL",
+		about, userID,
 	)
 	if err != nil {
 		return err
 	}
-
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
 	if rowsAffected == 0 {
-		return ErrUserNotFound
+		return withID(ErrUserNotFound, userID)
+	}
+	return nil
+}
+
+// UpdateUserPhoto sets or updates a user's profile photo
+func (db *appdbimpl) UpdateUserPhoto(ctx context.Context, userID ids.UserID, photo []byte) error {
+	found, err := db.replacePhoto(ctx, "users", "id = ? AND purged_at IS NULL", userID, photo)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return withID(ErrUserNotFound, userID)
 	}
 
 	return nil
 }
 
-// SearchUsers finds users matching a search query
-// If query is empty, returns all users
-func (db *appdbimpl) SearchUsers(query string) ([]User, error) {
+// SearchUsers finds users matching a search query, among the active users
+// of the requester's workspace. If query is empty, returns all of them.
+func (db *appdbimpl) SearchUsers(ctx context.Context, requesterID ids.UserID, query string) ([]User, error) {
 	var rows *sql.Rows
 	var err error
 
 	if query == "" {
 		// Return all users
-		rows, err = db.db.Query("SELECT id, name, photo FROM users ORDER BY name")
+		rows, err = db.db.QueryContext(ctx, `
+			SELECT id, name, photo_id, id IN (SELECT blocked_id FROM user_blocks WHERE blocker_id = ?), last_seen, hide_presence, about FROM users
+			WHERE purged_at IS NULL AND deactivated_at IS NULL AND workspace_id = (SELECT workspace_id FROM users WHERE id = ?)
+			ORDER BY name
+		`, requesterID, requesterID)
 	} else {
 		// Search by partial name match
-		rows, err = db.db.Query(
-			"SELECT id, name, photo FROM users WHERE name LIKE ? ORDER BY name",
-			"%"+query+"%",
-		)
+		rows, err = db.db.QueryContext(ctx, `
+			SELECT id, name, photo_id, id IN (SELECT blocked_id FROM user_blocks WHERE blocker_id = ?), last_seen, hide_presence, about FROM users
+			WHERE name LIKE ? AND purged_at IS NULL AND deactivated_at IS NULL AND workspace_id = (SELECT workspace_id FROM users WHERE id = ?)
+			ORDER BY name
+		`, requesterID, "%"+query+"%", requesterID)
 	}
 
 	if err != nil {
@@ -165,17 +242,81 @@ func (db *appdbimpl) SearchUsers(query string) ([]User, error) {
 	for rows.Next() {
 		var user User
 		var photo sql.NullString
+		var lastSeen sql.NullTime
 
-		if err := rows.Scan(&user.ID, &user.Name, &photo); err != nil {
+		if err := rows.Scan(&user.ID, &user.Name, &photo, &user.Blocked, &lastSeen, &user.HidePresence, &user.About); err != nil {
 			return nil, err
 		}
 
 		if photo.Valid {
-			user.Photo = []byte(photo.String)
+			user.PhotoID = photo.String
 		}
+		user.readLastSeen(lastSeen)
 
 		users = append(users, user)
 	}
 
 	return users, rows.Err()
 }
+
+// DeleteUser marks an account as deleted.
+// Personal data is kept until the purge job removes it after the
+// retention window (see PurgeDeletedUsers).
+func (db *appdbimpl) DeleteUser(ctx context.Context, userID ids.UserID) error {
+	result, err := db.db.ExecContext(ctx,
+		"UPDATE users SET purged_at = ? WHERE id = ? AND purged_at IS NULL",
+		time.Now(), userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return withID(ErrUserNotFound, userID)
+	}
+
+	return nil
+}
+
+/*
+DeactivateUser deactivates an account until its user logs in again
+(see CreateSession): unlike DeleteUser nothing is to be purged. Their
+sessions are closed, other users no longer find them in the search and
+see the account deactivated in their conversations, and their reminders
+and scheduled messages wait until they come back.
+*/
+func (db *appdbimpl) DeactivateUser(ctx context.Context, userID ids.UserID) error {
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+	}()
+
+	result, err := tx.ExecContext(ctx,
+		"UPDATE users SET deactivated_at = COALESCE(deactivated_at, ?) WHERE id = ? AND purged_at IS NULL",
+		time.Now(), userID,
+	)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return withID(ErrUserNotFound, userID)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM sessions WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
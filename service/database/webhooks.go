@@ -0,0 +1,348 @@
+/*
+Database operations for outbound webhooks.
+
+An outbound webhook is a URL told of events by signed POSTs (see
+api/webhooks.go), the reverse of the inbound hooks of hooks.go. The
+admin of a group registers webhooks for the events of the group; the
+admin of the server registers global ones, told of the events of every
+group and workspace, and of those of no group such as new accounts. A
+bot has a webhook of its own, told of the events of its conversations
+(see bots.go).
+
+Every event for a webhook is a delivery, queued here: the dispatcher
+takes the due ones, posts them and records the attempt, and a failed
+delivery waits for its next attempt. Finished deliveries are kept for a
+while as the log of the webhook.
+*/
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"wasatext/service/ids"
+)
+
+// MaxWebhooks is how many webhooks a group, or the server, can have
+const MaxWebhooks = 10
+
+// States of a delivery
+const (
+	DeliveryPending   = "pending"   // waiting for its first or next attempt
+	DeliveryDelivered = "delivered" // the URL answered 2xx
+	DeliveryFailed    = "failed"    // given up
+)
+
+// Webhook is a URL told of events
+type Webhook struct {
+	ID        int64
+	GroupID   ids.GroupID // "" for a global webhook
+	BotID     ids.UserID  // the bot of a bot's webhook, "" for any other
+	URL       string
+	Secret    string   // signs the deliveries
+	Events    []string // the events it is told of
+	CreatedBy ids.UserID
+	CreatedAt time.Time
+}
+
+// WebhookDelivery is an event on its way to a webhook
+type WebhookDelivery struct {
+	ID            int64
+	WebhookID     int64
+	Event         string
+	Payload       []byte
+	State         string // DeliveryPending, DeliveryDelivered, DeliveryFailed
+	Attempts      int
+	NextAttemptAt *time.Time // nil once finished
+	LastStatus    int        // the HTTP status of the last attempt, 0 if it got none
+	LastError     string     // why the last attempt failed, "" if it did not
+	CreatedAt     time.Time
+	FinishedAt    *time.Time
+
+	// The webhook, for sending (DueWebhookDeliveries only)
+	URL     string
+	Secret  string
+	GroupID ids.GroupID
+	BotID   ids.UserID
+}
+
+// WebhookAttempt is the outcome of a delivery attempt
+type WebhookAttempt struct {
+	State         string     // the state of the delivery after it
+	Status        int        // the HTTP status, 0 if none
+	Error         string     // "" if it succeeded
+	NextAttemptAt *time.Time // when to try again, for a delivery still pending
+	At            time.Time
+}
+
+// webhookScope matches the webhooks of a group, or the global ones for
+// ""; the webhooks of bots are neither
+const webhookScope = "(bot_id IS NULL AND ((? = '' AND group_id IS NULL) OR group_id = ?))"
+
+// newWebhookSecret returns a secret to sign deliveries with; it must not
+// be guessable
+func newWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+/*
+CreateWebhook registers a webhook for the events of a group, or a global
+one for groupID "". Its secret is generated. A group, like the server,
+has at most MaxWebhooks (409 past that).
+*/
+func (db *appdbimpl) CreateWebhook(ctx context.Context, groupID ids.GroupID, createdBy ids.UserID, url string, events []string) (*Webhook, error) {
+	var count int
+	err := db.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM outbound_webhooks WHERE "+webhookScope,
+		groupID, groupID,
+	).Scan(&count)
+	if err != nil {
+		return nil, err
+	}
+	if count >= MaxWebhooks {
+		return nil, withID(ErrTooManyWebhooks, groupID)
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+	webhook := Webhook{
+		GroupID:   groupID,
+		URL:       url,
+		Secret:    secret,
+		Events:    events,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+	}
+	result, err := db.db.ExecContext(ctx, `
+		INSERT INTO outbound_webhooks (group_id, url, secret, events, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, sql.NullString{String: string(groupID), Valid: groupID != ""}, url, webhook.Secret, strings.Join(events, " "),
+		sql.NullString{String: string(createdBy), Valid: createdBy != ""}, webhook.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if webhook.ID, err = result.LastInsertId(); err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// ListWebhooks returns the webhooks of a group, or the global ones for
+// groupID "", the oldest first
+func (db *appdbimpl) ListWebhooks(ctx context.Context, groupID ids.GroupID) ([]Webhook, error) {
+	rows, err := db.db.QueryContext(ctx, `
+		SELECT id, COALESCE(group_id, ''), url, secret, events, COALESCE(created_by, ''), created_at
+		FROM outbound_webhooks
+		WHERE `+webhookScope+`
+		ORDER BY id
+	`, groupID, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []Webhook
+	for rows.Next() {
+		var w Webhook
+		var events string
+		if err := rows.Scan(&w.ID, &w.GroupID, &w.URL, &w.Secret, &events, &w.CreatedBy, &w.CreatedAt); err != nil {
+			return nil, err
+		}
+		w.Events = strings.Fields(events)
+		webhooks = append(webhooks, w)
+	}
+	return webhooks, rows.Err()
+}
+
+// DeleteWebhook deletes a webhook of a group (a global one for groupID
+// ""), with its deliveries
+func (db *appdbimpl) DeleteWebhook(ctx context.Context, groupID ids.GroupID, webhookID int64) error {
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+	}()
+
+	result, err := tx.ExecContext(ctx,
+		"DELETE FROM outbound_webhooks WHERE id = ? AND "+webhookScope,
+		webhookID, groupID, groupID,
+	)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return withID(ErrWebhookNotFound, strconv.FormatInt(webhookID, 10))
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM webhook_deliveries WHERE webhook_id = ?", webhookID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// deliverySQL selects the deliveries; the caller adds the rest of the query
+const deliverySQL = `
+	SELECT d.id, d.webhook_id, d.event, d.payload, d.state, d.attempts, d.next_attempt_at,
+		d.last_status, d.last_error, d.created_at, d.finished_at, w.url, w.secret, COALESCE(w.group_id, ''),
+		COALESCE(w.bot_id, '')
+	FROM webhook_deliveries d
+	JOIN outbound_webhooks w ON w.id = d.webhook_id`
+
+// queryDeliveries runs deliverySQL with the rest of the query
+func (db *appdbimpl) queryDeliveries(ctx context.Context, query string, args ...interface{}) ([]WebhookDelivery, error) {
+	rows, err := db.db.QueryContext(ctx, deliverySQL+query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []WebhookDelivery
+	for rows.Next() {
+		var d WebhookDelivery
+		var next, finished sql.NullTime
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.Event, &d.Payload, &d.State, &d.Attempts, &next,
+			&d.LastStatus, &d.LastError, &d.CreatedAt, &finished, &d.URL, &d.Secret, &d.GroupID, &d.BotID)
+		if err != nil {
+			return nil, err
+		}
+		if next.Valid {
+			d.NextAttemptAt = &next.Time
+		}
+		if finished.Valid {
+			d.FinishedAt = &finished.Time
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// GetWebhookDeliveries returns the last deliveries of a webhook of a
+// group (a global one for groupID ""), the most recent first
+func (db *appdbimpl) GetWebhookDeliveries(ctx context.Context, groupID ids.GroupID, webhookID int64, limit int) ([]WebhookDelivery, error) {
+	var exists bool
+	err := db.db.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM outbound_webhooks WHERE id = ? AND "+webhookScope+")",
+		webhookID, groupID, groupID,
+	).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, withID(ErrWebhookNotFound, strconv.FormatInt(webhookID, 10))
+	}
+
+	return db.webhookLog(ctx, webhookID, limit)
+}
+
+// webhookLog returns the last deliveries of a webhook, without the webhook
+func (db *appdbimpl) webhookLog(ctx context.Context, webhookID int64, limit int) ([]WebhookDelivery, error) {
+	deliveries, err := db.queryDeliveries(ctx, " WHERE d.webhook_id = ? ORDER BY d.id DESC LIMIT ?", webhookID, limit)
+	for i := range deliveries {
+		deliveries[i].URL, deliveries[i].Secret = "", ""
+	}
+	return deliveries, err
+}
+
+/*
+QueueWebhookEvent queues an event for the webhooks told of it: the global
+ones, those of its group, given by groupID or else by the group of
+conversationID (none when both are ""), and those of the bots taking part
+in the conversation or the group, but for the bot that is the actor: a
+bot is not told of its own messages. It returns how many deliveries were
+queued. The times are stored in the local time zone, like time.Now(), so
+that they compare as text.
+*/
+func (db *appdbimpl) QueueWebhookEvent(ctx context.Context, event string, groupID ids.GroupID, conversationID ids.ConversationID, actor ids.UserID, payload []byte, now time.Time) (int64, error) {
+	result, err := db.db.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event, payload, state, next_attempt_at, created_at)
+		SELECT id, ?, ?, ?, ?, ? FROM outbound_webhooks
+		WHERE instr(' ' || events || ' ', ' ' || ? || ' ') > 0
+		AND ((bot_id IS NULL AND (group_id IS NULL OR group_id = ?
+				OR group_id = (SELECT group_id FROM conversations WHERE id = ? AND is_group = 1)))
+			OR (bot_id IS NOT NULL AND bot_id != ? AND EXISTS (
+				SELECT 1 FROM conversation_participants p
+				JOIN conversations c ON c.id = p.conversation_id
+				WHERE p.user_id = bot_id AND (c.id = ? OR (c.is_group = 1 AND c.group_id = ?)))))
+	`, event, payload, DeliveryPending, now.Local(), now.Local(), event, groupID, conversationID,
+		actor, conversationID, groupID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DueWebhookDeliveries returns up to limit deliveries due at now, the
+// longest waiting first, with their webhook
+func (db *appdbimpl) DueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]WebhookDelivery, error) {
+	return db.queryDeliveries(ctx, `
+		WHERE d.state = ? AND d.next_attempt_at <= ?
+		ORDER BY d.next_attempt_at, d.id LIMIT ?
+	`, DeliveryPending, now.Local(), limit)
+}
+
+// NextWebhookDelivery returns when the next delivery is due, nil when
+// none is pending
+func (db *appdbimpl) NextWebhookDelivery(ctx context.Context) (*time.Time, error) {
+	var next time.Time
+	err := db.db.QueryRowContext(ctx,
+		"SELECT next_attempt_at FROM webhook_deliveries WHERE state = ? ORDER BY next_attempt_at LIMIT 1",
+		DeliveryPending,
+	).Scan(&next)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &next, nil
+}
+
+// RecordWebhookAttempt records an attempt to send a delivery; a delivery
+// that is no longer pending is finished at the time of the attempt
+func (db *appdbimpl) RecordWebhookAttempt(ctx context.Context, deliveryID int64, attempt WebhookAttempt) error {
+	var next, finished sql.NullTime
+	if attempt.State == DeliveryPending && attempt.NextAttemptAt != nil {
+		next = sql.NullTime{Time: attempt.NextAttemptAt.Local(), Valid: true}
+	} else {
+		finished = sql.NullTime{Time: attempt.At.Local(), Valid: true}
+	}
+	_, err := db.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET state = ?, attempts = attempts + 1, last_status = ?, last_error = ?, next_attempt_at = ?, finished_at = ?
+		WHERE id = ?
+	`, attempt.State, attempt.Status, attempt.Error, next, finished, deliveryID)
+	return err
+}
+
+// PruneWebhookDeliveries deletes the deliveries finished before a time,
+// and returns how many
+func (db *appdbimpl) PruneWebhookDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	result, err := db.db.ExecContext(ctx,
+		"DELETE FROM webhook_deliveries WHERE state != ? AND finished_at < ?",
+		DeliveryPending, before.Local(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
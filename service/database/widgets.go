@@ -0,0 +1,160 @@
+/*
+Database operations for widget tokens.
+
+A participant can mint a widget token for a conversation, to embed a
+read-only view of it in another site (e.g. a support chat). The token
+reads the conversation on behalf of the user who minted it, and nothing
+else: the API checks its scope on every request. A token stops working
+when it expires, when its creator leaves the conversation or loses the
+account, and can be revoked by its creator at any time.
+*/
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"wasatext/service/ids"
+)
+
+// widgetTokenPrefix marks widget tokens, like the session and hook tokens
+const widgetTokenPrefix = "widget-"
+
+// WidgetToken grants read access to one conversation, as its creator
+type WidgetToken struct {
+	Token          string
+	ConversationID ids.ConversationID
+	Name           string // what the token is for, e.g. the site embedding it
+	CreatedBy      ids.UserID
+	CreatedAt      time.Time
+	ExpiresAt      *time.Time // nil means the token never expires
+}
+
+// IsWidgetToken reports whether a bearer token is a widget token
+func IsWidgetToken(token string) bool {
+	return len(token) > len(widgetTokenPrefix) && token[:len(widgetTokenPrefix)] == widgetTokenPrefix
+}
+
+// CreateWidgetToken mints a widget token for a conversation the user takes part in
+func (db *appdbimpl) CreateWidgetToken(ctx context.Context, conversationID ids.ConversationID, createdBy ids.UserID, name string, expiresAt *time.Time) (*WidgetToken, error) {
+	if err := db.checkParticipant(ctx, createdBy, conversationID); err != nil {
+		return nil, err
+	}
+
+	// Widget tokens are bearer credentials, so they must not be guessable
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+
+	wt := WidgetToken{
+		Token:          widgetTokenPrefix + hex.EncodeToString(buf),
+		ConversationID: conversationID,
+		Name:           name,
+		CreatedBy:      createdBy,
+		CreatedAt:      time.Now(),
+		ExpiresAt:      expiresAt,
+	}
+	_, err := db.db.ExecContext(ctx,
+		"INSERT INTO widget_tokens (token, conversation_id, name, created_by, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?)",
+		wt.Token, wt.ConversationID, wt.Name, wt.CreatedBy, wt.CreatedAt, wt.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &wt, nil
+}
+
+// ListWidgetTokens returns the widget tokens a user minted for a conversation, oldest first
+func (db *appdbimpl) ListWidgetTokens(ctx context.Context, conversationID ids.ConversationID, createdBy ids.UserID) ([]WidgetToken, error) {
+	if err := db.checkParticipant(ctx, createdBy, conversationID); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.db.QueryContext(ctx, `
+		SELECT token, conversation_id, name, created_by, created_at, expires_at
+		FROM widget_tokens
+		WHERE conversation_id = ? AND created_by = ?
+		ORDER BY created_at
+	`, conversationID, createdBy)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []WidgetToken
+	for rows.Next() {
+		var wt WidgetToken
+		var expiresAt sql.NullTime
+		if err := rows.Scan(&wt.Token, &wt.ConversationID, &wt.Name, &wt.CreatedBy, &wt.CreatedAt, &expiresAt); err != nil {
+			return nil, err
+		}
+		if expiresAt.Valid {
+			wt.ExpiresAt = &expiresAt.Time
+		}
+		tokens = append(tokens, wt)
+	}
+
+	return tokens, rows.Err()
+}
+
+/*
+GetWidgetToken finds a usable widget token. Expired tokens, tokens whose
+creator left the conversation and tokens of deleted or banned accounts
+are reported as not found.
+*/
+func (db *appdbimpl) GetWidgetToken(ctx context.Context, token string) (*WidgetToken, error) {
+	if !IsWidgetToken(token) {
+		return nil, ErrWidgetTokenNotFound
+	}
+
+	var wt WidgetToken
+	var expiresAt sql.NullTime
+	err := db.db.QueryRowContext(ctx, `
+		SELECT w.token, w.conversation_id, w.name, w.created_by, w.created_at, w.expires_at
+		FROM widget_tokens w
+		JOIN conversation_participants cp ON cp.conversation_id = w.conversation_id AND cp.user_id = w.created_by
+		JOIN users u ON u.id = w.created_by
+		WHERE w.token = ? AND u.purged_at IS NULL AND u.banned_at IS NULL
+	`, token).Scan(&wt.Token, &wt.ConversationID, &wt.Name, &wt.CreatedBy, &wt.CreatedAt, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWidgetTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if expiresAt.Valid {
+		if time.Now().After(expiresAt.Time) {
+			return nil, ErrWidgetTokenNotFound
+		}
+		wt.ExpiresAt = &expiresAt.Time
+	}
+	return &wt, nil
+}
+
+// DeleteWidgetToken revokes a widget token of a conversation (only its creator can)
+func (db *appdbimpl) DeleteWidgetToken(ctx context.Context, conversationID ids.ConversationID, createdBy ids.UserID, token string) error {
+	result, err := db.db.ExecContext(ctx,
+		"DELETE FROM widget_tokens WHERE token = ? AND conversation_id = ? AND created_by = ?",
+		token, conversationID, createdBy,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrWidgetTokenNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+/*
+Database operations for Workspaces.
+
+A workspace is an independent community hosted on the same server.
+Users, groups and conversations belong to exactly one workspace, and
+nothing is ever visible across workspaces. Data created before
+workspaces existed lives in the default workspace.
+*/
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// DefaultWorkspaceID is the workspace used when none is chosen
+const DefaultWorkspaceID = "default"
+
+// Workspace represents an independent community
+type Workspace struct {
+	ID        string
+	Name      string
+	CreatedAt time.Time
+}
+
+// ListWorkspaces returns every workspace, sorted by name
+func (db *appdbimpl) ListWorkspaces(ctx context.Context) ([]Workspace, error) {
+	rows, err := db.db.QueryContext(ctx, "SELECT id, name, created_at FROM workspaces ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var workspaces []Workspace
+	for rows.Next() {
+		var ws Workspace
+		if err := rows.Scan(&ws.ID, &ws.Name, &ws.CreatedAt); err != nil {
+			return nil, err
+		}
+		workspaces = append(workspaces, ws)
+	}
+
+	return workspaces, rows.Err()
+}
+
+// GetWorkspace finds a workspace by its ID
+func (db *appdbimpl) GetWorkspace(ctx context.Context, id string) (*Workspace, error) {
+	var ws Workspace
+	err := db.db.QueryRowContext(ctx,
+		"SELECT id, name, created_at FROM workspaces WHERE id = ?",
+		id,
+	).Scan(&ws.ID, &ws.Name, &ws.CreatedAt)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, withID(ErrWorkspaceNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &ws, nil
+}
+
+// CreateWorkspace creates a new workspace
+func (db *appdbimpl) CreateWorkspace(ctx context.Context, id, name string) (*Workspace, error) {
+	var count int
+	err := db.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM workspaces WHERE id = ? OR name = ?",
+		id, name,
+	).Scan(&count)
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, withID(ErrWorkspaceExists, id)
+	}
+
+	ws := Workspace{ID: id, Name: name, CreatedAt: time.Now()}
+	_, err = db.db.ExecContext(ctx,
+		"INSERT INTO workspaces (id, name, created_at) VALUES (?, ?, ?)",
+		ws.ID, ws.Name, ws.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ws, nil
+}
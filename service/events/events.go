@@ -0,0 +1,105 @@
+/*
+Package events is the in-process event bus of the server.
+
+The API handlers publish what their writes changed, once the database
+committed them: a message was created, a reaction added or removed, a
+member joined a group. What follows from it is done by the subscribers
+of the event, not by the handler: the real-time events of the clients,
+the push notifications, the webhooks and the Matrix bridge (see
+service/api). A handler that writes a message thus does not need to
+know who else is told; a new side effect subscribes instead of being
+wired into every handler that creates messages.
+
+Publish calls the subscribers of an event one after the other, in the
+order they subscribed, in the goroutine of the publisher and with its
+context: a subscriber that is slow queues its work for a worker. Errors
+are the subscribers' own to log; the write that raised the event
+happened anyway.
+
+The moderation and deletion audit logs are not subscribers: they are
+written in the transaction of the action they record, which an event
+published after the commit could not guarantee.
+*/
+package events
+
+import (
+	"context"
+	"sync"
+
+	"wasatext/service/database"
+	"wasatext/service/ids"
+)
+
+// Event is an event of the bus
+type Event interface {
+	// Name tells the type of event, e.g. "message.created"
+	Name() string
+}
+
+// MessageCreated is a new message, of any sender: a user, a bot, a
+// webhook or the server (a notice)
+type MessageCreated struct {
+	Message database.Message
+}
+
+func (MessageCreated) Name() string { return "message.created" }
+
+// ReactionAdded is a reaction of a user to a message
+type ReactionAdded struct {
+	ConversationID ids.ConversationID
+	MessageID      ids.MessageID
+	UserID         ids.UserID
+	Emoticon       string
+}
+
+func (ReactionAdded) Name() string { return "reaction.added" }
+
+// ReactionRemoved is a user taking back their reactions to a message
+type ReactionRemoved struct {
+	ConversationID ids.ConversationID
+	MessageID      ids.MessageID
+	UserID         ids.UserID
+	Emoticon       string // "" when all of them were taken back
+}
+
+func (ReactionRemoved) Name() string { return "reaction.removed" }
+
+// MemberJoined is a user added to a group, by themselves or by a member
+type MemberJoined struct {
+	GroupID ids.GroupID
+	UserID  ids.UserID
+	AddedBy ids.UserID // UserID when they joined by themselves
+}
+
+func (MemberJoined) Name() string { return "member.joined" }
+
+// Bus hands the events published to their subscribers
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[string][]func(context.Context, Event)
+}
+
+// NewBus returns a bus without subscribers
+func NewBus() *Bus {
+	return &Bus{subscribers: make(map[string][]func(context.Context, Event))}
+}
+
+// Subscribe calls handler with every event of type E published on the bus
+func Subscribe[E Event](b *Bus, handler func(context.Context, E)) {
+	var zero E
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers[zero.Name()] = append(b.subscribers[zero.Name()], func(ctx context.Context, event Event) {
+		handler(ctx, event.(E))
+	})
+}
+
+// Publish calls the subscribers of an event, and returns once they all did
+func (b *Bus) Publish(ctx context.Context, event Event) {
+	b.mu.RLock()
+	subscribers := b.subscribers[event.Name()]
+	b.mu.RUnlock()
+	for _, handler := range subscribers {
+		handler(ctx, event)
+	}
+}
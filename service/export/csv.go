@@ -0,0 +1,138 @@
+package export
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"wasatext/service/database"
+)
+
+// ErrUnknownField is returned when a requested CSV field does not exist
+var ErrUnknownField = errors.New("unknown field")
+
+// csvFlushEvery is how many rows are buffered before they are sent out
+const csvFlushEvery = 100
+
+// Column is one CSV column: its header and how to get its value from a row
+type Column[T any] struct {
+	Name  string
+	Value func(T) string
+}
+
+// UserColumns are the columns of the users export, in default order
+var UserColumns = []Column[database.UserReportRow]{
+	{"id", func(r database.UserReportRow) string { return string(r.ID) }},
+	{"workspace", func(r database.UserReportRow) string { return r.WorkspaceID }},
+	{"name", func(r database.UserReportRow) string { return r.Name }},
+	{"has_photo", func(r database.UserReportRow) string { return strconv.FormatBool(r.HasPhoto) }},
+	{"created_at", func(r database.UserReportRow) string { return formatTime(r.CreatedAt) }},
+	{"deleted_at", func(r database.UserReportRow) string { return formatTime(r.DeletedAt) }},
+	{"banned_at", func(r database.UserReportRow) string { return formatTime(r.BannedAt) }},
+	{"messages_sent", func(r database.UserReportRow) string { return strconv.Itoa(r.MessagesSent) }},
+}
+
+// ActivityColumns are the columns of the activity export, in default order
+var ActivityColumns = []Column[database.ActivityReportRow]{
+	{"timestamp", func(r database.ActivityReportRow) string { return r.Timestamp.UTC().Format(time.RFC3339) }},
+	{"workspace", func(r database.ActivityReportRow) string { return r.WorkspaceID }},
+	{"user_id", func(r database.ActivityReportRow) string { return string(r.UserID) }},
+	{"user_name", func(r database.ActivityReportRow) string { return r.UserName }},
+	{"conversation_id", func(r database.ActivityReportRow) string { return string(r.ConversationID) }},
+	{"is_group", func(r database.ActivityReportRow) string { return strconv.FormatBool(r.IsGroup) }},
+	{"message_id", func(r database.ActivityReportRow) string { return string(r.MessageID) }},
+	{"has_photo", func(r database.ActivityReportRow) string { return strconv.FormatBool(r.HasPhoto) }},
+	{"is_reply", func(r database.ActivityReportRow) string { return strconv.FormatBool(r.IsReply) }},
+}
+
+// SelectColumns picks the named columns, in the order given.
+// With no names, every column is returned.
+func SelectColumns[T any](all []Column[T], names []string) ([]Column[T], error) {
+	if len(names) == 0 {
+		return all, nil
+	}
+
+	selected := make([]Column[T], 0, len(names))
+	for _, name := range names {
+		found := false
+		for _, col := range all {
+			if col.Name == strings.TrimSpace(name) {
+				selected = append(selected, col)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownField, name)
+		}
+	}
+	return selected, nil
+}
+
+// CSVWriter streams rows as CSV, flushing every few rows
+type CSVWriter[T any] struct {
+	w       *csv.Writer
+	flush   func()
+	columns []Column[T]
+	pending int
+}
+
+/*
+NewCSVWriter writes the header line and returns a writer for the rows.
+flush, if not nil, is called after each batch of rows is written to w
+(pass the http.Flusher of the response to stream it).
+*/
+func NewCSVWriter[T any](w io.Writer, columns []Column[T], flush func()) (*CSVWriter[T], error) {
+	cw := &CSVWriter[T]{w: csv.NewWriter(w), flush: flush, columns: columns}
+
+	header := make([]string, len(columns))
+	for i, col := range columns {
+		header[i] = col.Name
+	}
+	if err := cw.w.Write(header); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+// Write writes one row
+func (cw *CSVWriter[T]) Write(row T) error {
+	record := make([]string, len(cw.columns))
+	for i, col := range cw.columns {
+		record[i] = col.Value(row)
+	}
+	if err := cw.w.Write(record); err != nil {
+		return err
+	}
+
+	cw.pending++
+	if cw.pending >= csvFlushEvery {
+		return cw.Flush()
+	}
+	return nil
+}
+
+// Flush sends out the buffered rows
+func (cw *CSVWriter[T]) Flush() error {
+	cw.w.Flush()
+	if err := cw.w.Error(); err != nil {
+		return err
+	}
+	if cw.flush != nil {
+		cw.flush()
+	}
+	cw.pending = 0
+	return nil
+}
+
+// formatTime formats an optional time, empty when not set
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
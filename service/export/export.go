@@ -0,0 +1,87 @@
+/*
+Package export turns server data into files for admins.
+
+Conversations are rendered into standalone archives: a single HTML file
+with everything inlined, the styles and a thumbnail of every photo (as
+a data: URL), so it can be stored for compliance and opened years later
+without the server. Users and activity are exported as CSV (csv.go).
+*/
+package export
+
+import (
+	"embed"
+	"encoding/base64"
+	"html/template"
+	"io"
+	"time"
+
+	"wasatext/service/database"
+	"wasatext/service/imaging"
+)
+
+// ThumbnailSize is the longest side of an inlined thumbnail, in pixels
+const ThumbnailSize = 320
+
+//go:embed templates/*.html
+var templateFiles embed.FS
+
+var conversationTemplate = template.Must(
+	template.New("conversation.html").ParseFS(templateFiles, "templates/conversation.html"),
+)
+
+// archive is the data passed to the conversation template
+type archive struct {
+	Conversation *database.Conversation
+	ExportedAt   time.Time
+	Messages     []archiveMessage
+}
+
+// PhotoLoader returns the bytes of a photo by media ID, like database.AppDatabase.GetPhoto
+// bound to a context
+type PhotoLoader func(photoID string) ([]byte, error)
+
+// archiveMessage is a message with its thumbnail ready for the template
+type archiveMessage struct {
+	database.Message
+	Thumbnail template.URL // empty when the message has no (readable) photo
+}
+
+/*
+WriteHTML writes a conversation as a standalone HTML archive.
+
+Messages are written oldest first, the way a transcript is read.
+Photos are read with loadPhoto; those that cannot be read or decoded
+are noted in the archive instead of failing the whole export.
+*/
+func WriteHTML(w io.Writer, conv *database.Conversation, loadPhoto PhotoLoader, exportedAt time.Time) error {
+	data := archive{
+		Conversation: conv,
+		ExportedAt:   exportedAt,
+	}
+
+	// The database returns messages newest first
+	for i := len(conv.Messages) - 1; i >= 0; i-- {
+		msg := archiveMessage{Message: conv.Messages[i]}
+		if msg.PhotoID != "" {
+			if photo, err := loadPhoto(msg.PhotoID); err == nil {
+				if thumb, err := thumbnail(photo, ThumbnailSize); err == nil {
+					msg.Thumbnail = thumb
+				}
+			}
+		}
+		data.Messages = append(data.Messages, msg)
+	}
+
+	return conversationTemplate.Execute(w, data)
+}
+
+// thumbnail scales a photo down to fit in size x size and returns it as a JPEG data: URL
+func thumbnail(photo []byte, size int) (template.URL, error) {
+	thumb, err := imaging.Fit(photo, size)
+	if err != nil {
+		return "", err
+	}
+
+	// The URL is built from our own encoder output, so it is safe to mark as trusted
+	return template.URL("data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(thumb)), nil //nolint:gosec
+}
@@ -0,0 +1,183 @@
+/*
+Password-protected zip archives.
+
+An export can be wrapped in a zip encrypted with the WinZip AES scheme
+(AE-2, AES-256), which 7-Zip, WinZip and libarchive open after asking
+for the password, so the file can be emailed or stored safely:
+
+  - PBKDF2-HMAC-SHA1 (1000 rounds) turns the password and a random salt
+    into the AES key, the HMAC key and a 2-byte password check;
+  - the deflated file is encrypted with AES in counter mode (the counter
+    is little-endian and starts at 1);
+  - HMAC-SHA1 over the encrypted data, cut to 10 bytes, authenticates it.
+
+AE-2 stores no CRC, so the archive leaks nothing about the plain file.
+*/
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"crypto/aes"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec // required by the WinZip AES format
+	"errors"
+	"io"
+	"time"
+)
+
+// MinPasswordLength is the shortest password accepted for an encrypted export
+const MinPasswordLength = 8
+
+// ErrPasswordTooShort is returned for passwords shorter than MinPasswordLength
+var ErrPasswordTooShort = errors.New("password too short")
+
+// WinZip AES parameters (AES-256)
+const (
+	aesKeyLength    = 32
+	aesSaltLength   = 16
+	aesVerifyLength = 2
+	aesMacLength    = 10
+	aesIterations   = 1000
+
+	methodWinZipAES = 99     // compression method of an AES entry
+	aesExtraID      = 0x9901 // extra field describing the AES entry
+	zipVersionAES   = 51     // "version needed to extract" for AES
+)
+
+/*
+WriteEncryptedZip writes a zip archive holding a single file, encrypted
+with the password.
+
+The whole file is in memory already (exports are rendered before they
+are sent), so the archive is built with known sizes and no data
+descriptor.
+*/
+func WriteEncryptedZip(w io.Writer, name string, content []byte, password string, modified time.Time) error {
+	if len(password) < MinPasswordLength {
+		return ErrPasswordTooShort
+	}
+
+	// Step 1: Compress
+	var compressed bytes.Buffer
+	fw, err := flate.NewWriter(&compressed, flate.DefaultCompression)
+	if err != nil {
+		return err
+	}
+	if _, err := fw.Write(content); err != nil {
+		return err
+	}
+	if err := fw.Close(); err != nil {
+		return err
+	}
+
+	// Step 2: Encrypt and authenticate with keys from the password and
+	// a random salt
+	salt := make([]byte, aesSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	ciphertext := compressed.Bytes()
+	verifier, authCode, err := encryptAES(password, salt, ciphertext)
+	if err != nil {
+		return err
+	}
+
+	// Step 3: Write the archive
+	size := aesSaltLength + aesVerifyLength + len(ciphertext) + aesMacLength
+	header := &zip.FileHeader{
+		Name:               name,
+		Method:             methodWinZipAES,
+		Flags:              0x1, // encrypted
+		ReaderVersion:      zipVersionAES,
+		CreatorVersion:     zipVersionAES,
+		CompressedSize64:   uint64(size),
+		UncompressedSize64: uint64(len(content)),
+		Extra:              aesExtra(),
+	}
+	header.ModifiedDate, header.ModifiedTime = msDosTime(modified)
+
+	zw := zip.NewWriter(w)
+	entry, err := zw.CreateRaw(header)
+	if err != nil {
+		return err
+	}
+	for _, part := range [][]byte{salt, verifier, ciphertext, authCode} {
+		if _, err := entry.Write(part); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// encryptAES encrypts data in place with the keys derived from the
+// password and salt, and returns the password check and the
+// authentication code of the encrypted data
+func encryptAES(password string, salt, data []byte) (verifier, authCode []byte, err error) {
+	keys, err := pbkdf2.Key(sha1.New, password, salt, aesIterations, 2*aesKeyLength+aesVerifyLength)
+	if err != nil {
+		return nil, nil, err
+	}
+	aesKey := keys[:aesKeyLength]
+	macKey := keys[aesKeyLength : 2*aesKeyLength]
+	verifier = keys[2*aesKeyLength:]
+
+	if err := aesCTR(aesKey, data); err != nil {
+		return nil, nil, err
+	}
+	mac := hmac.New(sha1.New, macKey)
+	mac.Write(data)
+	return verifier, mac.Sum(nil)[:aesMacLength], nil
+}
+
+// aesExtra is the AES extra field: AE-2, vendor "AE", AES-256, deflated
+func aesExtra() []byte {
+	return []byte{
+		byte(aesExtraID & 0xff), byte(aesExtraID >> 8),
+		7, 0, // size of the data below
+		2, 0, // AE-2
+		'A', 'E',
+		3,                                         // AES-256
+		byte(zip.Deflate), byte(zip.Deflate >> 8), // actual compression method
+	}
+}
+
+// aesCTR encrypts data in place with AES in the WinZip counter mode:
+// a 128-bit little-endian counter starting at 1
+func aesCTR(key, data []byte) error {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+
+	var counter, stream [aes.BlockSize]byte
+	for offset := 0; offset < len(data); offset += aes.BlockSize {
+		for i := range counter {
+			counter[i]++
+			if counter[i] != 0 {
+				break
+			}
+		}
+		block.Encrypt(stream[:], counter[:])
+
+		end := min(offset+aes.BlockSize, len(data))
+		for i := offset; i < end; i++ {
+			data[i] ^= stream[i-offset]
+		}
+	}
+	return nil
+}
+
+// msDosTime converts a time to the MS-DOS date and time of zip headers
+func msDosTime(t time.Time) (date, clock uint16) {
+	t = t.UTC()
+	if t.Year() < 1980 {
+		t = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	date = uint16(t.Day() + int(t.Month())<<5 + (t.Year()-1980)<<9) //nolint:gosec // fits by construction
+	clock = uint16(t.Second()/2 + t.Minute()<<5 + t.Hour()<<11)     //nolint:gosec // fits by construction
+	return date, clock
+}
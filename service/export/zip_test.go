@@ -0,0 +1,167 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha1" //nolint:gosec // required by the WinZip AES format
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestEncryptAESKnownAnswer checks the encryption of a deflate stored
+// block against values that libarchive (bsdtar --passphrase) accepted,
+// MAC included, in an archive built around them
+func TestEncryptAESKnownAnswer(t *testing.T) {
+	salt := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+	data := append([]byte{1, 16, 0, 0xef, 0xff}, "WASAText export\n"...)
+
+	verifier, authCode, err := encryptAES("correct horse", salt, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name string
+		got  []byte
+		want string
+	}{
+		{"verifier", verifier, "2d15"},
+		{"ciphertext", data, "94f0426f6b0bf99c35c725e053c8d0f74e90070141"},
+		{"authentication code", authCode, "68ec5f03da58855f4e0a"},
+	} {
+		if got := hex.EncodeToString(tt.got); got != tt.want {
+			t.Errorf("%s = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestWriteEncryptedZip reads an archive back the way an unzip tool
+// does: the extra field, then the salt, password check and MAC
+func TestWriteEncryptedZip(t *testing.T) {
+	const password = "correct horse battery"
+	content := []byte(strings.Repeat("Alice: see you at the station at 9\n", 200))
+	modified := time.Date(2024, 3, 9, 14, 30, 22, 0, time.UTC)
+
+	var archive bytes.Buffer
+	if err := WriteEncryptedZip(&archive, "export.txt", content, password, modified); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(archive.Bytes()), int64(archive.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zr.File) != 1 {
+		t.Fatalf("got %d files, want 1", len(zr.File))
+	}
+	f := zr.File[0]
+	if f.Name != "export.txt" || f.Method != methodWinZipAES || f.Flags&0x1 == 0 || f.ReaderVersion != zipVersionAES {
+		t.Errorf("header: name %q, method %d, flags %#x, version %d", f.Name, f.Method, f.Flags, f.ReaderVersion)
+	}
+	if f.UncompressedSize64 != uint64(len(content)) || f.CRC32 != 0 {
+		t.Errorf("header: size %d, crc %#x, want %d and no CRC (AE-2)", f.UncompressedSize64, f.CRC32, len(content))
+	}
+	if !f.Modified.Equal(modified) {
+		t.Errorf("modified %v, want %v", f.Modified, modified)
+	}
+
+	// The AE-x extra field
+	extra := findExtra(t, f.Extra, aesExtraID)
+	if len(extra) != 7 {
+		t.Fatalf("AES extra field of %d bytes, want 7", len(extra))
+	}
+	if version := binary.LittleEndian.Uint16(extra); version != 2 {
+		t.Errorf("AE-%d, want AE-2", version)
+	}
+	if vendor := string(extra[2:4]); vendor != "AE" {
+		t.Errorf("vendor %q, want AE", vendor)
+	}
+	if strength := extra[4]; strength != 3 {
+		t.Errorf("strength %d, want 3 (AES-256)", strength)
+	}
+	if method := binary.LittleEndian.Uint16(extra[5:]); method != zip.Deflate {
+		t.Errorf("actual method %d, want deflate", method)
+	}
+
+	// Salt, password check, encrypted data and MAC
+	rc, err := f.OpenRaw()
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(raw) < aesSaltLength+aesVerifyLength+aesMacLength {
+		t.Fatalf("entry of %d bytes", len(raw))
+	}
+	salt := raw[:aesSaltLength]
+	verifier := raw[aesSaltLength : aesSaltLength+aesVerifyLength]
+	ciphertext := raw[aesSaltLength+aesVerifyLength : len(raw)-aesMacLength]
+	authCode := raw[len(raw)-aesMacLength:]
+
+	keys, err := pbkdf2.Key(sha1.New, password, salt, aesIterations, 2*aesKeyLength+aesVerifyLength)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(verifier, keys[2*aesKeyLength:]) {
+		t.Errorf("password check %x, want %x", verifier, keys[2*aesKeyLength:])
+	}
+	mac := hmac.New(sha1.New, keys[aesKeyLength:2*aesKeyLength])
+	mac.Write(ciphertext)
+	if want := mac.Sum(nil)[:aesMacLength]; !bytes.Equal(authCode, want) {
+		t.Errorf("authentication code %x, want %x", authCode, want)
+	}
+
+	// The counter mode is its own inverse
+	if err := aesCTR(keys[:aesKeyLength], ciphertext); err != nil {
+		t.Fatal(err)
+	}
+	plain, err := io.ReadAll(flate.NewReader(bytes.NewReader(ciphertext)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plain, content) {
+		t.Error("decrypted content differs")
+	}
+
+	// Every archive has its own salt
+	var again bytes.Buffer
+	if err := WriteEncryptedZip(&again, "export.txt", content, password, modified); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(again.Bytes(), archive.Bytes()) {
+		t.Error("two archives of the same content are identical")
+	}
+}
+
+func TestWriteEncryptedZipShortPassword(t *testing.T) {
+	err := WriteEncryptedZip(io.Discard, "export.txt", []byte("hi"), "short", time.Now())
+	if !errors.Is(err, ErrPasswordTooShort) {
+		t.Errorf("got %v, want ErrPasswordTooShort", err)
+	}
+}
+
+// findExtra returns the data of the extra field with the ID
+func findExtra(t *testing.T, extra []byte, id uint16) []byte {
+	t.Helper()
+	for len(extra) >= 4 {
+		fieldID := binary.LittleEndian.Uint16(extra)
+		size := int(binary.LittleEndian.Uint16(extra[2:]))
+		if len(extra) < 4+size {
+			t.Fatalf("extra field %#x truncated", fieldID)
+		}
+		if fieldID == id {
+			return extra[4 : 4+size]
+		}
+		extra = extra[4+size:]
+	}
+	t.Fatalf("no extra field %#x", id)
+	return nil
+}
@@ -0,0 +1,764 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// validator checks an operation against the schema before it runs
+type validator struct {
+	doc       *document
+	defined   map[string]*variableDef
+	spreading map[string]bool // the fragments being expanded, to catch cycles
+	errors    []*Error
+	fields    int
+	maxDepth  int
+	maxFields int
+	aborted   bool // a limit was exceeded: the rest is not checked
+}
+
+// validate returns the errors of the operation, none if it can run
+func (s *Schema) validate(doc *document, op *operation) []*Error {
+	v := &validator{
+		doc:       doc,
+		defined:   make(map[string]*variableDef),
+		spreading: make(map[string]bool),
+		maxDepth:  s.MaxDepth,
+		maxFields: s.MaxFields,
+	}
+	if v.maxDepth == 0 {
+		v.maxDepth = DefaultMaxDepth
+	}
+	if v.maxFields == 0 {
+		v.maxFields = DefaultMaxFields
+	}
+
+	for _, def := range op.variables {
+		if v.defined[def.name] != nil {
+			v.errorf(def.loc, "There can be only one variable named \"$%s\".", def.name)
+			continue
+		}
+		v.defined[def.name] = def
+		t := def.typ.toType()
+		if t == nil {
+			v.errorf(def.loc, "Variable \"$%s\" must be of a scalar type or a list of one, not %q.", def.name, def.typ)
+			continue
+		}
+		if def.defVal != nil {
+			v.checkValue(t, def.defVal)
+		}
+	}
+	for _, d := range op.directives {
+		v.errorf(d.loc, "Directive \"@%s\" may not be used on QUERY.", d.name)
+	}
+	v.selectionSet(s.Query, op.selections, 1)
+	return v.errors
+}
+
+func (v *validator) errorf(loc Location, format string, args ...any) {
+	v.errors = append(v.errors, newErrorf(loc, format, args...))
+}
+
+func (v *validator) selectionSet(obj *Object, selections []selection, depth int) {
+	for _, sel := range selections {
+		if v.aborted {
+			return
+		}
+		switch sel := sel.(type) {
+		case *field:
+			v.field(obj, sel, depth)
+		case *inlineFragment:
+			v.directives(sel.directives)
+			if sel.typeCond != "" && sel.typeCond != obj.Name {
+				v.errorf(sel.loc, "Fragment cannot be spread here as objects of type %q can never be of type %q.", obj.Name, sel.typeCond)
+				continue
+			}
+			v.selectionSet(obj, sel.selections, depth)
+		case *fragmentSpread:
+			v.directives(sel.directives)
+			frag := v.doc.fragments[sel.name]
+			switch {
+			case frag == nil:
+				v.errorf(sel.loc, "Unknown fragment %q.", sel.name)
+			case v.spreading[sel.name]:
+				v.errorf(sel.loc, "Cannot spread fragment %q within itself.", sel.name)
+			case frag.typeCond != obj.Name:
+				v.errorf(sel.loc, "Fragment %q cannot be spread here as objects of type %q can never be of type %q.", sel.name, obj.Name, frag.typeCond)
+			default:
+				v.directives(frag.directives)
+				v.spreading[sel.name] = true
+				v.selectionSet(obj, frag.selections, depth)
+				delete(v.spreading, sel.name)
+			}
+		}
+	}
+}
+
+func (v *validator) field(obj *Object, f *field, depth int) {
+	// Step 1: Apply the limits
+	v.fields++
+	if v.fields > v.maxFields {
+		v.errorf(f.loc, "The query selects too many fields: at most %d are allowed, fragments expanded.", v.maxFields)
+		v.aborted = true
+		return
+	}
+	if depth > v.maxDepth {
+		v.errorf(f.loc, "The query is nested too deeply: at most %d levels of fields are allowed.", v.maxDepth)
+		v.aborted = true
+		return
+	}
+	v.directives(f.directives)
+
+	// Step 2: Check the field and its arguments
+	if f.name == "__typename" {
+		if len(f.args) > 0 || f.selections != nil {
+			v.errorf(f.loc, "Field \"__typename\" takes no arguments and has no subfields.")
+		}
+		return
+	}
+	def := obj.field(f.name)
+	if def == nil {
+		v.errorf(f.loc, "Cannot query field %q on type %q.", f.name, obj.Name)
+		return
+	}
+	for _, arg := range f.args {
+		argDef := findArg(def.Args, arg.name)
+		if argDef == nil {
+			v.errorf(arg.loc, "Unknown argument %q on field \"%s.%s\".", arg.name, obj.Name, f.name)
+			continue
+		}
+		v.checkValue(argDef.Type, arg.val)
+	}
+	for _, argDef := range def.Args {
+		if _, required := argDef.Type.(*NonNull); required && argDef.Default == nil && findArgument(f.args, argDef.Name) == nil {
+			v.errorf(f.loc, "Field %q argument %q of type %q is required, but it was not provided.", f.name, argDef.Name, argDef.Type)
+		}
+	}
+
+	// Step 3: Check its subfields
+	child, isObject := namedType(def.Type).(*Object)
+	switch {
+	case isObject && f.selections == nil:
+		v.errorf(f.loc, "Field %q of type %q must have a selection of subfields.", f.name, def.Type)
+	case !isObject && f.selections != nil:
+		v.errorf(f.loc, "Field %q must not have a selection since type %q has no subfields.", f.name, def.Type)
+	case isObject:
+		v.selectionSet(child, f.selections, depth+1)
+	}
+}
+
+// directives checks the @skip and @include directives
+func (v *validator) directives(directives []*directive) {
+	for _, d := range directives {
+		if d.name != "skip" && d.name != "include" {
+			v.errorf(d.loc, "Unknown directive \"@%s\".", d.name)
+			continue
+		}
+		condition := findArgument(d.args, "if")
+		if len(d.args) != 1 || condition == nil {
+			v.errorf(d.loc, "Directive \"@%s\" takes one argument, \"if\" of type \"Boolean!\".", d.name)
+			continue
+		}
+		v.checkValue(NonNullOf(Boolean), condition.val)
+	}
+}
+
+// checkValue checks a value written for an argument of type t
+func (v *validator) checkValue(t Type, val *value) {
+	if val.kind == variableValue {
+		def := v.defined[val.raw]
+		if def == nil {
+			v.errorf(val.loc, "Variable \"$%s\" is not defined.", val.raw)
+			return
+		}
+		if varType := def.typ.toType(); varType != nil && !fits(varType, def.defVal != nil && def.defVal.kind != nullValue, t) {
+			v.errorf(val.loc, "Variable \"$%s\" of type %q used in position expecting type %q.", val.raw, def.typ, t)
+		}
+		return
+	}
+	inner := t
+	if nonNull, ok := t.(*NonNull); ok {
+		inner = nonNull.Of
+	}
+	if list, ok := inner.(*List); ok && val.kind == listValue {
+		for _, item := range val.list {
+			v.checkValue(list.Of, item)
+		}
+		return
+	}
+	if _, err := coerceLiteral(t, val, nil); err != nil {
+		v.errorf(val.loc, "%s", err)
+	}
+}
+
+// fits reports whether a variable of type varType can be used where a
+// value of type t is expected
+func fits(varType Type, hasDefault bool, t Type) bool {
+	if nonNull, ok := t.(*NonNull); ok {
+		if varNonNull, ok := varType.(*NonNull); ok {
+			return fits(varNonNull.Of, false, nonNull.Of)
+		}
+		return hasDefault && fits(varType, false, nonNull.Of)
+	}
+	if varNonNull, ok := varType.(*NonNull); ok {
+		return fits(varNonNull.Of, false, t)
+	}
+	if list, ok := t.(*List); ok {
+		varList, ok := varType.(*List)
+		return ok && fits(varList.Of, false, list.Of)
+	}
+	return varType == t
+}
+
+// toType returns the type a variable is declared with, nil if it is not
+// a scalar or a list of scalars
+func (t *typeRef) toType() Type {
+	var result Type
+	if t.elem != nil {
+		elem := t.elem.toType()
+		if elem == nil {
+			return nil
+		}
+		result = ListOf(elem)
+	} else {
+		for _, scalar := range []*Scalar{String, Int, Float, Boolean, ID} {
+			if scalar.Name == t.name {
+				result = scalar
+			}
+		}
+		if result == nil {
+			return nil
+		}
+	}
+	if t.nonNull {
+		return NonNullOf(result)
+	}
+	return result
+}
+
+func findArg(args []*Arg, name string) *Arg {
+	for _, arg := range args {
+		if arg.Name == name {
+			return arg
+		}
+	}
+	return nil
+}
+
+func findArgument(args []*argument, name string) *argument {
+	for _, arg := range args {
+		if arg.name == name {
+			return arg
+		}
+	}
+	return nil
+}
+
+// coerceVariables returns the values of the variables of the operation:
+// those given, or their defaults
+func coerceVariables(op *operation, input map[string]any) (map[string]any, []*Error) {
+	vars := make(map[string]any)
+	var errs []*Error
+	for _, def := range op.variables {
+		t := def.typ.toType()
+		raw, given := input[def.name]
+		var err error
+		switch {
+		case given:
+			vars[def.name], err = coerceJSON(t, raw)
+		case def.defVal != nil:
+			vars[def.name], err = coerceLiteral(t, def.defVal, nil)
+		case def.typ.nonNull:
+			err = fmt.Errorf("variable of required type %q was not provided", def.typ)
+		}
+		if err != nil {
+			errs = append(errs, newErrorf(def.loc, "Variable \"$%s\" got an invalid value: %s.", def.name, strings.TrimSuffix(err.Error(), ".")))
+		}
+	}
+	return vars, errs
+}
+
+// coerceJSON converts the JSON value of a variable to its type
+func coerceJSON(t Type, raw any) (any, error) {
+	if nonNull, ok := t.(*NonNull); ok {
+		if raw == nil {
+			return nil, fmt.Errorf("expected a value of type %q, found null", t)
+		}
+		return coerceJSON(nonNull.Of, raw)
+	}
+	if raw == nil {
+		return nil, nil
+	}
+	if list, ok := t.(*List); ok {
+		items, isList := raw.([]any)
+		if !isList {
+			item, err := coerceJSON(list.Of, raw)
+			return []any{item}, err
+		}
+		result := make([]any, 0, len(items))
+		for _, item := range items {
+			coerced, err := coerceJSON(list.Of, item)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, coerced)
+		}
+		return result, nil
+	}
+
+	number, isNumber := raw.(float64)
+	switch t {
+	case String:
+		if s, ok := raw.(string); ok {
+			return s, nil
+		}
+	case ID:
+		if s, ok := raw.(string); ok {
+			return s, nil
+		}
+		if isNumber && number == math.Trunc(number) {
+			return strconv.FormatFloat(number, 'f', -1, 64), nil
+		}
+	case Int:
+		if isNumber && number == math.Trunc(number) && number >= math.MinInt32 && number <= math.MaxInt32 {
+			return int(number), nil
+		}
+	case Float:
+		if isNumber {
+			return number, nil
+		}
+	case Boolean:
+		if b, ok := raw.(bool); ok {
+			return b, nil
+		}
+	}
+	data, _ := json.Marshal(raw)
+	return nil, fmt.Errorf("%s cannot represent %s", t, data)
+}
+
+// coerceLiteral converts a value written in the query to type t; vars
+// are the values of the variables
+func coerceLiteral(t Type, val *value, vars map[string]any) (any, error) {
+	if val.kind == variableValue {
+		result := vars[val.raw]
+		if _, required := t.(*NonNull); required && result == nil {
+			return nil, errorf("Expected a value of type %q, found null.", t)
+		}
+		return result, nil
+	}
+	switch t := t.(type) {
+	case *NonNull:
+		if val.kind == nullValue {
+			return nil, errorf("Expected a value of type %q, found null.", t)
+		}
+		return coerceLiteral(t.Of, val, vars)
+	case *List:
+		if val.kind == nullValue {
+			return nil, nil
+		}
+		if val.kind != listValue {
+			item, err := coerceLiteral(t.Of, val, vars)
+			if err != nil {
+				return nil, err
+			}
+			return []any{item}, nil
+		}
+		items := make([]any, 0, len(val.list))
+		for _, item := range val.list {
+			coerced, err := coerceLiteral(t.Of, item, vars)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, coerced)
+		}
+		return items, nil
+	}
+
+	if val.kind == nullValue {
+		return nil, nil
+	}
+	switch {
+	case t == String && val.kind == stringValue:
+		return val.raw, nil
+	case t == ID && (val.kind == stringValue || val.kind == intValue):
+		return val.raw, nil
+	case t == Int && val.kind == intValue:
+		if n, err := strconv.ParseInt(val.raw, 10, 32); err == nil {
+			return int(n), nil
+		}
+	case t == Float && (val.kind == intValue || val.kind == floatValue):
+		if f, err := strconv.ParseFloat(val.raw, 64); err == nil {
+			return f, nil
+		}
+	case t == Boolean && val.kind == booleanValue:
+		return val.raw == "true", nil
+	}
+	return nil, errorf("Expected a value of type %q, found %s.", t, val)
+}
+
+// String writes the value back as in the query
+func (v *value) String() string {
+	switch v.kind {
+	case variableValue:
+		return "$" + v.raw
+	case stringValue:
+		return strconv.Quote(v.raw)
+	case nullValue:
+		return "null"
+	case listValue:
+		items := make([]string, 0, len(v.list))
+		for _, item := range v.list {
+			items = append(items, item.String())
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case objectValue:
+		fields := make([]string, 0, len(v.fields))
+		for _, f := range v.fields {
+			fields = append(fields, f.name+": "+f.val.String())
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	}
+	return v.raw
+}
+
+// executor runs a validated operation
+type executor struct {
+	doc    *document
+	vars   map[string]any
+	errors []*Error
+}
+
+func (e *executor) addError(err error, loc Location, path []any) {
+	var gqlErr *Error
+	if errors.As(err, &gqlErr) {
+		err := *gqlErr
+		if len(err.Locations) == 0 {
+			err.Locations = []Location{loc}
+		}
+		err.Path = path
+		e.errors = append(e.errors, &err)
+		return
+	}
+	e.errors = append(e.errors, &Error{Message: err.Error(), Locations: []Location{loc}, Path: path})
+}
+
+/*
+selectionSet resolves the fields of source, an object of type obj. It
+returns false when a non-null field is null: the object is then null,
+and the error is recorded.
+*/
+func (e *executor) selectionSet(ctx context.Context, obj *Object, source any, selections []selection, path []any) (any, bool) {
+	var keys []string
+	groups := make(map[string][]*field)
+	e.collectFields(selections, &keys, groups, make(map[string]bool))
+
+	result := &orderedMap{}
+	for _, key := range keys {
+		value, ok := e.field(ctx, obj, source, groups[key], appendPath(path, key))
+		if !ok {
+			return nil, false
+		}
+		result.keys = append(result.keys, key)
+		result.values = append(result.values, value)
+	}
+	return result, true
+}
+
+// collectFields groups the fields selected by their response key, in the
+// order of the query, leaving out those skipped by a directive
+func (e *executor) collectFields(selections []selection, keys *[]string, groups map[string][]*field, visited map[string]bool) {
+	for _, sel := range selections {
+		switch sel := sel.(type) {
+		case *field:
+			if !e.included(sel.directives) {
+				continue
+			}
+			if _, ok := groups[sel.alias]; !ok {
+				*keys = append(*keys, sel.alias)
+			}
+			groups[sel.alias] = append(groups[sel.alias], sel)
+		case *inlineFragment:
+			if e.included(sel.directives) {
+				e.collectFields(sel.selections, keys, groups, visited)
+			}
+		case *fragmentSpread:
+			if visited[sel.name] || !e.included(sel.directives) {
+				continue
+			}
+			visited[sel.name] = true
+			frag := e.doc.fragments[sel.name]
+			if e.included(frag.directives) {
+				e.collectFields(frag.selections, keys, groups, visited)
+			}
+		}
+	}
+}
+
+// included evaluates the @skip and @include directives
+func (e *executor) included(directives []*directive) bool {
+	for _, d := range directives {
+		condition, _ := coerceLiteral(NonNullOf(Boolean), findArgument(d.args, "if").val, e.vars)
+		if (d.name == "skip") == (condition == true) {
+			return false
+		}
+	}
+	return true
+}
+
+// field resolves the fields of one response key; it returns false when
+// the field is non-null and could not be resolved
+func (e *executor) field(ctx context.Context, obj *Object, source any, fields []*field, path []any) (any, bool) {
+	f := fields[0]
+	if f.name == "__typename" {
+		return obj.Name, true
+	}
+	def := obj.field(f.name)
+	_, required := def.Type.(*NonNull)
+
+	args, err := e.arguments(def, f)
+	if err != nil {
+		e.addError(err, f.loc, path)
+		return nil, !required
+	}
+	var result any
+	if def.Resolve != nil {
+		result, err = def.Resolve(ctx, source, args)
+	} else {
+		result, err = defaultResolve(source, def.Name)
+	}
+	if err != nil {
+		e.addError(err, f.loc, path)
+		return nil, !required
+	}
+	return e.complete(ctx, def.Type, fields, result, path)
+}
+
+// arguments returns the arguments of a field, with the defaults of those
+// left out; a variable that was not given counts as left out
+func (e *executor) arguments(def *Field, f *field) (map[string]any, error) {
+	args := make(map[string]any, len(def.Args))
+	for _, argDef := range def.Args {
+		given := findArgument(f.args, argDef.Name)
+		if given != nil && given.val.kind == variableValue {
+			if _, ok := e.vars[given.val.raw]; !ok {
+				given = nil
+			}
+		}
+		if given == nil {
+			if argDef.Default != nil {
+				args[argDef.Name] = argDef.Default
+			} else if _, required := argDef.Type.(*NonNull); required {
+				return nil, errorf("Argument %q of required type %q was not provided.", argDef.Name, argDef.Type)
+			}
+			continue
+		}
+		value, err := coerceLiteral(argDef.Type, given.val, e.vars)
+		if err != nil {
+			return nil, errorf("Argument %q has an invalid value: %s", argDef.Name, err)
+		}
+		args[argDef.Name] = value
+	}
+	return args, nil
+}
+
+// complete turns the value of a field into its result; it returns false
+// when the field is non-null and its value is null
+func (e *executor) complete(ctx context.Context, t Type, fields []*field, value any, path []any) (any, bool) {
+	nonNull, required := t.(*NonNull)
+	if !required {
+		result, ok := e.completeValue(ctx, t, fields, value, path)
+		if !ok {
+			return nil, true
+		}
+		return result, true
+	}
+	result, ok := e.completeValue(ctx, nonNull.Of, fields, value, path)
+	if !ok {
+		return nil, false
+	}
+	if result == nil {
+		e.addError(errorf("Cannot return null for non-nullable field %q.", fields[0].name), fields[0].loc, path)
+		return nil, false
+	}
+	return result, true
+}
+
+// completeValue completes a value of a nullable type; it returns false
+// when a non-null field or item inside it is null
+func (e *executor) completeValue(ctx context.Context, t Type, fields []*field, value any, path []any) (any, bool) {
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, true
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil, true
+	}
+
+	switch t := t.(type) {
+	case *List:
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			e.addError(fmt.Errorf("expected a list for field %q, got %T", fields[0].name, value), fields[0].loc, path)
+			return nil, false
+		}
+		items := make([]any, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			item, ok := e.complete(ctx, t.Of, fields, rv.Index(i).Interface(), appendPath(path, i))
+			if !ok {
+				return nil, false
+			}
+			items = append(items, item)
+		}
+		return items, true
+	case *Object:
+		var selections []selection
+		for _, f := range fields {
+			selections = append(selections, f.selections...)
+		}
+		return e.selectionSet(ctx, t, value, selections, path)
+	case *Scalar:
+		result, err := serialize(t, rv)
+		if err != nil {
+			e.addError(err, fields[0].loc, path)
+			return nil, false
+		}
+		return result, true
+	}
+	return nil, false
+}
+
+// serialize returns the JSON value of a scalar
+func serialize(t *Scalar, rv reflect.Value) (any, error) {
+	switch kind := rv.Kind(); {
+	case kind == reflect.String && (t == String || t == ID):
+		return rv.String(), nil
+	case kind == reflect.Bool && t == Boolean:
+		return rv.Bool(), nil
+	case rv.CanInt() && t == ID:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case rv.CanInt() && t == Int && rv.Int() >= math.MinInt32 && rv.Int() <= math.MaxInt32:
+		return rv.Int(), nil
+	case rv.CanUint() && t == Int && rv.Uint() <= math.MaxInt32:
+		return rv.Uint(), nil
+	case rv.CanInt() && t == Float:
+		return float64(rv.Int()), nil
+	case rv.CanFloat() && t == Float:
+		return rv.Float(), nil
+	}
+	return nil, fmt.Errorf("%s cannot represent the value %v", t, rv.Interface())
+}
+
+// defaultResolve reads a field that has no resolver from its source
+func defaultResolve(source any, name string) (any, error) {
+	rv := reflect.ValueOf(source)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			entry := rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()))
+			if !entry.IsValid() {
+				return nil, nil
+			}
+			return entry.Interface(), nil
+		}
+	case reflect.Struct:
+		if f, ok := structFields(rv.Type())[name]; ok {
+			fieldValue, err := rv.FieldByIndexErr(f.index)
+			if err != nil {
+				return nil, nil // through a nil embedded pointer
+			}
+			if f.omitEmpty && fieldValue.Kind() == reflect.String && fieldValue.Len() == 0 {
+				return nil, nil
+			}
+			return fieldValue.Interface(), nil
+		}
+	}
+	return nil, fmt.Errorf("field %q cannot be read from %T", name, source)
+}
+
+// structField is a field of a struct type read by defaultResolve
+type structField struct {
+	index     []int
+	omitEmpty bool // tagged omitempty: an empty string is null
+}
+
+// structFieldCache holds the fields of the struct types read by
+// defaultResolve, by name
+var structFieldCache sync.Map // reflect.Type -> map[string]structField
+
+// structFields returns the fields of a struct type by JSON name, or by
+// Go name starting with a lowercase letter
+func structFields(t reflect.Type) map[string]structField {
+	if cached, ok := structFieldCache.Load(t); ok {
+		return cached.(map[string]structField)
+	}
+	fields := make(map[string]structField)
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+		name, options, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name[:1]) + f.Name[1:]
+		}
+		fields[name] = structField{index: f.Index, omitEmpty: strings.Contains(","+options+",", ",omitempty,")}
+	}
+	structFieldCache.Store(t, fields)
+	return fields
+}
+
+// appendPath returns a copy of path with one more element: the paths of
+// the errors must not share their arrays
+func appendPath(path []any, elem any) []any {
+	result := make([]any, len(path)+1)
+	copy(result, path)
+	result[len(path)] = elem
+	return result
+}
+
+// orderedMap is an object of the result, its keys in the order of the query
+type orderedMap struct {
+	keys   []string
+	values []any
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		keyJSON, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		valueJSON, err := json.Marshal(m.values[i])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(keyJSON)
+		b.WriteByte(':')
+		b.Write(valueJSON)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
@@ -0,0 +1,332 @@
+/*
+Package graphql is a small GraphQL implementation: it runs queries
+against a schema of object types whose fields are resolved by Go
+functions.
+
+The API serves GraphQL so that clients fetch the fields they show and
+nothing more. Only what that needs is implemented:
+
+  - queries, with variables, aliases, fragments, inline fragments and
+    the @include and @skip directives; no mutations or subscriptions,
+    the REST API changes the data;
+  - object, list and non-null types and the built-in scalars; no
+    interfaces, unions, enums or input objects;
+  - __typename, but no other introspection: Schema.String prints the
+    schema in the GraphQL schema language instead.
+
+A query is validated before it runs (execute.go), and may nest at most
+MaxDepth fields and select at most MaxFields fields, fragments expanded.
+*/
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Default limits of a query
+const (
+	DefaultMaxDepth  = 10
+	DefaultMaxFields = 500
+)
+
+// Type is a *Scalar, *Object, *List or *NonNull
+type Type interface {
+	String() string
+}
+
+// Scalar is a built-in scalar type
+type Scalar struct {
+	Name string
+}
+
+// The built-in scalars. Resolvers return them as any Go string, integer,
+// float or bool type (or a pointer to one, nil for null); arguments are
+// passed to resolvers as string, int, float64 and bool.
+var (
+	String  = &Scalar{Name: "String"}
+	Int     = &Scalar{Name: "Int"}
+	Float   = &Scalar{Name: "Float"}
+	Boolean = &Scalar{Name: "Boolean"}
+	ID      = &Scalar{Name: "ID"}
+)
+
+func (s *Scalar) String() string { return s.Name }
+
+// Object is an object type. Its fields are set after it is created when
+// types refer to each other.
+type Object struct {
+	Name        string
+	Description string
+	Fields      []*Field
+}
+
+func (o *Object) String() string { return o.Name }
+
+// field returns the field of the given name, nil if there is none
+func (o *Object) field(name string) *Field {
+	for _, f := range o.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+/*
+ResolveFunc returns the value of a field of source, the value of the
+parent field (nil for the fields of Query). args holds the arguments
+given in the query, and the defaults of those left out.
+*/
+type ResolveFunc func(ctx context.Context, source any, args map[string]any) (any, error)
+
+/*
+Field is a field of an object type. When Resolve is nil the field is
+read from the source: the struct field of the same JSON name (or Go name
+starting with a lowercase letter), or the map entry of that name. Like
+in JSON, an empty string in a field tagged omitempty is left out: it is
+null.
+*/
+type Field struct {
+	Name        string
+	Description string
+	Type        Type
+	Args        []*Arg
+	Resolve     ResolveFunc
+}
+
+// Arg is an argument of a field; Default is used when it is left out
+type Arg struct {
+	Name        string
+	Description string
+	Type        Type
+	Default     any
+}
+
+// List is a list type, [Of]
+type List struct {
+	Of Type
+}
+
+func (l *List) String() string { return "[" + l.Of.String() + "]" }
+
+// NonNull is a non-null type, Of!
+type NonNull struct {
+	Of Type
+}
+
+func (n *NonNull) String() string { return n.Of.String() + "!" }
+
+// ListOf returns the type [t]
+func ListOf(t Type) *List { return &List{Of: t} }
+
+// NonNullOf returns the type t!
+func NonNullOf(t Type) *NonNull { return &NonNull{Of: t} }
+
+// Schema is the schema queries run against
+type Schema struct {
+	Query     *Object
+	MaxDepth  int // DefaultMaxDepth if 0
+	MaxFields int // DefaultMaxFields if 0
+}
+
+// Request is a GraphQL request, as clients post it
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+/*
+Response is the result of a request. A request that fails validation
+has no data; a request that ran has data, which is null when a non-null
+field of Query could not be resolved, and the errors met on the way.
+*/
+type Response struct {
+	Data   any
+	Errors []*Error
+	ran    bool
+}
+
+// Ran reports whether the request ran, i.e. whether it was valid
+func (r *Response) Ran() bool { return r.ran }
+
+// MarshalJSON writes the response in the format of the spec
+func (r *Response) MarshalJSON() ([]byte, error) {
+	var out struct {
+		Errors []*Error `json:"errors,omitempty"`
+		Data   *any     `json:"data,omitempty"`
+	}
+	out.Errors = r.Errors
+	if r.ran {
+		out.Data = &r.Data
+	}
+	return json.Marshal(out)
+}
+
+// Location is a position in the query, the first line and column being 1
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Error is an error of a request: in the query, or met resolving a field
+// (the path tells which)
+type Error struct {
+	Message   string     `json:"message"`
+	Locations []Location `json:"locations,omitempty"`
+	Path      []any      `json:"path,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if len(e.Locations) == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("%s (line %d, column %d)", e.Message, e.Locations[0].Line, e.Locations[0].Column)
+}
+
+// errorf returns an error without a location, for the messages of the
+// spec, which start with a capital letter
+func errorf(format string, args ...any) *Error {
+	return &Error{Message: fmt.Sprintf(format, args...)}
+}
+
+func newErrorf(loc Location, format string, args ...any) *Error {
+	return &Error{Message: fmt.Sprintf(format, args...), Locations: []Location{loc}}
+}
+
+// Execute runs a request
+func (s *Schema) Execute(ctx context.Context, req Request) *Response {
+	// Step 1: Parse the query and pick the operation to run
+	doc, err := parse(req.Query)
+	if err != nil {
+		return &Response{Errors: []*Error{err.(*Error)}}
+	}
+	op, err := pickOperation(doc, req.OperationName)
+	if err != nil {
+		return &Response{Errors: []*Error{err.(*Error)}}
+	}
+
+	// Step 2: Validate it and its variables
+	if errs := s.validate(doc, op); len(errs) > 0 {
+		return &Response{Errors: errs}
+	}
+	vars, errs := coerceVariables(op, req.Variables)
+	if len(errs) > 0 {
+		return &Response{Errors: errs}
+	}
+
+	// Step 3: Run it
+	e := &executor{doc: doc, vars: vars}
+	data, ok := e.selectionSet(ctx, s.Query, nil, op.selections, nil)
+	response := &Response{Errors: e.errors, ran: true}
+	if ok {
+		response.Data = data
+	}
+	return response
+}
+
+// pickOperation returns the operation of the document to run
+func pickOperation(doc *document, name string) (*operation, error) {
+	var op *operation
+	if name == "" {
+		if len(doc.operations) > 1 {
+			return nil, &Error{Message: "Must provide operationName if query contains multiple operations."}
+		}
+		op = doc.operations[0]
+	} else {
+		for _, candidate := range doc.operations {
+			if candidate.name == name {
+				op = candidate
+			}
+		}
+		if op == nil {
+			return nil, &Error{Message: fmt.Sprintf("Unknown operation named %q.", name)}
+		}
+	}
+	if op.kind != "query" {
+		return nil, newErrorf(op.loc, "Only queries are supported, not %ss: use the REST API to change data.", op.kind)
+	}
+	return op, nil
+}
+
+// String prints the schema in the GraphQL schema language, the types in
+// the order they are reached from Query
+func (s *Schema) String() string {
+	var b strings.Builder
+	seen := map[*Object]bool{s.Query: true}
+	queue := []*Object{s.Query}
+	for len(queue) > 0 {
+		obj := queue[0]
+		queue = queue[1:]
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		writeDescription(&b, "", obj.Description)
+		b.WriteString("type " + obj.Name + " {\n")
+		for _, f := range obj.Fields {
+			writeDescription(&b, "  ", f.Description)
+			b.WriteString("  " + f.Name)
+			if len(f.Args) > 0 {
+				args := make([]string, 0, len(f.Args))
+				for _, arg := range f.Args {
+					text := arg.Name + ": " + arg.Type.String()
+					if arg.Default != nil {
+						text += " = " + literal(arg.Default)
+					}
+					args = append(args, text)
+				}
+				b.WriteString("(" + strings.Join(args, ", ") + ")")
+			}
+			b.WriteString(": " + f.Type.String() + "\n")
+			if child, ok := namedType(f.Type).(*Object); ok && !seen[child] {
+				seen[child] = true
+				queue = append(queue, child)
+			}
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+func writeDescription(b *strings.Builder, indent, description string) {
+	if description == "" {
+		return
+	}
+	if !strings.Contains(description, "\n") {
+		b.WriteString(indent + strconv.Quote(description) + "\n")
+		return
+	}
+	b.WriteString(indent + `"""` + "\n")
+	for _, line := range strings.Split(description, "\n") {
+		b.WriteString(indent + line + "\n")
+	}
+	b.WriteString(indent + `"""` + "\n")
+}
+
+// literal writes a default value as a GraphQL literal
+func literal(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(bytes.TrimSpace(data))
+}
+
+// namedType returns the type without its list and non-null wrappers
+func namedType(t Type) Type {
+	for {
+		switch wrapper := t.(type) {
+		case *List:
+			t = wrapper.Of
+		case *NonNull:
+			t = wrapper.Of
+		default:
+			return t
+		}
+	}
+}
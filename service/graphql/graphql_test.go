@@ -0,0 +1,435 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		message string
+		loc     Location
+	}{
+		{"empty", "", "Syntax Error: the document has no operation.", Location{1, 1}},
+		{"only a fragment", "fragment F on Book { title }", "Syntax Error: the document has no operation.", Location{1, 29}},
+		{"unclosed selection", "{ book(id: 1) { title }", "Syntax Error: Expected Name, found <EOF>.", Location{1, 24}},
+		{"missing argument value", "{ book(id: ) { title } }", "Syntax Error: Unexpected \")\".", Location{1, 12}},
+		{"unexpected character", "{ title ? }", "Syntax Error: Unexpected character '?'.", Location{1, 9}},
+		{"unterminated string", `{ echo(s: "abc) }`, "Syntax Error: Unterminated string.", Location{1, 18}},
+		{"line break in a string", "{ echo(s: \"abc\n\") }", "Syntax Error: Unterminated string.", Location{1, 15}},
+		{"unterminated block string", `{ echo(s: """abc) }`, "Syntax Error: Unterminated string.", Location{1, 20}},
+		{"bad escape", `{ echo(s: "\q") }`, "Syntax Error: Invalid character escape sequence.", Location{1, 12}},
+		{"bad unicode escape", `{ echo(s: "\u12G4") }`, `Syntax Error: Invalid character escape sequence: "\\u12G4".`, Location{1, 12}},
+		{"control character in a string", "{ echo(s: \"a\x01\") }", `Syntax Error: Invalid character within String: '\x01'.`, Location{1, 13}},
+		{"leading zero", "{ books(first: 01) { title } }", "Syntax Error: Invalid number, unexpected digit after 0.", Location{1, 17}},
+		{"no digit after the point", "{ books(first: 1.) { title } }", "Syntax Error: Invalid number, expected digit.", Location{1, 18}},
+		{"no exponent", "{ books(first: 1e) { title } }", "Syntax Error: Invalid number, expected digit.", Location{1, 18}},
+		{"letter after a number", "{ books(first: 12abc) { title } }", "Syntax Error: Invalid number, expected digit.", Location{1, 18}},
+		{"lone minus", "{ books(first: -) { title } }", "Syntax Error: Invalid number, expected digit.", Location{1, 17}},
+		{"variable without a type", "query ($id) { book(id: $id) { title } }", "Syntax Error: Expected \":\", found \")\".", Location{1, 11}},
+		{"fragment without a type condition", "{ ...F } fragment F { title }", "Syntax Error: Expected \"on\", found \"{\".", Location{1, 21}},
+		{"fragment named on", "{ ...F } fragment on on Book { title }", "Syntax Error: Unexpected Name \"on\".", Location{1, 19}},
+		{"two fragments of a name", "{ ...F } fragment F on Book { title } fragment F on Book { id }", "There can be only one fragment named \"F\".", Location{1, 39}},
+		{"repeated argument", "{ book(id: 1, id: 2) { title } }", "There can be only one argument named \"id\".", Location{1, 15}},
+		{"location on a later line", "{\n  book(id: 1) {\n    title\n  ]\n}", "Syntax Error: Expected Name, found \"]\".", Location{4, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parse(tt.query)
+			var gqlErr *Error
+			if !errors.As(err, &gqlErr) {
+				t.Fatalf("got %v, want a syntax error", err)
+			}
+			if gqlErr.Message != tt.message {
+				t.Errorf("message %q, want %q", gqlErr.Message, tt.message)
+			}
+			if len(gqlErr.Locations) != 1 || gqlErr.Locations[0] != tt.loc {
+				t.Errorf("locations %v, want %v", gqlErr.Locations, tt.loc)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	doc, err := parse(`
+		# Everything a query may hold
+		query Book($id: ID!, $tags: [String!] = ["a", "b"], $n: Float = -1.5e3) {
+			book(id: $id) { ...Details, alias: title @skip(if: false) }
+		}
+		fragment Details on Book { title ... on Book { author { name } } }
+		{ books { title } }
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.operations) != 2 || len(doc.fragments) != 1 {
+		t.Fatalf("got %d operations and %d fragments, want 2 and 1", len(doc.operations), len(doc.fragments))
+	}
+
+	op := doc.operations[0]
+	if op.kind != "query" || op.name != "Book" || op.loc != (Location{3, 3}) {
+		t.Errorf("operation %s %q at %v", op.kind, op.name, op.loc)
+	}
+	var defs []string
+	for _, def := range op.variables {
+		d := "$" + def.name + ": " + def.typ.String()
+		if def.defVal != nil {
+			d += " = " + def.defVal.String()
+		}
+		defs = append(defs, d)
+	}
+	if got, want := strings.Join(defs, ", "), `$id: ID!, $tags: [String!] = ["a", "b"], $n: Float = -1.5e3`; got != want {
+		t.Errorf("variables %s, want %s", got, want)
+	}
+
+	book := op.selections[0].(*field)
+	if book.name != "book" || book.args[0].val.kind != variableValue || len(book.selections) != 2 {
+		t.Fatalf("field %+v", book)
+	}
+	if spread, ok := book.selections[0].(*fragmentSpread); !ok || spread.name != "Details" {
+		t.Errorf("first selection %+v, want the spread of Details", book.selections[0])
+	}
+	if alias := book.selections[1].(*field); alias.alias != "alias" || alias.name != "title" || alias.directives[0].name != "skip" {
+		t.Errorf("second selection %+v, want title aliased and skipped", alias)
+	}
+
+	details := doc.fragments["Details"]
+	if details.typeCond != "Book" || len(details.selections) != 2 {
+		t.Fatalf("fragment %+v", details)
+	}
+	if inline, ok := details.selections[1].(*inlineFragment); !ok || inline.typeCond != "Book" {
+		t.Errorf("selection %+v, want an inline fragment on Book", details.selections[1])
+	}
+
+	if anonymous := doc.operations[1]; anonymous.kind != "query" || anonymous.name != "" {
+		t.Errorf("shorthand operation %s %q, want an anonymous query", anonymous.kind, anonymous.name)
+	}
+}
+
+func TestParseStrings(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string // as written in the query
+		want  string
+	}{
+		{"plain", `"Hello, World!"`, "Hello, World!"},
+		{"escapes", `"\"quoted\" \\ \/ \b\f\n\r\t"`, "\"quoted\" \\ / \b\f\n\r\t"},
+		{"unicode escape", `"caf\u00e9 \u2615"`, "café ☕"},
+		{"unicode", `"città ☕"`, "città ☕"},
+		{"block", "\"\"\"\n    Hello,\n      World!\n\n    Bye\n  \"\"\"", "Hello,\n  World!\n\nBye"},
+		{"block with quotes", `"""say \""" and "hi" \n"""`, `say """ and "hi" \n`},
+		{"block, first line kept", "\"\"\"first\n  second\n  third\"\"\"", "first\nsecond\nthird"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parse("{ echo(s: " + tt.value + ") }")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := doc.operations[0].selections[0].(*field).args[0].val.raw; got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// The schema of the tests: books and their authors
+
+type testAuthor struct {
+	Name string `json:"name"`
+}
+
+type testBook struct {
+	ID     string      `json:"id"`
+	Title  string      `json:"title"`
+	Author *testAuthor `json:"author"`
+	Tags   []string    `json:"tags,omitempty"`
+	Year   int
+	Note   string `json:"note,omitempty"`
+}
+
+var testBooks = []*testBook{
+	{ID: "1", Title: "The Name of the Rose", Author: &testAuthor{Name: "Umberto Eco"}, Tags: []string{"novel", "mystery"}, Year: 1980, Note: "First edition"},
+	{ID: "2", Title: "Invisible Cities", Author: &testAuthor{Name: "Italo Calvino"}, Year: 1972},
+	{ID: "3", Title: "Anonymous Poems", Year: 1900},
+}
+
+func testSchema() *Schema {
+	author := &Object{Name: "Author", Fields: []*Field{
+		{Name: "name", Type: NonNullOf(String)},
+	}}
+	book := &Object{Name: "Book", Fields: []*Field{
+		{Name: "id", Type: NonNullOf(ID)},
+		{Name: "title", Type: NonNullOf(String)},
+		{Name: "author", Type: author},
+		{Name: "tags", Type: ListOf(NonNullOf(String))},
+		{Name: "year", Type: Int},
+		{Name: "note", Type: String},
+		{Name: "broken", Type: NonNullOf(String), Resolve: func(context.Context, any, map[string]any) (any, error) {
+			return nil, nil
+		}},
+	}}
+	query := &Object{Name: "Query", Fields: []*Field{
+		{
+			Name: "echo",
+			Type: String,
+			Args: []*Arg{{Name: "s", Type: String}},
+			Resolve: func(_ context.Context, _ any, args map[string]any) (any, error) {
+				return args["s"], nil
+			},
+		},
+		{
+			Name: "book",
+			Type: book,
+			Args: []*Arg{{Name: "id", Type: NonNullOf(ID)}},
+			Resolve: func(_ context.Context, _ any, args map[string]any) (any, error) {
+				for _, b := range testBooks {
+					if b.ID == args["id"] {
+						return b, nil
+					}
+				}
+				return nil, nil
+			},
+		},
+		{
+			Name: "books",
+			Type: NonNullOf(ListOf(NonNullOf(book))),
+			Args: []*Arg{{Name: "first", Type: Int, Default: 2}, {Name: "tags", Type: ListOf(String)}},
+			Resolve: func(_ context.Context, _ any, args map[string]any) (any, error) {
+				first := args["first"].(int)
+				return testBooks[:min(first, len(testBooks))], nil
+			},
+		},
+		{
+			Name: "sum",
+			Type: Float,
+			Args: []*Arg{{Name: "of", Type: NonNullOf(ListOf(NonNullOf(Float)))}},
+			Resolve: func(_ context.Context, _ any, args map[string]any) (any, error) {
+				var sum float64
+				for _, f := range args["of"].([]any) {
+					sum += f.(float64)
+				}
+				return sum, nil
+			},
+		},
+		{Name: "fail", Type: String, Resolve: func(context.Context, any, map[string]any) (any, error) {
+			return nil, errors.New("the library is closed")
+		}},
+		{Name: "failRequired", Type: NonNullOf(String), Resolve: func(context.Context, any, map[string]any) (any, error) {
+			return nil, errors.New("the library is closed")
+		}},
+	}}
+	return &Schema{Query: query}
+}
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		operationName string
+		variables     string // JSON
+		want          string // the JSON response
+	}{
+		{
+			name:  "fields and aliases",
+			query: `{ book(id: 1) { title year, author { name } } other: book(id: "2") { title } }`,
+			want:  `{"data":{"book":{"title":"The Name of the Rose","year":1980,"author":{"name":"Umberto Eco"}},"other":{"title":"Invisible Cities"}}}`,
+		},
+		{
+			name:  "null object, empty list and omitempty",
+			query: `{ book(id: 3) { author { name } tags note } missing: book(id: 9) { title } }`,
+			want:  `{"data":{"book":{"author":null,"tags":[],"note":null},"missing":null}}`,
+		},
+		{
+			name:  "list and argument default",
+			query: `{ books { id tags } }`,
+			want:  `{"data":{"books":[{"id":"1","tags":["novel","mystery"]},{"id":"2","tags":[]}]}}`,
+		},
+		{
+			name:      "variables",
+			query:     `query ($id: ID!, $n: Int) { book(id: $id) { title } books(first: $n) { id } }`,
+			variables: `{"id": 2, "n": 1}`,
+			want:      `{"data":{"book":{"title":"Invisible Cities"},"books":[{"id":"1"}]}}`,
+		},
+		{
+			name:  "variable default",
+			query: `query ($n: Int = 3, $s: String = "hi") { books(first: $n) { id } echo(s: $s) }`,
+			want:  `{"data":{"books":[{"id":"1"},{"id":"2"},{"id":"3"}],"echo":"hi"}}`,
+		},
+		{
+			name:  "variable not given: the argument default",
+			query: `query ($n: Int) { books(first: $n) { id } }`,
+			want:  `{"data":{"books":[{"id":"1"},{"id":"2"}]}}`,
+		},
+		{
+			name:      "list variable, a single value coerced",
+			query:     `query ($of: [Float!]!) { sum(of: $of) a: sum(of: [1, 2.5]) b: sum(of: 4) }`,
+			variables: `{"of": 1.5}`,
+			want:      `{"data":{"sum":1.5,"a":3.5,"b":4}}`,
+		},
+		{
+			name:      "fragments",
+			query:     `query ($id: ID!) { book(id: $id) { ...Title ... on Book { author { ...Author } } } } fragment Title on Book { title } fragment Author on Author { name }`,
+			variables: `{"id": "1"}`,
+			want:      `{"data":{"book":{"title":"The Name of the Rose","author":{"name":"Umberto Eco"}}}}`,
+		},
+		{
+			name:  "fields merged across fragments",
+			query: `{ book(id: 1) { author { name } ...F } } fragment F on Book { author { __typename } }`,
+			want:  `{"data":{"book":{"author":{"name":"Umberto Eco","__typename":"Author"}}}}`,
+		},
+		{
+			name:      "skip and include",
+			query:     `query ($yes: Boolean!) { book(id: 1) { id @skip(if: $yes) title @include(if: $yes) ... @skip(if: true) { year } ...Y @include(if: false) } } fragment Y on Book { year }`,
+			variables: `{"yes": true}`,
+			want:      `{"data":{"book":{"title":"The Name of the Rose"}}}`,
+		},
+		{
+			name:  "typename",
+			query: `{ __typename book(id: 1) { __typename } }`,
+			want:  `{"data":{"__typename":"Query","book":{"__typename":"Book"}}}`,
+		},
+		{
+			name:          "operation by name",
+			query:         `query A { echo(s: "a") } query B { echo(s: "b") }`,
+			operationName: "B",
+			want:          `{"data":{"echo":"b"}}`,
+		},
+		{
+			name:  "resolver error",
+			query: `{ echo(s: "x") fail }`,
+			want:  `{"errors":[{"message":"the library is closed","locations":[{"line":1,"column":16}],"path":["fail"]}],"data":{"echo":"x","fail":null}}`,
+		},
+		{
+			name:  "non-null error: the data is null",
+			query: `{ echo(s: "x") failRequired }`,
+			want:  `{"errors":[{"message":"the library is closed","locations":[{"line":1,"column":16}],"path":["failRequired"]}],"data":null}`,
+		},
+		{
+			name:  "non-null null: up to the nullable parent",
+			query: `{ book(id: 1) { title broken } }`,
+			want:  `{"errors":[{"message":"Cannot return null for non-nullable field \"broken\".","locations":[{"line":1,"column":23}],"path":["book","broken"]}],"data":{"book":null}}`,
+		},
+		{
+			name:  "non-null list item: the whole list",
+			query: `{ echo(s: "x") books(first: 1) { broken } }`,
+			want:  `{"errors":[{"message":"Cannot return null for non-nullable field \"broken\".","locations":[{"line":1,"column":34}],"path":["books",0,"broken"]}],"data":null}`,
+		},
+	}
+	schema := testSchema()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := Request{Query: tt.query, OperationName: tt.operationName}
+			if tt.variables != "" {
+				if err := json.Unmarshal([]byte(tt.variables), &req.Variables); err != nil {
+					t.Fatal(err)
+				}
+			}
+			response := schema.Execute(context.Background(), req)
+			if !response.Ran() {
+				t.Fatalf("did not run: %v", response.Errors)
+			}
+			got, err := json.Marshal(response)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestExecuteInvalid checks the requests rejected before they run: they
+// have errors and no data
+func TestExecuteInvalid(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		operationName string
+		variables     map[string]any
+		want          string // the first error
+	}{
+		{"syntax error", "{ book(id: 1) {", "", nil, "Syntax Error: Expected Name, found <EOF>."},
+		{"mutation", `mutation { echo(s: "x") }`, "", nil, "Only queries are supported, not mutations: use the REST API to change data."},
+		{"subscription", `subscription { echo }`, "", nil, "Only queries are supported, not subscriptions: use the REST API to change data."},
+		{"several operations, no name", "query A { echo } query B { echo }", "", nil, "Must provide operationName if query contains multiple operations."},
+		{"unknown operation", "query A { echo }", "C", nil, `Unknown operation named "C".`},
+		{"unknown field", "{ book(id: 1) { isbn } }", "", nil, `Cannot query field "isbn" on type "Book".`},
+		{"no subfields", "{ book(id: 1) }", "", nil, `Field "book" of type "Book" must have a selection of subfields.`},
+		{"subfields of a scalar", "{ echo { length } }", "", nil, `Field "echo" must not have a selection since type "String" has no subfields.`},
+		{"unknown argument", "{ echo(text: \"x\") }", "", nil, `Unknown argument "text" on field "Query.echo".`},
+		{"missing argument", "{ book { title } }", "", nil, `Field "book" argument "id" of type "ID!" is required, but it was not provided.`},
+		{"wrong argument type", `{ books(first: "two") { id } }`, "", nil, `Expected a value of type "Int", found "two".`},
+		{"Int out of range", `{ books(first: 3000000000) { id } }`, "", nil, `Expected a value of type "Int", found 3000000000.`},
+		{"null for a non-null argument", `{ book(id: null) { id } }`, "", nil, `Expected a value of type "ID!", found null.`},
+		{"unknown fragment", "{ book(id: 1) { ...F } }", "", nil, `Unknown fragment "F".`},
+		{"fragment on another type", "{ book(id: 1) { ...F } } fragment F on Author { name }", "", nil, `Fragment "F" cannot be spread here as objects of type "Book" can never be of type "Author".`},
+		{"inline fragment on another type", "{ book(id: 1) { ... on Author { name } } }", "", nil, `Fragment cannot be spread here as objects of type "Book" can never be of type "Author".`},
+		{"fragment cycle", "{ book(id: 1) { ...A } } fragment A on Book { title ...B } fragment B on Book { ...A }", "", nil, `Cannot spread fragment "A" within itself.`},
+		{"unknown directive", "{ echo @deprecated }", "", nil, `Unknown directive "@deprecated".`},
+		{"directive without if", "{ echo @skip }", "", nil, `Directive "@skip" takes one argument, "if" of type "Boolean!".`},
+		{"directive on the operation", "query @skip(if: true) { echo }", "", nil, `Directive "@skip" may not be used on QUERY.`},
+		{"undefined variable", "{ book(id: $id) { id } }", "", nil, `Variable "$id" is not defined.`},
+		{"variable of another type", "query ($id: String) { book(id: $id) { id } }", "", nil, `Variable "$id" of type "String" used in position expecting type "ID!".`},
+		{"nullable variable for a non-null argument", "query ($id: ID) { book(id: $id) { id } }", "", nil, `Variable "$id" of type "ID" used in position expecting type "ID!".`},
+		{"variable of an object type", "query ($b: Book) { echo }", "", nil, `Variable "$b" must be of a scalar type or a list of one, not "Book".`},
+		{"repeated variable", "query ($a: Int, $a: Int) { echo }", "", nil, `There can be only one variable named "$a".`},
+		{"required variable not given", "query ($id: ID!) { book(id: $id) { id } }", "", nil, `Variable "$id" got an invalid value: variable of required type "ID!" was not provided.`},
+		{"variable of the wrong type", "query ($n: Int) { books(first: $n) { id } }", "", map[string]any{"n": 1.5}, `Variable "$n" got an invalid value: Int cannot represent 1.5.`},
+		{"null variable for a non-null type", "query ($id: ID!) { book(id: $id) { id } }", "", map[string]any{"id": nil}, `Variable "$id" got an invalid value: expected a value of type "ID!", found null.`},
+		{"bad item of a list variable", "query ($of: [Float!]!) { sum(of: $of) }", "", map[string]any{"of": []any{1.0, "two"}}, `Variable "$of" got an invalid value: Float cannot represent "two".`},
+	}
+	schema := testSchema()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := schema.Execute(context.Background(), Request{Query: tt.query, OperationName: tt.operationName, Variables: tt.variables})
+			if response.Ran() {
+				t.Fatalf("ran, with data %v", response.Data)
+			}
+			if len(response.Errors) == 0 || response.Errors[0].Message != tt.want {
+				t.Errorf("errors %v, want %q", response.Errors, tt.want)
+			}
+			if got, err := json.Marshal(response); err != nil || strings.Contains(string(got), `"data"`) {
+				t.Errorf("got %s %v, want no data", got, err)
+			}
+		})
+	}
+}
+
+func TestExecuteLimits(t *testing.T) {
+	schema := testSchema()
+	schema.MaxDepth = 2
+	schema.MaxFields = 4
+
+	for _, tt := range []struct {
+		name  string
+		query string
+		want  string // the error, "" if the query runs
+	}{
+		{"at the depth", "{ book(id: 1) { title } }", ""},
+		{"too deep", "{ book(id: 1) { author { name } } }", "The query is nested too deeply: at most 2 levels of fields are allowed."},
+		{"at the count", "{ a: echo b: echo c: echo d: echo }", ""},
+		{"too many fields", "{ a: echo b: echo c: echo d: echo e: echo }", "The query selects too many fields: at most 4 are allowed, fragments expanded."},
+		{"too many fields through fragments", "{ ...F ...G } fragment F on Query { a: echo b: echo c: echo } fragment G on Query { d: echo e: echo }", "The query selects too many fields: at most 4 are allowed, fragments expanded."},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			response := schema.Execute(context.Background(), Request{Query: tt.query})
+			var got string
+			if len(response.Errors) > 0 {
+				got = response.Errors[0].Message
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if len(response.Errors) > 1 {
+				t.Errorf("got %d errors, want the checks to stop at the limit", len(response.Errors))
+			}
+		})
+	}
+}
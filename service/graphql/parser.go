@@ -0,0 +1,803 @@
+package graphql
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// The parsed query document (see the Executable Definitions of the spec)
+
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+type operation struct {
+	kind       string // query, mutation or subscription
+	name       string
+	variables  []*variableDef
+	directives []*directive
+	selections []selection
+	loc        Location
+}
+
+type variableDef struct {
+	name   string
+	typ    *typeRef
+	defVal *value // nil when it has no default value
+	loc    Location
+}
+
+// typeRef is a type written in a variable definition, e.g. [ID!]!
+type typeRef struct {
+	name    string   // "" for a list
+	elem    *typeRef // the type of the items of a list
+	nonNull bool
+}
+
+func (t *typeRef) String() string {
+	s := t.name
+	if t.elem != nil {
+		s = "[" + t.elem.String() + "]"
+	}
+	if t.nonNull {
+		s += "!"
+	}
+	return s
+}
+
+type fragment struct {
+	name       string
+	typeCond   string
+	directives []*directive
+	selections []selection
+	loc        Location
+}
+
+// selection is a *field, *fragmentSpread or *inlineFragment
+type selection interface {
+	location() Location
+}
+
+type field struct {
+	alias      string // the name when there is no alias
+	name       string
+	args       []*argument
+	directives []*directive
+	selections []selection
+	loc        Location
+}
+
+type fragmentSpread struct {
+	name       string
+	directives []*directive
+	loc        Location
+}
+
+type inlineFragment struct {
+	typeCond   string // "" when there is none
+	directives []*directive
+	selections []selection
+	loc        Location
+}
+
+func (f *field) location() Location          { return f.loc }
+func (f *fragmentSpread) location() Location { return f.loc }
+func (f *inlineFragment) location() Location { return f.loc }
+
+type argument struct {
+	name string
+	val  *value
+	loc  Location
+}
+
+type directive struct {
+	name string
+	args []*argument
+	loc  Location
+}
+
+// Kinds of values
+type valueKind int
+
+const (
+	variableValue valueKind = iota
+	intValue
+	floatValue
+	stringValue
+	booleanValue
+	nullValue
+	enumValue
+	listValue
+	objectValue
+)
+
+// value is a value written in the document; raw is the name of a
+// variable or enum, the text of a number, the decoded string, or
+// "true"/"false"
+type value struct {
+	kind   valueKind
+	raw    string
+	list   []*value
+	fields []*argument // of an object value
+	loc    Location
+}
+
+// Kinds of tokens
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+var tokenNames = map[tokenKind]string{
+	tokEOF:    "<EOF>",
+	tokPunct:  "punctuation",
+	tokName:   "Name",
+	tokInt:    "Int",
+	tokFloat:  "Float",
+	tokString: "String",
+}
+
+type token struct {
+	kind tokenKind
+	text string // the decoded value of a string
+	loc  Location
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "<EOF>"
+	case tokString:
+		return "String " + strconv.Quote(t.text)
+	case tokPunct:
+		return strconv.Quote(t.text)
+	}
+	return tokenNames[t.kind] + " " + strconv.Quote(t.text)
+}
+
+// lexer splits a document into tokens, skipping whitespace, commas and comments
+type lexer struct {
+	src       string
+	pos       int
+	line      int
+	lineStart int
+}
+
+func (l *lexer) loc(pos int) Location {
+	return Location{Line: l.line, Column: utf8.RuneCountInString(l.src[l.lineStart:pos]) + 1}
+}
+
+func (l *lexer) errorf(pos int, format string, args ...any) *Error {
+	return newErrorf(l.loc(pos), "Syntax Error: "+format, args...)
+}
+
+// newline records a line break that ends at pos
+func (l *lexer) newline(pos int) {
+	l.line++
+	l.lineStart = pos
+}
+
+func (l *lexer) next() (token, error) {
+	// Step 1: Skip what is ignored
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if c == '\n' {
+			l.pos++
+			l.newline(l.pos)
+		} else if c == '\r' {
+			l.pos++
+			if l.pos < len(l.src) && l.src[l.pos] == '\n' {
+				l.pos++
+			}
+			l.newline(l.pos)
+		} else if c == ' ' || c == '\t' || c == ',' {
+			l.pos++
+		} else if strings.HasPrefix(l.src[l.pos:], "\ufeff") {
+			l.pos += len("\ufeff")
+		} else if c == '#' {
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' && l.src[l.pos] != '\r' {
+				l.pos++
+			}
+		} else {
+			break
+		}
+	}
+	start := l.pos
+	if start == len(l.src) {
+		return token{kind: tokEOF, loc: l.loc(start)}, nil
+	}
+
+	// Step 2: Read the token
+	c := l.src[start]
+	switch {
+	case strings.HasPrefix(l.src[start:], "..."):
+		l.pos += 3
+		return token{kind: tokPunct, text: "...", loc: l.loc(start)}, nil
+	case strings.IndexByte("!$&():=@[]{|}", c) >= 0:
+		l.pos++
+		return token{kind: tokPunct, text: string(c), loc: l.loc(start)}, nil
+	case isNameStart(c):
+		for l.pos < len(l.src) && (isNameStart(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.pos++
+		}
+		return token{kind: tokName, text: l.src[start:l.pos], loc: l.loc(start)}, nil
+	case c == '-' || isDigit(c):
+		return l.number()
+	case strings.HasPrefix(l.src[start:], `"""`):
+		return l.blockString()
+	case c == '"':
+		return l.string()
+	}
+	r, _ := utf8.DecodeRuneInString(l.src[start:])
+	return token{}, l.errorf(start, "Unexpected character %q.", r)
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// number reads an Int or a Float: -?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?
+func (l *lexer) number() (token, error) {
+	start := l.pos
+	kind := tokInt
+	digits := func() error {
+		if l.pos >= len(l.src) || !isDigit(l.src[l.pos]) {
+			return l.errorf(l.pos, "Invalid number, expected digit.")
+		}
+		for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+			l.pos++
+		}
+		return nil
+	}
+
+	if l.src[l.pos] == '-' {
+		l.pos++
+	}
+	if l.pos < len(l.src) && l.src[l.pos] == '0' {
+		l.pos++
+		if l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+			return token{}, l.errorf(l.pos, "Invalid number, unexpected digit after 0.")
+		}
+	} else if err := digits(); err != nil {
+		return token{}, err
+	}
+	if l.pos < len(l.src) && l.src[l.pos] == '.' {
+		kind = tokFloat
+		l.pos++
+		if err := digits(); err != nil {
+			return token{}, err
+		}
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		kind = tokFloat
+		l.pos++
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.pos++
+		}
+		if err := digits(); err != nil {
+			return token{}, err
+		}
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == '.' || isNameStart(l.src[l.pos])) {
+		return token{}, l.errorf(l.pos, "Invalid number, expected digit.")
+	}
+	return token{kind: kind, text: l.src[start:l.pos], loc: l.loc(start)}, nil
+}
+
+// string reads a "..." string, decoding its escapes
+func (l *lexer) string() (token, error) {
+	start := l.pos
+	l.pos++
+	var b strings.Builder
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '"':
+			l.pos++
+			return token{kind: tokString, text: b.String(), loc: l.loc(start)}, nil
+		case c == '\n' || c == '\r':
+			return token{}, l.errorf(l.pos, "Unterminated string.")
+		case c == '\\':
+			if l.pos+1 >= len(l.src) {
+				return token{}, l.errorf(l.pos, "Unterminated string.")
+			}
+			escape := l.src[l.pos+1]
+			if decoded, ok := simpleEscapes[escape]; ok {
+				b.WriteByte(decoded)
+				l.pos += 2
+				continue
+			}
+			if escape != 'u' || l.pos+6 > len(l.src) {
+				return token{}, l.errorf(l.pos, "Invalid character escape sequence.")
+			}
+			code, err := strconv.ParseUint(l.src[l.pos+2:l.pos+6], 16, 32)
+			if err != nil {
+				return token{}, l.errorf(l.pos, "Invalid character escape sequence: %q.", l.src[l.pos:l.pos+6])
+			}
+			b.WriteRune(rune(code))
+			l.pos += 6
+		case c < ' ' && c != '\t':
+			return token{}, l.errorf(l.pos, "Invalid character within String: %q.", rune(c))
+		default:
+			b.WriteByte(c)
+			l.pos++
+		}
+	}
+	return token{}, l.errorf(l.pos, "Unterminated string.")
+}
+
+var simpleEscapes = map[byte]byte{
+	'"': '"', '\\': '\\', '/': '/', 'b': '\b', 'f': '\f', 'n': '\n', 'r': '\r', 't': '\t',
+}
+
+// blockString reads a """...""" string, removing its common indentation
+func (l *lexer) blockString() (token, error) {
+	loc := l.loc(l.pos)
+	l.pos += 3
+	var b strings.Builder
+	for l.pos < len(l.src) {
+		switch {
+		case strings.HasPrefix(l.src[l.pos:], `"""`):
+			l.pos += 3
+			return token{kind: tokString, text: blockStringValue(b.String()), loc: loc}, nil
+		case strings.HasPrefix(l.src[l.pos:], `\"""`):
+			b.WriteString(`"""`)
+			l.pos += 4
+		default:
+			c := l.src[l.pos]
+			b.WriteByte(c)
+			l.pos++
+			if c == '\n' || c == '\r' && (l.pos == len(l.src) || l.src[l.pos] != '\n') {
+				l.newline(l.pos)
+			}
+		}
+	}
+	return token{}, l.errorf(l.pos, "Unterminated string.")
+}
+
+// blockStringValue removes the common indentation of the lines of a
+// block string, and its leading and trailing blank lines
+func blockStringValue(raw string) string {
+	lines := strings.Split(strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(raw), "\n")
+	common := -1
+	for _, line := range lines[1:] {
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < len(line) && (common < 0 || indent < common) {
+			common = indent
+		}
+	}
+	if common > 0 {
+		for i := 1; i < len(lines); i++ {
+			if len(lines[i]) >= common {
+				lines[i] = lines[i][common:]
+			} else {
+				lines[i] = ""
+			}
+		}
+	}
+	for len(lines) > 0 && strings.TrimLeft(lines[0], " \t") == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimLeft(lines[len(lines)-1], " \t") == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parser reads a document with one token of lookahead
+type parser struct {
+	lex *lexer
+	tok token
+}
+
+// parse parses a query document
+func parse(src string) (*document, error) {
+	p := &parser{lex: &lexer{src: src, line: 1}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	doc := &document{fragments: make(map[string]*fragment)}
+	for {
+		switch {
+		case p.tok.kind == tokEOF:
+			if len(doc.operations) == 0 {
+				return nil, newErrorf(p.tok.loc, "Syntax Error: the document has no operation.")
+			}
+			return doc, nil
+		case p.is(tokPunct, "{"):
+			loc := p.tok.loc
+			selections, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, &operation{kind: "query", selections: selections, loc: loc})
+		case p.is(tokName, "query") || p.is(tokName, "mutation") || p.is(tokName, "subscription"):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		case p.is(tokName, "fragment"):
+			frag, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := doc.fragments[frag.name]; ok {
+				return nil, newErrorf(frag.loc, "There can be only one fragment named %q.", frag.name)
+			}
+			doc.fragments[frag.name] = frag
+		default:
+			return nil, p.unexpected()
+		}
+	}
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+// is reports whether the current token is of the kind, with the text
+func (p *parser) is(kind tokenKind, text string) bool {
+	return p.tok.kind == kind && p.tok.text == text
+}
+
+func (p *parser) unexpected() error {
+	return newErrorf(p.tok.loc, "Syntax Error: Unexpected %s.", p.tok)
+}
+
+// expect consumes a punctuator
+func (p *parser) expect(punct string) error {
+	if !p.is(tokPunct, punct) {
+		return newErrorf(p.tok.loc, "Syntax Error: Expected %q, found %s.", punct, p.tok)
+	}
+	return p.advance()
+}
+
+// skip consumes a punctuator if it is the current token
+func (p *parser) skip(punct string) (bool, error) {
+	if !p.is(tokPunct, punct) {
+		return false, nil
+	}
+	return true, p.advance()
+}
+
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokName {
+		return "", newErrorf(p.tok.loc, "Syntax Error: Expected Name, found %s.", p.tok)
+	}
+	name := p.tok.text
+	return name, p.advance()
+}
+
+// keyword consumes a name that must be the given one
+func (p *parser) keyword(word string) error {
+	if !p.is(tokName, word) {
+		return newErrorf(p.tok.loc, "Syntax Error: Expected %q, found %s.", word, p.tok)
+	}
+	return p.advance()
+}
+
+func (p *parser) operation() (*operation, error) {
+	op := &operation{kind: p.tok.text, loc: p.tok.loc}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	var err error
+	if p.tok.kind == tokName {
+		if op.name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if p.is(tokPunct, "(") {
+		if op.variables, err = p.variableDefs(); err != nil {
+			return nil, err
+		}
+	}
+	if op.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if op.selections, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+func (p *parser) variableDefs() ([]*variableDef, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var defs []*variableDef
+	for {
+		def := &variableDef{loc: p.tok.loc}
+		if err := p.expect("$"); err != nil {
+			return nil, err
+		}
+		var err error
+		if def.name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if def.typ, err = p.typeRef(); err != nil {
+			return nil, err
+		}
+		if ok, err := p.skip("="); err != nil {
+			return nil, err
+		} else if ok {
+			if def.defVal, err = p.value(true); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := p.directives(); err != nil {
+			return nil, err
+		}
+		defs = append(defs, def)
+		if ok, err := p.skip(")"); err != nil || ok {
+			return defs, err
+		}
+	}
+}
+
+func (p *parser) typeRef() (*typeRef, error) {
+	t := &typeRef{}
+	if ok, err := p.skip("["); err != nil {
+		return nil, err
+	} else if ok {
+		if t.elem, err = p.typeRef(); err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+	} else if t.name, err = p.name(); err != nil {
+		return nil, err
+	}
+	ok, err := p.skip("!")
+	t.nonNull = ok
+	return t, err
+}
+
+func (p *parser) fragment() (*fragment, error) {
+	frag := &fragment{loc: p.tok.loc}
+	if err := p.keyword("fragment"); err != nil {
+		return nil, err
+	}
+	if p.is(tokName, "on") {
+		return nil, p.unexpected()
+	}
+	var err error
+	if frag.name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if err := p.keyword("on"); err != nil {
+		return nil, err
+	}
+	if frag.typeCond, err = p.name(); err != nil {
+		return nil, err
+	}
+	if frag.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if frag.selections, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return frag, nil
+}
+
+func (p *parser) selectionSet() ([]selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var selections []selection
+	for {
+		sel, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, sel)
+		if ok, err := p.skip("}"); err != nil || ok {
+			return selections, err
+		}
+	}
+}
+
+func (p *parser) selection() (selection, error) {
+	loc := p.tok.loc
+	if ok, err := p.skip("..."); err != nil {
+		return nil, err
+	} else if ok {
+		return p.fragmentSelection(loc)
+	}
+
+	f := &field{loc: loc}
+	var err error
+	if f.name, err = p.name(); err != nil {
+		return nil, err
+	}
+	f.alias = f.name
+	if ok, err := p.skip(":"); err != nil {
+		return nil, err
+	} else if ok {
+		if f.name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if f.args, err = p.arguments(false); err != nil {
+		return nil, err
+	}
+	if f.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if p.is(tokPunct, "{") {
+		if f.selections, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// fragmentSelection reads what follows "...": a fragment spread or an
+// inline fragment
+func (p *parser) fragmentSelection(loc Location) (selection, error) {
+	if p.tok.kind == tokName && p.tok.text != "on" {
+		spread := &fragmentSpread{loc: loc}
+		var err error
+		if spread.name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if spread.directives, err = p.directives(); err != nil {
+			return nil, err
+		}
+		return spread, nil
+	}
+	inline := &inlineFragment{loc: loc}
+	var err error
+	if p.is(tokName, "on") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if inline.typeCond, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if inline.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if inline.selections, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return inline, nil
+}
+
+func (p *parser) arguments(isConst bool) ([]*argument, error) {
+	if ok, err := p.skip("("); err != nil || !ok {
+		return nil, err
+	}
+	var args []*argument
+	for {
+		arg := &argument{loc: p.tok.loc}
+		var err error
+		if arg.name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if arg.val, err = p.value(isConst); err != nil {
+			return nil, err
+		}
+		for _, other := range args {
+			if other.name == arg.name {
+				return nil, newErrorf(arg.loc, "There can be only one argument named %q.", arg.name)
+			}
+		}
+		args = append(args, arg)
+		if ok, err := p.skip(")"); err != nil || ok {
+			return args, err
+		}
+	}
+}
+
+func (p *parser) directives() ([]*directive, error) {
+	var directives []*directive
+	for p.is(tokPunct, "@") {
+		d := &directive{loc: p.tok.loc}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		var err error
+		if d.name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if d.args, err = p.arguments(false); err != nil {
+			return nil, err
+		}
+		directives = append(directives, d)
+	}
+	return directives, nil
+}
+
+// value reads a value; a constant one (a default value) cannot use variables
+func (p *parser) value(isConst bool) (*value, error) {
+	v := &value{raw: p.tok.text, loc: p.tok.loc}
+	switch {
+	case p.is(tokPunct, "$") && !isConst:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		v.kind = variableValue
+		var err error
+		v.raw, err = p.name()
+		return v, err
+	case p.is(tokPunct, "["):
+		v.kind = listValue
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		for {
+			if ok, err := p.skip("]"); err != nil || ok {
+				return v, err
+			}
+			item, err := p.value(isConst)
+			if err != nil {
+				return nil, err
+			}
+			v.list = append(v.list, item)
+		}
+	case p.is(tokPunct, "{"):
+		v.kind = objectValue
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		for {
+			if ok, err := p.skip("}"); err != nil || ok {
+				return v, err
+			}
+			f := &argument{loc: p.tok.loc}
+			var err error
+			if f.name, err = p.name(); err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if f.val, err = p.value(isConst); err != nil {
+				return nil, err
+			}
+			v.fields = append(v.fields, f)
+		}
+	case p.tok.kind == tokInt:
+		v.kind = intValue
+	case p.tok.kind == tokFloat:
+		v.kind = floatValue
+	case p.tok.kind == tokString:
+		v.kind = stringValue
+	case p.is(tokName, "true") || p.is(tokName, "false"):
+		v.kind = booleanValue
+	case p.is(tokName, "null"):
+		v.kind = nullValue
+	case p.tok.kind == tokName:
+		v.kind = enumValue
+	default:
+		return nil, p.unexpected()
+	}
+	return v, p.advance()
+}
@@ -0,0 +1,140 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client calls the methods of a gRPC server
+type Client struct {
+	base   string
+	http   *http.Client
+	Header http.Header // sent with every call, e.g. the Authorization
+}
+
+/*
+NewClient returns a client of the server at baseURL: http:// for
+unencrypted HTTP/2 (h2c), https:// for HTTP/2 over TLS.
+*/
+func NewClient(baseURL string) *Client {
+	var protocols http.Protocols
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(true)
+	transport := &http.Transport{
+		Protocols:       &protocols,
+		TLSClientConfig: &tls.Config{MinVersion: tls.VersionTLS12},
+	}
+	return &Client{
+		base:   strings.TrimSuffix(baseURL, "/"),
+		http:   &http.Client{Transport: transport},
+		Header: make(http.Header),
+	}
+}
+
+// Invoke calls a unary method, decoding its response into response; a
+// call that fails returns a *Status
+func (c *Client) Invoke(ctx context.Context, method string, request Marshaler, response Unmarshaler) error {
+	stream, err := c.NewStream(ctx, method, request)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	if err := stream.Recv(response); err != nil {
+		if errors.Is(err, io.EOF) {
+			return Errorf(Internal, "the server sent no response")
+		}
+		return err
+	}
+	if err := stream.Recv(response); !errors.Is(err, io.EOF) {
+		if err == nil {
+			return Errorf(Internal, "the server sent more than one response")
+		}
+		return err
+	}
+	return nil
+}
+
+// NewStream calls a server-streaming method; Recv returns the messages
+// of its response
+func (c *Client) NewStream(ctx context.Context, method string, request Marshaler) (*ClientStream, error) {
+	var body bytes.Buffer
+	if err := writeMessage(&body, Marshal(request)); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.base+method, &body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range c.Header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("Te", "trailers")
+	if deadline, ok := ctx.Deadline(); ok {
+		req.Header.Set("Grpc-Timeout", formatTimeout(time.Until(deadline)))
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, Errorf(Unavailable, "%v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		return nil, Errorf(Unavailable, "the server answered %s", resp.Status)
+	}
+	return &ClientStream{resp: resp}, nil
+}
+
+// ClientStream reads the messages of the response of a call
+type ClientStream struct {
+	resp *http.Response
+}
+
+// Recv decodes the next message into m. At the end of the response it
+// returns io.EOF when the call succeeded, its *Status otherwise; a
+// message cut short is an error even when the call succeeded.
+func (s *ClientStream) Recv(m Unmarshaler) error {
+	message, err := readMessage(s.resp.Body)
+	if err != nil {
+		if status := s.status(); status != nil {
+			return status
+		}
+		if !errors.Is(err, errNoMessage) {
+			return Errorf(Internal, "the response is not valid: %s", statusOf(err).Message)
+		}
+		return io.EOF
+	}
+	if err := Unmarshal(message, m); err != nil {
+		return Errorf(Internal, "the response is not a valid message: %v", err)
+	}
+	return nil
+}
+
+// status returns the status of the call once its response was read, nil if it succeeded
+func (s *ClientStream) status() *Status {
+	value := s.resp.Trailer.Get("Grpc-Status")
+	message := s.resp.Trailer.Get("Grpc-Message")
+	if value == "" { // a response with only headers
+		value = s.resp.Header.Get("Grpc-Status")
+		message = s.resp.Header.Get("Grpc-Message")
+	}
+	code, err := strconv.Atoi(value)
+	if err != nil {
+		return &Status{Code: Internal, Message: "the response has no grpc-status"}
+	}
+	if Code(code) == OK {
+		return nil
+	}
+	return &Status{Code: Code(code), Message: decodeMessage(message)}
+}
+
+// Close ends the call, canceling it if the response was not read to its end
+func (s *ClientStream) Close() {
+	_ = s.resp.Body.Close()
+}
@@ -0,0 +1,300 @@
+/*
+Package grpc is a small gRPC implementation over the HTTP/2 of net/http.
+
+The API serves gRPC to programmatic clients (command-line tools, bots)
+that prefer a typed protocol to REST. None of them needs more than this:
+
+  - unary and server-streaming methods, registered by their full name,
+    e.g. "/wasatext.v1.WASAText/SendMessage";
+  - protobuf messages encoded by hand (wire.go): strings, bools,
+    integers, nested and repeated messages;
+  - the status codes and grpc-message, sent as trailers, and the
+    grpc-timeout of the client as the deadline of the call;
+  - a client for the same (client.go).
+
+Compression and client streaming are not supported. Server is an
+http.Handler: serve it with HTTP/2, over TLS or unencrypted (h2c, see
+Protocols in net/http).
+*/
+package grpc
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxMessageSize is the largest message either side may send
+const MaxMessageSize = 4 << 20
+
+// Code is a gRPC status code
+type Code int
+
+// Status codes
+const (
+	OK                 Code = 0
+	Canceled           Code = 1
+	Unknown            Code = 2
+	InvalidArgument    Code = 3
+	DeadlineExceeded   Code = 4
+	NotFound           Code = 5
+	AlreadyExists      Code = 6
+	PermissionDenied   Code = 7
+	ResourceExhausted  Code = 8
+	FailedPrecondition Code = 9
+	Aborted            Code = 10
+	OutOfRange         Code = 11
+	Unimplemented      Code = 12
+	Internal           Code = 13
+	Unavailable        Code = 14
+	DataLoss           Code = 15
+	Unauthenticated    Code = 16
+)
+
+// Status is the outcome of a call that failed
+type Status struct {
+	Code    Code
+	Message string
+}
+
+func (s *Status) Error() string {
+	return fmt.Sprintf("grpc: code %d: %s", s.Code, s.Message)
+}
+
+// Errorf returns a status error
+func Errorf(code Code, format string, args ...any) error {
+	return &Status{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// statusOf returns the status of the error of a method: a *Status as
+// it is, any other error as Unknown (or Canceled, DeadlineExceeded)
+func statusOf(err error) *Status {
+	var status *Status
+	switch {
+	case errors.As(err, &status):
+		return status
+	case errors.Is(err, context.Canceled):
+		return &Status{Code: Canceled, Message: "the call was canceled"}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &Status{Code: DeadlineExceeded, Message: "the deadline of the call was exceeded"}
+	}
+	return &Status{Code: Unknown, Message: err.Error()}
+}
+
+// Call is a call being served
+type Call struct {
+	Header     http.Header // the metadata the client sent, e.g. Authorization
+	RemoteAddr string
+	request    []byte
+}
+
+// Decode decodes the request message of the call into m
+func (c *Call) Decode(m Unmarshaler) error {
+	if err := Unmarshal(c.request, m); err != nil {
+		return Errorf(InvalidArgument, "the request is not a valid message: %v", err)
+	}
+	return nil
+}
+
+// UnaryHandler serves a unary method: it returns the response message
+type UnaryHandler func(ctx context.Context, call *Call) (Marshaler, error)
+
+// StreamHandler serves a server-streaming method: it sends the messages
+// of the response until it returns
+type StreamHandler func(ctx context.Context, call *Call, stream *ServerStream) error
+
+// ServerStream sends the messages of a server-streaming call
+type ServerStream struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+// Send sends a message and flushes it to the client
+func (s *ServerStream) Send(m Marshaler) error {
+	if err := writeMessage(s.w, Marshal(m)); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// Server serves the methods registered on it
+type Server struct {
+	unary   map[string]UnaryHandler
+	streams map[string]StreamHandler
+}
+
+// NewServer returns a server without methods
+func NewServer() *Server {
+	return &Server{unary: make(map[string]UnaryHandler), streams: make(map[string]StreamHandler)}
+}
+
+// HandleUnary registers a unary method by its full name
+func (s *Server) HandleUnary(method string, handler UnaryHandler) {
+	s.unary[method] = handler
+}
+
+// HandleStream registers a server-streaming method by its full name
+func (s *Server) HandleStream(method string, handler StreamHandler) {
+	s.streams[method] = handler
+}
+
+// ServeHTTP serves a gRPC call
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Only gRPC over HTTP/2 is served
+	if r.ProtoMajor != 2 {
+		http.Error(w, "gRPC requires HTTP/2", http.StatusHTTPVersionNotSupported)
+		return
+	}
+	contentType := r.Header.Get("Content-Type")
+	if r.Method != http.MethodPost || contentType != "application/grpc" && !strings.HasPrefix(contentType, "application/grpc+proto") {
+		http.Error(w, "Not a gRPC request", http.StatusUnsupportedMediaType)
+		return
+	}
+	w.Header().Set("Content-Type", "application/grpc")
+	w.WriteHeader(http.StatusOK)
+
+	// Step 2: Apply the deadline of the client and read the request
+	ctx := r.Context()
+	if timeout, ok := parseTimeout(r.Header.Get("Grpc-Timeout")); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	unary, isUnary := s.unary[r.URL.Path]
+	stream, isStream := s.streams[r.URL.Path]
+	if !isUnary && !isStream {
+		writeStatus(w, &Status{Code: Unimplemented, Message: "unknown method " + r.URL.Path})
+		return
+	}
+	request, err := readMessage(r.Body)
+	if err != nil {
+		writeStatus(w, statusOf(err))
+		return
+	}
+	call := &Call{Header: r.Header, RemoteAddr: r.RemoteAddr, request: request}
+
+	// Step 3: Serve the call, and end it with its status
+	if isUnary {
+		response, err := unary(ctx, call)
+		if err == nil {
+			err = writeMessage(w, Marshal(response))
+		}
+		writeStatus(w, statusOrOK(err))
+		return
+	}
+	// The headers go out first: the client is waiting on them, and a
+	// stream may not send a message for a long time
+	rc := http.NewResponseController(w)
+	_ = rc.Flush()
+	err = stream(ctx, call, &ServerStream{w: w, rc: rc})
+	writeStatus(w, statusOrOK(err))
+}
+
+func statusOrOK(err error) *Status {
+	if err == nil {
+		return &Status{Code: OK}
+	}
+	return statusOf(err)
+}
+
+// writeStatus ends a call with its status, in the trailers
+func writeStatus(w http.ResponseWriter, status *Status) {
+	w.Header().Set(http.TrailerPrefix+"Grpc-Status", strconv.Itoa(int(status.Code)))
+	if status.Message != "" {
+		w.Header().Set(http.TrailerPrefix+"Grpc-Message", encodeMessage(status.Message))
+	}
+}
+
+// errNoMessage is the error of readMessage when the body ends before a
+// message starts: at the end of a response, that is not an error
+var errNoMessage = &Status{Code: InvalidArgument, Message: "the request has no message"}
+
+// readMessage reads the one message of a request: a compressed flag,
+// its length in 4 bytes, then the message
+func readMessage(r io.Reader) ([]byte, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(r, prefix[:]); errors.Is(err, io.EOF) {
+		return nil, errNoMessage
+	} else if err != nil {
+		return nil, Errorf(InvalidArgument, "the message was cut short")
+	}
+	if prefix[0] != 0 {
+		return nil, Errorf(Unimplemented, "compressed messages are not supported")
+	}
+	length := binary.BigEndian.Uint32(prefix[1:])
+	if length > MaxMessageSize {
+		return nil, Errorf(ResourceExhausted, "the message is larger than %d bytes", MaxMessageSize)
+	}
+	message := make([]byte, length)
+	if _, err := io.ReadFull(r, message); err != nil {
+		return nil, Errorf(InvalidArgument, "the message was cut short")
+	}
+	return message, nil
+}
+
+// writeMessage writes a message with its prefix
+func writeMessage(w io.Writer, message []byte) error {
+	frame := make([]byte, 5, 5+len(message))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+	_, err := w.Write(append(frame, message...))
+	return err
+}
+
+// parseTimeout parses a grpc-timeout: at most 8 digits and a unit
+func parseTimeout(value string) (time.Duration, bool) {
+	if len(value) < 2 || len(value) > 9 {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(value[:len(value)-1], 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	units := map[byte]time.Duration{
+		'H': time.Hour, 'M': time.Minute, 'S': time.Second,
+		'm': time.Millisecond, 'u': time.Microsecond, 'n': time.Nanosecond,
+	}
+	unit, ok := units[value[len(value)-1]]
+	return time.Duration(n) * unit, ok
+}
+
+// formatTimeout writes a deadline as a grpc-timeout, in milliseconds
+func formatTimeout(d time.Duration) string {
+	ms := d.Milliseconds()
+	if ms < 1 {
+		ms = 1
+	}
+	if ms > 99999999 {
+		return strconv.FormatInt(int64(d/time.Hour)+1, 10) + "H"
+	}
+	return strconv.FormatInt(ms, 10) + "m"
+}
+
+// encodeMessage percent-encodes a grpc-message, as the protocol requires
+// for the bytes that are not printable ASCII
+func encodeMessage(message string) string {
+	var b strings.Builder
+	for i := 0; i < len(message); i++ {
+		c := message[i]
+		if c >= ' ' && c <= '~' && c != '%' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// decodeMessage decodes a grpc-message; one that is not valid is kept as it is
+func decodeMessage(message string) string {
+	if decoded, err := url.PathUnescape(message); err == nil {
+		return decoded
+	}
+	return message
+}
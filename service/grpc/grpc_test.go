@@ -0,0 +1,379 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// prefixed returns a message with its prefix: the compressed flag and
+// the length
+func prefixed(compressed byte, length uint32, message []byte) []byte {
+	b := []byte{compressed}
+	b = binary.BigEndian.AppendUint32(b, length)
+	return append(b, message...)
+}
+
+func TestReadMessage(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		want  []byte
+		code  Code // OK if the message is read
+	}{
+		{"message", prefixed(0, 2, []byte("hi")), []byte("hi"), OK},
+		{"empty message", prefixed(0, 0, nil), []byte{}, OK},
+		{"only the first message is read", append(prefixed(0, 1, []byte("a")), prefixed(0, 1, []byte("b"))...), []byte("a"), OK},
+		{"no message", nil, nil, InvalidArgument},
+		{"prefix cut short", []byte{0, 0, 0}, nil, InvalidArgument},
+		{"message cut short", prefixed(0, 5, []byte("hi")), nil, InvalidArgument},
+		{"compressed", prefixed(1, 2, []byte("hi")), nil, Unimplemented},
+		{"at the largest size", prefixed(0, MaxMessageSize, make([]byte, MaxMessageSize)), make([]byte, MaxMessageSize), OK},
+		{"larger than the largest size", prefixed(0, MaxMessageSize+1, nil), nil, ResourceExhausted},
+		{"length of 4 GiB", prefixed(0, 0xFFFFFFFF, nil), nil, ResourceExhausted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message, err := readMessage(bytes.NewReader(tt.input))
+			if tt.code == OK {
+				if err != nil || !bytes.Equal(message, tt.want) {
+					t.Errorf("got %q %v, want %q", message, err, tt.want)
+				}
+				return
+			}
+			var status *Status
+			if !errors.As(err, &status) || status.Code != tt.code {
+				t.Errorf("got %v, want code %d", err, tt.code)
+			}
+		})
+	}
+}
+
+func TestWriteMessage(t *testing.T) {
+	var b bytes.Buffer
+	if err := writeMessage(&b, []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if want := prefixed(0, 5, []byte("hello")); !bytes.Equal(b.Bytes(), want) {
+		t.Errorf("wrote % x, want % x", b.Bytes(), want)
+	}
+	message, err := readMessage(&b)
+	if err != nil || string(message) != "hello" {
+		t.Errorf("read back %q %v", message, err)
+	}
+}
+
+func TestParseTimeout(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"1H", time.Hour, true},
+		{"90M", 90 * time.Minute, true},
+		{"30S", 30 * time.Second, true},
+		{"250m", 250 * time.Millisecond, true},
+		{"99999999u", 99999999 * time.Microsecond, true},
+		{"1n", time.Nanosecond, true},
+		{"0m", 0, true},
+		{"", 0, false},
+		{"m", 0, false},
+		{"100", 0, false},
+		{"100s", 0, false},
+		{"123456789m", 0, false},
+		{"-1S", 0, false},
+		{"1.5S", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseTimeout(tt.value)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseTimeout(%q) = %v %v, want %v %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFormatTimeout(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{1500 * time.Millisecond, "1500m"},
+		{time.Microsecond, "1m"},
+		{-time.Second, "1m"},
+		{99999999 * time.Millisecond, "99999999m"},
+		{100000000 * time.Millisecond, "28H"},
+	}
+	for _, tt := range tests {
+		got := formatTimeout(tt.d)
+		if got != tt.want {
+			t.Errorf("formatTimeout(%v) = %s, want %s", tt.d, got, tt.want)
+		}
+		if d, ok := parseTimeout(got); !ok || d < tt.d {
+			t.Errorf("%s parses back as %v %v, want at least %v", got, d, ok, tt.d)
+		}
+	}
+}
+
+func TestEncodeMessage(t *testing.T) {
+	message := "conversation « Famiglia » not found: 100%\n"
+	encoded := encodeMessage(message)
+	if want := "conversation %C2%AB Famiglia %C2%BB not found: 100%25%0A"; encoded != want {
+		t.Errorf("encoded %q, want %q", encoded, want)
+	}
+	if decoded := decodeMessage(encoded); decoded != message {
+		t.Errorf("decoded %q, want %q", decoded, message)
+	}
+	if decoded := decodeMessage("100% sure"); decoded != "100% sure" {
+		t.Errorf("an invalid encoding was changed to %q", decoded)
+	}
+}
+
+// newTestServer serves the server over unencrypted HTTP/2, and returns a
+// client of it
+func newTestServer(t *testing.T, handler http.Handler) *Client {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(handler)
+	srv.Config.Protocols = new(http.Protocols)
+	srv.Config.Protocols.SetUnencryptedHTTP2(true)
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return NewClient(srv.URL)
+}
+
+// echoServer has a unary method answering with its request, and a
+// stream sending it back once per child
+func echoServer() *Server {
+	s := NewServer()
+	s.HandleUnary("/test.Echo/Unary", func(_ context.Context, call *Call) (Marshaler, error) {
+		var m testMessage
+		if err := call.Decode(&m); err != nil {
+			return nil, err
+		}
+		if m.Name == "fail" {
+			return nil, Errorf(NotFound, "conversation « %s » not found", m.Name)
+		}
+		m.Name += " from " + call.Header.Get("X-Test")
+		return &m, nil
+	})
+	s.HandleUnary("/test.Echo/Deadline", func(ctx context.Context, _ *Call) (Marshaler, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	s.HandleStream("/test.Echo/Stream", func(_ context.Context, call *Call, stream *ServerStream) error {
+		var m testMessage
+		if err := call.Decode(&m); err != nil {
+			return err
+		}
+		for _, c := range m.Children {
+			if err := stream.Send(c); err != nil {
+				return err
+			}
+		}
+		if m.Name == "fail" {
+			return Errorf(Aborted, "stopped")
+		}
+		return nil
+	})
+	return s
+}
+
+func TestUnary(t *testing.T) {
+	client := newTestServer(t, echoServer())
+	client.Header.Set("X-Test", "the header")
+	ctx := context.Background()
+
+	var response testMessage
+	if err := client.Invoke(ctx, "/test.Echo/Unary", &testMessage{Name: "hi", Count: -5}, &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Name != "hi from the header" || response.Count != -5 {
+		t.Errorf("got %+v", response)
+	}
+
+	// An error, with a message that is not ASCII
+	err := client.Invoke(ctx, "/test.Echo/Unary", &testMessage{Name: "fail"}, &response)
+	var status *Status
+	if !errors.As(err, &status) || status.Code != NotFound || status.Message != "conversation « fail » not found" {
+		t.Errorf("got %v, want NotFound with the message", err)
+	}
+
+	err = client.Invoke(ctx, "/test.Echo/Missing", &testMessage{}, &response)
+	if !errors.As(err, &status) || status.Code != Unimplemented {
+		t.Errorf("unknown method: got %v, want Unimplemented", err)
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	// The client sends what is left of its deadline
+	timeouts := make(chan string, 1)
+	client := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timeouts <- r.Header.Get("Grpc-Timeout")
+		w.Header().Set("Content-Type", "application/grpc")
+		_ = writeMessage(w, nil)
+		w.Header().Set(http.TrailerPrefix+"Grpc-Status", "0")
+	}))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := client.Invoke(ctx, "/test.Echo/Deadline", &testMessage{}, new(testMessage)); err != nil {
+		t.Fatal(err)
+	}
+	if timeout, ok := parseTimeout(<-timeouts); !ok || timeout > time.Minute || timeout < 50*time.Second {
+		t.Errorf("grpc-timeout of %v, want about a minute", timeout)
+	}
+
+	// The server gives up at the deadline the client sent
+	srv := httptest.NewUnstartedServer(echoServer())
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/test.Echo/Deadline", bytes.NewReader(prefixed(0, 0, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("Grpc-Timeout", "50m")
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	if code := resp.Trailer.Get("Grpc-Status"); code != "4" {
+		t.Errorf("grpc-status %s, want 4 (deadline exceeded)", code)
+	}
+}
+
+func TestStream(t *testing.T) {
+	client := newTestServer(t, echoServer())
+	request := &testMessage{Children: []*testMessage{{Name: "a"}, {Name: "b"}, {}}}
+
+	stream, err := client.NewStream(context.Background(), "/test.Echo/Stream", request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	var names []string
+	for {
+		var m testMessage
+		err := stream.Recv(&m)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, m.Name)
+	}
+	if got := strings.Join(names, ","); got != "a,b," {
+		t.Errorf("received %q, want a, b and an empty message", got)
+	}
+
+	// A stream that fails after its messages
+	request.Name = "fail"
+	stream, err = client.NewStream(context.Background(), "/test.Echo/Stream", request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	var received int
+	for err == nil {
+		if err = stream.Recv(new(testMessage)); err == nil {
+			received++
+		}
+	}
+	var status *Status
+	if received != 3 || !errors.As(err, &status) || status.Code != Aborted {
+		t.Errorf("received %d messages and %v, want 3 and Aborted", received, err)
+	}
+}
+
+// TestBadRequests sends what the client never would
+func TestBadRequests(t *testing.T) {
+	srv := httptest.NewUnstartedServer(echoServer())
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+	httpClient := srv.Client()
+
+	post := func(path, contentType string, body []byte) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, srv.URL+path, bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", contentType)
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		return resp
+	}
+
+	if resp := post("/test.Echo/Unary", "application/json", prefixed(0, 0, nil)); resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("not gRPC: status %d, want %d", resp.StatusCode, http.StatusUnsupportedMediaType)
+	}
+
+	tests := []struct {
+		name string
+		body []byte
+		code string
+	}{
+		{"valid", prefixed(0, 4, []byte{0x0a, 0x02, 'h', 'i'}), "0"},
+		{"no message", nil, "3"},
+		{"prefix cut short", []byte{0, 0}, "3"},
+		{"message cut short", prefixed(0, 10, []byte{0x0a}), "3"},
+		{"compressed", prefixed(1, 0, nil), "12"},
+		{"too large", prefixed(0, MaxMessageSize+1, nil), "8"},
+		{"not protobuf", prefixed(0, 2, []byte{0x0a, 0x05}), "3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := post("/test.Echo/Unary", "application/grpc+proto", tt.body)
+			if resp.StatusCode != http.StatusOK || resp.ProtoMajor != 2 {
+				t.Fatalf("status %d over HTTP/%d", resp.StatusCode, resp.ProtoMajor)
+			}
+			if code := resp.Trailer.Get("Grpc-Status"); code != tt.code {
+				t.Errorf("grpc-status %s, want %s (%s)", code, tt.code, resp.Trailer.Get("Grpc-Message"))
+			}
+		})
+	}
+}
+
+// TestClientBadResponses reads responses the server never sends
+func TestClientBadResponses(t *testing.T) {
+	tests := []struct {
+		name string
+		body []byte
+		code Code
+	}{
+		{"no message", nil, Internal},
+		{"two messages", append(prefixed(0, 0, nil), prefixed(0, 0, nil)...), Internal},
+		{"prefix cut short", []byte{0, 0, 0}, Internal},
+		{"message cut short", prefixed(0, 10, []byte{0x0a, 0x02, 'h', 'i'}), Internal},
+		{"a message, then one cut short", append(prefixed(0, 0, nil), 0, 0, 0, 0, 10, 0x0a), Internal},
+		{"not protobuf", prefixed(0, 2, []byte{0x0a, 0x05}), Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", "application/grpc")
+				_, _ = w.Write(tt.body)
+				w.Header().Set(http.TrailerPrefix+"Grpc-Status", "0")
+			}))
+			err := client.Invoke(context.Background(), "/test.Echo/Unary", &testMessage{}, new(testMessage))
+			var status *Status
+			if !errors.As(err, &status) || status.Code != tt.code {
+				t.Errorf("got %v, want code %d", err, tt.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package grpc
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// Wire types of the protobuf encoding
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// ErrMalformed is returned when decoding a message that is not valid protobuf
+var ErrMalformed = errors.New("grpc: malformed protobuf message")
+
+// Marshaler is a message that writes its fields to an Encoder
+type Marshaler interface {
+	MarshalProto(e *Encoder)
+}
+
+// Unmarshaler is a message that reads its fields from a Decoder
+type Unmarshaler interface {
+	UnmarshalProto(d *Decoder) error
+}
+
+// Marshal encodes a message
+func Marshal(m Marshaler) []byte {
+	var e Encoder
+	m.MarshalProto(&e)
+	return e.buf
+}
+
+// Unmarshal decodes a message
+func Unmarshal(data []byte, m Unmarshaler) error {
+	return m.UnmarshalProto(&Decoder{data: data})
+}
+
+/*
+Encoder writes the fields of a message. Like proto3, it leaves out the
+fields that have their zero value; a repeated field is written by
+calling the method once per item.
+*/
+type Encoder struct {
+	buf []byte
+}
+
+func (e *Encoder) tag(field, wireType int) {
+	e.buf = binary.AppendUvarint(e.buf, uint64(field)<<3|uint64(wireType))
+}
+
+// String writes a string field
+func (e *Encoder) String(field int, s string) {
+	if s == "" {
+		return
+	}
+	e.tag(field, wireBytes)
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+// Bool writes a bool field
+func (e *Encoder) Bool(field int, b bool) {
+	if b {
+		e.tag(field, wireVarint)
+		e.buf = append(e.buf, 1)
+	}
+}
+
+// Int64 writes an int64 field; Int32 fields are written the same way
+func (e *Encoder) Int64(field int, n int64) {
+	if n != 0 {
+		e.tag(field, wireVarint)
+		e.buf = binary.AppendUvarint(e.buf, uint64(n))
+	}
+}
+
+// Message writes a message field, even when it is empty; nil is left out
+func (e *Encoder) Message(field int, m Marshaler) {
+	if m == nil {
+		return
+	}
+	data := Marshal(m)
+	e.tag(field, wireBytes)
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(data)))
+	e.buf = append(e.buf, data...)
+}
+
+/*
+Decoder reads the fields of a message, in the order they were written:
+
+	for d.Next() {
+		switch d.Field() {
+		case 1:
+			m.Name = d.String()
+		}
+	}
+	return d.Err()
+
+Fields the message does not read are skipped, as protobuf requires for
+the fields added by newer versions.
+*/
+type Decoder struct {
+	data     []byte
+	field    int
+	wireType int
+	varint   uint64
+	bytes    []byte
+	err      error
+}
+
+// Next reads the next field; it returns false at the end of the message
+// or on an error
+func (d *Decoder) Next() bool {
+	if d.err != nil || len(d.data) == 0 {
+		return false
+	}
+	key, n := binary.Uvarint(d.data)
+	if n <= 0 || key>>3 == 0 || key>>3 > math.MaxInt32 {
+		d.err = ErrMalformed
+		return false
+	}
+	d.data = d.data[n:]
+	d.field, d.wireType = int(key>>3), int(key&7)
+
+	switch d.wireType {
+	case wireVarint:
+		d.varint, n = binary.Uvarint(d.data)
+	case wireBytes:
+		var length uint64
+		length, n = binary.Uvarint(d.data)
+		if n > 0 && length > uint64(len(d.data)-n) {
+			n = 0
+		}
+		if n > 0 {
+			d.bytes = d.data[n : n+int(length)]
+			n += int(length)
+		}
+	case wireFixed64:
+		n = 8
+	case wireFixed32:
+		n = 4
+	default:
+		n = 0 // groups are not supported
+	}
+	if n <= 0 || n > len(d.data) {
+		d.err = ErrMalformed
+		return false
+	}
+	d.data = d.data[n:]
+	return true
+}
+
+// Field returns the number of the field read by Next
+func (d *Decoder) Field() int {
+	return d.field
+}
+
+// Err returns the error met decoding, nil if there was none
+func (d *Decoder) Err() error {
+	return d.err
+}
+
+// expect checks the wire type of the field read
+func (d *Decoder) expect(wireType int) bool {
+	if d.wireType != wireType {
+		d.err = ErrMalformed
+		return false
+	}
+	return true
+}
+
+// String returns the value of a string field
+func (d *Decoder) String() string {
+	if !d.expect(wireBytes) {
+		return ""
+	}
+	return string(d.bytes)
+}
+
+// Bool returns the value of a bool field
+func (d *Decoder) Bool() bool {
+	return d.expect(wireVarint) && d.varint != 0
+}
+
+// Int64 returns the value of an int64 field
+func (d *Decoder) Int64() int64 {
+	if !d.expect(wireVarint) {
+		return 0
+	}
+	return int64(d.varint)
+}
+
+// Int32 returns the value of an int32 field
+func (d *Decoder) Int32() int32 {
+	return int32(d.Int64())
+}
+
+// Message decodes the value of a message field into m
+func (d *Decoder) Message(m Unmarshaler) {
+	if !d.expect(wireBytes) {
+		return
+	}
+	if err := Unmarshal(d.bytes, m); err != nil {
+		d.err = err
+	}
+}
@@ -0,0 +1,174 @@
+package grpc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// testMessage has a field of every kind the encoder writes
+type testMessage struct {
+	Name     string
+	Flag     bool
+	Count    int64
+	Small    int32
+	Children []*testMessage
+}
+
+func (m *testMessage) MarshalProto(e *Encoder) {
+	e.String(1, m.Name)
+	e.Bool(2, m.Flag)
+	e.Int64(3, m.Count)
+	e.Int64(4, int64(m.Small))
+	for _, c := range m.Children {
+		e.Message(5, c)
+	}
+}
+
+func (m *testMessage) UnmarshalProto(d *Decoder) error {
+	for d.Next() {
+		switch d.Field() {
+		case 1:
+			m.Name = d.String()
+		case 2:
+			m.Flag = d.Bool()
+		case 3:
+			m.Count = d.Int64()
+		case 4:
+			m.Small = d.Int32()
+		case 5:
+			c := new(testMessage)
+			d.Message(c)
+			m.Children = append(m.Children, c)
+		}
+	}
+	return d.Err()
+}
+
+// mustHex decodes hex or fails the test
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// TestEncoding checks the bytes written against the protobuf encoding of
+// the message, worked out by hand, and that they decode back
+func TestEncoding(t *testing.T) {
+	tests := []struct {
+		name string
+		m    *testMessage
+		want string // hex
+	}{
+		{"empty", &testMessage{}, ""},
+		{"string", &testMessage{Name: "hi"}, "0a026869"},
+		{"utf-8 string", &testMessage{Name: "ciao ☕"}, "0a086369616f20e29895"},
+		{"bool", &testMessage{Flag: true}, "1001"},
+		{"varint of one byte", &testMessage{Count: 127}, "187f"},
+		{"varint of two bytes", &testMessage{Count: 128}, "188001"},
+		{"varint 300", &testMessage{Count: 300}, "18ac02"},
+		{"largest int64", &testMessage{Count: math.MaxInt64}, "18ffffffffffffffff7f"},
+		{"negative int64", &testMessage{Count: -1}, "18ffffffffffffffffff01"},
+		{"smallest int64", &testMessage{Count: math.MinInt64}, "1880808080808080808001"},
+		{"negative int32, sign extended", &testMessage{Small: -2}, "20feffffffffffffffff01"},
+		{"smallest int32", &testMessage{Small: math.MinInt32}, "2080808080f8ffffffff01"},
+		{"empty nested message", &testMessage{Children: []*testMessage{{}}}, "2a00"},
+		{
+			"nested and repeated",
+			&testMessage{Name: "a", Children: []*testMessage{{Name: "b"}, {Count: 1, Children: []*testMessage{{Flag: true}}}}},
+			"0a0161" + "2a030a0162" + "2a06" + "1801" + "2a021001",
+		},
+		{
+			"string longer than 127 bytes",
+			&testMessage{Name: string(bytes.Repeat([]byte{'x'}, 200))},
+			"0ac801" + hex.EncodeToString(bytes.Repeat([]byte{'x'}, 200)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := Marshal(tt.m)
+			if got := hex.EncodeToString(data); got != tt.want {
+				t.Errorf("encoded %s, want %s", got, tt.want)
+			}
+			decoded := new(testMessage)
+			if err := Unmarshal(data, decoded); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(decoded, tt.m) {
+				t.Errorf("decoded %+v, want %+v", decoded, tt.m)
+			}
+		})
+	}
+}
+
+// TestDecodeUnknownFields decodes a message written by a newer version,
+// with fields of every wire type this one does not know
+func TestDecodeUnknownFields(t *testing.T) {
+	data := mustHex(t, ""+
+		"0a026869"+ // name: "hi"
+		"30ac02"+ // field 6, varint
+		"390102030405060708"+ // field 7, fixed64
+		"4501020304"+ // field 8, fixed32
+		"520401020304"+ // field 10, bytes
+		"f8ffffff0f01"+ // field 536870911 (the largest), varint
+		"1801", // count: 1
+	)
+	var m testMessage
+	if err := Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Name != "hi" || m.Count != 1 {
+		t.Errorf("decoded %+v, want the known fields around the unknown ones", m)
+	}
+}
+
+func TestDecodeLastValueWins(t *testing.T) {
+	var m testMessage
+	if err := Unmarshal(mustHex(t, "0a01611801"+"0a01621802"), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Name != "b" || m.Count != 2 {
+		t.Errorf("decoded %+v, want the last value of each field", m)
+	}
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		data string // hex
+	}{
+		{"truncated tag", "80"},
+		{"field 0", "0001"},
+		{"field number too large", "8080808080800101"},
+		{"truncated varint", "1880"},
+		{"varint of 11 bytes", "18ffffffffffffffffffff01"},
+		{"varint over 64 bits", "18ffffffffffffffffff02"},
+		{"truncated length", "0a"},
+		{"length past the end", "0a0568"},
+		{"length of 2^63", "0a8080808080808080800168"},
+		{"truncated fixed64", "3901020304"},
+		{"truncated fixed32", "45010203"},
+		{"start group", "33"},
+		{"end group", "34"},
+		{"wire type 6", "36"},
+		{"wire type 7", "37"},
+		{"string as a varint", "0801"},
+		{"varint as bytes", "1a0101"},
+		{"malformed nested message", "2a020a05"},
+		{"malformed after a good field", "0a026869" + "1880"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m testMessage
+			if err := Unmarshal(mustHex(t, tt.data), &m); !errors.Is(err, ErrMalformed) {
+				t.Errorf("got %v, want ErrMalformed", err)
+			}
+		})
+	}
+}
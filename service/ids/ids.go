@@ -0,0 +1,192 @@
+/*
+Package ids defines the identifier types used across WASAText.
+
+Users, conversations, messages and groups are identified by UUIDs, or
+by short base62 IDs when the deployment chooses so (SetFormat). Giving
+each its own type lets the compiler catch mixups such as passing a group
+ID where a conversation ID is expected, and the Parse functions give the
+API one place to reject malformed IDs from the outside world.
+
+The format only decides how new IDs are generated: IDs of both formats
+are always accepted, so a deployment can switch without breaking the IDs
+it already handed out.
+
+The types are plain strings underneath, so they can be used directly as
+SQL parameters, scanned from rows and encoded in JSON.
+*/
+package ids
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/gofrs/uuid"
+)
+
+// ErrInvalidID is returned when a string is not a valid identifier
+var ErrInvalidID = errors.New("invalid identifier")
+
+// Format is how new identifiers are generated
+type Format string
+
+// Identifier formats
+const (
+	FormatUUID  Format = "uuid"  // random (version 4) UUIDs, the default
+	FormatShort Format = "short" // ShortLength random base62 characters
+)
+
+// Short IDs are ShortLength characters long when generated, and between
+// MinShortLength and MaxShortLength when parsed
+const (
+	ShortLength    = 11 // about 65 random bits
+	MinShortLength = 10
+	MaxShortLength = 12
+)
+
+// base62 is the alphabet of the short IDs
+const base62 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// format is the Format of new identifiers
+var format atomic.Value
+
+// ParseFormat validates an identifier format ("" means FormatUUID)
+func ParseFormat(s string) (Format, error) {
+	switch Format(s) {
+	case "", FormatUUID:
+		return FormatUUID, nil
+	case FormatShort:
+		return FormatShort, nil
+	}
+	return "", errors.New("invalid identifier format: " + s + " (uuid or short)")
+}
+
+// SetFormat sets the format of the identifiers generated from now on
+func SetFormat(f Format) {
+	format.Store(f)
+}
+
+// CurrentFormat returns the format of new identifiers
+func CurrentFormat() Format {
+	f, _ := format.Load().(Format)
+	if f == "" {
+		return FormatUUID
+	}
+	return f
+}
+
+// UserID identifies a user
+type UserID string
+
+// ConversationID identifies a conversation (direct or group)
+type ConversationID string
+
+// MessageID identifies a message
+type MessageID string
+
+// GroupID identifies a group. A group has its own conversation,
+// whose ConversationID is different from the GroupID.
+type GroupID string
+
+// ParseUserID validates a user ID
+func ParseUserID(s string) (UserID, error) {
+	id, err := parse(s)
+	return UserID(id), err
+}
+
+// ParseConversationID validates a conversation ID
+func ParseConversationID(s string) (ConversationID, error) {
+	id, err := parse(s)
+	return ConversationID(id), err
+}
+
+// ParseMessageID validates a message ID
+func ParseMessageID(s string) (MessageID, error) {
+	id, err := parse(s)
+	return MessageID(id), err
+}
+
+// ParseGroupID validates a group ID
+func ParseGroupID(s string) (GroupID, error) {
+	id, err := parse(s)
+	return GroupID(id), err
+}
+
+// NewUserID generates a new random user ID
+func NewUserID() (UserID, error) {
+	id, err := generate()
+	return UserID(id), err
+}
+
+// NewConversationID generates a new random conversation ID
+func NewConversationID() (ConversationID, error) {
+	id, err := generate()
+	return ConversationID(id), err
+}
+
+// NewMessageID generates a new random message ID
+func NewMessageID() (MessageID, error) {
+	id, err := generate()
+	return MessageID(id), err
+}
+
+// NewGroupID generates a new random group ID
+func NewGroupID() (GroupID, error) {
+	id, err := generate()
+	return GroupID(id), err
+}
+
+// parse checks that s is a short ID, or a UUID which it returns in
+// canonical form
+func parse(s string) (string, error) {
+	if IsShort(s) {
+		return s, nil
+	}
+	u, err := uuid.FromString(s)
+	if err != nil {
+		return "", ErrInvalidID
+	}
+	return u.String(), nil
+}
+
+// IsShort reports whether s is a short ID. Short IDs are case-sensitive,
+// and too short to be mistaken for a UUID.
+func IsShort(s string) bool {
+	if len(s) < MinShortLength || len(s) > MaxShortLength {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z') {
+			return false
+		}
+	}
+	return true
+}
+
+// generate returns a new random ID in the current format
+func generate() (string, error) {
+	if CurrentFormat() == FormatShort {
+		return generateShort()
+	}
+	u, err := uuid.NewV4()
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// generateShort returns ShortLength random base62 characters
+func generateShort() (string, error) {
+	max := big.NewInt(int64(len(base62)))
+	id := make([]byte, ShortLength)
+	for i := range id {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		id[i] = base62[n.Int64()]
+	}
+	return string(id), nil
+}
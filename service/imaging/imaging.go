@@ -0,0 +1,75 @@
+/*
+Package imaging checks the uploaded photos and scales them down, for the
+inlined thumbnails of the exports and the smaller renditions served to
+clients.
+
+Users can send JPEG, PNG, GIF and WebP photos (see Check), stripped of
+their metadata (see StripMetadata). Photos are decoded from JPEG, PNG
+and GIF and always written back as JPEG; WebP has no decoder here, so a
+WebP photo is only served as it is.
+*/
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+
+	// Decoders for the image formats users can send
+	_ "image/gif"
+	_ "image/png"
+)
+
+// JPEGQuality is the quality of the JPEGs written by Fit
+const JPEGQuality = 80
+
+// Fits reports whether a photo already fits in size x size, reading
+// only its header
+func Fits(photo []byte, size int) (bool, error) {
+	if Sniff(photo) == FormatWebP {
+		width, height, err := webpDimensions(photo)
+		return width <= size && height <= size, err
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(photo))
+	if err != nil {
+		return false, err
+	}
+	return config.Width <= size && config.Height <= size, nil
+}
+
+// Fit scales a photo down to fit in size x size, keeping its aspect
+// ratio, and returns it as a JPEG. A smaller photo keeps its size.
+func Fit(photo []byte, size int) ([]byte, error) {
+	src, _, err := image.Decode(bytes.NewReader(photo))
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > size || height > size {
+		if width >= height {
+			height = max(1, height*size/width)
+			width = size
+		} else {
+			width = max(1, width*size/height)
+			height = size
+		}
+	}
+
+	// Nearest-neighbour scaling is plenty for photos shown small
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		sy := bounds.Min.Y + y*bounds.Dy()/height
+		for x := 0; x < width; x++ {
+			sx := bounds.Min.X + x*bounds.Dx()/width
+			dst.Set(x, y, src.At(sx, sy))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: JPEGQuality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
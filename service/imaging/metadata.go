@@ -0,0 +1,224 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+/*
+StripMetadata removes the metadata a camera or a phone stores along the
+pixels of a photo of the given format (see Check), which can tell where
+and with what it was taken: the EXIF and XMP blocks and the IPTC
+records of a JPEG, the EXIF and text chunks of a PNG, the EXIF and XMP
+chunks of a WebP. The pixels, the color profile and the animation are
+kept as they are, without re-encoding. GIFs carry no EXIF and are
+returned unchanged.
+
+The orientation is the one EXIF tag a viewer needs: a JPEG keeps it, in
+an EXIF block of its own, so that portrait photos do not turn sideways.
+*/
+func StripMetadata(photo []byte, format string) ([]byte, error) {
+	switch format {
+	case FormatJPEG:
+		return stripJPEG(photo)
+	case FormatPNG:
+		return stripPNG(photo)
+	case FormatWebP:
+		return stripWebP(photo)
+	case FormatGIF:
+		return photo, nil
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+// JPEG markers
+const (
+	jpegSOI   = 0xd8
+	jpegSOS   = 0xda
+	jpegAPP0  = 0xe0
+	jpegAPP1  = 0xe1 // EXIF and XMP
+	jpegAPP13 = 0xed // Photoshop resources, where IPTC is kept
+)
+
+// stripJPEG drops the APP1 and APP13 segments of a JPEG before its scan
+func stripJPEG(photo []byte) ([]byte, error) {
+	if len(photo) < 2 || photo[0] != 0xff || photo[1] != jpegSOI {
+		return nil, ErrMalformed
+	}
+	out := make([]byte, 0, len(photo))
+	out = append(out, photo[:2]...)
+	var orientation []byte // the APP1 segment replacing the EXIF
+	wroteOrientation := false
+
+	rest := photo[2:]
+	for {
+		// Markers may be preceded by fill bytes
+		i := 0
+		for i < len(rest) && rest[i] == 0xff {
+			i++
+		}
+		if i == 0 || i >= len(rest) {
+			return nil, ErrMalformed
+		}
+		marker := rest[i]
+		segment := rest[i-1:] // from the last 0xff
+		if marker == jpegSOS {
+			// The scan and what follows are copied as they are
+			if orientation != nil && !wroteOrientation {
+				out = append(out, orientation...)
+			}
+			out = append(out, segment...)
+			return out, nil
+		}
+		if marker == 0x01 || (marker >= 0xd0 && marker <= 0xd7) {
+			// Markers without a segment
+			out = append(out, segment[:2]...)
+			rest = segment[2:]
+			continue
+		}
+		if len(segment) < 4 {
+			return nil, ErrMalformed
+		}
+		length := int(binary.BigEndian.Uint16(segment[2:4]))
+		if length < 2 || 2+length > len(segment) {
+			return nil, ErrMalformed
+		}
+
+		switch {
+		case marker == jpegAPP1 && bytes.HasPrefix(segment[4:], []byte("Exif\x00\x00")):
+			if value, ok := exifOrientation(segment[10 : 2+length]); ok && value != 1 {
+				orientation = orientationSegment(value)
+			}
+		case marker == jpegAPP1, marker == jpegAPP13:
+		default:
+			// The EXIF block goes after JFIF, where viewers expect it
+			if orientation != nil && !wroteOrientation && marker != jpegAPP0 {
+				out = append(out, orientation...)
+				wroteOrientation = true
+			}
+			out = append(out, segment[:2+length]...)
+		}
+		rest = segment[2+length:]
+	}
+}
+
+// exifOrientation reads the Orientation tag of the first IFD of an EXIF
+// block (the TIFF structure after "Exif\0\0")
+func exifOrientation(tiff []byte) (uint16, bool) {
+	if len(tiff) < 8 {
+		return 0, false
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0, false
+	}
+	ifd := int64(order.Uint32(tiff[4:8]))
+	if ifd+2 > int64(len(tiff)) {
+		return 0, false
+	}
+	count := int64(order.Uint16(tiff[ifd:]))
+	for i := int64(0); i < count; i++ {
+		entry := ifd + 2 + 12*i
+		if entry+12 > int64(len(tiff)) {
+			return 0, false
+		}
+		// Tag 0x0112 is Orientation, a SHORT from 1 to 8
+		if order.Uint16(tiff[entry:]) == 0x0112 && order.Uint16(tiff[entry+2:]) == 3 {
+			value := order.Uint16(tiff[entry+8:])
+			return value, value >= 1 && value <= 8
+		}
+	}
+	return 0, false
+}
+
+// orientationSegment returns an APP1 segment whose EXIF only holds an
+// Orientation tag
+func orientationSegment(value uint16) []byte {
+	tiff := []byte{
+		'M', 'M', 0, 42, 0, 0, 0, 8, // big endian, first IFD at 8
+		0, 1, // one entry
+		0x01, 0x12, 0, 3, 0, 0, 0, 1, byte(value >> 8), byte(value), 0, 0, // Orientation, SHORT
+		0, 0, 0, 0, // no next IFD
+	}
+	segment := []byte{0xff, jpegAPP1, 0, 0}
+	segment = append(segment, "Exif\x00\x00"...)
+	segment = append(segment, tiff...)
+	binary.BigEndian.PutUint16(segment[2:4], uint16(len(segment)-2))
+	return segment
+}
+
+// pngMetadata are the PNG chunks dropped: EXIF, texts (where XMP is
+// kept too) and the modification time
+var pngMetadata = map[string]bool{"eXIf": true, "tEXt": true, "zTXt": true, "iTXt": true, "tIME": true}
+
+// stripPNG drops the metadata chunks of a PNG
+func stripPNG(photo []byte) ([]byte, error) {
+	const signature = 8
+	if len(photo) < signature {
+		return nil, ErrMalformed
+	}
+	out := make([]byte, 0, len(photo))
+	out = append(out, photo[:signature]...)
+	rest := photo[signature:]
+	for len(rest) > 0 {
+		if len(rest) < 12 {
+			return nil, ErrMalformed
+		}
+		length := int64(binary.BigEndian.Uint32(rest[:4]))
+		if 12+length > int64(len(rest)) {
+			return nil, ErrMalformed
+		}
+		chunk := rest[:12+length]
+		if !pngMetadata[string(chunk[4:8])] {
+			out = append(out, chunk...)
+		}
+		rest = rest[12+length:]
+		if string(chunk[4:8]) == "IEND" {
+			break
+		}
+	}
+	return out, nil
+}
+
+// VP8X flags telling which metadata chunks follow
+const (
+	webpFlagXMP  = 0x04
+	webpFlagEXIF = 0x08
+)
+
+// stripWebP drops the EXIF and XMP chunks of a WebP and their flags
+func stripWebP(photo []byte) ([]byte, error) {
+	chunks, err := webpChunks(photo)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformed, err)
+	}
+	out := make([]byte, 12, len(photo))
+	copy(out, "RIFF\x00\x00\x00\x00WEBP")
+	for _, chunk := range chunks {
+		data := chunk.data
+		switch chunk.fourCC {
+		case "EXIF", "XMP ":
+			continue
+		case "VP8X":
+			if len(data) > 0 {
+				data = append([]byte{data[0] &^ (webpFlagXMP | webpFlagEXIF)}, data[1:]...)
+			}
+		}
+		header := make([]byte, 8)
+		copy(header, chunk.fourCC)
+		binary.LittleEndian.PutUint32(header[4:], uint32(len(data)))
+		out = append(out, header...)
+		out = append(out, data...)
+		if len(data)%2 == 1 {
+			out = append(out, 0)
+		}
+	}
+	binary.LittleEndian.PutUint32(out[4:8], uint32(len(out)-8))
+	return out, nil
+}
@@ -0,0 +1,190 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+)
+
+// Formats of the photos users can send
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	FormatGIF  = "gif"
+	FormatWebP = "webp"
+)
+
+// Errors of Check
+var (
+	// ErrUnsupportedFormat is returned for a file that is not a JPEG, PNG,
+	// GIF or WebP image, whatever its name or content type claims
+	ErrUnsupportedFormat = errors.New("not a JPEG, PNG, GIF or WebP image")
+
+	// ErrMalformed is returned for a file that starts like an image of a
+	// supported format but cannot be read as one
+	ErrMalformed = errors.New("malformed image")
+
+	// ErrTooLarge is returned for an image wider or higher than allowed
+	ErrTooLarge = errors.New("image too large")
+)
+
+// Info describes a photo
+type Info struct {
+	Format        string // one of the Format constants
+	Width, Height int    // in pixels
+}
+
+// Sniff returns the format of a photo from its magic bytes, "" when it
+// is none of the supported formats
+func Sniff(photo []byte) string {
+	switch {
+	case bytes.HasPrefix(photo, []byte("\xff\xd8\xff")):
+		return FormatJPEG
+	case bytes.HasPrefix(photo, []byte("\x89PNG\r\n\x1a\n")):
+		return FormatPNG
+	case bytes.HasPrefix(photo, []byte("GIF87a")), bytes.HasPrefix(photo, []byte("GIF89a")):
+		return FormatGIF
+	case len(photo) >= 12 && string(photo[:4]) == "RIFF" && string(photo[8:12]) == "WEBP":
+		return FormatWebP
+	}
+	return ""
+}
+
+/*
+Check validates an uploaded photo: its format is sniffed from its magic
+bytes, it must be no wider and no higher than maxDimension pixels (0 for
+no limit), and it must decode. The size is checked on the header before
+the pixels are decoded, so that a small file claiming huge dimensions
+is refused without allocating them.
+
+WebP has no decoder in the standard library: its container and the
+header of its bitstream are checked, not the pixels.
+*/
+func Check(photo []byte, maxDimension int) (Info, error) {
+	// Step 1: Sniff the format
+	info := Info{Format: Sniff(photo)}
+	if info.Format == "" {
+		return info, ErrUnsupportedFormat
+	}
+
+	// Step 2: Read the dimensions from the header
+	var err error
+	info.Width, info.Height, err = dimensions(photo, info.Format)
+	if err != nil {
+		return info, fmt.Errorf("%w: %w", ErrMalformed, err)
+	}
+	if info.Width <= 0 || info.Height <= 0 {
+		return info, fmt.Errorf("%w: empty image", ErrMalformed)
+	}
+	if maxDimension > 0 && (info.Width > maxDimension || info.Height > maxDimension) {
+		return info, fmt.Errorf("%w: %dx%d pixels", ErrTooLarge, info.Width, info.Height)
+	}
+
+	// Step 3: Decode the pixels, which catches truncated files
+	switch info.Format {
+	case FormatJPEG:
+		_, err = jpeg.Decode(bytes.NewReader(photo))
+	case FormatPNG:
+		_, err = png.Decode(bytes.NewReader(photo))
+	case FormatGIF:
+		_, err = gif.Decode(bytes.NewReader(photo))
+	}
+	if err != nil {
+		return info, fmt.Errorf("%w: %w", ErrMalformed, err)
+	}
+	return info, nil
+}
+
+// dimensions reads the size of a photo from its header
+func dimensions(photo []byte, format string) (width, height int, err error) {
+	var config image.Config
+	switch format {
+	case FormatJPEG:
+		config, err = jpeg.DecodeConfig(bytes.NewReader(photo))
+	case FormatPNG:
+		config, err = png.DecodeConfig(bytes.NewReader(photo))
+	case FormatGIF:
+		config, err = gif.DecodeConfig(bytes.NewReader(photo))
+	case FormatWebP:
+		return webpDimensions(photo)
+	default:
+		return 0, 0, ErrUnsupportedFormat
+	}
+	return config.Width, config.Height, err
+}
+
+// webpChunk is a chunk of a WebP file
+type webpChunk struct {
+	fourCC string
+	data   []byte
+}
+
+// webpChunks splits a WebP file into its chunks, checking they fit in it
+func webpChunks(photo []byte) ([]webpChunk, error) {
+	if len(photo) < 12 {
+		return nil, errors.New("truncated RIFF header")
+	}
+	size := int64(binary.LittleEndian.Uint32(photo[4:8]))
+	if size < 4 || 8+size > int64(len(photo)) {
+		return nil, errors.New("truncated RIFF container")
+	}
+	body := photo[12 : 8+size]
+
+	var chunks []webpChunk
+	for len(body) > 0 {
+		if len(body) < 8 {
+			return nil, errors.New("truncated chunk header")
+		}
+		chunkSize := int64(binary.LittleEndian.Uint32(body[4:8]))
+		if 8+chunkSize > int64(len(body)) {
+			return nil, fmt.Errorf("truncated %q chunk", body[:4])
+		}
+		chunks = append(chunks, webpChunk{fourCC: string(body[:4]), data: body[8 : 8+chunkSize]})
+		// Chunks are padded to an even size
+		next := min(8+chunkSize+chunkSize%2, int64(len(body)))
+		body = body[next:]
+	}
+	if len(chunks) == 0 {
+		return nil, errors.New("no chunk")
+	}
+	return chunks, nil
+}
+
+// webpDimensions reads the size of a WebP from its first chunk: the
+// extended header, or the header of a lossy or lossless bitstream
+func webpDimensions(photo []byte) (width, height int, err error) {
+	chunks, err := webpChunks(photo)
+	if err != nil {
+		return 0, 0, err
+	}
+	data := chunks[0].data
+	switch chunks[0].fourCC {
+	case "VP8X":
+		if len(data) < 10 {
+			return 0, 0, errors.New("truncated VP8X chunk")
+		}
+		width = 1 + int(uint32(data[4])|uint32(data[5])<<8|uint32(data[6])<<16)
+		height = 1 + int(uint32(data[7])|uint32(data[8])<<8|uint32(data[9])<<16)
+	case "VP8 ":
+		if len(data) < 10 || !bytes.Equal(data[3:6], []byte{0x9d, 0x01, 0x2a}) {
+			return 0, 0, errors.New("bad VP8 frame header")
+		}
+		width = int(binary.LittleEndian.Uint16(data[6:8]) & 0x3fff)
+		height = int(binary.LittleEndian.Uint16(data[8:10]) & 0x3fff)
+	case "VP8L":
+		if len(data) < 5 || data[0] != 0x2f {
+			return 0, 0, errors.New("bad VP8L header")
+		}
+		bits := binary.LittleEndian.Uint32(data[1:5])
+		width = 1 + int(bits&0x3fff)
+		height = 1 + int(bits>>14&0x3fff)
+	default:
+		return 0, 0, fmt.Errorf("unexpected %q chunk first", chunks[0].fourCC)
+	}
+	return width, height, nil
+}
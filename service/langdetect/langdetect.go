@@ -0,0 +1,215 @@
+/*
+Package langdetect guesses the language of a message from its letters.
+
+It is a small character n-gram detector in the manner of Cavnar and
+Trenkle: each language has a profile, the trigrams of a sample text
+ranked by frequency, and a text is given the language whose profile is
+closest to its own trigrams. The languages of alphabets used by a single
+language (Greek, Hebrew, Thai, Korean, Japanese, Chinese) are told by
+their script alone.
+
+Short texts ("ok", "ahah", a link) say little about their language:
+Detect then answers "" rather than guess.
+*/
+package langdetect
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// profileSize is how many trigrams a profile keeps
+	profileSize = 300
+
+	// minLetters is the fewest letters a text needs to be detected
+	minLetters = 12
+
+	// minMargin is how much closer to its profile than to the next one
+	// a text must be, as a share of the largest distance
+	minMargin = 0.02
+)
+
+// samples are everyday texts in each language detected by n-grams
+var samples = map[string]string{
+	"en": `Hi, how are you doing today? I was thinking about going to the cinema tonight with some friends, do you want to come with us?
+		The movie starts at eight, so we could have dinner first near the station. Let me know what you think, and tell me if you need a ride.
+		I have just finished work and I am really tired, but it was a good day. The meeting with the new team went well and they liked our idea.
+		Could you send me the photos from the weekend? Thanks a lot, see you soon! By the way, where did you put the keys of the car?
+		We should also buy something for the party on Saturday, maybe a cake or a bottle of wine. What time does the shop close?`,
+	"it": `Ciao, come stai oggi? Stavo pensando di andare al cinema stasera con degli amici, vuoi venire con noi?
+		Il film inizia alle otto, quindi potremmo cenare prima vicino alla stazione. Fammi sapere cosa ne pensi, e dimmi se hai bisogno di un passaggio.
+		Ho appena finito di lavorare e sono davvero stanco, ma è stata una bella giornata. La riunione con la nuova squadra è andata bene e la nostra idea gli è piaciuta.
+		Mi mandi le foto del fine settimana? Grazie mille, a presto! A proposito, dove hai messo le chiavi della macchina?
+		Dovremmo anche comprare qualcosa per la festa di sabato, magari una torta o una bottiglia di vino. A che ora chiude il negozio?`,
+	"es": `Hola, ¿cómo estás hoy? Estaba pensando en ir al cine esta noche con unos amigos, ¿quieres venir con nosotros?
+		La película empieza a las ocho, así que podríamos cenar antes cerca de la estación. Dime qué te parece, y avísame si necesitas que te lleve.
+		Acabo de terminar de trabajar y estoy muy cansado, pero ha sido un buen día. La reunión con el nuevo equipo fue bien y les gustó nuestra idea.
+		¿Me puedes enviar las fotos del fin de semana? Muchas gracias, ¡hasta pronto! Por cierto, ¿dónde pusiste las llaves del coche?
+		También deberíamos comprar algo para la fiesta del sábado, quizás una tarta o una botella de vino. ¿A qué hora cierra la tienda?`,
+	"fr": `Salut, comment vas-tu aujourd'hui ? Je pensais aller au cinéma ce soir avec des amis, tu veux venir avec nous ?
+		Le film commence à huit heures, donc nous pourrions dîner avant près de la gare. Dis-moi ce que tu en penses, et préviens-moi si tu as besoin qu'on te conduise.
+		Je viens de finir le travail et je suis vraiment fatigué, mais c'était une bonne journée. La réunion avec la nouvelle équipe s'est bien passée et ils ont aimé notre idée.
+		Tu peux m'envoyer les photos du week-end ? Merci beaucoup, à bientôt ! Au fait, où est-ce que tu as mis les clés de la voiture ?
+		Il faudrait aussi acheter quelque chose pour la fête de samedi, peut-être un gâteau ou une bouteille de vin. À quelle heure ferme le magasin ?`,
+	"de": `Hallo, wie geht es dir heute? Ich habe überlegt, heute Abend mit ein paar Freunden ins Kino zu gehen, willst du mitkommen?
+		Der Film fängt um acht an, also könnten wir vorher in der Nähe vom Bahnhof essen. Sag mir, was du denkst, und ob ich dich abholen soll.
+		Ich bin gerade mit der Arbeit fertig und wirklich müde, aber es war ein guter Tag. Das Treffen mit dem neuen Team lief gut und sie mochten unsere Idee.
+		Kannst du mir die Fotos vom Wochenende schicken? Vielen Dank, bis bald! Übrigens, wo hast du die Schlüssel vom Auto hingelegt?
+		Wir sollten auch etwas für die Party am Samstag kaufen, vielleicht einen Kuchen oder eine Flasche Wein. Wann macht der Laden zu?`,
+	"pt": `Olá, como você está hoje? Estava pensando em ir ao cinema hoje à noite com uns amigos, quer vir com a gente?
+		O filme começa às oito, então poderíamos jantar antes perto da estação. Me diga o que você acha, e avise se precisar de uma carona.
+		Acabei de sair do trabalho e estou muito cansado, mas foi um bom dia. A reunião com a nova equipe correu bem e eles gostaram da nossa ideia.
+		Você pode me mandar as fotos do fim de semana? Muito obrigado, até logo! Aliás, onde você colocou as chaves do carro?
+		Também devíamos comprar alguma coisa para a festa de sábado, talvez um bolo ou uma garrafa de vinho. A que horas fecha a loja?`,
+	"nl": `Hoi, hoe gaat het vandaag met je? Ik zat te denken om vanavond met een paar vrienden naar de bioscoop te gaan, wil je met ons mee?
+		De film begint om acht uur, dus we kunnen eerst bij het station eten. Laat me weten wat je ervan vindt, en zeg het als je een lift nodig hebt.
+		Ik ben net klaar met werken en ik ben echt moe, maar het was een goede dag. De vergadering met het nieuwe team ging goed en ze vonden ons idee leuk.
+		Kun je me de foto's van het weekend sturen? Heel erg bedankt, tot snel! Trouwens, waar heb je de sleutels van de auto gelegd?
+		We moeten ook iets kopen voor het feest op zaterdag, misschien een taart of een fles wijn. Hoe laat gaat de winkel dicht?`,
+}
+
+// scripts are the alphabets that tell their language by themselves
+var scripts = []struct {
+	language string
+	table    *unicode.RangeTable
+}{
+	{"el", unicode.Greek},
+	{"he", unicode.Hebrew},
+	{"th", unicode.Thai},
+	{"ko", unicode.Hangul},
+	{"ja", unicode.Hiragana},
+	{"ja", unicode.Katakana},
+	{"zh", unicode.Han},
+}
+
+// profiles are the ranks of the trigrams of each language, by language
+var profiles = buildProfiles()
+
+// Languages returns the codes (ISO 639-1) Detect can answer, sorted
+func Languages() []string {
+	languages := make([]string, 0, len(samples)+len(scripts))
+	for language := range samples {
+		languages = append(languages, language)
+	}
+	for _, s := range scripts {
+		if !slices.Contains(languages, s.language) {
+			languages = append(languages, s.language)
+		}
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// Detect returns the language of text as an ISO 639-1 code, or "" when
+// it cannot tell
+func Detect(text string) string {
+	// Step 1: Most letters in one of the single-language scripts decide
+	letters := 0
+	byScript := make(map[string]int)
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for _, s := range scripts {
+			if unicode.Is(s.table, r) {
+				byScript[s.language]++
+				break
+			}
+		}
+	}
+	// Japanese also writes with the Han characters of Chinese: any kana
+	// makes them Japanese
+	if byScript["ja"] > 0 {
+		byScript["ja"] += byScript["zh"]
+		delete(byScript, "zh")
+	}
+	for _, s := range scripts {
+		if n := byScript[s.language]; n > 0 && 2*n >= letters {
+			return s.language
+		}
+	}
+	if letters < minLetters {
+		return ""
+	}
+
+	// Step 2: Otherwise the closest trigram profile, if clearly closest
+	ranked := rankTrigrams(text)
+	if len(ranked) == 0 {
+		return ""
+	}
+	maxDistance := len(ranked) * profileSize
+	best, bestDistance, secondDistance := "", maxDistance+1, maxDistance+1
+	for language, profile := range profiles {
+		distance := 0
+		for rank, trigram := range ranked {
+			if profileRank, ok := profile[trigram]; ok {
+				distance += abs(rank - profileRank)
+			} else {
+				distance += profileSize
+			}
+		}
+		switch {
+		case distance < bestDistance:
+			best, bestDistance, secondDistance = language, distance, bestDistance
+		case distance < secondDistance:
+			secondDistance = distance
+		}
+	}
+	if float64(secondDistance-bestDistance) < minMargin*float64(maxDistance) {
+		return ""
+	}
+	return best
+}
+
+// buildProfiles ranks the trigrams of the samples
+func buildProfiles() map[string]map[string]int {
+	built := make(map[string]map[string]int, len(samples))
+	for language, sample := range samples {
+		profile := make(map[string]int, profileSize)
+		for rank, trigram := range rankTrigrams(sample) {
+			profile[trigram] = rank
+		}
+		built[language] = profile
+	}
+	return built
+}
+
+// rankTrigrams returns the most frequent trigrams of the words of text,
+// at most profileSize, the most frequent first. Words are lowercased and
+// padded with a space on each side, so that trigrams also tell how
+// words start and end.
+func rankTrigrams(text string) []string {
+	counts := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		runes := []rune(" " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			counts[string(runes[i:i+3])]++
+		}
+	}
+
+	ranked := make([]string, 0, len(counts))
+	for trigram := range counts {
+		ranked = append(ranked, trigram)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if counts[ranked[i]] != counts[ranked[j]] {
+			return counts[ranked[i]] > counts[ranked[j]]
+		}
+		return ranked[i] < ranked[j]
+	})
+	if len(ranked) > profileSize {
+		ranked = ranked[:profileSize]
+	}
+	return ranked
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
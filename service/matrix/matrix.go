@@ -0,0 +1,263 @@
+/*
+Package matrix is a small client of the Matrix client-server API, for
+the Matrix bridge of the API (see service/api/matrix.go).
+
+It does what a bridge bot needs and nothing more: it syncs the rooms
+the bot joined to get their new events, sends messages to them, and
+uploads and downloads media. It authenticates with the access token of
+an account of the homeserver; logging in, joining rooms and end-to-end
+encryption are left to the homeserver's own clients.
+*/
+package matrix
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// requestTimeout bounds the requests but the long-polling syncs
+const requestTimeout = 30 * time.Second
+
+// Types of event and of message
+const (
+	EventMessage = "m.room.message"
+
+	MsgText   = "m.text"
+	MsgNotice = "m.notice"
+	MsgEmote  = "m.emote"
+	MsgImage  = "m.image"
+)
+
+// Error is an error the homeserver answered
+type Error struct {
+	Status     int    // the HTTP status
+	Code       string `json:"errcode"` // e.g. M_FORBIDDEN, M_LIMIT_EXCEEDED
+	Message    string `json:"error"`
+	RetryAfter int64  `json:"retry_after_ms"` // with M_LIMIT_EXCEEDED
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("matrix: %d %s: %s", e.Status, e.Code, e.Message)
+}
+
+// Client calls a homeserver as one account
+type Client struct {
+	homeserver string
+	token      string
+	http       *http.Client
+}
+
+// NewClient returns a client of the homeserver at its base URL (e.g.
+// https://matrix.example.org), authenticated by an access token
+func NewClient(homeserver, accessToken string) *Client {
+	return &Client{
+		homeserver: strings.TrimSuffix(homeserver, "/"),
+		token:      accessToken,
+		http:       &http.Client{},
+	}
+}
+
+// Event is an event of a room
+type Event struct {
+	Type           string          `json:"type"`
+	EventID        string          `json:"event_id"`
+	Sender         string          `json:"sender"`
+	OriginServerTS int64           `json:"origin_server_ts"` // in milliseconds
+	StateKey       *string         `json:"state_key,omitempty"`
+	Content        json.RawMessage `json:"content"`
+}
+
+// MessageContent is the content of an m.room.message event
+type MessageContent struct {
+	MsgType   string     `json:"msgtype"`
+	Body      string     `json:"body"`
+	URL       string     `json:"url,omitempty"` // the mxc:// URI of the media
+	Info      *ImageInfo `json:"info,omitempty"`
+	RelatesTo *struct {
+		RelType string `json:"rel_type,omitempty"` // m.replace for an edit
+	} `json:"m.relates_to,omitempty"`
+}
+
+// ImageInfo describes the image of an m.image message
+type ImageInfo struct {
+	MimeType string `json:"mimetype,omitempty"`
+	Size     int    `json:"size,omitempty"`
+	Width    int    `json:"w,omitempty"`
+	Height   int    `json:"h,omitempty"`
+}
+
+// SyncResponse is what changed in the joined rooms since the last sync
+type SyncResponse struct {
+	NextBatch string `json:"next_batch"` // the since of the next sync
+	Rooms     struct {
+		Join map[string]struct {
+			Timeline struct {
+				Events  []Event `json:"events"`
+				Limited bool    `json:"limited"` // events were left out
+			} `json:"timeline"`
+		} `json:"join"`
+	} `json:"rooms"`
+}
+
+// WhoAmI returns the user ID of the account, e.g. @bridge:example.org
+func (c *Client) WhoAmI(ctx context.Context) (string, error) {
+	var resp struct {
+		UserID string `json:"user_id"`
+	}
+	err := c.do(ctx, http.MethodGet, "/_matrix/client/v3/account/whoami", nil, "", &resp)
+	return resp.UserID, err
+}
+
+/*
+Sync returns the events of the joined rooms since a previous sync,
+waiting up to timeout for one to happen. The first sync (since "")
+returns no events, only the point to sync from: what happened before
+the bridge started is not relayed.
+*/
+func (c *Client) Sync(ctx context.Context, since string, timeout time.Duration) (*SyncResponse, error) {
+	query := url.Values{}
+	if since == "" {
+		// Only the position: no timeline, no state
+		query.Set("filter", `{"room":{"timeline":{"limit":0},"state":{"lazy_load_members":true,"types":[]}},"presence":{"types":[]},"account_data":{"types":[]}}`)
+	} else {
+		query.Set("since", since)
+		query.Set("timeout", strconv.FormatInt(timeout.Milliseconds(), 10))
+		query.Set("filter", `{"room":{"timeline":{"types":["m.room.message"]},"state":{"types":[]},"ephemeral":{"types":[]}},"presence":{"types":[]},"account_data":{"types":[]}}`)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout+requestTimeout)
+	defer cancel()
+	var resp SyncResponse
+	if err := c.do(ctx, http.MethodGet, "/_matrix/client/v3/sync?"+query.Encode(), nil, "", &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SendMessage sends a message to a room and returns its event ID. The
+// transaction ID makes it idempotent: a retry with the same ID is not
+// sent twice.
+func (c *Client) SendMessage(ctx context.Context, roomID, txnID string, content MessageContent) (string, error) {
+	body, err := json.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+	var resp struct {
+		EventID string `json:"event_id"`
+	}
+	path := "/_matrix/client/v3/rooms/" + url.PathEscape(roomID) + "/send/" + EventMessage + "/" + url.PathEscape(txnID)
+	err = c.withTimeout(ctx, func(ctx context.Context) error {
+		return c.do(ctx, http.MethodPut, path, bytes.NewReader(body), "application/json", &resp)
+	})
+	return resp.EventID, err
+}
+
+// DisplayName returns the display name of a member of a room, the user
+// ID when the member has none
+func (c *Client) DisplayName(ctx context.Context, roomID, userID string) (string, error) {
+	var resp struct {
+		DisplayName string `json:"displayname"`
+	}
+	path := "/_matrix/client/v3/rooms/" + url.PathEscape(roomID) + "/state/m.room.member/" + url.PathEscape(userID)
+	err := c.withTimeout(ctx, func(ctx context.Context) error {
+		return c.do(ctx, http.MethodGet, path, nil, "", &resp)
+	})
+	if err != nil {
+		return "", err
+	}
+	if resp.DisplayName == "" {
+		return userID, nil
+	}
+	return resp.DisplayName, nil
+}
+
+// Upload stores media on the homeserver and returns its mxc:// URI
+func (c *Client) Upload(ctx context.Context, contentType, filename string, data []byte) (string, error) {
+	var resp struct {
+		ContentURI string `json:"content_uri"`
+	}
+	path := "/_matrix/media/v3/upload?filename=" + url.QueryEscape(filename)
+	err := c.withTimeout(ctx, func(ctx context.Context) error {
+		return c.do(ctx, http.MethodPost, path, bytes.NewReader(data), contentType, &resp)
+	})
+	return resp.ContentURI, err
+}
+
+// ErrTooLarge is returned by Download for media larger than its limit
+var ErrTooLarge = errors.New("matrix: media too large")
+
+// Download returns the media of an mxc:// URI, up to maxSize bytes
+func (c *Client) Download(ctx context.Context, mxc string, maxSize int64) ([]byte, error) {
+	server, mediaID, ok := strings.Cut(strings.TrimPrefix(mxc, "mxc://"), "/")
+	if !strings.HasPrefix(mxc, "mxc://") || !ok || server == "" || mediaID == "" {
+		return nil, fmt.Errorf("matrix: not a media URI: %q", mxc)
+	}
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	path := "/_matrix/client/v1/media/download/" + url.PathEscape(server) + "/" + url.PathEscape(mediaID)
+	resp, err := c.send(ctx, http.MethodGet, path, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, ErrTooLarge
+	}
+	return data, nil
+}
+
+func (c *Client) withTimeout(ctx context.Context, call func(context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	return call(ctx)
+}
+
+// do sends a request and decodes its JSON response into out
+func (c *Client) do(ctx context.Context, method, path string, body io.Reader, contentType string, out any) error {
+	resp, err := c.send(ctx, method, path, body, contentType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("matrix: decoding the response of %s: %w", strings.SplitN(path, "?", 2)[0], err)
+	}
+	return nil
+}
+
+// send sends a request; an answer that is not a 2xx is returned as an *Error
+func (c *Client) send(ctx context.Context, method, path string, body io.Reader, contentType string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.homeserver+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		matrixErr := &Error{Status: resp.StatusCode}
+		if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(matrixErr); err != nil || matrixErr.Code == "" {
+			matrixErr.Code, matrixErr.Message = "M_UNKNOWN", resp.Status
+		}
+		return nil, matrixErr
+	}
+	return resp, nil
+}
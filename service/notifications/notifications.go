@@ -0,0 +1,179 @@
+/*
+Package notifications delivers Web Push notifications, so that users
+hear of new messages while no client of theirs is connected.
+
+A browser subscribes with the public VAPID key of the server and hands
+the subscription (Subscription) to the API; Push encrypts a payload for
+it and posts it to its push service, which wakes the browser up. The
+Notifier sends in the background: deliveries that fail for a while are
+retried with a growing delay, and subscriptions the push service no
+longer knows are dropped from the Store. The endpoint of a subscription
+is chosen by the browser, hence by the user: it is only posted to at a
+public address (see service/publicnet).
+*/
+package notifications
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"wasatext/service/publicnet"
+)
+
+const (
+	// DefaultTTL is how long a push service keeps a notification for an
+	// offline browser
+	DefaultTTL = 24 * time.Hour
+
+	queueSize   = 1024             // notifications waiting to be sent
+	senders     = 4                // notifications sent at once
+	maxAttempts = 4                // tries of a notification before it is given up
+	retryDelay  = 10 * time.Second // the delay before the first retry, doubled for each next one
+	pushTimeout = 30 * time.Second // how long a push service may take to answer
+)
+
+// Store forgets the subscriptions that are gone
+type Store interface {
+	DropPushSubscription(ctx context.Context, endpoint string) error
+}
+
+// Validate checks that a subscription can be sent to
+func (s Subscription) Validate() error {
+	u, err := url.Parse(s.Endpoint)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return errors.New("the endpoint must be an https URL")
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil && !publicnet.IsPublic(ip) {
+		return errors.New("the endpoint must be at a public address")
+	}
+	if _, err := encrypt(s, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+// delivery is a notification on its way to one subscription
+type delivery struct {
+	sub      Subscription
+	payload  []byte
+	attempts int // failed so far
+}
+
+// Notifier sends notifications in the background
+type Notifier struct {
+	store  Store
+	keys   func() *VAPID // nil while push is off
+	client *http.Client
+	queue  chan delivery
+
+	mu      sync.Mutex
+	stopped bool
+}
+
+// NewNotifier returns a notifier signing with the keys keys returns at the
+// time of each send; Run starts it
+func NewNotifier(store Store, keys func() *VAPID) *Notifier {
+	return &Notifier{
+		store:  store,
+		keys:   keys,
+		client: publicnet.NewClient(pushTimeout),
+		queue:  make(chan delivery, queueSize),
+	}
+}
+
+// Enabled reports whether notifications are sent, i.e. VAPID keys are set
+func (n *Notifier) Enabled() bool {
+	return n.keys() != nil
+}
+
+// Notify queues a notification for each subscription. When the queue is
+// full the notification is dropped: it is a courtesy, the messages are
+// in the conversation anyway.
+func (n *Notifier) Notify(subs []Subscription, payload []byte) {
+	for _, sub := range subs {
+		n.enqueue(delivery{sub: sub, payload: payload})
+	}
+}
+
+// enqueue queues a delivery, unless the queue is full or the notifier
+// has stopped
+func (n *Notifier) enqueue(d delivery) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.stopped {
+		return
+	}
+	select {
+	case n.queue <- d:
+	default:
+		log.Printf("Push queue full, dropping a notification to %s", endpointHost(d.sub.Endpoint))
+	}
+}
+
+// Run sends until ctx is cancelled, completing the sends under way; the
+// notifications still queued or waiting for a retry are dropped
+func (n *Notifier) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for range senders {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case d := <-n.queue:
+					n.send(ctx, d)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	n.mu.Lock()
+	n.stopped = true
+	n.mu.Unlock()
+}
+
+// send sends one delivery, schedules its retry if the push service may
+// take it later, and drops its subscription if it is gone
+func (n *Notifier) send(ctx context.Context, d delivery) {
+	keys := n.keys()
+	if keys == nil {
+		return
+	}
+
+	err := Push(context.WithoutCancel(ctx), n.client, keys, d.sub, d.payload, DefaultTTL)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrGone):
+		if err := n.store.DropPushSubscription(context.WithoutCancel(ctx), d.sub.Endpoint); err != nil {
+			log.Printf("Error dropping a push subscription: %v", err)
+		}
+	case errors.Is(err, ErrRejected):
+		log.Printf("Push to %s rejected: %v", endpointHost(d.sub.Endpoint), err)
+	default:
+		d.attempts++
+		if d.attempts >= maxAttempts {
+			log.Printf("Giving up a push to %s after %d attempts: %v", endpointHost(d.sub.Endpoint), d.attempts, err)
+			return
+		}
+		time.AfterFunc(retryDelay<<(d.attempts-1), func() { n.enqueue(d) })
+	}
+}
+
+// endpointHost returns the push service of an endpoint, for the logs: the
+// rest of the URL identifies the browser
+func endpointHost(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "?"
+	}
+	return u.Host
+}
@@ -0,0 +1,282 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxPayloadSize is the largest payload a notification carries, in bytes:
+// push services take 4096 bytes of encrypted body, of which the header
+// and the encryption use 103
+const MaxPayloadSize = 3993
+
+// recordSize is the record size of the encrypted body (one record)
+const recordSize = 4096
+
+// vapidTokenLifetime is how long a VAPID token is valid (24 hours at most)
+const vapidTokenLifetime = 12 * time.Hour
+
+// ErrGone is returned for a subscription the push service no longer
+// knows (404 or 410): the browser unsubscribed, it should be dropped
+var ErrGone = errors.New("notifications: subscription gone")
+
+// ErrRejected is returned when the push service refuses a notification
+// for good (a 4xx other than 404, 410 and 429): retrying is pointless
+var ErrRejected = errors.New("notifications: notification rejected")
+
+// Subscription is where the browser of a user receives notifications, as
+// given by PushManager.subscribe()
+type Subscription struct {
+	Endpoint string // the URL of the push service for this browser
+	P256dh   string // the public key of the browser (base64url, P-256)
+	Auth     string // the authentication secret of the browser (base64url, 16 bytes)
+}
+
+// VAPID identifies the server to the push services (RFC 8292)
+type VAPID struct {
+	key       *ecdsa.PrivateKey
+	publicKey string // base64url, uncompressed point
+	subject   string
+}
+
+/*
+NewVAPID returns the VAPID identity of a private key, written like the
+web-push tools generate it: the 32-byte P-256 scalar in base64url. The
+subject is a mailto: or https: URL the push services can contact about
+the server.
+*/
+func NewVAPID(privateKey, subject string) (*VAPID, error) {
+	raw, err := decodeBase64(privateKey)
+	if err != nil || len(raw) != 32 {
+		return nil, errors.New("notifications: the VAPID private key must be 32 bytes in base64url")
+	}
+	u, err := url.Parse(subject)
+	if err != nil || (u.Scheme != "mailto" && u.Scheme != "https") {
+		return nil, errors.New("notifications: the VAPID subject must be a mailto: or https: URL")
+	}
+
+	// x509 parses a SEC 1 private key: the scalar wrapped in DER, with
+	// the OID of P-256
+	der, err := asn1.Marshal(struct {
+		Version    int
+		PrivateKey []byte
+		Curve      asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+	}{1, raw, asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}})
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParseECPrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("notifications: invalid VAPID private key: %w", err)
+	}
+	public, err := key.PublicKey.ECDH()
+	if err != nil {
+		return nil, err
+	}
+	return &VAPID{
+		key:       key,
+		publicKey: base64.RawURLEncoding.EncodeToString(public.Bytes()),
+		subject:   subject,
+	}, nil
+}
+
+// PublicKey returns the public key browsers subscribe with (the
+// applicationServerKey of PushManager.subscribe()), in base64url
+func (v *VAPID) PublicKey() string {
+	return v.publicKey
+}
+
+// authorization returns the Authorization header for a push service
+func (v *VAPID) authorization(endpoint string, now time.Time) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	header, err := json.Marshal(map[string]string{"typ": "JWT", "alg": "ES256"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]any{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": now.Add(vapidTokenLifetime).Unix(),
+		"sub": v.subject,
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	// ES256 signatures are r and s side by side, 32 bytes each
+	digest := sha256.Sum256([]byte(unsigned))
+	r, s, err := ecdsa.Sign(rand.Reader, v.key, digest[:])
+	if err != nil {
+		return "", err
+	}
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	token := unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)
+	return "vapid t=" + token + ", k=" + v.publicKey, nil
+}
+
+/*
+Push sends one notification to a subscription: the payload is encrypted
+for the browser (RFC 8291) and posted to its push service (RFC 8030),
+which keeps it up to ttl while the browser is offline. It returns
+ErrGone or ErrRejected, wrapped, when retrying is pointless; other errors
+(the push service is unreachable, overloaded or failing) are worth
+retrying later.
+*/
+func Push(ctx context.Context, client *http.Client, keys *VAPID, sub Subscription, payload []byte, ttl time.Duration) error {
+	if len(payload) > MaxPayloadSize {
+		return fmt.Errorf("%w: payload of %d bytes", ErrRejected, len(payload))
+	}
+	body, err := encrypt(sub, payload)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+	authorization, err := keys.authorization(sub.Endpoint, time.Now())
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", strconv.Itoa(int(ttl/time.Second)))
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return fmt.Errorf("%w: %s", ErrGone, resp.Status)
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("notifications: push service answered %s", resp.Status)
+	default:
+		return fmt.Errorf("%w: %s", ErrRejected, resp.Status)
+	}
+}
+
+/*
+encrypt encrypts a payload for a browser with the aes128gcm content
+encoding of RFC 8291: a key agreed with a one-off P-256 key of ours and
+the key of the browser, mixed with its authentication secret, seals the
+payload in a single record. The header carries the salt and our public
+key, so that the browser derives the same key.
+*/
+func encrypt(sub Subscription, payload []byte) ([]byte, error) {
+	uaKey, err := decodeBase64(sub.P256dh)
+	if err != nil {
+		return nil, errors.New("invalid p256dh key")
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(uaKey)
+	if err != nil {
+		return nil, errors.New("invalid p256dh key")
+	}
+	authSecret, err := decodeBase64(sub.Auth)
+	if err != nil || len(authSecret) != 16 {
+		return nil, errors.New("invalid auth secret")
+	}
+
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return seal(uaPublic, authSecret, asPrivate, salt, payload)
+}
+
+// seal encrypts a payload for the browser key and auth secret with our
+// one-off key and the salt, as encrypt does
+func seal(uaPublic *ecdh.PublicKey, authSecret []byte, asPrivate *ecdh.PrivateKey, salt, payload []byte) ([]byte, error) {
+	uaKey := uaPublic.Bytes()
+	asPublic := asPrivate.PublicKey().Bytes()
+	sharedSecret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	// The input key mixes the shared secret with the auth secret...
+	prkKey, err := hkdf.Extract(sha256.New, sharedSecret, authSecret)
+	if err != nil {
+		return nil, err
+	}
+	keyInfo := "WebPush: info\x00" + string(uaKey) + string(asPublic)
+	ikm, err := hkdf.Expand(sha256.New, prkKey, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	// ...and gives the content key and nonce with the salt
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// Header: salt, record size, key length, key; then the record, whose
+	// plaintext ends with the delimiter of the last record
+	body := make([]byte, 0, 16+4+1+len(asPublic)+len(payload)+1+gcm.Overhead())
+	body = append(body, salt...)
+	body = binary.BigEndian.AppendUint32(body, recordSize)
+	body = append(body, byte(len(asPublic)))
+	body = append(body, asPublic...)
+	plaintext := append(append(make([]byte, 0, len(payload)+1), payload...), 0x02)
+	return gcm.Seal(body, nonce, plaintext, nil), nil
+}
+
+// decodeBase64 decodes base64url, padded or not, as browsers and tools
+// write both
+func decodeBase64(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
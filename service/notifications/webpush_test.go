@@ -0,0 +1,221 @@
+package notifications
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/hkdf"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The example of RFC 8291, appendix A
+const (
+	rfcPlaintext = "When I grow up, I want to be a watermelon"
+	rfcASPrivate = "yfWPiYE-n46HLnH0KqZOF1fJJU3MYrct3AELtAQ-oRw"
+	rfcASPublic  = "BP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A8"
+	rfcUAPrivate = "q1dXpw3UpT5VOmu_cf_v6ih07Aems3njxI-JWgLcM94"
+	rfcUAPublic  = "BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4"
+	rfcSalt      = "DGv6ra1nlYgDCS1FRnbzlw"
+	rfcAuth      = "BTBZMqHH6r4Tts7J_aSIgg"
+	rfcBody      = "DGv6ra1nlYgDCS1FRnbzlwAAEABBBP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A_yl95bQpu6cVPTpK4Mqgkf1CXztLVBSt2Ks3oZwbuwXPXLWyouBWLVWGNWQexSgSxsj_Qulcy4a-fN"
+)
+
+// mustDecode decodes base64url or fails the test
+func mustDecode(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := decodeBase64(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestSealKnownAnswer(t *testing.T) {
+	asPrivate, err := ecdh.P256().NewPrivateKey(mustDecode(t, rfcASPrivate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(mustDecode(t, rfcUAPublic))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := seal(uaPublic, mustDecode(t, rfcAuth), asPrivate, mustDecode(t, rfcSalt), []byte(rfcPlaintext))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := base64.RawURLEncoding.EncodeToString(body); got != rfcBody {
+		t.Errorf("got body\n%s\nwant\n%s", got, rfcBody)
+	}
+}
+
+// TestEncrypt decrypts a notification as the browser of RFC 8291 would
+func TestEncrypt(t *testing.T) {
+	sub := Subscription{Endpoint: "https://push.example.net/push/abc", P256dh: rfcUAPublic, Auth: rfcAuth}
+	body, err := encrypt(sub, []byte(rfcPlaintext))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Header: salt, record size, key length, key
+	if len(body) < 21 {
+		t.Fatalf("body of %d bytes", len(body))
+	}
+	salt := body[:16]
+	if rs := binary.BigEndian.Uint32(body[16:20]); rs != recordSize {
+		t.Errorf("record size %d, want %d", rs, recordSize)
+	}
+	keyLength := int(body[20])
+	if keyLength != 65 || len(body) < 21+keyLength {
+		t.Fatalf("key of %d bytes", keyLength)
+	}
+	asPublic, err := ecdh.P256().NewPublicKey(body[21 : 21+keyLength])
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := body[21+keyLength:]
+
+	uaPrivate, err := ecdh.P256().NewPrivateKey(mustDecode(t, rfcUAPrivate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sharedSecret, err := uaPrivate.ECDH(asPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prkKey, err := hkdf.Extract(sha256.New, sharedSecret, mustDecode(t, rfcAuth))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ikm, err := hkdf.Expand(sha256.New, prkKey, "WebPush: info\x00"+string(uaPrivate.PublicKey().Bytes())+string(asPublic.Bytes()), 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cek, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := gcm.Open(nil, nonce, record, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := rfcPlaintext + "\x02"; string(plaintext) != want {
+		t.Errorf("decrypted %q, want %q", plaintext, want)
+	}
+
+	// Every notification has its own key and salt
+	again, err := encrypt(sub, []byte(rfcPlaintext))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again[:21+keyLength]) == string(body[:21+keyLength]) {
+		t.Error("two notifications share their salt and key")
+	}
+}
+
+func TestEncryptInvalidSubscription(t *testing.T) {
+	for _, sub := range []Subscription{
+		{P256dh: "not a key", Auth: rfcAuth},
+		{P256dh: rfcUAPublic[:20], Auth: rfcAuth},
+		{P256dh: rfcUAPublic, Auth: "c2hvcnQ"},
+	} {
+		if _, err := encrypt(sub, []byte("hi")); err == nil {
+			t.Errorf("%+v: encrypted", sub)
+		}
+	}
+}
+
+func TestNewVAPID(t *testing.T) {
+	// The public key of the RFC 8291 application server
+	keys, err := NewVAPID(rfcASPrivate, "mailto:admin@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys.PublicKey() != rfcASPublic {
+		t.Errorf("public key %s, want %s", keys.PublicKey(), rfcASPublic)
+	}
+
+	for _, tt := range []struct{ key, subject string }{
+		{rfcASPrivate[:20], "mailto:admin@example.com"},
+		{rfcASPrivate, "admin@example.com"},
+		{rfcASPrivate, "http://example.com"},
+	} {
+		if _, err := NewVAPID(tt.key, tt.subject); err == nil {
+			t.Errorf("NewVAPID(%q, %q) accepted", tt.key, tt.subject)
+		}
+	}
+}
+
+// TestVAPIDAuthorization checks the token of RFC 8292: its claims, and
+// an ES256 signature that the public key verifies
+func TestVAPIDAuthorization(t *testing.T) {
+	keys, err := NewVAPID(rfcASPrivate, "mailto:admin@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+	header, err := keys.authorization("https://push.example.net:8443/push/abc?x=1", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, key, ok := strings.Cut(strings.TrimPrefix(header, "vapid t="), ", k=")
+	if !ok || !strings.HasPrefix(header, "vapid t=") || key != rfcASPublic {
+		t.Fatalf("header %q", header)
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("token of %d parts", len(parts))
+	}
+
+	var jwtHeader map[string]string
+	if err := json.Unmarshal(mustDecode(t, parts[0]), &jwtHeader); err != nil {
+		t.Fatal(err)
+	}
+	if jwtHeader["alg"] != "ES256" || jwtHeader["typ"] != "JWT" {
+		t.Errorf("JWT header %v", jwtHeader)
+	}
+	var claims struct {
+		Aud string `json:"aud"`
+		Exp int64  `json:"exp"`
+		Sub string `json:"sub"`
+	}
+	if err := json.Unmarshal(mustDecode(t, parts[1]), &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims.Aud != "https://push.example.net:8443" || claims.Sub != "mailto:admin@example.com" ||
+		claims.Exp != now.Add(vapidTokenLifetime).Unix() {
+		t.Errorf("claims %+v", claims)
+	}
+
+	signature := mustDecode(t, parts[2])
+	if len(signature) != 64 {
+		t.Fatalf("signature of %d bytes, want r and s of 32", len(signature))
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r := new(big.Int).SetBytes(signature[:32])
+	s := new(big.Int).SetBytes(signature[32:])
+	if !ecdsa.Verify(&keys.key.PublicKey, digest[:], r, s) {
+		t.Error("the signature does not verify")
+	}
+}
@@ -0,0 +1,76 @@
+/*
+Package ocr reads the text of images, so that photos of screenshots,
+receipts or whiteboards can be found by the message search.
+
+An Engine is whatever turns an image into text. Command, which runs an
+external program such as tesseract, is the only implementation so far:
+libraries or OCR services can be added behind the same interface.
+*/
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ErrUnreadable is returned for an image the engine failed on; other
+// errors (the engine is unavailable) are worth retrying later
+var ErrUnreadable = errors.New("ocr: unreadable image")
+
+// Engine extracts the text of an image (JPEG, PNG, GIF or WebP); an
+// image without text gives "" and no error
+type Engine interface {
+	Extract(ctx context.Context, image []byte) (string, error)
+}
+
+// DefaultTimeout is how long a Command may take on one image
+const DefaultTimeout = 30 * time.Second
+
+/*
+Command is an Engine running a program for each image: the image goes
+to its standard input, the text is read from its standard output. With
+tesseract that is
+
+	Command{Path: "tesseract", Args: []string{"stdin", "stdout"}}
+*/
+type Command struct {
+	Path    string
+	Args    []string
+	Timeout time.Duration // DefaultTimeout when zero
+}
+
+// Extract runs the program on the image
+func (c *Command) Extract(ctx context.Context, image []byte) (string, error) {
+	if c.Path == "" {
+		return "", errors.New("ocr: no command")
+	}
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.Path, c.Args...) //nolint:gosec // the command comes from the configuration
+	cmd.Stdin = bytes.NewReader(image)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		// The program ran and failed (or was killed at the timeout)
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			err = ErrUnreadable
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("ocr: %s: %w: %s", c.Path, err, msg)
+		}
+		return "", fmt.Errorf("ocr: %s: %w", c.Path, err)
+	}
+	return strings.Join(strings.Fields(stdout.String()), " "), nil
+}
@@ -0,0 +1,58 @@
+/*
+Package publicnet sends the HTTP requests whose URL a user chose: the
+webhooks of groups and bots, the push services of the browsers.
+
+Such a URL may name any host, including the server itself or the
+machines of its private network, which a request of the server would
+reach on the user's behalf. The clients of NewClient only connect to
+public addresses, checked after the DNS resolution, and do not follow
+redirects, which could lead elsewhere.
+*/
+package publicnet
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateAddress refuses a connection to a non-public address
+var ErrPrivateAddress = errors.New("the host resolves to a private address")
+
+// IsPublic reports whether an address may be connected to: not loopback,
+// private, link-local, multicast nor unspecified
+func IsPublic(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast())
+}
+
+// Control is a net.Dialer Control refusing the non-public addresses; it
+// checks the address actually dialed, after the DNS resolution
+func Control(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !IsPublic(ip) {
+		return ErrPrivateAddress
+	}
+	return nil
+}
+
+// NoRedirect is an http.Client CheckRedirect returning the redirect
+// itself instead of following it
+func NoRedirect(*http.Request, []*http.Request) error {
+	return http.ErrUseLastResponse
+}
+
+// NewClient returns a client connecting to public addresses only, without
+// a proxy, and not following redirects
+func NewClient(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{Timeout: timeout, Control: Control}).DialContext
+	return &http.Client{Timeout: timeout, CheckRedirect: NoRedirect, Transport: transport}
+}
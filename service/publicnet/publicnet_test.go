@@ -0,0 +1,43 @@
+package publicnet
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestIsPublic(t *testing.T) {
+	for address, want := range map[string]bool{
+		"93.184.216.34":   true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"::1":             false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"fe80::1":         false,
+		"fc00::1":         false,
+		"0.0.0.0":         false,
+		"224.0.0.1":       false,
+	} {
+		if got := IsPublic(net.ParseIP(address)); got != want {
+			t.Errorf("IsPublic(%s) = %v, want %v", address, got, want)
+		}
+	}
+}
+
+func TestNewClientRefusesLoopback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		t.Error("the request reached a loopback server")
+	}))
+	defer srv.Close()
+
+	_, err := NewClient(time.Second).Get(srv.URL)
+	if !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("error = %v, want %v", err, ErrPrivateAddress)
+	}
+}
@@ -0,0 +1,325 @@
+/*
+Package redis is a small Redis client, for the event backplane of the
+API (see service/api/backplane.go).
+
+It speaks RESP2, the protocol of every Redis server and of its forks,
+and does what the backplane needs and nothing more: it connects to the
+server of a redis:// (or rediss://, over TLS) URL, authenticates with
+its user and password, publishes messages to a channel and subscribes
+to one. A Conn is used by one goroutine at a time, and a subscribed
+Conn only receives: the backplane publishes on another.
+*/
+package redis
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPort    = "6379"
+	requestTimeout = 10 * time.Second // bounds a command without a deadline of its own
+	maxBulkLength  = 512 << 20        // the largest string Redis stores
+)
+
+// Error is an error reply of the server, e.g. "NOAUTH Authentication required."
+type Error string
+
+func (e Error) Error() string {
+	return "redis: " + string(e)
+}
+
+// Message is a message published to a channel the Conn subscribed to,
+// or the pong of a Ping
+type Message struct {
+	Channel string
+	Payload []byte
+	Pong    bool // the reply to a Ping, without channel nor payload
+}
+
+// Conn is a connection to a Redis server
+type Conn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+/*
+Dial connects to the server of a URL and authenticates:
+
+	redis://[[user]:password@]host[:port][/db]
+
+rediss:// connects over TLS. The database only matters to the commands
+on keys: channels are the same in every database.
+*/
+func Dial(ctx context.Context, rawURL string) (*Conn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("redis: invalid URL: %w", err)
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" || u.Hostname() == "" {
+		return nil, errors.New("redis: the URL must be redis://host[:port] or rediss://host[:port]")
+	}
+	address := u.Host
+	if u.Port() == "" {
+		address = net.JoinHostPort(u.Hostname(), defaultPort)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "rediss" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: u.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+	c := &Conn{conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+
+	// AUTH with a user is the ACL of Redis 6; a password alone, the
+	// requirepass of every version
+	if password, ok := u.User.Password(); ok {
+		args := []string{"AUTH", password}
+		if user := u.User.Username(); user != "" {
+			args = []string{"AUTH", user, password}
+		}
+		if _, err := c.Do(ctx, args...); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" && db != "0" {
+		if _, err := strconv.Atoi(db); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("redis: invalid database %q", db)
+		}
+		if _, err := c.Do(ctx, "SELECT", db); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Close closes the connection
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+/*
+Do sends a command and returns its reply: a string for a simple
+string, an int64 for an integer, a []byte for a bulk string (nil when
+null) and a []any for an array. An error reply is returned as an Error.
+*/
+func (c *Conn) Do(ctx context.Context, args ...string) (any, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(requestTimeout)
+	}
+	_ = c.conn.SetDeadline(deadline)
+	defer c.conn.SetDeadline(time.Time{})
+	if err := c.send(args); err != nil {
+		return nil, err
+	}
+	reply, err := c.read()
+	if err != nil {
+		return nil, err
+	}
+	if replyErr, ok := reply.(Error); ok {
+		return nil, replyErr
+	}
+	return reply, nil
+}
+
+// Publish publishes a message to a channel and returns how many
+// subscribers, of every client of the server, got it
+func (c *Conn) Publish(ctx context.Context, channel string, message []byte) (int64, error) {
+	reply, err := c.Do(ctx, "PUBLISH", channel, string(message))
+	if err != nil {
+		return 0, err
+	}
+	n, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("redis: unexpected reply to PUBLISH: %T", reply)
+	}
+	return n, nil
+}
+
+// Subscribe subscribes to channels; from then on the Conn only
+// receives (see Receive) and pings
+func (c *Conn) Subscribe(ctx context.Context, channels ...string) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(requestTimeout)
+	}
+	_ = c.conn.SetDeadline(deadline)
+	defer c.conn.SetDeadline(time.Time{})
+	if err := c.send(append([]string{"SUBSCRIBE"}, channels...)); err != nil {
+		return err
+	}
+	// Every channel is confirmed by a reply of its own
+	for range channels {
+		reply, err := c.read()
+		if err != nil {
+			return err
+		}
+		if replyErr, ok := reply.(Error); ok {
+			return replyErr
+		}
+		if kind, _ := pushKind(reply); kind != "subscribe" {
+			return fmt.Errorf("redis: unexpected reply to SUBSCRIBE: %v", reply)
+		}
+	}
+	return nil
+}
+
+// Ping asks a subscribed server for a pong, which Receive returns: it
+// keeps an idle connection alive, and tells whether it still is
+func (c *Conn) Ping() error {
+	_ = c.conn.SetWriteDeadline(time.Now().Add(requestTimeout))
+	return c.send([]string{"PING"})
+}
+
+// ErrIdle is returned by Receive when no message came in time
+var ErrIdle = errors.New("redis: no message")
+
+/*
+Receive waits up to idle for the next message of the channels
+subscribed to. It returns ErrIdle when none came, the connection then
+being as good as before; any other error leaves it unusable.
+*/
+func (c *Conn) Receive(idle time.Duration) (Message, error) {
+	for {
+		_ = c.conn.SetReadDeadline(time.Now().Add(idle))
+		if _, err := c.r.Peek(1); err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				return Message{}, ErrIdle
+			}
+			return Message{}, err
+		}
+		// A reply has started: the rest follows right away
+		_ = c.conn.SetReadDeadline(time.Now().Add(requestTimeout))
+		reply, err := c.read()
+		if err != nil {
+			return Message{}, err
+		}
+		if replyErr, ok := reply.(Error); ok {
+			return Message{}, replyErr
+		}
+		kind, fields := pushKind(reply)
+		if kind == "pong" {
+			return Message{Pong: true}, nil
+		}
+		if kind != "message" {
+			continue // the confirmation of a subscription
+		}
+		if len(fields) != 3 {
+			return Message{}, fmt.Errorf("redis: malformed message: %v", reply)
+		}
+		channel, _ := fields[1].([]byte)
+		payload, _ := fields[2].([]byte)
+		return Message{Channel: string(channel), Payload: payload}, nil
+	}
+}
+
+// pushKind returns the kind of a reply of a subscribed connection, e.g.
+// "message" for ["message", channel, payload], and its fields
+func pushKind(reply any) (string, []any) {
+	fields, ok := reply.([]any)
+	if !ok || len(fields) == 0 {
+		return "", nil
+	}
+	kind, _ := fields[0].([]byte)
+	return strings.ToLower(string(kind)), fields
+}
+
+// send writes a command as an array of bulk strings
+func (c *Conn) send(args []string) error {
+	fmt.Fprintf(c.w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(c.w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	return c.w.Flush()
+}
+
+// read reads a reply
+func (c *Conn) read() (any, error) {
+	line, err := c.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return Error(line[1:]), nil
+	case ':':
+		n, err := strconv.ParseInt(line[1:], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("redis: invalid integer %q", line[1:])
+		}
+		return n, nil
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < -1 || n > maxBulkLength {
+			return nil, fmt.Errorf("redis: invalid bulk length %q", line[1:])
+		}
+		if n < 0 {
+			return []byte(nil), nil
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, data); err != nil {
+			return nil, err
+		}
+		if string(data[n:]) != "\r\n" {
+			return nil, errors.New("redis: bulk string longer than its length")
+		}
+		return data[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < -1 {
+			return nil, fmt.Errorf("redis: invalid array length %q", line[1:])
+		}
+		if n < 0 {
+			return []any(nil), nil
+		}
+		items := make([]any, 0, min(n, 64))
+		for range n {
+			item, err := c.read()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: unknown reply %q", line)
+}
+
+// readLine reads a line without its CRLF
+func (c *Conn) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
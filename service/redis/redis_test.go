@@ -0,0 +1,318 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// replyConn returns a Conn reading the replies from a string
+func replyConn(replies string) *Conn {
+	return &Conn{r: bufio.NewReader(strings.NewReader(replies))}
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string
+		want  any
+	}{
+		{"simple string", "+OK\r\n", "OK"},
+		{"empty simple string", "+\r\n", ""},
+		{"error", "-NOAUTH Authentication required.\r\n", Error("NOAUTH Authentication required.")},
+		{"integer", ":1000\r\n", int64(1000)},
+		{"negative integer", ":-42\r\n", int64(-42)},
+		{"largest integer", ":9223372036854775807\r\n", int64(9223372036854775807)},
+		{"bulk string", "$5\r\nhello\r\n", []byte("hello")},
+		{"empty bulk string", "$0\r\n\r\n", []byte{}},
+		{"bulk string with CRLF inside", "$7\r\nab\r\ncd\n\r\n", []byte("ab\r\ncd\n")},
+		{"binary bulk string", "$3\r\n\x00\xff\x01\r\n", []byte{0, 0xff, 1}},
+		{"null bulk string", "$-1\r\n", []byte(nil)},
+		{"empty array", "*0\r\n", []any{}},
+		{"null array", "*-1\r\n", []any(nil)},
+		{"array of every type", "*5\r\n+OK\r\n-ERR no\r\n:7\r\n$2\r\nhi\r\n$-1\r\n", []any{"OK", Error("ERR no"), int64(7), []byte("hi"), []byte(nil)}},
+		{
+			"nested arrays",
+			"*2\r\n*3\r\n:1\r\n:2\r\n:3\r\n*2\r\n+Foo\r\n*1\r\n$3\r\nbar\r\n",
+			[]any{[]any{int64(1), int64(2), int64(3)}, []any{"Foo", []any{[]byte("bar")}}},
+		},
+		{"a message", "*3\r\n$7\r\nmessage\r\n$6\r\nevents\r\n$2\r\n{}\r\n", []any{[]byte("message"), []byte("events"), []byte("{}")}},
+		{"line ended by LF alone", "+OK\n", "OK"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := replyConn(tt.reply).read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadMalformed(t *testing.T) {
+	tests := []struct {
+		name      string
+		reply     string
+		truncated bool // the error is an EOF
+	}{
+		{"nothing", "", true},
+		{"truncated line", "+OK", true},
+		{"truncated integer", ":10", true},
+		{"truncated bulk length", "$5", true},
+		{"truncated bulk string", "$5\r\nhel", true},
+		{"bulk string without its CRLF", "$5\r\nhello", true},
+		{"truncated array", "*3\r\n:1\r\n:2\r\n", true},
+		{"truncated nested array", "*2\r\n*2\r\n:1\r\n", true},
+		{"truncated array length", "*", true},
+		{"empty line", "\r\n", false},
+		{"unknown type", "!3\r\nerr\r\n", false},
+		{"RESP3 map", "%1\r\n+a\r\n:1\r\n", false},
+		{"integer not a number", ":ten\r\n", false},
+		{"integer over 64 bits", ":9223372036854775808\r\n", false},
+		{"bulk length not a number", "$x\r\nhello\r\n", false},
+		{"negative bulk length", "$-2\r\n", false},
+		{"bulk string too large", "$536870913\r\n", false},
+		{"bulk string longer than its length", "$3\r\nhello\r\n", false},
+		{"array length not a number", "*x\r\n", false},
+		{"negative array length", "*-2\r\n", false},
+		{"malformed item", "*2\r\n:1\r\n?\r\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := replyConn(tt.reply).read()
+			if err == nil {
+				t.Fatalf("got %#v, want an error", got)
+			}
+			if eof := errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF); eof != tt.truncated {
+				t.Errorf("got %v, want an EOF: %v", err, tt.truncated)
+			}
+		})
+	}
+}
+
+func TestSend(t *testing.T) {
+	var b strings.Builder
+	c := &Conn{w: bufio.NewWriter(&b)}
+	if err := c.send([]string{"PUBLISH", "events", "{\"a\":\"b\r\n\"}", ""}); err != nil {
+		t.Fatal(err)
+	}
+	want := "*4\r\n$7\r\nPUBLISH\r\n$6\r\nevents\r\n$11\r\n{\"a\":\"b\r\n\"}\r\n$0\r\n\r\n"
+	if b.String() != want {
+		t.Errorf("sent %q, want %q", b.String(), want)
+	}
+}
+
+// fakeServer serves one connection: it reads each command and answers
+// with the reply the test gives for it
+type fakeServer struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// newPipe returns a Conn over an in-memory connection to a fake server
+func newPipe(t *testing.T) (*Conn, *fakeServer) {
+	client, server := net.Pipe()
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	s := &fakeServer{t: t, conn: server, r: bufio.NewReader(server)}
+	return &Conn{conn: client, r: bufio.NewReader(client), w: bufio.NewWriter(client)}, s
+}
+
+// readCommand reads a command as the server does
+func readCommand(r *bufio.Reader) ([]string, error) {
+	command, err := (&Conn{r: r}).read()
+	if err != nil {
+		return nil, err
+	}
+	items, _ := command.([]any)
+	args := make([]string, 0, len(items))
+	for _, item := range items {
+		arg, _ := item.([]byte)
+		args = append(args, string(arg))
+	}
+	return args, nil
+}
+
+// expect reads a command and checks it
+func (s *fakeServer) expect(want ...string) {
+	s.t.Helper()
+	got, err := readCommand(s.r)
+	if err != nil {
+		s.t.Errorf("reading the command: %v", err)
+		return
+	}
+	if !reflect.DeepEqual(got, want) {
+		s.t.Errorf("got command %q, want %q", got, want)
+	}
+}
+
+// write sends replies
+func (s *fakeServer) write(replies string) {
+	if _, err := io.WriteString(s.conn, replies); err != nil {
+		s.t.Errorf("writing %q: %v", replies, err)
+	}
+}
+
+func TestDo(t *testing.T) {
+	c, s := newPipe(t)
+	go func() {
+		s.expect("PUBLISH", "events", "hello")
+		s.write(":2\r\n")
+		s.expect("GET", "missing")
+		s.write("$-1\r\n")
+		s.expect("SET", "k")
+		s.write("-ERR wrong number of arguments for 'set' command\r\n")
+	}()
+	ctx := context.Background()
+
+	if n, err := c.Publish(ctx, "events", []byte("hello")); err != nil || n != 2 {
+		t.Errorf("Publish = %d %v, want 2 subscribers", n, err)
+	}
+	if reply, err := c.Do(ctx, "GET", "missing"); err != nil || reply.([]byte) != nil {
+		t.Errorf("GET = %#v %v, want a null bulk string", reply, err)
+	}
+	_, err := c.Do(ctx, "SET", "k")
+	var replyErr Error
+	if !errors.As(err, &replyErr) || !strings.HasPrefix(string(replyErr), "ERR wrong number") {
+		t.Errorf("SET: got %v, want the error reply", err)
+	}
+}
+
+func TestDoTruncatedReply(t *testing.T) {
+	c, s := newPipe(t)
+	go func() {
+		s.expect("PUBLISH", "events", "hello")
+		s.write("*2\r\n:1\r\n")
+		s.conn.Close()
+	}()
+	if _, err := c.Do(context.Background(), "PUBLISH", "events", "hello"); err == nil {
+		t.Error("a truncated reply was accepted")
+	}
+}
+
+func TestSubscribeReceive(t *testing.T) {
+	c, s := newPipe(t)
+	go func() {
+		s.expect("SUBSCRIBE", "a", "b")
+		s.write("*3\r\n$9\r\nsubscribe\r\n$1\r\na\r\n:1\r\n" + "*3\r\n$9\r\nsubscribe\r\n$1\r\nb\r\n:2\r\n")
+		s.write("*3\r\n$7\r\nmessage\r\n$1\r\nb\r\n$5\r\nhello\r\n")
+		s.expect("PING")
+		s.write("*2\r\n$4\r\npong\r\n$0\r\n\r\n")
+		s.write("*2\r\n$7\r\nmessage\r\n$1\r\na\r\n") // without its payload
+	}()
+
+	if err := c.Subscribe(context.Background(), "a", "b"); err != nil {
+		t.Fatal(err)
+	}
+	m, err := c.Receive(time.Second)
+	if err != nil || m.Channel != "b" || string(m.Payload) != "hello" {
+		t.Errorf("got %+v %v, want hello on b", m, err)
+	}
+	if err := c.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	if m, err := c.Receive(time.Second); err != nil || !m.Pong {
+		t.Errorf("got %+v %v, want the pong", m, err)
+	}
+	if _, err := c.Receive(time.Second); err == nil || errors.Is(err, ErrIdle) {
+		t.Errorf("got %v, want a malformed message", err)
+	}
+}
+
+func TestReceiveIdle(t *testing.T) {
+	c, s := newPipe(t)
+	if _, err := c.Receive(20 * time.Millisecond); !errors.Is(err, ErrIdle) {
+		t.Fatalf("got %v, want ErrIdle", err)
+	}
+
+	// The connection is as good as before
+	go s.write("*3\r\n$7\r\nmessage\r\n$1\r\na\r\n$2\r\nhi\r\n")
+	if m, err := c.Receive(time.Second); err != nil || string(m.Payload) != "hi" {
+		t.Errorf("got %+v %v, want hi", m, err)
+	}
+}
+
+func TestSubscribeRefused(t *testing.T) {
+	c, s := newPipe(t)
+	go func() {
+		s.expect("SUBSCRIBE", "a")
+		s.write("-NOPERM this user has no permissions to access the 'a' channel\r\n")
+	}()
+	var replyErr Error
+	if err := c.Subscribe(context.Background(), "a"); !errors.As(err, &replyErr) {
+		t.Errorf("got %v, want the error reply", err)
+	}
+}
+
+func TestDial(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("no loopback: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					args, err := readCommand(r)
+					if err != nil {
+						return
+					}
+					reply := "-ERR unexpected " + strings.Join(args, " ") + "\r\n"
+					switch strings.Join(args, " ") {
+					case "AUTH backplane s3cret", "AUTH s3cret", "SELECT 2":
+						reply = "+OK\r\n"
+					case "AUTH backplane wrong":
+						reply = "-WRONGPASS invalid username-password pair or user is disabled.\r\n"
+					}
+					_, _ = io.WriteString(conn, reply)
+				}
+			}()
+		}
+	}()
+	ctx := context.Background()
+	address := ln.Addr().String()
+
+	for _, rawURL := range []string{
+		"redis://" + address,
+		"redis://backplane:s3cret@" + address + "/2",
+		"redis://:s3cret@" + address + "/0",
+	} {
+		c, err := Dial(ctx, rawURL)
+		if err != nil {
+			t.Errorf("Dial(%s): %v", rawURL, err)
+			continue
+		}
+		c.Close()
+	}
+
+	for _, rawURL := range []string{
+		"redis://backplane:wrong@" + address,
+		"redis://" + address + "/two",
+		"http://" + address,
+		"redis:///0",
+	} {
+		if c, err := Dial(ctx, rawURL); err == nil {
+			c.Close()
+			t.Errorf("Dial(%s) connected", rawURL)
+		}
+	}
+}
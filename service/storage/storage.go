@@ -0,0 +1,126 @@
+/*
+Package storage keeps the bytes of media (profile, group and message
+photos) outside the database.
+
+The database only records a media ID and the SHA-256 hash of the bytes;
+the bytes themselves go to a BlobStore. FileStore, one file per blob in
+a directory, is the only implementation so far: object stores can be
+added behind the same interface.
+*/
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrNotFound is returned by Get when there is no blob with that ID
+var ErrNotFound = errors.New("storage: blob not found")
+
+// ErrInvalidID is returned for IDs that are not lowercase hex
+var ErrInvalidID = errors.New("storage: invalid blob ID")
+
+/*
+BlobStore stores immutable blobs by ID. The caller chooses the IDs;
+they are lowercase hex strings of at least 4 characters. Put with an
+existing ID replaces the blob, Delete of a missing blob is not an error.
+*/
+type BlobStore interface {
+	Put(id string, data []byte) error
+	Get(id string) ([]byte, error)
+	Delete(id string) error
+}
+
+// FileStore is a BlobStore keeping each blob in a file of a directory.
+// Blobs are spread over subdirectories named after the first two
+// characters of their ID, so no directory grows too large.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore returns a FileStore in dir, creating the directory if needed
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("storage: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// path returns the file of a blob
+func (fs *FileStore) path(id string) (string, error) {
+	if len(id) < 4 {
+		return "", ErrInvalidID
+	}
+	for _, c := range id {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return "", ErrInvalidID
+		}
+	}
+	return filepath.Join(fs.dir, id[:2], id), nil
+}
+
+// Put writes a blob. The bytes go to a temporary file first, which is
+// then renamed: a crash never leaves a half-written blob behind.
+func (fs *FileStore) Put(id string, data []byte) error {
+	path, err := fs.path(id)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("storage: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), id+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("storage: %w", err)
+	}
+	defer func() {
+		// Only left over when something failed
+		_ = os.Remove(tmp.Name())
+	}()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("storage: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("storage: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("storage: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("storage: %w", err)
+	}
+	return nil
+}
+
+// Get reads a blob
+func (fs *FileStore) Get(id string) ([]byte, error) {
+	path, err := fs.path(id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("storage: %w", err)
+	}
+	return data, nil
+}
+
+// Delete removes a blob
+func (fs *FileStore) Delete(id string) error {
+	path, err := fs.path(id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("storage: %w", err)
+	}
+	return nil
+}
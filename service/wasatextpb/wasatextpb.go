@@ -0,0 +1,393 @@
+/*
+Package wasatextpb holds the messages of the gRPC API of WASAText, as
+defined in doc/wasatext.proto, with their protobuf encoding (see
+service/grpc). They are written by hand: a field added to the .proto
+file must be added here with the same number.
+
+Clients call the methods by their full name, e.g.
+
+	client := grpc.NewClient("http://localhost:3001")
+	client.Header.Set("Authorization", "Bearer "+token)
+	var sent wasatextpb.Message
+	err := client.Invoke(ctx, wasatextpb.MethodSendMessage, &wasatextpb.SendMessageRequest{...}, &sent)
+*/
+package wasatextpb
+
+import "wasatext/service/grpc"
+
+// Full names of the methods of the WASAText service
+const (
+	MethodGetConversations = "/wasatext.v1.WASAText/GetConversations"
+	MethodGetConversation  = "/wasatext.v1.WASAText/GetConversation"
+	MethodSendMessage      = "/wasatext.v1.WASAText/SendMessage"
+	MethodStreamMessages   = "/wasatext.v1.WASAText/StreamMessages"
+)
+
+// GetConversationsRequest asks for a page of the user's conversations
+type GetConversationsRequest struct {
+	Limit  int32  // the size of the page, 0 for all
+	Cursor string // the NextCursor of the previous page
+}
+
+func (m *GetConversationsRequest) MarshalProto(e *grpc.Encoder) {
+	e.Int64(1, int64(m.Limit))
+	e.String(2, m.Cursor)
+}
+
+func (m *GetConversationsRequest) UnmarshalProto(d *grpc.Decoder) error {
+	for d.Next() {
+		switch d.Field() {
+		case 1:
+			m.Limit = d.Int32()
+		case 2:
+			m.Cursor = d.String()
+		}
+	}
+	return d.Err()
+}
+
+// GetConversationsResponse is a page of the user's conversations
+type GetConversationsResponse struct {
+	Conversations []*ConversationPreview
+	Total         int32
+	NextCursor    string // empty on the last page
+}
+
+func (m *GetConversationsResponse) MarshalProto(e *grpc.Encoder) {
+	for _, c := range m.Conversations {
+		e.Message(1, c)
+	}
+	e.Int64(2, int64(m.Total))
+	e.String(3, m.NextCursor)
+}
+
+func (m *GetConversationsResponse) UnmarshalProto(d *grpc.Decoder) error {
+	for d.Next() {
+		switch d.Field() {
+		case 1:
+			c := new(ConversationPreview)
+			d.Message(c)
+			m.Conversations = append(m.Conversations, c)
+		case 2:
+			m.Total = d.Int32()
+		case 3:
+			m.NextCursor = d.String()
+		}
+	}
+	return d.Err()
+}
+
+// ConversationPreview is a conversation in the list, with its last message
+type ConversationPreview struct {
+	ConversationID       string
+	IsGroup              bool
+	IsChannel            bool
+	Name                 string
+	PhotoURL             string // signed, short-lived
+	LastMessageTimestamp string
+	LastMessagePreview   string
+	LastMessageIsPhoto   bool
+	Blocked              bool
+}
+
+func (m *ConversationPreview) MarshalProto(e *grpc.Encoder) {
+	e.String(1, m.ConversationID)
+	e.Bool(2, m.IsGroup)
+	e.Bool(3, m.IsChannel)
+	e.String(4, m.Name)
+	e.String(5, m.PhotoURL)
+	e.String(6, m.LastMessageTimestamp)
+	e.String(7, m.LastMessagePreview)
+	e.Bool(8, m.LastMessageIsPhoto)
+	e.Bool(9, m.Blocked)
+}
+
+func (m *ConversationPreview) UnmarshalProto(d *grpc.Decoder) error {
+	for d.Next() {
+		switch d.Field() {
+		case 1:
+			m.ConversationID = d.String()
+		case 2:
+			m.IsGroup = d.Bool()
+		case 3:
+			m.IsChannel = d.Bool()
+		case 4:
+			m.Name = d.String()
+		case 5:
+			m.PhotoURL = d.String()
+		case 6:
+			m.LastMessageTimestamp = d.String()
+		case 7:
+			m.LastMessagePreview = d.String()
+		case 8:
+			m.LastMessageIsPhoto = d.Bool()
+		case 9:
+			m.Blocked = d.Bool()
+		}
+	}
+	return d.Err()
+}
+
+// GetConversationRequest asks for a conversation with its latest messages
+type GetConversationRequest struct {
+	ConversationID string
+	Limit          int32  // how many messages, 0 for the server cap
+	Before         string // the NextBefore of the previous page
+}
+
+func (m *GetConversationRequest) MarshalProto(e *grpc.Encoder) {
+	e.String(1, m.ConversationID)
+	e.Int64(2, int64(m.Limit))
+	e.String(3, m.Before)
+}
+
+func (m *GetConversationRequest) UnmarshalProto(d *grpc.Decoder) error {
+	for d.Next() {
+		switch d.Field() {
+		case 1:
+			m.ConversationID = d.String()
+		case 2:
+			m.Limit = d.Int32()
+		case 3:
+			m.Before = d.String()
+		}
+	}
+	return d.Err()
+}
+
+// Conversation is a conversation with a page of its messages
+type Conversation struct {
+	ConversationID string
+	IsGroup        bool
+	IsChannel      bool
+	Name           string
+	PhotoURL       string
+	Members        []*User
+	Messages       []*Message // the newest first
+	HasMore        bool
+	NextBefore     string
+}
+
+func (m *Conversation) MarshalProto(e *grpc.Encoder) {
+	e.String(1, m.ConversationID)
+	e.Bool(2, m.IsGroup)
+	e.Bool(3, m.IsChannel)
+	e.String(4, m.Name)
+	e.String(5, m.PhotoURL)
+	for _, u := range m.Members {
+		e.Message(6, u)
+	}
+	for _, message := range m.Messages {
+		e.Message(7, message)
+	}
+	e.Bool(8, m.HasMore)
+	e.String(9, m.NextBefore)
+}
+
+func (m *Conversation) UnmarshalProto(d *grpc.Decoder) error {
+	for d.Next() {
+		switch d.Field() {
+		case 1:
+			m.ConversationID = d.String()
+		case 2:
+			m.IsGroup = d.Bool()
+		case 3:
+			m.IsChannel = d.Bool()
+		case 4:
+			m.Name = d.String()
+		case 5:
+			m.PhotoURL = d.String()
+		case 6:
+			u := new(User)
+			d.Message(u)
+			m.Members = append(m.Members, u)
+		case 7:
+			message := new(Message)
+			d.Message(message)
+			m.Messages = append(m.Messages, message)
+		case 8:
+			m.HasMore = d.Bool()
+		case 9:
+			m.NextBefore = d.String()
+		}
+	}
+	return d.Err()
+}
+
+// User is a member of a conversation
+type User struct {
+	Identifier string
+	Name       string
+	Role       string // admin or member, for the members of a group
+	Online     bool
+	Bot        bool
+}
+
+func (m *User) MarshalProto(e *grpc.Encoder) {
+	e.String(1, m.Identifier)
+	e.String(2, m.Name)
+	e.String(3, m.Role)
+	e.Bool(4, m.Online)
+	e.Bool(5, m.Bot)
+}
+
+func (m *User) UnmarshalProto(d *grpc.Decoder) error {
+	for d.Next() {
+		switch d.Field() {
+		case 1:
+			m.Identifier = d.String()
+		case 2:
+			m.Name = d.String()
+		case 3:
+			m.Role = d.String()
+		case 4:
+			m.Online = d.Bool()
+		case 5:
+			m.Bot = d.Bool()
+		}
+	}
+	return d.Err()
+}
+
+// Message is a message of a conversation
+type Message struct {
+	MessageID      string
+	ConversationID string
+	SenderID       string
+	SenderName     string
+	Content        string
+	PhotoURL       string
+	Timestamp      string // RFC 3339
+	Status         string // sent, received or read
+	ReplyTo        string
+	Edited         bool
+	Deleted        bool
+	System         bool
+	Comments       []*Comment
+}
+
+func (m *Message) MarshalProto(e *grpc.Encoder) {
+	e.String(1, m.MessageID)
+	e.String(2, m.ConversationID)
+	e.String(3, m.SenderID)
+	e.String(4, m.SenderName)
+	e.String(5, m.Content)
+	e.String(6, m.PhotoURL)
+	e.String(7, m.Timestamp)
+	e.String(8, m.Status)
+	e.String(9, m.ReplyTo)
+	e.Bool(10, m.Edited)
+	e.Bool(11, m.Deleted)
+	e.Bool(12, m.System)
+	for _, c := range m.Comments {
+		e.Message(13, c)
+	}
+}
+
+func (m *Message) UnmarshalProto(d *grpc.Decoder) error {
+	for d.Next() {
+		switch d.Field() {
+		case 1:
+			m.MessageID = d.String()
+		case 2:
+			m.ConversationID = d.String()
+		case 3:
+			m.SenderID = d.String()
+		case 4:
+			m.SenderName = d.String()
+		case 5:
+			m.Content = d.String()
+		case 6:
+			m.PhotoURL = d.String()
+		case 7:
+			m.Timestamp = d.String()
+		case 8:
+			m.Status = d.String()
+		case 9:
+			m.ReplyTo = d.String()
+		case 10:
+			m.Edited = d.Bool()
+		case 11:
+			m.Deleted = d.Bool()
+		case 12:
+			m.System = d.Bool()
+		case 13:
+			c := new(Comment)
+			d.Message(c)
+			m.Comments = append(m.Comments, c)
+		}
+	}
+	return d.Err()
+}
+
+// Comment is a reaction to a message
+type Comment struct {
+	UserID   string
+	UserName string
+	Emoticon string
+}
+
+func (m *Comment) MarshalProto(e *grpc.Encoder) {
+	e.String(1, m.UserID)
+	e.String(2, m.UserName)
+	e.String(3, m.Emoticon)
+}
+
+func (m *Comment) UnmarshalProto(d *grpc.Decoder) error {
+	for d.Next() {
+		switch d.Field() {
+		case 1:
+			m.UserID = d.String()
+		case 2:
+			m.UserName = d.String()
+		case 3:
+			m.Emoticon = d.String()
+		}
+	}
+	return d.Err()
+}
+
+// SendMessageRequest sends a text message
+type SendMessageRequest struct {
+	ConversationID string
+	Content        string
+	ReplyTo        string
+}
+
+func (m *SendMessageRequest) MarshalProto(e *grpc.Encoder) {
+	e.String(1, m.ConversationID)
+	e.String(2, m.Content)
+	e.String(3, m.ReplyTo)
+}
+
+func (m *SendMessageRequest) UnmarshalProto(d *grpc.Decoder) error {
+	for d.Next() {
+		switch d.Field() {
+		case 1:
+			m.ConversationID = d.String()
+		case 2:
+			m.Content = d.String()
+		case 3:
+			m.ReplyTo = d.String()
+		}
+	}
+	return d.Err()
+}
+
+// StreamMessagesRequest asks for the new messages as they are sent
+type StreamMessagesRequest struct {
+	ConversationID string // only the messages of this conversation, all if empty
+}
+
+func (m *StreamMessagesRequest) MarshalProto(e *grpc.Encoder) {
+	e.String(1, m.ConversationID)
+}
+
+func (m *StreamMessagesRequest) UnmarshalProto(d *grpc.Decoder) error {
+	for d.Next() {
+		if d.Field() == 1 {
+			m.ConversationID = d.String()
+		}
+	}
+	return d.Err()
+}
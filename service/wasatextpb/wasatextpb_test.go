@@ -0,0 +1,145 @@
+package wasatextpb
+
+import (
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"testing"
+
+	"wasatext/service/grpc"
+)
+
+// message is a message of the API
+type message interface {
+	grpc.Marshaler
+	grpc.Unmarshaler
+}
+
+// TestRoundTrip encodes every message with all its fields set and
+// decodes it back
+func TestRoundTrip(t *testing.T) {
+	msg := &Message{
+		MessageID:      "m1",
+		ConversationID: "c1",
+		SenderID:       "u1",
+		SenderName:     "Alice",
+		Content:        "ciao ☕",
+		PhotoURL:       "/media/message-m1?exp=1&sig=x",
+		Timestamp:      "2024-03-09T14:30:22Z",
+		Status:         "read",
+		ReplyTo:        "m0",
+		Edited:         true,
+		Deleted:        true,
+		System:         true,
+		Comments:       []*Comment{{UserID: "u2", UserName: "Bob", Emoticon: "👍"}, {UserID: "u3", UserName: "Carol", Emoticon: "❤️"}},
+	}
+	tests := []struct {
+		name string
+		m    message
+		new  func() message
+	}{
+		{"GetConversationsRequest", &GetConversationsRequest{Limit: 20, Cursor: "next"}, func() message { return new(GetConversationsRequest) }},
+		{"GetConversationsResponse", &GetConversationsResponse{
+			Conversations: []*ConversationPreview{
+				{ConversationID: "c1", IsGroup: true, IsChannel: true, Name: "News", PhotoURL: "/media/x", LastMessageTimestamp: "2024-03-09T14:30:22Z", LastMessagePreview: "hi", LastMessageIsPhoto: true, Blocked: true},
+				{ConversationID: "c2"},
+			},
+			Total:      2,
+			NextCursor: "next",
+		}, func() message { return new(GetConversationsResponse) }},
+		{"GetConversationRequest", &GetConversationRequest{ConversationID: "c1", Limit: 50, Before: "m9"}, func() message { return new(GetConversationRequest) }},
+		{"Conversation", &Conversation{
+			ConversationID: "c1",
+			IsGroup:        true,
+			IsChannel:      true,
+			Name:           "Family",
+			PhotoURL:       "/media/group-g1",
+			Members:        []*User{{Identifier: "u1", Name: "Alice", Role: "admin", Online: true, Bot: true}, {Identifier: "u2", Name: "Bob"}},
+			Messages:       []*Message{msg, {MessageID: "m2"}},
+			HasMore:        true,
+			NextBefore:     "m2",
+		}, func() message { return new(Conversation) }},
+		{"Message", msg, func() message { return new(Message) }},
+		{"SendMessageRequest", &SendMessageRequest{ConversationID: "c1", Content: "hi", ReplyTo: "m1"}, func() message { return new(SendMessageRequest) }},
+		{"StreamMessagesRequest", &StreamMessagesRequest{ConversationID: "c1"}, func() message { return new(StreamMessagesRequest) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded := tt.new()
+			if err := grpc.Unmarshal(grpc.Marshal(tt.m), decoded); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(decoded, tt.m) {
+				t.Errorf("decoded %+v, want %+v", decoded, tt.m)
+			}
+
+			// The empty message is encoded as no bytes
+			if data := grpc.Marshal(tt.new()); len(data) != 0 {
+				t.Errorf("the empty message is encoded as % x", data)
+			}
+		})
+	}
+}
+
+// TestFieldNumbers checks the numbers of doc/wasatext.proto against the
+// protobuf encoding of a message
+func TestFieldNumbers(t *testing.T) {
+	m := &SendMessageRequest{ConversationID: "c", Content: "hi", ReplyTo: "m"}
+	if got, want := hex.EncodeToString(grpc.Marshal(m)), "0a0163"+"12026869"+"1a016d"; got != want {
+		t.Errorf("SendMessageRequest encoded as %s, want %s", got, want)
+	}
+
+	preview := &ConversationPreview{ConversationID: "c", Blocked: true}
+	if got, want := hex.EncodeToString(grpc.Marshal(preview)), "0a0163"+"4801"; got != want {
+		t.Errorf("ConversationPreview encoded as %s, want %s", got, want)
+	}
+
+	request := &GetConversationsRequest{Limit: 300}
+	if got, want := hex.EncodeToString(grpc.Marshal(request)), "08ac02"; got != want {
+		t.Errorf("GetConversationsRequest encoded as %s, want %s", got, want)
+	}
+}
+
+// TestNewerFields decodes messages from a newer version of the API:
+// the fields it added are skipped
+func TestNewerFields(t *testing.T) {
+	// A Message with a field 14 (a string) and a field 15 (a varint),
+	// and a comment with a field 4 (a fixed32)
+	data, err := hex.DecodeString("0a026d31" + "72036e6577" + "7801" + "6a0b" + "1a04f09f918d" + "2501020304" + "120161")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m Message
+	if err := grpc.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	want := Message{MessageID: "m1", ConversationID: "a", Comments: []*Comment{{Emoticon: "👍"}}}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("decoded %+v, want %+v", m, want)
+	}
+}
+
+func TestMalformed(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		data string // hex
+		m    message
+	}{
+		{"string as a varint", "0801", new(SendMessageRequest)},
+		{"limit as a string", "0a0131", new(GetConversationsRequest)},
+		{"bool as a string", "12015a", new(ConversationPreview)},
+		{"cut short", "0a05", new(StreamMessagesRequest)},
+		{"malformed member", "3202" + "0a05", new(Conversation)},
+		{"malformed comment of a message", "3a04" + "6a02" + "0a05", new(Conversation)},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := hex.DecodeString(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := grpc.Unmarshal(data, tt.m); !errors.Is(err, grpc.ErrMalformed) {
+				t.Errorf("got %v, want ErrMalformed", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,332 @@
+/*
+Package websocket is a small WebSocket implementation (RFC 6455).
+
+The API pushes its real-time events over WebSocket, and the command-line
+tools listen to them. Neither needs extensions or subprotocols:
+
+  - Upgrade answers the opening handshake and takes over the connection;
+  - Dial (client.go) opens a connection to a server;
+  - ReadMessage returns the messages of the other side, reassembling
+    fragments and answering pings and the closing handshake;
+  - WriteText and Ping send frames; they can be called while another
+    goroutine is reading.
+
+Client frames are masked, server frames are not; messages are limited to
+MaxMessageSize.
+*/
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec // required by the WebSocket handshake
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// acceptGUID is appended to the client key in the handshake (RFC 6455, 1.3)
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Opcodes
+const (
+	OpContinuation = 0x0
+	OpText         = 0x1
+	OpBinary       = 0x2
+	OpClose        = 0x8
+	OpPing         = 0x9
+	OpPong         = 0xA
+)
+
+// MaxMessageSize is the largest message the other side may send
+const MaxMessageSize = 64 << 10
+
+// closeTimeout bounds the closing handshake
+const closeTimeout = time.Second
+
+var (
+	// ErrBadHandshake is returned by Upgrade for a request that is not a WebSocket handshake
+	ErrBadHandshake = errors.New("websocket: bad handshake")
+	// ErrProtocol is returned by ReadMessage when the other side breaks the protocol
+	ErrProtocol = errors.New("websocket: protocol error")
+	// ErrMessageTooLarge is returned by ReadMessage for messages over MaxMessageSize
+	ErrMessageTooLarge = errors.New("websocket: message too large")
+)
+
+// Conn is a WebSocket connection, on the server or the client side
+type Conn struct {
+	conn   net.Conn
+	br     *bufio.Reader
+	client bool // opened by Dial: frames are masked on the way out
+
+	writeMu sync.Mutex // frames are written whole, one at a time
+	closed  bool       // a close frame was sent (guarded by writeMu)
+
+	// The message being reassembled, kept across the control frames
+	// that may come between its fragments (used by the reader only)
+	partial   []byte
+	partialOp int // its opcode, 0 when there is none
+}
+
+/*
+Upgrade answers the opening handshake of a WebSocket request and returns
+the connection. When the request is not a valid handshake it answers
+with an HTTP error and returns ErrBadHandshake.
+*/
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	// Step 1: Check the handshake
+	if r.Method != http.MethodGet ||
+		!headerHasToken(r.Header, "Connection", "upgrade") ||
+		!headerHasToken(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "WebSocket upgrade required", http.StatusUpgradeRequired)
+		return nil, ErrBadHandshake
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "Unsupported WebSocket version", http.StatusUpgradeRequired)
+		return nil, ErrBadHandshake
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		http.Error(w, "Invalid Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, ErrBadHandshake
+	}
+
+	// Step 2: Take over the connection
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "WebSocket not supported", http.StatusInternalServerError)
+		return nil, ErrBadHandshake
+	}
+	conn, brw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 3: Accept
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n"
+	if _, err := conn.Write([]byte(response)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &Conn{conn: conn, br: brw.Reader}, nil
+}
+
+// acceptKey computes Sec-WebSocket-Accept from Sec-WebSocket-Key
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID)) //nolint:gosec // required by the WebSocket handshake
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// headerHasToken reports whether a comma-separated header contains a token
+func headerHasToken(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, item := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(item), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/*
+ReadMessage returns the next message of the other side with its opcode
+(OpText or OpBinary). Pings are answered, and returned as OpPing, and
+pongs are returned as OpPong, so that the caller can tell the other side
+is alive; they may come between the fragments of a message, which the
+next call goes on reassembling. When the other side closes the
+connection, ReadMessage answers the close frame and returns io.EOF.
+*/
+func (c *Conn) ReadMessage() (int, []byte, error) {
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch opcode {
+		case OpPing:
+			if err := c.writeFrame(OpPong, payload); err != nil {
+				return 0, nil, err
+			}
+			return OpPing, payload, nil
+		case OpPong:
+			return OpPong, payload, nil
+		case OpClose:
+			_ = c.writeClose(payload)
+			return 0, nil, io.EOF
+		case OpText, OpBinary:
+			if c.partialOp != 0 {
+				return 0, nil, ErrProtocol // a new message inside a fragmented one
+			}
+			c.partialOp = opcode
+		case OpContinuation:
+			if c.partialOp == 0 {
+				return 0, nil, ErrProtocol // nothing to continue
+			}
+		default:
+			return 0, nil, ErrProtocol
+		}
+
+		if len(c.partial)+len(payload) > MaxMessageSize {
+			return 0, nil, ErrMessageTooLarge
+		}
+		c.partial = append(c.partial, payload...)
+		if fin {
+			messageOp, message := c.partialOp, c.partial
+			c.partial, c.partialOp = nil, 0
+			return messageOp, message, nil
+		}
+	}
+}
+
+// readFrame reads one frame and unmasks the payload of a client frame
+func (c *Conn) readFrame() (fin bool, opcode int, payload []byte, err error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin = header[0]&0x80 != 0
+	opcode = int(header[0] & 0x0F)
+	masked := header[1]&0x80 != 0
+	if header[0]&0x70 != 0 || masked == c.client {
+		return false, 0, nil, ErrProtocol // no extensions; clients mask, servers do not
+	}
+
+	// Payload length: 7 bits, or 16 or 64 bits that follow
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if opcode >= OpClose && (length > 125 || !fin) {
+		return false, 0, nil, ErrProtocol // control frames are short and whole
+	}
+	if length > MaxMessageSize {
+		return false, 0, nil, ErrMessageTooLarge
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		maskBytes(payload, mask)
+	}
+
+	return fin, opcode, payload, nil
+}
+
+// WriteText sends a text message
+func (c *Conn) WriteText(data []byte) error {
+	return c.writeFrame(OpText, data)
+}
+
+// Ping sends a ping; the other side answers with a pong
+func (c *Conn) Ping() error {
+	return c.writeFrame(OpPing, nil)
+}
+
+// writeFrame sends one frame, masked on the client side
+func (c *Conn) writeFrame(opcode int, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closed {
+		return net.ErrClosed
+	}
+	return c.writeFrameLocked(opcode, payload)
+}
+
+func (c *Conn) writeFrameLocked(opcode int, payload []byte) error {
+	var maskBit byte
+	if c.client {
+		maskBit = 0x80
+	}
+	frame := make([]byte, 0, 14+len(payload))
+	frame = append(frame, 0x80|byte(opcode))
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, maskBit|byte(n))
+	case n <= 0xFFFF:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	if c.client {
+		var mask [4]byte
+		_, _ = rand.Read(mask[:])
+		frame = append(frame, mask[:]...)
+		start := len(frame)
+		frame = append(frame, payload...)
+		maskBytes(frame[start:], mask)
+	} else {
+		frame = append(frame, payload...)
+	}
+
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// writeClose sends the close frame, once
+func (c *Conn) writeClose(payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	_ = c.conn.SetWriteDeadline(time.Now().Add(closeTimeout))
+	return c.writeFrameLocked(OpClose, payload)
+}
+
+// maskBytes masks or unmasks a payload (the same XOR both ways)
+func maskBytes(payload []byte, mask [4]byte) {
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+}
+
+// SetReadDeadline bounds the wait for the next frame of the other side
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline bounds the writes to the other side
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// Close sends a close frame (going away) and closes the connection
+func (c *Conn) Close() error {
+	_ = c.writeClose([]byte{0x03, 0xE9}) // 1001: going away
+	return c.conn.Close()
+}
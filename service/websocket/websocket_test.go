@@ -0,0 +1,243 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// fakeConn records what the Conn writes to the other side; what the
+// other side sends is read from a buffer (see newTestConn)
+type fakeConn struct {
+	net.Conn
+	output bytes.Buffer
+}
+
+func (f *fakeConn) Write(b []byte) (int, error)      { return f.output.Write(b) }
+func (f *fakeConn) Close() error                     { return nil }
+func (f *fakeConn) SetReadDeadline(time.Time) error  { return nil }
+func (f *fakeConn) SetWriteDeadline(time.Time) error { return nil }
+
+// newTestConn returns the server side of a connection (the client side
+// when client is set) receiving the frames
+func newTestConn(client bool, frames ...[]byte) (*Conn, *fakeConn) {
+	fc := &fakeConn{}
+	input := bytes.NewReader(bytes.Join(frames, nil))
+	return &Conn{conn: fc, br: bufio.NewReader(input), client: client}, fc
+}
+
+var testMask = [4]byte{0x37, 0xfa, 0x21, 0x3d}
+
+// frame encodes a frame the way the other side would, masked with
+// testMask when masked is set
+func frame(fin bool, opcode int, payload []byte, masked bool) []byte {
+	first := byte(opcode)
+	if fin {
+		first |= 0x80
+	}
+	var maskBit byte
+	if masked {
+		maskBit = 0x80
+	}
+	b := []byte{first}
+	switch n := len(payload); {
+	case n <= 125:
+		b = append(b, maskBit|byte(n))
+	case n <= 0xFFFF:
+		b = append(b, maskBit|126)
+		b = binary.BigEndian.AppendUint16(b, uint16(n))
+	default:
+		b = append(b, maskBit|127)
+		b = binary.BigEndian.AppendUint64(b, uint64(n))
+	}
+	if !masked {
+		return append(b, payload...)
+	}
+	b = append(b, testMask[:]...)
+	for i, c := range payload {
+		b = append(b, c^testMask[i%4])
+	}
+	return b
+}
+
+// clientFrame is a whole masked frame from a client
+func clientFrame(opcode int, payload []byte) []byte {
+	return frame(true, opcode, payload, true)
+}
+
+func TestAcceptKey(t *testing.T) {
+	// The example of RFC 6455, 1.3
+	if got := acceptKey("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestReadMaskedClientFrame(t *testing.T) {
+	// The masked "Hello" of RFC 6455, 5.7
+	c, _ := newTestConn(false, []byte{0x81, 0x85, 0x37, 0xfa, 0x21, 0x3d, 0x7f, 0x9f, 0x4d, 0x51, 0x58})
+	opcode, data, err := c.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opcode != OpText || string(data) != "Hello" {
+		t.Errorf("got %d %q, want a text Hello", opcode, data)
+	}
+}
+
+func TestPayloadLengths(t *testing.T) {
+	tests := []struct {
+		name   string
+		size   int
+		header []byte // of the server frame
+	}{
+		{"7-bit", 125, []byte{0x81, 125}},
+		{"16-bit smallest", 126, []byte{0x81, 126, 0x00, 0x7E}},
+		{"16-bit largest", 0xFFFF, []byte{0x81, 126, 0xFF, 0xFF}},
+		{"64-bit", MaxMessageSize, []byte{0x81, 127, 0, 0, 0, 0, 0, 0x01, 0x00, 0x00}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := bytes.Repeat([]byte{'x'}, tt.size)
+
+			// Written by the server
+			server, out := newTestConn(false)
+			if err := server.WriteText(payload); err != nil {
+				t.Fatal(err)
+			}
+			if want := append(tt.header, payload...); !bytes.Equal(out.output.Bytes(), want) {
+				t.Errorf("server frame starts with % x, want % x", out.output.Bytes()[:len(tt.header)], tt.header)
+			}
+
+			// Written by a client, masked, and read by the server
+			client, out := newTestConn(true)
+			if err := client.WriteText(payload); err != nil {
+				t.Fatal(err)
+			}
+			if out.output.Bytes()[1]&0x80 == 0 {
+				t.Error("client frame not masked")
+			}
+			server, _ = newTestConn(false, out.output.Bytes())
+			_, data, err := server.ReadMessage()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, payload) {
+				t.Errorf("read %d bytes back, want %d", len(data), len(payload))
+			}
+		})
+	}
+}
+
+func TestFragmentedMessage(t *testing.T) {
+	c, out := newTestConn(false,
+		frame(false, OpText, []byte("Hel"), true),
+		frame(false, OpContinuation, []byte("lo, "), true),
+		clientFrame(OpPing, []byte("still there?")), // between fragments
+		frame(true, OpContinuation, []byte("world"), true),
+		clientFrame(OpBinary, []byte{1, 2, 3}),
+	)
+
+	opcode, data, err := c.ReadMessage()
+	if err != nil || opcode != OpPing || string(data) != "still there?" {
+		t.Fatalf("got %d %q %v, want the ping", opcode, data, err)
+	}
+	opcode, data, err = c.ReadMessage()
+	if err != nil || opcode != OpText || string(data) != "Hello, world" {
+		t.Fatalf("got %d %q %v, want the reassembled text", opcode, data, err)
+	}
+	opcode, data, err = c.ReadMessage()
+	if err != nil || opcode != OpBinary || !bytes.Equal(data, []byte{1, 2, 3}) {
+		t.Fatalf("got %d %v %v, want the binary message", opcode, data, err)
+	}
+
+	// The ping was answered with its payload
+	if want := frame(true, OpPong, []byte("still there?"), false); !bytes.Equal(out.output.Bytes(), want) {
+		t.Errorf("wrote % x, want the pong % x", out.output.Bytes(), want)
+	}
+}
+
+func TestPong(t *testing.T) {
+	c, out := newTestConn(false, clientFrame(OpPong, nil))
+	opcode, _, err := c.ReadMessage()
+	if err != nil || opcode != OpPong {
+		t.Fatalf("got %d %v, want the pong", opcode, err)
+	}
+	if out.output.Len() != 0 {
+		t.Errorf("a pong was answered with % x", out.output.Bytes())
+	}
+}
+
+func TestProtocolErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		client bool // the Conn reading is a client
+		frames [][]byte
+		want   error
+	}{
+		{"unmasked client frame", false, [][]byte{frame(true, OpText, []byte("hi"), false)}, ErrProtocol},
+		{"masked server frame", true, [][]byte{frame(true, OpText, []byte("hi"), true)}, ErrProtocol},
+		{"reserved bits", false, [][]byte{{0xC1, 0x80, 0, 0, 0, 0}}, ErrProtocol},
+		{"unknown opcode", false, [][]byte{clientFrame(0x3, nil)}, ErrProtocol},
+		{"continuation of nothing", false, [][]byte{clientFrame(OpContinuation, []byte("lo"))}, ErrProtocol},
+		{"message inside a fragmented one", false, [][]byte{
+			frame(false, OpText, []byte("Hel"), true),
+			clientFrame(OpText, []byte("lo")),
+		}, ErrProtocol},
+		{"fragmented control frame", false, [][]byte{frame(false, OpPing, nil, true)}, ErrProtocol},
+		{"long control frame", false, [][]byte{clientFrame(OpPing, bytes.Repeat([]byte{'x'}, 126))}, ErrProtocol},
+		{"frame too large", false, [][]byte{clientFrame(OpBinary, make([]byte, MaxMessageSize+1))}, ErrMessageTooLarge},
+		{"fragments too large", false, [][]byte{
+			frame(false, OpBinary, make([]byte, MaxMessageSize), true),
+			frame(true, OpContinuation, []byte{0}, true),
+		}, ErrMessageTooLarge},
+		{"truncated length", false, [][]byte{{0x81, 0xFE, 0x01}}, io.ErrUnexpectedEOF},
+		{"truncated payload", false, [][]byte{clientFrame(OpText, []byte("Hello"))[:8]}, io.ErrUnexpectedEOF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newTestConn(tt.client, tt.frames...)
+			if _, _, err := c.ReadMessage(); !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCloseHandshake(t *testing.T) {
+	// The client closes (1000, normal closure): the server answers with
+	// the same close frame, once, and sends nothing after it
+	closeFrame := []byte{0x03, 0xE8, 'b', 'y', 'e'}
+	c, out := newTestConn(false, clientFrame(OpClose, closeFrame))
+	if _, _, err := c.ReadMessage(); !errors.Is(err, io.EOF) {
+		t.Fatalf("got %v, want io.EOF", err)
+	}
+	if want := frame(true, OpClose, closeFrame, false); !bytes.Equal(out.output.Bytes(), want) {
+		t.Errorf("answered % x, want % x", out.output.Bytes(), want)
+	}
+	if err := c.WriteText([]byte("too late")); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("write after close: got %v, want net.ErrClosed", err)
+	}
+	out.output.Reset()
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if out.output.Len() != 0 {
+		t.Errorf("a second close frame was sent: % x", out.output.Bytes())
+	}
+}
+
+func TestClose(t *testing.T) {
+	// Closing first sends 1001, going away
+	c, out := newTestConn(false)
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{0x88, 0x02, 0x03, 0xE9}; !bytes.Equal(out.output.Bytes(), want) {
+		t.Errorf("sent % x, want % x", out.output.Bytes(), want)
+	}
+}
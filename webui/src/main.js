@@ -4,6 +4,17 @@ import router from './router';
 import 'bootstrap/dist/css/bootstrap.min.css';
 import 'bootstrap/dist/js/bootstrap.bundle.min.js';
 import './assets/main.css';
+import { config } from './services/api.js';
+
+// Branding of the deployment (GET /config.js)
+if (config.appName) {
+    document.title = config.appName;
+}
+if (config.accentColor) {
+    const rgb = [1, 3, 5].map((i) => parseInt(config.accentColor.slice(i, i + 2), 16));
+    document.documentElement.style.setProperty('--bs-success', config.accentColor);
+    document.documentElement.style.setProperty('--bs-success-rgb', rgb.join(', '));
+}
 
 const app = createApp(App);
 app.use(router);
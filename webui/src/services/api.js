@@ -1,63 +1,146 @@
 import axios from 'axios';
 
+// Deployment settings, set by GET /config.js (loaded by index.html)
+export const config = window.__WASATEXT_CONFIG__ || {};
+
 const instance = axios.create({
-    baseURL: location.origin,
+    baseURL: config.apiBaseUrl || location.origin,
     timeout: 10000,
 });
 
 // Interceptor to add Authorization header
 instance.interceptors.request.use((config) => {
-    const userId = sessionStorage.getItem('userId');
-    if (userId) {
-        config.headers['Authorization'] = `Bearer ${userId}`;
+    const token = sessionStorage.getItem('token');
+    if (token) {
+        config.headers['Authorization'] = `Bearer ${token}`;
     }
     return config;
 });
 
 export default {
     // LOGIN
-    async login(username) {
-        const response = await instance.post('/session', { name: username });
+    async login(username, workspace) {
+        const response = await instance.post('/session', { name: username, workspace: workspace });
         return response.data;
     },
+    async logout() {
+        await instance.delete('/session');
+    },
+    async listWorkspaces() {
+        const response = await instance.get('/workspaces');
+        return response.data.items;
+    },
 
     // USERS
     async searchUsers(query) {
         const response = await instance.get('/users', { params: { search: query } });
-        return response.data;
+        return response.data.items;
     },
     async setMyUserName(userId, name) {
         const response = await instance.put(`/users/${userId}/username`, { name: name });
         return response.data;
     },
+    async getUserProfile(userId) {
+        const response = await instance.get(`/users/${userId}`);
+        return response.data;
+    },
+    async setMyAbout(userId, about) {
+        const response = await instance.put(`/users/${userId}/about`, { about: about });
+        return response.data;
+    },
     async setMyPhoto(userId, photoData) {
         const response = await instance.put(`/users/${userId}/photo`, photoData, {
             headers: { 'Content-Type': 'image/png' }
         });
         return response.data;
     },
+    async blockUser(userId) {
+        await instance.put(`/users/${userId}/block`);
+    },
+    async unblockUser(userId) {
+        await instance.delete(`/users/${userId}/block`);
+    },
+    async getMyPrivacy() {
+        const response = await instance.get('/users/me/privacy');
+        return response.data;
+    },
+    async setMyPrivacy(hidePresence) {
+        const response = await instance.put('/users/me/privacy', { hidePresence: hidePresence });
+        return response.data;
+    },
+    async deactivateMyAccount() {
+        await instance.post('/users/me/deactivate');
+    },
+    async getMyKeyBackup() {
+        const response = await instance.get('/users/me/key-backup');
+        return response.data;
+    },
+    async putMyKeyBackup(data, replaces) {
+        const response = await instance.put('/users/me/key-backup', { data: data, replaces: replaces });
+        return response.data;
+    },
+    async deleteMyKeyBackup() {
+        await instance.delete('/users/me/key-backup');
+    },
 
     // CONVERSATIONS
     async getMyConversations() {
         const response = await instance.get('/conversations');
-        return response.data;
+        return response.data.items;
     },
-    async getConversation(conversationId) {
-        const response = await instance.get(`/conversations/${conversationId}`);
+    async getConversation(conversationId, before) {
+        const params = before ? { before: before } : {};
+        const response = await instance.get(`/conversations/${conversationId}`, { params: params });
         return response.data;
     },
+    async markConversationRead(conversationId, upToMessageId) {
+        const body = upToMessageId ? { upToMessageId: upToMessageId } : {};
+        await instance.put(`/conversations/${conversationId}/read`, body);
+    },
     async startConversation(targetUserId) {
         const response = await instance.post('/conversations', { userId: targetUserId });
         return response.data;
     },
+    async getConversationMute(conversationId) {
+        const response = await instance.get(`/conversations/${conversationId}/mute`);
+        return response.data;
+    },
+    async muteConversation(conversationId, until) {
+        const body = until ? { until: until } : {};
+        const response = await instance.put(`/conversations/${conversationId}/mute`, body);
+        return response.data;
+    },
+    async unmuteConversation(conversationId) {
+        await instance.delete(`/conversations/${conversationId}/mute`);
+    },
+
+    // PUSH NOTIFICATIONS
+    async getVapidKey() {
+        const response = await instance.get('/push/vapid-key');
+        return response.data.publicKey;
+    },
+    async subscribePush(subscription) {
+        // subscription: the PushSubscription of the browser, sent as its JSON
+        const response = await instance.post('/push/subscriptions', subscription.toJSON ? subscription.toJSON() : subscription);
+        return response.data;
+    },
+    async getMyPushSubscriptions() {
+        const response = await instance.get('/push/subscriptions');
+        return response.data.items;
+    },
+    async unsubscribePush(subscriptionId) {
+        await instance.delete(`/push/subscriptions/${subscriptionId}`);
+    },
 
     // MESSAGES
     async sendMessage(conversationId, content) {
         const response = await instance.post(`/conversations/${conversationId}/messages`, { content: content });
         return response.data;
     },
-    async deleteMessage(conversationId, messageId) {
-        const response = await instance.delete(`/conversations/${conversationId}/messages/${messageId}`);
+    async deleteMessage(conversationId, messageId, scope = 'everyone') {
+        const response = await instance.delete(`/conversations/${conversationId}/messages/${messageId}`, {
+            params: { scope: scope }
+        });
         return response.data;
     },
     async forwardMessage(conversationId, messageId, targetConversationId) {
@@ -74,8 +157,26 @@ export default {
         });
         return response.data;
     },
-    async uncommentMessage(conversationId, messageId) {
-        const response = await instance.delete(`/conversations/${conversationId}/messages/${messageId}/comments`);
+    async uncommentMessage(conversationId, messageId, emoticon) {
+        let path = `/conversations/${conversationId}/messages/${messageId}/comments`;
+        if (emoticon) {
+            path += '/' + encodeURIComponent(emoticon);
+        }
+        const response = await instance.delete(path);
+        return response.data;
+    },
+
+    // PINS
+    async pinMessage(conversationId, messageId) {
+        const response = await instance.put(`/conversations/${conversationId}/messages/${messageId}/pin`);
+        return response.data;
+    },
+    async unpinMessage(conversationId, messageId) {
+        const response = await instance.delete(`/conversations/${conversationId}/messages/${messageId}/pin`);
+        return response.data;
+    },
+    async getPins(conversationId) {
+        const response = await instance.get(`/conversations/${conversationId}/pins`);
         return response.data;
     },
 
@@ -84,6 +185,10 @@ export default {
         const response = await instance.post('/groups', { name: name, memberIds: memberIds });
         return response.data;
     },
+    async getGroup(groupId) {
+        const response = await instance.get(`/groups/${groupId}`);
+        return response.data;
+    },
     async addToGroup(groupId, userId) {
         const response = await instance.post(`/groups/${groupId}/members`, { userId: userId });
         return response.data;
@@ -96,10 +201,50 @@ export default {
         const response = await instance.put(`/groups/${groupId}/name`, { name: name });
         return response.data;
     },
+    async setGroupDescription(groupId, description) {
+        const response = await instance.put(`/groups/${groupId}/description`, { description: description });
+        return response.data;
+    },
     async setGroupPhoto(groupId, photoData) {
         const response = await instance.put(`/groups/${groupId}/photo`, photoData, {
             headers: { 'Content-Type': 'image/png' }
         });
         return response.data;
     },
+    async setGroupGovernance(groupId, enabled) {
+        const response = await instance.put(`/groups/${groupId}/governance`, { enabled: enabled });
+        return response.data;
+    },
+    async createProposal(groupId, proposal) {
+        const response = await instance.post(`/groups/${groupId}/proposals`, proposal);
+        return response.data;
+    },
+    async getProposals(groupId) {
+        const response = await instance.get(`/groups/${groupId}/proposals`);
+        return response.data;
+    },
+    async voteOnProposal(groupId, proposalId, approve) {
+        const response = await instance.put(`/groups/${groupId}/proposals/${proposalId}/vote`, { approve: approve });
+        return response.data;
+    },
+    async createGroupWebhook(groupId, url, events) {
+        const response = await instance.post(`/groups/${groupId}/webhooks`, { url: url, events: events });
+        return response.data;
+    },
+    async listGroupWebhooks(groupId) {
+        const response = await instance.get(`/groups/${groupId}/webhooks`);
+        return response.data;
+    },
+    async deleteGroupWebhook(groupId, webhookId) {
+        const response = await instance.delete(`/groups/${groupId}/webhooks/${webhookId}`);
+        return response.data;
+    },
+    async getGroupWebhookDeliveries(groupId, webhookId) {
+        const response = await instance.get(`/groups/${groupId}/webhooks/${webhookId}/deliveries`);
+        return response.data;
+    },
+    async graphql(query, variables) {
+        const response = await instance.post('/graphql', { query: query, variables: variables || {} });
+        return response.data;
+    },
 };